@@ -17,6 +17,10 @@ import (
 
 var baseURL string
 var errorLog bool
+var readOnly bool
+
+// ErrReadOnly is returned for mutations while read-only mode is on
+var ErrReadOnly = errors.New("changes are blocked in read-only mode, drop --read-only or unset FLY_READ_ONLY to make them")
 
 // SetBaseURL - Sets the base URL for the API
 func SetBaseURL(url string) {
@@ -28,6 +32,11 @@ func SetErrorLog(log bool) {
 	errorLog = log
 }
 
+// SetReadOnly - Sets whether mutations are refused, so nothing can be changed by accident
+func SetReadOnly(enabled bool) {
+	readOnly = enabled
+}
+
 // Client - API client encapsulating the http and GraphQL clients
 type Client struct {
 	httpClient  *http.Client
@@ -61,6 +70,10 @@ func (c *Client) Run(req *graphql.Request) (Query, error) {
 
 // RunWithContext - Runs a GraphQL request within a Go context
 func (c *Client) RunWithContext(ctx context.Context, req *graphql.Request) (Query, error) {
+	if readOnly && strings.HasPrefix(strings.TrimSpace(req.Query()), "mutation") {
+		return Query{}, ErrReadOnly
+	}
+
 	req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", c.accessToken))
 	req.Header.Set("User-Agent", c.userAgent)
 
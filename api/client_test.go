@@ -0,0 +1,38 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadOnlyBlocksMutations(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"data": {"app": {"name": "my-app"}}}`))
+	}))
+	defer server.Close()
+
+	origURL := baseURL
+	SetBaseURL(server.URL)
+	SetReadOnly(true)
+	t.Cleanup(func() {
+		SetBaseURL(origURL)
+		SetReadOnly(false)
+	})
+
+	client := NewClient("token", "test")
+
+	_, err := client.SetSecrets("my-app", map[string]string{"KEY": "value"})
+	assert.Equal(t, ErrReadOnly, err)
+	assert.Equal(t, 0, requests)
+
+	app, err := client.GetApp("my-app")
+	assert.NoError(t, err)
+	assert.Equal(t, "my-app", app.Name)
+	assert.Equal(t, 1, requests)
+}
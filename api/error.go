@@ -1,6 +1,9 @@
 package api
 
-import "net/http"
+import (
+	"net/http"
+	"strings"
+)
 
 type ApiError struct {
 	WrappedError error
@@ -44,3 +47,16 @@ func IsClientError(err error) bool {
 	}
 	return false
 }
+
+// IsRateLimitError reports whether the API turned the request away for being over a rate limit,
+// either with a 429 or in a GraphQL error
+func IsRateLimitError(err error) bool {
+	if apiErr, ok := err.(*ApiError); ok {
+		return apiErr.Status == 429
+	}
+	if err == nil {
+		return false
+	}
+	msg := strings.ToLower(err.Error())
+	return strings.Contains(msg, "rate limit") || strings.Contains(msg, "too many requests")
+}
@@ -1,9 +1,10 @@
 package api
 
+// GetApps returns every app the user can see, fetching them a page at a time
 func (client *Client) GetApps(role *string) ([]App, error) {
 	query := `
-		query($role: String) {
-			apps(type: "container", first: 400, role: $role) {
+		query($role: String, $after: String) {
+			apps(type: "container", first: 400, after: $after, role: $role) {
 				nodes {
 					id
 					name
@@ -16,22 +17,39 @@ func (client *Client) GetApps(role *string) ([]App, error) {
 						createdAt
 					}
 					status
+					labels
+				}
+				pageInfo {
+					hasNextPage
+					endCursor
 				}
 			}
 		}
 		`
 
-	req := client.NewRequest(query)
-	if role != nil {
-		req.Var("role", *role)
-	}
+	var apps []App
+	after := ""
 
-	data, err := client.Run(req)
-	if err != nil {
-		return nil, err
-	}
+	for {
+		req := client.NewRequest(query)
+		if role != nil {
+			req.Var("role", *role)
+		}
+		if after != "" {
+			req.Var("after", after)
+		}
+
+		data, err := client.Run(req)
+		if err != nil {
+			return nil, err
+		}
 
-	return data.Apps.Nodes, nil
+		apps = append(apps, data.Apps.Nodes...)
+		if !data.Apps.PageInfo.HasNextPage || data.Apps.PageInfo.EndCursor == "" {
+			return apps, nil
+		}
+		after = data.Apps.PageInfo.EndCursor
+	}
 }
 
 func (client *Client) GetAppID(appName string) (string, error) {
@@ -69,6 +87,7 @@ func (client *Client) GetApp(appName string) (*App, error) {
 					id
 					slug
 				}
+				labels
 				services {
 					description
 					protocol
@@ -227,6 +246,38 @@ func (client *Client) MoveApp(appName string, orgID string) (*App, error) {
 	return &data.App, err
 }
 
+// RenameApp changes an app's name, keeping its releases, certificates, IP addresses and volumes
+func (client *Client) RenameApp(appName string, newName string) (*App, error) {
+	query := `
+		mutation ($input: RenameAppInput!) {
+			renameApp(input: $input) {
+				app {
+					id
+					name
+					hostname
+					organization {
+						slug
+					}
+				}
+			}
+		}
+	`
+
+	req := client.NewRequest(query)
+
+	req.Var("input", map[string]string{
+		"appId": appName,
+		"name":  newName,
+	})
+
+	data, err := client.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data.RenameApp.App, nil
+}
+
 // SuspendApp - Send GQL mutation to suspend app
 func (client *Client) SuspendApp(appName string) (*App, error) {
 	query := `
@@ -328,3 +379,101 @@ func (client *Client) ResolveImageForApp(appName, imageRef string) (*Image, erro
 
 	return data.App.Image, nil
 }
+
+// GetSuspendSchedule returns the app's suspend schedule, nil when it doesn't have one
+func (client *Client) GetSuspendSchedule(appName string) (*SuspendSchedule, error) {
+	query := `
+		query ($appName: String!) {
+			app(name: $appName) {
+				suspendSchedule {
+					suspend
+					resume
+					timezone
+				}
+			}
+		}
+	`
+
+	req := client.NewRequest(query)
+
+	req.Var("appName", appName)
+
+	data, err := client.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.App.SuspendSchedule, nil
+}
+
+func (client *Client) SetSuspendSchedule(input SetSuspendScheduleInput) (*SuspendSchedule, error) {
+	query := `
+		mutation ($input: SetSuspendScheduleInput!) {
+			setSuspendSchedule(input: $input) {
+				app {
+					suspendSchedule {
+						suspend
+						resume
+						timezone
+					}
+				}
+			}
+		}
+	`
+
+	req := client.NewRequest(query)
+
+	req.Var("input", input)
+
+	data, err := client.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.SetSuspendSchedule.App.SuspendSchedule, nil
+}
+
+func (client *Client) DeleteSuspendSchedule(appName string) error {
+	query := `
+		mutation ($input: DeleteSuspendScheduleInput!) {
+			deleteSuspendSchedule(input: $input) {
+				app {
+					id
+				}
+			}
+		}
+	`
+
+	req := client.NewRequest(query)
+
+	req.Var("input", map[string]string{
+		"appId": appName,
+	})
+
+	_, err := client.Run(req)
+	return err
+}
+
+// SetAppLabels adds or changes labels and removes the labels with removeKeys, returning the app's labels
+func (client *Client) SetAppLabels(input SetAppLabelsInput) (map[string]string, error) {
+	query := `
+		mutation ($input: SetAppLabelsInput!) {
+			setAppLabels(input: $input) {
+				app {
+					labels
+				}
+			}
+		}
+	`
+
+	req := client.NewRequest(query)
+
+	req.Var("input", input)
+
+	data, err := client.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.SetAppLabels.App.Labels, nil
+}
@@ -9,6 +9,16 @@ func (c *Client) GetAppCertificates(appName string) ([]AppCertificateCompact, er
 						createdAt
 						hostname
 						clientStatus
+						issued {
+							nodes {
+								type
+								expiresAt
+							}
+						}
+						validationErrors {
+							message
+							timestamp
+						}
 					}
 				}
 			}
@@ -53,6 +63,10 @@ func (c *Client) CheckAppCertificate(appName, hostname string) (*AppCertificate,
 							expiresAt
 						}
 					}
+					validationErrors {
+						message
+						timestamp
+					}
 				}
 				check {
 					aRecords
@@ -108,6 +122,10 @@ func (c *Client) AddCertificate(appName, hostname string) (*AppCertificate, *Hos
 							expiresAt
 						}
 					}
+					validationErrors {
+						message
+						timestamp
+					}
 				}
 				check {
 					aRecords
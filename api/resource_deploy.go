@@ -10,6 +10,11 @@ func (client *Client) DeployImage(input DeployImageInput) (*Release, error) {
 						reason
 						description
 						deploymentStrategy
+						approvalStatus
+						releaseCommand {
+							id
+							command
+						}
 						user {
 							id
 							email
@@ -48,6 +53,7 @@ func (c *Client) GetDeploymentStatus(appName string, deploymentID string) (*Depl
 					placedCount
 					healthyCount
 					unhealthyCount
+					rolloutRegion
 					allocations {
 						id
 						idShort
@@ -55,14 +61,29 @@ func (c *Client) GetDeploymentStatus(appName string, deploymentID string) (*Depl
 						region
 						desiredStatus
 						version
+						privateIP
 						healthy
             			failed
 						canary
 						restarts
+						passingCheckCount
+						warningCheckCount
+						criticalCheckCount
 						checks {
 							status
 							serviceName
 						}
+						warmup {
+							status
+							completed
+							total
+							error
+						}
+						draining {
+							activeConnections
+							startedAt
+							killTimeout
+						}
 					}
 				}
 			}
@@ -81,3 +102,57 @@ func (c *Client) GetDeploymentStatus(appName string, deploymentID string) (*Depl
 
 	return data.App.DeploymentStatus, nil
 }
+
+// PromoteDeployment continues a deployment held at its canary instance, replacing the rest of the instances
+func (c *Client) PromoteDeployment(deploymentID string) (*DeploymentStatus, error) {
+	query := `
+		mutation ($input: PromoteDeploymentInput!) {
+			promoteDeployment(input: $input) {
+				deployment {
+					id
+					status
+					inProgress
+					version
+				}
+			}
+		}
+	`
+
+	req := c.NewRequest(query)
+
+	req.Var("input", map[string]string{"deploymentId": deploymentID})
+
+	data, err := c.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data.PromoteDeployment.Deployment, nil
+}
+
+// AbortDeployment stops a deployment and rolls the app back to its last stable release
+func (c *Client) AbortDeployment(deploymentID string, reason string) (*DeploymentStatus, error) {
+	query := `
+		mutation ($input: AbortDeploymentInput!) {
+			abortDeployment(input: $input) {
+				deployment {
+					id
+					status
+					inProgress
+					version
+				}
+			}
+		}
+	`
+
+	req := c.NewRequest(query)
+
+	req.Var("input", map[string]string{"deploymentId": deploymentID, "reason": reason})
+
+	data, err := c.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data.AbortDeployment.Deployment, nil
+}
@@ -0,0 +1,110 @@
+package api
+
+// AcquireDeployLock takes an app's deploy lock for holder until it expires after ttl seconds. When
+// the lock is already held by someone else, it returns that lock as held instead.
+func (c *Client) AcquireDeployLock(appName string, holder string, ttl int) (lock *DeployLock, held *DeployLock, err error) {
+	query := `
+		mutation ($input: AcquireDeployLockInput!) {
+			acquireDeployLock(input: $input) {
+				lock {
+					id
+					holder
+					createdAt
+					expiresAt
+				}
+				heldBy {
+					id
+					holder
+					createdAt
+					expiresAt
+				}
+			}
+		}
+	`
+
+	req := c.NewRequest(query)
+
+	req.Var("input", map[string]interface{}{"appId": appName, "holder": holder, "ttl": ttl})
+
+	data, err := c.Run(req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return data.AcquireDeployLock.Lock, data.AcquireDeployLock.HeldBy, nil
+}
+
+// RefreshDeployLock extends a deploy lock to expire ttl seconds from now
+func (c *Client) RefreshDeployLock(lockID string, ttl int) (*DeployLock, error) {
+	query := `
+		mutation ($input: RefreshDeployLockInput!) {
+			refreshDeployLock(input: $input) {
+				lock {
+					id
+					holder
+					createdAt
+					expiresAt
+				}
+			}
+		}
+	`
+
+	req := c.NewRequest(query)
+
+	req.Var("input", map[string]interface{}{"lockId": lockID, "ttl": ttl})
+
+	data, err := c.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.RefreshDeployLock.Lock, nil
+}
+
+// ReleaseDeployLock gives up a deploy lock
+func (c *Client) ReleaseDeployLock(lockID string) error {
+	query := `
+		mutation ($input: ReleaseDeployLockInput!) {
+			releaseDeployLock(input: $input) {
+				lock {
+					id
+				}
+			}
+		}
+	`
+
+	req := c.NewRequest(query)
+
+	req.Var("input", map[string]string{"lockId": lockID})
+
+	_, err := c.Run(req)
+	return err
+}
+
+// ForceReleaseDeployLock releases an app's deploy lock whoever holds it, returning the lock that was
+// released, or nil when there wasn't one
+func (c *Client) ForceReleaseDeployLock(appName string) (*DeployLock, error) {
+	query := `
+		mutation ($input: ForceReleaseDeployLockInput!) {
+			forceReleaseDeployLock(input: $input) {
+				lock {
+					id
+					holder
+					createdAt
+					expiresAt
+				}
+			}
+		}
+	`
+
+	req := c.NewRequest(query)
+
+	req.Var("input", map[string]string{"appId": appName})
+
+	data, err := c.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.ForceReleaseDeployLock.Lock, nil
+}
@@ -0,0 +1,81 @@
+package api
+
+func (c *Client) GetAppHostnames(appName string) ([]AppHostname, error) {
+	query := `
+		query($appName: String!) {
+			app(name: $appName) {
+				hostnames {
+					nodes {
+						id
+						hostname
+						internal
+						createdAt
+					}
+				}
+			}
+		}
+	`
+
+	req := c.NewRequest(query)
+
+	req.Var("appName", appName)
+
+	data, err := c.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.App.Hostnames.Nodes, nil
+}
+
+func (c *Client) AddHostname(appName, hostname string, internal bool) (*AppHostname, error) {
+	query := `
+		mutation($input: AddHostnameInput!) {
+			addHostname(input: $input) {
+				hostname {
+					id
+					hostname
+					internal
+					createdAt
+				}
+			}
+		}
+	`
+
+	req := c.NewRequest(query)
+
+	req.Var("input", map[string]interface{}{
+		"appId":    appName,
+		"hostname": hostname,
+		"internal": internal,
+	})
+
+	data, err := c.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data.AddHostname.Hostname, nil
+}
+
+func (c *Client) RemoveHostname(appName, hostname string) error {
+	query := `
+		mutation($input: RemoveHostnameInput!) {
+			removeHostname(input: $input) {
+				app {
+					name
+				}
+			}
+		}
+	`
+
+	req := c.NewRequest(query)
+
+	req.Var("input", map[string]string{
+		"appId":    appName,
+		"hostname": hostname,
+	})
+
+	_, err := c.Run(req)
+	return err
+}
@@ -1,5 +1,7 @@
 package api
 
+import "fmt"
+
 func (client *Client) CreatePostgresCluster(input CreatePostgresClusterInput) (*CreatePostgresClusterPayload, error) {
 	query := `
 		mutation($input: CreatePostgresClusterInput!) {
@@ -66,7 +68,7 @@ func (client *Client) AttachPostgresCluster(input AttachPostgresClusterInput) (*
 				}
 				environmentVariableName
 				connectionString
-				environmentVariableName
+				databaseUser
 			}
 		}
 		`
@@ -82,7 +84,7 @@ func (client *Client) AttachPostgresCluster(input AttachPostgresClusterInput) (*
 	return data.AttachPostgresCluster, nil
 }
 
-func (client *Client) DetachPostgresCluster(postgresAppName string, appName string) error {
+func (client *Client) DetachPostgresCluster(input DetachPostgresClusterInput) error {
 	query := `
 		mutation($input: DetachPostgresClusterInput!) {
 			detachPostgresCluster(input: $input) {
@@ -92,10 +94,7 @@ func (client *Client) DetachPostgresCluster(postgresAppName string, appName stri
 		`
 
 	req := client.NewRequest(query)
-	req.Var("input", map[string]string{
-		"postgresClusterAppId": postgresAppName,
-		"appId":                appName,
-	})
+	req.Var("input", input)
 
 	_, err := client.Run(req)
 	return err
@@ -158,57 +157,378 @@ func (client *Client) ListPostgresUsers(appName string) ([]PostgresClusterUser,
 	return *data.App.PostgresAppRole.Users, nil
 }
 
-// func (client *Client) CreatePostgresDatabase(name string) (*PostgresClusterUser, error) {
-// 	query := `
-// 		mutation($input: CreatePostgresClusterUserInput!) {
-// 			createPostgresClusterUser(input: $input) {
-// 				user {
-// 					username
-// 				}
-// 			}
-// 		}
-// 		`
-
-// 	req := client.NewRequest(query)
-// 	req.Var("input", map[string]interface{}{
-// 		"username":  username,
-// 		"password":  password,
-// 		"superuser": superuser,
-// 	})
-
-// 	data, err := client.Run(req)
-// 	if err != nil {
-// 		return nil, err
-// 	}
-
-// 	return *data.App.PostgresAppRole.Users, nil
-// }
-
-// func (client *Client) CreatePostgresDatabase(database string) (PostgresClusterDatabase, error) {
-// 	query := `
-// 		mutation($appName: String!) {
-// 			app(name: $appName) {
-// 				postgresAppRole: role {
-// 					name
-// 					... on PostgresClusterAppRole {
-// 						users {
-// 							username
-// 							isSuperuser
-// 							databases
-// 						}
-// 					}
-// 				}
-// 			}
-// 		}
-// 		`
-
-// 	req := client.NewRequest(query)
-// 	req.Var("appName", appName)
-
-// 	data, err := client.Run(req)
-// 	if err != nil {
-// 		return nil, err
-// 	}
-
-// 	return *data.App.PostgresAppRole.Users, nil
-// }
+// GetPostgresVersion returns a postgres cluster's major version
+func (client *Client) GetPostgresVersion(appName string) (int, error) {
+	query := `
+		query($appName: String!) {
+			app(name: $appName) {
+				postgresAppRole: role {
+					name
+					... on PostgresClusterAppRole {
+						version
+					}
+				}
+			}
+		}
+		`
+
+	req := client.NewRequest(query)
+	req.Var("appName", appName)
+
+	data, err := client.Run(req)
+	if err != nil {
+		return 0, err
+	}
+
+	if data.App.PostgresAppRole == nil || data.App.PostgresAppRole.Version == 0 {
+		return 0, fmt.Errorf("%s is not a postgres cluster", appName)
+	}
+
+	return data.App.PostgresAppRole.Version, nil
+}
+
+// GetPostgresMetrics returns connection, replication, disk and slow query figures for each of a
+// postgres cluster's instances
+func (client *Client) GetPostgresMetrics(appName string) ([]PostgresNodeMetrics, error) {
+	query := `
+		query($appName: String!) {
+			app(name: $appName) {
+				postgresAppRole: role {
+					name
+					... on PostgresClusterAppRole {
+						metrics {
+							nodes {
+								allocationId
+								region
+								role
+								healthy
+								connections
+								maxConnections
+								replicationLagBytes
+								diskUsedBytes
+								diskTotalBytes
+								slowQueries
+							}
+						}
+					}
+				}
+			}
+		}
+		`
+
+	req := client.NewRequest(query)
+	req.Var("appName", appName)
+
+	data, err := client.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if data.App.PostgresAppRole == nil || data.App.PostgresAppRole.Metrics == nil {
+		return nil, fmt.Errorf("%s is not a postgres cluster", appName)
+	}
+
+	return data.App.PostgresAppRole.Metrics.Nodes, nil
+}
+
+// ListPostgresBackups returns a postgres cluster's backups, newest first
+func (client *Client) ListPostgresBackups(appName string) ([]PostgresBackup, error) {
+	query := `
+		query($appName: String!) {
+			app(name: $appName) {
+				postgresAppRole: role {
+					name
+					... on PostgresClusterAppRole {
+						backups {
+							nodes {
+								id
+								status
+								scheduled
+								sizeBytes
+								createdAt
+								completedAt
+							}
+						}
+					}
+				}
+			}
+		}
+		`
+
+	req := client.NewRequest(query)
+	req.Var("appName", appName)
+
+	data, err := client.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if data.App.PostgresAppRole == nil || data.App.PostgresAppRole.Backups == nil {
+		return nil, fmt.Errorf("%s is not a postgres cluster", appName)
+	}
+
+	return data.App.PostgresAppRole.Backups.Nodes, nil
+}
+
+// CreatePostgresBackup starts a backup of a postgres cluster
+func (client *Client) CreatePostgresBackup(appName string) (*PostgresBackup, error) {
+	query := `
+		mutation($input: CreatePostgresBackupInput!) {
+			createPostgresBackup(input: $input) {
+				backup {
+					id
+					status
+					scheduled
+					sizeBytes
+					createdAt
+					completedAt
+				}
+			}
+		}
+		`
+
+	req := client.NewRequest(query)
+	req.Var("input", map[string]string{"appId": appName})
+
+	data, err := client.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data.CreatePostgresBackup.Backup, nil
+}
+
+// RestorePostgresBackup replaces a postgres cluster's data with a backup's, in a release that
+// restarts the cluster
+func (client *Client) RestorePostgresBackup(appName string, backupID string) (*Release, error) {
+	query := `
+		mutation($input: RestorePostgresBackupInput!) {
+			restorePostgresBackup(input: $input) {
+				release {
+					id
+					version
+					reason
+					description
+					createdAt
+				}
+			}
+		}
+		`
+
+	req := client.NewRequest(query)
+	req.Var("input", map[string]string{"appId": appName, "backupId": backupID})
+
+	data, err := client.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data.RestorePostgresBackup.Release, nil
+}
+
+// CreatePostgresDatabase creates a database in a postgres cluster
+func (client *Client) CreatePostgresDatabase(appName string, name string) (*PostgresClusterDatabase, error) {
+	query := `
+		mutation($input: CreatePostgresClusterDatabaseInput!) {
+			createPostgresClusterDatabase(input: $input) {
+				database {
+					name
+					users
+				}
+			}
+		}
+		`
+
+	req := client.NewRequest(query)
+	req.Var("input", map[string]string{
+		"appName":      appName,
+		"databaseName": name,
+	})
+
+	data, err := client.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data.CreatePostgresClusterDatabase.Database, nil
+}
+
+// DeletePostgresDatabase drops a database from a postgres cluster
+func (client *Client) DeletePostgresDatabase(appName string, name string) error {
+	query := `
+		mutation($input: DeletePostgresClusterDatabaseInput!) {
+			deletePostgresClusterDatabase(input: $input) {
+				clientMutationId
+			}
+		}
+		`
+
+	req := client.NewRequest(query)
+	req.Var("input", map[string]string{
+		"appName":      appName,
+		"databaseName": name,
+	})
+
+	_, err := client.Run(req)
+	return err
+}
+
+// CreatePostgresUser creates a user in a postgres cluster
+func (client *Client) CreatePostgresUser(appName string, username string, password string, superuser bool) (*PostgresClusterUser, error) {
+	query := `
+		mutation($input: CreatePostgresClusterUserInput!) {
+			createPostgresClusterUser(input: $input) {
+				user {
+					username
+					isSuperuser
+					databases
+				}
+			}
+		}
+		`
+
+	req := client.NewRequest(query)
+	req.Var("input", map[string]interface{}{
+		"appName":   appName,
+		"username":  username,
+		"password":  password,
+		"superuser": superuser,
+	})
+
+	data, err := client.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data.CreatePostgresClusterUser.User, nil
+}
+
+// GrantPostgresAccess gives a user access to a database, read only or read and write
+func (client *Client) GrantPostgresAccess(appName string, username string, database string, readOnly bool) (*PostgresClusterUser, error) {
+	query := `
+		mutation($input: GrantPostgresClusterUserAccessInput!) {
+			grantPostgresClusterUserAccess(input: $input) {
+				user {
+					username
+					isSuperuser
+					databases
+				}
+			}
+		}
+		`
+
+	req := client.NewRequest(query)
+	req.Var("input", map[string]interface{}{
+		"appName":      appName,
+		"username":     username,
+		"databaseName": database,
+		"readOnly":     readOnly,
+	})
+
+	data, err := client.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data.GrantPostgresClusterUserAccess.User, nil
+}
+
+// ImportPostgresDatabase starts copying an external database into a postgres cluster
+func (client *Client) ImportPostgresDatabase(input ImportPostgresDatabaseInput) (*PostgresImport, error) {
+	query := `
+		mutation($input: ImportPostgresDatabaseInput!) {
+			importPostgresDatabase(input: $input) {
+				import {
+					id
+					status
+					inProgress
+					database
+					bytesTransferred
+					error
+					createdAt
+					updatedAt
+				}
+			}
+		}
+		`
+
+	req := client.NewRequest(query)
+	req.Var("input", input)
+
+	data, err := client.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data.ImportPostgresDatabase.Import, nil
+}
+
+// GetPostgresImport returns the progress of an import
+func (client *Client) GetPostgresImport(importID string) (*PostgresImport, error) {
+	query := `
+		query($id: ID!) {
+			postgresImport: node(id: $id) {
+				... on PostgresImport {
+					id
+					status
+					inProgress
+					database
+					bytesTransferred
+					error
+					createdAt
+					updatedAt
+				}
+			}
+		}
+		`
+
+	req := client.NewRequest(query)
+	req.Var("id", importID)
+
+	data, err := client.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if data.PostgresImport == nil {
+		return nil, ErrNotFound
+	}
+
+	return data.PostgresImport, nil
+}
+
+// ListPostgresAttachments returns the attachments an app is part of, as the app using a database or
+// as the postgres cluster holding it
+func (client *Client) ListPostgresAttachments(appName string) ([]PostgresAttachment, error) {
+	query := `
+		query($appName: String!) {
+			app(name: $appName) {
+				postgresAttachments {
+					nodes {
+						id
+						databaseName
+						databaseUser
+						environmentVariableName
+						replica
+						app {
+							name
+						}
+						postgresClusterApp {
+							name
+						}
+					}
+				}
+			}
+		}
+		`
+
+	req := client.NewRequest(query)
+	req.Var("appName", appName)
+
+	data, err := client.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.App.PostgresAttachments.Nodes, nil
+}
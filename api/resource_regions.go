@@ -55,3 +55,30 @@ func (c *Client) ListAppRegions(appName string) ([]Region, []Region, error) {
 
 	return *data.App.Regions, *data.App.BackupRegions, nil
 }
+
+func (c *Client) ListProcessGroupRegions(appName string) ([]ProcessGroup, error) {
+	query := `
+		query ($appName: String!) {
+			app(name: $appName) {
+				processGroups {
+					name
+					regions {
+						code
+						name
+					}
+				}
+			}
+		}
+	`
+
+	req := c.NewRequest(query)
+
+	req.Var("appName", appName)
+
+	data, err := c.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.App.ProcessGroups, nil
+}
@@ -0,0 +1,58 @@
+package api
+
+// GetRegistryRetentionPolicy returns the organization's registry retention policy, or nil when
+// deployment images are kept forever
+func (c *Client) GetRegistryRetentionPolicy(slug string) (*RegistryRetentionPolicy, error) {
+	query := `
+		query($slug: String!) {
+			organization(slug: $slug) {
+				registryRetentionPolicy {
+					keepDeployments
+					updatedAt
+				}
+			}
+		}
+	`
+
+	req := c.NewRequest(query)
+
+	req.Var("slug", slug)
+
+	data, err := c.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.Organization.RegistryRetentionPolicy, nil
+}
+
+// SetRegistryRetentionPolicy keeps the newest keep deployment images of each of the organization's
+// apps. A keep of 0 removes the policy so images are kept forever.
+func (c *Client) SetRegistryRetentionPolicy(orgID string, keep int) (*RegistryRetentionPolicy, error) {
+	query := `
+		mutation($input: SetRegistryRetentionPolicyInput!) {
+			setRegistryRetentionPolicy(input: $input) {
+				organization {
+					registryRetentionPolicy {
+						keepDeployments
+						updatedAt
+					}
+				}
+			}
+		}
+	`
+
+	req := c.NewRequest(query)
+
+	req.Var("input", map[string]interface{}{
+		"organizationId":  orgID,
+		"keepDeployments": keep,
+	})
+
+	data, err := c.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.SetRegistryRetentionPolicy.Organization.RegistryRetentionPolicy, nil
+}
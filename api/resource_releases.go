@@ -13,6 +13,7 @@ func (c *Client) GetAppReleases(appName string, limit int) ([]Release, error) {
 						reason
 						status
 						stable
+						imageRef
 						user {
 							id
 							email
@@ -37,3 +38,222 @@ func (c *Client) GetAppReleases(appName string, limit int) ([]Release, error) {
 
 	return data.App.Releases.Nodes, nil
 }
+
+func (c *Client) GetAppRelease(appName string, version int) (*Release, error) {
+	query := `
+		query ($appName: String!, $version: Int!) {
+			app(name: $appName) {
+				release(version: $version) {
+					id
+					version
+					reason
+					description
+					status
+					stable
+					imageRef
+					approvalStatus
+					user {
+						id
+						email
+						name
+					}
+					createdAt
+				}
+			}
+		}
+	`
+
+	req := c.NewRequest(query)
+
+	req.Var("appName", appName)
+	req.Var("version", version)
+
+	data, err := c.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if data.App.Release == nil {
+		return nil, ErrNotFound
+	}
+
+	return data.App.Release, nil
+}
+
+// ApproveRelease approves a release that is pending approval, which starts its deployment
+func (c *Client) ApproveRelease(releaseID string) (*Release, error) {
+	query := `
+		mutation ($input: ApproveReleaseInput!) {
+			approveRelease(input: $input) {
+				release {
+					id
+					version
+					status
+					approvalStatus
+					deploymentStrategy
+					releaseCommand {
+						id
+						command
+					}
+					user {
+						id
+						email
+						name
+					}
+					createdAt
+				}
+			}
+		}
+	`
+
+	req := c.NewRequest(query)
+
+	req.Var("input", map[string]string{"releaseId": releaseID})
+
+	data, err := c.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data.ApproveRelease.Release, nil
+}
+
+// RejectRelease rejects a release that is pending approval, so it's never deployed
+func (c *Client) RejectRelease(releaseID string, reason string) (*Release, error) {
+	query := `
+		mutation ($input: RejectReleaseInput!) {
+			rejectRelease(input: $input) {
+				release {
+					id
+					version
+					status
+					approvalStatus
+					createdAt
+				}
+			}
+		}
+	`
+
+	req := c.NewRequest(query)
+
+	req.Var("input", map[string]string{"releaseId": releaseID, "reason": reason})
+
+	data, err := c.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data.RejectRelease.Release, nil
+}
+
+// GetReleaseConfig returns the configuration a release was deployed with
+func (c *Client) GetReleaseConfig(appName string, version int) (*AppConfig, error) {
+	query := `
+		query ($appName: String!, $version: Int!) {
+			app(name: $appName) {
+				release(version: $version) {
+					id
+					version
+					config {
+						definition
+					}
+				}
+			}
+		}
+	`
+
+	req := c.NewRequest(query)
+
+	req.Var("appName", appName)
+	req.Var("version", version)
+
+	data, err := c.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if data.App.Release == nil {
+		return nil, ErrNotFound
+	}
+	if data.App.Release.Config == nil {
+		return &AppConfig{}, nil
+	}
+
+	return data.App.Release.Config, nil
+}
+
+// GetReleaseSecrets returns the names and digests of the secrets a release was deployed with
+func (c *Client) GetReleaseSecrets(appName string, version int) ([]Secret, error) {
+	query := `
+		query ($appName: String!, $version: Int!) {
+			app(name: $appName) {
+				release(version: $version) {
+					id
+					version
+					secrets {
+						name
+						digest
+						createdAt
+					}
+				}
+			}
+		}
+	`
+
+	req := c.NewRequest(query)
+
+	req.Var("appName", appName)
+	req.Var("version", version)
+
+	data, err := c.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if data.App.Release == nil {
+		return nil, ErrNotFound
+	}
+
+	return data.App.Release.Secrets, nil
+}
+
+// GetReleaseCommand returns the status of a release's release_command
+func (c *Client) GetReleaseCommand(appName string, version int) (*ReleaseCommand, error) {
+	query := `
+		query ($appName: String!, $version: Int!) {
+			app(name: $appName) {
+				release(version: $version) {
+					id
+					version
+					releaseCommand {
+						id
+						command
+						status
+						inProgress
+						succeeded
+						exitCode
+						instanceId
+						createdAt
+						updatedAt
+					}
+				}
+			}
+		}
+	`
+
+	req := c.NewRequest(query)
+
+	req.Var("appName", appName)
+	req.Var("version", version)
+
+	data, err := c.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if data.App.Release == nil || data.App.Release.ReleaseCommand == nil {
+		return nil, ErrNotFound
+	}
+
+	return data.App.Release.ReleaseCommand, nil
+}
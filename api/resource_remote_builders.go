@@ -1,12 +1,16 @@
 package api
 
-func (client *Client) EnsureRemoteBuilder(appName string) (string, *App, error) {
+func (client *Client) EnsureRemoteBuilder(input EnsureRemoteBuilderInput) (string, *App, error) {
 	query := `
 		mutation($input: EnsureRemoteBuilderInput!) {
 			ensureRemoteBuilder(input: $input) {
 				url,
 				app {
 					name
+					organization {
+						id
+						slug
+					}
 				}
 			}
 		}
@@ -14,9 +18,7 @@ func (client *Client) EnsureRemoteBuilder(appName string) (string, *App, error)
 
 	req := client.NewRequest(query)
 
-	req.Var("input", EnsureRemoteBuilderInput{
-		AppName: appName,
-	})
+	req.Var("input", input)
 
 	data, err := client.Run(req)
 	if err != nil {
@@ -25,3 +27,36 @@ func (client *Client) EnsureRemoteBuilder(appName string) (string, *App, error)
 
 	return data.EnsureRemoteBuilder.URL, data.EnsureRemoteBuilder.App, nil
 }
+
+// ListRemoteBuilders returns the remote builders of the organization with slug
+func (client *Client) ListRemoteBuilders(slug string) ([]RemoteBuilder, error) {
+	query := `
+		query($slug: String!) {
+			organization(slug: $slug) {
+				remoteBuilders {
+					nodes {
+						name
+						default
+						createdAt
+						app {
+							name
+							status
+							deployed
+						}
+					}
+				}
+			}
+		}
+	`
+
+	req := client.NewRequest(query)
+
+	req.Var("slug", slug)
+
+	data, err := client.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.Organization.RemoteBuilders.Nodes, nil
+}
@@ -180,6 +180,11 @@ func (c *Client) GetAppVMCount(appID string) ([]TaskGroupCount, error) {
 }
 
 func (c *Client) SetAppVMCount(appID string, count int) ([]TaskGroupCount, []string, error) {
+	return c.SetAppVMCounts(appID, []VMCountInput{{Group: "app", Count: count}})
+}
+
+// SetAppVMCounts scales each process group to its count
+func (c *Client) SetAppVMCounts(appID string, counts []VMCountInput) ([]TaskGroupCount, []string, error) {
 	query := `
 		mutation ($input: SetVMCountInput!) {
 			setVmCount(input: $input) {
@@ -197,10 +202,9 @@ func (c *Client) SetAppVMCount(appID string, count int) ([]TaskGroupCount, []str
 	req := c.NewRequest(query)
 
 	req.Var("input", SetVMCountInput{
-		AppID: appID,
-		GroupCounts: []VMCountInput{
-			{Group: "app", Count: count},
-		}})
+		AppID:       appID,
+		GroupCounts: counts,
+	})
 
 	data, err := c.Run(req)
 	if err != nil {
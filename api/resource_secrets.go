@@ -1,6 +1,12 @@
 package api
 
 func (c *Client) SetSecrets(appName string, secrets map[string]string) (*Release, error) {
+	return c.SetProcessSecrets(appName, nil, secrets)
+}
+
+// SetProcessSecrets sets secrets injected only into instances of the given process groups, or
+// into every group when there are none
+func (c *Client) SetProcessSecrets(appName string, processGroups []string, secrets map[string]string) (*Release, error) {
 	query := `
 		mutation($input: SetSecretsInput!) {
 			setSecrets(input: $input) {
@@ -20,7 +26,7 @@ func (c *Client) SetSecrets(appName string, secrets map[string]string) (*Release
 		}
 	`
 
-	input := SetSecretsInput{AppID: appName}
+	input := SetSecretsInput{AppID: appName, ProcessGroups: processGroups}
 	for k, v := range secrets {
 		input.Secrets = append(input.Secrets, SetSecretsInputSecret{Key: k, Value: v})
 	}
@@ -77,6 +83,8 @@ func (c *Client) GetAppSecrets(appName string) ([]Secret, error) {
 					name
 					digest
 					createdAt
+					staged
+					processGroups
 				}
 			}
 		}
@@ -93,3 +101,120 @@ func (c *Client) GetAppSecrets(appName string) ([]Secret, error) {
 
 	return data.App.Secrets, nil
 }
+
+// StageSecrets records secrets without creating a release, they take effect with the next deploy
+// or DeploySecrets
+func (c *Client) StageSecrets(appName string, secrets map[string]string) error {
+	return c.StageProcessSecrets(appName, nil, secrets)
+}
+
+// StageProcessSecrets records secrets scoped to the given process groups without creating a release
+func (c *Client) StageProcessSecrets(appName string, processGroups []string, secrets map[string]string) error {
+	query := `
+		mutation($input: SetSecretsInput!) {
+			setSecrets(input: $input) {
+				app {
+					id
+				}
+			}
+		}
+	`
+
+	input := SetSecretsInput{AppID: appName, Stage: true, ProcessGroups: processGroups}
+	for k, v := range secrets {
+		input.Secrets = append(input.Secrets, SetSecretsInputSecret{Key: k, Value: v})
+	}
+
+	req := c.NewRequest(query)
+
+	req.Var("input", input)
+
+	_, err := c.Run(req)
+	return err
+}
+
+// StageUnsetSecrets removes secrets without creating a release
+func (c *Client) StageUnsetSecrets(appName string, keys []string) error {
+	query := `
+		mutation($input: UnsetSecretsInput!) {
+			unsetSecrets(input: $input) {
+				app {
+					id
+				}
+			}
+		}
+	`
+
+	req := c.NewRequest(query)
+
+	req.Var("input", UnsetSecretsInput{AppID: appName, Keys: keys, Stage: true})
+
+	_, err := c.Run(req)
+	return err
+}
+
+// DeploySecrets creates a release with the app's staged secrets
+func (c *Client) DeploySecrets(appName string) (*Release, error) {
+	query := `
+		mutation($input: DeploySecretsInput!) {
+			deploySecrets(input: $input) {
+				release {
+					id
+					version
+					reason
+					description
+					user {
+						id
+						email
+						name
+					}
+					createdAt
+				}
+			}
+		}
+	`
+
+	req := c.NewRequest(query)
+
+	req.Var("input", map[string]string{"appId": appName})
+
+	data, err := c.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data.DeploySecrets.Release, nil
+}
+
+// CopySecrets sets secrets on toApp to the values they have on fromApp, so they're copied without
+// being read
+func (c *Client) CopySecrets(fromApp string, toApp string, keys []string) (*Release, error) {
+	query := `
+		mutation($input: CopySecretsInput!) {
+			copySecrets(input: $input) {
+				release {
+					id
+					version
+					reason
+					description
+					createdAt
+				}
+			}
+		}
+	`
+
+	req := c.NewRequest(query)
+
+	req.Var("input", CopySecretsInput{
+		SourceAppID: fromApp,
+		AppID:       toApp,
+		Keys:        keys,
+	})
+
+	data, err := c.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data.CopySecrets.Release, nil
+}
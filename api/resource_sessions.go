@@ -0,0 +1,78 @@
+package api
+
+// ListSessions returns the sessions of the current user, most recently used first
+func (c *Client) ListSessions() ([]Session, error) {
+	query := `
+		query {
+			currentUser {
+				sessions {
+					nodes {
+						id
+						name
+						client
+						current
+						createdAt
+						lastUsedAt
+						lastUsedIp
+					}
+				}
+			}
+		}
+	`
+
+	req := c.NewRequest(query)
+
+	data, err := c.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.CurrentUser.Sessions.Nodes, nil
+}
+
+// RevokeSession revokes a session's token, logging out the machine that uses it
+func (c *Client) RevokeSession(sessionID string) (*Session, error) {
+	query := `
+		mutation ($input: RevokeSessionInput!) {
+			revokeSession(input: $input) {
+				session {
+					id
+					name
+					client
+				}
+			}
+		}
+	`
+
+	req := c.NewRequest(query)
+
+	req.Var("input", map[string]string{"sessionId": sessionID})
+
+	data, err := c.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data.RevokeSession.Session, nil
+}
+
+// RevokeAllSessions revokes every session of the current user, including the one making the
+// request, and returns how many were revoked
+func (c *Client) RevokeAllSessions() (int, error) {
+	query := `
+		mutation {
+			revokeAllSessions(input: {}) {
+				revokedCount
+			}
+		}
+	`
+
+	req := c.NewRequest(query)
+
+	data, err := c.Run(req)
+	if err != nil {
+		return 0, err
+	}
+
+	return data.RevokeAllSessions.RevokedCount, nil
+}
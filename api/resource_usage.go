@@ -0,0 +1,39 @@
+package api
+
+// GetAppUsage fetches what an app has used in the current billing period and its estimated cost
+func (c *Client) GetAppUsage(appName string) (*AppUsage, error) {
+	query := `
+		query ($appName: String!) {
+			app(name: $appName) {
+				usage {
+					periodStart
+					periodEnd
+					vmHours
+					vmCostCents
+					bandwidthGb
+					bandwidthCostCents
+					volumeGb
+					volumeCostCents
+					totalCostCents
+				}
+			}
+		}
+	`
+
+	req := c.NewRequest(query)
+
+	req.Var("appName", appName)
+
+	data, err := c.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := AppUsage{}
+	if data.App.Usage != nil {
+		usage = *data.App.Usage
+	}
+	usage.App = appName
+
+	return &usage, nil
+}
@@ -13,7 +13,16 @@ func (c *Client) GetVolumes(appName string) ([]Volume, error) {
 					encrypted
 					createdAt
 					attachedAllocation {
+						id
 						idShort
+						region
+						status
+					}
+					usage {
+						usedBytes
+						freeBytes
+						totalBytes
+						updatedAt
 					}
 				}
 			}
@@ -34,6 +43,16 @@ func (c *Client) GetVolumes(appName string) ([]Volume, error) {
 }
 
 func (c *Client) CreateVolume(appName string, volname string, region string, sizeGb int, encrypted bool) (*Volume, error) {
+	return c.createVolume(CreateVolumeInput{AppID: appName, Name: volname, Region: region, SizeGb: sizeGb, Encrypted: encrypted})
+}
+
+// RestoreVolumeSnapshot creates a volume from a snapshot, in any region. A zero sizeGb keeps the
+// snapshot's size.
+func (c *Client) RestoreVolumeSnapshot(appName string, snapshotID string, volname string, region string, sizeGb int) (*Volume, error) {
+	return c.createVolume(CreateVolumeInput{AppID: appName, Name: volname, Region: region, SizeGb: sizeGb, Encrypted: true, SnapshotID: snapshotID})
+}
+
+func (c *Client) createVolume(input CreateVolumeInput) (*Volume, error) {
 	query := `
 		mutation($input: CreateVolumeInput!) {
 			createVolume(input: $input) {
@@ -52,8 +71,6 @@ func (c *Client) CreateVolume(appName string, volname string, region string, siz
 		}
 	`
 
-	input := CreateVolumeInput{AppID: appName, Name: volname, Region: region, SizeGb: sizeGb, Encrypted: encrypted}
-
 	req := c.NewRequest(query)
 
 	req.Var("input", input)
@@ -102,6 +119,18 @@ func (c *Client) GetVolume(volID string) (Volume *Volume, err error) {
 				region
 				encrypted
 				createdAt
+				attachedAllocation {
+					id
+					idShort
+					region
+					status
+				}
+				usage {
+					usedBytes
+					freeBytes
+					totalBytes
+					updatedAt
+				}
 			}
 		}
 	}`
@@ -117,3 +146,292 @@ func (c *Client) GetVolume(volID string) (Volume *Volume, err error) {
 
 	return &data.Volume, nil
 }
+
+// CreateVolumeSnapshotDownload prepares a snapshot for download, returning a signed URL to its disk image
+func (c *Client) CreateVolumeSnapshotDownload(snapshotID string) (*VolumeSnapshotDownload, error) {
+	query := `
+		mutation($input: CreateVolumeSnapshotDownloadInput!) {
+			createVolumeSnapshotDownload(input: $input) {
+				download {
+					url
+					size
+					sha256
+					format
+					expiresAt
+				}
+			}
+		}
+	`
+
+	req := c.NewRequest(query)
+
+	req.Var("input", map[string]string{"snapshotId": snapshotID})
+
+	data, err := c.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data.CreateVolumeSnapshotDownload.Download, nil
+}
+
+// ExtendVolume grows a volume to sizeGb, resizing the filesystem of the instance it's attached to
+func (c *Client) ExtendVolume(volID string, sizeGb int) (*ExtendVolumePayload, error) {
+	query := `
+		mutation($input: ExtendVolumeInput!) {
+			extendVolume(input: $input) {
+				volume {
+					id
+					name
+					region
+					sizeGb
+					encrypted
+					createdAt
+					attachedAllocation {
+						idShort
+					}
+				}
+				needsRestart
+			}
+		}
+	`
+
+	req := c.NewRequest(query)
+
+	req.Var("input", map[string]interface{}{"volumeId": volID, "sizeGb": sizeGb})
+
+	data, err := c.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data.ExtendVolume, nil
+}
+
+// GetVolumeSnapshotSchedule returns a volume's automatic snapshot schedule, the platform's default
+// when it doesn't have its own
+func (c *Client) GetVolumeSnapshotSchedule(volID string) (*VolumeSnapshotSchedule, error) {
+	query := `
+	query($id: ID!) {
+		volume: node(id: $id) {
+			... on Volume {
+				snapshotSchedule {
+					frequency
+					retain
+					default
+					nextSnapshotAt
+				}
+			}
+		}
+	}`
+
+	req := c.NewRequest(query)
+
+	req.Var("id", volID)
+
+	data, err := c.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.Volume.SnapshotSchedule, nil
+}
+
+func (c *Client) SetVolumeSnapshotSchedule(input SetVolumeSnapshotScheduleInput) (*VolumeSnapshotSchedule, error) {
+	query := `
+		mutation($input: SetVolumeSnapshotScheduleInput!) {
+			setVolumeSnapshotSchedule(input: $input) {
+				volume {
+					snapshotSchedule {
+						frequency
+						retain
+						default
+						nextSnapshotAt
+					}
+				}
+			}
+		}
+	`
+
+	req := c.NewRequest(query)
+
+	req.Var("input", input)
+
+	data, err := c.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.SetVolumeSnapshotSchedule.Volume.SnapshotSchedule, nil
+}
+
+// GetVolumeSnapshots lists a volume's snapshots, newest first
+func (c *Client) GetVolumeSnapshots(volID string) ([]VolumeSnapshot, error) {
+	query := `
+	query($id: ID!) {
+		volume: node(id: $id) {
+			... on Volume {
+				snapshots {
+					nodes {
+						id
+						size
+						digest
+						createdAt
+					}
+				}
+			}
+		}
+	}`
+
+	req := c.NewRequest(query)
+
+	req.Var("id", volID)
+
+	data, err := c.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return data.Volume.Snapshots.Nodes, nil
+}
+
+// CreateVolumeSnapshot takes a snapshot of a volume's current contents
+func (c *Client) CreateVolumeSnapshot(volID string) (*VolumeSnapshot, error) {
+	query := `
+		mutation($input: CreateVolumeSnapshotInput!) {
+			createVolumeSnapshot(input: $input) {
+				snapshot {
+					id
+					size
+					digest
+					createdAt
+				}
+			}
+		}
+	`
+
+	req := c.NewRequest(query)
+
+	req.Var("input", map[string]string{"volumeId": volID})
+
+	data, err := c.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data.CreateVolumeSnapshot.Snapshot, nil
+}
+
+// ExportVolume starts streaming an archive of a volume to object storage
+func (c *Client) ExportVolume(input ExportVolumeInput) (*VolumeTransfer, error) {
+	query := `
+		mutation($input: ExportVolumeInput!) {
+			exportVolume(input: $input) {
+				transfer {
+					id
+					status
+					inProgress
+					url
+					bytesTransferred
+					error
+					volume {
+						id
+						name
+						region
+						sizeGb
+					}
+					createdAt
+					updatedAt
+				}
+			}
+		}
+	`
+
+	req := c.NewRequest(query)
+
+	req.Var("input", input)
+
+	data, err := c.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data.ExportVolume.Transfer, nil
+}
+
+// ImportVolume starts restoring an archive from object storage into a new volume
+func (c *Client) ImportVolume(input ImportVolumeInput) (*VolumeTransfer, error) {
+	query := `
+		mutation($input: ImportVolumeInput!) {
+			importVolume(input: $input) {
+				transfer {
+					id
+					status
+					inProgress
+					url
+					bytesTransferred
+					error
+					volume {
+						id
+						name
+						region
+						sizeGb
+					}
+					createdAt
+					updatedAt
+				}
+			}
+		}
+	`
+
+	req := c.NewRequest(query)
+
+	req.Var("input", input)
+
+	data, err := c.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	return &data.ImportVolume.Transfer, nil
+}
+
+// GetVolumeTransfer returns the progress of an export or import
+func (c *Client) GetVolumeTransfer(transferID string) (*VolumeTransfer, error) {
+	query := `
+	query($id: ID!) {
+		volumeTransfer: node(id: $id) {
+			... on VolumeTransfer {
+				id
+				status
+				inProgress
+				url
+				bytesTransferred
+				error
+				volume {
+					id
+					name
+					region
+					sizeGb
+				}
+				createdAt
+				updatedAt
+			}
+		}
+	}`
+
+	req := c.NewRequest(query)
+
+	req.Var("id", transferID)
+
+	data, err := c.Run(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if data.VolumeTransfer == nil {
+		return nil, ErrNotFound
+	}
+
+	return data.VolumeTransfer, nil
+}
@@ -9,7 +9,8 @@ type Query struct {
 	Errors Errors
 
 	Apps struct {
-		Nodes []App
+		Nodes    []App
+		PageInfo PageInfo
 	}
 	App                  App
 	AppCompact           AppCompact
@@ -26,6 +27,8 @@ type Query struct {
 	OrganizationDetails OrganizationDetails
 	Build               Build
 	Volume              Volume
+	VolumeTransfer      *VolumeTransfer
+	PostgresImport      *PostgresImport
 	Domain              *Domain
 
 	Node  interface{}
@@ -49,6 +52,18 @@ type Query struct {
 		App App
 	}
 
+	RenameApp struct {
+		App App
+	}
+
+	SetSuspendSchedule struct {
+		App App
+	}
+
+	SetAppLabels struct {
+		App App
+	}
+
 	SetSecrets struct {
 		Release Release
 	}
@@ -57,10 +72,81 @@ type Query struct {
 		Release Release
 	}
 
+	CopySecrets struct {
+		Release Release
+	}
+
+	DeploySecrets struct {
+		Release Release
+	}
+
 	DeployImage struct {
 		Release Release
 	}
 
+	PromoteDeployment struct {
+		Deployment DeploymentStatus
+	}
+
+	AbortDeployment struct {
+		Deployment DeploymentStatus
+	}
+
+	AcquireDeployLock struct {
+		Lock   *DeployLock
+		HeldBy *DeployLock
+	}
+
+	RefreshDeployLock struct {
+		Lock *DeployLock
+	}
+
+	ForceReleaseDeployLock struct {
+		Lock *DeployLock
+	}
+
+	ApproveRelease struct {
+		Release Release
+	}
+
+	CreateVolumeSnapshotDownload struct {
+		Download VolumeSnapshotDownload
+	}
+
+	CreateVolumeSnapshot struct {
+		Snapshot VolumeSnapshot
+	}
+
+	ExtendVolume ExtendVolumePayload
+
+	SetVolumeSnapshotSchedule struct {
+		Volume Volume
+	}
+
+	ExportVolume struct {
+		Transfer VolumeTransfer
+	}
+
+	ImportVolume struct {
+		Transfer VolumeTransfer
+	}
+
+	RejectRelease struct {
+		Release Release
+	}
+
+	RevokeSession struct {
+		Session Session
+	}
+
+	RevokeAllSessions struct {
+		RevokedCount int
+	}
+
+	SetRegistryRetentionPolicy struct {
+		Organization Organization
+	}
+
 	EnsureRemoteBuilder *struct {
 		App     *App
 		URL     string
@@ -80,6 +166,10 @@ type Query struct {
 
 	DeleteCertificate DeleteCertificatePayload
 
+	AddHostname struct {
+		Hostname AppHostname
+	}
+
 	CheckCertificate struct {
 		App         *App
 		Certificate *AppCertificate
@@ -173,6 +263,24 @@ type Query struct {
 	}
 
 	CreatePostgresCluster *CreatePostgresClusterPayload
+	CreatePostgresBackup  struct {
+		Backup PostgresBackup
+	}
+	RestorePostgresBackup struct {
+		Release Release
+	}
+	CreatePostgresClusterDatabase struct {
+		Database PostgresClusterDatabase
+	}
+	CreatePostgresClusterUser struct {
+		User PostgresClusterUser
+	}
+	GrantPostgresClusterUserAccess struct {
+		User PostgresClusterUser
+	}
+	ImportPostgresDatabase struct {
+		Import PostgresImport
+	}
 
 	AttachPostgresCluster *AttachPostgresClusterPayload
 }
@@ -236,7 +344,10 @@ type App struct {
 	Certificates struct {
 		Nodes []AppCertificate
 	}
-	Certificate      AppCertificate
+	Certificate AppCertificate
+	Hostnames   struct {
+		Nodes []AppHostname
+	}
 	Config           AppConfig
 	ParseConfig      AppConfig
 	Allocations      []*AllocationStatus
@@ -250,14 +361,72 @@ type App struct {
 		Nodes []Volume
 	}
 	TaskGroupCounts []TaskGroupCount
+	ProcessGroups   []ProcessGroup
 	HealthChecks    *struct {
 		Nodes []CheckState
 	}
+	PostgresAttachments struct {
+		Nodes []PostgresAttachment
+	}
 	PostgresAppRole *struct {
 		Databases *[]PostgresClusterDatabase
 		Users     *[]PostgresClusterUser
+		Backups   *struct {
+			Nodes []PostgresBackup
+		}
+		// Version is the cluster's postgres major version
+		Version int
+		Metrics *struct {
+			Nodes []PostgresNodeMetrics
+		}
 	}
-	Image *Image
+	Image           *Image
+	Usage           *AppUsage
+	SuspendSchedule *SuspendSchedule
+	// Labels tag the app for filtering, like env=staging
+	Labels map[string]string
+}
+
+// SuspendSchedule suspends and resumes an app on cron schedules, like nights and weekends for a staging app
+type SuspendSchedule struct {
+	Suspend string `json:"suspend"`
+	// Resume is empty when the app stays suspended until it's resumed by hand
+	Resume   string `json:"resume,omitempty"`
+	Timezone string `json:"timezone"`
+}
+
+type SetAppLabelsInput struct {
+	AppID      string            `json:"appId"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	RemoveKeys []string          `json:"removeKeys,omitempty"`
+}
+
+type SetSuspendScheduleInput struct {
+	AppID    string `json:"appId"`
+	Suspend  string `json:"suspend"`
+	Resume   string `json:"resume,omitempty"`
+	Timezone string `json:"timezone"`
+}
+
+// AppUsage is what an app has used so far in the current billing period, costs are estimates in cents
+type AppUsage struct {
+	App                string
+	PeriodStart        time.Time
+	PeriodEnd          time.Time
+	VMHours            float64
+	VMCostCents        int
+	BandwidthGB        float64
+	BandwidthCostCents int
+	// VolumeGB is the average provisioned volume size over the period
+	VolumeGB        float64
+	VolumeCostCents int
+	TotalCostCents  int
+}
+
+// PageInfo is where a page of a paginated query ends
+type PageInfo struct {
+	HasNextPage bool
+	EndCursor   string
 }
 
 type TaskGroupCount struct {
@@ -265,6 +434,12 @@ type TaskGroupCount struct {
 	Count int
 }
 
+// ProcessGroup is a process group's region pin, it can run in any of the app's regions when Regions is empty
+type ProcessGroup struct {
+	Name    string
+	Regions []Region
+}
+
 type Volume struct {
 	ID                 string `json:"id"`
 	App                string
@@ -274,14 +449,68 @@ type Volume struct {
 	Encrypted          bool
 	CreatedAt          time.Time
 	AttachedAllocation *AllocationStatus
+	// Usage is the filesystem usage last reported by the attached instance, nil when the volume
+	// hasn't been mounted
+	Usage     *VolumeUsage
+	Snapshots struct {
+		Nodes []VolumeSnapshot
+	} `json:"-"`
+	SnapshotSchedule *VolumeSnapshotSchedule `json:"-"`
+}
+
+// VolumeUsage is the space used on a volume's filesystem
+type VolumeUsage struct {
+	UsedBytes  int64
+	FreeBytes  int64
+	TotalBytes int64
+	UpdatedAt  time.Time
+}
+
+// VolumeSnapshotSchedule is how often a volume is snapshotted automatically and how many of those
+// snapshots are kept
+type VolumeSnapshotSchedule struct {
+	// Frequency is hourly, daily, weekly or off
+	Frequency string `json:"frequency"`
+	Retain    int    `json:"retain"`
+	// Default is true when the volume uses the platform's schedule rather than its own
+	Default        bool       `json:"default"`
+	NextSnapshotAt *time.Time `json:"nextSnapshotAt,omitempty"`
+}
+
+// SetVolumeSnapshotScheduleInput replaces a volume's snapshot schedule. A zero Retain keeps the
+// current retention.
+type SetVolumeSnapshotScheduleInput struct {
+	VolumeID  string `json:"volumeId"`
+	Frequency string `json:"frequency"`
+	Retain    int    `json:"retain,omitempty"`
+}
+
+// VolumeSnapshot is a point-in-time copy of a volume, which new volumes can be created from
+type VolumeSnapshot struct {
+	ID string `json:"id"`
+	// Size is the snapshot's size in bytes
+	Size      int64
+	Digest    string
+	CreatedAt time.Time
+}
+
+// VolumeSnapshotDownload is a short lived link to a compressed disk image of a volume snapshot
+type VolumeSnapshotDownload struct {
+	URL       string
+	Size      int64
+	Sha256    string
+	Format    string
+	ExpiresAt time.Time
 }
 
 type CreateVolumeInput struct {
 	AppID     string `json:"appId"`
 	Name      string `json:"name"`
 	Region    string `json:"region"`
-	SizeGb    int    `json:"sizeGb"`
+	SizeGb    int    `json:"sizeGb,omitempty"`
 	Encrypted bool   `json:"encrypted"`
+	// SnapshotID restores the snapshot into the new volume, which defaults to the snapshot's size
+	SnapshotID string `json:"snapshotId,omitempty"`
 }
 
 type CreateVolumePayload struct {
@@ -289,6 +518,70 @@ type CreateVolumePayload struct {
 	Volume Volume
 }
 
+// VolumeTransfer is an export of a volume to object storage, or an import of one into a new
+// volume, streamed as a tar.gz archive by a temporary VM. Exports archive a snapshot taken when
+// they start, so the archive is consistent while the app keeps writing.
+type VolumeTransfer struct {
+	ID string `json:"id"`
+	// Status is pending, running, succeeded or failed
+	Status           string
+	InProgress       bool
+	URL              string
+	BytesTransferred int64
+	Error            string
+	// Volume is the exported volume, or the volume created by an import
+	Volume    *Volume
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// ObjectStorageInput is the location of a volume archive in S3 or an S3 compatible store. The
+// temporary VM reaches it through a presigned URL, so no credentials leave the user's machine.
+type ObjectStorageInput struct {
+	// URL is the archive's s3://<bucket>/<key> location, shown in the transfer's status
+	URL string `json:"url"`
+	// SignedURL is a presigned https URL to GET the archive, for imports
+	SignedURL string `json:"signedUrl,omitempty"`
+	// Multipart is the presigned multipart upload exports write the archive to, since a single
+	// PUT is limited to 5GB and needs the archive's length up front
+	Multipart *MultipartUploadInput `json:"multipart,omitempty"`
+}
+
+// MultipartUploadInput is an S3 multipart upload started locally, with presigned URLs for the
+// temporary VM to upload the archive's parts and finish it
+type MultipartUploadInput struct {
+	// PartSize is the size of every part but the last, which can be smaller
+	PartSize int64 `json:"partSize"`
+	// PartURLs are presigned URLs to PUT parts 1, 2, ... to. The VM uses as many as it needs.
+	PartURLs []string `json:"partUrls"`
+	// CompleteURL is a presigned URL to POST the CompleteMultipartUpload request listing the
+	// uploaded parts' ETags to
+	CompleteURL string `json:"completeUrl"`
+	// AbortURL is a presigned URL to DELETE the upload with when the export fails
+	AbortURL string `json:"abortUrl"`
+}
+
+type ExportVolumeInput struct {
+	VolumeID string             `json:"volumeId"`
+	Storage  ObjectStorageInput `json:"storage"`
+}
+
+type ImportVolumeInput struct {
+	AppID   string             `json:"appId"`
+	Name    string             `json:"name"`
+	Region  string             `json:"region"`
+	SizeGb  int                `json:"sizeGb,omitempty"`
+	Storage ObjectStorageInput `json:"storage"`
+}
+
+// ExtendVolumePayload is a volume after growing it
+type ExtendVolumePayload struct {
+	Volume Volume
+	// NeedsRestart is true when the attached instance couldn't grow its filesystem while
+	// running, and only sees the new size after a restart
+	NeedsRestart bool
+}
+
 type DeleteVolumeInput struct {
 	VolumeID string `json:"volumeId"`
 }
@@ -307,6 +600,10 @@ type AppCertificateCompact struct {
 	CreatedAt    time.Time
 	Hostname     string
 	ClientStatus string
+	Issued       struct {
+		Nodes []AppCertificateIssued
+	}
+	ValidationErrors []AppCertificateValidationError
 }
 
 type AppCompact struct {
@@ -367,6 +664,12 @@ type Organization struct {
 		}
 	}
 
+	RemoteBuilders struct {
+		Nodes []RemoteBuilder
+	}
+
+	RegistryRetentionPolicy *RegistryRetentionPolicy
+
 	DelegatedWireGuardTokens struct {
 		Nodes *[]*DelegatedWireGuardTokenHandle
 		Edges *[]*struct {
@@ -439,20 +742,51 @@ type IPAddress struct {
 }
 
 type User struct {
-	ID    string
-	Name  string
-	Email string
+	ID       string
+	Name     string
+	Email    string
+	Sessions struct {
+		Nodes []Session
+	}
+}
+
+// Session is an access token issued to a user when flyctl, or another client, logged in
+type Session struct {
+	ID string
+	// Name is the hostname of the machine that logged in
+	Name   string
+	Client string
+	// Current is true for the session flyctl is using
+	Current    bool
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+	LastUsedIP string
 }
 
 type Secret struct {
 	Name      string
 	Digest    string
 	CreatedAt time.Time
+	// Staged secrets are set or unset, but not released to the app yet
+	Staged bool
+	// ProcessGroups the secret is injected into, every group when empty
+	ProcessGroups []string
+}
+
+// CopySecretsInput copies secrets between apps by name
+type CopySecretsInput struct {
+	SourceAppID string   `json:"sourceAppId"`
+	AppID       string   `json:"appId"`
+	Keys        []string `json:"keys"`
 }
 
 type SetSecretsInput struct {
 	AppID   string                  `json:"appId"`
 	Secrets []SetSecretsInputSecret `json:"secrets"`
+	// Stage records the secrets without a release, they take effect with the next one
+	Stage bool `json:"stage,omitempty"`
+	// ProcessGroups scopes the secrets to instances of these groups, every group when empty
+	ProcessGroups []string `json:"processGroups,omitempty"`
 }
 
 type SetSecretsInputSecret struct {
@@ -463,6 +797,7 @@ type SetSecretsInputSecret struct {
 type UnsetSecretsInput struct {
 	AppID string   `json:"appId"`
 	Keys  []string `json:"keys"`
+	Stage bool     `json:"stage,omitempty"`
 }
 
 type CreateAppInput struct {
@@ -482,6 +817,8 @@ type LogEntry struct {
 		Instance string
 		Region   string
 	}
+	// App is set by flyctl when logs from several apps are shown together
+	App string `json:",omitempty"`
 }
 
 type Release struct {
@@ -493,8 +830,43 @@ type Release struct {
 	Description        string
 	Status             string
 	DeploymentStrategy string
-	User               User
-	CreatedAt          time.Time
+	ImageRef           string
+	// ApprovalStatus is pending, approved or rejected for releases that require approval, empty otherwise
+	ApprovalStatus string
+	User           User
+	CreatedAt      time.Time
+	// Config is the configuration the release was deployed with, only fetched by GetReleaseConfig
+	Config *AppConfig
+	// ReleaseCommand is set when the release runs a release_command before its instances are replaced
+	ReleaseCommand *ReleaseCommand
+	// Secrets are the secrets the release was deployed with, only fetched by GetReleaseSecrets
+	Secrets []Secret
+}
+
+// ReleaseCommand is the run of a [deploy] release_command in a one-off VM with the release's image.
+// The release's instances are only replaced once it succeeds.
+type ReleaseCommand struct {
+	ID         string
+	Command    string
+	Status     string
+	InProgress bool
+	Succeeded  bool
+	ExitCode   *int
+	// InstanceID is the one-off VM running the command, once it's been placed
+	InstanceID *string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+const (
+	ReleaseApprovalPending  = "pending"
+	ReleaseApprovalApproved = "approved"
+	ReleaseApprovalRejected = "rejected"
+)
+
+// PendingApproval returns true when the release has to be approved before it's deployed
+func (r *Release) PendingApproval() bool {
+	return r.ApprovalStatus == ReleaseApprovalPending
 }
 
 type Build struct {
@@ -526,6 +898,19 @@ type AppChange struct {
 	User        User
 }
 
+// DeployLock stops other deploys of an app while one runs. It expires unless it's refreshed, so a
+// deploy that's killed doesn't hold it forever.
+type DeployLock struct {
+	ID string
+	// Holder describes the deploy holding the lock, like the host and CI job running it
+	Holder    string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+// DeploymentAwaitingPromotion is the status of a deployment held until it's promoted or aborted
+const DeploymentAwaitingPromotion = "awaiting_promotion"
+
 type DeploymentStatus struct {
 	ID             string
 	Status         string
@@ -539,6 +924,22 @@ type DeploymentStatus struct {
 	PlacedCount    int
 	HealthyCount   int
 	UnhealthyCount int
+	// RolloutRegion is the region a staged rollout has reached, held there while it's awaiting promotion
+	RolloutRegion string
+}
+
+// AwaitingPromotion returns true when the deployment is held until it's promoted or aborted
+func (d *DeploymentStatus) AwaitingPromotion() bool {
+	return d.InProgress && d.Status == DeploymentAwaitingPromotion
+}
+
+// AppHostname is an additional name an app answers to. Internal hostnames only resolve
+// on the organization's private network.
+type AppHostname struct {
+	ID        string
+	Hostname  string
+	Internal  bool
+	CreatedAt time.Time
 }
 
 type AppCertificate struct {
@@ -558,11 +959,21 @@ type AppCertificate struct {
 	IsApex                    bool
 	IsWildcard                bool
 	Issued                    struct {
-		Nodes []struct {
-			ExpiresAt time.Time
-			Type      string
-		}
+		Nodes []AppCertificateIssued
 	}
+	// ValidationErrors are the failed attempts to validate the hostname, oldest first
+	ValidationErrors []AppCertificateValidationError
+}
+
+// AppCertificateIssued is an issued certificate, one for each key type
+type AppCertificateIssued struct {
+	ExpiresAt time.Time
+	Type      string
+}
+
+type AppCertificateValidationError struct {
+	Message   string
+	Timestamp time.Time
 }
 
 type CreateOrganizationPayload struct {
@@ -594,6 +1005,59 @@ type DeployImageInput struct {
 	Services   *[]Service  `json:"services"`
 	Definition *Definition `json:"definition"`
 	Strategy   *string     `json:"strategy"`
+	// RequireApproval holds the release until another organization member approves it
+	RequireApproval bool `json:"requireApproval,omitempty"`
+	// KillTimeout overrides how many seconds instances of the previous release drain connections before they're killed
+	KillTimeout *int `json:"killTimeout,omitempty"`
+	// ConnectionThreshold and MaxConnectionWait, in seconds, tune when the websocket strategy stops old instances
+	ConnectionThreshold *int `json:"connectionThreshold,omitempty"`
+	MaxConnectionWait   *int `json:"maxConnectionWait,omitempty"`
+	// HoldCanary stops a canary deployment once its canary instance is healthy, until it's promoted or aborted
+	HoldCanary bool `json:"holdCanary,omitempty"`
+	// HoldTraffic keeps a blue-green deployment's new instances out of load balancing once they're
+	// healthy, until it's promoted or aborted
+	HoldTraffic bool `json:"holdTraffic,omitempty"`
+	// HoldFirstInstance stops a deployment once its first new instance is healthy, until it's
+	// promoted or aborted
+	HoldFirstInstance bool `json:"holdFirstInstance,omitempty"`
+	// RolloutOrder deploys to one region at a time in this order, holding after each region until
+	// it's promoted or aborted. Regions that aren't listed are deployed to together last.
+	RolloutOrder []string `json:"rolloutOrder,omitempty"`
+	// OnlyRegions places the new release's instances in these regions only, leaving instances in
+	// the app's other regions on the release they're running
+	OnlyRegions []string `json:"onlyRegions,omitempty"`
+	// RegionOverrides tune the release's instances in particular regions
+	RegionOverrides []RegionOverrideInput `json:"regionOverrides,omitempty"`
+	// ProcessGroups sets up each process group of the release, creating new groups and removing
+	// those that aren't listed
+	ProcessGroups []ProcessGroupInput `json:"processGroups,omitempty"`
+}
+
+// ProcessGroupInput is a process group of a release
+type ProcessGroupInput struct {
+	Name    string `json:"name"`
+	Command string `json:"command"`
+	// VMSize is the group's VM size, the app's when empty
+	VMSize string `json:"vmSize,omitempty"`
+	// Count scales the group, keeping its current count when zero
+	Count int `json:"count,omitempty"`
+	// Image is the group's own image, the release's image when empty
+	Image string `json:"image,omitempty"`
+	// Env is merged over the app's environment variables for the group's instances
+	Env map[string]string `json:"env,omitempty"`
+	// Secrets scopes these app secrets to the group, other groups don't get them
+	Secrets []string `json:"secrets,omitempty"`
+}
+
+// RegionOverrideInput replaces settings of a release for instances in one region
+type RegionOverrideInput struct {
+	Region string `json:"region"`
+	// Env is merged over the app's environment variables
+	Env    map[string]string `json:"env,omitempty"`
+	VMSize string            `json:"vmSize,omitempty"`
+	// SoftLimit and HardLimit replace the concurrency limits of each service, when set
+	SoftLimit int `json:"softLimit,omitempty"`
+	HardLimit int `json:"hardLimit,omitempty"`
 }
 
 type Service struct {
@@ -682,6 +1146,41 @@ type AllocationStatus struct {
 	AttachedVolumes    struct {
 		Nodes []Volume
 	}
+	Warmup *WarmupStatus
+	// Draining is set while an instance of the previous release is stopping
+	Draining *DrainingStatus
+}
+
+// WarmupStatus tracks the warm-up requests sent to a new instance before it's added to load balancing
+type WarmupStatus struct {
+	Status    string
+	Completed int
+	Total     int
+	Error     string
+}
+
+const (
+	WarmupRunning = "running"
+	WarmupPassed  = "passed"
+	WarmupFailed  = "failed"
+)
+
+// DrainingStatus tracks an instance that stopped accepting connections and is waiting up to its
+// kill timeout for open ones to close before it's killed
+type DrainingStatus struct {
+	ActiveConnections int
+	StartedAt         time.Time
+	// KillTimeout is in seconds
+	KillTimeout int
+}
+
+// TimeLeft is how long the instance has until it's killed, zero once the kill timeout passed
+func (d *DrainingStatus) TimeLeft(now time.Time) time.Duration {
+	left := d.StartedAt.Add(time.Duration(d.KillTimeout) * time.Second).Sub(now)
+	if left < 0 {
+		return 0
+	}
+	return left
 }
 
 type AllocationEvent struct {
@@ -779,7 +1278,9 @@ type BuildArgInput struct {
 }
 
 type ConfigureRegionsInput struct {
-	AppID         string   `json:"appId"`
+	AppID string `json:"appId"`
+	// Group configures the regions a process group is pinned to instead of the app's region pool
+	Group         string   `json:"group,omitempty"`
 	AllowRegions  []string `json:"allowRegions"`
 	DenyRegions   []string `json:"denyRegions"`
 	BackupRegions []string `json:"backupRegions"`
@@ -906,6 +1407,10 @@ type CreatePostgresClusterInput struct {
 	Password       *string `json:"password,omitempty"`
 	VMSize         *string `json:"vmSize,omitempty"`
 	VolumeSizeGB   *int    `json:"volumeSizeGb,omitempty"`
+	// BackupID creates the cluster with the data of another cluster's backup
+	BackupID *string `json:"backupId,omitempty"`
+	// Version is the postgres major version, the platform's default when nil
+	Version *int `json:"postgresVersion,omitempty"`
 }
 
 type CreatePostgresClusterPayload struct {
@@ -927,6 +1432,17 @@ type AttachPostgresClusterInput struct {
 	PostgresClusterAppID string  `json:"postgresClusterAppId"`
 	DatabaseName         *string `json:"databaseName,omitempty"`
 	VariableName         *string `json:"variableName,omitempty"`
+	// Replica points the connection string at the cluster's read-only replicas
+	Replica bool `json:"replica,omitempty"`
+}
+
+type DetachPostgresClusterInput struct {
+	AppID                string `json:"appId"`
+	PostgresClusterAppID string `json:"postgresClusterAppId"`
+	// VariableName picks one of several attachments of the app to the cluster
+	VariableName string `json:"variableName,omitempty"`
+	// RevokeUser drops the database user created for the attachment
+	RevokeUser bool `json:"revokeUser,omitempty"`
 }
 
 type AttachPostgresClusterPayload struct {
@@ -934,10 +1450,105 @@ type AttachPostgresClusterPayload struct {
 	PostgresClusterApp      App
 	ConnectionString        string
 	EnvironmentVariableName string
+	DatabaseUser            string
+}
+
+// RegistryRetentionPolicy is how many deployment images the registry keeps for each of an
+// organization's apps. Older deployment-<timestamp> tags are garbage collected, except the image of
+// each app's current release.
+type RegistryRetentionPolicy struct {
+	KeepDeployments int
+	UpdatedAt       time.Time
+}
+
+// RemoteBuilder is one of an organization's remote builders. Builders without a name are the default
+// one used when a build doesn't pick a builder.
+type RemoteBuilder struct {
+	Name      string
+	Default   bool
+	App       *App
+	CreatedAt time.Time
 }
 
 type EnsureRemoteBuilderInput struct {
 	AppName string `json:"appName"`
+	// Name picks one of the organization's named builders, creating it if needed. The default
+	// builder is used when it's empty.
+	Name     string `json:"name,omitempty"`
+	MemoryMb int    `json:"memoryMb,omitempty"`
+	CpuCount int    `json:"cpuCount,omitempty"`
+}
+
+// PostgresAttachment is an app's connection to a database in a postgres cluster app, made by
+// postgres attach
+type PostgresAttachment struct {
+	ID                      string
+	DatabaseName            string
+	DatabaseUser            string
+	EnvironmentVariableName string
+	// Replica is set when the connection string points at the cluster's read-only replicas
+	Replica            bool
+	App                App
+	PostgresClusterApp App
+}
+
+// PostgresBackup is a base backup of a postgres cluster's data, taken on demand or by the
+// cluster's daily schedule
+type PostgresBackup struct {
+	ID string `json:"id"`
+	// Status is pending, running, completed or failed
+	Status    string
+	Scheduled bool
+	SizeBytes int64
+	CreatedAt time.Time
+	// CompletedAt is nil until the backup completes
+	CompletedAt *time.Time
+}
+
+// PostgresNodeMetrics is a snapshot of one cluster instance's pg_stat views and volume, taken
+// when it's queried
+type PostgresNodeMetrics struct {
+	AllocationID string `json:"allocationId"`
+	Region       string
+	// Role is leader or replica
+	Role    string
+	Healthy bool
+	// Connections counts the open backends, out of max_connections
+	Connections    int
+	MaxConnections int
+	// ReplicationLagBytes is how far a replica's replay is behind the leader, 0 on the leader
+	ReplicationLagBytes int64
+	DiskUsedBytes       int64
+	DiskTotalBytes      int64
+	// SlowQueries counts the queries that took over a second in the last hour
+	SlowQueries int
+}
+
+// PostgresImport copies an external database into a postgres cluster, with pg_dump and pg_restore
+// run by a temporary VM on the cluster's private network
+type PostgresImport struct {
+	ID string `json:"id"`
+	// Status is pending, dumping, restoring, succeeded or failed
+	Status     string
+	InProgress bool
+	Database   string
+	// BytesTransferred is how much of the dump has been read
+	BytesTransferred int64
+	Error            string
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+}
+
+type ImportPostgresDatabaseInput struct {
+	AppID string `json:"appId"`
+	// SourceURI is the postgres:// connection string of the database to copy
+	SourceURI string `json:"sourceUri,omitempty"`
+	// SourceAppID copies the database from another of the organization's clusters instead
+	SourceAppID string `json:"sourceAppId,omitempty"`
+	// Database is created in the cluster to hold the copy
+	Database string `json:"databaseName"`
+	// Clean drops the database's existing objects before restoring
+	Clean bool `json:"clean,omitempty"`
 }
 
 type PostgresClusterUser struct {
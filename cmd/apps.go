@@ -1,8 +1,11 @@
 package cmd
 
 import (
+	"sort"
+
 	"github.com/superfly/flyctl/cmdctx"
 	"github.com/superfly/flyctl/internal/client"
+	"github.com/superfly/flyctl/internal/labels"
 
 	"github.com/spf13/cobra"
 	"github.com/superfly/flyctl/cmd/presenters"
@@ -23,11 +26,39 @@ func newAppsCommand(client *client.Client) *Command {
 
 	appsListStrings := docstrings.Get("apps.list")
 
-	BuildCommand(cmd, runAppsList, appsListStrings.Usage, appsListStrings.Short, appsListStrings.Long, client, requireSession)
+	listCmd := BuildCommand(cmd, runAppsList, appsListStrings.Usage, appsListStrings.Short, appsListStrings.Long, client, requireSession)
+	listCmd.AddStringFlag(StringFlagOpts{
+		Name:        "org",
+		Shorthand:   "o",
+		Description: "Show only apps in this organization",
+	})
+	listCmd.AddStringFlag(StringFlagOpts{
+		Name:        "status",
+		Shorthand:   "s",
+		Description: "Show only apps with this status, like running, pending or suspended",
+	})
+	listCmd.AddStringFlag(StringFlagOpts{
+		Name:        "name-prefix",
+		Description: "Show only apps whose names start with this prefix",
+	})
+	listCmd.AddStringFlag(StringFlagOpts{
+		Name:        "selector",
+		Shorthand:   "l",
+		Description: "Show only apps with matching labels, like env=staging,team=payments",
+	})
+
+	appsUsageStrings := docstrings.Get("apps.usage-report")
+	usageCmd := BuildCommand(cmd, runAppsUsage, appsUsageStrings.Usage, appsUsageStrings.Short, appsUsageStrings.Long, client, requireSession)
+	usageCmd.Args = cobra.RangeArgs(0, 1)
+	usageCmd.AddStringFlag(StringFlagOpts{
+		Name:        "org",
+		Shorthand:   "o",
+		Description: "Show only apps in this organization",
+	})
 
 	appsCreateStrings := docstrings.Get("apps.create")
 
-	create := BuildCommand(cmd, runInit, appsCreateStrings.Usage, appsCreateStrings.Short, appsCreateStrings.Long, client, requireSession)
+	create := BuildCommand(cmd, runInit, appsCreateStrings.Usage, appsCreateStrings.Short, appsCreateStrings.Long, client, requireSession, mutating)
 	create.Args = cobra.RangeArgs(0, 1)
 
 	// TODO: Move flag descriptions into the docStrings
@@ -53,13 +84,14 @@ func newAppsCommand(client *client.Client) *Command {
 	})
 
 	appsDestroyStrings := docstrings.Get("apps.destroy")
-	destroy := BuildCommand(cmd, runDestroy, appsDestroyStrings.Usage, appsDestroyStrings.Short, appsDestroyStrings.Long, client, requireSession)
+	destroy := BuildCommand(cmd, runDestroy, appsDestroyStrings.Usage, appsDestroyStrings.Short, appsDestroyStrings.Long, client, requireSession, mutating)
 	destroy.Args = cobra.ExactArgs(1)
 	// TODO: Move flag descriptions into the docStrings
 	destroy.AddBoolFlag(BoolFlagOpts{Name: "yes", Shorthand: "y", Description: "Accept all confirmations"})
+	destroy.AddBoolFlag(BoolFlagOpts{Name: "dry-run", Description: "List what destroying the app would remove without destroying it"})
 
 	appsMoveStrings := docstrings.Get("apps.move")
-	move := BuildCommand(cmd, runMove, appsMoveStrings.Usage, appsMoveStrings.Short, appsMoveStrings.Long, client, requireSession)
+	move := BuildCommand(cmd, runMove, appsMoveStrings.Usage, appsMoveStrings.Short, appsMoveStrings.Long, client, requireSession, mutating)
 	move.Args = cobra.ExactArgs(1)
 	// TODO: Move flag descriptions into the docStrings
 	move.AddBoolFlag(BoolFlagOpts{Name: "yes", Shorthand: "y", Description: "Accept all confirmations"})
@@ -68,16 +100,66 @@ func newAppsCommand(client *client.Client) *Command {
 		Description: `The organization to move the app to`,
 	})
 
+	appsCloneStrings := docstrings.Get("apps.clone")
+	clone := BuildCommand(cmd, runAppsClone, appsCloneStrings.Usage, appsCloneStrings.Short, appsCloneStrings.Long, client, requireSession, mutating)
+	clone.Args = cobra.ExactArgs(2)
+	clone.AddBoolFlag(BoolFlagOpts{Name: "yes", Shorthand: "y", Description: "Accept all confirmations"})
+	clone.AddStringFlag(StringFlagOpts{
+		Name:        "org",
+		Description: "The organization that will own the clone, defaults to the source app's organization",
+	})
+	clone.AddBoolFlag(BoolFlagOpts{
+		Name:        "image",
+		Description: "Deploy the source app's current image to the clone",
+	})
+	clone.AddBoolFlag(BoolFlagOpts{
+		Name:        "no-secrets",
+		Description: "Don't copy the source app's secrets",
+	})
+	clone.AddStringFlag(StringFlagOpts{
+		Name:        "save-config",
+		Description: "Write the clone's config to this file",
+	})
+
+	appsRenameStrings := docstrings.Get("apps.rename")
+	rename := BuildCommand(cmd, runAppsRename, appsRenameStrings.Usage, appsRenameStrings.Short, appsRenameStrings.Long, client, requireSession, mutating)
+	rename.Args = cobra.ExactArgs(2)
+	rename.AddBoolFlag(BoolFlagOpts{Name: "yes", Shorthand: "y", Description: "Accept all confirmations"})
+	rename.AddIntFlag(pushConcurrencyFlag)
+
 	appsSuspendStrings := docstrings.Get("apps.suspend")
-	appsSuspendCmd := BuildCommand(cmd, runSuspend, appsSuspendStrings.Usage, appsSuspendStrings.Short, appsSuspendStrings.Long, client, requireSession, requireAppNameAsArg)
+	appsSuspendCmd := BuildCommand(cmd, forEachConfirmedApp("Suspend", runSuspend), appsSuspendStrings.Usage, appsSuspendStrings.Short, appsSuspendStrings.Long, client, requireSession, requireAppNameAsArg, changesSelectedApps, mutating)
 	appsSuspendCmd.Args = cobra.RangeArgs(0, 1)
 
 	appsResumeStrings := docstrings.Get("apps.resume")
-	appsResumeCmd := BuildCommand(cmd, runResume, appsResumeStrings.Usage, appsResumeStrings.Short, appsResumeStrings.Long, client, requireSession, requireAppNameAsArg)
+	appsResumeCmd := BuildCommand(cmd, forEachConfirmedApp("Resume", runResume), appsResumeStrings.Usage, appsResumeStrings.Short, appsResumeStrings.Long, client, requireSession, requireAppNameAsArg, changesSelectedApps, mutating)
 	appsResumeCmd.Args = cobra.RangeArgs(0, 1)
 
+	appsScheduleStrings := docstrings.Get("apps.schedule")
+	appsScheduleCmd := BuildCommand(cmd, runAppsSchedule, appsScheduleStrings.Usage, appsScheduleStrings.Short, appsScheduleStrings.Long, client, requireSession, requireAppNameAsArg, mutating)
+	appsScheduleCmd.Args = cobra.RangeArgs(0, 1)
+	appsScheduleCmd.AddStringFlag(StringFlagOpts{
+		Name:        "suspend",
+		Description: "Cron schedule to suspend the app on, like \"0 20 * * 5\" for 20:00 on Fridays",
+	})
+	appsScheduleCmd.AddStringFlag(StringFlagOpts{
+		Name:        "resume",
+		Description: "Cron schedule to resume the app on, like \"0 8 * * 1\" for 08:00 on Mondays",
+	})
+	appsScheduleCmd.AddStringFlag(StringFlagOpts{
+		Name:        "timezone",
+		Description: "IANA timezone the schedules run in",
+		Default:     "UTC",
+	})
+	appsScheduleCmd.AddBoolFlag(BoolFlagOpts{
+		Name:        "clear",
+		Description: "Remove the suspend schedule",
+	})
+
+	newAppsLabelCommand(cmd, client)
+
 	appsRestartStrings := docstrings.Get("apps.restart")
-	appsRestartCmd := BuildCommand(cmd, runRestart, appsRestartStrings.Usage, appsRestartStrings.Short, appsRestartStrings.Long, client, requireSession, requireAppNameAsArg)
+	appsRestartCmd := BuildCommand(cmd, forEachConfirmedApp("Restart", runRestart), appsRestartStrings.Usage, appsRestartStrings.Short, appsRestartStrings.Long, client, requireSession, requireAppNameAsArg, changesSelectedApps, mutating)
 	appsRestartCmd.Args = cobra.RangeArgs(0, 1)
 
 	return cmd
@@ -89,5 +171,26 @@ func runAppsList(ctx *cmdctx.CmdContext) error {
 		return err
 	}
 
+	var filter appFilter
+	filter.Org, _ = ctx.Config.GetString("org")
+	filter.Status, _ = ctx.Config.GetString("status")
+	filter.NamePrefix, _ = ctx.Config.GetString("name-prefix")
+	selector, _ := ctx.Config.GetString("selector")
+	if filter.Selector, err = labels.ParseSelector(selector); err != nil {
+		return err
+	}
+	listapps = filterApps(listapps, filter)
+
+	sort.Slice(listapps, func(i, j int) bool { return listapps[i].Name < listapps[j].Name })
+
+	if ctx.OutputJSON() {
+		apps := make([]appCondensed, len(listapps))
+		for i, app := range listapps {
+			apps[i] = condenseApp(app)
+		}
+		ctx.WriteJSON(apps)
+		return nil
+	}
+
 	return ctx.Render(&presenters.Apps{Apps: listapps})
 }
@@ -6,6 +6,8 @@ import (
 	"os/exec"
 	"time"
 
+	"github.com/spf13/cobra"
+	"github.com/superfly/flyctl/cmd/presenters"
 	"github.com/superfly/flyctl/cmdctx"
 
 	"github.com/pkg/errors"
@@ -60,7 +62,26 @@ func newAuthCommand(client *client.Client) *Command {
 	})
 
 	authLogoutStrings := docstrings.Get("auth.logout")
-	BuildCommand(cmd, runLogout, authLogoutStrings.Usage, authLogoutStrings.Short, authLogoutStrings.Long, client, requireSession)
+	logout := BuildCommand(cmd, runLogout, authLogoutStrings.Usage, authLogoutStrings.Short, authLogoutStrings.Long, client, requireSession, mutating)
+	logout.AddBoolFlag(BoolFlagOpts{
+		Name:        "everywhere",
+		Description: "Revoke every session of the user, logging out all machines and CI runners, not just this one",
+	})
+	logout.AddBoolFlag(BoolFlagOpts{Name: "yes", Shorthand: "y", Description: "Accept all confirmations"})
+
+	sessionsStrings := docstrings.Get("auth.sessions")
+	sessions := BuildCommandKS(cmd, nil, sessionsStrings, client, requireSession)
+
+	sessionsListStrings := docstrings.Get("auth.sessions.list")
+	BuildCommandKS(sessions, runSessionsList, sessionsListStrings, client, requireSession)
+
+	sessionsRevokeStrings := docstrings.Get("auth.sessions.revoke")
+	revoke := BuildCommandKS(sessions, runSessionsRevoke, sessionsRevokeStrings, client, requireSession, mutating)
+	revoke.Args = cobra.ArbitraryArgs
+	revoke.AddBoolFlag(BoolFlagOpts{
+		Name:        "others",
+		Description: "Revoke every session except the one in use on this machine",
+	})
 
 	authSignupStrings := docstrings.Get("auth.signup")
 	BuildCommand(cmd, runSignup, authSignupStrings.Usage, authSignupStrings.Short, authSignupStrings.Long, client)
@@ -215,6 +236,18 @@ func runInteractiveLogin(ctx *cmdctx.CmdContext) error {
 }
 
 func runLogout(ctx *cmdctx.CmdContext) error {
+	if ctx.Config.GetBool("everywhere") {
+		if !ctx.Config.GetBool("yes") && !confirm("Log out of every machine, including CI runners using a token from flyctl auth token?") {
+			return nil
+		}
+
+		count, err := ctx.Client.API().RevokeAllSessions()
+		if err != nil {
+			return errors.Wrap(err, "error revoking sessions")
+		}
+		fmt.Printf("Revoked %d sessions\n", count)
+	}
+
 	viper.Set(flyctl.ConfigAPIToken, "")
 
 	if err := flyctl.SaveConfig(); err != nil {
@@ -240,6 +273,62 @@ func runLogout(ctx *cmdctx.CmdContext) error {
 	return nil
 }
 
+func runSessionsList(ctx *cmdctx.CmdContext) error {
+	sessions, err := ctx.Client.API().ListSessions()
+	if err != nil {
+		return err
+	}
+
+	return ctx.Render(&presenters.Sessions{Sessions: sessions})
+}
+
+func runSessionsRevoke(ctx *cmdctx.CmdContext) error {
+	others := ctx.Config.GetBool("others")
+	if len(ctx.Args) == 0 && !others {
+		return errors.New("give the IDs of the sessions to revoke, from flyctl auth sessions list, or use --others")
+	}
+	if len(ctx.Args) > 0 && others {
+		return errors.New("session IDs can't be combined with --others")
+	}
+
+	sessions, err := ctx.Client.API().ListSessions()
+	if err != nil {
+		return err
+	}
+
+	var ids []string
+	if others {
+		for _, session := range sessions {
+			if !session.Current {
+				ids = append(ids, session.ID)
+			}
+		}
+	} else {
+		for _, id := range ctx.Args {
+			for _, session := range sessions {
+				if session.ID == id && session.Current {
+					return fmt.Errorf("session %s is the one in use on this machine, use flyctl auth logout instead", id)
+				}
+			}
+			ids = append(ids, id)
+		}
+	}
+
+	for _, id := range ids {
+		session, err := ctx.Client.API().RevokeSession(id)
+		if err != nil {
+			return errors.Wrapf(err, "error revoking session %s", id)
+		}
+		fmt.Fprintf(ctx.Out, "Revoked session %s (%s, %s)\n", session.ID, session.Name, session.Client)
+	}
+
+	if len(ids) == 0 {
+		fmt.Fprintln(ctx.Out, "No other sessions to revoke")
+	}
+
+	return nil
+}
+
 func runAuthToken(ctx *cmdctx.CmdContext) error {
 	token := flyctl.GetAPIToken()
 
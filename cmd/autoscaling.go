@@ -22,19 +22,19 @@ func newAutoscaleCommand(client *client.Client) *Command {
 	//cmd.Deprecated = "use `flyctl scale` instead"
 
 	disableCmdStrings := docstrings.Get("autoscale.disable")
-	disableCmd := BuildCommand(cmd, runDisableAutoscaling, disableCmdStrings.Usage, disableCmdStrings.Short, disableCmdStrings.Long, client, requireSession, requireAppName)
+	disableCmd := BuildCommand(cmd, runDisableAutoscaling, disableCmdStrings.Usage, disableCmdStrings.Short, disableCmdStrings.Long, client, requireSession, requireAppName, mutating)
 	disableCmd.Args = cobra.RangeArgs(0, 2)
 
 	balanceCmdStrings := docstrings.Get("autoscale.balanced")
-	balanceCmd := BuildCommand(cmd, runBalanceScale, balanceCmdStrings.Usage, balanceCmdStrings.Short, balanceCmdStrings.Long, client, requireSession, requireAppName)
+	balanceCmd := BuildCommand(cmd, runBalanceScale, balanceCmdStrings.Usage, balanceCmdStrings.Short, balanceCmdStrings.Long, client, requireSession, requireAppName, mutating)
 	balanceCmd.Args = cobra.RangeArgs(0, 2)
 
 	standardCmdStrings := docstrings.Get("autoscale.standard")
-	standardCmd := BuildCommand(cmd, runStandardScale, standardCmdStrings.Usage, standardCmdStrings.Short, standardCmdStrings.Long, client, requireSession, requireAppName)
+	standardCmd := BuildCommand(cmd, runStandardScale, standardCmdStrings.Usage, standardCmdStrings.Short, standardCmdStrings.Long, client, requireSession, requireAppName, mutating)
 	standardCmd.Args = cobra.RangeArgs(0, 2)
 
 	setCmdStrings := docstrings.Get("autoscale.set")
-	setCmd := BuildCommand(cmd, runSetParamsOnly, setCmdStrings.Usage, setCmdStrings.Short, setCmdStrings.Long, client, requireSession, requireAppName)
+	setCmd := BuildCommand(cmd, runSetParamsOnly, setCmdStrings.Usage, setCmdStrings.Short, setCmdStrings.Long, client, requireSession, requireAppName, mutating)
 	setCmd.Args = cobra.RangeArgs(0, 2)
 
 	showCmdStrings := docstrings.Get("autoscale.show")
@@ -0,0 +1,312 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/docker/docker/api/types"
+	"github.com/dustin/go-humanize"
+	"github.com/logrusorgru/aurora"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/cmd/presenters"
+	"github.com/superfly/flyctl/cmdctx"
+	"github.com/superfly/flyctl/docstrings"
+	"github.com/superfly/flyctl/flyname"
+	"github.com/superfly/flyctl/helpers"
+	"github.com/superfly/flyctl/internal/build/imgsrc"
+	"github.com/superfly/flyctl/internal/client"
+)
+
+func newBuilderCommand(client *client.Client) *Command {
+	builderStrings := docstrings.Get("builder")
+	cmd := BuildCommandKS(nil, nil, builderStrings, client, requireSession, requireAppName)
+
+	listStrings := docstrings.Get("builder.list")
+	BuildCommandKS(cmd, runBuilderList, listStrings, client, requireSession, requireAppName)
+
+	statusStrings := docstrings.Get("builder.status")
+	statusCmd := BuildCommandKS(cmd, runBuilderStatus, statusStrings, client, requireSession, requireAppName)
+	statusCmd.AddStringFlag(builderFlag)
+
+	warmStrings := docstrings.Get("builder.warm")
+	warmCmd := BuildCommandKS(cmd, runBuilderWarm, warmStrings, client, requireSession, requireAppName, mutating)
+	warmCmd.AddStringFlag(builderFlag)
+
+	destroyStrings := docstrings.Get("builder.destroy")
+	destroyCmd := BuildCommandKS(cmd, runBuilderDestroy, destroyStrings, client, requireSession, requireAppName, mutating)
+	destroyCmd.AddBoolFlag(BoolFlagOpts{Name: "yes", Shorthand: "y", Description: "Accept all confirmations"})
+	destroyCmd.AddBoolFlag(BoolFlagOpts{Name: "recreate", Description: "Provision a new builder after destroying the current one"})
+	destroyCmd.AddStringFlag(builderFlag)
+
+	cacheStrings := docstrings.Get("builder.cache")
+	cacheCmd := BuildCommandKS(cmd, nil, cacheStrings, client, requireSession, requireAppName)
+
+	cacheListStrings := docstrings.Get("builder.cache.ls")
+	cacheListCmd := BuildCommandKS(cacheCmd, runBuilderCacheList, cacheListStrings, client, requireSession, requireAppName)
+	cacheListCmd.AddStringFlag(builderFlag)
+
+	cachePruneStrings := docstrings.Get("builder.cache.prune")
+	cachePruneCmd := BuildCommandKS(cacheCmd, runBuilderCachePrune, cachePruneStrings, client, requireSession, requireAppName, mutating)
+	cachePruneCmd.AddBoolFlag(BoolFlagOpts{Name: "yes", Shorthand: "y", Description: "Accept all confirmations"})
+	cachePruneCmd.AddBoolFlag(BoolFlagOpts{Name: "all", Description: "Remove all unused images and build cache, not just dangling ones"})
+	cachePruneCmd.AddStringFlag(builderFlag)
+
+	return cmd
+}
+
+var builderFlag = StringFlagOpts{
+	Name:        "builder",
+	Description: "Name of the organization's remote builder to use, created if it doesn't exist. Defaults to the organization's default builder",
+}
+
+// newRemoteBuilder returns the remote builder picked with --builder
+func newRemoteBuilder(cmdCtx *cmdctx.CmdContext) *imgsrc.RemoteBuilder {
+	name, _ := cmdCtx.Config.GetString("builder")
+	return imgsrc.NewRemoteBuilder(cmdCtx.Client.API(), cmdCtx.AppName, name, cmdCtx.IO, wireGuardNetwork(cmdCtx))
+}
+
+func runBuilderList(cmdCtx *cmdctx.CmdContext) error {
+	app, err := cmdCtx.Client.API().GetApp(cmdCtx.AppName)
+	if err != nil {
+		return err
+	}
+
+	builders, err := cmdCtx.Client.API().ListRemoteBuilders(app.Organization.Slug)
+	if err != nil {
+		return err
+	}
+
+	return cmdCtx.Render(&presenters.RemoteBuilders{Builders: builders})
+}
+
+func runBuilderStatus(cmdCtx *cmdctx.CmdContext) error {
+	ctx := createCancellableContext()
+
+	builder := newRemoteBuilder(cmdCtx)
+
+	// looking at the builder shouldn't create one
+	builderName, err := builder.ExistingAppName()
+	if err == imgsrc.ErrNoBuilder {
+		fmt.Fprintf(cmdCtx.Out, "No builder. One is created on the next remote build, or with `%s builder warm`.\n", flyname.Name())
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	status, err := cmdCtx.Client.API().GetAppStatus(builderName, false)
+	if err != nil {
+		return err
+	}
+
+	err = cmdCtx.Frender(
+		cmdctx.PresenterOption{Presentable: &presenters.AppStatus{AppStatus: *status}, HideHeader: true, Vertical: true, Title: "Builder"},
+		cmdctx.PresenterOption{Presentable: &presenters.Allocations{Allocations: status.Allocations}, Title: "Instances"},
+	)
+	if err != nil {
+		return err
+	}
+
+	if cmdCtx.OutputJSON() {
+		return nil
+	}
+
+	// checking disk usage needs a running builder, don't start one just to look at it
+	if !builderRunning(status) {
+		fmt.Fprintf(cmdCtx.Out, "Builder is not running. Start it with `%s builder warm` to see disk usage.\n", flyname.Name())
+		return nil
+	}
+
+	usage, err := builder.DiskUsage(ctx)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(cmdCtx.Out, aurora.Bold("Disk Usage"))
+	printDiskUsage(cmdCtx, usage)
+
+	return nil
+}
+
+func builderRunning(status *api.AppStatus) bool {
+	for _, alloc := range status.Allocations {
+		if alloc.DesiredStatus == "run" && alloc.Status == "running" {
+			return true
+		}
+	}
+	return false
+}
+
+func printDiskUsage(cmdCtx *cmdctx.CmdContext, usage types.DiskUsage) {
+	var imagesSize, containersSize, volumesSize, cacheSize, cacheReclaimable int64
+
+	for _, img := range usage.Images {
+		imagesSize += img.Size
+	}
+	for _, c := range usage.Containers {
+		containersSize += c.SizeRw
+	}
+	for _, v := range usage.Volumes {
+		if v.UsageData != nil && v.UsageData.Size > 0 {
+			volumesSize += v.UsageData.Size
+		}
+	}
+	for _, bc := range usage.BuildCache {
+		cacheSize += bc.Size
+		if !bc.InUse && !bc.Shared {
+			cacheReclaimable += bc.Size
+		}
+	}
+
+	table := helpers.MakeSimpleTable(cmdCtx.Out, []string{"Type", "Count", "Size", "Reclaimable"})
+	table.Append([]string{"Images", fmt.Sprint(len(usage.Images)), humanize.Bytes(uint64(imagesSize)), ""})
+	table.Append([]string{"Containers", fmt.Sprint(len(usage.Containers)), humanize.Bytes(uint64(containersSize)), ""})
+	table.Append([]string{"Volumes", fmt.Sprint(len(usage.Volumes)), humanize.Bytes(uint64(volumesSize)), ""})
+	table.Append([]string{"Build Cache", fmt.Sprint(len(usage.BuildCache)), humanize.Bytes(uint64(cacheSize)), humanize.Bytes(uint64(cacheReclaimable))})
+	table.Render()
+}
+
+func runBuilderWarm(cmdCtx *cmdctx.CmdContext) error {
+	ctx := createCancellableContext()
+
+	builder := newRemoteBuilder(cmdCtx)
+
+	if _, err := builder.Docker(ctx); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(cmdCtx.Out, "Remote builder is ready")
+
+	return nil
+}
+
+func runBuilderDestroy(cmdCtx *cmdctx.CmdContext) error {
+	builder := newRemoteBuilder(cmdCtx)
+
+	builderName, err := builder.ExistingAppName()
+	if err == imgsrc.ErrNoBuilder {
+		fmt.Fprintln(cmdCtx.Out, "No builder to destroy")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	if !cmdCtx.Config.GetBool("yes") {
+		fmt.Fprintln(cmdCtx.Out, aurora.Red("Destroying the builder removes its build cache. A new builder is created on the next remote build."))
+
+		confirm := false
+		prompt := &survey.Confirm{
+			Message: fmt.Sprintf("Destroy builder %s?", builderName),
+		}
+		if err := survey.AskOne(prompt, &confirm); err != nil {
+			return err
+		}
+		if !confirm {
+			return nil
+		}
+	}
+
+	if err := cmdCtx.Client.API().DeleteApp(builderName); err != nil {
+		return err
+	}
+
+	fmt.Fprintln(cmdCtx.Out, "Destroyed builder", builderName)
+
+	if !cmdCtx.Config.GetBool("recreate") {
+		return nil
+	}
+
+	builderName, err = builder.AppName()
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(cmdCtx.Out, "Created builder", builderName)
+
+	return nil
+}
+
+func runBuilderCacheList(cmdCtx *cmdctx.CmdContext) error {
+	ctx := createCancellableContext()
+
+	builder := newRemoteBuilder(cmdCtx)
+
+	images, cache, err := builder.BuildCache(ctx)
+	if err != nil {
+		return err
+	}
+
+	if cmdCtx.OutputJSON() {
+		cmdCtx.WriteJSON(map[string]interface{}{
+			"DanglingImages": images,
+			"BuildCache":     cache,
+		})
+		return nil
+	}
+
+	out := cmdCtx.Out
+
+	fmt.Fprintln(out, aurora.Bold("Dangling Images"))
+	if len(images) == 0 {
+		fmt.Fprintln(out, "No dangling images")
+	} else {
+		table := helpers.MakeSimpleTable(out, []string{"ID", "Size", "Created"})
+		for _, img := range images {
+			table.Append([]string{shortDigest(img.ID), humanize.Bytes(uint64(img.Size)), humanize.Time(time.Unix(img.Created, 0))})
+		}
+		table.Render()
+	}
+	fmt.Fprintln(out)
+
+	fmt.Fprintln(out, aurora.Bold("Build Cache"))
+	if len(cache) == 0 {
+		fmt.Fprintln(out, "No build cache")
+		return nil
+	}
+	table := helpers.MakeSimpleTable(out, []string{"ID", "Type", "Size", "In Use", "Shared", "Last Used"})
+	for _, bc := range cache {
+		lastUsed := ""
+		if bc.LastUsedAt != nil {
+			lastUsed = humanize.Time(*bc.LastUsedAt)
+		}
+		table.Append([]string{truncate(bc.ID, 12), bc.Type, humanize.Bytes(uint64(bc.Size)), fmt.Sprint(bc.InUse), fmt.Sprint(bc.Shared), lastUsed})
+	}
+	table.Render()
+
+	return nil
+}
+
+func runBuilderCachePrune(cmdCtx *cmdctx.CmdContext) error {
+	ctx := createCancellableContext()
+
+	all := cmdCtx.Config.GetBool("all")
+
+	if !cmdCtx.Config.GetBool("yes") {
+		message := "Remove dangling images and unused build cache from the builder?"
+		if all {
+			message = "Remove all unused images and build cache from the builder?"
+		}
+
+		confirm := false
+		prompt := &survey.Confirm{Message: message}
+		if err := survey.AskOne(prompt, &confirm); err != nil {
+			return err
+		}
+		if !confirm {
+			return nil
+		}
+	}
+
+	builder := newRemoteBuilder(cmdCtx)
+
+	reclaimed, err := builder.PruneBuildCache(ctx, all)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(cmdCtx.Out, "Reclaimed", humanize.Bytes(reclaimed))
+
+	return nil
+}
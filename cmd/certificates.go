@@ -4,11 +4,13 @@ import (
 	"fmt"
 	"net"
 	"strings"
+	"time"
 
 	"github.com/dustin/go-humanize"
 	"github.com/superfly/flyctl/api"
 	"github.com/superfly/flyctl/cmdctx"
 	"github.com/superfly/flyctl/internal/client"
+	"github.com/superfly/flyctl/internal/dnsprovider"
 
 	"github.com/superfly/flyctl/docstrings"
 
@@ -26,23 +28,44 @@ func newCertificatesCommand(client *client.Client) *Command {
 	BuildCommandKS(cmd, runCertsList, certsListStrings, client, requireSession, requireAppName)
 
 	certsCreateStrings := docstrings.Get("certs.add")
-	createCmd := BuildCommandKS(cmd, runCertAdd, certsCreateStrings, client, requireSession, requireAppName)
+	createCmd := BuildCommandKS(cmd, runCertAdd, certsCreateStrings, client, requireSession, requireAppName, mutating)
 	createCmd.Aliases = []string{"create"}
 	createCmd.Command.Args = cobra.ExactArgs(1)
+	createCmd.AddStringFlag(StringFlagOpts{
+		Name:        "dns-provider",
+		Description: fmt.Sprintf("Create the DNS records through the provider's API and wait for the certificate, one of %s", strings.Join(dnsprovider.Names, ", ")),
+	})
+	createCmd.AddStringFlag(StringFlagOpts{
+		Name:        "wait-timeout",
+		Description: "How long to wait for the certificate with --dns-provider, like 10m",
+		Default:     "10m",
+	})
 
 	certsDeleteStrings := docstrings.Get("certs.remove")
-	deleteCmd := BuildCommandKS(cmd, runCertDelete, certsDeleteStrings, client, requireSession, requireAppName)
+	deleteCmd := BuildCommandKS(cmd, runCertDelete, certsDeleteStrings, client, requireSession, requireAppName, mutating)
 	deleteCmd.Aliases = []string{"delete"}
 	deleteCmd.Command.Args = cobra.ExactArgs(1)
 	deleteCmd.AddBoolFlag(BoolFlagOpts{Name: "yes", Shorthand: "y", Description: "accept all confirmations"})
 
+	certsImportStrings := docstrings.Get("certs.import")
+	importCmd := BuildCommandKS(cmd, runCertsImport, certsImportStrings, client, requireSession, requireAppName, mutating)
+	importCmd.Command.Args = cobra.ExactArgs(1)
+	importCmd.AddIntFlag(IntFlagOpts{Name: "batch-size", Description: "Certificates to add before pausing", Default: 20})
+	importCmd.AddStringFlag(StringFlagOpts{Name: "batch-interval", Description: "Pause between batches, like 1m. Rate limited requests back off from it", Default: "1m"})
+	importCmd.AddStringFlag(StringFlagOpts{Name: "state", Description: "File the import's progress is saved to, defaults to the list's path with .progress.json appended"})
+
 	certsShowStrings := docstrings.Get("certs.show")
 	show := BuildCommandKS(cmd, runCertShow, certsShowStrings, client, requireSession, requireAppName)
 	show.Command.Args = cobra.ExactArgs(1)
 
 	certsCheckStrings := docstrings.Get("certs.check")
 	check := BuildCommandKS(cmd, runCertCheck, certsCheckStrings, client, requireSession, requireAppName)
-	check.Command.Args = cobra.ExactArgs(1)
+	check.Command.Args = cobra.MaximumNArgs(1)
+	check.AddIntFlag(IntFlagOpts{
+		Name:        "warn-days",
+		Description: "Exit non-zero when a certificate expires within this many days, or isn't issued an hour after it was added",
+		Default:     14,
+	})
 
 	return cmd
 }
@@ -76,6 +99,10 @@ func runCertShow(commandContext *cmdctx.CmdContext) error {
 }
 
 func runCertCheck(commandContext *cmdctx.CmdContext) error {
+	warnDays := commandContext.Config.GetInt("warn-days")
+	if len(commandContext.Args) == 0 {
+		return runCertsCheckAll(commandContext, warnDays)
+	}
 	hostname := commandContext.Args[0]
 
 	cert, hostcheck, err := commandContext.Client.API().CheckAppCertificate(commandContext.AppName, hostname)
@@ -87,23 +114,42 @@ func runCertCheck(commandContext *cmdctx.CmdContext) error {
 		// A certificate has been issued
 		commandContext.Statusf("certs", cmdctx.SINFO, "The certificate for %s has been issued.\n", hostname)
 		printCertificate(commandContext, cert)
-		// Details should go here
-		return nil
+	} else {
+		commandContext.Statusf("certs", cmdctx.SINFO, "The certificate for %s has not been issued yet.\n", hostname)
+		if err := reportNextStepCert(commandContext, hostname, cert, hostcheck); err != nil {
+			return err
+		}
 	}
 
-	commandContext.Statusf("certs", cmdctx.SINFO, "The certificate for %s has not been issued yet.\n", hostname)
-
-	return reportNextStepCert(commandContext, hostname, cert, hostcheck)
+	return checkCertProblem(commandContext, cert, warnDays)
 }
 
 func runCertAdd(commandContext *cmdctx.CmdContext) error {
 	hostname := commandContext.Args[0]
 
+	// the provider's credentials are checked before the certificate is added
+	var provider dnsprovider.Provider
+	var timeout time.Duration
+	if name, _ := commandContext.Config.GetString("dns-provider"); name != "" {
+		var err error
+		if provider, err = dnsprovider.New(name); err != nil {
+			return err
+		}
+		v, _ := commandContext.Config.GetString("wait-timeout")
+		if timeout, err = time.ParseDuration(v); err != nil || timeout <= 0 {
+			return fmt.Errorf("invalid --wait-timeout \"%s\", expected a duration like 10m", v)
+		}
+	}
+
 	cert, hostcheck, err := commandContext.Client.API().AddCertificate(commandContext.AppName, hostname)
 	if err != nil {
 		return err
 	}
 
+	if provider != nil {
+		return configureCertDNS(commandContext, provider, hostname, cert, hostcheck, timeout)
+	}
+
 	return reportNextStepCert(commandContext, hostname, cert, hostcheck)
 }
 
@@ -300,6 +346,12 @@ func printCertificate(commandContext *cmdctx.CmdContext, cert *api.AppCertificat
 	myprnt("Certificate Authority", readableCertAuthority(cert.CertificateAuthority))
 	myprnt("Issued", strings.Join(certtypes, ","))
 	myprnt("Added to App", humanize.Time(cert.CreatedAt))
+	if expiresAt := certExpiry(cert.Issued.Nodes); expiresAt != nil {
+		myprnt("Expires", fmt.Sprintf("%s (%s)", humanize.Time(*expiresAt), expiresAt.Format(time.RFC3339)))
+	}
+	if lastError := certLastError(cert.ValidationErrors); lastError != "" {
+		myprnt("Last Error", lastError)
+	}
 	myprnt("Source", cert.Source)
 }
 
@@ -316,12 +368,13 @@ func printCertificates(commandContext *cmdctx.CmdContext, certs []api.AppCertifi
 		return nil
 	}
 
-	commandContext.Statusf("certs", cmdctx.STITLE, "%-25s %-20s %s\n", "Host Name", "Added", "Status")
+	commandContext.Statusf("certs", cmdctx.STITLE, "%-25s %-20s %-20s %s\n", "Host Name", "Added", "Expires", "Status")
 
 	for _, v := range certs {
-		commandContext.Statusf("certs", cmdctx.SINFO, "%-25s %-20s %s\n",
+		commandContext.Statusf("certs", cmdctx.SINFO, "%-25s %-20s %-20s %s\n",
 			v.Hostname,
 			humanize.Time(v.CreatedAt),
+			formatCertExpiry(certExpiry(v.Issued.Nodes)),
 			v.ClientStatus)
 	}
 
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/cmdctx"
+	"github.com/superfly/flyctl/internal/dnsprovider"
+)
+
+// configureCertDNS creates the records routing hostname to the app and validating its
+// certificate through the DNS provider's API, then waits for the certificate to be issued
+func configureCertDNS(cmdCtx *cmdctx.CmdContext, provider dnsprovider.Provider, hostname string, cert *api.AppCertificate, hostcheck *api.HostnameCheck, timeout time.Duration) error {
+	ctx := createCancellableContext()
+
+	if cert.ClientStatus == "Ready" {
+		cmdCtx.Statusf("certs", cmdctx.SINFO, "The certificate for %s has been issued\n", hostname)
+		return nil
+	}
+
+	records, err := certDNSRecords(cmdCtx, hostname, cert, hostcheck)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		if err := provider.UpsertRecord(ctx, record); err != nil {
+			return errors.WithMessagef(err, "Failed to create %s", record)
+		}
+		cmdCtx.Statusf("certs", cmdctx.SDONE, "Created %s\n", record)
+	}
+
+	return waitForCertificate(ctx, cmdCtx, hostname, timeout)
+}
+
+// certDNSRecords returns the records missing for hostname: an A and AAAA record for an apex
+// domain or a CNAME to the app otherwise, unless it already resolves to the app, and the
+// _acme-challenge CNAME unless it's in place
+func certDNSRecords(cmdCtx *cmdctx.CmdContext, hostname string, cert *api.AppCertificate, hostcheck *api.HostnameCheck) ([]dnsprovider.Record, error) {
+	var records []dnsprovider.Record
+
+	ips, err := cmdCtx.Client.API().GetIPAddresses(cmdCtx.AppName)
+	if err != nil {
+		return nil, err
+	}
+
+	routed := false
+	for _, address := range hostcheck.ResolvedAddresses {
+		for _, ip := range ips {
+			if net.ParseIP(address).Equal(net.ParseIP(ip.Address)) {
+				routed = true
+			}
+		}
+	}
+
+	if !routed {
+		if cert.IsApex {
+			for _, ip := range ips {
+				switch ip.Type {
+				case "v4":
+					records = append(records, dnsprovider.Record{Type: "A", Name: hostname, Value: ip.Address})
+				case "v6":
+					records = append(records, dnsprovider.Record{Type: "AAAA", Name: hostname, Value: ip.Address})
+				}
+			}
+		} else {
+			records = append(records, dnsprovider.Record{Type: "CNAME", Name: hostname, Value: cmdCtx.AppName + ".fly.dev"})
+		}
+	}
+
+	if !cert.AcmeDNSConfigured && cert.DNSValidationHostname != "" {
+		records = append(records, dnsprovider.Record{
+			Type:  "CNAME",
+			Name:  strings.TrimSuffix(cert.DNSValidationHostname, "."),
+			Value: strings.TrimSuffix(cert.DNSValidationTarget, "."),
+		})
+	}
+
+	return records, nil
+}
+
+// waitForCertificate checks the certificate until it's issued, or timeout passes
+func waitForCertificate(ctx context.Context, cmdCtx *cmdctx.CmdContext, hostname string, timeout time.Duration) error {
+	deadline := time.After(timeout)
+
+	cmdCtx.IO.StartProgressIndicatorMsg(fmt.Sprintf("Waiting for the certificate for %s", hostname))
+	defer cmdCtx.IO.StopProgressIndicator()
+
+	for {
+		cert, _, err := cmdCtx.Client.API().CheckAppCertificate(cmdCtx.AppName, hostname)
+		if err != nil {
+			return err
+		}
+		if cert.ClientStatus == "Ready" {
+			cmdCtx.IO.StopProgressIndicator()
+			cmdCtx.Statusf("certs", cmdctx.SDONE, "The certificate for %s has been issued\n", hostname)
+			printCertificate(cmdCtx, cert)
+			return nil
+		}
+		cmdCtx.IO.ChangeProgressIndicatorMsg(fmt.Sprintf("Waiting for the certificate for %s: %s", hostname, cert.ClientStatus))
+
+		select {
+		case <-time.After(10 * time.Second):
+		case <-deadline:
+			return fmt.Errorf("the certificate for %s wasn't issued within %s, DNS changes can take a while to propagate. Check on it with flyctl certs check %s", hostname, timeout, hostname)
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/cmdctx"
+	"github.com/superfly/flyctl/internal/certimport"
+)
+
+// certImportAttempts is how many times a hostname is tried while the API rate limits the import
+const certImportAttempts = 5
+
+// certImportResult is the outcome of certs import
+type certImportResult struct {
+	Hostnames int
+	// Added counts the certificates added by this run
+	Added int
+	// Skipped counts the hostnames that already had certificates
+	Skipped   int
+	Failed    map[string]string
+	StateFile string
+}
+
+// runCertsImport adds certificates for each hostname in a file, in batches spaced out to stay
+// under the rate limits, saving its progress so an interrupted import resumes where it stopped
+func runCertsImport(cmdCtx *cmdctx.CmdContext) error {
+	ctx := createCancellableContext()
+	client := cmdCtx.Client.API()
+	path := cmdCtx.Args[0]
+
+	batchSize := cmdCtx.Config.GetInt("batch-size")
+	if batchSize <= 0 {
+		return fmt.Errorf("--batch-size must be at least 1")
+	}
+	v, _ := cmdCtx.Config.GetString("batch-interval")
+	interval, err := time.ParseDuration(v)
+	if err != nil || interval < 0 {
+		return fmt.Errorf("invalid --batch-interval \"%s\", expected a duration like 1m", v)
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	hostnames, err := certimport.ParseHostnames(f)
+	f.Close()
+	if err != nil {
+		return errors.Wrapf(err, "could not read %s", path)
+	}
+	if len(hostnames) == 0 {
+		return fmt.Errorf("%s has no hostnames, list one per line", path)
+	}
+
+	statePath, _ := cmdCtx.Config.GetString("state")
+	if statePath == "" {
+		statePath = path + ".progress.json"
+	}
+	state, err := certimport.LoadState(statePath)
+	if err != nil {
+		return err
+	}
+
+	certs, err := client.GetAppCertificates(cmdCtx.AppName)
+	if err != nil {
+		return err
+	}
+	existing := map[string]bool{}
+	for _, c := range certs {
+		existing[c.Hostname] = true
+	}
+
+	// the state covers hostnames added by earlier runs even when the app lists only some of its
+	// certificates
+	result := certImportResult{Hostnames: len(hostnames), StateFile: statePath}
+	var pending []string
+	for _, hostname := range hostnames {
+		switch {
+		case existing[hostname]:
+			state.MarkAdded(hostname)
+			result.Skipped++
+		case state.IsAdded(hostname):
+			result.Skipped++
+		default:
+			pending = append(pending, hostname)
+		}
+	}
+
+	cmdCtx.Statusf("certs", cmdctx.SBEGIN, "Adding certificates for %d of %d hostnames, %d at a time every %s\n", len(pending), len(hostnames), batchSize, interval)
+	cmdCtx.IO.StartProgressIndicatorMsg("Adding certificates")
+	defer cmdCtx.IO.StopProgressIndicator()
+
+	for i, hostname := range pending {
+		if i > 0 && i%batchSize == 0 {
+			cmdCtx.IO.ChangeProgressIndicatorMsg(fmt.Sprintf("Added %d of %d, waiting %s for the next batch", i, len(pending), interval))
+			if err := sleepOrDone(ctx, interval); err != nil {
+				return stopCertsImport(cmdCtx, state, statePath, err)
+			}
+		}
+		cmdCtx.IO.ChangeProgressIndicatorMsg(fmt.Sprintf("Adding %s, %d of %d", hostname, i+1, len(pending)))
+
+		err := addCertificateWithBackoff(ctx, client, cmdCtx.AppName, hostname, interval)
+		if ctx.Err() != nil {
+			return stopCertsImport(cmdCtx, state, statePath, ctx.Err())
+		}
+		if err != nil {
+			state.MarkFailed(hostname, err)
+		} else {
+			state.MarkAdded(hostname)
+			result.Added++
+		}
+
+		if err := state.Save(statePath); err != nil {
+			return errors.Wrap(err, "could not save the import's progress")
+		}
+	}
+	cmdCtx.IO.StopProgressIndicator()
+
+	result.Failed = state.Failed
+	if cmdCtx.OutputJSON() {
+		cmdCtx.WriteJSON(result)
+	} else {
+		cmdCtx.Statusf("certs", cmdctx.SDONE, "Added %d certificates, %d hostnames already had one\n", result.Added, result.Skipped)
+		for _, hostname := range state.FailedHostnames() {
+			cmdCtx.Statusf("certs", cmdctx.SERROR, "%s: %s\n", hostname, state.Failed[hostname])
+		}
+		if result.Added > 0 {
+			fmt.Fprintf(cmdCtx.Out, "Certificates are issued once each hostname's DNS points at the app, check on them with flyctl certs check\n")
+		}
+	}
+
+	if len(state.Failed) > 0 {
+		return fmt.Errorf("%d hostnames failed, run the import again to retry them", len(state.Failed))
+	}
+	return nil
+}
+
+// addCertificateWithBackoff adds a certificate, waiting longer after each rate limited attempt
+func addCertificateWithBackoff(ctx context.Context, client *api.Client, appName string, hostname string, delay time.Duration) error {
+	if delay <= 0 {
+		delay = time.Second
+	}
+
+	var err error
+	for attempt := 1; attempt <= certImportAttempts; attempt++ {
+		if _, _, err = client.AddCertificate(appName, hostname); !api.IsRateLimitError(err) {
+			return err
+		}
+		if attempt < certImportAttempts {
+			if err := sleepOrDone(ctx, delay); err != nil {
+				return err
+			}
+			delay *= 2
+		}
+	}
+	return errors.WithMessagef(err, "still rate limited after %d attempts", certImportAttempts)
+}
+
+func stopCertsImport(cmdCtx *cmdctx.CmdContext, state *certimport.State, statePath string, err error) error {
+	if saveErr := state.Save(statePath); saveErr != nil {
+		return errors.Wrap(saveErr, "could not save the import's progress")
+	}
+	cmdCtx.IO.StopProgressIndicator()
+	fmt.Fprintf(cmdCtx.Out, "Stopped, progress is saved in %s. Run the same command to resume\n", statePath)
+	return err
+}
+
+func sleepOrDone(ctx context.Context, d time.Duration) error {
+	select {
+	case <-time.After(d):
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
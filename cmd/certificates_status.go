@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/logrusorgru/aurora"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/cmdctx"
+	"github.com/superfly/flyctl/helpers"
+)
+
+// certStuckAfter is how long a certificate may go unissued before certs check reports it
+const certStuckAfter = time.Hour
+
+// certStatus is a certificate's issuance state as reported by certs check
+type certStatus struct {
+	Hostname     string
+	ClientStatus string
+	ExpiresAt    *time.Time
+	LastError    string
+	// Problem is why the certificate needs attention, empty when it doesn't
+	Problem string
+}
+
+// runCertsCheckAll checks every certificate of the app, exiting non-zero when any is close to
+// expiry or stuck
+func runCertsCheckAll(ctx *cmdctx.CmdContext, warnDays int) error {
+	certs, err := ctx.Client.API().GetAppCertificates(ctx.AppName)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	statuses := make([]certStatus, 0, len(certs))
+	failing := 0
+	for _, c := range certs {
+		status := certStatus{
+			Hostname:     c.Hostname,
+			ClientStatus: c.ClientStatus,
+			ExpiresAt:    certExpiry(c.Issued.Nodes),
+			LastError:    certLastError(c.ValidationErrors),
+		}
+		status.Problem = certProblem(c.ClientStatus, c.CreatedAt, status.ExpiresAt, status.LastError, warnDays, now)
+		if status.Problem != "" {
+			failing++
+		}
+		statuses = append(statuses, status)
+	}
+
+	if ctx.OutputJSON() {
+		ctx.WriteJSON(statuses)
+	} else if len(statuses) == 0 {
+		fmt.Fprintf(ctx.Out, "%s has no certificates\n", ctx.AppName)
+	} else {
+		table := helpers.MakeSimpleTable(ctx.Out, []string{"Hostname", "Status", "Expires", "Problem"})
+		for _, s := range statuses {
+			table.Append([]string{s.Hostname, s.ClientStatus, formatCertExpiry(s.ExpiresAt), s.Problem})
+		}
+		table.Render()
+	}
+
+	if failing > 0 {
+		reason := fmt.Sprintf("%d of %d certificates need attention", failing, len(statuses))
+		if !ctx.OutputJSON() {
+			fmt.Fprintln(ctx.Out, aurora.Red(reason))
+		}
+		return &ExitError{Code: 1, Reason: reason}
+	}
+
+	return nil
+}
+
+// checkCertProblem reports the certificate when it's close to expiry or stuck, returning an
+// ExitError so the command exits non-zero
+func checkCertProblem(ctx *cmdctx.CmdContext, cert *api.AppCertificate, warnDays int) error {
+	problem := certProblem(cert.ClientStatus, cert.CreatedAt, certExpiry(cert.Issued.Nodes), certLastError(cert.ValidationErrors), warnDays, time.Now())
+	if problem == "" {
+		return nil
+	}
+
+	ctx.Statusf("certs", cmdctx.SERROR, "The certificate for %s needs attention: %s\n", cert.Hostname, problem)
+	return &ExitError{Code: 1, Reason: fmt.Sprintf("the certificate for %s needs attention: %s", cert.Hostname, problem)}
+}
+
+// certProblem describes why a certificate needs attention: it's expired or expires within
+// warnDays, or it still hasn't been issued certStuckAfter after it was added
+func certProblem(status string, createdAt time.Time, expiresAt *time.Time, lastError string, warnDays int, now time.Time) string {
+	if expiresAt != nil {
+		left := expiresAt.Sub(now)
+		if left <= 0 {
+			return fmt.Sprintf("expired %s", humanize.Time(*expiresAt))
+		}
+		if left < time.Duration(warnDays)*24*time.Hour {
+			return fmt.Sprintf("expires %s", humanize.Time(*expiresAt))
+		}
+	}
+
+	if status != "Ready" && now.Sub(createdAt) > certStuckAfter {
+		problem := fmt.Sprintf("not issued, added %s", humanize.Time(createdAt))
+		if lastError != "" {
+			problem += ": " + lastError
+		}
+		return problem
+	}
+
+	return ""
+}
+
+// certExpiry returns when the first of the certificate's key types expires, nil before it's issued
+func certExpiry(issued []api.AppCertificateIssued) *time.Time {
+	var expiry *time.Time
+	for i := range issued {
+		if expiry == nil || issued[i].ExpiresAt.Before(*expiry) {
+			expiry = &issued[i].ExpiresAt
+		}
+	}
+	return expiry
+}
+
+func certLastError(errs []api.AppCertificateValidationError) string {
+	if len(errs) == 0 {
+		return ""
+	}
+	return errs[len(errs)-1].Message
+}
+
+func formatCertExpiry(expiresAt *time.Time) string {
+	if expiresAt == nil {
+		return ""
+	}
+	return humanize.Time(*expiresAt)
+}
@@ -25,12 +25,12 @@ func newChecksCommand(client *client.Client) *Command {
 	listHandlersCmd.Args = cobra.ExactArgs(1)
 
 	handlersCreateStrings := docstrings.Get("checks.handlers.create")
-	createHandlersCmd := BuildCommandKS(handlersCmd, runCreateChecksHandler, handlersCreateStrings, client, requireSession)
+	createHandlersCmd := BuildCommandKS(handlersCmd, runCreateChecksHandler, handlersCreateStrings, client, requireSession, mutating)
 	createHandlersCmd.AddStringFlag(StringFlagOpts{Name: "type", Description: "The type of handler to create, can be slack or pagerduty"})
 	createHandlersCmd.AddStringFlag(StringFlagOpts{Name: "organization", Shorthand: "o", Description: "The organization to add the handler to"})
 
 	handlersDeleteStrings := docstrings.Get("checks.handlers.delete")
-	deleteHandlerCmd := BuildCommandKS(handlersCmd, runDeleteChecksHandler, handlersDeleteStrings, client, requireSession)
+	deleteHandlerCmd := BuildCommandKS(handlersCmd, runDeleteChecksHandler, handlersDeleteStrings, client, requireSession, mutating)
 	deleteHandlerCmd.Args = cobra.ExactArgs(2)
 
 	checksListStrings := docstrings.Get("checks.list")
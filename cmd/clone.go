@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/pkg/errors"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/cmdctx"
+	"github.com/superfly/flyctl/flyctl"
+)
+
+func runAppsClone(ctx *cmdctx.CmdContext) error {
+	sourceName, name := ctx.Args[0], ctx.Args[1]
+	client := ctx.Client.API()
+
+	source, err := client.GetApp(sourceName)
+	if err != nil {
+		return errors.Wrap(err, "Error fetching app")
+	}
+
+	orgSlug, _ := ctx.Config.GetString("org")
+	if orgSlug == "" {
+		orgSlug = source.Organization.Slug
+	}
+	org, err := selectOrganization(client, orgSlug)
+	switch {
+	case isInterrupt(err):
+		return nil
+	case err != nil || org == nil:
+		return fmt.Errorf("Error setting organization: %s", err)
+	}
+
+	serverCfg, err := client.GetConfig(sourceName)
+	if err != nil {
+		return err
+	}
+
+	var secretNames []string
+	if !ctx.Config.GetBool("no-secrets") {
+		secrets, err := client.GetAppSecrets(sourceName)
+		if err != nil {
+			return err
+		}
+		for _, secret := range secrets {
+			secretNames = append(secretNames, secret.Name)
+		}
+	}
+
+	if len(secretNames) > 0 && !ctx.Config.GetBool("yes") {
+		fmt.Println(aurora.Red(fmt.Sprintf("%s has secrets that will be copied to %s: %s", sourceName, name, strings.Join(secretNames, ", "))))
+		if !confirm("Copy these secrets?") {
+			fmt.Println("Not copying secrets, use --no-secrets to skip this question")
+			secretNames = nil
+		}
+	}
+
+	newApp, err := client.CreateApp(name, org.ID, nil)
+	if err != nil {
+		return errors.WithMessage(err, "Failed to create app")
+	}
+	ctx.Statusf("clone", cmdctx.SDONE, "Created %s in organization %s\n", newApp.Name, org.Slug)
+
+	if err := cloneRegions(ctx, sourceName, newApp.Name); err != nil {
+		return err
+	}
+
+	if err := cloneScale(ctx, sourceName, newApp.Name); err != nil {
+		return err
+	}
+
+	if len(secretNames) > 0 {
+		if _, err := client.CopySecrets(sourceName, newApp.Name, secretNames); err != nil {
+			return errors.WithMessage(err, "Failed to copy secrets")
+		}
+		ctx.Statusf("clone", cmdctx.SDONE, "Copied %d secrets\n", len(secretNames))
+	}
+
+	if path, _ := ctx.Config.GetString("save-config"); path != "" {
+		appConfig := flyctl.NewAppConfig()
+		appConfig.AppName = newApp.Name
+		appConfig.Definition = serverCfg.Definition
+		if err := writeAppConfig(path, appConfig); err != nil {
+			return err
+		}
+	}
+
+	if !ctx.Config.GetBool("image") {
+		fmt.Printf("Cloned %s to %s, deploy it with: flyctl deploy -a %s\n", sourceName, newApp.Name, newApp.Name)
+		return nil
+	}
+
+	releases, err := client.GetAppReleases(sourceName, 1)
+	if err != nil {
+		return err
+	}
+	if len(releases) == 0 || releases[0].ImageRef == "" {
+		return fmt.Errorf("%s has no deployed image to clone, deploy %s with: flyctl deploy -a %s", sourceName, newApp.Name, newApp.Name)
+	}
+
+	release, err := client.DeployImage(api.DeployImageInput{
+		AppID:      newApp.Name,
+		Image:      releases[0].ImageRef,
+		Definition: &serverCfg.Definition,
+	})
+	if err != nil {
+		return errors.WithMessage(err, "Failed to deploy image")
+	}
+
+	ctx.Statusf("clone", cmdctx.SDONE, "Deployed %s as release v%d\n", releases[0].ImageRef, release.Version)
+	fmt.Printf("Cloned %s to %s, watch it with: flyctl status -a %s\n", sourceName, newApp.Name, newApp.Name)
+
+	return nil
+}
+
+// cloneRegions gives the clone the source's region pool and backup regions
+func cloneRegions(ctx *cmdctx.CmdContext, sourceName string, name string) error {
+	client := ctx.Client.API()
+
+	regions, backupRegions, err := client.ListAppRegions(sourceName)
+	if err != nil {
+		return err
+	}
+	current, _, err := client.ListAppRegions(name)
+	if err != nil {
+		return err
+	}
+
+	addList, delList := regionChanges(current, regionCodes(regions))
+	input := api.ConfigureRegionsInput{
+		AppID:         name,
+		AllowRegions:  addList,
+		DenyRegions:   delList,
+		BackupRegions: regionCodes(backupRegions),
+	}
+	if _, _, err := client.ConfigureRegions(input); err != nil {
+		return errors.WithMessage(err, "Failed to set regions")
+	}
+
+	ctx.Statusf("clone", cmdctx.SDONE, "Set regions to %s\n", strings.Join(regionCodes(regions), ", "))
+	return nil
+}
+
+// cloneScale gives the clone the source's VM size and the count of each process group
+func cloneScale(ctx *cmdctx.CmdContext, sourceName string, name string) error {
+	client := ctx.Client.API()
+
+	size, counts, err := client.AppVMResources(sourceName)
+	if err != nil {
+		return err
+	}
+
+	if _, err := client.SetAppVMSize(name, size.Name, int64(size.MemoryMB)); err != nil {
+		return errors.WithMessage(err, "Failed to set VM size")
+	}
+
+	input := make([]api.VMCountInput, 0, len(counts))
+	for _, count := range counts {
+		input = append(input, api.VMCountInput{Group: count.Name, Count: count.Count})
+	}
+	if len(input) > 0 {
+		_, warnings, err := client.SetAppVMCounts(name, input)
+		if err != nil {
+			return errors.WithMessage(err, "Failed to set VM counts")
+		}
+		for _, warning := range warnings {
+			ctx.Statusf("clone", cmdctx.SWARN, "%s\n", warning)
+		}
+	}
+
+	ctx.Statusf("clone", cmdctx.SDONE, "Set VM size to %s with %s memory\n", size.Name, formatMemory(size))
+	return nil
+}
+
+func regionCodes(regions []api.Region) []string {
+	codes := make([]string, 0, len(regions))
+	for _, r := range regions {
+		codes = append(codes, r.Code)
+	}
+	return codes
+}
@@ -8,6 +8,7 @@ import (
 	"os/signal"
 	"path"
 	"path/filepath"
+	"strings"
 	"syscall"
 
 	"github.com/superfly/flyctl/cmdctx"
@@ -19,6 +20,7 @@ import (
 	"github.com/spf13/viper"
 	"github.com/superfly/flyctl/helpers"
 	"github.com/superfly/flyctl/internal/client"
+	"github.com/superfly/flyctl/internal/exithooks"
 	"github.com/superfly/flyctl/terminal"
 )
 
@@ -208,6 +210,7 @@ func BuildCommand(parent *Command, fn RunFn, usageText string, shortHelpText str
 			}
 
 			err = fn(ctx)
+			runExitHooks(ctx, cmd, err)
 			checkErr(err)
 		}
 	}
@@ -228,6 +231,18 @@ func requireSession(cmd *Command) Initializer {
 	}
 }
 
+// mutating blocks a command that changes apps or organizations when read-only mode is on
+func mutating(cmd *Command) Initializer {
+	return Initializer{
+		PreRun: func(ctx *cmdctx.CmdContext) error {
+			if viper.GetBool(flyctl.ConfigReadOnly) {
+				return fmt.Errorf("`%s` makes changes and is blocked in read-only mode. Drop --read-only or unset FLY_READ_ONLY to run it", cmd.CommandPath())
+			}
+			return nil
+		},
+	}
+}
+
 func requireAppName(cmd *Command) Initializer {
 	// TODO: Add Flags to docStrings
 
@@ -244,6 +259,11 @@ func requireAppName(cmd *Command) Initializer {
 		Default:     defaultConfigFilePath,
 		EnvName:     "FLY_APP_CONFIG",
 	})
+	cmd.AddStringFlag(StringFlagOpts{
+		Name:        "environment",
+		Description: "Environment to load the app config for, from fly.<environment>.toml or an [environments.<environment>] section",
+		EnvName:     "FLY_ENVIRONMENT",
+	})
 
 	return Initializer{
 		Setup: func(ctx *cmdctx.CmdContext) error {
@@ -263,18 +283,8 @@ func requireAppName(cmd *Command) Initializer {
 			if err != nil {
 				return err
 			}
-			ctx.ConfigFile = resolvedPath
-
-			// load the config file if it exists
-			if helpers.FileExists(ctx.ConfigFile) {
-				terminal.Debug("Loading app config from", ctx.ConfigFile)
-				appConfig, err := flyctl.LoadAppConfig(ctx.ConfigFile)
-				if err != nil {
-					return err
-				}
-				ctx.AppConfig = appConfig
-			} else {
-				ctx.AppConfig = flyctl.NewAppConfig()
+			if err := loadAppConfigFile(ctx, resolvedPath); err != nil {
+				return err
 			}
 
 			// set the app name if provided
@@ -288,11 +298,11 @@ func requireAppName(cmd *Command) Initializer {
 			return nil
 		},
 		PreRun: func(ctx *cmdctx.CmdContext) error {
-			if ctx.AppName == "" {
+			if ctx.AppName == "" && ctx.Selector == "" {
 				return fmt.Errorf("No app specified. Specify an app or create an app with '" + flyname.Name() + " init'")
 			}
 
-			if ctx.AppConfig == nil {
+			if ctx.AppConfig == nil || ctx.Selector != "" {
 				return nil
 			}
 
@@ -318,6 +328,51 @@ func requireAppName(cmd *Command) Initializer {
 	}
 }
 
+// loadAppConfigFile loads configFile into ctx, or the config for --environment when it's set. With
+// --template-values, the files are rendered as Go templates first. An app without a config file
+// gets an empty config.
+func loadAppConfigFile(ctx *cmdctx.CmdContext, configFile string) error {
+	ctx.ConfigFile = configFile
+
+	var values map[string]interface{}
+	if valuesFile, _ := ctx.Config.GetString("template-values"); valuesFile != "" {
+		var err error
+		if values, err = flyctl.LoadTemplateValues(valuesFile); err != nil {
+			return err
+		}
+	}
+
+	environment, _ := ctx.Config.GetString("environment")
+	if environment != "" || values != nil {
+		appConfig, loadedFile, err := flyctl.LoadAppConfigTemplate(configFile, environment, values)
+		if err != nil {
+			return err
+		}
+		if environment != "" {
+			terminal.Debugf("Loaded app config for the %s environment from %s\n", environment, loadedFile)
+		} else {
+			terminal.Debugf("Rendered app config template %s\n", loadedFile)
+		}
+		ctx.ConfigFile = loadedFile
+		ctx.AppConfig = appConfig
+		return nil
+	}
+
+	// load the config file if it exists
+	if helpers.FileExists(ctx.ConfigFile) {
+		terminal.Debug("Loading app config from", ctx.ConfigFile)
+		appConfig, err := flyctl.LoadAppConfig(ctx.ConfigFile)
+		if err != nil {
+			return err
+		}
+		ctx.AppConfig = appConfig
+	} else {
+		ctx.AppConfig = flyctl.NewAppConfig()
+	}
+
+	return nil
+}
+
 func requireAppNameAsArg(cmd *Command) Initializer {
 	cmd.AddStringFlag(StringFlagOpts{
 		Name:        "app",
@@ -333,6 +388,11 @@ func requireAppNameAsArg(cmd *Command) Initializer {
 		Default:     defaultConfigFilePath,
 		EnvName:     "FLY_APP_CONFIG",
 	})
+	cmd.AddStringFlag(StringFlagOpts{
+		Name:        "environment",
+		Description: "Environment to load the app config for, from fly.<environment>.toml or an [environments.<environment>] section",
+		EnvName:     "FLY_ENVIRONMENT",
+	})
 
 	return Initializer{
 		Setup: func(ctx *cmdctx.CmdContext) error {
@@ -352,18 +412,8 @@ func requireAppNameAsArg(cmd *Command) Initializer {
 			if err != nil {
 				return err
 			}
-			ctx.ConfigFile = resolvedPath
-
-			// load the config file if it exists
-			if helpers.FileExists(ctx.ConfigFile) {
-				terminal.Debug("Loading app config from", ctx.ConfigFile)
-				appConfig, err := flyctl.LoadAppConfig(ctx.ConfigFile)
-				if err != nil {
-					return err
-				}
-				ctx.AppConfig = appConfig
-			} else {
-				ctx.AppConfig = flyctl.NewAppConfig()
+			if err := loadAppConfigFile(ctx, resolvedPath); err != nil {
+				return err
 			}
 
 			// set the app name if provided
@@ -381,11 +431,11 @@ func requireAppNameAsArg(cmd *Command) Initializer {
 				ctx.AppName = ctx.Args[0]
 			}
 
-			if ctx.AppName == "" {
+			if ctx.AppName == "" && ctx.Selector == "" {
 				return fmt.Errorf("No app specified")
 			}
 
-			if ctx.AppConfig == nil {
+			if ctx.AppConfig == nil || ctx.Selector != "" {
 				return nil
 			}
 
@@ -411,6 +461,30 @@ func requireAppNameAsArg(cmd *Command) Initializer {
 	}
 }
 
+// selectsApps adds the --selector flag, picking apps by label instead of by name. The command's
+// RunFn is wrapped with forEachSelectedApp to run for each of them.
+func selectsApps(cmd *Command) Initializer {
+	cmd.AddStringFlag(StringFlagOpts{
+		Name:        "selector",
+		Shorthand:   "l",
+		Description: "Run for every app with matching labels, like env=staging,team=payments",
+	})
+
+	return Initializer{
+		Setup: func(ctx *cmdctx.CmdContext) error {
+			ctx.Selector, _ = ctx.Config.GetString("selector")
+			return nil
+		},
+	}
+}
+
+// changesSelectedApps is selectsApps for commands changing the apps, which are confirmed before
+// they run unless --yes is given. The RunFn is wrapped with forEachConfirmedApp.
+func changesSelectedApps(cmd *Command) Initializer {
+	cmd.AddBoolFlag(BoolFlagOpts{Name: "yes", Shorthand: "y", Description: "Accept all confirmations"})
+	return selectsApps(cmd)
+}
+
 func checkAliasFile(appname string) (present bool, err error) {
 	if helpers.FileExists("fly.alias") {
 		file, err := os.Open("fly.alias")
@@ -462,6 +536,31 @@ func workingDirectoryFromArg(index int) func(*Command) Initializer {
 	}
 }
 
+// runExitHooks runs the hooks configured in the flyctl config file for how a command finished.
+// Hook failures are reported but don't change the command's outcome.
+func runExitHooks(ctx *cmdctx.CmdContext, cmd *cobra.Command, cmdErr error) {
+	cfg, err := exithooks.Load()
+	if err != nil {
+		terminal.Warn(err)
+		return
+	}
+
+	result := exithooks.Result{
+		// the command path without the binary name, like "ssh console"
+		Command: strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()+" "),
+		AppName: ctx.AppName,
+		Err:     cmdErr,
+		Values:  ctx.Results,
+	}
+
+	for _, hook := range cfg.Matching(result) {
+		terminal.Debugf("running exit hook %s\n", hook.Run)
+		if err := exithooks.Run(context.Background(), hook, result, ctx.WorkingDir, ctx.IO.Out, ctx.IO.ErrOut); err != nil {
+			terminal.Warn(err)
+		}
+	}
+}
+
 func createCancellableContext() context.Context {
 	signals := make(chan os.Signal)
 	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
@@ -3,6 +3,13 @@ package cmd
 import (
 	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/superfly/flyctl/api"
 
 	"github.com/superfly/flyctl/cmdctx"
 	"github.com/superfly/flyctl/internal/client"
@@ -10,8 +17,8 @@ import (
 	"github.com/superfly/flyctl/docstrings"
 
 	"github.com/logrusorgru/aurora"
-	"github.com/superfly/flyctl/api"
 	"github.com/superfly/flyctl/flyctl"
+	"github.com/superfly/flyctl/flyname"
 	"github.com/superfly/flyctl/helpers"
 )
 
@@ -24,11 +31,32 @@ func newConfigCommand(client *client.Client) *Command {
 	configDisplayStrings := docstrings.Get("config.display")
 	BuildCommandKS(cmd, runDisplayConfig, configDisplayStrings, client, requireSession, requireAppName)
 
+	configShowStrings := docstrings.Get("config.show")
+	showCmd := BuildCommandKS(cmd, runShowConfig, configShowStrings, client, requireAppName)
+	showCmd.AddBoolFlag(BoolFlagOpts{
+		Name:        "resolved",
+		Description: "Show the config with its includes and the --environment overlay merged in",
+	})
+
+	configDiffStrings := docstrings.Get("config.diff")
+	BuildCommandKS(cmd, runDiffConfig, configDiffStrings, client, requireSession, requireAppName)
+
+	configSchemaStrings := docstrings.Get("config.schema")
+	BuildCommandKS(cmd, runConfigSchema, configSchemaStrings, client)
+
 	configSaveStrings := docstrings.Get("config.save")
-	BuildCommandKS(cmd, runSaveConfig, configSaveStrings, client, requireSession, requireAppName)
+	saveCmd := BuildCommandKS(cmd, runSaveConfig, configSaveStrings, client, requireSession, requireAppName)
+	saveCmd.AddBoolFlag(BoolFlagOpts{
+		Name:        "all",
+		Description: "Also save the app's scale, VM size and required secrets as config, with its IPs and volumes noted in comments",
+	})
 
 	configValidateStrings := docstrings.Get("config.validate")
-	BuildCommandKS(cmd, runValidateConfig, configValidateStrings, client, requireSession, requireAppName)
+	validateCmd := BuildCommandKS(cmd, runValidateConfig, configValidateStrings, client, requireSession, requireAppName)
+	validateCmd.AddBoolFlag(BoolFlagOpts{
+		Name:        "local",
+		Description: "Only check the config against flyctl's copy of the platform schema, without contacting the Fly service",
+	})
 
 	return cmd
 }
@@ -46,6 +74,92 @@ func runDisplayConfig(ctx *cmdctx.CmdContext) error {
 	return nil
 }
 
+func runShowConfig(ctx *cmdctx.CmdContext) error {
+	if ctx.AppConfig == nil {
+		return fmt.Errorf("no config file found at %s", helpers.PathRelativeToCWD(ctx.ConfigFile))
+	}
+
+	format := flyctl.ConfigFormatFromPath(ctx.ConfigFile)
+	if ctx.OutputJSON() {
+		format = flyctl.JSONFormat
+	}
+
+	if !ctx.Config.GetBool("resolved") {
+		if format != flyctl.ConfigFormatFromPath(ctx.ConfigFile) {
+			return errors.New("--json shows the resolved config, use it with --resolved")
+		}
+		source, err := ioutil.ReadFile(ctx.ConfigFile)
+		if err != nil {
+			return err
+		}
+		_, err = ctx.Out.Write(source)
+		return err
+	}
+
+	for _, include := range ctx.AppConfig.Includes {
+		fmt.Fprintf(ctx.IO.ErrOut, "Included %s\n", helpers.PathRelativeToCWD(include))
+	}
+	return ctx.AppConfig.WriteTo(ctx.Out, format)
+}
+
+func runDiffConfig(ctx *cmdctx.CmdContext) error {
+	if ctx.AppConfig == nil {
+		return fmt.Errorf("no config file found at %s", helpers.PathRelativeToCWD(ctx.ConfigFile))
+	}
+
+	current, err := ctx.Client.API().GetConfig(ctx.AppName)
+	if err != nil {
+		return fmt.Errorf("error fetching the deployed config: %w", err)
+	}
+
+	// the local config is parsed by the server first, as deploy does, so both sides are in the
+	// same form
+	parsed, err := ctx.Client.API().ParseConfig(ctx.AppName, ctx.AppConfig.Definition)
+	if err != nil {
+		return err
+	}
+	file := helpers.PathRelativeToCWD(ctx.ConfigFile)
+	if !parsed.Valid {
+		return fmt.Errorf("%s isn't valid, see the problems with `%s config validate`", file, flyname.Name())
+	}
+
+	changes, err := flyctl.DiffDefinitions(current.Definition, parsed.Definition)
+	if err != nil {
+		return err
+	}
+
+	if ctx.OutputJSON() {
+		ctx.WriteJSON(changes)
+		return nil
+	}
+
+	if len(changes) == 0 {
+		fmt.Fprintf(ctx.Out, "%s matches the config deployed to %s\n", file, ctx.AppName)
+		return nil
+	}
+
+	fmt.Fprintln(ctx.Out, aurora.Bold(fmt.Sprintf("Changes from %s's deployed config to %s", ctx.AppName, file)))
+	for _, change := range changes {
+		line := change.String()
+		switch change.Kind {
+		case flyctl.ChangeAdded:
+			line = aurora.Green(line).String()
+		case flyctl.ChangeRemoved:
+			line = aurora.Red(line).String()
+		default:
+			line = aurora.Yellow(line).String()
+		}
+		fmt.Fprintf(ctx.Out, "  %s\n", line)
+	}
+
+	return nil
+}
+
+func runConfigSchema(ctx *cmdctx.CmdContext) error {
+	ctx.WriteJSON(flyctl.AppConfigJSONSchema())
+	return nil
+}
+
 func runSaveConfig(ctx *cmdctx.CmdContext) error {
 	configfilename, err := flyctl.ResolveConfigFileFromPath(ctx.WorkingDir)
 
@@ -73,37 +187,182 @@ func runSaveConfig(ctx *cmdctx.CmdContext) error {
 
 	ctx.AppConfig.Definition = serverCfg.Definition
 
-	return writeAppConfig(ctx.ConfigFile, ctx.AppConfig)
+	if !ctx.Config.GetBool("all") {
+		return writeAppConfig(ctx.ConfigFile, ctx.AppConfig)
+	}
+
+	snapshot, err := fetchAppSnapshot(ctx)
+	if err != nil {
+		return err
+	}
+	snapshot.apply(ctx.AppConfig)
+
+	if err := helpers.MkdirAll(ctx.ConfigFile); err != nil {
+		return err
+	}
+	file, err := os.Create(ctx.ConfigFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	format := flyctl.ConfigFormatFromPath(ctx.ConfigFile)
+	// JSON has no comments, so the notes are left out
+	if format != flyctl.JSONFormat {
+		for _, line := range snapshot.notes(ctx.AppName) {
+			fmt.Fprintln(file, strings.TrimSpace("# "+line))
+		}
+		fmt.Fprintln(file)
+	}
+	if err := ctx.AppConfig.WriteTo(file, format); err != nil {
+		return err
+	}
+
+	fmt.Println("Wrote config file", helpers.PathRelativeToCWD(ctx.ConfigFile))
+	return nil
+}
+
+// appSnapshot is the state of a live app that isn't in its config
+type appSnapshot struct {
+	VMSize  api.VMSize
+	Counts  []api.TaskGroupCount
+	IPs     []api.IPAddress
+	Volumes []api.Volume
+	Secrets []api.Secret
+}
+
+func fetchAppSnapshot(ctx *cmdctx.CmdContext) (*appSnapshot, error) {
+	client := ctx.Client.API()
+	s := &appSnapshot{}
+	var err error
+
+	if s.VMSize, s.Counts, err = client.AppVMResources(ctx.AppName); err != nil {
+		return nil, fmt.Errorf("error fetching the app's scale: %w", err)
+	}
+	if s.IPs, err = client.GetIPAddresses(ctx.AppName); err != nil {
+		return nil, fmt.Errorf("error fetching the app's IPs: %w", err)
+	}
+	if s.Volumes, err = client.GetVolumes(ctx.AppName); err != nil {
+		return nil, fmt.Errorf("error fetching the app's volumes: %w", err)
+	}
+	if s.Secrets, err = client.GetAppSecrets(ctx.AppName); err != nil {
+		return nil, fmt.Errorf("error fetching the app's secrets: %w", err)
+	}
+
+	return s, nil
+}
+
+// apply adds what the config can hold: secret names become [secrets] required, and each process
+// group's command gets its count and the app's VM size
+func (s *appSnapshot) apply(cfg *flyctl.AppConfig) {
+	if len(s.Secrets) > 0 {
+		names := make([]interface{}, len(s.Secrets))
+		for i, secret := range s.Secrets {
+			names[i] = secret.Name
+		}
+		sort.Slice(names, func(i, j int) bool { return names[i].(string) < names[j].(string) })
+		cfg.Definition["secrets"] = map[string]interface{}{"required": names}
+	}
+
+	processes, ok := cfg.Definition["processes"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for name, command := range processes {
+		group := map[string]interface{}{"command": command}
+		if s.VMSize.Name != "" {
+			group["vm_size"] = s.VMSize.Name
+		}
+		for _, c := range s.Counts {
+			if c.Name == name {
+				group["count"] = c.Count
+			}
+		}
+		processes[name] = group
+	}
+}
+
+// notes describes the state the config can't hold, written as comments at the top of the file
+func (s *appSnapshot) notes(appName string) []string {
+	lines := []string{
+		fmt.Sprintf("Saved from %s on %s", appName, time.Now().UTC().Format(time.RFC3339)),
+		"",
+		fmt.Sprintf("VM size: %s, %s CPU cores, %s", s.VMSize.Name, formatCores(s.VMSize), formatMemory(s.VMSize)),
+	}
+
+	for _, c := range s.Counts {
+		lines = append(lines, fmt.Sprintf("Scale: %s = %d", c.Name, c.Count))
+	}
+	for _, ip := range s.IPs {
+		lines = append(lines, fmt.Sprintf("IP: %s %s", ip.Type, ip.Address))
+	}
+	for _, v := range s.Volumes {
+		lines = append(lines, fmt.Sprintf("Volume: %s %s %dGB in %s", v.Name, v.ID, v.SizeGb, v.Region))
+	}
+
+	return lines
+}
+
+// configValidation is the result of config validate printed with --json
+type configValidation struct {
+	File   string               `json:"file"`
+	Valid  bool                 `json:"valid"`
+	Errors []flyctl.ConfigError `json:"errors"`
 }
 
 func runValidateConfig(commandContext *cmdctx.CmdContext) error {
-	if commandContext.AppConfig == nil {
+	if commandContext.AppConfig == nil || !helpers.FileExists(commandContext.ConfigFile) {
 		return errors.New("App config file not found")
 	}
 
-	commandContext.Status("config", cmdctx.STITLE, "Validating", commandContext.ConfigFile)
+	if !commandContext.OutputJSON() {
+		commandContext.Status("config", cmdctx.STITLE, "Validating", commandContext.ConfigFile)
+	}
 
-	serverCfg, err := commandContext.Client.API().ParseConfig(commandContext.AppName, commandContext.AppConfig.Definition)
-	if err != nil {
-		return err
+	configErrs := commandContext.AppConfig.Validate()
+
+	if len(configErrs) == 0 && !commandContext.Config.GetBool("local") {
+		serverCfg, err := commandContext.Client.API().ParseConfig(commandContext.AppName, commandContext.AppConfig.Definition)
+		if err != nil {
+			return err
+		}
+		if !serverCfg.Valid {
+			configErrs = flyctl.MessageConfigErrors(serverCfg.Errors)
+		}
 	}
 
-	if serverCfg.Valid {
-		fmt.Println(aurora.Green("✓").String(), "Configuration is valid")
-		return nil
+	// lines are only located in fly.toml, not fly.yaml or fly.json
+	if flyctl.ConfigFormatFromPath(commandContext.ConfigFile) == flyctl.TOMLFormat {
+		if source, err := ioutil.ReadFile(commandContext.ConfigFile); err == nil {
+			flyctl.LocateConfigErrors(source, configErrs)
+		}
 	}
 
-	printAppConfigErrors(*serverCfg)
+	if commandContext.OutputJSON() {
+		commandContext.WriteJSON(configValidation{File: commandContext.ConfigFile, Valid: len(configErrs) == 0, Errors: configErrs})
+		if len(configErrs) > 0 {
+			return ErrAbort
+		}
+		return nil
+	}
 
-	return errors.New("App configuration is not valid")
-}
+	if len(configErrs) == 0 {
+		fmt.Println(aurora.Green("✓").String(), "Configuration is valid")
+		return nil
+	}
 
-func printAppConfigErrors(cfg api.AppConfig) {
 	fmt.Println()
-	for _, error := range cfg.Errors {
-		fmt.Println("   ", aurora.Red("✘").String(), error)
+	file := helpers.PathRelativeToCWD(commandContext.ConfigFile)
+	for _, e := range configErrs {
+		location := file
+		if e.Line > 0 {
+			location = fmt.Sprintf("%s:%d", file, e.Line)
+		}
+		fmt.Println("   ", aurora.Red("✘").String(), location, e.Error())
 	}
 	fmt.Println()
+
+	return errors.New("App configuration is not valid")
 }
 
 func writeAppConfig(path string, appConfig *flyctl.AppConfig) error {
@@ -116,3 +375,17 @@ func writeAppConfig(path string, appConfig *flyctl.AppConfig) error {
 
 	return nil
 }
+
+// configSectionError prints the problems found in a section of fly.toml and returns the error
+// that stops the command
+func configSectionError(cmdCtx *cmdctx.CmdContext, section string, errs []flyctl.ConfigError) error {
+	printConfigErrors(cmdCtx, errs)
+	return fmt.Errorf("invalid %s section in fly.toml", section)
+}
+
+// printConfigErrors prints problems with fly.toml, like config validate does
+func printConfigErrors(cmdCtx *cmdctx.CmdContext, errs []flyctl.ConfigError) {
+	for _, e := range errs {
+		cmdCtx.Status("config", cmdctx.SERROR, "   ", aurora.Red("✘").String(), e.Error())
+	}
+}
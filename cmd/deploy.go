@@ -3,30 +3,41 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/dustin/go-humanize"
 	"github.com/logrusorgru/aurora"
 	"github.com/morikuni/aec"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
 	"github.com/superfly/flyctl/api"
 	"github.com/superfly/flyctl/cmd/presenters"
 	"github.com/superfly/flyctl/cmdctx"
 	"github.com/superfly/flyctl/docstrings"
 	"github.com/superfly/flyctl/flyctl"
+	"github.com/superfly/flyctl/flyname"
+	"github.com/superfly/flyctl/helpers"
 	"github.com/superfly/flyctl/internal/build/imgsrc"
 	"github.com/superfly/flyctl/internal/client"
 	"github.com/superfly/flyctl/internal/cmdfmt"
 	"github.com/superfly/flyctl/internal/cmdutil"
 	"github.com/superfly/flyctl/internal/deployment"
+	"github.com/superfly/flyctl/internal/registry"
+	"github.com/superfly/flyctl/terminal"
 )
 
 func newDeployCommand(client *client.Client) *Command {
 	deployStrings := docstrings.Get("deploy")
-	cmd := BuildCommandKS(nil, runDeploy, deployStrings, client, workingDirectoryFromArg(0), requireSession, requireAppName)
+	cmd := BuildCommandKS(nil, runDeploy, deployStrings, client, workingDirectoryFromArg(0), requireSession, requireAppName, mutating)
 	cmd.AddStringFlag(StringFlagOpts{
 		Name:        "image",
 		Shorthand:   "i",
@@ -48,9 +59,67 @@ func newDeployCommand(client *client.Client) *Command {
 		Name:        "local-only",
 		Description: "Only perform builds locally using the local docker daemon",
 	})
+	cmd.AddStringFlag(builderFlag)
+	cmd.AddBoolFlag(BoolFlagOpts{
+		Name:        "no-build",
+		Description: "Deploy an existing image without building, from --image, image in the [build] section of fly.toml, or FLY_IMAGE_REF. A digest in the reference, like app@sha256:..., must match the image found",
+	})
+	cmd.AddBoolFlag(BoolFlagOpts{
+		Name:        "config-only",
+		Description: "Deploy fly.toml changes as a new release of the current release's image, without building or pushing one",
+	})
+	cmd.AddStringFlag(StringFlagOpts{
+		Name:        "template-values",
+		Description: "Path to a YAML or JSON file of values to render fly.toml with as a Go template, like app = \"{{ .name }}\"",
+	})
+	cmd.AddStringFlag(StringFlagOpts{
+		Name:        "signature-key",
+		Description: "Path to a cosign public key. The image must have a cosign signature made with it, and can't be built by the deploy",
+	})
+	cmd.AddBoolFlag(BoolFlagOpts{
+		Name:        "dry-run",
+		Description: "Show how the image would be built and the config changes that would be applied, without building or deploying",
+	})
+	cmd.AddStringFlag(StringFlagOpts{
+		Name:        "git",
+		Description: "Build from a git repository instead of the working directory, like https://github.com/org/repo#v1.2.3. Only the ref's commit is fetched. Builds run on a remote builder unless --local-only is set",
+	})
 	cmd.AddStringFlag(StringFlagOpts{
 		Name:        "strategy",
-		Description: "The strategy for replacing running instances. Options are canary, rolling, bluegreen, or immediate. Default is canary",
+		Description: "The strategy for replacing running instances. Options are canary, rolling, bluegreen, immediate, or websocket for apps with long-lived connections. Overrides strategy in the [deploy] section of fly.toml. Default is canary",
+	})
+	cmd.AddStringFlag(StringFlagOpts{
+		Name:        "canary-verify",
+		Description: "Command to run against the canary instance once it's healthy, like ./smoke-test.sh. The rest of the instances are replaced when it succeeds, and the deployment is rolled back when it fails. Overrides canary_verify in the [deploy] section of fly.toml",
+	})
+	cmd.AddStringFlag(StringFlagOpts{
+		Name:        "smoke-test",
+		Description: "Command to run once the first new instance is healthy, with its private address in FLY_INSTANCE_ADDR. The rest of the instances are replaced when it succeeds, and the deployment is rolled back when it fails. Overrides command in the [deploy.smoke_test] section of fly.toml",
+	})
+	cmd.AddStringFlag(StringFlagOpts{
+		Name:        "smoke-test-http",
+		Description: "Path to GET from the first new instance once it's healthy, like /healthz. The rest of the instances are replaced when it responds with a 2xx status, and the deployment is rolled back when it doesn't. Overrides http_path in the [deploy.smoke_test] section of fly.toml",
+	})
+	addDeployLockFlags(cmd)
+	cmd.AddStringSliceFlag(StringSliceFlagOpts{
+		Name:        "only-regions",
+		Description: "Deploy the new release to these regions only, like iad,ord. Instances in the app's other regions keep running the release they're on",
+	})
+	cmd.AddStringSliceFlag(StringSliceFlagOpts{
+		Name:        "rollout-order",
+		Description: "Deploy to one region at a time in this order, like iad,lhr,syd, halting and rolling back if a region's new instances fail their checks. Regions that aren't listed are deployed to last. Overrides rollout_order in the [deploy] section of fly.toml",
+	})
+	cmd.AddStringFlag(StringFlagOpts{
+		Name:        "bake-time",
+		Description: "How long each region's new instances must stay healthy before the rollout moves to the next region, like 5m. Overrides bake_time in the [deploy] section of fly.toml",
+	})
+	cmd.AddBoolFlag(BoolFlagOpts{
+		Name:        "no-auto-rollback",
+		Description: "Keep monitoring when new instances fail their health checks, instead of rolling back once auto_rollback_threshold of them are failing",
+	})
+	cmd.AddBoolFlag(BoolFlagOpts{
+		Name:        "manual-promote",
+		Description: "With the bluegreen strategy, keep traffic on the old instances once the new ones are healthy until the release is promoted with `releases promote`. Overrides manual_promote in the [deploy] section of fly.toml",
 	})
 	cmd.AddStringFlag(StringFlagOpts{
 		Name:        "dockerfile",
@@ -69,148 +138,1261 @@ func newDeployCommand(client *client.Client) *Command {
 		Name:        "image-label",
 		Description: "Image label to use when tagging and pushing to the fly registry. Defaults to \"deployment-{timestamp}\".",
 	})
+	cmd.AddStringFlag(StringFlagOpts{
+		Name:        "build-output",
+		Description: "Format for build progress output. Options are text or json, which keeps stdout for the build progress. Default is text",
+		Default:     imgsrc.BuildOutputText,
+	})
+	cmd.AddStringFlag(StringFlagOpts{
+		Name:        "build-timeout",
+		Description: "Maximum time the image build may take, like 30m. Overrides timeout in the [build] section of fly.toml. No limit by default",
+	})
+	cmd.AddStringFlag(StringFlagOpts{
+		Name:        "builder-timeout",
+		Description: "Maximum time to wait for the remote builder to become ready, like 1m. Overrides builder_timeout in the [build] section of fly.toml. Default is 5m",
+	})
+	cmd.AddStringFlag(StringFlagOpts{
+		Name:        "build-memory",
+		Description: "Maximum memory the build may use, like 4gb. Local builds are capped and remote builders are sized to fit",
+	})
+	cmd.AddStringFlag(StringFlagOpts{
+		Name:        "build-cpus",
+		Description: "Maximum cpus the build may use, like 2 or 1.5. Local builds are capped and remote builders are sized to fit",
+	})
+	cmd.AddBoolFlag(BoolFlagOpts{
+		Name:        "scan",
+		Description: "Scan the image for vulnerabilities before deploying it, failing the deploy if any are found at --scan-severity or worse",
+	})
+	cmd.AddStringFlag(StringFlagOpts{
+		Name:        "scan-severity",
+		Description: "Lowest vulnerability severity that fails a scan: LOW, MEDIUM, HIGH or CRITICAL. Default is HIGH",
+	})
+	cmd.AddStringFlag(StringFlagOpts{
+		Name:        "scan-image",
+		Description: "Trivy image to scan with, like aquasec/trivy:0.55.2@sha256:... Overrides scan_image in the [build] section of fly.toml",
+		EnvName:     "FLY_SCAN_IMAGE",
+	})
+	cmd.AddStringSliceFlag(StringSliceFlagOpts{
+		Name:        "ssh",
+		Description: "SSH agent socket or keys to expose to the build, like default or id=path[,path]. Used by RUN --mount=type=ssh in the Dockerfile. Can be specified multiple times.",
+	})
+	cmd.AddBoolFlag(BoolFlagOpts{
+		Name:        "reproducible",
+		Description: "Build so the same source produces the same image digest, using SOURCE_DATE_EPOCH or the last git commit time for file timestamps",
+	})
+	cmd.AddBoolFlag(BoolFlagOpts{
+		Name:        "require-approvals",
+		Description: "Hold the release until another member of the organization approves it with `deploys approve`",
+	})
+	cmd.AddStringFlag(approvalWebhookFlag)
+	cmd.AddIntFlag(pushConcurrencyFlag)
+	cmd.AddIntFlag(IntFlagOpts{
+		Name:        "compression-level",
+		Description: "Gzip level from 1 (fastest) to 9 (smallest) for the build context and the layers flyctl pushes. Overrides compression_level in the [build] section of fly.toml",
+	})
+	cmd.AddStringFlag(StringFlagOpts{
+		Name:        "drain-timeout",
+		Description: "How long instances of the previous release may drain open connections before they're killed, like 30s. Overrides kill_timeout in fly.toml for this deploy",
+	})
 
 	cmd.Command.Args = cobra.MaximumNArgs(1)
 
 	return cmd
 }
 
-func runDeploy(cmdCtx *cmdctx.CmdContext) error {
+func runDeploy(cmdCtx *cmdctx.CmdContext) (err error) {
 	ctx := createCancellableContext()
 
-	cmdCtx.Status("deploy", cmdctx.STITLE, "Deploying", cmdCtx.AppName)
+	restoreOutput := cmdCtx.StreamEvents()
+	// --build-output json on its own keeps stdout for the build events the same way
+	var buildEvents io.Writer
+	if buildOutput, _ := cmdCtx.Config.GetString("build-output"); buildOutput == imgsrc.BuildOutputJSON && !cmdCtx.OutputJSON() {
+		buildEvents, restoreOutput = cmdCtx.ReserveStdout()
+	}
+	defer func() {
+		if err != nil && cmdCtx.OutputJSON() && !isCancelledError(err) {
+			// report the error as an event too, so stdout stays lines of JSON
+			cmdCtx.Emit("error", map[string]string{"message": err.Error()})
+			fmt.Fprintln(cmdCtx.IO.ErrOut, aurora.Red("Error"), err)
+			err = ErrAbort
+		}
+		restoreOutput()
+	}()
+
+	if cmdCtx.AppConfig != nil && cmdCtx.AppConfig.Environment != "" {
+		cmdCtx.Statusf("deploy", cmdctx.STITLE, "Deploying %s (%s environment)\n", cmdCtx.AppName, cmdCtx.AppConfig.Environment)
+	} else {
+		cmdCtx.Status("deploy", cmdctx.STITLE, "Deploying", cmdCtx.AppName)
+	}
+
+	gitSource, _ := cmdCtx.Config.GetString("git")
+	if gitSource != "" {
+		dir, err := checkoutGitSource(ctx, cmdCtx, gitSource)
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+	}
+
+	plan, err := resolveDeployPlan(cmdCtx)
+	if err != nil {
+		return err
+	}
+
+	resolver, opts, err := resolveBuild(cmdCtx, gitSource, buildEvents)
+	if err != nil {
+		return err
+	}
+
+	if !cmdCtx.Config.GetBool("dry-run") && !cmdCtx.Config.GetBool("build-only") {
+		var release func()
+		if ctx, release, err = holdDeployLock(ctx, cmdCtx); err != nil {
+			return err
+		}
+		defer release()
+	}
+
+	img, groupImages, err := resolveImage(ctx, cmdCtx, plan, resolver, opts, gitSource)
+	if err != nil || img == nil {
+		// there's no image after a dry run, which only prints the plan
+		return err
+	}
+
+	if cmdCtx.Config.GetBool("build-only") {
+		cmdCtx.Emit("image_built", imageEvent{Image: img.Tag, Digest: img.Digest, Size: img.Size})
+		return nil
+	}
+	if err := publishImage(ctx, cmdCtx, img); err != nil {
+		return err
+	}
 
+	release, err := createRelease(cmdCtx, plan, img, groupImages)
+	if err != nil {
+		return err
+	}
+
+	// apps can be set to always require approval, so check the release rather than the flag
+	if !release.PendingApproval() {
+		defer func() {
+			runDeployHooks(cmdCtx, plan.deployCfg, deployment.Outcome{
+				App:      cmdCtx.AppName,
+				Version:  release.Version,
+				Image:    img.Tag,
+				Author:   release.User.Email,
+				Err:      err,
+				Detached: err == nil && !followsDeployment(cmdCtx, release),
+			})
+		}()
+	}
+
+	return monitorRelease(ctx, cmdCtx, plan, release, img.Tag)
+}
+
+// deployPlan is what a deploy resolved from fly.toml and the flags before building anything
+type deployPlan struct {
+	deployCfg       *flyctl.DeployConfig
+	strategy        string
+	killTimeout     *int
+	onlyRegions     []string
+	regionOverrides []api.RegionOverrideInput
+	processGroups   []flyctl.ProcessGroup
+}
+
+// resolveDeployPlan validates the app's configuration, locally and with the API, applies the
+// deploy flags to it, and prints what will be deployed
+func resolveDeployPlan(cmdCtx *cmdctx.CmdContext) (*deployPlan, error) {
 	cmdfmt.PrintBegin(cmdCtx.Out, "Validating app configuration")
 
-	if cmdCtx.AppConfig == nil {
-		cmdCtx.AppConfig = flyctl.NewAppConfig()
+	if cmdCtx.AppConfig == nil {
+		cmdCtx.AppConfig = flyctl.NewAppConfig()
+	}
+
+	if extraEnv := cmdCtx.Config.GetStringSlice("env"); len(extraEnv) > 0 {
+		parsedEnv, err := cmdutil.ParseKVStringsToMap(cmdCtx.Config.GetStringSlice("env"))
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid env")
+		}
+		cmdCtx.AppConfig.SetEnvVariables(parsedEnv)
+	}
+
+	routes, errs := cmdCtx.AppConfig.Routes()
+	if len(errs) > 0 {
+		return nil, configSectionError(cmdCtx, "[[services.routes]]", errs)
+	}
+
+	restartSchedule, errs := cmdCtx.AppConfig.RestartSchedule()
+	if len(errs) > 0 {
+		return nil, configSectionError(cmdCtx, "[restart]", errs)
+	}
+
+	warmups, errs := cmdCtx.AppConfig.WarmupRequests()
+	if len(errs) > 0 {
+		return nil, configSectionError(cmdCtx, "[[services.warmup]]", errs)
+	}
+
+	deployCfg, errs := cmdCtx.AppConfig.DeployConfig()
+	if len(errs) > 0 {
+		return nil, configSectionError(cmdCtx, "[deploy]", errs)
+	}
+	plan := &deployPlan{deployCfg: deployCfg, strategy: deployCfg.Strategy}
+
+	if val, _ := cmdCtx.Config.GetString("strategy"); val != "" {
+		if err := flyctl.ValidateStrategy(val); err != nil {
+			return nil, err
+		}
+		plan.strategy = strings.ToLower(val)
+	}
+
+	if val, _ := cmdCtx.Config.GetString("canary-verify"); val != "" {
+		deployCfg.CanaryVerify = val
+	}
+	if deployCfg.CanaryVerify != "" {
+		if plan.strategy == "" {
+			plan.strategy = flyctl.StrategyCanary
+		}
+		switch {
+		case plan.strategy != flyctl.StrategyCanary:
+			return nil, fmt.Errorf("canary verification needs the canary strategy, not %s", plan.strategy)
+		case cmdCtx.Config.GetBool("detach"):
+			return nil, errors.New("canary verification can't be used with --detach, the deployment waits while flyctl runs it")
+		case cmdCtx.Config.GetBool("require-approvals"):
+			return nil, errors.New("canary verification can't be used with --require-approvals")
+		}
+	}
+
+	if cmdCtx.Config.GetBool("manual-promote") {
+		deployCfg.ManualPromote = true
+	}
+	if deployCfg.ManualPromote {
+		if plan.strategy == "" {
+			plan.strategy = flyctl.StrategyBlueGreen
+		}
+		if plan.strategy != flyctl.StrategyBlueGreen {
+			return nil, fmt.Errorf("manual promotion needs the bluegreen strategy, not %s", plan.strategy)
+		}
+	}
+
+	var err error
+	if err = resolveSmokeTest(cmdCtx, deployCfg, plan.strategy); err != nil {
+		return nil, err
+	}
+	if err = resolveRollout(cmdCtx, deployCfg, plan.strategy); err != nil {
+		return nil, err
+	}
+	if plan.onlyRegions, err = resolveOnlyRegions(cmdCtx, deployCfg); err != nil {
+		return nil, err
+	}
+	if plan.regionOverrides, err = resolveRegionOverrides(cmdCtx); err != nil {
+		return nil, err
+	}
+	if plan.processGroups, err = resolveProcessGroups(cmdCtx); err != nil {
+		return nil, err
+	}
+	if err = checkRequiredSecrets(cmdCtx); err != nil {
+		return nil, err
+	}
+	if plan.killTimeout, err = drainTimeout(cmdCtx); err != nil {
+		return nil, err
+	}
+
+	parsedCfg, err := cmdCtx.Client.API().ParseConfig(cmdCtx.AppName, cmdCtx.AppConfig.Definition)
+	if err != nil {
+		if parsedCfg == nil {
+			// No error data has been returned
+			return nil, fmt.Errorf("not possible to validate configuration: server returned %s", err)
+		}
+		printConfigErrors(cmdCtx, flyctl.MessageConfigErrors(parsedCfg.Errors))
+		return nil, err
+	}
+	cmdCtx.AppConfig.Definition = parsedCfg.Definition
+	cmdfmt.PrintDone(cmdCtx.Out, "Validating app configuration done")
+
+	if parsedCfg.Valid && len(parsedCfg.Services) > 0 {
+		cmdfmt.PrintServicesList(cmdCtx.IO, parsedCfg.Services)
+	}
+
+	if len(routes) > 0 {
+		cmdfmt.PrintRoutesList(cmdCtx.IO, routes)
+	}
+
+	if len(warmups) > 0 {
+		cmdfmt.PrintWarmupList(cmdCtx.IO, warmups)
+	}
+
+	if restartSchedule != nil {
+		cmdfmt.PrintRestartSchedule(cmdCtx.IO, restartSchedule)
+	}
+
+	if plan.strategy != "" {
+		cmdfmt.PrintDeployStrategy(cmdCtx.IO, plan.strategy, deployCfg)
+	}
+
+	return plan, nil
+}
+
+// resolveBuild checks the build flags and returns the resolver and options an image is built
+// or resolved with. It's done before the deploy lock is taken, so bad flags fail right away.
+func resolveBuild(cmdCtx *cmdctx.CmdContext, gitSource string, buildEvents io.Writer) (*imgsrc.Resolver, imgsrc.ImageOptions, error) {
+	opts := imgsrc.ImageOptions{
+		AppName:      cmdCtx.AppName,
+		WorkingDir:   cmdCtx.WorkingDir,
+		AppConfig:    cmdCtx.AppConfig,
+		Publish:      !cmdCtx.Config.GetBool("build-only"),
+		ScanImage:    buildScanImage(cmdCtx),
+		Reproducible: cmdCtx.Config.GetBool("reproducible"),
+		SSH:          cmdCtx.Config.GetStringSlice("ssh"),
+	}
+	opts.ImageLabel, _ = cmdCtx.Config.GetString("image-label")
+	if cfg := cmdCtx.AppConfig.Build; cfg != nil && cfg.Reproducible {
+		opts.Reproducible = true
+	}
+
+	opts.BuildOutput, _ = cmdCtx.Config.GetString("build-output")
+	if err := imgsrc.ValidateBuildOutput(opts.BuildOutput); err != nil {
+		return nil, opts, err
+	}
+	// with --json the build progress joins the deploy's events, so stdout has one schema
+	if cmdCtx.OutputJSON() {
+		opts.Events = cmdCtx.Emit
+	} else if buildEvents != nil {
+		opts.Events = imgsrc.JSONLines(buildEvents)
+	}
+
+	buildTimeout, builderTimeout, err := buildTimeouts(cmdCtx)
+	if err != nil {
+		return nil, opts, err
+	}
+	opts.Timeout = buildTimeout
+
+	if opts.ScanSeverity, err = buildScanSeverity(cmdCtx); err != nil {
+		return nil, opts, err
+	}
+	if opts.PushConcurrency, err = pushConcurrency(cmdCtx); err != nil {
+		return nil, opts, err
+	}
+	if opts.CompressionLevel, err = compressionLevel(cmdCtx); err != nil {
+		return nil, opts, err
+	}
+
+	buildMemory, _ := cmdCtx.Config.GetString("build-memory")
+	buildCPUs, _ := cmdCtx.Config.GetString("build-cpus")
+	if opts.Resources, err = imgsrc.ParseBuildResources(buildMemory, buildCPUs); err != nil {
+		return nil, opts, err
+	}
+
+	if dockerfilePath, _ := cmdCtx.Config.GetString("dockerfile"); dockerfilePath != "" {
+		dockerfilePath, err := filepath.Abs(dockerfilePath)
+		if err != nil {
+			return nil, opts, err
+		}
+		opts.DockerfilePath = dockerfilePath
+	} else if cfg := cmdCtx.AppConfig.Build; cfg != nil && cfg.Dockerfile != "" {
+		opts.DockerfilePath = filepath.Join(cmdCtx.WorkingDir, cfg.Dockerfile)
+	}
+
+	if opts.ExtraBuildArgs, err = cmdutil.ParseKVStringsToMap(cmdCtx.Config.GetStringSlice("build-arg")); err != nil {
+		return nil, opts, errors.Wrap(err, "invalid build-arg")
+	}
+
+	// builds from git default to a remote builder, there's no local checkout worth keeping a local cache for
+	remoteOnly := cmdCtx.Config.GetBool("remote-only") || (gitSource != "" && !cmdCtx.Config.GetBool("local-only"))
+	daemonType := imgsrc.NewDockerDaemonType(!remoteOnly, !cmdCtx.Config.GetBool("local-only"))
+	builder, _ := cmdCtx.Config.GetString("builder")
+
+	resolver := imgsrc.NewResolver(daemonType, cmdCtx.Client.API(), cmdCtx.AppName, cmdCtx.IO, builderTimeout, opts.Resources, wireGuardNetwork(cmdCtx), builder)
+	return resolver, opts, nil
+}
+
+// resolveImage finds the image to deploy: the current release's with --config-only, the one
+// given with --image, or one built from source, along with the images of process groups built
+// from their own targets. The image is nil after a dry run, which prints the plan instead.
+func resolveImage(ctx context.Context, cmdCtx *cmdctx.CmdContext, plan *deployPlan, resolver *imgsrc.Resolver, opts imgsrc.ImageOptions, gitSource string) (*imgsrc.DeploymentImage, map[string]string, error) {
+	var img *imgsrc.DeploymentImage
+	// the images of process groups built from their own targets
+	var groupImages map[string]string
+
+	ref, err := deployImageRef(cmdCtx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	signatureKey := plan.deployCfg.SignatureKey
+	if val, _ := cmdCtx.Config.GetString("signature-key"); val != "" {
+		signatureKey = val
+	}
+	// the digest of the image whose signature was verified
+	signedDigest := ""
+
+	configOnly := cmdCtx.Config.GetBool("config-only")
+	if signatureKey != "" && !configOnly && ref == "" {
+		return nil, nil, errors.New("a signature key only allows deploying signed images, deploy one with --image instead of building it")
+	}
+
+	if configOnly {
+		if ref != "" || gitSource != "" || cmdCtx.Config.GetBool("build-only") {
+			return nil, nil, errors.New("--config-only deploys the current release's image, it can't be used with --image, --no-build, --git or --build-only")
+		}
+		img, err = currentReleaseImage(ctx, cmdCtx)
+		if err != nil {
+			return nil, nil, err
+		}
+		if signatureKey != "" {
+			// the release's image is already pinned by digest, so it's the one verified
+			if _, err = verifyImageSignature(ctx, cmdCtx, img.Tag, signatureKey); err != nil {
+				return nil, nil, err
+			}
+		}
+		if cmdCtx.Config.GetBool("dry-run") {
+			return nil, nil, printDeployPlan(cmdCtx, &imgsrc.BuildPlan{Source: imgsrc.PlanSourceRelease, Daemon: "none", Image: img.Tag, Tag: img.Tag})
+		}
+	} else if ref != "" {
+		if signatureKey != "" {
+			signed, err := verifyImageSignature(ctx, cmdCtx, ref, signatureKey)
+			if err != nil {
+				return nil, nil, err
+			}
+			// resolve the signed digest rather than the tag, which could be pushed again after
+			// the signature was checked
+			ref, signedDigest = signed.String(), signed.Digest
+		}
+
+		refOpts := imgsrc.RefOptions{
+			AppName:          opts.AppName,
+			WorkingDir:       opts.WorkingDir,
+			AppConfig:        opts.AppConfig,
+			Publish:          opts.Publish,
+			ImageRef:         ref,
+			ImageLabel:       opts.ImageLabel,
+			BuildOutput:      opts.BuildOutput,
+			Events:           opts.Events,
+			PushConcurrency:  opts.PushConcurrency,
+			CompressionLevel: opts.CompressionLevel,
+		}
+
+		if cmdCtx.Config.GetBool("dry-run") {
+			return nil, nil, printDeployPlan(cmdCtx, resolver.PlanReference(refOpts))
+		}
+
+		cmdCtx.Emit("build_started", map[string]string{"app": cmdCtx.AppName, "image": ref})
+		img, err = resolver.ResolveReference(ctx, cmdCtx.IO, refOpts)
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		if cmdCtx.Config.GetBool("dry-run") {
+			buildPlan, err := resolver.PlanBuild(opts)
+			if err != nil {
+				return nil, nil, err
+			}
+			return nil, nil, printDeployPlan(cmdCtx, buildPlan)
+		}
+
+		started := time.Now()
+		cmdCtx.Emit("build_started", map[string]string{"app": cmdCtx.AppName})
+		img, err = resolver.BuildImage(ctx, cmdCtx.IO, opts)
+		if err != nil {
+			return nil, nil, err
+		}
+		if img == nil {
+			return nil, nil, errors.New("could not find an image to deploy")
+		}
+		if opts.Publish {
+			attachProvenance(ctx, cmdCtx, resolver, opts, img, started)
+		}
+
+		if groupImages, err = buildProcessGroupImages(ctx, cmdCtx, resolver, opts, img, plan.processGroups); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	if img == nil {
+		return nil, nil, errors.New("could not find an image to deploy")
+	}
+	if groupImages == nil && len(processGroupsWithTargets(cmdCtx, plan.processGroups)) > 0 {
+		terminal.Warnf("Process groups with their own build target run %s too, since no image is built\n", img.Tag)
+	}
+	if signedDigest != "" && img.SourceDigest != signedDigest {
+		// a local image is pushed again under a new digest, so it's the digest it was found
+		// with that has to be the signed one
+		return nil, nil, fmt.Errorf("the image found for %s has digest %s, not the signed digest %s", ref, img.SourceDigest, signedDigest)
+	}
+
+	fmt.Fprintf(cmdCtx.Client.IO.Out, "Image: %s\n", img.Tag)
+	if configOnly {
+		fmt.Fprintln(cmdCtx.Client.IO.Out, "Reusing the current release's image, only the config changes")
+	} else {
+		fmt.Fprintf(cmdCtx.Client.IO.Out, "Image size: %s\n", humanize.Bytes(uint64(img.Size)))
+	}
+
+	return img, groupImages, nil
+}
+
+// publishImage reports the pushed image and mirrors it to the registries in push_to. The current
+// release's image, deployed again with --config-only, was already mirrored when it was deployed.
+func publishImage(ctx context.Context, cmdCtx *cmdctx.CmdContext, img *imgsrc.DeploymentImage) error {
+	if cmdCtx.Config.GetBool("config-only") {
+		return nil
+	}
+	cmdCtx.Emit("image_pushed", imageEvent{Image: img.Tag, Digest: img.Digest, Size: img.Size})
+
+	cfg := cmdCtx.AppConfig.Build
+	if cfg == nil {
+		return nil
+	}
+	for _, target := range cfg.PushTo {
+		digest, err := pushImage(ctx, cmdCtx, img.Tag, target)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmdCtx.Out, "Pushed image to %s (%s)\n", target, digest)
+		cmdCtx.Emit("image_pushed", imageEvent{Image: target, Digest: digest})
+	}
+	return nil
+}
+
+// createRelease creates the release of img with the app's configuration and the deploy settings
+func createRelease(cmdCtx *cmdctx.CmdContext, plan *deployPlan, img *imgsrc.DeploymentImage, groupImages map[string]string) (*api.Release, error) {
+	cmdfmt.PrintBegin(cmdCtx.Out, "Creating release")
+
+	deployCfg := plan.deployCfg
+	input := api.DeployImageInput{
+		AppID: cmdCtx.AppName,
+		Image: img.Tag,
+	}
+	if plan.strategy != "" {
+		input.Strategy = api.StringPointer(strings.ToUpper(plan.strategy))
+	}
+	if plan.strategy == flyctl.StrategyWebsocket {
+		maxWait := int(deployCfg.MaxConnectionWait / time.Second)
+		input.ConnectionThreshold = &deployCfg.ConnectionThreshold
+		input.MaxConnectionWait = &maxWait
+	}
+	if cmdCtx.AppConfig != nil && len(cmdCtx.AppConfig.Definition) > 0 {
+		input.Definition = api.DefinitionPtr(cmdCtx.AppConfig.Definition)
+	}
+	input.RequireApproval = cmdCtx.Config.GetBool("require-approvals")
+	input.KillTimeout = plan.killTimeout
+	input.HoldCanary = deployCfg.CanaryVerify != ""
+	input.HoldTraffic = deployCfg.ManualPromote
+	input.HoldFirstInstance = deployCfg.SmokeTest != nil
+	input.RolloutOrder = deployCfg.RolloutOrder
+	input.OnlyRegions = plan.onlyRegions
+	input.RegionOverrides = plan.regionOverrides
+	for _, g := range plan.processGroups {
+		input.ProcessGroups = append(input.ProcessGroups, api.ProcessGroupInput{
+			Name:    g.Name,
+			Command: g.Command,
+			VMSize:  g.VMSize,
+			Count:   g.Count,
+			Image:   groupImages[g.Name],
+			Env:     g.Env,
+			Secrets: g.Secrets,
+		})
+	}
+
+	release, err := cmdCtx.Client.API().DeployImage(input)
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(cmdCtx.Out, "Release v%d created\n", release.Version)
+	if len(plan.onlyRegions) > 0 {
+		fmt.Fprintf(cmdCtx.Out, "Deploying to %s only, instances in other regions stay on their current release until the next deploy\n", strings.Join(plan.onlyRegions, ", "))
+	}
+	cmdCtx.Emit("release_created", releaseEvent{Version: release.Version, Image: img.Tag, Strategy: release.DeploymentStrategy})
+	cmdCtx.SetResult("release_version", strconv.Itoa(release.Version))
+	cmdCtx.SetResult("image", img.Tag)
+
+	return release, nil
+}
+
+// monitorRelease follows a release of image until its deployment finishes, running the canary
+// verification, smoke test and rollout gates and rolling back failing deployments. It returns
+// right away for releases waiting for approval, and with --detach.
+func monitorRelease(ctx context.Context, cmdCtx *cmdctx.CmdContext, plan *deployPlan, release *api.Release, image string) error {
+	deployCfg := plan.deployCfg
+
+	if release.PendingApproval() {
+		event := approvalRequestedEvent(cmdCtx.AppName, release, image)
+		fmt.Fprintf(cmdCtx.Out, "Release v%d is waiting for approval. Another member of the organization can approve it with `%s`\n", release.Version, event.ApproveCommand)
+		notifyApproval(cmdCtx, event)
+		return nil
+	}
+	if err := watchReleaseCommand(ctx, cmdCtx, release); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmdCtx.Out, "Deploying to %s.fly.dev\n", cmdCtx.AppName)
+
+	if release.DeploymentStrategy == "IMMEDIATE" {
+		return nil
+	}
+
+	if cmdCtx.Config.GetBool("detach") {
+		printDetachedDeployment(ctx, cmdCtx, release.Version)
+		if deployCfg.ManualPromote {
+			fmt.Fprintf(cmdCtx.Out, "Once its instances are healthy, v%d waits to be promoted with `%s releases promote v%d -a %s`\n", release.Version, flyname.Name(), release.Version, cmdCtx.AppName)
+		}
+		return nil
+	}
+
+	var gates deployment.Gates
+	if deployCfg.CanaryVerify != "" {
+		gates = append(gates, &deployment.CanaryVerifier{
+			AppName: cmdCtx.AppName,
+			Command: deployCfg.CanaryVerify,
+			Timeout: deployCfg.CanaryVerifyTimeout,
+			Out:     cmdCtx.Out,
+			ErrOut:  cmdCtx.IO.ErrOut,
+		})
+	}
+	if st := deployCfg.SmokeTest; st != nil {
+		smokeTest := &deployment.SmokeTest{
+			AppName:  cmdCtx.AppName,
+			Command:  st.Command,
+			HTTPPath: st.HTTPPath,
+			Port:     st.Port,
+			Timeout:  st.Timeout,
+			Out:      cmdCtx.Out,
+			ErrOut:   cmdCtx.IO.ErrOut,
+		}
+		if st.HTTPPath != "" {
+			dial, closeTunnel, err := privateNetworkDialer(ctx, cmdCtx)
+			if err != nil {
+				return err
+			}
+			defer closeTunnel()
+			smokeTest.Dial = dial
+		}
+		gates = append(gates, smokeTest)
+	}
+	if len(deployCfg.RolloutOrder) > 0 {
+		gates = append(gates, &deployment.Rollout{
+			Regions:  deployCfg.RolloutOrder,
+			BakeTime: deployCfg.BakeTime,
+			Status: func(id string) (*api.DeploymentStatus, error) {
+				return cmdCtx.Client.API().GetDeploymentStatus(cmdCtx.AppName, id)
+			},
+		})
+	}
+
+	var gate deployment.Gate
+	if len(gates) > 0 {
+		gate = gates
+	}
+
+	var autoRollback *deployment.AutoRollback
+	if deployCfg.AutoRollback && !cmdCtx.Config.GetBool("no-auto-rollback") {
+		autoRollback = deployment.NewAutoRollback(deployCfg.AutoRollbackThreshold)
+	}
+
+	return watchGatedDeployment(ctx, cmdCtx, gate, autoRollback)
+}
+
+// resolveSmokeTest applies --smoke-test and --smoke-test-http to the [deploy.smoke_test] section,
+// checks the deploy can wait for it, and defaults its port to the app's internal port
+func resolveSmokeTest(cmdCtx *cmdctx.CmdContext, deployCfg *flyctl.DeployConfig, strategy string) error {
+	command, _ := cmdCtx.Config.GetString("smoke-test")
+	httpPath, _ := cmdCtx.Config.GetString("smoke-test-http")
+	if command != "" || httpPath != "" {
+		if deployCfg.SmokeTest == nil {
+			deployCfg.SmokeTest = flyctl.NewSmokeTestConfig()
+		}
+		if command != "" {
+			deployCfg.SmokeTest.Command = command
+		}
+		if httpPath != "" {
+			if !strings.HasPrefix(httpPath, "/") {
+				httpPath = "/" + httpPath
+			}
+			deployCfg.SmokeTest.HTTPPath = httpPath
+		}
+	}
+
+	st := deployCfg.SmokeTest
+	if st == nil {
+		return nil
+	}
+
+	switch {
+	case strategy == flyctl.StrategyImmediate:
+		return errors.New("smoke tests can't be used with the immediate strategy, it replaces every instance at once")
+	case cmdCtx.Config.GetBool("detach"):
+		return errors.New("smoke tests can't be used with --detach, the deployment waits while flyctl runs them")
+	case cmdCtx.Config.GetBool("require-approvals"):
+		return errors.New("smoke tests can't be used with --require-approvals")
+	}
+
+	if st.Port == 0 && cmdCtx.AppConfig != nil {
+		if port, err := cmdCtx.AppConfig.GetInternalPort(); err == nil {
+			st.Port = port
+		}
+	}
+	if st.Port == 0 && st.HTTPPath != "" {
+		return errors.New("the smoke test needs a port to request, set port in the [deploy.smoke_test] section of fly.toml")
+	}
+
+	return nil
+}
+
+// resolveRollout applies --rollout-order and --bake-time to the [deploy] section and checks the
+// deploy can follow a staged rollout
+func resolveRollout(cmdCtx *cmdctx.CmdContext, deployCfg *flyctl.DeployConfig, strategy string) error {
+	if regions := cmdCtx.Config.GetStringSlice("rollout-order"); len(regions) > 0 {
+		if err := flyctl.ValidateRolloutOrder(regions); err != nil {
+			return err
+		}
+		deployCfg.RolloutOrder = regions
+	}
+	if val, _ := cmdCtx.Config.GetString("bake-time"); val != "" {
+		d, err := time.ParseDuration(val)
+		if err != nil || d < 0 {
+			return fmt.Errorf("--bake-time must be a duration like 5m, got %s", val)
+		}
+		deployCfg.BakeTime = d
+	}
+
+	if len(deployCfg.RolloutOrder) == 0 {
+		return nil
+	}
+
+	switch {
+	case strategy == flyctl.StrategyImmediate:
+		return errors.New("staged rollouts can't be used with the immediate strategy, it replaces every instance at once")
+	case deployCfg.CanaryVerify != "" || deployCfg.SmokeTest != nil || deployCfg.ManualPromote:
+		return errors.New("staged rollouts can't be combined with canary verification, smoke tests or manual promotion")
+	case cmdCtx.Config.GetBool("detach"):
+		return errors.New("staged rollouts can't be used with --detach, flyctl moves the rollout from region to region")
+	case cmdCtx.Config.GetBool("require-approvals"):
+		return errors.New("staged rollouts can't be used with --require-approvals")
+	}
+
+	return nil
+}
+
+// resolveOnlyRegions returns the regions given with --only-regions, checking the app runs in each
+func resolveOnlyRegions(cmdCtx *cmdctx.CmdContext, deployCfg *flyctl.DeployConfig) ([]string, error) {
+	regions := cmdCtx.Config.GetStringSlice("only-regions")
+	if len(regions) == 0 {
+		return nil, nil
+	}
+	if len(deployCfg.RolloutOrder) > 0 {
+		return nil, errors.New("--only-regions can't be combined with a staged rollout")
+	}
+
+	current, _, err := cmdCtx.Client.API().ListAppRegions(cmdCtx.AppName)
+	if err != nil {
+		return nil, err
+	}
+
+	codes := make([]string, len(current))
+	running := map[string]bool{}
+	for i, r := range current {
+		codes[i] = r.Code
+		running[r.Code] = true
+	}
+	for _, region := range regions {
+		if !running[region] {
+			return nil, fmt.Errorf("%s isn't one of %s's regions (%s), add it with `%s regions add %s` first", region, cmdCtx.AppName, strings.Join(codes, ", "), flyname.Name(), region)
+		}
+	}
+
+	return regions, nil
+}
+
+// resolveRegionOverrides reads the [regions.<code>] sections of fly.toml for the release, checking
+// their VM sizes against the platform's. Overrides for regions the app doesn't run in are kept for
+// when it's scaled there, with a warning.
+func resolveRegionOverrides(cmdCtx *cmdctx.CmdContext) ([]api.RegionOverrideInput, error) {
+	if cmdCtx.AppConfig == nil {
+		return nil, nil
+	}
+	overrides, errs := cmdCtx.AppConfig.RegionOverrides()
+	if len(errs) > 0 {
+		return nil, configSectionError(cmdCtx, "[regions.<code>]", errs)
+	}
+	if len(overrides) == 0 {
+		return nil, nil
+	}
+
+	current, _, err := cmdCtx.Client.API().ListAppRegions(cmdCtx.AppName)
+	if err != nil {
+		return nil, err
+	}
+	running := map[string]bool{}
+	for _, r := range current {
+		running[r.Code] = true
+	}
+
+	validVMSize := vmSizeValidator(cmdCtx)
+	inputs := make([]api.RegionOverrideInput, 0, len(overrides))
+	for _, o := range overrides {
+		if !running[o.Region] {
+			terminal.Warnf("%s has overrides in fly.toml but %s doesn't run there, they apply once it's added with `%s regions add %s`\n", o.Region, cmdCtx.AppName, flyname.Name(), o.Region)
+		}
+
+		if o.VMSize != "" {
+			if ok, err := validVMSize(o.VMSize); err != nil {
+				return nil, err
+			} else if !ok {
+				return nil, fmt.Errorf("regions.%s.vm_size: %s isn't a VM size, list them with `%s platform vm-sizes`", o.Region, o.VMSize, flyname.Name())
+			}
+		}
+
+		input := api.RegionOverrideInput{Region: o.Region, Env: o.Env, VMSize: o.VMSize}
+		if o.Concurrency != nil {
+			input.SoftLimit = o.Concurrency.SoftLimit
+			input.HardLimit = o.Concurrency.HardLimit
+		}
+		inputs = append(inputs, input)
+
+		var settings []string
+		if len(o.Env) > 0 {
+			settings = append(settings, fmt.Sprintf("%d env vars", len(o.Env)))
+		}
+		if o.VMSize != "" {
+			settings = append(settings, "vm "+o.VMSize)
+		}
+		if o.Concurrency != nil && o.Concurrency.SoftLimit > 0 {
+			settings = append(settings, fmt.Sprintf("soft limit %d", o.Concurrency.SoftLimit))
+		}
+		if o.Concurrency != nil && o.Concurrency.HardLimit > 0 {
+			settings = append(settings, fmt.Sprintf("hard limit %d", o.Concurrency.HardLimit))
+		}
+		cmdCtx.Statusf("deploy", cmdctx.SDETAIL, "Region %s overrides: %s\n", o.Region, strings.Join(settings, ", "))
+	}
+
+	return inputs, nil
+}
+
+// resolveProcessGroups reads the [processes] section of fly.toml for the release. The server only
+// reads each process's command, so the section is reduced to commands for it, and build targets
+// move to [build] targets where the image builder finds them.
+func resolveProcessGroups(cmdCtx *cmdctx.CmdContext) ([]flyctl.ProcessGroup, error) {
+	if cmdCtx.AppConfig == nil {
+		return nil, nil
+	}
+	groups, errs := cmdCtx.AppConfig.ProcessGroups()
+	if len(errs) > 0 {
+		return nil, configSectionError(cmdCtx, "[processes]", errs)
+	}
+	if len(groups) == 0 {
+		return nil, nil
+	}
+
+	validVMSize := vmSizeValidator(cmdCtx)
+	for _, g := range groups {
+		if g.VMSize == "" {
+			continue
+		}
+		if ok, err := validVMSize(g.VMSize); err != nil {
+			return nil, err
+		} else if !ok {
+			return nil, fmt.Errorf("processes.%s.vm_size: %s isn't a VM size, list them with `%s platform vm-sizes`", g.Name, g.VMSize, flyname.Name())
+		}
+	}
+
+	cmdCtx.AppConfig.Definition["processes"] = flyctl.ProcessCommands(groups)
+	for _, g := range groups {
+		if g.BuildTarget == "" {
+			continue
+		}
+		if cmdCtx.AppConfig.Build == nil {
+			cmdCtx.AppConfig.Build = &flyctl.Build{}
+		}
+		if cmdCtx.AppConfig.Build.Targets == nil {
+			cmdCtx.AppConfig.Build.Targets = map[string]string{}
+		}
+		cmdCtx.AppConfig.Build.Targets[g.Name] = g.BuildTarget
+	}
+
+	return groups, nil
+}
+
+// processGroupsWithTargets returns the process groups built from a different Dockerfile stage than
+// the app's image
+func processGroupsWithTargets(cmdCtx *cmdctx.CmdContext, groups []flyctl.ProcessGroup) []flyctl.ProcessGroup {
+	var targeted []flyctl.ProcessGroup
+	for _, g := range groups {
+		if cmdCtx.AppConfig.BuildTarget(g.Name) != cmdCtx.AppConfig.BuildTarget("") {
+			targeted = append(targeted, g)
+		}
+	}
+	return targeted
+}
+
+// buildProcessGroupImages builds an image for each process group with its own build target,
+// tagged after the app's image. Groups sharing a target share an image. It returns the image of
+// each of those groups.
+func buildProcessGroupImages(ctx context.Context, cmdCtx *cmdctx.CmdContext, resolver *imgsrc.Resolver, opts imgsrc.ImageOptions, img *imgsrc.DeploymentImage, groups []flyctl.ProcessGroup) (map[string]string, error) {
+	targeted := processGroupsWithTargets(cmdCtx, groups)
+	if len(targeted) == 0 {
+		return nil, nil
+	}
+
+	images := map[string]string{}
+	built := map[string]string{}
+	for _, g := range targeted {
+		target := cmdCtx.AppConfig.BuildTarget(g.Name)
+		if tag, ok := built[target]; ok {
+			images[g.Name] = tag
+			continue
+		}
+
+		groupOpts := opts
+		groupOpts.ProcessGroup = g.Name
+		groupOpts.Tag = img.Tag + "-" + g.Name
+
+		cmdCtx.Statusf("deploy", cmdctx.SBEGIN, "Building the %s process group's image, target %s\n", g.Name, target)
+		started := time.Now()
+		groupImg, err := resolver.BuildImage(ctx, cmdCtx.IO, groupOpts)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error building the %s process group's image", g.Name)
+		}
+		if groupImg == nil {
+			return nil, fmt.Errorf("could not find an image for the %s process group", g.Name)
+		}
+		if groupOpts.Publish {
+			attachProvenance(ctx, cmdCtx, resolver, groupOpts, groupImg, started)
+			cmdCtx.Emit("image_pushed", imageEvent{Image: groupImg.Tag, Digest: groupImg.Digest, Size: groupImg.Size})
+		}
+		fmt.Fprintf(cmdCtx.Out, "Image for %s: %s\n", g.Name, groupImg.Tag)
+
+		built[target] = groupImg.Tag
+		images[g.Name] = groupImg.Tag
+	}
+
+	return images, nil
+}
+
+// checkRequiredSecrets fails the deploy when a secret required by the [secrets] section of
+// fly.toml isn't set on the app, before anything is built or released
+func checkRequiredSecrets(cmdCtx *cmdctx.CmdContext) error {
+	if cmdCtx.AppConfig == nil {
+		return nil
+	}
+	required, errs := cmdCtx.AppConfig.RequiredSecrets()
+	if len(errs) > 0 {
+		return configSectionError(cmdCtx, "[secrets]", errs)
+	}
+	if len(required) == 0 {
+		return nil
+	}
+
+	secrets, err := cmdCtx.Client.API().GetAppSecrets(cmdCtx.AppName)
+	if err != nil {
+		return errors.Wrap(err, "error checking the app's secrets")
+	}
+
+	missing := flyctl.MissingSecrets(required, secrets)
+	if len(missing) > 0 {
+		return fmt.Errorf("%s is missing secrets required by fly.toml: %s\nSet them with `%s secrets set %s=...` before deploying", cmdCtx.AppName, strings.Join(missing, ", "), flyname.Name(), missing[0])
+	}
+
+	cmdCtx.Statusf("deploy", cmdctx.SDETAIL, "Required secrets are set: %s\n", strings.Join(required, ", "))
+	return nil
+}
+
+// vmSizeValidator returns a check of VM size names against the platform's, fetched the first time
+// it's needed
+func vmSizeValidator(cmdCtx *cmdctx.CmdContext) func(size string) (bool, error) {
+	var sizes map[string]bool
+	return func(size string) (bool, error) {
+		if sizes == nil {
+			platformSizes, err := cmdCtx.Client.API().PlatformVMSizes()
+			if err != nil {
+				return false, err
+			}
+			sizes = map[string]bool{}
+			for _, s := range platformSizes {
+				sizes[s.Name] = true
+			}
+		}
+		return sizes[size], nil
+	}
+}
+
+// acquireDeployLock takes the app's deploy lock, so concurrent deploys queue instead of racing.
+// --force-unlock releases a lock held by another deploy first, and --wait-for-lock waits for it.
+func acquireDeployLock(ctx context.Context, cmdCtx *cmdctx.CmdContext) (*deployment.Lock, error) {
+	if cmdCtx.Config.GetBool("force-unlock") {
+		released, err := cmdCtx.Client.API().ForceReleaseDeployLock(cmdCtx.AppName)
+		if err != nil {
+			return nil, errors.Wrap(err, "could not release the deploy lock")
+		}
+		if released != nil {
+			terminal.Warnf("Released the deploy lock held by %s\n", released.Holder)
+		}
+	}
+
+	waitNoted := false
+	lock, err := deployment.AcquireLock(ctx, cmdCtx.Client.API(), cmdCtx.AppName, deployment.LockHolder(), cmdCtx.Config.GetBool("wait-for-lock"), func(held *api.DeployLock) {
+		if !waitNoted {
+			waitNoted = true
+			cmdCtx.Statusf("deploy", cmdctx.SINFO, "Waiting for the deploy lock, held by %s since %s\n", held.Holder, humanize.Time(held.CreatedAt))
+		}
+	})
+
+	var locked *deployment.LockedError
+	if errors.As(err, &locked) {
+		return nil, fmt.Errorf("%s is being deployed by %s, started %s. Use --wait-for-lock to deploy once it finishes, or --force-unlock if that deploy was killed", cmdCtx.AppName, locked.Lock.Holder, humanize.Time(locked.Lock.CreatedAt))
+	}
+	return lock, err
+}
+
+// holdDeployLock takes the app's deploy lock for the rest of a command. The returned context is
+// canceled if the lock is lost, since another deploy can start from then on, and release gives
+// the lock up.
+func holdDeployLock(ctx context.Context, cmdCtx *cmdctx.CmdContext) (context.Context, func(), error) {
+	lock, err := acquireDeployLock(ctx, cmdCtx)
+	if err != nil {
+		return ctx, nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-lock.Lost():
+			terminal.Errorf("Lost the deploy lock of %s, stopping so another deploy doesn't run alongside this one: %v\n", cmdCtx.AppName, lock.Err())
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	release := func() {
+		cancel()
+		if err := lock.Release(); err != nil {
+			terminal.Warnf("Could not release the deploy lock, it expires in a few minutes: %v\n", err)
+		}
+	}
+	return ctx, release, nil
+}
+
+// addDeployLockFlags adds the flags of acquireDeployLock to a command that creates releases
+func addDeployLockFlags(cmd *Command) {
+	cmd.AddBoolFlag(BoolFlagOpts{
+		Name:        "wait-for-lock",
+		Description: "Wait for another deploy of the app to finish instead of failing when it holds the deploy lock",
+	})
+	cmd.AddBoolFlag(BoolFlagOpts{
+		Name:        "force-unlock",
+		Description: "Release the app's deploy lock whoever holds it before deploying, for a lock left by a deploy that was killed",
+	})
+}
+
+// privateNetworkDialer connects to the app's private network over WireGuard, returning a dialer for
+// its instances' private addresses and a func that closes the connection
+func privateNetworkDialer(ctx context.Context, cmdCtx *cmdctx.CmdContext) (func(context.Context, string, string) (net.Conn, error), func(), error) {
+	app, err := cmdCtx.Client.API().GetApp(cmdCtx.AppName)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	if extraEnv := cmdCtx.Config.GetStringSlice("env"); len(extraEnv) > 0 {
-		parsedEnv, err := cmdutil.ParseKVStringsToMap(cmdCtx.Config.GetStringSlice("env"))
-		if err != nil {
-			return errors.Wrap(err, "invalid env")
-		}
-		cmdCtx.AppConfig.SetEnvVariables(parsedEnv)
+	tunnel, err := wireGuardNetwork(cmdCtx)(ctx, &app.Organization)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error connecting to the app's private network over WireGuard")
 	}
 
-	parsedCfg, err := cmdCtx.Client.API().ParseConfig(cmdCtx.AppName, cmdCtx.AppConfig.Definition)
-	if err != nil {
-		if parsedCfg == nil {
-			// No error data has been returned
-			return fmt.Errorf("not possible to validate configuration: server returned %s", err)
-		}
-		for _, error := range parsedCfg.Errors {
-			//	fmt.Println("   ", aurora.Red("✘").String(), error)
-			cmdCtx.Status("deploy", cmdctx.SERROR, "   ", aurora.Red("✘").String(), error)
+	closeTunnel := func() {
+		if err := tunnel.Close(); err != nil {
+			terminal.Debugf("Error closing WireGuard tunnel: %v\n", err)
 		}
-		return err
 	}
-	cmdCtx.AppConfig.Definition = parsedCfg.Definition
-	cmdfmt.PrintDone(cmdCtx.Out, "Validating app configuration done")
+	return tunnel.DialContext, closeTunnel, nil
+}
 
-	if parsedCfg.Valid && len(parsedCfg.Services) > 0 {
-		cmdfmt.PrintServicesList(cmdCtx.IO, parsedCfg.Services)
+// watchReleaseCommand follows the release_command of a release until it finishes, printing its
+// output. The release's instances are only replaced once it succeeds, so a failed command fails
+// the deploy. With --detach it returns once the command has started.
+func watchReleaseCommand(ctx context.Context, cmdCtx *cmdctx.CmdContext, release *api.Release) error {
+	if release.ReleaseCommand == nil {
+		return nil
 	}
 
-	daemonType := imgsrc.NewDockerDaemonType(!cmdCtx.Config.GetBool("remote-only"), !cmdCtx.Config.GetBool("local-only"))
-	resolver := imgsrc.NewResolver(daemonType, cmdCtx.Client.API(), cmdCtx.AppName, cmdCtx.IO)
+	cmdfmt.PrintBegin(cmdCtx.Out, "Running release command: ", release.ReleaseCommand.Command)
+	if cmdCtx.Config.GetBool("detach") {
+		fmt.Fprintf(cmdCtx.Out, "v%d deploys once it succeeds\n", release.Version)
+		return nil
+	}
 
-	var img *imgsrc.DeploymentImage
+	logPresenter := presenters.LogPresenter{HideAllocID: true, HideRegion: true, RemoveNewlines: true}
+	nextToken := ""
 
-	if ref, _ := cmdCtx.Config.GetString("image"); ref != "" {
-		opts := imgsrc.RefOptions{
-			AppName:    cmdCtx.AppName,
-			WorkingDir: cmdCtx.WorkingDir,
-			AppConfig:  cmdCtx.AppConfig,
-			Publish:    !cmdCtx.Config.GetBool("build-only"),
-			ImageRef:   ref,
+	printLogs := func(instanceID string) {
+		entries, token, err := cmdCtx.Client.API().GetAppLogs(cmdCtx.AppName, nextToken, "", instanceID)
+		if err != nil {
+			terminal.Debugf("error fetching release command logs: %v\n", err)
+			return
+		}
+		logPresenter.FPrint(cmdCtx.Out, cmdCtx.OutputJSON(), entries)
+		if token != "" {
+			nextToken = token
 		}
-		opts.ImageLabel, _ = cmdCtx.Config.GetString("image-label")
+	}
 
-		img, err = resolver.ResolveReference(ctx, cmdCtx.IO, opts)
+	for {
+		rc, err := cmdCtx.Client.API().GetReleaseCommand(cmdCtx.AppName, release.Version)
 		if err != nil {
-			return err
+			return errors.Wrap(err, "error checking the release command")
 		}
-	} else {
-		opts := imgsrc.ImageOptions{
-			AppName:    cmdCtx.AppName,
-			WorkingDir: cmdCtx.WorkingDir,
-			AppConfig:  cmdCtx.AppConfig,
-			Publish:    !cmdCtx.Config.GetBool("build-only"),
+
+		if rc.InstanceID != nil {
+			printLogs(*rc.InstanceID)
 		}
-		opts.ImageLabel, _ = cmdCtx.Config.GetString("image-label")
 
-		if dockerfilePath, _ := cmdCtx.Config.GetString("dockerfile"); dockerfilePath != "" {
-			dockerfilePath, err := filepath.Abs(dockerfilePath)
-			if err != nil {
-				return err
+		if !rc.InProgress {
+			if rc.Succeeded {
+				cmdfmt.PrintDone(cmdCtx.Out, "Release command succeeded")
+				return nil
 			}
-			opts.DockerfilePath = dockerfilePath
+			if rc.ExitCode != nil {
+				return fmt.Errorf("release command failed with exit code %d, v%d was not deployed", *rc.ExitCode, release.Version)
+			}
+			return fmt.Errorf("release command %s, v%d was not deployed", rc.Status, release.Version)
 		}
 
-		extraArgs, err := cmdutil.ParseKVStringsToMap(cmdCtx.Config.GetStringSlice("build-arg"))
-		if err != nil {
-			return errors.Wrap(err, "invalid build-arg")
+		select {
+		case <-time.After(2 * time.Second):
+		case <-ctx.Done():
+			return ctx.Err()
 		}
-		opts.ExtraBuildArgs = extraArgs
+	}
+}
 
-		img, err = resolver.BuildImage(ctx, cmdCtx.IO, opts)
+// attachProvenance attests how img was built next to it in the fly registry. The image is already
+// pushed, so failing to only warns.
+func attachProvenance(ctx context.Context, cmdCtx *cmdctx.CmdContext, resolver *imgsrc.Resolver, opts imgsrc.ImageOptions, img *imgsrc.DeploymentImage, started time.Time) {
+	ref, err := registry.ParseReference(img.Tag)
+	if err != nil || ref.Host != viper.GetString(flyctl.ConfigRegistryHost) {
+		return
+	}
+
+	p, err := resolver.Provenance(opts, started, time.Now())
+	if err != nil {
+		terminal.Warnf("Could not record the image's provenance: %v\n", err)
+		return
+	}
+
+	c := newRegistryClient(ref.Host)
+	digest := img.Digest
+	if digest == "" {
+		m, err := c.GetManifest(ctx, ref.Repository, ref.Identifier())
 		if err != nil {
-			return err
-		}
-		if img == nil {
-			return errors.New("could not find an image to deploy")
+			terminal.Warnf("Could not record the image's provenance: %v\n", err)
+			return
 		}
+		digest = m.Digest
 	}
 
-	if img == nil {
-		return errors.New("could not find an image to deploy")
+	if _, err := registry.AttachProvenance(ctx, c, ref, digest, p); err != nil {
+		terminal.Warnf("Could not attach provenance to %s: %v\n", img.Tag, err)
+		return
 	}
+	terminal.Debugf("attached provenance to %s@%s\n", img.Tag, digest)
+}
 
-	fmt.Fprintf(cmdCtx.Client.IO.Out, "Image: %s\n", img.Tag)
-	fmt.Fprintf(cmdCtx.Client.IO.Out, "Image size: %s\n", humanize.Bytes(uint64(img.Size)))
+// buildTimeouts returns the total build and builder readiness timeouts, preferring flags over fly.toml
+func buildTimeouts(cmdCtx *cmdctx.CmdContext) (build time.Duration, builder time.Duration, err error) {
+	if cfg := cmdCtx.AppConfig.Build; cfg != nil {
+		build, builder = cfg.Timeout, cfg.BuilderTimeout
+	}
 
-	if cmdCtx.Config.GetBool("build-only") {
-		return nil
+	if v, _ := cmdCtx.Config.GetString("build-timeout"); v != "" {
+		if build, err = time.ParseDuration(v); err != nil || build <= 0 {
+			return 0, 0, fmt.Errorf("invalid --build-timeout \"%s\", expected a duration like 30m", v)
+		}
 	}
 
-	cmdfmt.PrintBegin(cmdCtx.Out, "Creating release")
+	if v, _ := cmdCtx.Config.GetString("builder-timeout"); v != "" {
+		if builder, err = time.ParseDuration(v); err != nil || builder <= 0 {
+			return 0, 0, fmt.Errorf("invalid --builder-timeout \"%s\", expected a duration like 1m", v)
+		}
+	}
 
-	input := api.DeployImageInput{
-		AppID: cmdCtx.AppName,
-		Image: img.Tag,
+	return build, builder, nil
+}
+
+// drainTimeout returns the --drain-timeout in whole seconds, rounded up, or nil to keep the configured kill_timeout
+func drainTimeout(cmdCtx *cmdctx.CmdContext) (*int, error) {
+	v, _ := cmdCtx.Config.GetString("drain-timeout")
+	if v == "" {
+		return nil, nil
 	}
-	if val, _ := cmdCtx.Config.GetString("strategy"); val != "" {
-		input.Strategy = api.StringPointer(strings.ToUpper(val))
+
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return nil, fmt.Errorf("invalid --drain-timeout \"%s\", expected a duration like 30s", v)
 	}
-	if cmdCtx.AppConfig != nil && len(cmdCtx.AppConfig.Definition) > 0 {
-		input.Definition = api.DefinitionPtr(cmdCtx.AppConfig.Definition)
+
+	seconds := int((d + time.Second - 1) / time.Second)
+	return &seconds, nil
+}
+
+// compressionLevel returns the gzip level from --compression-level or the [build] section, or zero for gzip's default
+func compressionLevel(cmdCtx *cmdctx.CmdContext) (int, error) {
+	level := cmdCtx.Config.GetInt("compression-level")
+	if level == 0 {
+		if cfg := cmdCtx.AppConfig.Build; cfg != nil {
+			return cfg.CompressionLevel, nil
+		}
+		return 0, nil
 	}
 
-	release, err := cmdCtx.Client.API().DeployImage(input)
-	if err != nil {
-		return err
+	if level < 1 || level > 9 {
+		return 0, fmt.Errorf("invalid --compression-level %d, expected a number from 1 (fastest) to 9 (smallest)", level)
 	}
+	return level, nil
+}
 
-	fmt.Fprintf(cmdCtx.Out, "Release v%d created\n", release.Version)
-	fmt.Fprintf(cmdCtx.Out, "Deploying to %s.fly.dev\n", cmdCtx.AppName)
+// buildScanSeverity returns the severity that fails an image scan, or an empty string when scanning is disabled
+func buildScanSeverity(cmdCtx *cmdctx.CmdContext) (string, error) {
+	cfg := cmdCtx.AppConfig.Build
+	if !cmdCtx.Config.GetBool("scan") && (cfg == nil || !cfg.Scan) {
+		return "", nil
+	}
 
-	if release.DeploymentStrategy == "IMMEDIATE" {
-		return nil
+	severity, _ := cmdCtx.Config.GetString("scan-severity")
+	if severity == "" && cfg != nil {
+		severity = cfg.ScanSeverity
+	}
+	if severity == "" {
+		severity = imgsrc.DefaultScanSeverity
 	}
 
-	if cmdCtx.Config.GetBool("detach") {
-		return nil
+	if err := imgsrc.ValidateScanSeverity(severity); err != nil {
+		return "", err
 	}
+	return strings.ToUpper(severity), nil
+}
 
-	return watchDeployment(ctx, cmdCtx)
+// buildScanImage returns the trivy image to scan with, empty for the default
+func buildScanImage(cmdCtx *cmdctx.CmdContext) string {
+	if image, _ := cmdCtx.Config.GetString("scan-image"); image != "" {
+		return image
+	}
+	if cfg := cmdCtx.AppConfig.Build; cfg != nil {
+		return cfg.ScanImage
+	}
+	return ""
 }
 
 func watchDeployment(ctx context.Context, cmdCtx *cmdctx.CmdContext) error {
+	return watchGatedDeployment(ctx, cmdCtx, nil, nil)
+}
+
+// watchGatedDeployment monitors a deployment, checking the instance it's held at with gate once
+// that's ready. The deployment is promoted when the check passes and aborted, which rolls it back,
+// when it fails. It's also aborted once autoRollback trips. Without either it only monitors.
+func watchGatedDeployment(ctx context.Context, cmdCtx *cmdctx.CmdContext, gate deployment.Gate, autoRollback *deployment.AutoRollback) error {
 	if cmdCtx.Config.GetBool("detach") {
 		return nil
 	}
+	return watchDeploymentID(ctx, cmdCtx, "", gate, autoRollback)
+}
+
+// watchDeploymentID shows the progress of the deployment with deploymentID, or of the app's next
+// deployment when it's empty, gating and rolling it back like watchGatedDeployment
+func watchDeploymentID(ctx context.Context, cmdCtx *cmdctx.CmdContext, deploymentID string, gate deployment.Gate, autoRollback *deployment.AutoRollback) error {
 
 	cmdCtx.Status("deploy", cmdctx.STITLE, "Monitoring Deployment")
 	cmdCtx.Status("deploy", cmdctx.SDETAIL, "You can detach the terminal anytime without stopping the deployment")
@@ -220,6 +1402,7 @@ func watchDeployment(ctx context.Context, cmdCtx *cmdctx.CmdContext) error {
 	endmessage := ""
 
 	monitor := deployment.NewDeploymentMonitor(cmdCtx.Client.API(), cmdCtx.AppName)
+	monitor.DeploymentID = deploymentID
 
 	monitor.DeploymentStarted = func(idx int, d *api.DeploymentStatus) error {
 		if idx > 0 {
@@ -230,11 +1413,84 @@ func watchDeployment(ctx context.Context, cmdCtx *cmdctx.CmdContext) error {
 		return nil
 	}
 
+	// lines printed under the summary for draining instances, replaced on every update
+	drainingLines := 0
+
+	// the stages the gate has checked, keyed by the region a staged rollout is held at, or "" for
+	// deployments held once
+	gateChecked := map[string]bool{}
+	promotionNoted := false
+	rolledBack := false
+
+	// the new instances reported healthy and failed with --json
+	reportedHealthy := map[string]bool{}
+	reportedFailed := map[string]bool{}
+
 	monitor.DeploymentUpdated = func(d *api.DeploymentStatus, updatedAllocs []*api.AllocationStatus) error {
+		for _, alloc := range d.Allocations {
+			if alloc.Version != d.Version {
+				continue
+			}
+			if alloc.Healthy && !reportedHealthy[alloc.ID] {
+				reportedHealthy[alloc.ID] = true
+				cmdCtx.Emit("allocation_healthy", newAllocationEvent(alloc))
+			}
+			if alloc.Failed && !reportedFailed[alloc.ID] {
+				reportedFailed[alloc.ID] = true
+				cmdCtx.Emit("allocation_failed", newAllocationEvent(alloc))
+			}
+		}
+
+		if gate == nil && d.AwaitingPromotion() && !promotionNoted {
+			promotionNoted = true
+			cmdCtx.StatusLn()
+			cmdCtx.Statusf("deploy", cmdctx.SINFO, "v%d is healthy and waiting to be promoted, traffic is still served by the previous release\n", d.Version)
+			cmdCtx.Statusf("deploy", cmdctx.SDETAIL, "Promote it with `%s releases promote v%d -a %s`, or remove its instances with `%s releases cancel v%d -a %s`\n", flyname.Name(), d.Version, cmdCtx.AppName, flyname.Name(), d.Version, cmdCtx.AppName)
+			if interactive && !cmdCtx.OutputJSON() {
+				fmt.Fprintln(cmdCtx.Out, presenters.FormatDeploymentAllocSummary(d))
+				drainingLines = 0
+			}
+		}
+
+		if gate != nil && !gateChecked[d.RolloutRegion] {
+			if alloc := gate.Ready(d); alloc != nil {
+				gateChecked[d.RolloutRegion] = true
+				if err := checkGate(ctx, cmdCtx, gate, d, alloc); err != nil {
+					return err
+				}
+				// start a new summary below the check's output instead of overwriting it
+				if interactive && !cmdCtx.OutputJSON() {
+					fmt.Fprintln(cmdCtx.Out, presenters.FormatDeploymentAllocSummary(d))
+					drainingLines = 0
+				}
+			}
+		}
+
+		if autoRollback != nil && !rolledBack && d.InProgress {
+			if failErr := autoRollback.Check(d); failErr != nil {
+				rolledBack = true
+				if err := rollBackDeployment(cmdCtx, d, failErr); err != nil {
+					return err
+				}
+				if interactive && !cmdCtx.OutputJSON() {
+					fmt.Fprintln(cmdCtx.Out, presenters.FormatDeploymentAllocSummary(d))
+					drainingLines = 0
+				}
+			}
+		}
+
 		if interactive && !cmdCtx.OutputJSON() {
-			fmt.Fprint(cmdCtx.Out, aec.Up(1))
-			fmt.Fprint(cmdCtx.Out, aec.EraseLine(aec.EraseModes.All))
+			fmt.Fprint(cmdCtx.Out, aec.Up(uint(1+drainingLines)))
+			fmt.Fprint(cmdCtx.Out, aec.EraseDisplay(aec.EraseModes.Tail))
 			fmt.Fprintln(cmdCtx.Out, presenters.FormatDeploymentAllocSummary(d))
+
+			drainingLines = 0
+			for _, alloc := range d.Allocations {
+				if alloc.Draining != nil {
+					fmt.Fprintf(cmdCtx.Out, "  %s: %s %s\n", alloc.IDShort, alloc.Region, presenters.FormatDrainingSummary(alloc.Draining, time.Now()))
+					drainingLines++
+				}
+			}
 		} else {
 			for _, alloc := range updatedAllocs {
 				cmdCtx.Status("deploy", cmdctx.SINFO, presenters.FormatAllocSummary(alloc))
@@ -246,6 +1502,13 @@ func watchDeployment(ctx context.Context, cmdCtx *cmdctx.CmdContext) error {
 
 	monitor.DeploymentFailed = func(d *api.DeploymentStatus, failedAllocs []*api.AllocationStatus) error {
 		cmdCtx.Statusf("deploy", cmdctx.SDETAIL, "v%d %s - %s\n", d.Version, d.Status, d.Description)
+		cmdCtx.Emit("deploy_failed", deploymentEvent{Version: d.Version, Status: d.Status, Description: d.Description})
+		for _, alloc := range failedAllocs {
+			if !reportedFailed[alloc.ID] {
+				reportedFailed[alloc.ID] = true
+				cmdCtx.Emit("allocation_failed", newAllocationEvent(alloc))
+			}
+		}
 
 		if endmessage == "" && d.Status == "failed" {
 			if strings.Contains(d.Description, "no stable release to revert to") {
@@ -318,6 +1581,7 @@ func watchDeployment(ctx context.Context, cmdCtx *cmdctx.CmdContext) error {
 
 	monitor.DeploymentSucceeded = func(d *api.DeploymentStatus) error {
 		cmdCtx.Statusf("deploy", cmdctx.SDONE, "v%d deployed successfully\n", d.Version)
+		cmdCtx.Emit("deploy_succeeded", deploymentEvent{Version: d.Version, Status: d.Status})
 		return nil
 	}
 
@@ -338,3 +1602,350 @@ func watchDeployment(ctx context.Context, cmdCtx *cmdctx.CmdContext) error {
 
 	return nil
 }
+
+// detachedDeploymentWait is how long a detached deploy waits for its release's deployment to start,
+// so its ID can be printed
+var detachedDeploymentWait = 30 * time.Second
+
+// printDetachedDeployment prints the ID of the deployment of a release, and how to attach to it
+// later with releases watch
+func printDetachedDeployment(ctx context.Context, cmdCtx *cmdctx.CmdContext, version int) {
+	ctx, cancel := context.WithTimeout(ctx, detachedDeploymentWait)
+	defer cancel()
+
+	for {
+		d, err := cmdCtx.Client.API().GetDeploymentStatus(cmdCtx.AppName, "")
+		if err != nil {
+			terminal.Debugf("Failed to look up the deployment of v%d: %v\n", version, err)
+		} else if d != nil && d.Version == version {
+			fmt.Fprintf(cmdCtx.Out, "Deployment %s started, watch it with `%s releases watch %s -a %s`\n", d.ID, flyname.Name(), d.ID, cmdCtx.AppName)
+			cmdCtx.SetResult("deployment_id", d.ID)
+			cmdCtx.Emit("deployment_started", deploymentEvent{ID: d.ID, Version: d.Version, Status: d.Status})
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			fmt.Fprintf(cmdCtx.Out, "Watch the deployment of v%d with `%s releases watch v%d -a %s`\n", version, flyname.Name(), version, cmdCtx.AppName)
+			return
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// checkGate runs gate's check against the instance the deployment is held at, then promotes the
+// deployment or aborts it
+func checkGate(ctx context.Context, cmdCtx *cmdctx.CmdContext, gate deployment.Gate, d *api.DeploymentStatus, alloc *api.AllocationStatus) error {
+	name := gate.Name()
+
+	cmdCtx.StatusLn()
+	cmdCtx.Statusf("deploy", cmdctx.SBEGIN, "Running %s against %s in %s\n", name, alloc.IDShort, alloc.Region)
+
+	checkErr := gate.Check(ctx, d, alloc)
+	if ctx.Err() != nil {
+		// the deployment would otherwise stay held at the instance
+		if _, err := cmdCtx.Client.API().AbortDeployment(d.ID, name+" was cancelled"); err != nil {
+			terminal.Warnf("Could not abort v%d, it's held at instance %s: %v\n", d.Version, alloc.IDShort, err)
+		}
+		return ctx.Err()
+	}
+
+	if checkErr == nil {
+		if _, err := cmdCtx.Client.API().PromoteDeployment(d.ID); err != nil {
+			return errors.Wrapf(err, "%s passed but the deployment could not be promoted", name)
+		}
+		cmdCtx.Statusf("deploy", cmdctx.SDONE, "%s passed, promoted v%d\n", strings.ToUpper(name[:1])+name[1:], d.Version)
+		return nil
+	}
+
+	cmdCtx.Status("deploy", cmdctx.SERROR, checkErr.Error())
+	if _, err := cmdCtx.Client.API().AbortDeployment(d.ID, checkErr.Error()); err != nil {
+		return errors.Wrapf(err, "%s failed and the deployment could not be aborted", name)
+	}
+	cmdCtx.Emit("deploy_aborted", deploymentEvent{Version: d.Version, Reason: checkErr.Error()})
+	cmdCtx.Statusf("deploy", cmdctx.SINFO, "Aborted v%d, rolling back to the last stable release\n", d.Version)
+	return nil
+}
+
+// imageEvent, releaseEvent, deploymentEvent and allocationEvent are the data of the events deploy
+// streams with --json
+type imageEvent struct {
+	Image  string `json:"image"`
+	Digest string `json:"digest,omitempty"`
+	Size   int64  `json:"size,omitempty"`
+}
+
+type releaseEvent struct {
+	Version  int    `json:"version"`
+	Image    string `json:"image"`
+	Strategy string `json:"strategy,omitempty"`
+}
+
+type deploymentEvent struct {
+	ID          string `json:"id,omitempty"`
+	Version     int    `json:"version"`
+	Status      string `json:"status,omitempty"`
+	Description string `json:"description,omitempty"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+type allocationEvent struct {
+	ID       string `json:"id"`
+	Region   string `json:"region"`
+	Version  int    `json:"version"`
+	Restarts int    `json:"restarts"`
+}
+
+func newAllocationEvent(alloc *api.AllocationStatus) allocationEvent {
+	return allocationEvent{ID: alloc.ID, Region: alloc.Region, Version: alloc.Version, Restarts: alloc.Restarts}
+}
+
+// rollBackDeployment aborts a deployment whose new instances are failing their health checks, which
+// rolls the app back to its last stable release
+func rollBackDeployment(cmdCtx *cmdctx.CmdContext, d *api.DeploymentStatus, reason error) error {
+	cmdCtx.StatusLn()
+	cmdCtx.Status("deploy", cmdctx.SERROR, reason.Error())
+	if _, err := cmdCtx.Client.API().AbortDeployment(d.ID, reason.Error()); err != nil {
+		return errors.Wrap(err, "the deployment is failing and could not be rolled back")
+	}
+	cmdCtx.Emit("deploy_aborted", deploymentEvent{Version: d.Version, Reason: reason.Error()})
+	cmdCtx.Statusf("deploy", cmdctx.SINFO, "Aborted v%d, rolling back to the last stable release. Deploy with --no-auto-rollback to leave failing deployments to the orchestrator\n", d.Version)
+	return nil
+}
+
+// checkoutGitSource fetches the commit to build from a --git source and makes it the working
+// directory. Its fly.toml is used when there's no local one. It returns the checkout's directory.
+func checkoutGitSource(ctx context.Context, cmdCtx *cmdctx.CmdContext, source string) (dir string, err error) {
+	if ref, _ := cmdCtx.Config.GetString("image"); ref != "" {
+		return "", errors.New("--git and --image can't be used together")
+	}
+
+	src, err := imgsrc.ParseGitSource(source)
+	if err != nil {
+		return "", err
+	}
+
+	cmdfmt.PrintBegin(cmdCtx.Out, fmt.Sprintf("Fetching %s", src))
+	dir, commit, err := src.Clone(ctx, cmdCtx.IO.ErrOut)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(dir)
+		}
+	}()
+	cmdfmt.PrintDone(cmdCtx.Out, fmt.Sprintf("Checked out commit %s", commit))
+
+	cmdCtx.WorkingDir = dir
+
+	if helpers.FileExists(cmdCtx.ConfigFile) {
+		return dir, nil
+	}
+
+	configFile, err := flyctl.ResolveConfigFileFromPath(dir)
+	if err != nil {
+		return "", err
+	}
+	if environment, _ := cmdCtx.Config.GetString("environment"); environment != "" || helpers.FileExists(configFile) {
+		if err := loadAppConfigFile(cmdCtx, configFile); err != nil {
+			return "", err
+		}
+	}
+
+	return dir, nil
+}
+
+// printDeployPlan shows what a --dry-run deploy would do: how the image would be found or built,
+// and how the app's config would change from the current release
+func printDeployPlan(cmdCtx *cmdctx.CmdContext, plan *imgsrc.BuildPlan) error {
+	current, err := cmdCtx.Client.API().GetConfig(cmdCtx.AppName)
+	if err != nil {
+		return errors.Wrap(err, "error fetching the current config")
+	}
+	changes, err := flyctl.DiffDefinitions(current.Definition, cmdCtx.AppConfig.Definition)
+	if err != nil {
+		return err
+	}
+
+	if cmdCtx.OutputJSON() {
+		cmdCtx.WriteJSON(map[string]interface{}{
+			"Plan":          plan,
+			"ConfigChanges": changes,
+		})
+		return nil
+	}
+
+	out := cmdCtx.Out
+	fmt.Fprintln(out, aurora.Bold("Deploy plan (dry run, nothing was built or deployed)"))
+
+	switch plan.Source {
+	case imgsrc.PlanSourceImage:
+		fmt.Fprintf(out, "  Image:       %s, from the %s docker daemon or its registry\n", plan.Image, plan.Daemon)
+	case imgsrc.PlanSourceRelease:
+		fmt.Fprintf(out, "  Image:       %s, the current release's image\n", plan.Image)
+	case imgsrc.PlanSourceBuildpacks:
+		fmt.Fprintf(out, "  Build:       buildpacks with builder %s\n", plan.Builder)
+		if len(plan.Buildpacks) > 0 {
+			fmt.Fprintf(out, "  Buildpacks:  %s\n", strings.Join(plan.Buildpacks, ", "))
+		}
+	case imgsrc.PlanSourceDockerfile:
+		fmt.Fprintf(out, "  Build:       Dockerfile %s\n", plan.Dockerfile)
+		if plan.Target != "" {
+			fmt.Fprintf(out, "  Target:      %s\n", plan.Target)
+		}
+	case imgsrc.PlanSourceBuiltin:
+		fmt.Fprintf(out, "  Build:       builtin %s\n", plan.Builtin)
+	}
+
+	if plan.Source != imgsrc.PlanSourceImage && plan.Source != imgsrc.PlanSourceRelease {
+		daemon := "no docker daemon available"
+		switch plan.Daemon {
+		case "local":
+			daemon = "local docker daemon"
+		case "remote":
+			daemon = "remote builder"
+			if plan.RemoteBuilder != "" {
+				daemon += " " + plan.RemoteBuilder
+			}
+		}
+		fmt.Fprintf(out, "  Builds on:   %s\n", daemon)
+	}
+	fmt.Fprintf(out, "  Deploy tag:  %s\n", plan.Tag)
+	fmt.Fprintln(out)
+
+	if len(changes) == 0 {
+		fmt.Fprintln(out, "No config changes from the current release")
+		return nil
+	}
+	fmt.Fprintln(out, aurora.Bold("Config changes from the current release"))
+	for _, change := range changes {
+		fmt.Fprintf(out, "  %s\n", change)
+	}
+
+	return nil
+}
+
+// currentReleaseImage returns the image of the app's latest release, pinned by digest so a
+// --config-only release runs exactly the same image even if its tag was pushed again
+func currentReleaseImage(ctx context.Context, cmdCtx *cmdctx.CmdContext) (*imgsrc.DeploymentImage, error) {
+	releases, err := cmdCtx.Client.API().GetAppReleases(cmdCtx.AppName, 1)
+	if err != nil {
+		return nil, err
+	}
+	if len(releases) == 0 || releases[0].ImageRef == "" {
+		return nil, fmt.Errorf("%s has no deployed image to reuse, deploy without --config-only first", cmdCtx.AppName)
+	}
+
+	version := releases[0].Version
+	ref, err := pinnedImageRef(ctx, cmdCtx, fmt.Sprintf("v%d", version))
+	if err != nil {
+		return nil, errors.Wrapf(err, "can't find the image of v%d, it may have been pruned", version)
+	}
+
+	return &imgsrc.DeploymentImage{Tag: ref.String(), Digest: ref.Digest}, nil
+}
+
+// verifyImageSignature checks the image ref has a cosign signature made with the public key at
+// keyPath, a path relative to the directory being deployed. It returns the reference pinned to
+// the digest that's signed.
+func verifyImageSignature(ctx context.Context, cmdCtx *cmdctx.CmdContext, ref, keyPath string) (*registry.Reference, error) {
+	if !filepath.IsAbs(keyPath) {
+		keyPath = filepath.Join(cmdCtx.WorkingDir, keyPath)
+	}
+	data, err := ioutil.ReadFile(keyPath)
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading signature key")
+	}
+	key, err := registry.ParsePublicKey(data)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := registry.ParseReference(ref)
+	if err != nil {
+		return nil, errors.Wrap(err, "signed images must be referenced with their registry, like docker.io/me/app:v1")
+	}
+	c, err := registryClientFor(parsed.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	digest := parsed.Digest
+	if digest == "" {
+		m, err := c.GetManifest(ctx, parsed.Repository, parsed.Tag)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error fetching manifest for %s", ref)
+		}
+		digest = m.Digest
+	}
+
+	err = registry.VerifySignature(ctx, c, parsed.Repository, digest, key)
+	if err == registry.ErrUnsigned {
+		return nil, fmt.Errorf("%s (%s) has no valid signature made with %s", ref, digest, keyPath)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "error verifying the signature of %s", ref)
+	}
+
+	fmt.Fprintf(cmdCtx.Out, "Verified the signature of %s (%s)\n", ref, digest)
+	return &registry.Reference{Host: parsed.Host, Repository: parsed.Repository, Digest: digest}, nil
+}
+
+// deployImageRef returns the existing image to deploy: the --image flag, or with --no-build the
+// image from fly.toml or FLY_IMAGE_REF. It's empty when the image is to be built.
+func deployImageRef(cmdCtx *cmdctx.CmdContext) (string, error) {
+	if ref, _ := cmdCtx.Config.GetString("image"); ref != "" {
+		return ref, nil
+	}
+	if !cmdCtx.Config.GetBool("no-build") {
+		return "", nil
+	}
+
+	if cfg := cmdCtx.AppConfig.Build; cfg != nil && cfg.Image != "" {
+		return cfg.Image, nil
+	}
+	if ref := os.Getenv("FLY_IMAGE_REF"); ref != "" {
+		return ref, nil
+	}
+
+	return "", errors.New("--no-build needs an image to deploy, set --image, image in the [build] section of fly.toml, or FLY_IMAGE_REF")
+}
+
+// followsDeployment returns true when monitorRelease waits for release's deployment to finish,
+// rather than returning once it has started
+func followsDeployment(cmdCtx *cmdctx.CmdContext, release *api.Release) bool {
+	return release.DeploymentStrategy != "IMMEDIATE" && !cmdCtx.Config.GetBool("detach")
+}
+
+// runDeployHooks runs the [deploy.hooks] command for how the deploy finished and posts to the
+// [deploy.notify] webhooks. The deploy has already finished, so their failures are only warnings.
+// Detached deploys haven't finished, so they only notify the webhooks that the deploy started.
+//
+// They don't use the deploy's context, so an interrupted or cancelled deploy still reports its
+// failure. RunHook and PostWebhook limit how long each one runs.
+func runDeployHooks(cmdCtx *cmdctx.CmdContext, deployCfg *flyctl.DeployConfig, outcome deployment.Outcome) {
+	ctx := context.Background()
+
+	hook := deployCfg.Hooks.Success
+	if !outcome.Succeeded() {
+		hook = deployCfg.Hooks.Failure
+	}
+	if hook != "" && !outcome.Detached {
+		// the hook's output goes to stderr so --json keeps stdout to events
+		if err := deployment.RunHook(ctx, hook, outcome, cmdCtx.IO.ErrOut, cmdCtx.IO.ErrOut); err != nil {
+			terminal.Warnf("%s\n", err)
+		}
+	}
+
+	if url := deployCfg.Notify.Webhook; url != "" {
+		if err := deployment.Notify(ctx, url, false, outcome); err != nil {
+			terminal.Warnf("Failed to notify deploy webhook: %s\n", err)
+		}
+	}
+	if url := deployCfg.Notify.Slack; url != "" {
+		if err := deployment.Notify(ctx, url, true, outcome); err != nil {
+			terminal.Warnf("Failed to notify Slack: %s\n", err)
+		}
+	}
+}
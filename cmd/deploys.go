@@ -0,0 +1,179 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/cmdctx"
+	"github.com/superfly/flyctl/docstrings"
+	"github.com/superfly/flyctl/flyname"
+	"github.com/superfly/flyctl/internal/client"
+	"github.com/superfly/flyctl/internal/deployment"
+	"github.com/superfly/flyctl/terminal"
+)
+
+func newDeploysCommand(client *client.Client) *Command {
+	deploysStrings := docstrings.Get("deploys")
+	cmd := BuildCommandKS(nil, nil, deploysStrings, client, requireSession, requireAppName)
+
+	approveStrings := docstrings.Get("deploys.approve")
+	approveCmd := BuildCommandKS(cmd, runDeploysApprove, approveStrings, client, requireSession, requireAppName, mutating)
+	approveCmd.Args = cobra.ExactArgs(1)
+	approveCmd.AddBoolFlag(BoolFlagOpts{
+		Name:        "detach",
+		Description: "Return immediately instead of monitoring deployment progress",
+	})
+	approveCmd.AddStringFlag(approvalWebhookFlag)
+
+	rejectStrings := docstrings.Get("deploys.reject")
+	rejectCmd := BuildCommandKS(cmd, runDeploysReject, rejectStrings, client, requireSession, requireAppName, mutating)
+	rejectCmd.Args = cobra.ExactArgs(1)
+	rejectCmd.AddStringFlag(StringFlagOpts{
+		Name:        "reason",
+		Description: "Why the release was rejected",
+	})
+	rejectCmd.AddStringFlag(approvalWebhookFlag)
+
+	return cmd
+}
+
+var approvalWebhookFlag = StringFlagOpts{
+	Name:        "approval-webhook",
+	Description: "URL to POST a JSON notification to when a release is waiting for approval, approved or rejected",
+	EnvName:     "FLY_APPROVAL_WEBHOOK",
+}
+
+// pendingRelease finds a release by ID, or by version when given like v42
+func pendingRelease(cmdCtx *cmdctx.CmdContext, arg string) (*api.Release, error) {
+	if !strings.HasPrefix(strings.ToLower(arg), "v") {
+		return &api.Release{ID: arg}, nil
+	}
+
+	version, err := parseReleaseVersion(arg)
+	if err != nil {
+		return nil, err
+	}
+
+	release, err := cmdCtx.Client.API().GetAppRelease(cmdCtx.AppName, version)
+	if err != nil {
+		if err == api.ErrNotFound {
+			return nil, fmt.Errorf("release v%d not found", version)
+		}
+		return nil, err
+	}
+	if !release.PendingApproval() {
+		return nil, fmt.Errorf("release v%d is not waiting for approval", version)
+	}
+
+	return release, nil
+}
+
+func runDeploysApprove(cmdCtx *cmdctx.CmdContext) error {
+	ctx := createCancellableContext()
+
+	pending, err := pendingRelease(cmdCtx, cmdCtx.Args[0])
+	if err != nil {
+		return err
+	}
+
+	release, err := cmdCtx.Client.API().ApproveRelease(pending.ID)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmdCtx.Out, "Release v%d approved\n", release.Version)
+
+	notifyApproval(cmdCtx, approvalEvent{
+		Event:   "approved",
+		App:     cmdCtx.AppName,
+		Release: release.ID,
+		Version: release.Version,
+		Text:    fmt.Sprintf("Release v%d of %s was approved", release.Version, cmdCtx.AppName),
+	})
+
+	if err := watchReleaseCommand(ctx, cmdCtx, release); err != nil {
+		return err
+	}
+
+	if release.DeploymentStrategy == "IMMEDIATE" {
+		return nil
+	}
+
+	return watchDeployment(ctx, cmdCtx)
+}
+
+func runDeploysReject(cmdCtx *cmdctx.CmdContext) error {
+	pending, err := pendingRelease(cmdCtx, cmdCtx.Args[0])
+	if err != nil {
+		return err
+	}
+
+	reason, _ := cmdCtx.Config.GetString("reason")
+
+	release, err := cmdCtx.Client.API().RejectRelease(pending.ID, reason)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmdCtx.Out, "Release v%d rejected\n", release.Version)
+
+	text := fmt.Sprintf("Release v%d of %s was rejected", release.Version, cmdCtx.AppName)
+	if reason != "" {
+		text += ": " + reason
+	}
+	notifyApproval(cmdCtx, approvalEvent{
+		Event:   "rejected",
+		App:     cmdCtx.AppName,
+		Release: release.ID,
+		Version: release.Version,
+		Reason:  reason,
+		Text:    text,
+	})
+
+	return nil
+}
+
+// approvalEvent is the body posted to the approval webhook. Text is set so the
+// payload can be sent straight to Slack compatible incoming webhooks.
+type approvalEvent struct {
+	Event          string `json:"event"`
+	App            string `json:"app"`
+	Release        string `json:"release_id"`
+	Version        int    `json:"version"`
+	Image          string `json:"image,omitempty"`
+	User           string `json:"user,omitempty"`
+	Reason         string `json:"reason,omitempty"`
+	ApproveCommand string `json:"approve_command,omitempty"`
+	Text           string `json:"text"`
+}
+
+func approvalRequestedEvent(appName string, release *api.Release, image string) approvalEvent {
+	approve := fmt.Sprintf("%s deploys approve v%d -a %s", flyname.Name(), release.Version, appName)
+
+	return approvalEvent{
+		Event:          "approval_requested",
+		App:            appName,
+		Release:        release.ID,
+		Version:        release.Version,
+		Image:          image,
+		User:           release.User.Email,
+		ApproveCommand: approve,
+		Text:           fmt.Sprintf("%s requested approval to deploy release v%d of %s. Approve with `%s`", release.User.Email, release.Version, appName, approve),
+	}
+}
+
+// notifyApproval posts the event to the approval webhook when one is configured. Failures are
+// only warned about since the release itself has already been updated.
+func notifyApproval(cmdCtx *cmdctx.CmdContext, event approvalEvent) {
+	url, _ := cmdCtx.Config.GetString("approval-webhook")
+	if url == "" {
+		return
+	}
+
+	if err := deployment.PostWebhook(context.Background(), url, event); err != nil {
+		terminal.Warnf("Could not notify approval webhook: %v\n", err)
+	}
+}
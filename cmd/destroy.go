@@ -3,6 +3,8 @@ package cmd
 import (
 	"fmt"
 
+	"github.com/pkg/errors"
+	"github.com/superfly/flyctl/api"
 	"github.com/superfly/flyctl/cmdctx"
 	"github.com/superfly/flyctl/internal/client"
 
@@ -18,32 +20,54 @@ func newDestroyCommand(client *client.Client) *Command {
 
 	destroyStrings := docstrings.Get("destroy")
 
-	destroy := BuildCommand(nil, runDestroy, destroyStrings.Usage, destroyStrings.Short, destroyStrings.Long, client, requireSession)
+	destroy := BuildCommand(nil, runDestroy, destroyStrings.Usage, destroyStrings.Short, destroyStrings.Long, client, requireSession, mutating)
 
 	destroy.Args = cobra.ExactArgs(1)
 
 	destroy.AddBoolFlag(BoolFlagOpts{Name: "yes", Shorthand: "y", Description: "Accept all confirmations"})
+	destroy.AddBoolFlag(BoolFlagOpts{Name: "dry-run", Description: "List what destroying the app would remove without destroying it"})
 
 	return destroy
 }
 
 func runDestroy(ctx *cmdctx.CmdContext) error {
 	appName := ctx.Args[0]
+	dryRun := ctx.Config.GetBool("dry-run")
+
+	var plan []string
+	if dryRun || !ctx.Config.GetBool("yes") {
+		var err error
+		if plan, err = destroyPlan(ctx.Client.API(), appName); err != nil {
+			return errors.Wrap(err, "Error fetching app resources")
+		}
+	}
+
+	if dryRun {
+		fmt.Printf("Destroying %s would remove:\n", appName)
+		printDestroyPlan(plan)
+		return nil
+	}
 
 	if !ctx.Config.GetBool("yes") {
 		fmt.Println(aurora.Red("Destroying an app is not reversible."))
-
-		confirm := false
-		prompt := &survey.Confirm{
-			Message: fmt.Sprintf("Destroy app %s?", appName),
+		if len(plan) > 0 {
+			fmt.Println("These go with it:")
+			printDestroyPlan(plan)
 		}
-		err := survey.AskOne(prompt, &confirm)
 
-		if err != nil {
+		typed := ""
+		prompt := &survey.Input{
+			Message: fmt.Sprintf("Type the app name (%s) to destroy it:", appName),
+		}
+		if err := survey.AskOne(prompt, &typed); err != nil {
+			if isInterrupt(err) {
+				return nil
+			}
 			return err
 		}
 
-		if !confirm {
+		if typed != appName {
+			fmt.Println("App name didn't match, not destroying", appName)
 			return nil
 		}
 	}
@@ -56,3 +80,44 @@ func runDestroy(ctx *cmdctx.CmdContext) error {
 
 	return nil
 }
+
+// destroyPlan describes what destroying the app deletes or leaves orphaned
+func destroyPlan(client *api.Client, appName string) ([]string, error) {
+	inv, err := fetchMoveInventory(client, appName)
+	if err != nil {
+		return nil, err
+	}
+	attachments, err := client.ListPostgresAttachments(appName)
+	if err != nil {
+		return nil, err
+	}
+
+	var plan []string
+	for _, vol := range inv.Volumes {
+		plan = append(plan, fmt.Sprintf("Volume %s (%s, %dGB in %s) and its data will be deleted", vol.ID, vol.Name, vol.SizeGb, vol.Region))
+	}
+	for _, cert := range inv.Certificates {
+		plan = append(plan, fmt.Sprintf("Certificate for %s will be deleted", cert.Hostname))
+	}
+	for _, ip := range inv.IPs {
+		plan = append(plan, fmt.Sprintf("IP address %s (%s) will be released", ip.Address, ip.Type))
+	}
+	for _, a := range attachments {
+		if a.PostgresClusterApp.Name == appName {
+			plan = append(plan, fmt.Sprintf("Database %s will be deleted, %s will lose %s", a.DatabaseName, a.App.Name, a.EnvironmentVariableName))
+		} else {
+			plan = append(plan, fmt.Sprintf("Database %s and user %s on %s will be orphaned, remove them with flyctl postgres detach", a.DatabaseName, a.DatabaseUser, a.PostgresClusterApp.Name))
+		}
+	}
+
+	return plan, nil
+}
+
+func printDestroyPlan(plan []string) {
+	if len(plan) == 0 {
+		fmt.Println("  nothing besides the app itself")
+	}
+	for _, line := range plan {
+		fmt.Println("  " + line)
+	}
+}
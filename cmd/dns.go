@@ -30,7 +30,7 @@ func newDNSCommand(client *client.Client) *Command {
 	})
 
 	recordsImportStrings := docstrings.Get("dns-records.import")
-	recordsImportCmd := BuildCommandKS(cmd, runRecordsImport, recordsImportStrings, client, requireSession)
+	recordsImportCmd := BuildCommandKS(cmd, runRecordsImport, recordsImportStrings, client, requireSession, mutating)
 	recordsImportCmd.Args = cobra.MaximumNArgs(3)
 	recordsImportCmd.Args = cobra.MinimumNArgs(1)
 
@@ -27,10 +27,10 @@ func newDomainsCommand(client *client.Client) *Command {
 	showCmd := BuildCommandKS(cmd, runDomainsShow, docstrings.Get("domains.show"), client, requireSession)
 	showCmd.Args = cobra.ExactArgs(1)
 
-	addCmd := BuildCommandKS(cmd, runDomainsCreate, docstrings.Get("domains.add"), client, requireSession)
+	addCmd := BuildCommandKS(cmd, runDomainsCreate, docstrings.Get("domains.add"), client, requireSession, mutating)
 	addCmd.Args = cobra.MaximumNArgs(2)
 
-	registerCmd := BuildCommandKS(cmd, runDomainsRegister, docstrings.Get("domains.register"), client, requireSession)
+	registerCmd := BuildCommandKS(cmd, runDomainsRegister, docstrings.Get("domains.register"), client, requireSession, mutating)
 	registerCmd.Args = cobra.MaximumNArgs(2)
 
 	return cmd
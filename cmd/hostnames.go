@@ -0,0 +1,240 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/spf13/cobra"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/cmdctx"
+	"github.com/superfly/flyctl/docstrings"
+	"github.com/superfly/flyctl/flyname"
+	"github.com/superfly/flyctl/helpers"
+	"github.com/superfly/flyctl/internal/client"
+)
+
+func newHostnamesCommand(client *client.Client) *Command {
+	hostnamesStrings := docstrings.Get("hostnames")
+	cmd := BuildCommandKS(nil, nil, hostnamesStrings, client, requireSession, requireAppName)
+
+	listStrings := docstrings.Get("hostnames.list")
+	BuildCommandKS(cmd, runHostnamesList, listStrings, client, requireSession, requireAppName)
+
+	addStrings := docstrings.Get("hostnames.add")
+	addCmd := BuildCommandKS(cmd, runHostnamesAdd, addStrings, client, requireSession, requireAppName, mutating)
+	addCmd.Args = cobra.ExactArgs(1)
+	addCmd.AddBoolFlag(BoolFlagOpts{
+		Name:        "internal",
+		Description: "Only resolve the hostname on the organization's private network",
+	})
+
+	removeStrings := docstrings.Get("hostnames.remove")
+	removeCmd := BuildCommandKS(cmd, runHostnamesRemove, removeStrings, client, requireSession, requireAppName, mutating)
+	removeCmd.Aliases = []string{"delete"}
+	removeCmd.Args = cobra.ExactArgs(1)
+	removeCmd.AddBoolFlag(BoolFlagOpts{Name: "yes", Shorthand: "y", Description: "accept all confirmations"})
+
+	return cmd
+}
+
+const (
+	hostnameTypeDefault  = "default"
+	hostnameTypePublic   = "public"
+	hostnameTypeInternal = "internal"
+)
+
+type hostnameCoverage struct {
+	Hostname          string   `json:"hostname"`
+	Type              string   `json:"type"`
+	Certificate       string   `json:"certificate,omitempty"`
+	CertificateStatus string   `json:"certificateStatus,omitempty"`
+	Services          []string `json:"services"`
+}
+
+func runHostnamesList(cmdCtx *cmdctx.CmdContext) error {
+	apiClient := cmdCtx.Client.API()
+
+	app, err := apiClient.GetAppCompact(cmdCtx.AppName)
+	if err != nil {
+		return err
+	}
+	aliases, err := apiClient.GetAppHostnames(cmdCtx.AppName)
+	if err != nil {
+		return err
+	}
+	certs, err := apiClient.GetAppCertificates(cmdCtx.AppName)
+	if err != nil {
+		return err
+	}
+
+	hostnames := hostnameCoverages(app, aliases, certs)
+
+	if cmdCtx.OutputJSON() {
+		cmdCtx.WriteJSON(hostnames)
+		return nil
+	}
+
+	table := helpers.MakeSimpleTable(cmdCtx.Out, []string{"Hostname", "Type", "Certificate", "Services"})
+	for _, h := range hostnames {
+		cert := "-"
+		if h.Certificate != "" {
+			cert = fmt.Sprintf("%s (%s)", h.Certificate, h.CertificateStatus)
+		}
+		services := "-"
+		if len(h.Services) > 0 {
+			services = strings.Join(h.Services, ", ")
+		}
+		table.Append([]string{h.Hostname, h.Type, cert, services})
+	}
+	table.Render()
+
+	return nil
+}
+
+// hostnameCoverages lists every hostname of an app, with the certificate and service ports that serve it.
+// Hostnames with a certificate are included even when they weren't added as aliases.
+func hostnameCoverages(app *api.AppCompact, aliases []api.AppHostname, certs []api.AppCertificateCompact) []hostnameCoverage {
+	var publicPorts, internalPorts []string
+	seenInternal := map[int]bool{}
+	for _, svc := range app.Services {
+		for _, p := range svc.Ports {
+			port := fmt.Sprintf("%d → %d", p.Port, svc.InternalPort)
+			if len(p.Handlers) > 0 {
+				port = fmt.Sprintf("%d/%s → %d", p.Port, strings.Join(p.Handlers, "+"), svc.InternalPort)
+			}
+			publicPorts = append(publicPorts, port)
+		}
+		if !seenInternal[svc.InternalPort] {
+			seenInternal[svc.InternalPort] = true
+			internalPorts = append(internalPorts, fmt.Sprint(svc.InternalPort))
+		}
+	}
+
+	defaultHostname := app.Hostname
+	if defaultHostname == "" {
+		defaultHostname = app.Name + ".fly.dev"
+	}
+
+	out := []hostnameCoverage{
+		{Hostname: defaultHostname, Type: hostnameTypeDefault, Certificate: "*.fly.dev", CertificateStatus: "managed by Fly", Services: publicPorts},
+		{Hostname: app.Name + ".internal", Type: hostnameTypeInternal, Services: internalPorts},
+	}
+
+	public := map[string]bool{}
+	var others []hostnameCoverage
+	for _, a := range aliases {
+		h := hostnameCoverage{Hostname: a.Hostname, Type: hostnameTypePublic, Services: publicPorts}
+		if a.Internal {
+			h.Type, h.Services = hostnameTypeInternal, internalPorts
+		} else {
+			public[a.Hostname] = true
+		}
+		others = append(others, h)
+	}
+	for _, c := range certs {
+		if !public[c.Hostname] {
+			public[c.Hostname] = true
+			others = append(others, hostnameCoverage{Hostname: c.Hostname, Type: hostnameTypePublic, Services: publicPorts})
+		}
+	}
+
+	sort.Slice(others, func(i, j int) bool { return others[i].Hostname < others[j].Hostname })
+
+	for i, h := range others {
+		if h.Type != hostnameTypePublic {
+			continue
+		}
+		if cert := coveringCertificate(h.Hostname, certs); cert != nil {
+			others[i].Certificate, others[i].CertificateStatus = cert.Hostname, cert.ClientStatus
+		}
+	}
+
+	return append(out, others...)
+}
+
+// coveringCertificate finds the certificate for a hostname, either for the exact name or a wildcard one level up
+func coveringCertificate(hostname string, certs []api.AppCertificateCompact) *api.AppCertificateCompact {
+	var wildcard *api.AppCertificateCompact
+
+	for i, c := range certs {
+		if c.Hostname == hostname {
+			return &certs[i]
+		}
+		if dot := strings.Index(hostname, "."); dot > 0 && c.Hostname == "*"+hostname[dot:] {
+			wildcard = &certs[i]
+		}
+	}
+
+	return wildcard
+}
+
+func runHostnamesAdd(cmdCtx *cmdctx.CmdContext) error {
+	hostname := strings.ToLower(cmdCtx.Args[0])
+	internal := cmdCtx.Config.GetBool("internal")
+
+	if internal && !strings.HasSuffix(hostname, ".internal") {
+		return fmt.Errorf("internal hostnames must end with .internal, like %s.internal", strings.Split(hostname, ".")[0])
+	}
+	if !internal && strings.HasSuffix(hostname, ".internal") {
+		return fmt.Errorf("%s can only be added as an internal hostname, use --internal", hostname)
+	}
+
+	added, err := cmdCtx.Client.API().AddHostname(cmdCtx.AppName, hostname, internal)
+	if err != nil {
+		return err
+	}
+
+	if added.Internal {
+		fmt.Fprintf(cmdCtx.Out, "Added %s, it resolves to %s instances on your organization's private network\n", added.Hostname, cmdCtx.AppName)
+		return nil
+	}
+
+	fmt.Fprintf(cmdCtx.Out, "Added %s to %s\n", added.Hostname, cmdCtx.AppName)
+
+	certs, err := cmdCtx.Client.API().GetAppCertificates(cmdCtx.AppName)
+	if err != nil {
+		return err
+	}
+	if coveringCertificate(added.Hostname, certs) == nil {
+		fmt.Fprintf(cmdCtx.Out, "No certificate covers %s yet, add one with `%s certs add %s`\n", added.Hostname, flyname.Name(), added.Hostname)
+	}
+
+	return nil
+}
+
+func runHostnamesRemove(cmdCtx *cmdctx.CmdContext) error {
+	hostname := strings.ToLower(cmdCtx.Args[0])
+
+	if !cmdCtx.Config.GetBool("yes") {
+		confirm := false
+		prompt := &survey.Confirm{
+			Message: fmt.Sprintf("Remove hostname %s from app %s?", hostname, cmdCtx.AppName),
+		}
+		if err := survey.AskOne(prompt, &confirm); err != nil {
+			return err
+		}
+		if !confirm {
+			return nil
+		}
+	}
+
+	if err := cmdCtx.Client.API().RemoveHostname(cmdCtx.AppName, hostname); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmdCtx.Out, "Removed %s from %s\n", hostname, cmdCtx.AppName)
+
+	certs, err := cmdCtx.Client.API().GetAppCertificates(cmdCtx.AppName)
+	if err != nil {
+		return err
+	}
+	for _, c := range certs {
+		if c.Hostname == hostname {
+			fmt.Fprintf(cmdCtx.Out, "The certificate for %s is still attached, remove it with `%s certs remove %s`\n", hostname, flyname.Name(), hostname)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,622 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/logrusorgru/aurora"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/cmdctx"
+	"github.com/superfly/flyctl/docstrings"
+	"github.com/superfly/flyctl/flyctl"
+	"github.com/superfly/flyctl/helpers"
+	"github.com/superfly/flyctl/internal/client"
+	"github.com/superfly/flyctl/internal/dockerconfig"
+	"github.com/superfly/flyctl/internal/registry"
+	"github.com/superfly/flyctl/terminal"
+)
+
+func newImageCommand(client *client.Client) *Command {
+	imageStrings := docstrings.Get("image")
+	cmd := BuildCommandKS(nil, nil, imageStrings, client, requireSession, requireAppName)
+
+	diffStrings := docstrings.Get("image.diff")
+	diffCmd := BuildCommandKS(cmd, runImageDiff, diffStrings, client, requireSession, requireAppName)
+	diffCmd.Args = cobra.ExactArgs(2)
+	diffCmd.AddIntFlag(IntFlagOpts{
+		Name:        "limit",
+		Description: "Maximum number of changed files to show",
+		Default:     25,
+	})
+
+	pushStrings := docstrings.Get("image.push")
+	pushCmd := BuildCommandKS(cmd, runImagePush, pushStrings, client, requireSession, requireAppName, mutating)
+	pushCmd.Args = cobra.MaximumNArgs(1)
+	pushCmd.AddStringSliceFlag(StringSliceFlagOpts{
+		Name:        "registry",
+		Description: "Image reference to push to, like ghcr.io/me/app:tag. Can be specified multiple times.",
+	})
+	pushCmd.AddIntFlag(pushConcurrencyFlag)
+
+	showStrings := docstrings.Get("image.show")
+	showCmd := BuildCommandKS(cmd, runImageShow, showStrings, client, requireSession, requireAppName)
+	showCmd.Args = cobra.MaximumNArgs(1)
+
+	historyStrings := docstrings.Get("image.history")
+	historyCmd := BuildCommandKS(cmd, runImageHistory, historyStrings, client, requireSession, requireAppName)
+	historyCmd.Args = cobra.MaximumNArgs(1)
+
+	digestStrings := docstrings.Get("image.digest")
+	digestCmd := BuildCommandKS(cmd, runImageDigest, digestStrings, client, requireSession, requireAppName)
+	digestCmd.Args = cobra.MaximumNArgs(1)
+
+	tagsStrings := docstrings.Get("image.tags")
+	tagsCmd := BuildCommandKS(cmd, runImageTags, tagsStrings, client, requireSession, requireAppName)
+	tagsCmd.Args = cobra.NoArgs
+	tagsCmd.AddIntFlag(IntFlagOpts{
+		Name:        "limit",
+		Description: "Maximum number of tags to show, 0 for all",
+		Default:     20,
+	})
+
+	pinStrings := docstrings.Get("image.pin")
+	pinCmd := BuildCommandKS(cmd, runImagePin, pinStrings, client, requireSession, requireAppName, mutating)
+	pinCmd.Args = cobra.ExactArgs(1)
+	pinCmd.AddBoolFlag(BoolFlagOpts{
+		Name:        "detach",
+		Description: "Return immediately instead of monitoring deployment progress",
+	})
+	addDeployLockFlags(pinCmd)
+
+	return cmd
+}
+
+func parseReleaseVersion(arg string) (int, error) {
+	version, err := strconv.Atoi(strings.TrimPrefix(strings.ToLower(arg), "v"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid release version \"%s\", expected a version like v42", arg)
+	}
+	return version, nil
+}
+
+func releaseImageRef(cmdCtx *cmdctx.CmdContext, arg string) (registry.Reference, error) {
+	version, err := parseReleaseVersion(arg)
+	if err != nil {
+		return registry.Reference{}, err
+	}
+
+	release, err := cmdCtx.Client.API().GetAppRelease(cmdCtx.AppName, version)
+	if err != nil {
+		if err == api.ErrNotFound {
+			return registry.Reference{}, fmt.Errorf("release v%d not found", version)
+		}
+		return registry.Reference{}, err
+	}
+	if release.ImageRef == "" {
+		return registry.Reference{}, fmt.Errorf("release v%d does not have an image", version)
+	}
+
+	return registry.ParseReference(release.ImageRef)
+}
+
+// appImageRef returns the image of the release given in args, or of the latest release
+func appImageRef(cmdCtx *cmdctx.CmdContext) (registry.Reference, error) {
+	if len(cmdCtx.Args) > 0 {
+		return releaseImageRef(cmdCtx, cmdCtx.Args[0])
+	}
+
+	releases, err := cmdCtx.Client.API().GetAppReleases(cmdCtx.AppName, 1)
+	if err != nil {
+		return registry.Reference{}, err
+	}
+	if len(releases) == 0 || releases[0].ImageRef == "" {
+		return registry.Reference{}, fmt.Errorf("%s does not have a deployed image", cmdCtx.AppName)
+	}
+	return registry.ParseReference(releases[0].ImageRef)
+}
+
+func newRegistryClient(host string) *registry.Client {
+	return registry.NewClient(host, "x", flyctl.GetAPIToken())
+}
+
+// registryClientFor authenticates to the fly registry with the API token, and to other
+// registries with the credentials saved by `docker login`
+func registryClientFor(host string) (*registry.Client, error) {
+	if host == viper.GetString(flyctl.ConfigRegistryHost) {
+		return newRegistryClient(host), nil
+	}
+
+	dockerCfg, err := dockerconfig.Load()
+	if err != nil {
+		return nil, err
+	}
+	username, password, _ := dockerCfg.Credentials(host)
+
+	return registry.NewClient(host, username, password), nil
+}
+
+var pushConcurrencyFlag = IntFlagOpts{
+	Name:        "push-concurrency",
+	Description: "Number of image layers to upload at once",
+	Default:     registry.DefaultPushConcurrency,
+}
+
+// pushConcurrency reads the --push-concurrency flag
+func pushConcurrency(cmdCtx *cmdctx.CmdContext) (int, error) {
+	n := cmdCtx.Config.GetInt("push-concurrency")
+	if n < 1 {
+		return 0, fmt.Errorf("--push-concurrency must be 1 or more, got %d", n)
+	}
+	return n, nil
+}
+
+// pushImage copies an image to another registry, returning the pushed digest
+func pushImage(ctx context.Context, cmdCtx *cmdctx.CmdContext, source, target string) (string, error) {
+	concurrency, err := pushConcurrency(cmdCtx)
+	if err != nil {
+		return "", err
+	}
+
+	from, err := registry.ParseReference(source)
+	if err != nil {
+		return "", err
+	}
+	to, err := registry.ParseReference(target)
+	if err != nil {
+		return "", err
+	}
+
+	src, err := registryClientFor(from.Host)
+	if err != nil {
+		return "", err
+	}
+	dst, err := registryClientFor(to.Host)
+	if err != nil {
+		return "", err
+	}
+
+	msg := fmt.Sprintf("Pushing %s", to)
+	cmdCtx.IO.StartProgressIndicatorMsg(msg)
+	digest, err := registry.CopyImage(ctx, src, from, dst, to, concurrency, func(done, total int64) {
+		cmdCtx.IO.ChangeProgressIndicatorMsg(fmt.Sprintf("%s (%s / %s)", msg, humanize.Bytes(uint64(done)), humanize.Bytes(uint64(total))))
+	})
+	cmdCtx.IO.StopProgressIndicator()
+	if err != nil {
+		var unauthorized *registry.UnauthorizedError
+		if errors.As(err, &unauthorized) {
+			return "", fmt.Errorf("not authorized to push to %s, log in with `docker login %s` and try again", to, to.Host)
+		}
+		return "", errors.Wrapf(err, "error pushing to %s", to)
+	}
+
+	return digest, nil
+}
+
+func runImagePush(cmdCtx *cmdctx.CmdContext) error {
+	ctx := createCancellableContext()
+
+	targets := cmdCtx.Config.GetStringSlice("registry")
+	if len(targets) == 0 {
+		return errors.New("--registry is required")
+	}
+
+	source, err := appImageRef(cmdCtx)
+	if err != nil {
+		return err
+	}
+
+	for _, target := range targets {
+		digest, err := pushImage(ctx, cmdCtx, source.String(), target)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmdCtx.Out, "Pushed %s to %s (%s)\n", source, target, digest)
+	}
+
+	return nil
+}
+
+func runImageDiff(cmdCtx *cmdctx.CmdContext) error {
+	ctx := createCancellableContext()
+
+	from, err := releaseImageRef(cmdCtx, cmdCtx.Args[0])
+	if err != nil {
+		return err
+	}
+	to, err := releaseImageRef(cmdCtx, cmdCtx.Args[1])
+	if err != nil {
+		return err
+	}
+	if from.Host != to.Host {
+		return fmt.Errorf("images are stored in different registries (%s, %s)", from.Host, to.Host)
+	}
+
+	cmdCtx.IO.StartProgressIndicatorMsg("Comparing images...")
+	diff, err := registry.DiffImages(ctx, newRegistryClient(from.Host), from, to)
+	cmdCtx.IO.StopProgressIndicator()
+	if err != nil {
+		return err
+	}
+
+	if cmdCtx.OutputJSON() {
+		cmdCtx.WriteJSON(diff)
+		return nil
+	}
+
+	out := cmdCtx.Out
+
+	fmt.Fprintf(out, "From: %s (%s)\n", diff.From, humanize.Bytes(uint64(diff.FromSize)))
+	fmt.Fprintf(out, "To:   %s (%s)\n", diff.To, humanize.Bytes(uint64(diff.ToSize)))
+	fmt.Fprintf(out, "Compressed size change: %s\n\n", formatSizeDelta(diff.ToSize-diff.FromSize))
+
+	fmt.Fprintln(out, aurora.Bold("Layers"))
+	table := helpers.MakeSimpleTable(out, []string{"Change", "Digest", "Size", "Created By"})
+	for _, l := range diff.Layers {
+		table.Append([]string{l.Change, shortDigest(l.Digest), humanize.Bytes(uint64(l.Size)), truncate(l.CreatedBy, 60)})
+	}
+	table.Render()
+	fmt.Fprintln(out)
+
+	fmt.Fprintln(out, aurora.Bold("Files"))
+	if len(diff.Files) == 0 {
+		fmt.Fprintln(out, "No file changes")
+	} else {
+		limit := cmdCtx.Config.GetInt("limit")
+		table = helpers.MakeSimpleTable(out, []string{"Change", "Path", "Old Size", "New Size", "Delta"})
+		for i, f := range diff.Files {
+			if limit > 0 && i >= limit {
+				break
+			}
+			table.Append([]string{f.Change, f.Path, humanize.Bytes(uint64(f.OldSize)), humanize.Bytes(uint64(f.NewSize)), formatSizeDelta(f.SizeDelta())})
+		}
+		table.Render()
+		if limit > 0 && len(diff.Files) > limit {
+			fmt.Fprintf(out, "... and %d more, use --limit to show more\n", len(diff.Files)-limit)
+		}
+	}
+	fmt.Fprintln(out)
+
+	fmt.Fprintln(out, aurora.Bold("Packages"))
+	if len(diff.Packages) == 0 {
+		fmt.Fprintln(out, "No package changes")
+		return nil
+	}
+	table = helpers.MakeSimpleTable(out, []string{"Change", "Package", "Old Version", "New Version"})
+	for _, p := range diff.Packages {
+		table.Append([]string{p.Change, p.Name, p.OldVersion, p.NewVersion})
+	}
+	table.Render()
+
+	return nil
+}
+
+func inspectAppImage(ctx context.Context, cmdCtx *cmdctx.CmdContext) (*registry.ImageInfo, error) {
+	ref, err := appImageRef(cmdCtx)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := registryClientFor(ref.Host)
+	if err != nil {
+		return nil, err
+	}
+
+	cmdCtx.IO.StartProgressIndicatorMsg("Fetching image details...")
+	info, err := registry.InspectImage(ctx, c, ref)
+	cmdCtx.IO.StopProgressIndicator()
+	return info, err
+}
+
+func runImageShow(cmdCtx *cmdctx.CmdContext) error {
+	ctx := createCancellableContext()
+
+	info, err := inspectAppImage(ctx, cmdCtx)
+	if err != nil {
+		return err
+	}
+
+	// images built before provenance was recorded, or pushed from elsewhere, have none
+	if ref, err := registry.ParseReference(info.Reference); err == nil {
+		if c, err := registryClientFor(ref.Host); err == nil {
+			if info.Provenance, err = registry.GetProvenance(ctx, c, ref.Repository, info.Digest); err != nil {
+				terminal.Warnf("Could not fetch the image's provenance: %v\n", err)
+			}
+		}
+	}
+
+	if cmdCtx.OutputJSON() {
+		cmdCtx.WriteJSON(info)
+		return nil
+	}
+
+	out := cmdCtx.Out
+
+	fmt.Fprintf(out, "%-12s %s\n", "Image:", info.Reference)
+	fmt.Fprintf(out, "%-12s %s\n", "Digest:", info.Digest)
+	fmt.Fprintf(out, "%-12s %s\n", "Created:", info.Created)
+	fmt.Fprintf(out, "%-12s %s/%s\n", "Platform:", info.OS, info.Architecture)
+	fmt.Fprintf(out, "%-12s %s\n", "Size:", humanize.Bytes(uint64(info.Size)))
+	if len(info.Entrypoint) > 0 {
+		fmt.Fprintf(out, "%-12s %s\n", "Entrypoint:", strings.Join(info.Entrypoint, " "))
+	}
+	if len(info.Cmd) > 0 {
+		fmt.Fprintf(out, "%-12s %s\n", "Cmd:", strings.Join(info.Cmd, " "))
+	}
+	if info.WorkingDir != "" {
+		fmt.Fprintf(out, "%-12s %s\n", "WorkingDir:", info.WorkingDir)
+	}
+	fmt.Fprintln(out)
+
+	fmt.Fprintln(out, aurora.Bold("Labels"))
+	if len(info.Labels) == 0 {
+		fmt.Fprintln(out, "No labels")
+	} else {
+		keys := make([]string, 0, len(info.Labels))
+		for k := range info.Labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		table := helpers.MakeSimpleTable(out, []string{"Name", "Value"})
+		for _, k := range keys {
+			table.Append([]string{k, info.Labels[k]})
+		}
+		table.Render()
+	}
+	fmt.Fprintln(out)
+
+	fmt.Fprintln(out, aurora.Bold("Provenance"))
+	if info.Provenance == nil {
+		fmt.Fprintln(out, "No provenance recorded")
+	} else {
+		printProvenance(out, info.Provenance)
+	}
+	fmt.Fprintln(out)
+
+	fmt.Fprintln(out, aurora.Bold("Layers"))
+	table := helpers.MakeSimpleTable(out, []string{"Digest", "Size", "Created By"})
+	for _, l := range info.Layers {
+		table.Append([]string{shortDigest(l.Digest), humanize.Bytes(uint64(l.Size)), truncate(l.CreatedBy, 60)})
+	}
+	table.Render()
+
+	return nil
+}
+
+func printProvenance(out io.Writer, p *registry.Provenance) {
+	source := p.Invocation.ConfigSource
+	params := p.Invocation.Parameters
+
+	fmt.Fprintf(out, "%-12s %s\n", "Builder:", p.Builder.ID)
+	fmt.Fprintf(out, "%-12s %s\n", "Build type:", p.BuildType)
+	if source.URI != "" {
+		fmt.Fprintf(out, "%-12s %s\n", "Source:", source.URI)
+	}
+	if commit := source.Digest["sha1"]; commit != "" {
+		if params["sourceDirty"] == "true" {
+			commit += " (with uncommitted changes)"
+		}
+		fmt.Fprintf(out, "%-12s %s\n", "Revision:", commit)
+	} else {
+		fmt.Fprintf(out, "%-12s %s\n", "Revision:", "unknown, not built from a git checkout")
+	}
+	if source.EntryPoint != "" {
+		fmt.Fprintf(out, "%-12s %s\n", "Dockerfile:", source.EntryPoint)
+	}
+	if params["target"] != "" {
+		fmt.Fprintf(out, "%-12s %s\n", "Target:", params["target"])
+	}
+	if params["buildArgs"] != "" {
+		fmt.Fprintf(out, "%-12s %s\n", "Build args:", params["buildArgs"])
+	}
+	if p.Metadata.BuildFinishedOn != nil {
+		fmt.Fprintf(out, "%-12s %s\n", "Built:", p.Metadata.BuildFinishedOn.Format(time.RFC3339))
+	}
+}
+
+func runImageHistory(cmdCtx *cmdctx.CmdContext) error {
+	info, err := inspectAppImage(createCancellableContext(), cmdCtx)
+	if err != nil {
+		return err
+	}
+
+	if cmdCtx.OutputJSON() {
+		cmdCtx.WriteJSON(info.History)
+		return nil
+	}
+
+	// history is oldest first, show it newest first like docker history
+	table := helpers.MakeSimpleTable(cmdCtx.Out, []string{"Created", "Size", "Created By"})
+	layer := len(info.Layers) - 1
+	for i := len(info.History) - 1; i >= 0; i-- {
+		h := info.History[i]
+		size := "0 B"
+		if !h.EmptyLayer && layer >= 0 {
+			size = humanize.Bytes(uint64(info.Layers[layer].Size))
+			layer--
+		}
+		table.Append([]string{h.Created, size, truncate(h.CreatedBy, 80)})
+	}
+	table.Render()
+
+	return nil
+}
+
+func runImageDigest(cmdCtx *cmdctx.CmdContext) error {
+	ref, err := appImageRef(cmdCtx)
+	if err != nil {
+		return err
+	}
+
+	c, err := registryClientFor(ref.Host)
+	if err != nil {
+		return err
+	}
+
+	m, err := c.GetManifest(createCancellableContext(), ref.Repository, ref.Identifier())
+	if err != nil {
+		return errors.Wrapf(err, "error fetching manifest for %s", ref)
+	}
+
+	fmt.Fprintln(cmdCtx.Out, m.Digest)
+	return nil
+}
+
+func runImageTags(cmdCtx *cmdctx.CmdContext) error {
+	ctx := createCancellableContext()
+	host := viper.GetString(flyctl.ConfigRegistryHost)
+
+	tags, err := newRegistryClient(host).ListTags(ctx, cmdCtx.AppName)
+	if err != nil {
+		var notFound *registry.NotFoundError
+		if errors.As(err, &notFound) {
+			return fmt.Errorf("%s has no images in %s", cmdCtx.AppName, host)
+		}
+		return err
+	}
+	tags = registry.SortDeploymentTags(tags)
+
+	if limit := cmdCtx.Config.GetInt("limit"); limit > 0 && len(tags) > limit {
+		tags = tags[:limit]
+	}
+
+	if cmdCtx.OutputJSON() {
+		cmdCtx.WriteJSON(tags)
+		return nil
+	}
+
+	table := helpers.MakeSimpleTable(cmdCtx.Out, []string{"Image", "Pushed"})
+	for _, tag := range tags {
+		pushed := ""
+		if ts, err := strconv.ParseInt(strings.TrimPrefix(tag, registry.DeploymentTagPrefix), 10, 64); err == nil {
+			pushed = humanize.Time(time.Unix(ts, 0))
+		}
+		table.Append([]string{fmt.Sprintf("%s/%s:%s", host, cmdCtx.AppName, tag), pushed})
+	}
+	table.Render()
+
+	return nil
+}
+
+// pinnedImageRef resolves a release version, deployment tag or digest to an image reference by digest
+func pinnedImageRef(ctx context.Context, cmdCtx *cmdctx.CmdContext, arg string) (registry.Reference, error) {
+	var ref registry.Reference
+
+	switch {
+	case strings.HasPrefix(arg, "sha256:"):
+		return registry.Reference{Host: viper.GetString(flyctl.ConfigRegistryHost), Repository: cmdCtx.AppName, Digest: arg}, nil
+	case strings.Contains(arg, "/"):
+		parsed, err := registry.ParseReference(arg)
+		if err != nil {
+			return ref, err
+		}
+		ref = parsed
+	case strings.HasPrefix(arg, registry.DeploymentTagPrefix):
+		ref = registry.Reference{Host: viper.GetString(flyctl.ConfigRegistryHost), Repository: cmdCtx.AppName, Tag: arg}
+	default:
+		parsed, err := releaseImageRef(cmdCtx, arg)
+		if err != nil {
+			return ref, err
+		}
+		ref = parsed
+	}
+
+	if ref.Digest != "" {
+		return registry.Reference{Host: ref.Host, Repository: ref.Repository, Digest: ref.Digest}, nil
+	}
+
+	c, err := registryClientFor(ref.Host)
+	if err != nil {
+		return ref, err
+	}
+	m, err := c.GetManifest(ctx, ref.Repository, ref.Tag)
+	if err != nil {
+		return ref, errors.Wrapf(err, "error fetching manifest for %s", ref)
+	}
+	if m.Digest == "" {
+		return ref, fmt.Errorf("registry did not return a digest for %s", ref)
+	}
+
+	return registry.Reference{Host: ref.Host, Repository: ref.Repository, Digest: m.Digest}, nil
+}
+
+func runImagePin(cmdCtx *cmdctx.CmdContext) error {
+	ctx := createCancellableContext()
+
+	ref, err := pinnedImageRef(ctx, cmdCtx, cmdCtx.Args[0])
+	if err != nil {
+		return err
+	}
+
+	ctx, releaseLock, err := holdDeployLock(ctx, cmdCtx)
+	if err != nil {
+		return err
+	}
+	defer releaseLock()
+
+	// the release keeps the app's current configuration, only the image changes
+	release, err := cmdCtx.Client.API().DeployImage(api.DeployImageInput{
+		AppID: cmdCtx.AppName,
+		Image: ref.String(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return followRelease(ctx, cmdCtx, release, ref.String())
+}
+
+// followRelease reports a release created with image and monitors its deployment, unless it's
+// waiting for approval or deploys immediately
+func followRelease(ctx context.Context, cmdCtx *cmdctx.CmdContext, release *api.Release, image string) error {
+	fmt.Fprintf(cmdCtx.Out, "Release v%d created with %s\n", release.Version, image)
+	cmdCtx.SetResult("release_version", strconv.Itoa(release.Version))
+	cmdCtx.SetResult("image", image)
+
+	if release.PendingApproval() {
+		event := approvalRequestedEvent(cmdCtx.AppName, release, image)
+		fmt.Fprintf(cmdCtx.Out, "Release v%d is waiting for approval. Another member of the organization can approve it with `%s`\n", release.Version, event.ApproveCommand)
+		notifyApproval(cmdCtx, event)
+		return nil
+	}
+
+	if err := watchReleaseCommand(ctx, cmdCtx, release); err != nil {
+		return err
+	}
+
+	if release.DeploymentStrategy == "IMMEDIATE" {
+		return nil
+	}
+
+	return watchDeployment(ctx, cmdCtx)
+}
+
+func formatSizeDelta(delta int64) string {
+	if delta < 0 {
+		return "-" + humanize.Bytes(uint64(-delta))
+	}
+	return "+" + humanize.Bytes(uint64(delta))
+}
+
+func shortDigest(digest string) string {
+	digest = strings.TrimPrefix(digest, "sha256:")
+	if len(digest) > 12 {
+		return digest[:12]
+	}
+	return digest
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	return s[:max-3] + "..."
+}
@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/pkg/errors"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/cmdctx"
+	"github.com/superfly/flyctl/helpers"
+	"github.com/superfly/flyctl/internal/client"
+	"github.com/superfly/flyctl/internal/labels"
+
+	"github.com/spf13/cobra"
+	"github.com/superfly/flyctl/docstrings"
+)
+
+func newAppsLabelCommand(parent *Command, client *client.Client) {
+	labelStrings := docstrings.Get("apps.label")
+	labelCmd := BuildCommandKS(parent, nil, labelStrings, client, requireSession)
+
+	setStrings := docstrings.Get("apps.label.set")
+	setCmd := BuildCommandKS(labelCmd, runAppsLabelSet, setStrings, client, requireSession, requireAppName, mutating)
+	setCmd.Args = cobra.MinimumNArgs(1)
+
+	unsetStrings := docstrings.Get("apps.label.unset")
+	unsetCmd := BuildCommandKS(labelCmd, runAppsLabelUnset, unsetStrings, client, requireSession, requireAppName, mutating)
+	unsetCmd.Args = cobra.MinimumNArgs(1)
+
+	listStrings := docstrings.Get("apps.label.list")
+	BuildCommandKS(labelCmd, runAppsLabelList, listStrings, client, requireSession, requireAppName)
+}
+
+func runAppsLabelSet(ctx *cmdctx.CmdContext) error {
+	set, err := labels.Parse(ctx.Args)
+	if err != nil {
+		return err
+	}
+
+	current, err := ctx.Client.API().SetAppLabels(api.SetAppLabelsInput{AppID: ctx.AppName, Labels: set})
+	if err != nil {
+		return err
+	}
+
+	return printAppLabels(ctx, current)
+}
+
+func runAppsLabelUnset(ctx *cmdctx.CmdContext) error {
+	for _, key := range ctx.Args {
+		if err := labels.ValidateKey(key); err != nil {
+			return err
+		}
+	}
+
+	current, err := ctx.Client.API().SetAppLabels(api.SetAppLabelsInput{AppID: ctx.AppName, RemoveKeys: ctx.Args})
+	if err != nil {
+		return err
+	}
+
+	return printAppLabels(ctx, current)
+}
+
+func runAppsLabelList(ctx *cmdctx.CmdContext) error {
+	app, err := ctx.Client.API().GetApp(ctx.AppName)
+	if err != nil {
+		return err
+	}
+
+	return printAppLabels(ctx, app.Labels)
+}
+
+func printAppLabels(ctx *cmdctx.CmdContext, appLabels map[string]string) error {
+	if ctx.OutputJSON() {
+		if appLabels == nil {
+			appLabels = map[string]string{}
+		}
+		ctx.WriteJSON(appLabels)
+		return nil
+	}
+
+	if len(appLabels) == 0 {
+		fmt.Fprintf(ctx.Out, "%s has no labels\n", ctx.AppName)
+		return nil
+	}
+
+	keys := make([]string, 0, len(appLabels))
+	for k := range appLabels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	table := helpers.MakeSimpleTable(ctx.Out, []string{"Key", "Value"})
+	for _, k := range keys {
+		table.Append([]string{k, appLabels[k]})
+	}
+	table.Render()
+
+	return nil
+}
+
+// selectedApps returns the names of the apps matching the context's selector
+func selectedApps(ctx *cmdctx.CmdContext) ([]string, error) {
+	sel, err := labels.ParseSelector(ctx.Selector)
+	if err != nil {
+		return nil, err
+	}
+
+	apps, err := ctx.Client.API().GetApps(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, app := range filterApps(apps, appFilter{Selector: sel}) {
+		names = append(names, app.Name)
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no apps have labels matching %s", sel)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// forEachSelectedApp runs fn for each app matching --selector, or once for the named app without
+// one. Every app is tried, errors are returned together once they've all run.
+func forEachSelectedApp(fn RunFn) RunFn {
+	return func(ctx *cmdctx.CmdContext) error {
+		if ctx.Selector == "" {
+			return fn(ctx)
+		}
+
+		names, err := selectedApps(ctx)
+		if err != nil {
+			return err
+		}
+		return runForApps(ctx, names, fn)
+	}
+}
+
+// forEachConfirmedApp is forEachSelectedApp for commands changing the apps. The apps matching
+// --selector are listed and confirmed first, unless --yes is given.
+func forEachConfirmedApp(action string, fn RunFn) RunFn {
+	return func(ctx *cmdctx.CmdContext) error {
+		if ctx.Selector == "" {
+			return fn(ctx)
+		}
+
+		names, err := selectedApps(ctx)
+		if err != nil {
+			return err
+		}
+
+		if !ctx.Config.GetBool("yes") {
+			fmt.Fprintf(ctx.Out, "Apps with labels matching %s:\n", ctx.Selector)
+			for _, name := range names {
+				fmt.Fprintf(ctx.Out, "  %s\n", name)
+			}
+			if !confirm(fmt.Sprintf("%s %d apps?", action, len(names))) {
+				return ErrAbort
+			}
+		}
+
+		return runForApps(ctx, names, fn)
+	}
+}
+
+// runForApps runs fn with each app in turn
+func runForApps(ctx *cmdctx.CmdContext, names []string, fn RunFn) error {
+	var failed []string
+	for i, name := range names {
+		if i > 0 {
+			fmt.Fprintln(ctx.Out)
+		}
+		if !ctx.OutputJSON() {
+			fmt.Fprintln(ctx.Out, aurora.Bold(name))
+		}
+
+		ctx.AppName = name
+		if err := fn(ctx); err != nil {
+			fmt.Fprintf(ctx.IO.ErrOut, "%s: %s\n", name, err)
+			failed = append(failed, name)
+		}
+	}
+
+	if len(failed) > 0 {
+		return errors.Errorf("failed for %d of %d apps: %v", len(failed), len(names), failed)
+	}
+	return nil
+}
@@ -3,32 +3,44 @@ package cmd
 import (
 	"fmt"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/AlecAivazis/survey/v2"
+	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/superfly/flyctl/cmdctx"
 	"github.com/superfly/flyctl/flyctl"
+	"github.com/superfly/flyctl/flyname"
+	"github.com/superfly/flyctl/helpers"
 	"github.com/superfly/flyctl/internal/client"
+	"github.com/superfly/flyctl/internal/compose"
+	"github.com/superfly/flyctl/internal/scaffold"
 	"github.com/superfly/flyctl/internal/sourcecode"
+	"github.com/superfly/flyctl/terminal"
 
 	"github.com/superfly/flyctl/docstrings"
 )
 
 func newLaunchCommand(client *client.Client) *Command {
 	launchStrings := docstrings.Get("launch")
-	launchCmd := BuildCommandKS(nil, runLaunch, launchStrings, client, requireSession)
+	launchCmd := BuildCommandKS(nil, runLaunch, launchStrings, client, requireSession, mutating)
 	launchCmd.Args = cobra.NoArgs
 	launchCmd.AddStringFlag(StringFlagOpts{Name: "path", Description: `path to app code and where a fly.toml file will be saved.`, Default: "."})
 	launchCmd.AddStringFlag(StringFlagOpts{Name: "org", Description: `the organization that will own the app`})
 	launchCmd.AddStringFlag(StringFlagOpts{Name: "name", Description: "the name of the new app"})
 	launchCmd.AddStringFlag(StringFlagOpts{Name: "region", Description: "the region to launch the new app in"})
 	launchCmd.AddStringFlag(StringFlagOpts{Name: "image", Description: "the image to launch"})
+	launchCmd.AddStringFlag(StringFlagOpts{Name: "from-compose", Description: "a docker-compose file whose services are each launched as an app"})
 
 	return launchCmd
 }
 
 func runLaunch(cmdctx *cmdctx.CmdContext) error {
+	if composePath, _ := cmdctx.Config.GetString("from-compose"); composePath != "" {
+		return runLaunchFromCompose(cmdctx, composePath)
+	}
+
 	dir, _ := cmdctx.Config.GetString("path")
 
 	if absDir, err := filepath.Abs(dir); err == nil {
@@ -42,7 +54,11 @@ func runLaunch(cmdctx *cmdctx.CmdContext) error {
 
 	var srcInfo *sourcecode.SourceInfo
 
-	configFilePath := filepath.Join(dir, "fly.toml")
+	// an existing fly.yaml or fly.json is found too, and written back in its own format
+	configFilePath, err := flyctl.ResolveConfigFileFromPath(dir)
+	if err != nil {
+		return err
+	}
 
 	if exists, _ := flyctl.ConfigFileExistsAtPath(configFilePath); exists {
 		cfg, err := flyctl.LoadAppConfig(configFilePath)
@@ -50,9 +66,9 @@ func runLaunch(cmdctx *cmdctx.CmdContext) error {
 			return err
 		}
 		if cfg.AppName != "" {
-			fmt.Println("An existing fly.toml file was found for app", cfg.AppName)
+			fmt.Printf("An existing %s file was found for app %s\n", filepath.Base(configFilePath), cfg.AppName)
 		} else {
-			fmt.Println("An existing fly.toml file was found")
+			fmt.Printf("An existing %s file was found\n", filepath.Base(configFilePath))
 		}
 		if confirm("Would you like to copy its configuration to the new app?") {
 			appConfig.Definition = cfg.Definition
@@ -153,7 +169,7 @@ func runLaunch(cmdctx *cmdctx.CmdContext) error {
 		}
 	}
 
-	if err := writeAppConfig(filepath.Join(dir, "fly.toml"), appConfig); err != nil {
+	if err := writeAppConfig(configFilePath, appConfig); err != nil {
 		return err
 	}
 
@@ -204,3 +220,100 @@ func runLaunch(cmdctx *cmdctx.CmdContext) error {
 
 	// return nil
 }
+
+// runLaunchFromCompose creates an app for each service of a compose file, named <name>-<service>,
+// and writes a fly.toml for each next to the service's source.
+func runLaunchFromCompose(cmdctx *cmdctx.CmdContext, composePath string) error {
+	project, err := compose.Load(composePath)
+	if err != nil {
+		return err
+	}
+
+	for _, warning := range project.Warnings {
+		terminal.Warn(warning)
+	}
+
+	for _, svc := range project.Services {
+		if helpers.FileExists(project.ConfigPath(svc)) {
+			return fmt.Errorf("%s already exists, remove it to launch service %s", helpers.PathRelativeToCWD(project.ConfigPath(svc)), svc.Name)
+		}
+	}
+
+	prefix, _ := cmdctx.Config.GetString("name")
+	if prefix == "" {
+		prefix = scaffold.AppName(project.Dir)
+	}
+
+	orgSlug, _ := cmdctx.Config.GetString("org")
+	org, err := selectOrganization(cmdctx.Client.API(), orgSlug)
+	if err != nil {
+		return err
+	}
+
+	regionCode, _ := cmdctx.Config.GetString("region")
+	region, err := selectRegion(cmdctx.Client.API(), regionCode)
+	if err != nil {
+		return err
+	}
+
+	appNames := map[string]string{}
+
+	for _, svc := range project.Services {
+		appConfig, warnings := svc.AppConfig(scaffold.AppName(prefix + "-" + svc.Name))
+		for _, warning := range warnings {
+			terminal.Warn(warning)
+		}
+
+		app, err := cmdctx.Client.API().CreateApp(appConfig.AppName, org.ID, &region.Code)
+		if err != nil {
+			return errors.Wrapf(err, "could not create app for service %s", svc.Name)
+		}
+		appConfig.AppName = app.Name
+		appNames[svc.Name] = app.Name
+		fmt.Printf("Created app %s for service %s in organization %s\n", app.Name, svc.Name, org.Slug)
+
+		if len(svc.Secrets) > 0 {
+			if _, err := cmdctx.Client.API().SetSecrets(app.Name, svc.Secrets); err != nil {
+				return errors.Wrapf(err, "could not set secrets of service %s", svc.Name)
+			}
+			keys := make([]string, 0, len(svc.Secrets))
+			for k := range svc.Secrets {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+			fmt.Printf("Set secrets on %s: %s\n", app.Name, strings.Join(keys, ", "))
+		}
+
+		if len(svc.Volumes) > 0 {
+			volName := compose.VolumeName(svc.Volumes[0].Source)
+			vol, err := cmdctx.Client.API().CreateVolume(app.Name, volName, region.Code, defaultVolumeSizeGb, true)
+			if err != nil {
+				return errors.Wrapf(err, "could not create volume %s", volName)
+			}
+			fmt.Printf("Created %dGB volume %s for %s\n", vol.SizeGb, vol.Name, app.Name)
+		}
+
+		if err := writeAppConfig(project.ConfigPath(svc), appConfig); err != nil {
+			return err
+		}
+	}
+
+	fmt.Println("\nDeploy each app with:")
+	for _, svc := range project.Services {
+		configPath := project.ConfigPath(svc)
+		dir := helpers.PathRelativeToCWD(filepath.Dir(configPath))
+		if filepath.Base(configPath) == "fly.toml" {
+			fmt.Printf("  %s deploy %s\n", flyname.Name(), dir)
+		} else {
+			fmt.Printf("  %s deploy %s -c %s\n", flyname.Name(), dir, filepath.Base(configPath))
+		}
+	}
+
+	for _, svc := range project.Services {
+		if dependents := project.Dependents(svc); len(dependents) > 0 {
+			fmt.Printf("\nService %s is now reachable at %s.internal, update references to it in %s\n", svc.Name, appNames[svc.Name], strings.Join(dependents, ", "))
+		}
+	}
+
+	return nil
+}
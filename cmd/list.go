@@ -6,9 +6,11 @@ import (
 	"time"
 
 	"github.com/dustin/go-humanize"
+	"github.com/superfly/flyctl/api"
 	"github.com/superfly/flyctl/cmdctx"
 	"github.com/superfly/flyctl/helpers"
 	"github.com/superfly/flyctl/internal/client"
+	"github.com/superfly/flyctl/internal/labels"
 
 	"github.com/superfly/flyctl/docstrings"
 )
@@ -61,23 +63,71 @@ type appCondensed struct {
 	Hostname     string
 	Organization string
 	CreatedAt    time.Time
+	Labels       map[string]string
+}
+
+func condenseApp(app api.App) appCondensed {
+	var createdAt time.Time
+
+	// CreatedAt may or may not exist
+	if app.Deployed && app.CurrentRelease != nil {
+		createdAt = app.CurrentRelease.CreatedAt
+	}
+
+	return appCondensed{
+		ID:           app.ID,
+		Name:         app.Name,
+		Status:       app.Status,
+		Deployed:     app.Deployed,
+		Hostname:     app.Hostname,
+		Organization: app.Organization.Slug,
+		CreatedAt:    createdAt,
+		Labels:       app.Labels,
+	}
+}
+
+// appFilter selects apps, empty fields match every app
+type appFilter struct {
+	Org          string
+	Status       string
+	NameContains string
+	NamePrefix   string
+	Selector     labels.Selector
+}
+
+func (f appFilter) matches(app api.App) bool {
+	return (f.Org == "" || f.Org == app.Organization.Slug) &&
+		(f.Status == "" || strings.EqualFold(f.Status, app.Status)) &&
+		strings.Contains(app.Name, f.NameContains) &&
+		strings.HasPrefix(app.Name, f.NamePrefix) &&
+		f.Selector.Matches(app.Labels)
+}
+
+func filterApps(apps []api.App, filter appFilter) []api.App {
+	filtered := make([]api.App, 0, len(apps))
+	for _, app := range apps {
+		if filter.matches(app) {
+			filtered = append(filtered, app)
+		}
+	}
+	return filtered
 }
 
 func runListApps(commandContext *cmdctx.CmdContext) error {
 
 	asJSON := commandContext.OutputJSON()
 
-	appPart := ""
+	var filter appFilter
 
 	if len(commandContext.Args) == 1 {
-		appPart = commandContext.Args[0]
+		filter.NameContains = commandContext.Args[0]
 	} else if len(commandContext.Args) > 0 {
 		commandContext.Status("list", cmdctx.SERROR, "Too many arguments - discarding excess")
 	}
 
-	orgSlug, _ := commandContext.Config.GetString("org")
+	filter.Org, _ = commandContext.Config.GetString("org")
 
-	status, _ := commandContext.Config.GetString("status")
+	filter.Status, _ = commandContext.Config.GetString("status")
 
 	exact := commandContext.Config.GetBool("exact")
 
@@ -86,43 +136,10 @@ func runListApps(commandContext *cmdctx.CmdContext) error {
 		return err
 	}
 
-	var filteredApps []appCondensed
-
-	filteredApps = make([]appCondensed, 0)
-
-	for i := range apps {
-		saved := false
-
-		if appPart != "" {
-			saved = strings.Contains(apps[i].Name, appPart)
-		} else {
-			saved = true
-		}
-
-		if orgSlug != "" {
-			saved = saved && orgSlug == apps[i].Organization.Slug
-		}
+	filteredApps := make([]appCondensed, 0)
 
-		if status != "" {
-			saved = saved && status == apps[i].Status
-		}
-
-		if saved {
-			var createdAt time.Time
-
-			// CreatedAt may or may not exist
-			if apps[i].Deployed && apps[i].CurrentRelease != nil {
-				createdAt = apps[i].CurrentRelease.CreatedAt
-			}
-
-			filteredApps = append(filteredApps, appCondensed{ID: apps[i].ID,
-				Name:         apps[i].Name,
-				Status:       apps[i].Status,
-				Deployed:     apps[i].Deployed,
-				Hostname:     apps[i].Hostname,
-				Organization: apps[i].Organization.Slug,
-				CreatedAt:    createdAt})
-		}
+	for _, app := range filterApps(apps, filter) {
+		filteredApps = append(filteredApps, condenseApp(app))
 	}
 
 	sortType, _ := commandContext.Config.GetString("sort")
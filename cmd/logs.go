@@ -1,6 +1,7 @@
 package cmd
 
 import (
+	"context"
 	"math"
 	"time"
 
@@ -16,7 +17,7 @@ import (
 
 func newLogsCommand(client *client.Client) *Command {
 	logsStrings := docstrings.Get("logs")
-	cmd := BuildCommandKS(nil, runLogs, logsStrings, client, requireSession, requireAppName)
+	cmd := BuildCommandKS(nil, runLogs, logsStrings, client, requireSession, requireAppName, selectsApps)
 
 	// TODO: Move flag descriptions into the docStrings
 	cmd.AddStringFlag(StringFlagOpts{
@@ -39,40 +40,71 @@ func runLogs(ctx *cmdctx.CmdContext) error {
 	instanceFilter, _ := ctx.Config.GetString("instance")
 	regionFilter, _ := ctx.Config.GetString("region")
 
-	nextToken := ""
+	appNames := []string{ctx.AppName}
+	if ctx.Selector != "" {
+		var err error
+		if appNames, err = selectedApps(ctx); err != nil {
+			return err
+		}
+	}
+
+	// where each app's logs were read up to
+	nextTokens := map[string]string{}
 
 	logPresenter := presenters.LogPresenter{}
 
+	// stop tailing on ctrl-c rather than exiting so exit hooks still run
+	cancelCtx := createCancellableContext()
+
 	for {
-		entries, token, err := ctx.Client.API().GetAppLogs(ctx.AppName, nextToken, regionFilter, instanceFilter)
-
-		if err != nil {
-			if api.IsNotAuthenticatedError(err) {
-				return err
-			} else if api.IsNotFoundError(err) {
-				return err
-			} else {
-				errorCount++
-				if errorCount > 3 {
+		if cancelCtx.Err() != nil {
+			return nil
+		}
+
+		received := 0
+		for _, appName := range appNames {
+			entries, token, err := ctx.Client.API().GetAppLogs(appName, nextTokens[appName], regionFilter, instanceFilter)
+
+			if err != nil {
+				if api.IsNotAuthenticatedError(err) {
+					return err
+				} else if api.IsNotFoundError(err) {
 					return err
+				} else {
+					errorCount++
+					if errorCount > 3 {
+						return err
+					}
+					sleep(cancelCtx, errorCount)
+					continue
 				}
-				sleep(errorCount)
 			}
-		}
-		errorCount = 0
+			errorCount = 0
 
-		if len(entries) == 0 {
-			emptyCount++
-			sleep(emptyCount)
-		} else {
-			emptyCount = 0
+			if len(entries) == 0 {
+				continue
+			}
+			received += len(entries)
 
+			// label each line with its app when following more than one
+			if len(appNames) > 1 {
+				for i := range entries {
+					entries[i].App = appName
+				}
+			}
 			logPresenter.FPrint(ctx.Out, ctx.OutputJSON(), entries)
 
 			if token != "" {
-				nextToken = token
+				nextTokens[appName] = token
 			}
 		}
+
+		if received == 0 {
+			emptyCount++
+			sleep(cancelCtx, emptyCount)
+		} else {
+			emptyCount = 0
+		}
 	}
 
 	// This should not be reached
@@ -80,11 +112,14 @@ func runLogs(ctx *cmdctx.CmdContext) error {
 
 var maxBackoff float64 = 5000
 
-func sleep(backoffCount int) {
+func sleep(ctx context.Context, backoffCount int) {
 	sleepTime := math.Pow(float64(backoffCount), 2) * 250
 	if sleepTime > maxBackoff {
 		sleepTime = maxBackoff
 	}
 	terminal.Debug("backoff ms:", sleepTime)
-	time.Sleep(time.Duration(sleepTime) * time.Millisecond)
+	select {
+	case <-time.After(time.Duration(sleepTime) * time.Millisecond):
+	case <-ctx.Done():
+	}
 }
@@ -2,7 +2,9 @@ package cmd
 
 import (
 	"fmt"
+	"strings"
 
+	"github.com/superfly/flyctl/api"
 	"github.com/superfly/flyctl/cmdctx"
 	"github.com/superfly/flyctl/internal/client"
 
@@ -18,7 +20,7 @@ import (
 func newMoveCommand(client *client.Client) *Command {
 
 	moveStrings := docstrings.Get("move")
-	moveCmd := BuildCommandKS(nil, runMove, moveStrings, client, requireSession)
+	moveCmd := BuildCommandKS(nil, runMove, moveStrings, client, requireSession, mutating)
 	moveCmd.Args = cobra.ExactArgs(1)
 	// TODO: Move flag descriptions into the docStrings
 	moveCmd.AddBoolFlag(BoolFlagOpts{Name: "yes", Shorthand: "y", Description: "Accept all confirmations"})
@@ -50,6 +52,12 @@ func runMove(commandContext *cmdctx.CmdContext) error {
 		return fmt.Errorf("Error setting organization: %s", err)
 	}
 
+	before, err := fetchMoveInventory(commandContext.Client.API(), appName)
+	if err != nil {
+		return errors.Wrap(err, "Error fetching app resources")
+	}
+	fmt.Printf("%s will move with its %s\n", appName, before.summary())
+
 	if !commandContext.Config.GetBool("yes") {
 		fmt.Println(aurora.Red("Are you sure you want to move this app?"))
 
@@ -73,5 +81,100 @@ func runMove(commandContext *cmdctx.CmdContext) error {
 
 	fmt.Printf("Successfully moved %s to %s\n", appName, org.Slug)
 
+	after, err := fetchMoveInventory(commandContext.Client.API(), appName)
+	if err != nil {
+		return errors.Wrap(err, "Error checking the moved app's resources")
+	}
+	left := before.leftBehind(after)
+	if len(left) == 0 {
+		fmt.Printf("All of the app's %s moved with it\n", before.summary())
+		return nil
+	}
+	commandContext.Statusf("move", cmdctx.SWARN, "Some of the app's resources didn't move to %s:\n", org.Slug)
+	for _, msg := range left {
+		commandContext.Statusf("move", cmdctx.SDETAIL, "%s\n", msg)
+	}
+
 	return nil
 }
+
+// moveInventory is what an app owns that has to follow it to another organization
+type moveInventory struct {
+	IPs          []api.IPAddress
+	Certificates []api.AppCertificateCompact
+	Volumes      []api.Volume
+	Secrets      []api.Secret
+}
+
+func fetchMoveInventory(client *api.Client, appName string) (*moveInventory, error) {
+	var inv moveInventory
+	var err error
+
+	if inv.IPs, err = client.GetIPAddresses(appName); err != nil {
+		return nil, err
+	}
+	if inv.Certificates, err = client.GetAppCertificates(appName); err != nil {
+		return nil, err
+	}
+	if inv.Volumes, err = client.GetVolumes(appName); err != nil {
+		return nil, err
+	}
+	if inv.Secrets, err = client.GetAppSecrets(appName); err != nil {
+		return nil, err
+	}
+
+	return &inv, nil
+}
+
+func (inv *moveInventory) summary() string {
+	return fmt.Sprintf("%d IP addresses, %d certificates, %d volumes and %d secrets", len(inv.IPs), len(inv.Certificates), len(inv.Volumes), len(inv.Secrets))
+}
+
+// leftBehind describes what the app had before the move that it doesn't have after, with how to
+// put each back
+func (inv *moveInventory) leftBehind(after *moveInventory) []string {
+	var msgs []string
+
+	ips := map[string]bool{}
+	for _, ip := range after.IPs {
+		ips[ip.Address] = true
+	}
+	for _, ip := range inv.IPs {
+		if !ips[ip.Address] {
+			kind := strings.TrimPrefix(strings.ToLower(ip.Type), "v")
+			msgs = append(msgs, fmt.Sprintf("IP address %s was released, allocate a new one with flyctl ips allocate-v%s and update DNS records pointing at it", ip.Address, kind))
+		}
+	}
+
+	certs := map[string]bool{}
+	for _, cert := range after.Certificates {
+		certs[cert.Hostname] = true
+	}
+	for _, cert := range inv.Certificates {
+		if !certs[cert.Hostname] {
+			msgs = append(msgs, fmt.Sprintf("Certificate for %s was removed, add it again with flyctl certs create %s", cert.Hostname, cert.Hostname))
+		}
+	}
+
+	volumes := map[string]bool{}
+	for _, vol := range after.Volumes {
+		volumes[vol.ID] = true
+	}
+	for _, vol := range inv.Volumes {
+		if !volumes[vol.ID] {
+			msgs = append(msgs, fmt.Sprintf("Volume %s (%s, %dGB in %s) is no longer on the app, create a replacement with flyctl volumes create %s --region %s", vol.ID, vol.Name, vol.SizeGb, vol.Region, vol.Name, vol.Region))
+		}
+	}
+
+	secrets := map[string]bool{}
+	for _, secret := range after.Secrets {
+		secrets[secret.Name] = true
+	}
+	for _, secret := range inv.Secrets {
+		if !secrets[secret.Name] {
+			msgs = append(msgs, fmt.Sprintf("Secret %s was removed, set it again with flyctl secrets set %s=...", secret.Name, secret.Name))
+		}
+	}
+
+	return msgs
+}
@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/superfly/flyctl/cmdctx"
+	"github.com/superfly/flyctl/docstrings"
+	"github.com/superfly/flyctl/flyname"
+	"github.com/superfly/flyctl/helpers"
+	"github.com/superfly/flyctl/internal/client"
+	"github.com/superfly/flyctl/internal/scaffold"
+)
+
+func newNewCommand(client *client.Client) *Command {
+	newStrings := docstrings.Get("new")
+	cmd := BuildCommandKS(nil, runNew, newStrings, client)
+	cmd.Args = cobra.RangeArgs(0, 2)
+	cmd.AddStringFlag(StringFlagOpts{
+		Name:        "name",
+		Description: "The app name to put in fly.toml. Defaults to the directory name",
+	})
+
+	return cmd
+}
+
+func runNew(cmdCtx *cmdctx.CmdContext) error {
+	if len(cmdCtx.Args) == 0 {
+		table := helpers.MakeSimpleTable(cmdCtx.Out, []string{"Template", "Description"})
+		for _, t := range scaffold.Templates() {
+			table.Append([]string{t.Name, t.Description})
+		}
+		table.Render()
+		return nil
+	}
+
+	tmpl := scaffold.Get(cmdCtx.Args[0])
+	if tmpl == nil {
+		return fmt.Errorf("unknown template %s, choose one of %s", cmdCtx.Args[0], strings.Join(scaffold.Names(), ", "))
+	}
+
+	dir := tmpl.Name
+	if len(cmdCtx.Args) > 1 {
+		dir = cmdCtx.Args[1]
+	}
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+
+	appName, _ := cmdCtx.Config.GetString("name")
+	if appName == "" {
+		appName = scaffold.AppName(absDir)
+	}
+
+	if err := os.MkdirAll(absDir, 0755); err != nil {
+		return err
+	}
+
+	written, err := tmpl.Render(absDir, scaffold.Vars{AppName: appName})
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmdCtx.Out, "Created %s project %s in %s\n", tmpl.Name, appName, dir)
+	for _, path := range written {
+		fmt.Fprintf(cmdCtx.Out, "  %s\n", path)
+	}
+
+	fmt.Fprintln(cmdCtx.Out, "\nNext steps:")
+	fmt.Fprintf(cmdCtx.Out, "  cd %s\n", dir)
+	fmt.Fprintf(cmdCtx.Out, "  %s launch       create the app and deploy it\n", flyname.Name())
+	fmt.Fprintf(cmdCtx.Out, "  %s auth token   copy your token into the FLY_API_TOKEN secret of the GitHub repository to deploy on push to main\n", flyname.Name())
+
+	return nil
+}
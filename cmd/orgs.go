@@ -24,23 +24,23 @@ func newOrgsCommand(client *client.Client) *Command {
 	orgsShowCommand.Args = cobra.ExactArgs(1)
 
 	orgsInviteStrings := docstrings.Get("orgs.invite")
-	orgsInviteCommand := BuildCommandKS(orgscmd, runOrgsInvite, orgsInviteStrings, client, requireSession)
+	orgsInviteCommand := BuildCommandKS(orgscmd, runOrgsInvite, orgsInviteStrings, client, requireSession, mutating)
 	orgsInviteCommand.Args = cobra.MaximumNArgs(2)
 
 	orgsRevokeStrings := docstrings.Get("orgs.revoke")
-	orgsRevokeCommand := BuildCommandKS(orgscmd, runOrgsRevoke, orgsRevokeStrings, client, requireSession)
+	orgsRevokeCommand := BuildCommandKS(orgscmd, runOrgsRevoke, orgsRevokeStrings, client, requireSession, mutating)
 	orgsRevokeCommand.Args = cobra.MaximumNArgs(2)
 
 	orgsRemoveStrings := docstrings.Get("orgs.remove")
-	orgsRemoveCommand := BuildCommandKS(orgscmd, runOrgsRemove, orgsRemoveStrings, client, requireSession)
+	orgsRemoveCommand := BuildCommandKS(orgscmd, runOrgsRemove, orgsRemoveStrings, client, requireSession, mutating)
 	orgsRemoveCommand.Args = cobra.MaximumNArgs(2)
 
 	orgsCreateStrings := docstrings.Get("orgs.create")
-	orgsCreateCommand := BuildCommandKS(orgscmd, runOrgsCreate, orgsCreateStrings, client, requireSession)
+	orgsCreateCommand := BuildCommandKS(orgscmd, runOrgsCreate, orgsCreateStrings, client, requireSession, mutating)
 	orgsCreateCommand.Args = cobra.RangeArgs(0, 1)
 
 	orgsDeleteStrings := docstrings.Get("orgs.delete")
-	orgsDeleteCommand := BuildCommandKS(orgscmd, runOrgsDelete, orgsDeleteStrings, client, requireSession)
+	orgsDeleteCommand := BuildCommandKS(orgscmd, runOrgsDelete, orgsDeleteStrings, client, requireSession, mutating)
 	orgsDeleteCommand.Args = cobra.ExactArgs(1)
 
 	return orgscmd
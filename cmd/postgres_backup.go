@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/logrusorgru/aurora"
+	"github.com/pkg/errors"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/cmdctx"
+	"github.com/superfly/flyctl/helpers"
+)
+
+func runListPostgresBackups(ctx *cmdctx.CmdContext) error {
+	backups, err := ctx.Client.API().ListPostgresBackups(ctx.AppName)
+	if err != nil {
+		return err
+	}
+
+	if ctx.OutputJSON() {
+		ctx.WriteJSON(backups)
+		return nil
+	}
+
+	if len(backups) == 0 {
+		fmt.Fprintf(ctx.Out, "%s has no backups, take one with flyctl postgres backup create %s\n", ctx.AppName, ctx.AppName)
+		return nil
+	}
+
+	table := helpers.MakeSimpleTable(ctx.Out, []string{"ID", "Status", "Size", "Type", "Created At"})
+	for _, b := range backups {
+		kind := "manual"
+		if b.Scheduled {
+			kind = "scheduled"
+		}
+		size := ""
+		if b.SizeBytes > 0 {
+			size = humanize.Bytes(uint64(b.SizeBytes))
+		}
+		table.Append([]string{b.ID, b.Status, size, kind, humanize.Time(b.CreatedAt)})
+	}
+	table.Render()
+
+	return nil
+}
+
+func runCreatePostgresBackup(ctx *cmdctx.CmdContext) error {
+	cancelCtx := createCancellableContext()
+	client := ctx.Client.API()
+
+	backup, err := client.CreatePostgresBackup(ctx.AppName)
+	if err != nil {
+		return errors.WithMessage(err, "Failed to start the backup")
+	}
+	ctx.Statusf("postgres", cmdctx.SBEGIN, "Backing up %s as %s\n", ctx.AppName, backup.ID)
+
+	if ctx.Config.GetBool("detach") {
+		if ctx.OutputJSON() {
+			ctx.WriteJSON(backup)
+		}
+		return nil
+	}
+
+	ctx.IO.StartProgressIndicatorMsg(fmt.Sprintf("Backing up %s", ctx.AppName))
+	for backup.Status == "pending" || backup.Status == "running" {
+		select {
+		case <-time.After(2 * time.Second):
+		case <-cancelCtx.Done():
+			ctx.IO.StopProgressIndicator()
+			fmt.Fprintf(ctx.Out, "Stopped watching, backup %s continues in the background\n", backup.ID)
+			return cancelCtx.Err()
+		}
+
+		backups, err := client.ListPostgresBackups(ctx.AppName)
+		if err != nil {
+			ctx.IO.StopProgressIndicator()
+			return errors.Wrap(err, "error checking the backup")
+		}
+		found := false
+		for i := range backups {
+			if backups[i].ID == backup.ID {
+				backup = &backups[i]
+				found = true
+				break
+			}
+		}
+		if !found {
+			ctx.IO.StopProgressIndicator()
+			return fmt.Errorf("backup %s is no longer listed", backup.ID)
+		}
+	}
+	ctx.IO.StopProgressIndicator()
+
+	if backup.Status != "completed" {
+		return fmt.Errorf("backup %s %s", backup.ID, backup.Status)
+	}
+
+	if ctx.OutputJSON() {
+		ctx.WriteJSON(backup)
+		return nil
+	}
+
+	ctx.Statusf("postgres", cmdctx.SDONE, "Backup %s completed, %s\n", backup.ID, humanize.Bytes(uint64(backup.SizeBytes)))
+	return nil
+}
+
+// runRestorePostgresBackup restores a backup into a new cluster with --new-cluster, or replaces
+// the cluster's data with it
+func runRestorePostgresBackup(ctx *cmdctx.CmdContext) error {
+	backupID := ctx.Args[1]
+	client := ctx.Client.API()
+
+	backups, err := client.ListPostgresBackups(ctx.AppName)
+	if err != nil {
+		return err
+	}
+	var backup *api.PostgresBackup
+	for i := range backups {
+		if backups[i].ID == backupID {
+			backup = &backups[i]
+		}
+	}
+	if backup == nil {
+		return fmt.Errorf("%s has no backup %s, list them with flyctl postgres backup list %s", ctx.AppName, backupID, ctx.AppName)
+	}
+	if backup.Status != "completed" {
+		return fmt.Errorf("backup %s is %s, only completed backups can be restored", backupID, backup.Status)
+	}
+
+	if ctx.Config.GetBool("new-cluster") {
+		fmt.Fprintf(ctx.Out, "Restoring backup %s of %s, taken %s, into a new cluster\n", backupID, ctx.AppName, humanize.Time(backup.CreatedAt))
+		return createPostgresCluster(ctx, api.StringPointer(backupID))
+	}
+
+	if !ctx.Config.GetBool("yes") {
+		fmt.Println(aurora.Red(fmt.Sprintf("This replaces all of %s's data with backup %s, taken %s. Changes since then are lost.", ctx.AppName, backupID, humanize.Time(backup.CreatedAt))))
+		fmt.Println("Use --new-cluster to restore into a new cluster instead.")
+		if !confirm(fmt.Sprintf("Restore %s?", ctx.AppName)) {
+			return nil
+		}
+	}
+
+	release, err := client.RestorePostgresBackup(ctx.AppName, backupID)
+	if err != nil {
+		return errors.WithMessage(err, "Failed to restore the backup")
+	}
+	ctx.Statusf("postgres", cmdctx.SINFO, "Release v%d created, restoring backup %s\n", release.Version, backupID)
+
+	return watchDeployment(createCancellableContext(), ctx)
+}
@@ -0,0 +1,152 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"sync"
+
+	"github.com/pkg/errors"
+	"github.com/superfly/flyctl/cmdctx"
+	"github.com/superfly/flyctl/pkg/wg"
+	"github.com/superfly/flyctl/terminal"
+)
+
+// postgresProxyPort is the port of a cluster's proxy, which routes connections to its leader
+const postgresProxyPort = 5432
+
+// runPostgresConnect forwards a local port to a cluster's leader over WireGuard. With --proxy it
+// only listens, for local tools to connect to, otherwise it runs psql through it.
+func runPostgresConnect(cmdCtx *cmdctx.CmdContext) error {
+	ctx := createCancellableContext()
+	client := cmdCtx.Client.API()
+
+	proxyOnly := cmdCtx.Config.GetBool("proxy")
+	var psql string
+	if !proxyOnly {
+		var err error
+		if psql, err = exec.LookPath("psql"); err != nil {
+			return errors.New("psql isn't installed, use --proxy to connect with another client")
+		}
+	}
+
+	app, err := client.GetApp(cmdCtx.AppName)
+	if err != nil {
+		return err
+	}
+
+	state, err := wireGuardForOrg(cmdCtx, &app.Organization)
+	if err != nil {
+		return fmt.Errorf("create wireguard config: %w", err)
+	}
+	terminal.Debugf("Establishing WireGuard connection (%s)\n", state.Name)
+	tunnel, err := wg.Connect(*state.TunnelConfig())
+	if err != nil {
+		return fmt.Errorf("connect wireguard: %w", err)
+	}
+	defer tunnel.Close()
+
+	remote := fmt.Sprintf("%s.internal", app.Name)
+	addrs, err := tunnel.Resolver().LookupHost(ctx, remote)
+	if err != nil {
+		return errors.Wrapf(err, "could not resolve %s on the private network", remote)
+	}
+	if len(addrs) == 0 {
+		return fmt.Errorf("%s has no running instances", app.Name)
+	}
+	target := net.JoinHostPort(addrs[0], strconv.Itoa(postgresProxyPort))
+
+	port := cmdCtx.Config.GetInt("port")
+	if !proxyOnly {
+		// psql gets a port of its own so it doesn't clash with a local postgres
+		port = 0
+	}
+	bind, _ := cmdCtx.Config.GetString("bind")
+	ln, err := net.Listen("tcp", net.JoinHostPort(bind, strconv.Itoa(port)))
+	if err != nil {
+		return errors.Wrap(err, "could not listen for connections")
+	}
+	defer ln.Close()
+	local := ln.Addr().(*net.TCPAddr)
+
+	dial := func(ctx context.Context) (net.Conn, error) {
+		return tunnel.DialContext(ctx, "tcp", target)
+	}
+
+	user, _ := cmdCtx.Config.GetString("user")
+	database, _ := cmdCtx.Config.GetString("database")
+
+	if proxyOnly {
+		go proxyConnections(ctx, ln, dial)
+		fmt.Fprintf(cmdCtx.Out, "Proxying %s to %s's leader, stop with ctrl-c\n", local, app.Name)
+		fmt.Fprintf(cmdCtx.Out, "Connect with: postgres://%s@%s/%s\n", user, local, database)
+		<-ctx.Done()
+		return nil
+	}
+
+	// neither psql nor the proxy are tied to ctx, ctrl-c belongs to psql, which cancels the running
+	// query over a new connection to the proxy. The proxy stops once psql exits.
+	stopProxy := startPsqlProxy(ln, dial)
+	defer stopProxy()
+
+	cmd := exec.Command(psql, fmt.Sprintf("postgres://%s@%s/%s", user, local, database))
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+// startPsqlProxy proxies the connections accepted by ln until the returned func is called,
+// ignoring ctrl-c
+func startPsqlProxy(ln net.Listener, dial func(context.Context) (net.Conn, error)) func() {
+	ctx, cancel := context.WithCancel(context.Background())
+	go proxyConnections(ctx, ln, dial)
+	return cancel
+}
+
+// proxyConnections copies each connection accepted by ln to and from a connection made by dial,
+// until ctx is done
+func proxyConnections(ctx context.Context, ln net.Listener, dial func(context.Context) (net.Conn, error)) {
+	go func() {
+		<-ctx.Done()
+		ln.Close()
+	}()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+
+		go func() {
+			defer conn.Close()
+
+			upstream, err := dial(ctx)
+			if err != nil {
+				terminal.Warnf("Could not connect to the cluster: %s\n", err)
+				return
+			}
+			defer upstream.Close()
+
+			var copies sync.WaitGroup
+			copies.Add(2)
+			go func() {
+				defer copies.Done()
+				io.Copy(upstream, conn)
+				if c, ok := upstream.(interface{ CloseWrite() error }); ok {
+					c.CloseWrite()
+				}
+			}()
+			go func() {
+				defer copies.Done()
+				io.Copy(conn, upstream)
+				if c, ok := conn.(interface{ CloseWrite() error }); ok {
+					c.CloseWrite()
+				}
+			}()
+			copies.Wait()
+		}()
+	}
+}
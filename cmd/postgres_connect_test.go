@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"context"
+	"net"
+	"os"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPsqlProxySurvivesInterrupt(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("sends itself SIGINT")
+	}
+
+	upstream, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer upstream.Close()
+	go func() {
+		for {
+			conn, err := upstream.Accept()
+			if err != nil {
+				return
+			}
+			conn.Write([]byte("ok"))
+			conn.Close()
+		}
+	}()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	stop := startPsqlProxy(ln, func(ctx context.Context) (net.Conn, error) {
+		var d net.Dialer
+		return d.DialContext(ctx, "tcp", upstream.Addr().String())
+	})
+
+	roundTrip := func() error {
+		conn, err := net.DialTimeout("tcp", ln.Addr().String(), time.Second)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		buf := make([]byte, 2)
+		_, err = conn.Read(buf)
+		return err
+	}
+
+	// the first ctrl-c cancels flyctl's context, psql then connects again to cancel its query
+	ctx := createCancellableContext()
+	assert.NoError(t, roundTrip())
+	self, err := os.FindProcess(os.Getpid())
+	require.NoError(t, err)
+	require.NoError(t, self.Signal(os.Interrupt))
+	select {
+	case <-ctx.Done():
+	case <-time.After(5 * time.Second):
+		t.Fatal("interrupt didn't cancel the context")
+	}
+	assert.NoError(t, roundTrip())
+
+	stop()
+	assert.Eventually(t, func() bool { return roundTrip() != nil }, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,133 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/superfly/flyctl/cmdctx"
+	"github.com/superfly/flyctl/internal/secretgen"
+)
+
+func runCreatePostgresDatabase(ctx *cmdctx.CmdContext) error {
+	name, _ := ctx.Config.GetString("name")
+	if name == "" {
+		return fmt.Errorf("--name <database> flag required")
+	}
+
+	database, err := ctx.Client.API().CreatePostgresDatabase(ctx.AppName, name)
+	if err != nil {
+		return err
+	}
+
+	if ctx.OutputJSON() {
+		ctx.WriteJSON(database)
+		return nil
+	}
+
+	fmt.Fprintf(ctx.Out, "Created database %s in %s\n", database.Name, ctx.AppName)
+	fmt.Fprintf(ctx.Out, "Give a user access with: flyctl postgres users grant %s --username <user> --database %s\n", ctx.AppName, database.Name)
+	return nil
+}
+
+func runDropPostgresDatabase(ctx *cmdctx.CmdContext) error {
+	name, _ := ctx.Config.GetString("name")
+	if name == "" {
+		return fmt.Errorf("--name <database> flag required")
+	}
+	client := ctx.Client.API()
+
+	attachments, err := client.ListPostgresAttachments(ctx.AppName)
+	if err != nil {
+		return err
+	}
+	var attachedApps []string
+	for _, a := range attachments {
+		if a.DatabaseName == name {
+			attachedApps = append(attachedApps, a.App.Name)
+		}
+	}
+	if len(attachedApps) > 0 {
+		return fmt.Errorf("%s is used by %s, detach them with flyctl postgres detach first", name, strings.Join(attachedApps, ", "))
+	}
+
+	if !ctx.Config.GetBool("yes") {
+		fmt.Println(aurora.Red(fmt.Sprintf("Dropping %s deletes all of its data, it can only be recovered from a backup of %s", name, ctx.AppName)))
+		if !confirm(fmt.Sprintf("Drop database %s?", name)) {
+			return nil
+		}
+	}
+
+	if err := client.DeletePostgresDatabase(ctx.AppName, name); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(ctx.Out, "Dropped database %s from %s\n", name, ctx.AppName)
+	return nil
+}
+
+func runCreatePostgresUser(ctx *cmdctx.CmdContext) error {
+	username, _ := ctx.Config.GetString("username")
+	if username == "" {
+		return fmt.Errorf("--username <user> flag required")
+	}
+
+	password, _ := ctx.Config.GetString("password")
+	generated := password == ""
+	if generated {
+		var err error
+		if password, err = secretgen.Generate(32, "alphanumeric"); err != nil {
+			return err
+		}
+	}
+
+	user, err := ctx.Client.API().CreatePostgresUser(ctx.AppName, username, password, ctx.Config.GetBool("superuser"))
+	if err != nil {
+		return err
+	}
+
+	if ctx.OutputJSON() {
+		ctx.WriteJSON(struct {
+			Username    string
+			Password    string
+			IsSuperuser bool
+		}{user.Username, password, user.IsSuperuser})
+		return nil
+	}
+
+	fmt.Fprintf(ctx.Out, "Created user %s in %s\n", user.Username, ctx.AppName)
+	if generated {
+		fmt.Fprintf(ctx.Out, "  Password: %s\n", password)
+		fmt.Fprintln(ctx.Out, aurora.Italic("Save the password in a secure place, you won't be able to see it again!"))
+	}
+	if !user.IsSuperuser {
+		fmt.Fprintf(ctx.Out, "Give it access to a database with: flyctl postgres users grant %s --username %s --database <database>\n", ctx.AppName, user.Username)
+	}
+	return nil
+}
+
+func runGrantPostgresUser(ctx *cmdctx.CmdContext) error {
+	username, _ := ctx.Config.GetString("username")
+	database, _ := ctx.Config.GetString("database")
+	if username == "" || database == "" {
+		return fmt.Errorf("--username <user> and --database <database> flags required")
+	}
+	readOnly := ctx.Config.GetBool("readonly-access")
+
+	user, err := ctx.Client.API().GrantPostgresAccess(ctx.AppName, username, database, readOnly)
+	if err != nil {
+		return err
+	}
+
+	if ctx.OutputJSON() {
+		ctx.WriteJSON(user)
+		return nil
+	}
+
+	access := "read and write"
+	if readOnly {
+		access = "read only"
+	}
+	fmt.Fprintf(ctx.Out, "Gave %s %s access to %s, it can use %s\n", user.Username, access, database, strings.Join(user.Databases, ", "))
+	return nil
+}
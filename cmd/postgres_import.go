@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/pkg/errors"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/cmdctx"
+)
+
+// runImportPostgres copies an external database into the cluster. The dump and restore run in a
+// temporary VM next to the cluster, so the data doesn't pass through this machine.
+func runImportPostgres(ctx *cmdctx.CmdContext) error {
+	cancelCtx := createCancellableContext()
+	client := ctx.Client.API()
+
+	source, _ := ctx.Config.GetString("source")
+	if source == "" {
+		return fmt.Errorf("--source postgres://<user>:<password>@<host>/<database> flag required")
+	}
+	sourceURL, err := url.Parse(source)
+	if err != nil || (sourceURL.Scheme != "postgres" && sourceURL.Scheme != "postgresql") || sourceURL.Host == "" {
+		return fmt.Errorf("--source must be a postgres:// connection string")
+	}
+
+	database, _ := ctx.Config.GetString("database")
+	if database == "" {
+		database = strings.TrimPrefix(sourceURL.Path, "/")
+	}
+	if database == "" {
+		return fmt.Errorf("--source doesn't name a database, give the one to create with --database")
+	}
+
+	imp, err := client.ImportPostgresDatabase(api.ImportPostgresDatabaseInput{
+		AppID:     ctx.AppName,
+		SourceURI: source,
+		Database:  database,
+		Clean:     ctx.Config.GetBool("clean"),
+	})
+	if err != nil {
+		return errors.WithMessage(err, "Failed to start the import")
+	}
+	ctx.Statusf("postgres", cmdctx.SBEGIN, "Importing %s from %s into %s\n", database, sourceURL.Redacted(), ctx.AppName)
+
+	if ctx.Config.GetBool("detach") {
+		if ctx.OutputJSON() {
+			ctx.WriteJSON(imp)
+		}
+		return nil
+	}
+
+	imp, err = watchPostgresImport(cancelCtx, ctx, imp)
+	if err != nil {
+		return err
+	}
+
+	if ctx.OutputJSON() {
+		ctx.WriteJSON(imp)
+		return nil
+	}
+
+	ctx.Statusf("postgres", cmdctx.SDONE, "Imported %s into %s, %s copied\n", database, ctx.AppName, humanize.Bytes(uint64(imp.BytesTransferred)))
+	fmt.Fprintf(ctx.Out, "Attach it to an app with: flyctl postgres attach --postgres-app %s --database-name %s -a <app>\n", ctx.AppName, database)
+	return nil
+}
+
+// watchPostgresImport waits for an import to finish, showing its progress, and fails unless it
+// succeeded
+func watchPostgresImport(cancelCtx context.Context, ctx *cmdctx.CmdContext, imp *api.PostgresImport) (*api.PostgresImport, error) {
+	ctx.IO.StartProgressIndicatorMsg(fmt.Sprintf("Importing %s", imp.Database))
+	for imp.InProgress {
+		select {
+		case <-time.After(2 * time.Second):
+		case <-cancelCtx.Done():
+			ctx.IO.StopProgressIndicator()
+			fmt.Fprintf(ctx.Out, "Stopped watching, import %s continues in the background\n", imp.ID)
+			return nil, cancelCtx.Err()
+		}
+
+		next, err := ctx.Client.API().GetPostgresImport(imp.ID)
+		if err != nil {
+			ctx.IO.StopProgressIndicator()
+			return nil, errors.Wrap(err, "error checking the import")
+		}
+		imp = next
+		ctx.IO.ChangeProgressIndicatorMsg(fmt.Sprintf("Importing %s: %s, %s read", imp.Database, imp.Status, humanize.Bytes(uint64(imp.BytesTransferred))))
+	}
+	ctx.IO.StopProgressIndicator()
+
+	if imp.Status != "succeeded" {
+		if imp.Error != "" {
+			return nil, fmt.Errorf("import %s failed: %s", imp.ID, imp.Error)
+		}
+		return nil, fmt.Errorf("import %s %s", imp.ID, imp.Status)
+	}
+
+	return imp, nil
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/dustin/go-humanize"
+	"github.com/logrusorgru/aurora"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/cmdctx"
+	"github.com/superfly/flyctl/helpers"
+)
+
+// the share of connections or disk in use past which postgres metrics warns
+const (
+	postgresConnectionsWarnPercent = 80
+	postgresDiskWarnPercent        = 90
+)
+
+func runPostgresMetrics(ctx *cmdctx.CmdContext) error {
+	nodes, err := ctx.Client.API().GetPostgresMetrics(ctx.AppName)
+	if err != nil {
+		return err
+	}
+
+	if ctx.OutputJSON() {
+		ctx.WriteJSON(nodes)
+		return nil
+	}
+
+	if len(nodes) == 0 {
+		fmt.Fprintf(ctx.Out, "%s has no running instances\n", ctx.AppName)
+		return nil
+	}
+
+	table := helpers.MakeSimpleTable(ctx.Out, []string{"Instance", "Region", "Role", "Health", "Connections", "Replication Lag", "Disk", "Slow Queries"})
+	for _, n := range nodes {
+		health := aurora.Green("healthy").String()
+		if !n.Healthy {
+			health = aurora.Red("unhealthy").String()
+		}
+		lag := "-"
+		if n.Role != "leader" {
+			lag = humanize.Bytes(uint64(n.ReplicationLagBytes))
+		}
+		table.Append([]string{
+			n.AllocationID,
+			n.Region,
+			n.Role,
+			health,
+			fmt.Sprintf("%d/%d", n.Connections, n.MaxConnections),
+			lag,
+			formatVolumeUsage(&api.VolumeUsage{UsedBytes: n.DiskUsedBytes, TotalBytes: n.DiskTotalBytes}),
+			fmt.Sprint(n.SlowQueries),
+		})
+	}
+	table.Render()
+
+	for _, warning := range postgresMetricsWarnings(ctx.AppName, nodes) {
+		fmt.Fprintln(ctx.Out, aurora.Yellow(warning))
+	}
+
+	return nil
+}
+
+// postgresMetricsWarnings describes the instances that are unhealthy or close to running out of
+// connections or disk
+func postgresMetricsWarnings(cluster string, nodes []api.PostgresNodeMetrics) []string {
+	var warnings []string
+
+	for _, n := range nodes {
+		if !n.Healthy {
+			warnings = append(warnings, fmt.Sprintf("%s is failing its health checks, see flyctl status --all -a %s", n.AllocationID, cluster))
+		}
+		if n.MaxConnections > 0 && n.Connections*100/n.MaxConnections >= postgresConnectionsWarnPercent {
+			warnings = append(warnings, fmt.Sprintf("%s is using %d of %d connections, consider a connection pooler", n.AllocationID, n.Connections, n.MaxConnections))
+		}
+		if n.DiskTotalBytes > 0 && n.DiskUsedBytes*100/n.DiskTotalBytes >= postgresDiskWarnPercent {
+			warnings = append(warnings, fmt.Sprintf("%s's volume is %d%% full, grow it with flyctl volumes extend", n.AllocationID, n.DiskUsedBytes*100/n.DiskTotalBytes))
+		}
+	}
+
+	return warnings
+}
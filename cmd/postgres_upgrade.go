@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/pkg/errors"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/cmdctx"
+)
+
+// runUpgradePostgres moves a cluster's databases to a new cluster running a newer major version
+// with a dump and restore, then switches the apps attached to it over. The old cluster is left
+// running so --rollback can switch the apps back.
+func runUpgradePostgres(ctx *cmdctx.CmdContext) error {
+	cancelCtx := createCancellableContext()
+	client := ctx.Client.API()
+	source := ctx.AppName
+
+	if target, _ := ctx.Config.GetString("rollback"); target != "" {
+		return rollbackPostgresUpgrade(ctx, target, source)
+	}
+
+	to := ctx.Config.GetInt("to")
+	if to == 0 {
+		return fmt.Errorf("--to <major version> flag required, like --to 14")
+	}
+	version, err := client.GetPostgresVersion(source)
+	if err != nil {
+		return err
+	}
+	if to <= version {
+		return fmt.Errorf("%s already runs postgres %d, upgrades go to a newer major version", source, version)
+	}
+
+	name, _ := ctx.Config.GetString("name")
+	if name == "" {
+		name = fmt.Sprintf("%s-pg%d", source, to)
+	}
+
+	app, err := client.GetApp(source)
+	if err != nil {
+		return err
+	}
+	databases, err := client.ListPostgresDatabases(source)
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, db := range databases {
+		if db.Name != "postgres" && !strings.HasPrefix(db.Name, "template") {
+			names = append(names, db.Name)
+		}
+	}
+	attachments, err := clusterAttachments(ctx, source)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Upgrading %s from postgres %d to %d:\n", source, version, to)
+	fmt.Printf("  Create the cluster %s running postgres %d\n", name, to)
+	fmt.Printf("  Copy the databases %s with pg_dump and pg_restore\n", strings.Join(names, ", "))
+	for _, a := range attachments {
+		fmt.Printf("  Switch %s's %s to %s\n", a.App.Name, a.EnvironmentVariableName, name)
+	}
+	fmt.Printf("%s keeps running, unchanged, for a rollback\n", source)
+	if !ctx.Config.GetBool("yes") {
+		fmt.Println(aurora.Yellow("Writes made while the databases are copied are lost, stop the apps' writes first"))
+		if !confirm("Upgrade?") {
+			return nil
+		}
+	}
+
+	input := api.CreatePostgresClusterInput{
+		OrganizationID: app.Organization.ID,
+		Name:           name,
+		Version:        api.IntPointer(to),
+	}
+	if vmSize, _, err := client.AppVMResources(source); err == nil {
+		input.VMSize = api.StringPointer(vmSize.Name)
+	}
+	if volumes, err := client.GetVolumes(source); err == nil && len(volumes) > 0 {
+		input.Region = api.StringPointer(volumes[0].Region)
+		input.VolumeSizeGB = api.IntPointer(volumes[0].SizeGb)
+	}
+
+	payload, err := client.CreatePostgresCluster(input)
+	if err != nil {
+		return errors.WithMessage(err, "Failed to create the new cluster")
+	}
+	ctx.Statusf("postgres", cmdctx.SDONE, "Created %s\n", payload.App.Name)
+	fmt.Printf("  Username:    %s\n", payload.Username)
+	fmt.Printf("  Password:    %s\n", payload.Password)
+	fmt.Println(aurora.Italic("Save your credentials in a secure place, you won't be able to see them again!"))
+
+	ctx.AppName = payload.App.Name
+	err = watchDeployment(cancelCtx, ctx)
+	ctx.AppName = source
+	if err != nil {
+		return errors.WithMessagef(err, "%s didn't start, %s is unchanged", name, source)
+	}
+
+	for _, db := range names {
+		imp, err := client.ImportPostgresDatabase(api.ImportPostgresDatabaseInput{
+			AppID:       name,
+			SourceAppID: source,
+			Database:    db,
+		})
+		if err != nil {
+			return errors.WithMessagef(err, "Failed to copy %s, %s is unchanged", db, source)
+		}
+		if _, err := watchPostgresImport(cancelCtx, ctx, imp); err != nil {
+			return errors.WithMessagef(err, "Failed to copy %s, %s is unchanged", db, source)
+		}
+		ctx.Statusf("postgres", cmdctx.SDONE, "Copied %s\n", db)
+	}
+
+	if err := swapPostgresAttachments(ctx, attachments, source, name); err != nil {
+		return err
+	}
+
+	fmt.Printf("Upgraded to %s, running postgres %d\n", name, to)
+	fmt.Printf("Switch the apps back with: flyctl postgres upgrade %s --rollback %s\n", source, name)
+	fmt.Printf("Once you're happy with the upgrade, remove the old cluster with: flyctl apps destroy %s\n", source)
+	return nil
+}
+
+// rollbackPostgresUpgrade switches the apps attached to the upgraded cluster back to the original
+func rollbackPostgresUpgrade(ctx *cmdctx.CmdContext, upgraded string, original string) error {
+	attachments, err := clusterAttachments(ctx, upgraded)
+	if err != nil {
+		return err
+	}
+	if len(attachments) == 0 {
+		return fmt.Errorf("no apps are attached to %s", upgraded)
+	}
+
+	if !ctx.Config.GetBool("yes") {
+		fmt.Println(aurora.Yellow(fmt.Sprintf("Writes made to %s since the upgrade aren't copied back to %s", upgraded, original)))
+		if !confirm(fmt.Sprintf("Switch %d apps back to %s?", len(attachments), original)) {
+			return nil
+		}
+	}
+
+	if err := swapPostgresAttachments(ctx, attachments, upgraded, original); err != nil {
+		return err
+	}
+	fmt.Printf("Switched the apps back to %s, %s is still running\n", original, upgraded)
+	return nil
+}
+
+// clusterAttachments returns the attachments of apps to databases in the cluster
+func clusterAttachments(ctx *cmdctx.CmdContext, cluster string) ([]api.PostgresAttachment, error) {
+	all, err := ctx.Client.API().ListPostgresAttachments(cluster)
+	if err != nil {
+		return nil, err
+	}
+	var attachments []api.PostgresAttachment
+	for _, a := range all {
+		if a.PostgresClusterApp.Name == cluster {
+			attachments = append(attachments, a)
+		}
+	}
+	return attachments, nil
+}
+
+// swapPostgresAttachments attaches each app to the same database, under the same secret, in
+// another cluster
+func swapPostgresAttachments(ctx *cmdctx.CmdContext, attachments []api.PostgresAttachment, from string, to string) error {
+	client := ctx.Client.API()
+
+	for _, a := range attachments {
+		// the user stays, so the original cluster is untouched for a rollback
+		err := client.DetachPostgresCluster(api.DetachPostgresClusterInput{
+			AppID:                a.App.Name,
+			PostgresClusterAppID: from,
+			VariableName:         a.EnvironmentVariableName,
+		})
+		if err != nil {
+			return errors.WithMessagef(err, "Failed to detach %s from %s", a.App.Name, from)
+		}
+		_, err = client.AttachPostgresCluster(api.AttachPostgresClusterInput{
+			AppID:                a.App.Name,
+			PostgresClusterAppID: to,
+			DatabaseName:         api.StringPointer(a.DatabaseName),
+			VariableName:         api.StringPointer(a.EnvironmentVariableName),
+			Replica:              a.Replica,
+		})
+		if err != nil {
+			return errors.WithMessagef(err, "Failed to attach %s to %s, it's detached from both clusters. Attach it with: flyctl postgres attach --postgres-app %s --variable-name %s -a %s", a.App.Name, to, from, a.EnvironmentVariableName, a.App.Name)
+		}
+		ctx.Statusf("postgres", cmdctx.SDONE, "Switched %s's %s to %s\n", a.App.Name, a.EnvironmentVariableName, to)
+	}
+
+	return nil
+}
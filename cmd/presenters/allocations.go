@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/logrusorgru/aurora"
 	"github.com/superfly/flyctl/api"
@@ -139,13 +140,26 @@ func FormatDeploymentSummary(d *api.DeploymentStatus) string {
 func FormatDeploymentAllocSummary(d *api.DeploymentStatus) string {
 	allocCounts := fmt.Sprintf("%d desired, %d placed, %d healthy, %d unhealthy", d.DesiredCount, d.PlacedCount, d.HealthyCount, d.UnhealthyCount)
 
-	restarts := 0
+	restarts, warming, draining, connections := 0, 0, 0, 0
 	for _, alloc := range d.Allocations {
 		restarts += alloc.Restarts
+		if alloc.Warmup != nil && alloc.Warmup.Status == api.WarmupRunning {
+			warming++
+		}
+		if alloc.Draining != nil {
+			draining++
+			connections += alloc.Draining.ActiveConnections
+		}
 	}
 	if restarts > 0 {
 		allocCounts = fmt.Sprintf("%s [restarts: %d]", allocCounts, restarts)
 	}
+	if warming > 0 {
+		allocCounts = fmt.Sprintf("%s [warming up: %d]", allocCounts, warming)
+	}
+	if draining > 0 {
+		allocCounts = fmt.Sprintf("%s [draining: %d, %d connections]", allocCounts, draining, connections)
+	}
 
 	checkCounts := FormatHealthChecksSummary(d.Allocations...)
 
@@ -179,9 +193,54 @@ func FormatAllocSummary(alloc *api.AllocationStatus) string {
 		msg += " [health checks: " + checkStr + "]"
 	}
 
+	if warmupStr := FormatWarmupSummary(alloc.Warmup); warmupStr != "" {
+		msg += " [" + warmupStr + "]"
+	}
+
+	if drainingStr := FormatDrainingSummary(alloc.Draining, time.Now()); drainingStr != "" {
+		msg += " [" + drainingStr + "]"
+	}
+
 	return msg
 }
 
+func FormatDrainingSummary(d *api.DrainingStatus, now time.Time) string {
+	if d == nil {
+		return ""
+	}
+
+	connections := "1 connection"
+	if d.ActiveConnections != 1 {
+		connections = fmt.Sprintf("%d connections", d.ActiveConnections)
+	}
+
+	left := d.TimeLeft(now)
+	if left == 0 {
+		return fmt.Sprintf("draining %s, kill timeout of %ds reached", connections, d.KillTimeout)
+	}
+	return fmt.Sprintf("draining %s, %s left of %ds kill timeout", connections, left.Round(time.Second), d.KillTimeout)
+}
+
+func FormatWarmupSummary(w *api.WarmupStatus) string {
+	if w == nil {
+		return ""
+	}
+
+	switch w.Status {
+	case api.WarmupRunning:
+		return fmt.Sprintf("warming up %d/%d", w.Completed, w.Total)
+	case api.WarmupPassed:
+		return fmt.Sprintf("warmed up %d/%d", w.Completed, w.Total)
+	case api.WarmupFailed:
+		if w.Error != "" {
+			return fmt.Sprintf("warm-up failed after %d/%d: %s", w.Completed, w.Total, w.Error)
+		}
+		return fmt.Sprintf("warm-up failed after %d/%d", w.Completed, w.Total)
+	}
+
+	return ""
+}
+
 func FormatHealthChecksSummary(allocs ...*api.AllocationStatus) string {
 	var total, pass, crit, warn int
 
@@ -42,3 +42,8 @@ func FormatRelativeTime(t time.Time) string {
 func FormatTime(t time.Time) string {
 	return t.Format(time.RFC3339)
 }
+
+// FormatCents formats an amount in cents as dollars
+func FormatCents(cents int) string {
+	return fmt.Sprintf("$%.2f", float64(cents)/100)
+}
@@ -34,6 +34,10 @@ func (lp *LogPresenter) printEntry(w io.Writer, asJSON bool, entry api.LogEntry)
 
 	fmt.Fprintf(w, "%s ", aurora.Faint(entry.Timestamp))
 
+	if entry.App != "" {
+		fmt.Fprintf(w, "%s ", aurora.Cyan(entry.App))
+	}
+
 	if !lp.HideAllocID {
 		fmt.Fprintf(w, "%s ", entry.Instance)
 	}
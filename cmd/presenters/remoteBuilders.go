@@ -0,0 +1,45 @@
+package presenters
+
+import "github.com/superfly/flyctl/api"
+
+type RemoteBuilders struct {
+	Builders []api.RemoteBuilder
+}
+
+func (p *RemoteBuilders) APIStruct() interface{} {
+	return p.Builders
+}
+
+func (p *RemoteBuilders) FieldNames() []string {
+	return []string{"Name", "App", "Status", "Created At"}
+}
+
+func (p *RemoteBuilders) Records() []map[string]string {
+	out := []map[string]string{}
+
+	for _, builder := range p.Builders {
+		name := builder.Name
+		if builder.Default {
+			if name == "" {
+				name = "default"
+			} else {
+				name += " (default)"
+			}
+		}
+
+		app, status := "", "not created"
+		if builder.App != nil {
+			app = builder.App.Name
+			status = builder.App.Status
+		}
+
+		out = append(out, map[string]string{
+			"Name":       name,
+			"App":        app,
+			"Status":     status,
+			"Created At": FormatRelativeTime(builder.CreatedAt),
+		})
+	}
+
+	return out
+}
@@ -1,6 +1,8 @@
 package presenters
 
 import (
+	"strings"
+
 	"github.com/superfly/flyctl/api"
 )
 
@@ -12,17 +14,23 @@ func (p *Secrets) APIStruct() interface{} {
 	return nil
 }
 func (p *Secrets) FieldNames() []string {
-	return []string{"Name", "Digest", "Date"}
+	return []string{"Name", "Digest", "Date", "Processes", "Staged"}
 }
 
 func (p *Secrets) Records() []map[string]string {
 	out := []map[string]string{}
 
 	for _, secret := range p.Secrets {
+		staged := ""
+		if secret.Staged {
+			staged = "yes"
+		}
 		out = append(out, map[string]string{
-			"Name":   secret.Name,
-			"Digest": secret.Digest,
-			"Date":   FormatRelativeTime(secret.CreatedAt),
+			"Name":      secret.Name,
+			"Digest":    secret.Digest,
+			"Date":      FormatRelativeTime(secret.CreatedAt),
+			"Processes": strings.Join(secret.ProcessGroups, ", "),
+			"Staged":    staged,
 		})
 	}
 
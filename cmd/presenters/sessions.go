@@ -0,0 +1,42 @@
+package presenters
+
+import "github.com/superfly/flyctl/api"
+
+type Sessions struct {
+	Sessions []api.Session
+}
+
+func (p *Sessions) APIStruct() interface{} {
+	return p.Sessions
+}
+
+func (p *Sessions) FieldNames() []string {
+	return []string{"ID", "Name", "Client", "Created At", "Last Used", "Last IP"}
+}
+
+func (p *Sessions) Records() []map[string]string {
+	out := []map[string]string{}
+
+	for _, session := range p.Sessions {
+		name := session.Name
+		if session.Current {
+			name += " (current)"
+		}
+
+		lastUsed := "never"
+		if session.LastUsedAt != nil {
+			lastUsed = FormatRelativeTime(*session.LastUsedAt)
+		}
+
+		out = append(out, map[string]string{
+			"ID":         session.ID,
+			"Name":       name,
+			"Client":     session.Client,
+			"Created At": FormatRelativeTime(session.CreatedAt),
+			"Last Used":  lastUsed,
+			"Last IP":    session.LastUsedIP,
+		})
+	}
+
+	return out
+}
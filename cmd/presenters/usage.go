@@ -0,0 +1,35 @@
+package presenters
+
+import (
+	"fmt"
+
+	"github.com/superfly/flyctl/api"
+)
+
+type AppUsage struct {
+	Usage []api.AppUsage
+}
+
+func (p *AppUsage) APIStruct() interface{} {
+	return p.Usage
+}
+
+func (p *AppUsage) FieldNames() []string {
+	return []string{"App", "VM Hours", "Bandwidth", "Volumes", "Estimated Cost"}
+}
+
+func (p *AppUsage) Records() []map[string]string {
+	out := []map[string]string{}
+
+	for _, usage := range p.Usage {
+		out = append(out, map[string]string{
+			"App":            usage.App,
+			"VM Hours":       fmt.Sprintf("%.1f", usage.VMHours),
+			"Bandwidth":      fmt.Sprintf("%.2f GB", usage.BandwidthGB),
+			"Volumes":        fmt.Sprintf("%.1f GB", usage.VolumeGB),
+			"Estimated Cost": FormatCents(usage.TotalCostCents),
+		})
+	}
+
+	return out
+}
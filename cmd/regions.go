@@ -1,8 +1,16 @@
 package cmd
 
 import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
 	"github.com/superfly/flyctl/cmdctx"
+	"github.com/superfly/flyctl/flyctl"
+	"github.com/superfly/flyctl/helpers"
 	"github.com/superfly/flyctl/internal/client"
+	"github.com/superfly/flyctl/terminal"
 
 	"github.com/superfly/flyctl/api"
 	"github.com/superfly/flyctl/docstrings"
@@ -16,21 +24,25 @@ func newRegionsCommand(client *client.Client) *Command {
 	cmd := BuildCommandKS(nil, nil, regionsStrings, client, requireAppName, requireSession)
 
 	addStrings := docstrings.Get("regions.add")
-	addCmd := BuildCommandKS(cmd, runRegionsAdd, addStrings, client, requireSession, requireAppName)
+	addCmd := BuildCommandKS(cmd, runRegionsAdd, addStrings, client, requireSession, requireAppName, mutating)
 	addCmd.Args = cobra.MinimumNArgs(1)
 
 	removeStrings := docstrings.Get("regions.remove")
-	removeCmd := BuildCommandKS(cmd, runRegionsRemove, removeStrings, client, requireSession, requireAppName)
+	removeCmd := BuildCommandKS(cmd, runRegionsRemove, removeStrings, client, requireSession, requireAppName, mutating)
 	removeCmd.Args = cobra.MinimumNArgs(1)
 
 	setStrings := docstrings.Get("regions.set")
-	setCmd := BuildCommandKS(cmd, runRegionsSet, setStrings, client, requireSession, requireAppName)
+	setCmd := BuildCommandKS(cmd, runRegionsSet, setStrings, client, requireSession, requireAppName, mutating)
 	setCmd.Args = cobra.MinimumNArgs(1)
 
 	setBackupStrings := docstrings.Get("regions.backup")
-	setBackupCmd := BuildCommand(cmd, runBackupRegionsSet, setBackupStrings.Usage, setBackupStrings.Short, setBackupStrings.Long, client, requireSession, requireAppName)
+	setBackupCmd := BuildCommand(cmd, runBackupRegionsSet, setBackupStrings.Usage, setBackupStrings.Short, setBackupStrings.Long, client, requireSession, requireAppName, mutating)
 	setBackupCmd.Args = cobra.MinimumNArgs(1)
 
+	pinStrings := docstrings.Get("regions.pin")
+	pinCmd := BuildCommandKS(cmd, runRegionsPin, pinStrings, client, requireSession, requireAppName, mutating)
+	pinCmd.Args = cobra.ArbitraryArgs
+
 	listStrings := docstrings.Get("regions.list")
 	BuildCommand(cmd, runRegionsList, listStrings.Usage, listStrings.Short, listStrings.Long, client, requireSession, requireAppName)
 
@@ -70,18 +82,38 @@ func runRegionsRemove(ctx *cmdctx.CmdContext) error {
 }
 
 func runRegionsSet(ctx *cmdctx.CmdContext) error {
-	addList := make([]string, 0)
-	delList := make([]string, 0)
-
 	// Get the Region List
 	regions, _, err := ctx.Client.API().ListAppRegions(ctx.AppName)
 	if err != nil {
 		return err
 	}
 
-	for _, r := range ctx.Args {
+	addList, delList := regionChanges(regions, ctx.Args)
+
+	input := api.ConfigureRegionsInput{
+		AppID:        ctx.AppName,
+		AllowRegions: addList,
+		DenyRegions:  delList,
+	}
+
+	newregions, backupRegions, err := ctx.Client.API().ConfigureRegions(input)
+	if err != nil {
+		return err
+	}
+
+	printRegions(ctx, newregions, backupRegions)
+
+	return nil
+}
+
+// regionChanges returns the regions to allow and deny to go from current to want
+func regionChanges(current []api.Region, want []string) (addList []string, delList []string) {
+	addList = make([]string, 0)
+	delList = make([]string, 0)
+
+	for _, r := range want {
 		found := false
-		for _, er := range regions {
+		for _, er := range current {
 			if r == er.Code {
 				found = true
 				break
@@ -92,9 +124,9 @@ func runRegionsSet(ctx *cmdctx.CmdContext) error {
 		}
 	}
 
-	for _, er := range regions {
+	for _, er := range current {
 		found := false
-		for _, r := range ctx.Args {
+		for _, r := range want {
 			if r == er.Code {
 				found = true
 				break
@@ -105,29 +137,32 @@ func runRegionsSet(ctx *cmdctx.CmdContext) error {
 		}
 	}
 
-	input := api.ConfigureRegionsInput{
-		AppID:        ctx.AppName,
-		AllowRegions: addList,
-		DenyRegions:  delList,
-	}
+	return addList, delList
+}
 
-	newregions, backupRegions, err := ctx.Client.API().ConfigureRegions(input)
+func runRegionsList(ctx *cmdctx.CmdContext) error {
+	regions, backupRegions, err := ctx.Client.API().ListAppRegions(ctx.AppName)
 	if err != nil {
 		return err
 	}
 
-	printRegions(ctx, newregions, backupRegions)
+	printRegions(ctx, regions, backupRegions)
 
-	return nil
-}
+	if ctx.OutputJSON() {
+		return nil
+	}
 
-func runRegionsList(ctx *cmdctx.CmdContext) error {
-	regions, backupRegions, err := ctx.Client.API().ListAppRegions(ctx.AppName)
+	groups, err := ctx.Client.API().ListProcessGroupRegions(ctx.AppName)
 	if err != nil {
 		return err
 	}
-
-	printRegions(ctx, regions, backupRegions)
+	for _, g := range groups {
+		if len(g.Regions) > 0 {
+			fmt.Fprintln(ctx.Out)
+			printProcessGroupRegions(ctx, groups)
+			break
+		}
+	}
 
 	return nil
 }
@@ -148,6 +183,138 @@ func runBackupRegionsSet(ctx *cmdctx.CmdContext) error {
 	return nil
 }
 
+func runRegionsPin(ctx *cmdctx.CmdContext) error {
+	var pins map[string][]string
+
+	switch len(ctx.Args) {
+	case 0:
+		if ctx.AppConfig == nil {
+			return errors.New("no fly.toml found, pin a process group with regions pin <group> <region>...")
+		}
+		var errs []flyctl.ConfigError
+		pins, errs = ctx.AppConfig.ProcessRegions()
+		if len(errs) > 0 {
+			return configSectionError(ctx, "[regions]", errs)
+		}
+		if len(pins) == 0 {
+			return errors.New("fly.toml has no [regions] section, pin a process group with regions pin <group> <region>...")
+		}
+	case 1:
+		return errors.New("at least one region is required, like regions pin worker iad")
+	default:
+		pins = map[string][]string{ctx.Args[0]: ctx.Args[1:]}
+	}
+
+	placement, err := regionPlacement(ctx)
+	if err != nil {
+		return err
+	}
+
+	errs, warnings := flyctl.ValidateRegionPins(pins, placement)
+	for _, w := range warnings {
+		terminal.Warn(w)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("can't pin regions:\n  %s", strings.Join(errs, "\n  "))
+	}
+
+	current, err := ctx.Client.API().ListProcessGroupRegions(ctx.AppName)
+	if err != nil {
+		return err
+	}
+
+	groups := make([]string, 0, len(pins))
+	for group := range pins {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	for _, group := range groups {
+		var currentRegions []api.Region
+		for _, g := range current {
+			if g.Name == group {
+				currentRegions = g.Regions
+			}
+		}
+
+		addList, delList := regionChanges(currentRegions, pins[group])
+		if len(addList) == 0 && len(delList) == 0 {
+			continue
+		}
+
+		_, _, err := ctx.Client.API().ConfigureRegions(api.ConfigureRegionsInput{
+			AppID:        ctx.AppName,
+			Group:        group,
+			AllowRegions: addList,
+			DenyRegions:  delList,
+		})
+		if err != nil {
+			return errors.Wrapf(err, "error pinning process group %s", group)
+		}
+	}
+
+	updated, err := ctx.Client.API().ListProcessGroupRegions(ctx.AppName)
+	if err != nil {
+		return err
+	}
+	printProcessGroupRegions(ctx, updated)
+
+	return nil
+}
+
+// regionPlacement collects the instance counts and volumes region pins are validated against
+func regionPlacement(ctx *cmdctx.CmdContext) (flyctl.RegionPlacement, error) {
+	placement := flyctl.RegionPlacement{Counts: map[string]int{}}
+
+	counts, err := ctx.Client.API().GetAppVMCount(ctx.AppName)
+	if err != nil {
+		return placement, err
+	}
+	for _, c := range counts {
+		placement.Counts[c.Name] = c.Count
+	}
+
+	if ctx.AppConfig == nil {
+		return placement, nil
+	}
+	if placement.Volume = ctx.AppConfig.MountSource(); placement.Volume == "" {
+		return placement, nil
+	}
+
+	volumes, err := ctx.Client.API().GetVolumes(ctx.AppName)
+	if err != nil {
+		return placement, err
+	}
+	placement.VolumeRegions = map[string]int{}
+	for _, v := range volumes {
+		if v.Name == placement.Volume {
+			placement.VolumeRegions[v.Region]++
+		}
+	}
+
+	return placement, nil
+}
+
+func printProcessGroupRegions(ctx *cmdctx.CmdContext, groups []api.ProcessGroup) {
+	if ctx.OutputJSON() {
+		ctx.WriteJSON(groups)
+		return
+	}
+
+	table := helpers.MakeSimpleTable(ctx.Out, []string{"Process Group", "Regions"})
+	for _, g := range groups {
+		codes := make([]string, 0, len(g.Regions))
+		for _, r := range g.Regions {
+			codes = append(codes, r.Code)
+		}
+		if len(codes) == 0 {
+			codes = append(codes, "any")
+		}
+		table.Append([]string{g.Name, strings.Join(codes, ", ")})
+	}
+	table.Render()
+}
+
 func printRegions(ctx *cmdctx.CmdContext, regions []api.Region, backupRegions []api.Region) {
 
 	if ctx.OutputJSON() {
@@ -0,0 +1,285 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/superfly/flyctl/cmdctx"
+	"github.com/superfly/flyctl/docstrings"
+	"github.com/superfly/flyctl/flyctl"
+	"github.com/superfly/flyctl/flyname"
+	"github.com/superfly/flyctl/helpers"
+	"github.com/superfly/flyctl/internal/client"
+	"github.com/superfly/flyctl/internal/dockerconfig"
+	"github.com/superfly/flyctl/internal/registry"
+)
+
+func newRegistryCommand(client *client.Client) *Command {
+	registryStrings := docstrings.Get("registry")
+	cmd := BuildCommandKS(nil, nil, registryStrings, client, requireSession)
+
+	pruneStrings := docstrings.Get("registry.prune")
+	pruneCmd := BuildCommandKS(cmd, runRegistryPrune, pruneStrings, client, requireSession, requireAppName, mutating)
+	pruneCmd.Args = cobra.NoArgs
+	pruneCmd.AddIntFlag(IntFlagOpts{
+		Name:        "keep",
+		Description: "Number of the newest deployment images to keep",
+		Default:     10,
+	})
+	pruneCmd.AddBoolFlag(BoolFlagOpts{
+		Name:        "dry-run",
+		Description: "List the images that would be removed without removing them",
+	})
+	pruneCmd.AddBoolFlag(BoolFlagOpts{Name: "yes", Shorthand: "y", Description: "Accept all confirmations"})
+
+	retentionStrings := docstrings.Get("registry.retention")
+	retentionCmd := BuildCommandKS(cmd, nil, retentionStrings, client, requireSession)
+
+	retentionShowStrings := docstrings.Get("registry.retention.show")
+	retentionShowCmd := BuildCommandKS(retentionCmd, runRegistryRetentionShow, retentionShowStrings, client, requireSession)
+	retentionShowCmd.Args = cobra.NoArgs
+	retentionShowCmd.AddStringFlag(StringFlagOpts{
+		Name:        "org",
+		Description: "The organization to show the policy of",
+	})
+
+	retentionSetStrings := docstrings.Get("registry.retention.set")
+	retentionSetCmd := BuildCommandKS(retentionCmd, runRegistryRetentionSet, retentionSetStrings, client, requireSession, mutating)
+	retentionSetCmd.Args = cobra.ExactArgs(1)
+	retentionSetCmd.AddStringFlag(StringFlagOpts{
+		Name:        "org",
+		Description: "The organization to set the policy of",
+	})
+
+	loginStrings := docstrings.Get("registry.login")
+	loginCmd := BuildCommandKS(cmd, runRegistryLogin, loginStrings, client, requireSession)
+	loginCmd.Args = cobra.NoArgs
+
+	// docker runs this through the docker-credential-<name> script written by registry login,
+	// so it can't require a session: without one docker is told there are no credentials
+	helperStrings := docstrings.Get("registry.credential-helper")
+	helperCmd := BuildCommandKS(cmd, runRegistryCredentialHelper, helperStrings, client)
+	helperCmd.Args = cobra.ExactArgs(1)
+	helperCmd.Hidden = true
+
+	return cmd
+}
+
+func runRegistryPrune(cmdCtx *cmdctx.CmdContext) error {
+	ctx := createCancellableContext()
+
+	keep := cmdCtx.Config.GetInt("keep")
+	if keep < 1 {
+		return errors.New("--keep must be at least 1, the current release's image is always kept as well")
+	}
+	dryRun := cmdCtx.Config.GetBool("dry-run")
+
+	host := viper.GetString(flyctl.ConfigRegistryHost)
+	c := newRegistryClient(host)
+
+	opts := registry.PruneOptions{Keep: keep, DryRun: true}
+
+	// the current release's image is kept even when it's older than the images kept by --keep,
+	// like after rolling back
+	releases, err := cmdCtx.Client.API().GetAppReleases(cmdCtx.AppName, 1)
+	if err != nil {
+		return err
+	}
+	if len(releases) > 0 && releases[0].ImageRef != "" {
+		if ref, err := registry.ParseReference(releases[0].ImageRef); err == nil && ref.Host == host && ref.Repository == cmdCtx.AppName {
+			opts.Protect = append(opts.Protect, ref.Identifier())
+		}
+	}
+
+	pruned, err := registry.PruneDeploymentTags(ctx, c, cmdCtx.AppName, opts)
+	if err != nil {
+		var notFound *registry.NotFoundError
+		if errors.As(err, &notFound) {
+			return fmt.Errorf("%s has no images in %s", cmdCtx.AppName, host)
+		}
+		return err
+	}
+
+	if !dryRun && len(pruned) > 0 && !cmdCtx.Config.GetBool("yes") && !cmdCtx.OutputJSON() {
+		printPrunedTags(cmdCtx, host, pruned)
+		if !confirm(fmt.Sprintf("Remove these %d images?", len(pruned))) {
+			return nil
+		}
+	}
+
+	if !dryRun && len(pruned) > 0 {
+		opts.DryRun = false
+		if pruned, err = registry.PruneDeploymentTags(ctx, c, cmdCtx.AppName, opts); err != nil {
+			return err
+		}
+	}
+
+	if cmdCtx.OutputJSON() {
+		cmdCtx.WriteJSON(pruned)
+		return nil
+	}
+
+	if len(pruned) == 0 {
+		fmt.Fprintf(cmdCtx.Out, "Nothing to prune, %s has %d or fewer deployment images\n", cmdCtx.AppName, keep)
+		return nil
+	}
+
+	if dryRun {
+		printPrunedTags(cmdCtx, host, pruned)
+		fmt.Fprintf(cmdCtx.Out, "%d images would be removed\n", len(pruned))
+		return nil
+	}
+
+	fmt.Fprintf(cmdCtx.Out, "Removed %d images\n", len(pruned))
+	return nil
+}
+
+func printPrunedTags(cmdCtx *cmdctx.CmdContext, host string, pruned []registry.PrunedTag) {
+	table := helpers.MakeSimpleTable(cmdCtx.Out, []string{"Image", "Digest"})
+	for _, p := range pruned {
+		table.Append([]string{fmt.Sprintf("%s/%s:%s", host, cmdCtx.AppName, p.Tag), shortDigest(p.Digest)})
+	}
+	table.Render()
+}
+
+func runRegistryRetentionShow(cmdCtx *cmdctx.CmdContext) error {
+	slug, _ := cmdCtx.Config.GetString("org")
+	org, err := selectOrganization(cmdCtx.Client.API(), slug)
+	if err != nil {
+		return err
+	}
+
+	policy, err := cmdCtx.Client.API().GetRegistryRetentionPolicy(org.Slug)
+	if err != nil {
+		return err
+	}
+
+	if cmdCtx.OutputJSON() {
+		cmdCtx.WriteJSON(policy)
+		return nil
+	}
+
+	if policy == nil || policy.KeepDeployments == 0 {
+		fmt.Fprintf(cmdCtx.Out, "%s has no retention policy, deployment images are kept forever\n", org.Slug)
+		return nil
+	}
+	fmt.Fprintf(cmdCtx.Out, "%s keeps the newest %d deployment images of each app\n", org.Slug, policy.KeepDeployments)
+	return nil
+}
+
+func runRegistryRetentionSet(cmdCtx *cmdctx.CmdContext) error {
+	keep, err := strconv.Atoi(cmdCtx.Args[0])
+	if err != nil || keep < 0 {
+		return fmt.Errorf("invalid number of images \"%s\", use 0 to keep every image", cmdCtx.Args[0])
+	}
+
+	slug, _ := cmdCtx.Config.GetString("org")
+	org, err := selectOrganization(cmdCtx.Client.API(), slug)
+	if err != nil {
+		return err
+	}
+
+	if _, err := cmdCtx.Client.API().SetRegistryRetentionPolicy(org.ID, keep); err != nil {
+		return err
+	}
+
+	if keep == 0 {
+		fmt.Fprintf(cmdCtx.Out, "Removed the retention policy of %s, deployment images are kept forever\n", org.Slug)
+		return nil
+	}
+	fmt.Fprintf(cmdCtx.Out, "%s now keeps the newest %d deployment images of each app, older ones are removed automatically\n", org.Slug, keep)
+	return nil
+}
+
+// credentialHelperName is the docker credential helper flyctl installs itself as, docker runs
+// docker-credential-<name>
+func credentialHelperName() string {
+	return strings.TrimSuffix(flyname.Name(), filepath.Ext(flyname.Name()))
+}
+
+func runRegistryLogin(cmdCtx *cmdctx.CmdContext) error {
+	host := viper.GetString(flyctl.ConfigRegistryHost)
+	name := credentialHelperName()
+
+	executable, err := os.Executable()
+	if err != nil {
+		return errors.Wrap(err, "can't find the flyctl executable")
+	}
+	if resolved, err := filepath.EvalSymlinks(executable); err == nil {
+		executable = resolved
+	}
+
+	// the helper goes next to flyctl, which is on the PATH, unless flyctl was installed somewhere
+	// only root can write to
+	path, err := writeCredentialHelper(filepath.Dir(executable), name, executable)
+	if err != nil {
+		if path, err = writeCredentialHelper(filepath.Join(flyctl.ConfigDir(), "bin"), name, executable); err != nil {
+			return errors.Wrap(err, "error writing the docker credential helper")
+		}
+	}
+
+	if err := dockerconfig.SetCredHelper(host, name); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmdCtx.Out, "Docker now gets credentials for %s from %s\n", host, path)
+	if found, err := exec.LookPath(filepath.Base(path)); err != nil || found != path {
+		fmt.Fprintf(cmdCtx.Out, "Add %s to your PATH so docker can run it\n", filepath.Dir(path))
+	}
+	fmt.Fprintf(cmdCtx.Out, "You can now pull and push images with docker, like `docker pull %s/<app>:latest`\n", host)
+
+	return nil
+}
+
+// writeCredentialHelper writes a docker-credential-<name> script to dir that runs flyctl's
+// credential helper, returning its path
+func writeCredentialHelper(dir, name, executable string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", err
+	}
+
+	path := filepath.Join(dir, "docker-credential-"+name)
+	script := fmt.Sprintf("#!/bin/sh\nexec %s registry credential-helper \"$@\"\n", shellQuote(executable))
+	if runtime.GOOS == "windows" {
+		path += ".cmd"
+		script = fmt.Sprintf("@\"%s\" registry credential-helper %%*\r\n", executable)
+	}
+
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// shellQuote quotes s as a single word for sh, in single quotes so $, ` and \ are kept as written
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func runRegistryCredentialHelper(cmdCtx *cmdctx.CmdContext) error {
+	host := viper.GetString(flyctl.ConfigRegistryHost)
+
+	// the token is read each time docker asks, so credentials follow flyctl auth login and logout
+	lookup := func(server string) (dockerconfig.Credential, bool) {
+		token := flyctl.GetAPIToken()
+		if server != host || token == "" {
+			return dockerconfig.Credential{}, false
+		}
+		return dockerconfig.Credential{ServerAddress: host, Username: "x", Password: token}, true
+	}
+
+	if err := dockerconfig.ServeHelper(cmdCtx.Args[0], os.Stdin, cmdCtx.Out, []string{host}, lookup); err != nil {
+		// docker reads errors from stdout, and only recognizes missing credentials by the exact message
+		fmt.Fprintln(cmdCtx.Out, err)
+		return ErrAbort
+	}
+	return nil
+}
@@ -1,8 +1,18 @@
 package cmd
 
 import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/superfly/flyctl/api"
 	"github.com/superfly/flyctl/cmdctx"
+	"github.com/superfly/flyctl/flyctl"
 	"github.com/superfly/flyctl/internal/client"
+	"github.com/superfly/flyctl/terminal"
 
 	"github.com/superfly/flyctl/docstrings"
 
@@ -12,6 +22,37 @@ import (
 func newReleasesCommand(client *client.Client) *Command {
 	releasesStrings := docstrings.Get("releases")
 	cmd := BuildCommandKS(nil, runReleases, releasesStrings, client, requireSession, requireAppName)
+
+	promoteStrings := docstrings.Get("releases.promote")
+	promoteCmd := BuildCommandKS(cmd, runReleasesPromote, promoteStrings, client, requireSession, requireAppName, mutating)
+	promoteCmd.Args = cobra.MaximumNArgs(1)
+	promoteCmd.AddBoolFlag(BoolFlagOpts{
+		Name:        "detach",
+		Description: "Return immediately instead of monitoring deployment progress",
+	})
+
+	cancelStrings := docstrings.Get("releases.cancel")
+	cancelCmd := BuildCommandKS(cmd, runReleasesCancel, cancelStrings, client, requireSession, requireAppName, mutating)
+	cancelCmd.Args = cobra.MaximumNArgs(1)
+
+	rollbackStrings := docstrings.Get("releases.rollback")
+	rollbackCmd := BuildCommandKS(cmd, runReleasesRollback, rollbackStrings, client, requireSession, requireAppName, mutating)
+	rollbackCmd.Args = cobra.MaximumNArgs(1)
+	rollbackCmd.AddBoolFlag(BoolFlagOpts{
+		Name:        "detach",
+		Description: "Return immediately instead of monitoring deployment progress",
+	})
+	rollbackCmd.AddBoolFlag(BoolFlagOpts{Name: "yes", Shorthand: "y", Description: "Accept all confirmations"})
+	addDeployLockFlags(rollbackCmd)
+
+	diffStrings := docstrings.Get("releases.diff")
+	diffCmd := BuildCommandKS(cmd, runReleasesDiff, diffStrings, client, requireSession, requireAppName)
+	diffCmd.Args = cobra.RangeArgs(1, 2)
+
+	watchStrings := docstrings.Get("releases.watch")
+	watchCmd := BuildCommandKS(cmd, runReleasesWatch, watchStrings, client, requireSession, requireAppName)
+	watchCmd.Args = cobra.MaximumNArgs(1)
+
 	return cmd
 }
 
@@ -22,3 +63,351 @@ func runReleases(ctx *cmdctx.CmdContext) error {
 	}
 	return ctx.Render(&presenters.Releases{Releases: releases})
 }
+
+// heldDeployment returns the app's deployment that's waiting to be promoted, checking it's of the
+// release version given in args, if any
+func heldDeployment(cmdCtx *cmdctx.CmdContext) (*api.DeploymentStatus, error) {
+	d, err := cmdCtx.Client.API().GetDeploymentStatus(cmdCtx.AppName, "")
+	if err != nil {
+		return nil, err
+	}
+	if d == nil || !d.AwaitingPromotion() {
+		return nil, fmt.Errorf("%s has no release waiting to be promoted", cmdCtx.AppName)
+	}
+
+	if len(cmdCtx.Args) > 0 {
+		version, err := parseReleaseVersion(cmdCtx.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		if version != d.Version {
+			return nil, fmt.Errorf("release v%d is not waiting to be promoted, v%d is", version, d.Version)
+		}
+	}
+
+	return d, nil
+}
+
+func runReleasesPromote(cmdCtx *cmdctx.CmdContext) error {
+	ctx := createCancellableContext()
+
+	d, err := heldDeployment(cmdCtx)
+	if err != nil {
+		return err
+	}
+
+	if _, err := cmdCtx.Client.API().PromoteDeployment(d.ID); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmdCtx.Out, "Promoted v%d, traffic is switching to its instances\n", d.Version)
+
+	return watchDeployment(ctx, cmdCtx)
+}
+
+// watchedDeployment returns the deployment given in args by ID or release version, or the app's
+// latest deployment
+func watchedDeployment(cmdCtx *cmdctx.CmdContext) (*api.DeploymentStatus, error) {
+	id := ""
+	version := 0
+	if len(cmdCtx.Args) > 0 {
+		if v, err := parseReleaseVersion(cmdCtx.Args[0]); err == nil {
+			version = v
+		} else {
+			id = cmdCtx.Args[0]
+		}
+	}
+
+	d, err := cmdCtx.Client.API().GetDeploymentStatus(cmdCtx.AppName, id)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case d == nil && id != "":
+		return nil, fmt.Errorf("%s has no deployment %s", cmdCtx.AppName, id)
+	case d == nil:
+		return nil, fmt.Errorf("%s has no deployments", cmdCtx.AppName)
+	case version != 0 && d.Version != version:
+		return nil, fmt.Errorf("the latest deployment of %s is of v%d, not v%d. Watch older deployments by ID", cmdCtx.AppName, d.Version, version)
+	}
+	return d, nil
+}
+
+func runReleasesWatch(cmdCtx *cmdctx.CmdContext) error {
+	ctx := createCancellableContext()
+
+	restoreOutput := cmdCtx.StreamEvents()
+	defer restoreOutput()
+
+	d, err := watchedDeployment(cmdCtx)
+	if err != nil {
+		return err
+	}
+
+	if d.InProgress {
+		fmt.Fprintf(cmdCtx.Out, "Attaching to deployment %s of v%d\n", d.ID, d.Version)
+	} else {
+		fmt.Fprintf(cmdCtx.Out, "Deployment %s of v%d has already finished\n", d.ID, d.Version)
+	}
+
+	return watchDeploymentID(ctx, cmdCtx, d.ID, nil, nil)
+}
+
+func runReleasesCancel(cmdCtx *cmdctx.CmdContext) error {
+	d, err := heldDeployment(cmdCtx)
+	if err != nil {
+		return err
+	}
+
+	if _, err := cmdCtx.Client.API().AbortDeployment(d.ID, "cancelled before promotion"); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmdCtx.Out, "Cancelled v%d, its instances are being removed and the previous release keeps serving traffic\n", d.Version)
+	return nil
+}
+
+// rollbackTarget returns the release given in args, or the newest stable release before the current one
+func rollbackTarget(cmdCtx *cmdctx.CmdContext, current *api.Release, releases []api.Release) (*api.Release, error) {
+	if len(cmdCtx.Args) > 0 {
+		version, err := parseReleaseVersion(cmdCtx.Args[0])
+		if err != nil {
+			return nil, err
+		}
+		if version == current.Version {
+			return nil, fmt.Errorf("v%d is already the current release", version)
+		}
+		release, err := cmdCtx.Client.API().GetAppRelease(cmdCtx.AppName, version)
+		if err != nil {
+			if err == api.ErrNotFound {
+				return nil, fmt.Errorf("release v%d not found", version)
+			}
+			return nil, err
+		}
+		return release, nil
+	}
+
+	for i := range releases {
+		r := &releases[i]
+		if r.Version < current.Version && r.Stable && r.ImageRef != "" {
+			return r, nil
+		}
+	}
+	return nil, fmt.Errorf("%s has no stable release before v%d to roll back to, give a version to roll back to", cmdCtx.AppName, current.Version)
+}
+
+func runReleasesRollback(cmdCtx *cmdctx.CmdContext) error {
+	ctx := createCancellableContext()
+
+	releases, err := cmdCtx.Client.API().GetAppReleases(cmdCtx.AppName, 25)
+	if err != nil {
+		return err
+	}
+	if len(releases) == 0 {
+		return fmt.Errorf("%s has no releases", cmdCtx.AppName)
+	}
+	current := &releases[0]
+
+	target, err := rollbackTarget(cmdCtx, current, releases)
+	if err != nil {
+		return err
+	}
+	if target.ImageRef == "" {
+		return fmt.Errorf("release v%d does not have an image", target.Version)
+	}
+
+	// the image is deployed by digest, so a tag pushed again since can't change what's rolled back to
+	ref, err := pinnedImageRef(ctx, cmdCtx, fmt.Sprintf("v%d", target.Version))
+	if err != nil {
+		return errors.Wrapf(err, "can't find the image of v%d, it may have been pruned", target.Version)
+	}
+
+	config, err := cmdCtx.Client.API().GetReleaseConfig(cmdCtx.AppName, target.Version)
+	if err != nil {
+		return err
+	}
+
+	if !cmdCtx.Config.GetBool("yes") && cmdCtx.IO.IsInteractive() {
+		if !confirm(fmt.Sprintf("Roll %s back from v%d to the image and config of v%d?", cmdCtx.AppName, current.Version, target.Version)) {
+			return nil
+		}
+	}
+
+	ctx, releaseLock, err := holdDeployLock(ctx, cmdCtx)
+	if err != nil {
+		return err
+	}
+	defer releaseLock()
+
+	input := api.DeployImageInput{
+		AppID: cmdCtx.AppName,
+		Image: ref.String(),
+	}
+	if len(config.Definition) > 0 {
+		input.Definition = api.DefinitionPtr(config.Definition)
+	}
+
+	release, err := cmdCtx.Client.API().DeployImage(input)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(cmdCtx.Out, "Rolling back to v%d\n", target.Version)
+	return followRelease(ctx, cmdCtx, release, ref.String())
+}
+
+// releaseSnapshot is what a release deployed, as compared by releases diff
+type releaseSnapshot struct {
+	Version     int
+	ImageRef    string
+	ImageDigest string
+	Definition  api.Definition
+	Secrets     []api.Secret
+}
+
+func getReleaseSnapshot(ctx context.Context, cmdCtx *cmdctx.CmdContext, version int) (*releaseSnapshot, error) {
+	release, err := cmdCtx.Client.API().GetAppRelease(cmdCtx.AppName, version)
+	if err != nil {
+		if err == api.ErrNotFound {
+			return nil, fmt.Errorf("release v%d not found", version)
+		}
+		return nil, err
+	}
+	config, err := cmdCtx.Client.API().GetReleaseConfig(cmdCtx.AppName, version)
+	if err != nil {
+		return nil, err
+	}
+	secrets, err := cmdCtx.Client.API().GetReleaseSecrets(cmdCtx.AppName, version)
+	if err != nil {
+		return nil, err
+	}
+
+	snapshot := &releaseSnapshot{Version: version, ImageRef: release.ImageRef, Definition: config.Definition, Secrets: secrets}
+	if release.ImageRef != "" {
+		// tags can be pushed again, so the digest is what shows whether the image changed
+		if ref, err := pinnedImageRef(ctx, cmdCtx, fmt.Sprintf("v%d", version)); err == nil {
+			snapshot.ImageDigest = ref.Digest
+		} else {
+			terminal.Debugf("Could not resolve the image digest of v%d: %v\n", version, err)
+		}
+	}
+	return snapshot, nil
+}
+
+// releaseDiff is what changed from one release to another. Secrets are compared by digest and only
+// their names are shown.
+type releaseDiff struct {
+	From          int
+	To            int
+	FromImage     string
+	ToImage       string
+	ImageChanged  bool
+	EnvChanges    []flyctl.DefinitionChange
+	ConfigChanges []flyctl.DefinitionChange
+	SecretChanges []flyctl.SecretChange
+}
+
+func diffReleases(from, to *releaseSnapshot) (*releaseDiff, error) {
+	d := &releaseDiff{From: from.Version, To: to.Version, FromImage: from.ImageRef, ToImage: to.ImageRef}
+
+	if from.ImageDigest != "" && to.ImageDigest != "" {
+		d.ImageChanged = from.ImageDigest != to.ImageDigest
+		d.FromImage += "@" + from.ImageDigest
+		d.ToImage += "@" + to.ImageDigest
+	} else {
+		d.ImageChanged = from.ImageRef != to.ImageRef
+	}
+
+	changes, err := flyctl.DiffDefinitions(from.Definition, to.Definition)
+	if err != nil {
+		return nil, err
+	}
+	for _, change := range changes {
+		if strings.HasPrefix(change.Path, "env.") {
+			change.Path = strings.TrimPrefix(change.Path, "env.")
+			d.EnvChanges = append(d.EnvChanges, change)
+		} else {
+			d.ConfigChanges = append(d.ConfigChanges, change)
+		}
+	}
+
+	d.SecretChanges = flyctl.DiffSecrets(from.Secrets, to.Secrets)
+	return d, nil
+}
+
+func runReleasesDiff(cmdCtx *cmdctx.CmdContext) error {
+	ctx := createCancellableContext()
+
+	to, err := parseReleaseVersion(cmdCtx.Args[len(cmdCtx.Args)-1])
+	if err != nil {
+		return err
+	}
+	from := to - 1
+	if len(cmdCtx.Args) == 2 {
+		if from, err = parseReleaseVersion(cmdCtx.Args[0]); err != nil {
+			return err
+		}
+	}
+	if from < 0 || from == to {
+		return fmt.Errorf("give two different releases to compare, like v41 v42")
+	}
+
+	fromSnapshot, err := getReleaseSnapshot(ctx, cmdCtx, from)
+	if err != nil {
+		return err
+	}
+	toSnapshot, err := getReleaseSnapshot(ctx, cmdCtx, to)
+	if err != nil {
+		return err
+	}
+
+	diff, err := diffReleases(fromSnapshot, toSnapshot)
+	if err != nil {
+		return err
+	}
+
+	if cmdCtx.OutputJSON() {
+		cmdCtx.WriteJSON(diff)
+		return nil
+	}
+
+	out := cmdCtx.Out
+	fmt.Fprintf(out, "%s\n\n", aurora.Bold(fmt.Sprintf("Changes from v%d to v%d", from, to)))
+
+	fmt.Fprintln(out, aurora.Bold("Image"))
+	if diff.ImageChanged {
+		fmt.Fprintf(out, "  - %s\n  + %s\n", diff.FromImage, diff.ToImage)
+	} else {
+		fmt.Fprintf(out, "  unchanged, %s\n", diff.ToImage)
+	}
+
+	printSection := func(title string, lines []fmt.Stringer) {
+		fmt.Fprintln(out)
+		fmt.Fprintln(out, aurora.Bold(title))
+		if len(lines) == 0 {
+			fmt.Fprintln(out, "  unchanged")
+		}
+		for _, line := range lines {
+			fmt.Fprintf(out, "  %s\n", line)
+		}
+	}
+
+	env := make([]fmt.Stringer, len(diff.EnvChanges))
+	for i, c := range diff.EnvChanges {
+		env[i] = c
+	}
+	config := make([]fmt.Stringer, len(diff.ConfigChanges))
+	for i, c := range diff.ConfigChanges {
+		config[i] = c
+	}
+	secrets := make([]fmt.Stringer, len(diff.SecretChanges))
+	for i, c := range diff.SecretChanges {
+		secrets[i] = c
+	}
+
+	printSection("Environment", env)
+	printSection("Config", config)
+	printSection("Secrets", secrets)
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/cmdctx"
+	"github.com/superfly/flyctl/flyctl"
+	"github.com/superfly/flyctl/helpers"
+	"github.com/superfly/flyctl/internal/registry"
+)
+
+func runAppsRename(cmdCtx *cmdctx.CmdContext) error {
+	oldName, newName := cmdCtx.Args[0], cmdCtx.Args[1]
+	client := cmdCtx.Client.API()
+
+	app, err := client.GetApp(oldName)
+	if err != nil {
+		return errors.Wrap(err, "Error fetching app")
+	}
+
+	before, err := fetchMoveInventory(client, oldName)
+	if err != nil {
+		return errors.Wrap(err, "Error fetching app resources")
+	}
+	fmt.Printf("%s will keep its releases and its %s\n", oldName, before.summary())
+
+	if !cmdCtx.Config.GetBool("yes") {
+		fmt.Println(aurora.Red(fmt.Sprintf("%s will stop answering once renamed, update anything that uses the old name", app.Hostname)))
+		if !confirm(fmt.Sprintf("Rename %s to %s?", oldName, newName)) {
+			return nil
+		}
+	}
+
+	releases, err := client.GetAppReleases(oldName, 1)
+	if err != nil {
+		return err
+	}
+
+	renamed, err := client.RenameApp(oldName, newName)
+	if err != nil {
+		return errors.WithMessage(err, "Failed to rename app")
+	}
+	cmdCtx.Statusf("rename", cmdctx.SDONE, "Renamed %s to %s, now at %s\n", oldName, renamed.Name, renamed.Hostname)
+
+	if len(releases) > 0 {
+		if err := retagRenamedImage(cmdCtx, releases[0].ImageRef, oldName, renamed.Name); err != nil {
+			return err
+		}
+	}
+
+	after, err := fetchMoveInventory(client, renamed.Name)
+	if err != nil {
+		return errors.Wrap(err, "Error checking the renamed app's resources")
+	}
+	if left := before.leftBehind(after); len(left) > 0 {
+		cmdCtx.Statusf("rename", cmdctx.SWARN, "Some of the app's resources didn't follow the rename:\n")
+		for _, msg := range left {
+			cmdCtx.Statusf("rename", cmdctx.SDETAIL, "%s\n", msg)
+		}
+	}
+
+	renameLocalConfig(cmdCtx, oldName, renamed.Name)
+
+	return nil
+}
+
+// retagRenamedImage copies the current image from the old app's repository in the fly registry
+// to the new one and deploys it from there, since deployment tags embed the app name
+func retagRenamedImage(cmdCtx *cmdctx.CmdContext, imageRef string, oldName string, newName string) error {
+	ref, err := registry.ParseReference(imageRef)
+	if err != nil || ref.Host != viper.GetString(flyctl.ConfigRegistryHost) || ref.Repository != oldName {
+		// images from other registries don't depend on the app's name
+		return nil
+	}
+
+	target := ref
+	target.Repository = newName
+	target.Digest = ""
+
+	ctx := createCancellableContext()
+	if _, err := pushImage(ctx, cmdCtx, imageRef, target.String()); err != nil {
+		return errors.WithMessagef(err, "Failed to copy %s to %s, redeploy with flyctl deploy -a %s", imageRef, target, newName)
+	}
+
+	client := cmdCtx.Client.API()
+	serverCfg, err := client.GetConfig(newName)
+	if err != nil {
+		return err
+	}
+	release, err := client.DeployImage(api.DeployImageInput{
+		AppID:      newName,
+		Image:      target.String(),
+		Definition: &serverCfg.Definition,
+	})
+	if err != nil {
+		return errors.WithMessage(err, "Failed to deploy the retagged image")
+	}
+
+	cmdCtx.Statusf("rename", cmdctx.SDONE, "Deployed %s as release v%d\n", target, release.Version)
+	return nil
+}
+
+// renameLocalConfig updates the app name in the working directory's config file when it names the
+// old app. Configs built from includes or templates are left for the user to edit.
+func renameLocalConfig(cmdCtx *cmdctx.CmdContext, oldName string, newName string) {
+	configFile, err := flyctl.ResolveConfigFileFromPath(cmdCtx.WorkingDir)
+	if err != nil || !helpers.FileExists(configFile) {
+		return
+	}
+	path := helpers.PathRelativeToCWD(configFile)
+
+	appConfig, err := flyctl.LoadAppConfig(configFile)
+	if err != nil {
+		fmt.Printf("Update the app name in %s to %s\n", path, newName)
+		return
+	}
+	if appConfig.AppName != oldName {
+		return
+	}
+	if len(appConfig.Includes) > 0 {
+		fmt.Printf("Update the app name in %s to %s\n", path, newName)
+		return
+	}
+
+	appConfig.AppName = newName
+	if err := appConfig.WriteToFile(configFile); err != nil {
+		fmt.Printf("Could not update %s, change its app name to %s: %s\n", path, newName, err)
+		return
+	}
+	fmt.Printf("Updated the app name in %s\n", path)
+}
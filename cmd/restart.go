@@ -14,7 +14,7 @@ import (
 
 func newRestartCommand(client *client.Client) *Command {
 	restartStrings := docstrings.Get("restart")
-	restartCmd := BuildCommandKS(nil, runRestart, restartStrings, client, requireSession, requireAppNameAsArg)
+	restartCmd := BuildCommandKS(nil, forEachConfirmedApp("Restart", runRestart), restartStrings, client, requireSession, requireAppNameAsArg, changesSelectedApps, mutating)
 	restartCmd.Args = cobra.RangeArgs(0, 1)
 
 	return restartCmd
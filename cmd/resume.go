@@ -18,7 +18,7 @@ import (
 func newResumeCommand(client *client.Client) *Command {
 
 	resumeStrings := docstrings.Get("resume")
-	resumeCmd := BuildCommandKS(nil, runResume, resumeStrings, client, requireSession, requireAppNameAsArg)
+	resumeCmd := BuildCommandKS(nil, forEachConfirmedApp("Resume", runResume), resumeStrings, client, requireSession, requireAppNameAsArg, changesSelectedApps, mutating)
 	resumeCmd.Args = cobra.RangeArgs(0, 1)
 
 	return resumeCmd
@@ -10,6 +10,7 @@ import (
 	"github.com/logrusorgru/aurora"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	"github.com/superfly/flyctl/api"
 	"github.com/superfly/flyctl/docstrings"
 	"github.com/superfly/flyctl/flyctl"
 	"github.com/superfly/flyctl/internal/client"
@@ -18,6 +19,17 @@ import (
 // ErrAbort - Error generated when application aborts
 var ErrAbort = errors.New("abort")
 
+// ExitError ends a command with Code as its exit status after it has reported why itself, like a
+// check that found problems. Exit hooks still see it as a failure.
+type ExitError struct {
+	Code   int
+	Reason string
+}
+
+func (e *ExitError) Error() string {
+	return e.Reason
+}
+
 func NewRootCmd(client *client.Client) *cobra.Command {
 	rootStrings := docstrings.Get("flyctl")
 	rootCmd := &Command{
@@ -28,6 +40,7 @@ func NewRootCmd(client *client.Client) *cobra.Command {
 			PersistentPreRun: func(cmd *cobra.Command, args []string) {
 				cmd.SilenceUsage = true
 				cmd.SilenceErrors = true
+				api.SetReadOnly(viper.GetBool(flyctl.ConfigReadOnly))
 			},
 		},
 	}
@@ -44,6 +57,10 @@ func NewRootCmd(client *client.Client) *cobra.Command {
 	err = viper.BindPFlag(flyctl.ConfigJSONOutput, rootCmd.PersistentFlags().Lookup("json"))
 	checkErr(err)
 
+	rootCmd.PersistentFlags().Bool("read-only", false, "Block commands that make changes, for screensharing or recorded sessions. Also set with FLY_READ_ONLY=1")
+	err = viper.BindPFlag(flyctl.ConfigReadOnly, rootCmd.PersistentFlags().Lookup("read-only"))
+	checkErr(err)
+
 	rootCmd.PersistentFlags().String("builtinsfile", "", "Load builtins from named file")
 	err = viper.BindPFlag(flyctl.ConfigBuiltinsfile, rootCmd.PersistentFlags().Lookup("builtinsfile"))
 	checkErr(err)
@@ -54,15 +71,19 @@ func NewRootCmd(client *client.Client) *cobra.Command {
 	rootCmd.AddCommand(
 		newAppsCommand(client),
 		newAuthCommand(client),
+		newBuilderCommand(client),
 		newBuildsCommand(client),
 		newCurlCommand(client),
 		newCertificatesCommand(client),
 		newConfigCommand(client),
 		newDashboardCommand(client),
 		newDeployCommand(client),
+		newDeploysCommand(client),
 		newDestroyCommand(client),
 		newDocsCommand(client),
 		newHistoryCommand(client),
+		newHostnamesCommand(client),
+		newImageCommand(client),
 		newInfoCommand(client),
 		newInitCommand(client),
 		newIPAddressesCommand(client),
@@ -70,9 +91,11 @@ func NewRootCmd(client *client.Client) *cobra.Command {
 		newLogsCommand(client),
 		newMonitorCommand(client),
 		newMoveCommand(client),
+		newNewCommand(client),
 		newOpenCommand(client),
 		newPlatformCommand(client),
 		newRegionsCommand(client),
+		newRegistryCommand(client),
 		newReleasesCommand(client),
 		newRestartCommand(client),
 		newResumeCommand(client),
@@ -107,6 +130,11 @@ func checkErr(err error) {
 		return
 	}
 
+	if exitErr, ok := err.(*ExitError); ok {
+		flyctl.BackgroundTaskWG.Wait()
+		os.Exit(exitErr.Code)
+	}
+
 	if !isCancelledError(err) {
 		fmt.Println(aurora.Red("Error"), err)
 	}
@@ -20,7 +20,7 @@ func newScaleCommand(client *client.Client) *Command {
 	cmd := BuildCommandKS(nil, nil, scaleStrings, client, requireSession, requireAppName)
 
 	vmCmdStrings := docstrings.Get("scale.vm")
-	vmCmd := BuildCommand(cmd, runScaleVM, vmCmdStrings.Usage, vmCmdStrings.Short, vmCmdStrings.Long, client, requireSession, requireAppName)
+	vmCmd := BuildCommand(cmd, runScaleVM, vmCmdStrings.Usage, vmCmdStrings.Short, vmCmdStrings.Long, client, requireSession, requireAppName, mutating)
 	vmCmd.Args = cobra.ExactArgs(1)
 	vmCmd.AddIntFlag(IntFlagOpts{
 		Name:        "memory",
@@ -29,11 +29,11 @@ func newScaleCommand(client *client.Client) *Command {
 	})
 
 	memoryCmdStrings := docstrings.Get("scale.memory")
-	memoryCmd := BuildCommandKS(cmd, runScaleMemory, memoryCmdStrings, client, requireSession, requireAppName)
+	memoryCmd := BuildCommandKS(cmd, runScaleMemory, memoryCmdStrings, client, requireSession, requireAppName, mutating)
 	memoryCmd.Args = cobra.ExactArgs(1)
 
 	countCmdStrings := docstrings.Get("scale.count")
-	countCmd := BuildCommand(cmd, runScaleCount, countCmdStrings.Usage, countCmdStrings.Short, countCmdStrings.Long, client, requireSession, requireAppName)
+	countCmd := BuildCommand(cmd, runScaleCount, countCmdStrings.Usage, countCmdStrings.Short, countCmdStrings.Long, client, requireSession, requireAppName, mutating)
 	countCmd.Args = cobra.ExactArgs(1)
 
 	showCmdStrings := docstrings.Get("scale.show")
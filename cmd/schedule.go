@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/logrusorgru/aurora"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/cmdctx"
+	"github.com/superfly/flyctl/internal/cron"
+)
+
+func runAppsSchedule(ctx *cmdctx.CmdContext) error {
+	client := ctx.Client.API()
+
+	if ctx.Config.GetBool("clear") {
+		if err := client.DeleteSuspendSchedule(ctx.AppName); err != nil {
+			return err
+		}
+		fmt.Fprintf(ctx.Out, "Removed the suspend schedule from %s\n", ctx.AppName)
+		return nil
+	}
+
+	suspend, _ := ctx.Config.GetString("suspend")
+	resume, _ := ctx.Config.GetString("resume")
+	timezone, _ := ctx.Config.GetString("timezone")
+
+	if suspend == "" && resume == "" {
+		schedule, err := client.GetSuspendSchedule(ctx.AppName)
+		if err != nil {
+			return err
+		}
+		return printSuspendSchedule(ctx, schedule)
+	}
+
+	if suspend == "" {
+		return fmt.Errorf("--suspend is required with --resume, like --suspend \"0 20 * * 5\"")
+	}
+	if _, err := cron.Parse(suspend); err != nil {
+		return fmt.Errorf("--suspend: %s", err)
+	}
+	if resume != "" {
+		if _, err := cron.Parse(resume); err != nil {
+			return fmt.Errorf("--resume: %s", err)
+		}
+	}
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return fmt.Errorf("unknown timezone %s, use an IANA name like \"America/Chicago\"", timezone)
+	}
+
+	schedule, err := client.SetSuspendSchedule(api.SetSuspendScheduleInput{
+		AppID:    ctx.AppName,
+		Suspend:  suspend,
+		Resume:   resume,
+		Timezone: timezone,
+	})
+	if err != nil {
+		return err
+	}
+
+	if resume == "" && !ctx.OutputJSON() {
+		fmt.Fprintln(ctx.Out, aurora.Yellow("Without --resume the app stays suspended until it's resumed with flyctl apps resume"))
+	}
+
+	return printSuspendSchedule(ctx, schedule)
+}
+
+func printSuspendSchedule(ctx *cmdctx.CmdContext, schedule *api.SuspendSchedule) error {
+	if ctx.OutputJSON() {
+		ctx.WriteJSON(schedule)
+		return nil
+	}
+
+	if schedule == nil {
+		fmt.Fprintf(ctx.Out, "%s has no suspend schedule\n", ctx.AppName)
+		return nil
+	}
+
+	loc, err := time.LoadLocation(schedule.Timezone)
+	if err != nil {
+		loc = time.UTC
+	}
+	now := time.Now().In(loc)
+
+	fmt.Fprintln(ctx.Out, aurora.Bold("Suspend Schedule"))
+	for _, entry := range []struct{ name, spec string }{{"Suspend", schedule.Suspend}, {"Resume", schedule.Resume}} {
+		if entry.spec == "" {
+			continue
+		}
+		line := fmt.Sprintf("%-8s \"%s\" %s", entry.name, entry.spec, schedule.Timezone)
+		if s, err := cron.Parse(entry.spec); err == nil {
+			if next := s.Next(now); !next.IsZero() {
+				line += fmt.Sprintf(", next at %s", next.Format(time.RFC1123))
+			}
+		}
+		fmt.Fprintln(ctx.Out, line)
+	}
+
+	return nil
+}
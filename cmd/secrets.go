@@ -1,14 +1,25 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"sort"
 	"strings"
 
+	"filippo.io/age"
+	"filippo.io/age/armor"
+	"github.com/logrusorgru/aurora"
+	"github.com/superfly/flyctl/api"
 	"github.com/superfly/flyctl/cmdctx"
+	"github.com/superfly/flyctl/flyctl"
 	"github.com/superfly/flyctl/internal/client"
+	"github.com/superfly/flyctl/internal/dotenv"
+	"github.com/superfly/flyctl/internal/secretgen"
+	"github.com/superfly/flyctl/internal/secretsync"
 
 	"github.com/superfly/flyctl/docstrings"
 
@@ -26,34 +37,150 @@ func newSecretsCommand(client *client.Client) *Command {
 	BuildCommandKS(cmd, runListSecrets, secretsListStrings, client, requireSession, requireAppName)
 
 	secretsSetStrings := docstrings.Get("secrets.set")
-	set := BuildCommandKS(cmd, runSetSecrets, secretsSetStrings, client, requireSession, requireAppName)
+	set := BuildCommandKS(cmd, runSetSecrets, secretsSetStrings, client, requireSession, requireAppName, mutating)
 
 	//TODO: Move examples into docstrings
 	set.Command.Example = `flyctl secrets set FLY_ENV=production LOG_LEVEL=info
 	echo "long text..." | flyctl secrets set LONG_TEXT=-
 	flyctl secrets set FROM_A_FILE=- < file.txt
+	flyctl secrets set TLS_KEY=@certs/server.key GOOGLE_CREDENTIALS=@service-account.json
+	flyctl secrets set --stdin SIGNING_KEY < signing.pem
+	flyctl secrets set DATABASE_URL=postgres://... --encrypt-with age1...
+	flyctl secrets set --process worker AWS_ACCESS_KEY_ID=... AWS_SECRET_ACCESS_KEY=...
 	`
 	set.Command.Args = cobra.MinimumNArgs(1)
 	set.AddBoolFlag(BoolFlagOpts{
 		Name:        "detach",
 		Description: "Return immediately instead of monitoring deployment progress",
 	})
+	addDeployLockFlags(set)
+	set.AddBoolFlag(BoolFlagOpts{
+		Name:        "stage",
+		Description: "Record the change without restarting the app, it's released by the next deploy or secrets deploy",
+	})
+	set.AddStringSliceFlag(StringSliceFlagOpts{
+		Name:        "process",
+		Description: "Only inject the secrets into instances of these process groups",
+	})
+	set.AddBoolFlag(BoolFlagOpts{
+		Name:        "stdin",
+		Description: "Read the value of a single secret, given by NAME, from standard input",
+	})
+	set.AddStringSliceFlag(StringSliceFlagOpts{
+		Name:        "encrypt-with",
+		Description: "Encrypt values locally for these age recipients (age1...) before sending them",
+		EnvName:     "FLY_SECRETS_ENCRYPT_WITH",
+	})
 
 	secretsImportStrings := docstrings.Get("secrets.import")
-	importCmd := BuildCommandKS(cmd, runImportSecrets, secretsImportStrings, client, requireSession, requireAppName)
+	importCmd := BuildCommandKS(cmd, runImportSecrets, secretsImportStrings, client, requireSession, requireAppName, mutating)
+	importCmd.Command.Example = `flyctl secrets import < .env
+	flyctl secrets import --from-dotenv .env.production
+	`
+	importCmd.AddBoolFlag(BoolFlagOpts{
+		Name:        "stage",
+		Description: "Record the change without restarting the app, it's released by the next deploy or secrets deploy",
+	})
+	importCmd.AddStringFlag(StringFlagOpts{
+		Name:        "from-dotenv",
+		Description: "Read secrets from this .env file instead of standard input",
+	})
 	importCmd.AddBoolFlag(BoolFlagOpts{
 		Name:        "detach",
 		Description: "Return immediately instead of monitoring deployment progress",
 	})
+	addDeployLockFlags(importCmd)
 
 	secretsUnsetStrings := docstrings.Get("secrets.unset")
-	unset := BuildCommandKS(cmd, runSecretsUnset, secretsUnsetStrings, client, requireSession, requireAppName)
+	unset := BuildCommandKS(cmd, runSecretsUnset, secretsUnsetStrings, client, requireSession, requireAppName, mutating)
 	unset.Command.Args = cobra.MinimumNArgs(1)
 
 	unset.AddBoolFlag(BoolFlagOpts{
 		Name:        "detach",
 		Description: "Return immediately instead of monitoring deployment progress",
 	})
+	addDeployLockFlags(unset)
+	unset.AddBoolFlag(BoolFlagOpts{
+		Name:        "stage",
+		Description: "Record the change without restarting the app, it's released by the next deploy or secrets deploy",
+	})
+
+	secretsSyncStrings := docstrings.Get("secrets.sync")
+	syncCmd := BuildCommandKS(cmd, runSyncSecrets, secretsSyncStrings, client, requireSession, requireAppName, mutating)
+	syncCmd.Command.Example = `flyctl secrets sync --provider aws --source myapp/production/
+	flyctl secrets sync --provider gcp --source myapp_
+	flyctl secrets sync --provider vault --source secret/myapp/
+	`
+	syncCmd.AddStringFlag(StringFlagOpts{
+		Name:        "provider",
+		Description: "Secret manager to read from: " + strings.Join(secretsync.Providers, ", "),
+	})
+	syncCmd.AddStringFlag(StringFlagOpts{
+		Name:        "source",
+		Description: "Name prefix, or Vault path, of the secrets to sync",
+	})
+	syncCmd.AddBoolFlag(BoolFlagOpts{
+		Name:        "dry-run",
+		Description: "List the secrets that would be set without setting them",
+	})
+	syncCmd.AddBoolFlag(BoolFlagOpts{
+		Name:        "stage",
+		Description: "Record the change without restarting the app, it's released by the next deploy or secrets deploy",
+	})
+	syncCmd.AddBoolFlag(BoolFlagOpts{
+		Name:        "detach",
+		Description: "Return immediately instead of monitoring deployment progress",
+	})
+	addDeployLockFlags(syncCmd)
+
+	secretsDeployStrings := docstrings.Get("secrets.deploy")
+	deployCmd := BuildCommandKS(cmd, runDeploySecrets, secretsDeployStrings, client, requireSession, requireAppName, mutating)
+	deployCmd.AddBoolFlag(BoolFlagOpts{
+		Name:        "detach",
+		Description: "Return immediately instead of monitoring deployment progress",
+	})
+	addDeployLockFlags(deployCmd)
+
+	secretsGenerateStrings := docstrings.Get("secrets.generate")
+	generate := BuildCommandKS(cmd, runGenerateSecrets, secretsGenerateStrings, client, requireSession, requireAppName, mutating)
+	generate.Command.Example = `flyctl secrets generate SECRET_KEY_BASE --length 64 --format hex
+	flyctl secrets generate SESSION_SECRET CSRF_SECRET --format alphanumeric`
+	generate.Args = cobra.MinimumNArgs(1)
+	generate.AddIntFlag(IntFlagOpts{
+		Name:        "length",
+		Description: "Number of characters in each value",
+		Default:     64,
+	})
+	generate.AddStringFlag(StringFlagOpts{
+		Name:        "format",
+		Description: "Encoding of the values: " + strings.Join(secretgen.Formats, ", "),
+		Default:     "hex",
+	})
+	generate.AddBoolFlag(BoolFlagOpts{
+		Name:        "force",
+		Description: "Replace secrets that are already set",
+	})
+	generate.AddStringSliceFlag(StringSliceFlagOpts{
+		Name:        "process",
+		Description: "Only inject the secrets into instances of these process groups",
+	})
+	generate.AddBoolFlag(BoolFlagOpts{
+		Name:        "stage",
+		Description: "Record the change without restarting the app, it's released by the next deploy or secrets deploy",
+	})
+	generate.AddBoolFlag(BoolFlagOpts{
+		Name:        "detach",
+		Description: "Return immediately instead of monitoring deployment progress",
+	})
+	addDeployLockFlags(generate)
+
+	secretsDiffStrings := docstrings.Get("secrets.diff")
+	diffCmd := BuildCommandKS(cmd, runDiffSecrets, secretsDiffStrings, client, requireSession, requireAppName)
+	diffCmd.Args = cobra.ExactArgs(1)
+	diffCmd.AddBoolFlag(BoolFlagOpts{
+		Name:        "exit-code",
+		Description: "Exit with status 1 when the file and the app differ",
+	})
 
 	return cmd
 }
@@ -68,41 +195,88 @@ func runListSecrets(ctx *cmdctx.CmdContext) error {
 }
 
 func runSetSecrets(cc *cmdctx.CmdContext) error {
-	ctx := createCancellableContext()
-
-	app, err := cc.Client.API().GetApp(cc.AppName)
-	if err != nil {
-		return err
-	}
-
 	secrets := make(map[string]string)
 
-	for _, pair := range cc.Args {
-		parts := strings.SplitN(pair, "=", 2)
-		if len(parts) != 2 {
-			return fmt.Errorf("Secrets must be provided as NAME=VALUE pairs (%s is invalid)", pair)
+	if cc.Config.GetBool("stdin") {
+		if len(cc.Args) != 1 || strings.Contains(cc.Args[0], "=") {
+			return errors.New("--stdin sets a single secret, give only its NAME")
+		}
+		value, err := secretValue(cc.Args[0], "-")
+		if err != nil {
+			return err
 		}
-		key := parts[0]
-		value := parts[1]
-		if value == "-" {
-			if !helpers.HasPipedStdin() {
-				return fmt.Errorf("Secret `%s` expects standard input but none provided", parts[0])
+		secrets[cc.Args[0]] = value
+	} else {
+		for _, pair := range cc.Args {
+			parts := strings.SplitN(pair, "=", 2)
+			if len(parts) != 2 {
+				return fmt.Errorf("Secrets must be provided as NAME=VALUE pairs (%s is invalid)", pair)
 			}
-			inval, err := helpers.ReadStdin(4 * 1024)
+			key := parts[0]
+			value, err := secretValue(key, parts[1])
 			if err != nil {
-				return fmt.Errorf("Error reading stdin for '%s': %s", parts[0], err)
+				return err
 			}
-			value = inval
-		}
 
-		secrets[key] = value
+			secrets[key] = value
+		}
 	}
 
 	if len(secrets) < 1 {
 		return errors.New("requires at least one SECRET=VALUE pair")
 	}
 
-	release, err := cc.Client.API().SetSecrets(cc.AppName, secrets)
+	if recipients := cc.Config.GetStringSlice("encrypt-with"); len(recipients) > 0 {
+		if err := encryptSecrets(secrets, recipients); err != nil {
+			return err
+		}
+		cc.Statusf("secrets", cmdctx.SINFO, "Encrypted %d secrets for %d recipients\n", len(secrets), len(recipients))
+	}
+
+	processGroups := cc.Config.GetStringSlice("process")
+	if err := checkSecretProcessGroups(cc, processGroups); err != nil {
+		return err
+	}
+
+	return applySecrets(createCancellableContext(), cc, processGroups, secrets, nil)
+}
+
+// applySecrets sets secrets for processGroups, every group when there are none, and removes the
+// unset names. With --stage the change waits for the next deploy, otherwise its release is made
+// and watched holding the deploy lock.
+func applySecrets(ctx context.Context, cc *cmdctx.CmdContext, processGroups []string, secrets map[string]string, unset []string) error {
+	app, err := cc.Client.API().GetApp(cc.AppName)
+	if err != nil {
+		return err
+	}
+
+	if cc.Config.GetBool("stage") {
+		if len(unset) > 0 {
+			if err := cc.Client.API().StageUnsetSecrets(cc.AppName, unset); err != nil {
+				return err
+			}
+			cc.Statusf("secrets", cmdctx.SINFO, "Staged removing %d secrets, deploy the change with flyctl deploy or flyctl secrets deploy\n", len(unset))
+			return nil
+		}
+		if err := cc.Client.API().StageProcessSecrets(cc.AppName, processGroups, secrets); err != nil {
+			return err
+		}
+		cc.Statusf("secrets", cmdctx.SINFO, "Staged %d secrets, deploy them with flyctl deploy or flyctl secrets deploy\n", len(secrets))
+		return nil
+	}
+
+	ctx, releaseLock, err := holdDeployLock(ctx, cc)
+	if err != nil {
+		return err
+	}
+	defer releaseLock()
+
+	var release *api.Release
+	if len(unset) > 0 {
+		release, err = cc.Client.API().UnsetSecrets(cc.AppName, unset)
+	} else {
+		release, err = cc.Client.API().SetProcessSecrets(cc.AppName, processGroups, secrets)
+	}
 	if err != nil {
 		return err
 	}
@@ -112,12 +286,161 @@ func runSetSecrets(cc *cmdctx.CmdContext) error {
 		return nil
 	}
 
+	return watchSecretsRelease(ctx, cc, release)
+}
+
+// watchSecretsRelease follows the deployment of a release made by changing secrets
+func watchSecretsRelease(ctx context.Context, cc *cmdctx.CmdContext, release *api.Release) error {
 	cc.Statusf("secrets", cmdctx.SINFO, "Release v%d created\n", release.Version)
 
 	return watchDeployment(ctx, cc)
 }
 
+// checkSecretProcessGroups makes sure --process names groups from the [processes] section of
+// fly.toml, when there's one to check against
+func checkSecretProcessGroups(cc *cmdctx.CmdContext, processGroups []string) error {
+	if len(processGroups) == 0 || cc.AppConfig == nil {
+		return nil
+	}
+	groups, _ := cc.AppConfig.ProcessGroups()
+	if len(groups) == 0 {
+		return nil
+	}
+
+	known := make(map[string]bool, len(groups))
+	names := make([]string, 0, len(groups))
+	for _, g := range groups {
+		known[g.Name] = true
+		names = append(names, g.Name)
+	}
+	for _, name := range processGroups {
+		if !known[name] {
+			return fmt.Errorf("fly.toml has no %s process group, it has %s", name, strings.Join(names, ", "))
+		}
+	}
+	return nil
+}
+
+// maxSecretSize is the most read for a secret from standard input or a file
+const maxSecretSize = 64 * 1024
+
+// secretValue resolves a value from the command line: - reads standard input, @path reads a file
+// without its trailing newline, and @@ escapes a value that starts with @
+func secretValue(name string, value string) (string, error) {
+	switch {
+	case value == "-":
+		if !helpers.HasPipedStdin() {
+			return "", fmt.Errorf("Secret `%s` expects standard input but none provided", name)
+		}
+		inval, err := helpers.ReadStdin(maxSecretSize)
+		if err != nil {
+			return "", fmt.Errorf("Error reading stdin for '%s': %s", name, err)
+		}
+		return inval, nil
+	case strings.HasPrefix(value, "@@"):
+		return value[1:], nil
+	case strings.HasPrefix(value, "@"):
+		path := value[1:]
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", fmt.Errorf("Error reading file for '%s': %s", name, err)
+		}
+		if info.Size() > maxSecretSize {
+			return "", fmt.Errorf("File %s for '%s' is larger than %d bytes", path, name, maxSecretSize)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("Error reading file for '%s': %s", name, err)
+		}
+		return strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r"), nil
+	default:
+		return value, nil
+	}
+}
+
+// encryptSecrets replaces each value with an ASCII armored age ciphertext, so the plaintext
+// never leaves this machine and only holders of a matching identity can read it
+func encryptSecrets(secrets map[string]string, recipientKeys []string) error {
+	var recipients []age.Recipient
+	for _, key := range recipientKeys {
+		// bech32 keys are case insensitive, but the age package only takes them lowercased
+		r, err := age.ParseX25519Recipient(strings.ToLower(strings.TrimSpace(key)))
+		if err != nil {
+			return fmt.Errorf("invalid age recipient \"%s\": %s", key, err)
+		}
+		recipients = append(recipients, r)
+	}
+
+	for name, value := range secrets {
+		encrypted, err := encryptArmored([]byte(value), recipients)
+		if err != nil {
+			return fmt.Errorf("Error encrypting '%s': %s", name, err)
+		}
+		secrets[name] = encrypted
+	}
+
+	return nil
+}
+
+// encryptArmored encrypts plaintext in the PEM style armor that `age --decrypt` accepts, which is
+// safe to store as a secret value
+func encryptArmored(plaintext []byte, recipients []age.Recipient) (string, error) {
+	var out bytes.Buffer
+	armored := armor.NewWriter(&out)
+
+	w, err := age.Encrypt(armored, recipients...)
+	if err != nil {
+		return "", err
+	}
+	if _, err := w.Write(plaintext); err != nil {
+		return "", err
+	}
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+	if err := armored.Close(); err != nil {
+		return "", err
+	}
+
+	return out.String(), nil
+}
+
 func runImportSecrets(cc *cmdctx.CmdContext) error {
+	var data []byte
+	var err error
+	if path, _ := cc.Config.GetString("from-dotenv"); path != "" {
+		data, err = ioutil.ReadFile(path)
+	} else if helpers.HasPipedStdin() {
+		data, err = ioutil.ReadAll(os.Stdin)
+	} else {
+		return errors.New("pipe a .env file to standard input or use --from-dotenv")
+	}
+	if err != nil {
+		return err
+	}
+
+	secrets, names, err := dotenv.Parse(string(data))
+	if err != nil {
+		return err
+	}
+
+	if len(secrets) < 1 {
+		return errors.New("requires at least one SECRET=VALUE pair")
+	}
+	cc.Statusf("secrets", cmdctx.SINFO, "Importing %d secrets: %s\n", len(names), strings.Join(names, ", "))
+
+	return applySecrets(createCancellableContext(), cc, nil, secrets, nil)
+}
+
+func runSecretsUnset(cc *cmdctx.CmdContext) error {
+	if len(cc.Args) == 0 {
+		return errors.New("Requires at least one secret name")
+	}
+
+	return applySecrets(createCancellableContext(), cc, nil, nil, cc.Args)
+}
+
+func runDeploySecrets(cc *cmdctx.CmdContext) error {
 	ctx := createCancellableContext()
 
 	app, err := cc.Client.API().GetApp(cc.AppName)
@@ -125,102 +448,175 @@ func runImportSecrets(cc *cmdctx.CmdContext) error {
 		return err
 	}
 
-	secrets := make(map[string]string)
+	if !app.Deployed {
+		cc.Statusf("secrets", cmdctx.SINFO, "Staged secrets will be used by the first deployment\n")
+		return nil
+	}
 
-	secretsString, err := ioutil.ReadAll(os.Stdin)
+	ctx, releaseLock, err := holdDeployLock(ctx, cc)
+	if err != nil {
+		return err
+	}
+	defer releaseLock()
 
+	release, err := cc.Client.API().DeploySecrets(cc.AppName)
 	if err != nil {
 		return err
 	}
 
-	secretsArray := strings.Split(string(secretsString), "\n")
-
-	parsestate := 0
-	parsedkey := ""
-	var parsebuffer strings.Builder
-
-	for _, line := range secretsArray {
-		switch parsestate {
-		case 0:
-			if line != "" {
-				parts := strings.SplitN(line, "=", 2)
-				if strings.HasPrefix(parts[1], "\"\"\"") {
-					// Switch to multiline
-					parsestate = 1
-					parsedkey = parts[0]
-					parsebuffer.WriteString(strings.TrimPrefix(parts[1], "\"\"\""))
-					parsebuffer.WriteString("\n")
-				} else {
-					if len(parts) != 2 {
-						return fmt.Errorf("Secrets must be provided as NAME=VALUE pairs (%s is invalid)", line)
-					}
-					key := parts[0]
-					value := parts[1]
-					secrets[key] = value
-				}
-			}
-		case 1:
-			if strings.HasSuffix(line, "\"\"\"") {
-				// End of multiline
-				parsebuffer.WriteString(strings.TrimSuffix(line, "\"\"\""))
-				secrets[parsedkey] = parsebuffer.String()
-				parsebuffer.Reset()
-				parsestate = 0
-				parsedkey = ""
-			} else {
-				if line != "" {
-					parsebuffer.WriteString(line)
-				}
-				parsebuffer.WriteString("\n")
-			}
+	return watchSecretsRelease(ctx, cc, release)
+}
 
-		}
+func runSyncSecrets(cc *cmdctx.CmdContext) error {
+	ctx := createCancellableContext()
 
+	providerName, _ := cc.Config.GetString("provider")
+	source, _ := cc.Config.GetString("source")
+	if providerName == "" || source == "" {
+		return errors.New("--provider and --source are required")
 	}
 
-	if len(secrets) < 1 {
-		return errors.New("requires at least one SECRET=VALUE pair")
+	provider, err := secretsync.NewProvider(providerName)
+	if err != nil {
+		return err
 	}
-	fmt.Println(secrets)
 
-	release, err := cc.Client.API().SetSecrets(cc.AppName, secrets)
+	secrets, err := provider.Fetch(ctx, source)
 	if err != nil {
 		return err
 	}
+	if len(secrets) == 0 {
+		return fmt.Errorf("no secrets found in %s under %s", providerName, source)
+	}
 
-	if !app.Deployed {
-		cc.Statusf("secrets", cmdctx.SINFO, "Secrets are staged for the first deployment\n")
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if cc.Config.GetBool("dry-run") {
+		fmt.Fprintf(cc.Out, "Would set %d secrets from %s %s:\n", len(names), providerName, source)
+		for _, name := range names {
+			fmt.Fprintf(cc.Out, "  %s\n", name)
+		}
 		return nil
 	}
+	cc.Statusf("secrets", cmdctx.SINFO, "Syncing %d secrets from %s %s: %s\n", len(names), providerName, source, strings.Join(names, ", "))
 
-	cc.Statusf("secrets", cmdctx.SINFO, "Release v%d created\n", release.Version)
+	return applySecrets(ctx, cc, nil, secrets, nil)
+}
 
-	return watchDeployment(ctx, cc)
+// runGenerateSecrets sets each named secret to a random value that's never shown
+func runGenerateSecrets(cc *cmdctx.CmdContext) error {
+	length := cc.Config.GetInt("length")
+	format, _ := cc.Config.GetString("format")
+
+	if !cc.Config.GetBool("force") {
+		existing, err := cc.Client.API().GetAppSecrets(cc.AppName)
+		if err != nil {
+			return err
+		}
+		for _, secret := range existing {
+			for _, name := range cc.Args {
+				if secret.Name == name {
+					return fmt.Errorf("%s is already set, use --force to replace it with a new value", name)
+				}
+			}
+		}
+	}
+
+	secrets := make(map[string]string, len(cc.Args))
+	for _, name := range cc.Args {
+		value, err := secretgen.Generate(length, format)
+		if err != nil {
+			return err
+		}
+		secrets[name] = value
+	}
+
+	processGroups := cc.Config.GetStringSlice("process")
+	if err := checkSecretProcessGroups(cc, processGroups); err != nil {
+		return err
+	}
+	cc.Statusf("secrets", cmdctx.SINFO, "Generated %d character %s values for %s\n", length, format, strings.Join(cc.Args, ", "))
+
+	return applySecrets(createCancellableContext(), cc, processGroups, secrets, nil)
 }
 
-func runSecretsUnset(cc *cmdctx.CmdContext) error {
-	ctx := createCancellableContext()
+type secretDiff struct {
+	Name   string
+	Status string
+}
 
-	app, err := cc.Client.API().GetApp(cc.AppName)
+// runDiffSecrets compares a dotenv file with the app's secrets by digest, so no values
+// are sent or printed
+func runDiffSecrets(cc *cmdctx.CmdContext) error {
+	data, err := ioutil.ReadFile(cc.Args[0])
 	if err != nil {
 		return err
 	}
-
-	if len(cc.Args) == 0 {
-		return errors.New("Requires at least one secret name")
+	values, _, err := dotenv.Parse(string(data))
+	if err != nil {
+		return err
 	}
 
-	release, err := cc.Client.API().UnsetSecrets(cc.AppName, cc.Args)
+	appSecrets, err := cc.Client.API().GetAppSecrets(cc.AppName)
 	if err != nil {
 		return err
 	}
 
-	if !app.Deployed {
-		cc.Statusf("secrets", cmdctx.SINFO, "Secrets are staged for the first deployment\n")
-		return nil
+	local := make([]api.Secret, 0, len(values))
+	for name, value := range values {
+		local = append(local, api.Secret{Name: name, Digest: flyctl.SecretDigest(value)})
 	}
 
-	cc.Statusf("secrets", cmdctx.SINFO, "Release v%d created\n", release.Version)
+	changes := flyctl.DiffSecrets(appSecrets, local)
+	diffs := make([]secretDiff, 0, len(changes))
+	for _, change := range changes {
+		status := "different"
+		switch change.Kind {
+		case flyctl.ChangeAdded:
+			status = "only-local"
+		case flyctl.ChangeRemoved:
+			status = "only-app"
+		}
+		diffs = append(diffs, secretDiff{Name: change.Name, Status: status})
+	}
 
-	return watchDeployment(ctx, cc)
+	if cc.OutputJSON() {
+		cc.WriteJSON(diffs)
+	} else if len(diffs) == 0 {
+		fmt.Fprintf(cc.Out, "%s matches the secrets of %s\n", cc.Args[0], cc.AppName)
+	} else {
+		groups := []struct {
+			status string
+			title  string
+			color  func(interface{}) aurora.Value
+		}{
+			{"only-local", "Only in " + cc.Args[0], aurora.Green},
+			{"only-app", "Only on " + cc.AppName, aurora.Red},
+			{"different", "Different values", aurora.Yellow},
+		}
+		for _, group := range groups {
+			var names []string
+			for _, diff := range diffs {
+				if diff.Status == group.status {
+					names = append(names, diff.Name)
+				}
+			}
+			if len(names) == 0 {
+				continue
+			}
+			fmt.Fprintln(cc.Out, aurora.Bold(group.title))
+			for _, name := range names {
+				fmt.Fprintf(cc.Out, "  %s\n", group.color(name))
+			}
+		}
+	}
+
+	if len(diffs) > 0 && cc.Config.GetBool("exit-code") {
+		return &ExitError{Code: 1, Reason: fmt.Sprintf("%d secrets differ", len(diffs))}
+	}
+	return nil
 }
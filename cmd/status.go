@@ -20,7 +20,7 @@ import (
 
 func newStatusCommand(client *client.Client) *Command {
 	statusStrings := docstrings.Get("status")
-	cmd := BuildCommandKS(nil, runStatus, statusStrings, client, requireSession, requireAppNameAsArg)
+	cmd := BuildCommandKS(nil, forEachSelectedApp(runStatus), statusStrings, client, requireSession, requireAppNameAsArg, selectsApps)
 
 	//TODO: Move flag descriptions to docstrings
 	cmd.AddBoolFlag(BoolFlagOpts{Name: "all", Description: "Show completed instances"})
@@ -47,6 +47,9 @@ func runStatus(ctx *cmdctx.CmdContext) error {
 	if watch && ctx.OutputJSON() {
 		return fmt.Errorf("--watch and --json are not supported together")
 	}
+	if watch && ctx.Selector != "" {
+		return fmt.Errorf("--watch and --selector are not supported together")
+	}
 
 	for {
 		var app *api.AppStatus
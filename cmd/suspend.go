@@ -19,7 +19,7 @@ func newSuspendCommand(client *client.Client) *Command {
 
 	suspendStrings := docstrings.Get("suspend")
 
-	suspendCmd := BuildCommandKS(nil, runSuspend, suspendStrings, client, requireSession, requireAppNameAsArg)
+	suspendCmd := BuildCommandKS(nil, forEachConfirmedApp("Suspend", runSuspend), suspendStrings, client, requireSession, requireAppNameAsArg, changesSelectedApps, mutating)
 	suspendCmd.Args = cobra.RangeArgs(0, 1)
 
 	return suspendCmd
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/cmd/presenters"
+	"github.com/superfly/flyctl/cmdctx"
+)
+
+func runAppsUsage(ctx *cmdctx.CmdContext) error {
+	client := ctx.Client.API()
+
+	var appNames []string
+	if len(ctx.Args) > 0 {
+		appNames = ctx.Args
+	} else {
+		apps, err := client.GetApps(nil)
+		if err != nil {
+			return err
+		}
+		var filter appFilter
+		filter.Org, _ = ctx.Config.GetString("org")
+		for _, app := range filterApps(apps, filter) {
+			appNames = append(appNames, app.Name)
+		}
+		sort.Strings(appNames)
+	}
+
+	usage := make([]api.AppUsage, 0, len(appNames))
+	total := 0
+	for _, name := range appNames {
+		u, err := client.GetAppUsage(name)
+		if err != nil {
+			return err
+		}
+		usage = append(usage, *u)
+		total += u.TotalCostCents
+	}
+
+	if ctx.OutputJSON() {
+		ctx.WriteJSON(usage)
+		return nil
+	}
+
+	if len(usage) == 0 {
+		fmt.Fprintln(ctx.Out, "No apps found")
+		return nil
+	}
+
+	fmt.Fprintf(ctx.Out, "Usage from %s to %s\n", usage[0].PeriodStart.Format("Jan 2"), usage[0].PeriodEnd.Format("Jan 2, 2006"))
+	if err := ctx.Render(&presenters.AppUsage{Usage: usage}); err != nil {
+		return err
+	}
+	if len(usage) > 1 {
+		fmt.Fprintf(ctx.Out, "\nEstimated total: %s\n", presenters.FormatCents(total))
+	}
+
+	return nil
+}
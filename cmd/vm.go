@@ -12,10 +12,10 @@ import (
 func newVMCommand(client *client.Client) *Command {
 	vmCmd := BuildCommandKS(nil, nil, docstrings.Get("vm"), client)
 
-	vmRestartCmd := BuildCommandKS(vmCmd, runVMRestart, docstrings.Get("vm.restart"), client, requireSession, requireAppName)
+	vmRestartCmd := BuildCommandKS(vmCmd, runVMRestart, docstrings.Get("vm.restart"), client, requireSession, requireAppName, mutating)
 	vmRestartCmd.Args = cobra.ExactArgs(1)
 
-	vmStopCmd := BuildCommandKS(vmCmd, runVMStop, docstrings.Get("vm.stop"), client, requireSession, requireAppName)
+	vmStopCmd := BuildCommandKS(vmCmd, runVMStop, docstrings.Get("vm.stop"), client, requireSession, requireAppName, mutating)
 	vmStopCmd.Args = cobra.ExactArgs(1)
 
 	vmStatusCmd := BuildCommandKS(vmCmd, runAllocStatus, docstrings.Get("vm.status"), client, requireSession, requireAppName)
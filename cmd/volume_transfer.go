@@ -0,0 +1,323 @@
+package cmd
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dustin/go-humanize"
+	"github.com/pkg/errors"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/cmdctx"
+	"github.com/superfly/flyctl/internal/awsauth"
+	"github.com/superfly/flyctl/terminal"
+)
+
+// volumeTransferURLExpiry is how long the temporary VM has to start reading or writing the archive,
+// unless the AWS credentials expire sooner
+const volumeTransferURLExpiry = 12 * time.Hour
+
+const (
+	// minArchivePartSize is the smallest part an export uploads, S3 needs at least 5MB
+	minArchivePartSize = 64 << 20
+	// maxArchiveParts is the most parts S3 takes in a multipart upload
+	maxArchiveParts = 10000
+)
+
+func runExportVolume(cmdCtx *cmdctx.CmdContext) error {
+	ctx := createCancellableContext()
+	volID := cmdCtx.Args[0]
+
+	volume, err := cmdCtx.Client.API().GetVolume(volID)
+	if err != nil {
+		return err
+	}
+
+	to, _ := cmdCtx.Config.GetString("to")
+	storage, presign, err := volumeArchiveStorage(ctx, cmdCtx, "--to", to, fmt.Sprintf("%s-%s.tar.gz", volID, time.Now().UTC().Format("20060102T150405Z")))
+	if err != nil {
+		return err
+	}
+
+	if storage.Multipart, err = startArchiveUpload(ctx, presign, volume.SizeGb); err != nil {
+		return err
+	}
+
+	transfer, err := cmdCtx.Client.API().ExportVolume(api.ExportVolumeInput{VolumeID: volID, Storage: storage})
+	if err != nil {
+		// nothing will upload to it, so don't leave the upload behind
+		if abortErr := abortArchiveUpload(ctx, storage.Multipart); abortErr != nil {
+			terminal.Warnf("Could not abort the upload to %s: %v\n", storage.URL, abortErr)
+		}
+		return errors.WithMessage(err, "Failed to start the export")
+	}
+	cmdCtx.Statusf("volumes", cmdctx.SBEGIN, "Exporting %s to %s\n", volID, storage.URL)
+
+	return watchVolumeTransfer(ctx, cmdCtx, transfer)
+}
+
+func runImportVolume(cmdCtx *cmdctx.CmdContext) error {
+	ctx := createCancellableContext()
+	volName := cmdCtx.Args[0]
+
+	region, _ := cmdCtx.Config.GetString("region")
+	if region == "" {
+		return fmt.Errorf("--region <region> flag required")
+	}
+
+	from, _ := cmdCtx.Config.GetString("from")
+	storage, presign, err := volumeArchiveStorage(ctx, cmdCtx, "--from", from, "")
+	if err != nil {
+		return err
+	}
+	if storage.SignedURL, err = presign(http.MethodGet, nil); err != nil {
+		return errors.Wrap(err, "error signing the archive's URL")
+	}
+
+	app, err := cmdCtx.Client.API().GetApp(cmdCtx.AppName)
+	if err != nil {
+		return err
+	}
+
+	transfer, err := cmdCtx.Client.API().ImportVolume(api.ImportVolumeInput{
+		AppID:   app.ID,
+		Name:    volName,
+		Region:  region,
+		SizeGb:  cmdCtx.Config.GetInt("size"),
+		Storage: storage,
+	})
+	if err != nil {
+		return errors.WithMessage(err, "Failed to start the import")
+	}
+	cmdCtx.Statusf("volumes", cmdctx.SBEGIN, "Importing %s into a new %s volume in %s\n", storage.URL, volName, region)
+
+	return watchVolumeTransfer(ctx, cmdCtx, transfer)
+}
+
+// archivePresigner presigns a request for the archive's object with query added to its URL
+type archivePresigner func(method string, query url.Values) (string, error)
+
+// volumeArchiveStorage resolves an s3://bucket/key URL, adding name to keys ending in / when it's
+// given. The AWS credentials in the environment presign the archive's requests locally, and the
+// temporary VM only gets those URLs.
+func volumeArchiveStorage(ctx context.Context, cmdCtx *cmdctx.CmdContext, flag string, rawURL string, name string) (api.ObjectStorageInput, archivePresigner, error) {
+	var storage api.ObjectStorageInput
+
+	if rawURL == "" {
+		return storage, nil, fmt.Errorf("%s s3://<bucket>/<path> flag required", flag)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme != "s3" || u.Host == "" {
+		return storage, nil, fmt.Errorf("%s must be an s3://<bucket>/<path> URL, got %s", flag, rawURL)
+	}
+	bucket, key := u.Host, strings.TrimPrefix(u.Path, "/")
+	if key == "" || strings.HasSuffix(key, "/") {
+		if name == "" {
+			return storage, nil, fmt.Errorf("%s must name an archive, like s3://%s/%sbackup.tar.gz", flag, bucket, key)
+		}
+		key += name
+	}
+	storage.URL = fmt.Sprintf("s3://%s/%s", bucket, key)
+
+	// the credentials sign URLs for the transfer locally and aren't sent to the VM
+	creds, err := awsauth.LoadCredentials(ctx)
+	if err != nil {
+		return storage, nil, errors.Wrap(err, "error loading credentials for the bucket")
+	}
+	now := time.Now()
+	expiry, err := transferURLExpiry(creds, now)
+	if err != nil {
+		return storage, nil, err
+	}
+	if expiry < volumeTransferURLExpiry {
+		terminal.Warnf("The AWS credentials expire at %s, the transfer has to finish by then\n", creds.Expires.Local().Format(time.Kitchen))
+	}
+
+	region := awsauth.LoadRegion(ctx)
+	if region == "" {
+		region = "us-east-1"
+	}
+	endpoint, _ := cmdCtx.Config.GetString("endpoint")
+	object := objectURL(endpoint, region, bucket, key)
+
+	presign := func(method string, query url.Values) (string, error) {
+		rawURL := object
+		if len(query) > 0 {
+			rawURL += "?" + query.Encode()
+		}
+		return awsauth.Presign(method, rawURL, creds, region, "s3", expiry, now)
+	}
+	return storage, presign, nil
+}
+
+// transferURLExpiry is how long the transfer's presigned URLs last, volumeTransferURLExpiry or
+// until creds expire when that's sooner, since the URLs stop working with them
+func transferURLExpiry(creds awsauth.Credentials, now time.Time) (time.Duration, error) {
+	if creds.Expires.IsZero() {
+		return volumeTransferURLExpiry, nil
+	}
+	left := creds.Expires.Sub(now).Truncate(time.Second)
+	if left < time.Minute {
+		return 0, errors.New("the AWS credentials have expired, refresh them and try again")
+	}
+	if left < volumeTransferURLExpiry {
+		return left, nil
+	}
+	return volumeTransferURLExpiry, nil
+}
+
+// archiveParts returns the part size and number of parts an export of a volume of sizeGb gets,
+// with room for an archive a little larger than the volume, which gzip makes of data that doesn't
+// compress
+func archiveParts(sizeGb int) (int64, int) {
+	if sizeGb < 1 {
+		sizeGb = 1
+	}
+	room := int64(sizeGb) << 30
+	room += room/10 + minArchivePartSize
+
+	partSize := int64(minArchivePartSize)
+	if perPart := (room + maxArchiveParts - 1) / maxArchiveParts; perPart > partSize {
+		// whole megabytes
+		partSize = (perPart + 1<<20 - 1) &^ (1<<20 - 1)
+	}
+	return partSize, int((room + partSize - 1) / partSize)
+}
+
+// startArchiveUpload starts a multipart upload of the archive and presigns the requests the VM
+// makes to upload a volume of sizeGb to it
+func startArchiveUpload(ctx context.Context, presign archivePresigner, sizeGb int) (*api.MultipartUploadInput, error) {
+	createURL, err := presign(http.MethodPost, url.Values{"uploads": {""}})
+	if err != nil {
+		return nil, errors.Wrap(err, "error signing the archive's URL")
+	}
+	body, err := s3Request(ctx, http.MethodPost, createURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "could not start uploading the archive")
+	}
+	var created struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.Unmarshal(body, &created); err != nil || created.UploadID == "" {
+		return nil, fmt.Errorf("unexpected response starting the upload: %s", body)
+	}
+
+	upload := &api.MultipartUploadInput{}
+	partSize, parts := archiveParts(sizeGb)
+	upload.PartSize = partSize
+	for n := 1; n <= parts; n++ {
+		partURL, err := presign(http.MethodPut, url.Values{"partNumber": {strconv.Itoa(n)}, "uploadId": {created.UploadID}})
+		if err != nil {
+			return nil, errors.Wrap(err, "error signing the archive's URL")
+		}
+		upload.PartURLs = append(upload.PartURLs, partURL)
+	}
+	if upload.CompleteURL, err = presign(http.MethodPost, url.Values{"uploadId": {created.UploadID}}); err != nil {
+		return nil, errors.Wrap(err, "error signing the archive's URL")
+	}
+	if upload.AbortURL, err = presign(http.MethodDelete, url.Values{"uploadId": {created.UploadID}}); err != nil {
+		return nil, errors.Wrap(err, "error signing the archive's URL")
+	}
+
+	return upload, nil
+}
+
+// abortArchiveUpload deletes an upload and the parts uploaded to it
+func abortArchiveUpload(ctx context.Context, upload *api.MultipartUploadInput) error {
+	_, err := s3Request(ctx, http.MethodDelete, upload.AbortURL)
+	return err
+}
+
+// s3Request makes a request to a presigned URL, returning the response body
+func s3Request(ctx context.Context, method string, presignedURL string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, presignedURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
+
+// objectURL is the https URL of an object, path style for S3 compatible stores and for buckets
+// with dots, which don't match the wildcard certificate of virtual hosted style URLs
+func objectURL(endpoint string, region string, bucket string, key string) string {
+	key = awsauth.EscapePath(key)
+	switch {
+	case endpoint != "":
+		return fmt.Sprintf("%s/%s/%s", strings.TrimSuffix(endpoint, "/"), bucket, key)
+	case strings.Contains(bucket, "."):
+		return fmt.Sprintf("https://s3.%s.amazonaws.com/%s/%s", region, bucket, key)
+	default:
+		return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, region, key)
+	}
+}
+
+// watchVolumeTransfer follows an export or import until it finishes, unless --detach is set
+func watchVolumeTransfer(ctx context.Context, cmdCtx *cmdctx.CmdContext, transfer *api.VolumeTransfer) error {
+	if cmdCtx.Config.GetBool("detach") {
+		if cmdCtx.OutputJSON() {
+			cmdCtx.WriteJSON(transfer)
+			return nil
+		}
+		fmt.Fprintf(cmdCtx.Out, "Started transfer %s, it continues in the background\n", transfer.ID)
+		return nil
+	}
+
+	cmdCtx.IO.StartProgressIndicatorMsg(fmt.Sprintf("Transferring %s", transfer.URL))
+	for transfer.InProgress {
+		select {
+		case <-time.After(2 * time.Second):
+		case <-ctx.Done():
+			cmdCtx.IO.StopProgressIndicator()
+			fmt.Fprintf(cmdCtx.Out, "Stopped watching, transfer %s continues in the background\n", transfer.ID)
+			return ctx.Err()
+		}
+
+		next, err := cmdCtx.Client.API().GetVolumeTransfer(transfer.ID)
+		if err != nil {
+			cmdCtx.IO.StopProgressIndicator()
+			return errors.Wrap(err, "error checking the transfer")
+		}
+		transfer = next
+		cmdCtx.IO.ChangeProgressIndicatorMsg(fmt.Sprintf("Transferring %s: %s, %s", transfer.URL, transfer.Status, humanize.Bytes(uint64(transfer.BytesTransferred))))
+	}
+	cmdCtx.IO.StopProgressIndicator()
+
+	if transfer.Status != "succeeded" {
+		if transfer.Error != "" {
+			return fmt.Errorf("transfer %s failed: %s", transfer.ID, transfer.Error)
+		}
+		return fmt.Errorf("transfer %s %s", transfer.ID, transfer.Status)
+	}
+
+	if cmdCtx.OutputJSON() {
+		cmdCtx.WriteJSON(transfer)
+		return nil
+	}
+
+	cmdCtx.Statusf("volumes", cmdctx.SDONE, "Transferred %s\n", humanize.Bytes(uint64(transfer.BytesTransferred)))
+	if v := transfer.Volume; v != nil {
+		fmt.Fprintf(cmdCtx.Out, "Volume %s (%s), %dGB in %s\n", v.ID, v.Name, v.SizeGb, v.Region)
+	}
+
+	return nil
+}
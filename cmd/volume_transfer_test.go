@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/superfly/flyctl/internal/awsauth"
+)
+
+func TestArchiveParts(t *testing.T) {
+	partSize, parts := archiveParts(1)
+	assert.Equal(t, int64(64<<20), partSize)
+	assert.Equal(t, 19, parts)
+
+	// a 1000GB volume needs larger parts to stay under S3's limit on the number of parts
+	partSize, parts = archiveParts(1000)
+	assert.Equal(t, int64(113<<20), partSize)
+	assert.LessOrEqual(t, parts, maxArchiveParts)
+	assert.GreaterOrEqual(t, partSize*int64(parts), int64(1000)<<30)
+}
+
+func TestTransferURLExpiry(t *testing.T) {
+	now := time.Date(2021, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	expiry, err := transferURLExpiry(awsauth.Credentials{}, now)
+	assert.NoError(t, err)
+	assert.Equal(t, volumeTransferURLExpiry, expiry)
+
+	expiry, err = transferURLExpiry(awsauth.Credentials{Expires: now.Add(time.Hour)}, now)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Hour, expiry)
+
+	expiry, err = transferURLExpiry(awsauth.Credentials{Expires: now.Add(48 * time.Hour)}, now)
+	assert.NoError(t, err)
+	assert.Equal(t, volumeTransferURLExpiry, expiry)
+
+	_, err = transferURLExpiry(awsauth.Credentials{Expires: now.Add(-time.Minute)}, now)
+	assert.Error(t, err)
+}
+
+func TestStartArchiveUpload(t *testing.T) {
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.RawQuery)
+		w.Write([]byte(`<InitiateMultipartUploadResult><Bucket>b</Bucket><Key>k</Key><UploadId>up-1</UploadId></InitiateMultipartUploadResult>`))
+	}))
+	defer srv.Close()
+
+	presign := func(method string, query url.Values) (string, error) {
+		return srv.URL + "/b/k?" + query.Encode() + "&method=" + method, nil
+	}
+
+	upload, err := startArchiveUpload(context.Background(), presign, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"POST uploads=&method=POST"}, requests)
+	assert.Equal(t, int64(64<<20), upload.PartSize)
+	assert.Len(t, upload.PartURLs, 19)
+	assert.Equal(t, srv.URL+"/b/k?partNumber=1&uploadId=up-1&method=PUT", upload.PartURLs[0])
+	assert.Equal(t, srv.URL+"/b/k?uploadId=up-1&method=POST", upload.CompleteURL)
+	assert.Equal(t, srv.URL+"/b/k?uploadId=up-1&method=DELETE", upload.AbortURL)
+}
@@ -1,19 +1,25 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/dustin/go-humanize"
 	"github.com/spf13/cobra"
+	"github.com/superfly/flyctl/api"
 	"github.com/superfly/flyctl/cmdctx"
 	"github.com/superfly/flyctl/helpers"
 	"github.com/superfly/flyctl/internal/client"
+	"github.com/superfly/flyctl/internal/download"
 
 	"github.com/superfly/flyctl/docstrings"
 )
 
+const defaultVolumeSizeGb = 10
+
 func newVolumesCommand(client *client.Client) *Command {
 	volumesStrings := docstrings.Get("volumes")
 	volumesCmd := BuildCommandKS(nil, nil, volumesStrings, client, requireAppName, requireSession)
@@ -23,7 +29,7 @@ func newVolumesCommand(client *client.Client) *Command {
 	BuildCommandKS(volumesCmd, runListVolumes, listStrings, client, requireAppName, requireSession)
 
 	createStrings := docstrings.Get("volumes.create")
-	createCmd := BuildCommandKS(volumesCmd, runCreateVolume, createStrings, client, requireAppName, requireSession)
+	createCmd := BuildCommandKS(volumesCmd, runCreateVolume, createStrings, client, requireAppName, requireSession, mutating)
 	createCmd.Args = cobra.ExactArgs(1)
 
 	createCmd.AddStringFlag(StringFlagOpts{
@@ -34,7 +40,7 @@ func newVolumesCommand(client *client.Client) *Command {
 	createCmd.AddIntFlag(IntFlagOpts{
 		Name:        "size",
 		Description: "Size of volume in gigabytes, default 10GB",
-		Default:     10,
+		Default:     defaultVolumeSizeGb,
 	})
 
 	createCmd.AddBoolFlag(BoolFlagOpts{
@@ -44,13 +50,124 @@ func newVolumesCommand(client *client.Client) *Command {
 	})
 
 	deleteStrings := docstrings.Get("volumes.delete")
-	deleteCmd := BuildCommandKS(volumesCmd, runDestroyVolume, deleteStrings, client, requireSession)
+	deleteCmd := BuildCommandKS(volumesCmd, runDestroyVolume, deleteStrings, client, requireSession, mutating)
 	deleteCmd.Args = cobra.ExactArgs(1)
 
+	extendStrings := docstrings.Get("volumes.extend")
+	extendCmd := BuildCommandKS(volumesCmd, runExtendVolume, extendStrings, client, requireSession, mutating)
+	extendCmd.Args = cobra.ExactArgs(1)
+	extendCmd.AddIntFlag(IntFlagOpts{
+		Name:        "size",
+		Shorthand:   "s",
+		Description: "New size of the volume in gigabytes",
+	})
+
+	cloneStrings := docstrings.Get("volumes.clone")
+	cloneCmd := BuildCommandKS(volumesCmd, runCloneVolume, cloneStrings, client, requireAppName, requireSession, mutating)
+	cloneCmd.Args = cobra.ExactArgs(1)
+	cloneCmd.AddStringFlag(StringFlagOpts{
+		Name:        "region",
+		Description: "Region for the copy",
+	})
+	cloneCmd.AddStringFlag(StringFlagOpts{
+		Name:        "name",
+		Description: "Name of the copy, defaults to the volume's name so the app mounts it in the new region",
+	})
+
+	exportStrings := docstrings.Get("volumes.export")
+	exportCmd := BuildCommandKS(volumesCmd, runExportVolume, exportStrings, client, requireSession, mutating)
+	exportCmd.Args = cobra.ExactArgs(1)
+	exportCmd.Command.Example = `flyctl volumes export vol_1x2y3z --to s3://my-backups/myapp/`
+	exportCmd.AddStringFlag(StringFlagOpts{
+		Name:        "to",
+		Description: "s3:// URL to write the archive to, a name is generated when it ends with /",
+	})
+
+	importStrings := docstrings.Get("volumes.import")
+	importCmd := BuildCommandKS(volumesCmd, runImportVolume, importStrings, client, requireAppName, requireSession, mutating)
+	importCmd.Args = cobra.ExactArgs(1)
+	importCmd.Command.Example = `flyctl volumes import data --from s3://my-backups/myapp/vol_1x2y3z-20261016T120000Z.tar.gz --region fra`
+	importCmd.AddStringFlag(StringFlagOpts{
+		Name:        "from",
+		Description: "s3:// URL of the archive to import",
+	})
+	importCmd.AddStringFlag(StringFlagOpts{
+		Name:        "region",
+		Description: "Region for the new volume",
+	})
+	importCmd.AddIntFlag(IntFlagOpts{
+		Name:        "size",
+		Description: "Size of the new volume in gigabytes, defaults to the size of the exported volume",
+	})
+
+	for _, transferCmd := range []*Command{exportCmd, importCmd} {
+		transferCmd.AddStringFlag(StringFlagOpts{
+			Name:        "endpoint",
+			Description: "URL of an S3 compatible store, instead of AWS",
+			EnvName:     "AWS_ENDPOINT_URL",
+		})
+		transferCmd.AddBoolFlag(BoolFlagOpts{
+			Name:        "detach",
+			Description: "Return once the transfer starts instead of waiting for it",
+		})
+	}
+
 	showStrings := docstrings.Get("volumes.show")
 	showCmd := BuildCommandKS(volumesCmd, runShowVolume, showStrings, client, requireSession)
 	showCmd.Args = cobra.ExactArgs(1)
 
+	snapshotsStrings := docstrings.Get("volumes.snapshots")
+	snapshotsCmd := BuildCommandKS(volumesCmd, nil, snapshotsStrings, client, requireSession)
+
+	snapshotsListStrings := docstrings.Get("volumes.snapshots.list")
+	snapshotsListCmd := BuildCommandKS(snapshotsCmd, runListVolumeSnapshots, snapshotsListStrings, client, requireSession)
+	snapshotsListCmd.Args = cobra.ExactArgs(1)
+
+	snapshotsCreateStrings := docstrings.Get("volumes.snapshots.create")
+	snapshotsCreateCmd := BuildCommandKS(snapshotsCmd, runCreateVolumeSnapshot, snapshotsCreateStrings, client, requireSession, mutating)
+	snapshotsCreateCmd.Args = cobra.ExactArgs(1)
+
+	snapshotsRestoreStrings := docstrings.Get("volumes.snapshots.restore")
+	snapshotsRestoreCmd := BuildCommandKS(snapshotsCmd, runRestoreVolumeSnapshot, snapshotsRestoreStrings, client, requireAppName, requireSession, mutating)
+	snapshotsRestoreCmd.Args = cobra.ExactArgs(2)
+	snapshotsRestoreCmd.AddStringFlag(StringFlagOpts{
+		Name:        "region",
+		Description: "Region for the new volume",
+	})
+	snapshotsRestoreCmd.AddIntFlag(IntFlagOpts{
+		Name:        "size",
+		Description: "Size of the new volume in gigabytes, defaults to the snapshot's size",
+	})
+
+	snapshotsScheduleStrings := docstrings.Get("volumes.snapshots.schedule")
+	snapshotsScheduleCmd := BuildCommandKS(snapshotsCmd, runVolumeSnapshotSchedule, snapshotsScheduleStrings, client, requireSession, mutating)
+	snapshotsScheduleCmd.Args = cobra.ExactArgs(1)
+	snapshotsScheduleCmd.Command.Example = `flyctl volumes snapshots schedule vol_1x2y3z --daily --retain 7
+	flyctl volumes snapshots schedule vol_1x2y3z --off`
+	for _, frequency := range volumeSnapshotFrequencies {
+		snapshotsScheduleCmd.AddBoolFlag(BoolFlagOpts{
+			Name:        frequency,
+			Description: "Snapshot the volume " + frequency,
+		})
+	}
+	snapshotsScheduleCmd.AddBoolFlag(BoolFlagOpts{
+		Name:        "off",
+		Description: "Stop automatic snapshots of the volume",
+	})
+	snapshotsScheduleCmd.AddIntFlag(IntFlagOpts{
+		Name:        "retain",
+		Description: "Number of automatic snapshots to keep",
+	})
+
+	snapshotsDownloadStrings := docstrings.Get("volumes.snapshots.download")
+	snapshotsDownloadCmd := BuildCommandKS(snapshotsCmd, runDownloadVolumeSnapshot, snapshotsDownloadStrings, client, requireSession)
+	snapshotsDownloadCmd.Args = cobra.ExactArgs(1)
+	snapshotsDownloadCmd.AddStringFlag(StringFlagOpts{
+		Name:        "output",
+		Shorthand:   "o",
+		Description: "Path to write the disk image to. Defaults to <snapshot-id>.img.<format> in the current directory",
+	})
+
 	return volumesCmd
 }
 
@@ -62,24 +179,24 @@ func runListVolumes(ctx *cmdctx.CmdContext) error {
 		return err
 	}
 
-	if len(volumes) == 0 {
-		fmt.Printf("No Volumes Defined for %s\n", ctx.AppName)
+	if ctx.OutputJSON() {
+		ctx.WriteJSON(volumes)
 		return nil
 	}
 
-	if ctx.OutputJSON() {
-		ctx.WriteJSON(volumes)
+	if len(volumes) == 0 {
+		fmt.Printf("No Volumes Defined for %s\n", ctx.AppName)
 		return nil
 	}
 
-	table := helpers.MakeSimpleTable(ctx.Out, []string{"ID", "Name", "Size", "Region", "Attached VM", "Created At"})
+	table := helpers.MakeSimpleTable(ctx.Out, []string{"ID", "Name", "Size", "Used", "Region", "Attached VM", "Created At"})
 
 	for _, v := range volumes {
 		var attachedAllocID string
 		if v.AttachedAllocation != nil {
 			attachedAllocID = v.AttachedAllocation.IDShort
 		}
-		table.Append([]string{v.ID, v.Name, strconv.Itoa(v.SizeGb) + "GB", v.Region, attachedAllocID, humanize.Time(v.CreatedAt)})
+		table.Append([]string{v.ID, v.Name, strconv.Itoa(v.SizeGb) + "GB", formatVolumeUsage(v.Usage), v.Region, attachedAllocID, humanize.Time(v.CreatedAt)})
 	}
 
 	table.Render()
@@ -117,14 +234,7 @@ func runCreateVolume(ctx *cmdctx.CmdContext) error {
 		return err
 	}
 
-	fmt.Printf("%10s: %s\n", "ID", volume.ID)
-	fmt.Printf("%10s: %s\n", "Name", volume.Name)
-	fmt.Printf("%10s: %s\n", "Region", volume.Region)
-	fmt.Printf("%10s: %d\n", "Size GB", volume.SizeGb)
-	fmt.Printf("%10s: %t\n", "Encrypted", volume.Encrypted)
-	fmt.Printf("%10s: %s\n", "Created at", volume.CreatedAt.Format(time.RFC822))
-
-	return nil
+	return printVolume(ctx, volume)
 }
 
 func runDestroyVolume(ctx *cmdctx.CmdContext) error {
@@ -142,6 +252,91 @@ func runDestroyVolume(ctx *cmdctx.CmdContext) error {
 	return nil
 }
 
+func runExtendVolume(ctx *cmdctx.CmdContext) error {
+	volID := ctx.Args[0]
+
+	sizeGb := ctx.Config.GetInt("size")
+	if sizeGb <= 0 {
+		return fmt.Errorf("--size <gigabytes> flag required")
+	}
+
+	volume, err := ctx.Client.API().GetVolume(volID)
+	if err != nil {
+		return err
+	}
+	if sizeGb <= volume.SizeGb {
+		return fmt.Errorf("volumes can only grow, %s is already %dGB", volID, volume.SizeGb)
+	}
+
+	extended, err := ctx.Client.API().ExtendVolume(volID, sizeGb)
+	if err != nil {
+		return err
+	}
+
+	if ctx.OutputJSON() {
+		ctx.WriteJSON(extended)
+		return nil
+	}
+
+	fmt.Fprintf(ctx.Out, "Extended %s from %dGB to %dGB\n", volID, volume.SizeGb, extended.Volume.SizeGb)
+	if alloc := extended.Volume.AttachedAllocation; alloc != nil {
+		if extended.NeedsRestart {
+			fmt.Fprintf(ctx.Out, "Restart instance %s to use the new space: flyctl vm restart %s\n", alloc.IDShort, alloc.IDShort)
+		} else {
+			fmt.Fprintf(ctx.Out, "Resized the filesystem on instance %s\n", alloc.IDShort)
+		}
+	}
+
+	return nil
+}
+
+// runCloneVolume copies a volume to another region through a fresh snapshot
+func runCloneVolume(ctx *cmdctx.CmdContext) error {
+	volID := ctx.Args[0]
+
+	region, _ := ctx.Config.GetString("region")
+	if region == "" {
+		return fmt.Errorf("--region <region> flag required")
+	}
+
+	app, err := ctx.Client.API().GetApp(ctx.AppName)
+	if err != nil {
+		return err
+	}
+
+	source, err := ctx.Client.API().GetVolume(volID)
+	if err != nil {
+		return err
+	}
+	name, _ := ctx.Config.GetString("name")
+	if name == "" {
+		name = source.Name
+	}
+
+	ctx.Statusf("volumes", cmdctx.SBEGIN, "Snapshotting %s in %s\n", volID, source.Region)
+	snapshot, err := ctx.Client.API().CreateVolumeSnapshot(volID)
+	if err != nil {
+		return err
+	}
+	ctx.Statusf("volumes", cmdctx.SDONE, "Created snapshot %s (%s)\n", snapshot.ID, humanize.Bytes(uint64(snapshot.Size)))
+
+	ctx.Statusf("volumes", cmdctx.SBEGIN, "Restoring it to a new %s volume in %s\n", name, region)
+	volume, err := ctx.Client.API().RestoreVolumeSnapshot(app.ID, snapshot.ID, name, region, source.SizeGb)
+	if err != nil {
+		return fmt.Errorf("%s, the snapshot %s can be restored with flyctl volumes snapshots restore", err, snapshot.ID)
+	}
+
+	if ctx.OutputJSON() {
+		ctx.WriteJSON(volume)
+		return nil
+	}
+
+	fmt.Fprintf(ctx.Out, "Cloned %s to %s in %s\n", volID, volume.ID, volume.Region)
+	fmt.Fprintf(ctx.Out, "Writes to %s since the snapshot aren't in the copy, stop writing to it first when migrating regions\n", volID)
+
+	return nil
+}
+
 func runShowVolume(ctx *cmdctx.CmdContext) error {
 	volID := ctx.Args[0]
 
@@ -151,17 +346,244 @@ func runShowVolume(ctx *cmdctx.CmdContext) error {
 		return err
 	}
 
+	return printVolume(ctx, volume)
+}
+
+func printVolume(ctx *cmdctx.CmdContext, volume *api.Volume) error {
 	if ctx.OutputJSON() {
 		ctx.WriteJSON(volume)
 		return nil
 	}
 
-	fmt.Printf("%10s: %s\n", "ID", volume.ID)
-	fmt.Printf("%10s: %s\n", "Name", volume.Name)
-	fmt.Printf("%10s: %s\n", "Region", volume.Region)
-	fmt.Printf("%10s: %d\n", "Size GB", volume.SizeGb)
-	fmt.Printf("%10s: %t\n", "Encrypted", volume.Encrypted)
-	fmt.Printf("%10s: %s\n", "Created at", volume.CreatedAt.Format(time.RFC822))
+	fmt.Fprintf(ctx.Out, "%11s: %s\n", "ID", volume.ID)
+	fmt.Fprintf(ctx.Out, "%11s: %s\n", "Name", volume.Name)
+	fmt.Fprintf(ctx.Out, "%11s: %s\n", "Region", volume.Region)
+	fmt.Fprintf(ctx.Out, "%11s: %d\n", "Size GB", volume.SizeGb)
+	fmt.Fprintf(ctx.Out, "%11s: %t\n", "Encrypted", volume.Encrypted)
+	fmt.Fprintf(ctx.Out, "%11s: %s\n", "Created at", volume.CreatedAt.Format(time.RFC822))
+	if alloc := volume.AttachedAllocation; alloc != nil {
+		fmt.Fprintf(ctx.Out, "%11s: %s (%s, %s)\n", "Attached VM", alloc.IDShort, alloc.Region, alloc.Status)
+	} else {
+		fmt.Fprintf(ctx.Out, "%11s: none\n", "Attached VM")
+	}
+	if volume.Usage != nil {
+		fmt.Fprintf(ctx.Out, "%11s: %s\n", "Used", formatVolumeUsage(volume.Usage))
+		fmt.Fprintf(ctx.Out, "%11s: %s\n", "Free", humanize.Bytes(uint64(volume.Usage.FreeBytes)))
+	}
+
+	return nil
+}
+
+// formatVolumeUsage is the used space of a volume and its percentage, like 1.2 GB (12%)
+func formatVolumeUsage(usage *api.VolumeUsage) string {
+	if usage == nil {
+		return ""
+	}
+	if usage.TotalBytes <= 0 {
+		return humanize.Bytes(uint64(usage.UsedBytes))
+	}
+	return fmt.Sprintf("%s (%d%%)", humanize.Bytes(uint64(usage.UsedBytes)), usage.UsedBytes*100/usage.TotalBytes)
+}
+
+func runListVolumeSnapshots(ctx *cmdctx.CmdContext) error {
+	volID := ctx.Args[0]
+
+	snapshots, err := ctx.Client.API().GetVolumeSnapshots(volID)
+	if err != nil {
+		return err
+	}
+
+	if ctx.OutputJSON() {
+		ctx.WriteJSON(snapshots)
+		return nil
+	}
+
+	if len(snapshots) == 0 {
+		fmt.Fprintf(ctx.Out, "No snapshots of %s\n", volID)
+		return nil
+	}
+
+	table := helpers.MakeSimpleTable(ctx.Out, []string{"ID", "Size", "Digest", "Created At"})
+	for _, s := range snapshots {
+		table.Append([]string{s.ID, humanize.Bytes(uint64(s.Size)), s.Digest, humanize.Time(s.CreatedAt)})
+	}
+	table.Render()
+
+	return nil
+}
+
+func runCreateVolumeSnapshot(ctx *cmdctx.CmdContext) error {
+	volID := ctx.Args[0]
+
+	snapshot, err := ctx.Client.API().CreateVolumeSnapshot(volID)
+	if err != nil {
+		return err
+	}
+
+	if ctx.OutputJSON() {
+		ctx.WriteJSON(snapshot)
+		return nil
+	}
+
+	fmt.Fprintf(ctx.Out, "Created snapshot %s of %s\n", snapshot.ID, volID)
+	fmt.Fprintf(ctx.Out, "Restore it to a new volume with: flyctl volumes snapshots restore %s <volumename> --region <region>\n", snapshot.ID)
+
+	return nil
+}
+
+func runRestoreVolumeSnapshot(ctx *cmdctx.CmdContext) error {
+	snapshotID, volName := ctx.Args[0], ctx.Args[1]
+
+	region, _ := ctx.Config.GetString("region")
+	if region == "" {
+		return fmt.Errorf("--region <region> flag required")
+	}
+
+	app, err := ctx.Client.API().GetApp(ctx.AppName)
+	if err != nil {
+		return err
+	}
+
+	volume, err := ctx.Client.API().RestoreVolumeSnapshot(app.ID, snapshotID, volName, region, ctx.Config.GetInt("size"))
+	if err != nil {
+		return err
+	}
+
+	return printVolume(ctx, volume)
+}
+
+var volumeSnapshotFrequencies = []string{"hourly", "daily", "weekly"}
+
+func runVolumeSnapshotSchedule(ctx *cmdctx.CmdContext) error {
+	volID := ctx.Args[0]
+	client := ctx.Client.API()
+
+	var frequency string
+	for _, f := range append(volumeSnapshotFrequencies, "off") {
+		if !ctx.Config.GetBool(f) {
+			continue
+		}
+		if frequency != "" {
+			return fmt.Errorf("--%s and --%s can't be used together", frequency, f)
+		}
+		frequency = f
+	}
+	retain := ctx.Config.GetInt("retain")
+	if retain < 0 {
+		return fmt.Errorf("--retain must be at least 1")
+	}
+
+	if frequency == "" && retain == 0 {
+		schedule, err := client.GetVolumeSnapshotSchedule(volID)
+		if err != nil {
+			return err
+		}
+		return printVolumeSnapshotSchedule(ctx, volID, schedule)
+	}
+
+	if frequency == "" {
+		current, err := client.GetVolumeSnapshotSchedule(volID)
+		if err != nil {
+			return err
+		}
+		if current == nil || current.Frequency == "off" {
+			return fmt.Errorf("%s isn't snapshotted automatically, set how often with --%s", volID, strings.Join(volumeSnapshotFrequencies, ", --"))
+		}
+		frequency = current.Frequency
+	}
+
+	schedule, err := client.SetVolumeSnapshotSchedule(api.SetVolumeSnapshotScheduleInput{
+		VolumeID:  volID,
+		Frequency: frequency,
+		Retain:    retain,
+	})
+	if err != nil {
+		return err
+	}
+
+	return printVolumeSnapshotSchedule(ctx, volID, schedule)
+}
+
+func printVolumeSnapshotSchedule(ctx *cmdctx.CmdContext, volID string, schedule *api.VolumeSnapshotSchedule) error {
+	if ctx.OutputJSON() {
+		ctx.WriteJSON(schedule)
+		return nil
+	}
+
+	if schedule == nil || schedule.Frequency == "off" {
+		fmt.Fprintf(ctx.Out, "%s isn't snapshotted automatically\n", volID)
+		return nil
+	}
+
+	source := "its own schedule"
+	if schedule.Default {
+		source = "the platform default"
+	}
+	fmt.Fprintf(ctx.Out, "%s is snapshotted %s, keeping %d snapshots, by %s\n", volID, schedule.Frequency, schedule.Retain, source)
+	if schedule.NextSnapshotAt != nil {
+		fmt.Fprintf(ctx.Out, "Next snapshot at %s\n", schedule.NextSnapshotAt.Format(time.RFC1123))
+	}
+
+	return nil
+}
+
+func runDownloadVolumeSnapshot(cmdCtx *cmdctx.CmdContext) error {
+	ctx := createCancellableContext()
+
+	snapshotID := cmdCtx.Args[0]
+
+	dl, err := cmdCtx.Client.API().CreateVolumeSnapshotDownload(snapshotID)
+	if err != nil {
+		return err
+	}
+
+	path, _ := cmdCtx.Config.GetString("output")
+	if path == "" {
+		path = snapshotID + ".img"
+		if dl.Format != "" {
+			path += "." + dl.Format
+		}
+	}
+	if helpers.FileExists(path) {
+		return fmt.Errorf("%s already exists", path)
+	}
+
+	var lastUpdate time.Time
+	cmdCtx.IO.StartProgressIndicatorMsg(fmt.Sprintf("Downloading snapshot %s", snapshotID))
+	resumed, err := download.File(ctx, download.Options{
+		URL: dl.URL,
+		RefreshURL: func(ctx context.Context) (string, error) {
+			fresh, err := cmdCtx.Client.API().CreateVolumeSnapshotDownload(snapshotID)
+			if err != nil {
+				return "", err
+			}
+			return fresh.URL, nil
+		},
+		Path:   path,
+		Size:   dl.Size,
+		SHA256: dl.Sha256,
+		Progress: func(done, total int64) {
+			if total <= 0 || time.Since(lastUpdate) < 500*time.Millisecond {
+				return
+			}
+			lastUpdate = time.Now()
+			cmdCtx.IO.ChangeProgressIndicatorMsg(fmt.Sprintf("Downloading snapshot %s: %s of %s (%d%%)", snapshotID, humanize.Bytes(uint64(done)), humanize.Bytes(uint64(total)), done*100/total))
+		},
+	})
+	cmdCtx.IO.StopProgressIndicator()
+
+	if resumed > 0 {
+		fmt.Fprintf(cmdCtx.Out, "Resumed from %s\n", humanize.Bytes(uint64(resumed)))
+	}
+
+	if err != nil {
+		if helpers.FileExists(download.PartialPath(path)) {
+			fmt.Fprintln(cmdCtx.Out, "Run the same command again to resume the download")
+		}
+		return err
+	}
+
+	fmt.Fprintf(cmdCtx.Out, "Downloaded snapshot %s to %s (%s, sha256 verified)\n", snapshotID, path, humanize.Bytes(uint64(dl.Size)))
 
 	return nil
 }
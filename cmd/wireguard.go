@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
 	"encoding/base64"
 	"encoding/json"
@@ -24,6 +25,7 @@ import (
 	"github.com/superfly/flyctl/cmdctx"
 	"github.com/superfly/flyctl/docstrings"
 	"github.com/superfly/flyctl/flyctl"
+	"github.com/superfly/flyctl/internal/build/imgsrc"
 	"github.com/superfly/flyctl/internal/client"
 	"github.com/superfly/flyctl/pkg/wg"
 	"github.com/superfly/flyctl/terminal"
@@ -766,6 +768,21 @@ NEW_CONNECTION:
 	return stateb, err
 }
 
+// wireGuardNetwork connects to an organization's private network with the saved WireGuard peer,
+// creating one the first time, for reaching remote builders
+func wireGuardNetwork(ctx *cmdctx.CmdContext) imgsrc.PrivateNetwork {
+	return func(_ context.Context, org *api.Organization) (*wg.Tunnel, error) {
+		state, err := wireGuardForOrg(ctx, org)
+		if err != nil {
+			return nil, fmt.Errorf("create wireguard config: %w", err)
+		}
+
+		terminal.Debugf("Establishing WireGuard connection (%s)\n", state.Name)
+
+		return wg.Connect(*state.TunnelConfig())
+	}
+}
+
 func NearestGatewayRegion(ctx *cmdctx.CmdContext) (string, error) {
 	_, results, err := TimeRegions(ctx, "https://fly.io", false)
 	if err != nil {
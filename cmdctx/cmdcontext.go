@@ -30,6 +30,13 @@ type CmdContext struct {
 	ConfigFile   string
 	AppName      string
 	AppConfig    *flyctl.AppConfig
+	// Selector picks apps by label for commands that run across apps, no app name is needed when it's set
+	Selector string
+	// Results are values a command hands to exit hooks, like the release version of a deploy
+	Results map[string]string
+
+	// events receives Emit's events once StreamEvents is called
+	events io.Writer
 }
 
 // PresenterOption - options for RenderEx, RenderView, render etc...
@@ -73,6 +80,14 @@ func NewCmdContext(flyctlClient *client.Client, ns string, args []string) (*CmdC
 	return ctx, nil
 }
 
+// SetResult records a value for exit hooks, which see it as FLY_<NAME>
+func (commandContext *CmdContext) SetResult(name, value string) {
+	if commandContext.Results == nil {
+		commandContext.Results = map[string]string{}
+	}
+	commandContext.Results[name] = value
+}
+
 // Render - Render a presentable structure via the context
 func (commandContext *CmdContext) Render(presentable presenters.Presentable) error {
 	presenter := &presenters.Presenter{
@@ -214,6 +229,52 @@ func (commandContext *CmdContext) Statusf(source string, status string, format s
 	}
 }
 
+// Event is a line of the newline-delimited JSON a command streams with --json to report its
+// progress, like the release_created event of a deploy
+type Event struct {
+	TS    string      `json:"ts"`
+	Event string      `json:"event"`
+	Data  interface{} `json:"data,omitempty"`
+}
+
+// StreamEvents keeps stdout for the events written with Emit when JSON output is wanted, moving
+// everything else the command prints to stderr. It returns a func that restores the output.
+func (commandContext *CmdContext) StreamEvents() (restore func()) {
+	if !commandContext.OutputJSON() {
+		return func() {}
+	}
+
+	out, restoreOutput := commandContext.ReserveStdout()
+	commandContext.events = out
+
+	return func() {
+		restoreOutput()
+		commandContext.events = nil
+	}
+}
+
+// ReserveStdout moves everything the command prints to stderr and returns stdout, for output that
+// has to stay machine readable on its own. It returns a func that restores the output.
+func (commandContext *CmdContext) ReserveStdout() (stdout io.Writer, restore func()) {
+	out, ctxOut := commandContext.IO.Out, commandContext.Out
+	commandContext.IO.Out = commandContext.IO.ErrOut
+	commandContext.Out = commandContext.IO.ErrOut
+
+	return out, func() {
+		commandContext.IO.Out = out
+		commandContext.Out = ctxOut
+	}
+}
+
+// Emit writes an event to stdout while StreamEvents is in effect, and does nothing otherwise
+func (commandContext *CmdContext) Emit(event string, data interface{}) {
+	if commandContext.events == nil {
+		return
+	}
+	outBuf, _ := json.Marshal(Event{TS: time.Now().Format(time.RFC3339), Event: event, Data: data})
+	fmt.Fprintln(commandContext.events, string(outBuf))
+}
+
 func (commandContext *CmdContext) WriteJSON(myData interface{}) {
 	outBuf, _ := json.MarshalIndent(myData, "", "    ")
 	fmt.Fprintln(commandContext.IO.Out, string(outBuf))
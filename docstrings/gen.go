@@ -9,6 +9,19 @@ func Get(key string) KeyStrings {
 Start with the CREATE command to register your application.
 The LIST command will list all currently registered applications.`,
 		}
+	case "apps.clone":
+		return KeyStrings{"clone <SOURCE> <NEWNAME>", "Copy an app into a new app",
+			`The APPS CLONE command creates a new app from an existing one,
+for spinning up staging copies or per-customer instances. The clone gets the
+source app's config, regions, VM size and counts. Secrets are copied on the
+platform without their values being read, after confirming the list of names;
+use --no-secrets to leave them out and --yes to skip the question.
+
+The clone is created in the source app's organization unless --org is given.
+With --image, the source app's current image is deployed to the clone straight
+away, otherwise deploy it with flyctl deploy. --save-config writes the clone's
+config to a file for that deploy.`,
+		}
 	case "apps.create":
 		return KeyStrings{"create [APPNAME]", "Create a new application",
 			`The APPS CREATE command will both register a new application 
@@ -20,23 +33,74 @@ create the application image when it is deployed.`,
 	case "apps.destroy":
 		return KeyStrings{"destroy [APPNAME]", "Permanently destroys an App",
 			`The APPS DESTROY command will remove an application 
-from the Fly platform.`,
+from the Fly platform.
+
+Before destroying, the volumes, certificates, IP addresses and attached
+postgres databases that go with the app are listed, and the app's name has to
+be typed to confirm. Use --dry-run to only list them, or --yes to skip the
+confirmation.`,
+		}
+	case "apps.label":
+		return KeyStrings{"label", "Manage app labels",
+			`Labels tag apps with key=value pairs, like team=payments or
+env=prod, kept with the app on the platform. APPS LIST, LOGS, STATUS, RESTART,
+SUSPEND and RESUME take a --selector to pick apps by them. Selectors are comma
+separated and every part has to match: key=value, key!=value, key for apps
+with the label and !key for apps without it.`,
+		}
+	case "apps.label.list":
+		return KeyStrings{"list", "List an app's labels",
+			`Lists the app's labels.`,
+		}
+	case "apps.label.set":
+		return KeyStrings{"set KEY=VALUE...", "Add or change an app's labels",
+			`Adds labels to the app, replacing the values of labels it
+already has. Keys are lowercase letters, digits and . _ - / characters.`,
+		}
+	case "apps.label.unset":
+		return KeyStrings{"unset KEY...", "Remove labels from an app",
+			`Removes the labels with the given keys from the app.`,
 		}
 	case "apps.list":
 		return KeyStrings{"list", "List applications",
 			`The APPS LIST command will show the applications currently
 registered and available to this user. The list will include applications 
 from all the organizations the user is a member of. Each application will 
-be shown with its name, owner and when it was last deployed.`,
+be shown with its name, owner and when it was last deployed.
+
+Use --org, --status and --name-prefix to narrow the list, like
+--org personal --status running --name-prefix api-, and --selector to show
+only apps with matching labels, like --selector env=staging. With --json, each
+app is printed as an object with its ID, name, status, hostname, organization,
+labels and when it was last deployed, sorted by name.`,
 		}
 	case "apps.move":
 		return KeyStrings{"move [APPNAME]", "Move an App to another organization",
 			`The APPS MOVE command will move an application to another 
-organization the current user belongs to.`,
+organization the current user belongs to.
+
+Before moving, flyctl lists the IP addresses, certificates, volumes and secrets
+the app has. Afterwards it checks them again and reports anything that didn't
+follow the app to the new organization, with the command to replace it.`,
+		}
+	case "apps.rename":
+		return KeyStrings{"rename <APPNAME> <NEWNAME>", "Rename an application",
+			`The APPS RENAME command changes an application's name. Its releases,
+certificates, IP addresses, volumes and secrets are kept, and flyctl checks
+afterwards that each of them followed the app, reporting any that didn't.
+
+Deployment images are stored in the registry under the app's name, so the
+current image is copied to the new name and deployed from there. The app's
+hostname changes with its name. If the config file in the current directory
+names the old app, it's updated to the new name.`,
 		}
 	case "apps.restart":
 		return KeyStrings{"restart [APPNAME]", "Restart an application",
-			`The APPS RESTART command will restart all running vms.`,
+			`The APPS RESTART command will restart all running vms. 
+
+Use --selector to restart every app with matching labels instead, like
+--selector env=staging. The matching apps are listed and confirmed first, unless
+--yes is given. SUSPEND, RESUME and STATUS take --selector too.`,
 		}
 	case "apps.resume":
 		return KeyStrings{"resume [APPNAME]", "Resume an application",
@@ -45,6 +109,19 @@ The application will resume with its original region pool and a min count of one
 meaning there will be one running instance once restarted. Use SCALE SET MIN= to raise
 the number of configured instances.`,
 		}
+	case "apps.schedule":
+		return KeyStrings{"schedule [APPNAME]", "Suspend and resume an application on a schedule",
+			`The APPS SCHEDULE command suspends and resumes an application
+automatically, so development and staging apps can be stopped on nights and
+weekends. Schedules are cron expressions run in --timezone, UTC by default:
+
+  flyctl apps schedule --suspend "0 20 * * 5" --resume "0 8 * * 1"
+
+suspends the app at 20:00 on Fridays and resumes it at 08:00 on Mondays.
+Without --resume the app stays suspended until APPS RESUME. With no flags the
+current schedule is shown with the next time each part runs, and --clear
+removes it.`,
+		}
 	case "apps.suspend":
 		return KeyStrings{"suspend [APPNAME]", "Suspend an application",
 			`The APPS SUSPEND command will suspend an application. 
@@ -52,6 +129,15 @@ All instances will be halted leaving the application running nowhere.
 It will continue to consume networking resources (IP address). See APPS RESUME
 for details on restarting it.`,
 		}
+	case "apps.usage-report":
+		return KeyStrings{"usage [APPNAME]", "Show resource usage and estimated cost",
+			`The APPS USAGE command shows what each app has used so far in the
+current billing period: VM hours, outbound bandwidth, the average size of its
+volumes and the estimated cost of all three. Without an app name every app
+you can see is listed with a total, use --org to show only one organization's
+apps. With --json the usage is printed as a list of objects with the costs
+in cents.`,
+		}
 	case "auth":
 		return KeyStrings{"auth", "Manage authentication",
 			`Authenticate with Fly (and logout if you need to).
@@ -73,7 +159,29 @@ browser-based authentication.`,
 	case "auth.logout":
 		return KeyStrings{"logout", "Logs out the currently logged in user",
 			`Log the currently logged-in user out of the Fly platform. 
-To continue interacting with Fly, the user will need to log in again.`,
+To continue interacting with Fly, the user will need to log in again.
+
+Use the --everywhere flag to revoke every session of the user, not just the
+one on this machine. Other laptops and CI runners using a token from flyctl
+auth token are logged out too, so use it after losing a machine or leaking a
+token.`,
+		}
+	case "auth.sessions":
+		return KeyStrings{"sessions", "Manage the sessions logged in to your account",
+			`Lists and revokes the sessions logged in to your account from
+other machines, like CI runners or old laptops.`,
+		}
+	case "auth.sessions.list":
+		return KeyStrings{"list", "List the sessions logged in to your account",
+			`Lists the sessions logged in to your account, with the
+machine that logged in, when the session was last used and from which IP
+address. The session in use on this machine is marked as current.`,
+		}
+	case "auth.sessions.revoke":
+		return KeyStrings{"revoke [id]...", "Revoke sessions, logging out the machines using them",
+			`Revokes the sessions with the given IDs, from flyctl auth
+sessions list. Machines using them have to log in again. Use the --others flag
+to revoke every session except the one on this machine.`,
 		}
 	case "auth.signup":
 		return KeyStrings{"signup", "Create a new fly account",
@@ -124,6 +232,60 @@ max=int - maximum number of instances to be allocated from region pool.`,
 min=int - minimum number of instances to be allocated from region pool. 
 max=int - maximum number of instances to be allocated from region pool.`,
 		}
+	case "builder":
+		return KeyStrings{"builder", "Manage the remote builder",
+			`The BUILDER commands manage the remote builder used to build images
+for the application's organization when a local docker daemon isn't available
+or --remote-only is used.
+
+The builder is reached over the organization's private WireGuard network, so its
+Docker API isn't exposed to the internet. The first remote build creates a
+WireGuard peer for the organization, the same one used by flyctl ssh.
+
+Organizations can keep several named builders, such as one per region or per
+team. Pick one with --builder NAME, which creates it on first use. Without
+--builder the organization's default builder is used.`,
+		}
+	case "builder.cache":
+		return KeyStrings{"cache", "Manage the remote builder's disk cache",
+			`Commands for inspecting and cleaning up the images and build cache
+stored on the remote builder's disk.`,
+		}
+	case "builder.cache.ls":
+		return KeyStrings{"ls", "List images and build cache on the remote builder",
+			`Lists dangling images and build cache records on the remote builder,
+starting the builder if it isn't running.`,
+		}
+	case "builder.cache.prune":
+		return KeyStrings{"prune", "Free disk space on the remote builder",
+			`Removes dangling images and build cache that isn't in use from the
+remote builder. With --all, every image and cache record not used by a
+running build is removed. Builds after a prune may be slower while the
+cache is rebuilt.`,
+		}
+	case "builder.destroy":
+		return KeyStrings{"destroy", "Destroy the remote builder",
+			`Destroys the organization's remote builder, along with its build cache.
+A new builder is provisioned automatically on the next remote build, or
+immediately with --recreate. Use this when the builder is wedged.`,
+		}
+	case "builder.list":
+		return KeyStrings{"list", "List the organization's remote builders",
+			`List the remote builders of the application's organization, with the
+app each one runs as and its status. Use a builder's name with --builder to
+build with it.`,
+		}
+	case "builder.status":
+		return KeyStrings{"status", "Show remote builder status",
+			`Shows the name, status and instances of the organization's remote
+builder. When the builder is running, its disk usage is shown as well. No
+builder is created when the organization doesn't have one yet.`,
+		}
+	case "builder.warm":
+		return KeyStrings{"warm", "Start the remote builder ahead of a deploy",
+			`Starts the remote builder and waits until it is ready to accept
+builds, so a following deploy doesn't have to wait for it.`,
+		}
 	case "builds":
 		return KeyStrings{"builds", "Work with Fly Builds",
 			`Fly Builds are templates to make developing Fly applications easier.`,
@@ -166,16 +328,42 @@ certificates issued for the hostname/domain by Let's Encrypt.`,
 	case "certs.add":
 		return KeyStrings{"add <hostname>", "Add a certificate for an App.",
 			`Add a certificate for an application. Takes a hostname 
-as a parameter for the certificate.`,
+as a parameter for the certificate.
+
+With --dns-provider, the records pointing the hostname at the application and
+validating the certificate are created through the DNS provider's API, and the
+command waits for the certificate to be issued. Credentials are read from the
+environment:
+
+cloudflare: CLOUDFLARE_API_TOKEN
+route53: AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN, or the aws CLI's login
+gcloud: GOOGLE_OAUTH_ACCESS_TOKEN and GOOGLE_CLOUD_PROJECT, or the gcloud CLI's login`,
 		}
 	case "certs.check":
-		return KeyStrings{"check <hostname>", "Checks DNS configuration",
+		return KeyStrings{"check [<hostname>]", "Checks DNS configuration and certificate expiry",
 			`Checks the DNS configuration for the specified hostname. 
-Displays results in the same format as the SHOW command.`,
+Displays results in the same format as the SHOW command.
+
+Without a hostname, every certificate of the application is listed with its
+status and expiry. Either way the command exits non-zero when a certificate
+expires within --warn-days, 14 by default, or still hasn't been issued an hour
+after it was added, for use in cron jobs and CI.`,
+		}
+	case "certs.import":
+		return KeyStrings{"import <file>", "Add certificates for a list of hostnames",
+			`Adds a certificate for each hostname in a file, one per line, with #
+comments. Hostnames that already have a certificate are skipped.
+
+Certificates are added --batch-size at a time, pausing --batch-interval between
+batches, and a rate limited request is retried with a growing delay. Progress is
+saved after each hostname to the --state file, so an interrupted import resumes
+where it stopped when run again, and hostnames that failed are retried.`,
 		}
 	case "certs.list":
 		return KeyStrings{"list", "List certificates for an App.",
-			`List the certificates associated with a deployed application.`,
+			`List the certificates associated with a deployed application.
+With --json, each includes its issued key types with their expiry, and the
+validation errors of attempts to issue it.`,
 		}
 	case "certs.remove":
 		return KeyStrings{"remove <hostname>", "Removes a certificate from an App",
@@ -213,7 +401,19 @@ Takes hostname as a parameter to locate the certificate.`,
 		}
 	case "config":
 		return KeyStrings{"config", "Manage an Apps configuration",
-			`The CONFIG commands allow you to work with an application's configuration.`,
+			`The CONFIG commands allow you to work with an application's configuration.
+
+An app's configuration is read from fly.toml, or from fly.yaml, fly.yml or fly.json
+when there's no fly.toml. The YAML and JSON files hold the same settings as fly.toml,
+with each [section] as a map and each [[section]] as a list. Pass any of them with
+--config, the format is chosen by the file's extension.`,
+		}
+	case "config.diff":
+		return KeyStrings{"diff", "Show how the local config differs from the deployed one",
+			`Compares the app's local config file with the config of its current
+release, showing each setting a deploy would add (+), remove (-) or change (~).
+The local file is parsed by the Fly service first, the same way deploy does,
+so both sides are compared in the same form.`,
 		}
 	case "config.display":
 		return KeyStrings{"display", "Display an App's configuration",
@@ -223,12 +423,56 @@ in JSON format. The configuration data is retrieved from the Fly service.`,
 	case "config.save":
 		return KeyStrings{"save", "Save an App's config file",
 			`Save an application's configuration locally. The configuration data is 
-retrieved from the Fly service and saved in TOML format.`,
+retrieved from the Fly service and saved in TOML format.
+
+With --all, the live app is captured too, so its definition can be rebuilt from
+the file. The names of its secrets are saved as [secrets] required, and each
+process group in [processes] gets its count and the app's VM size. The VM size,
+scale, IP addresses and volumes are noted in comments at the top of the file,
+which are left out of fly.json files.`,
+		}
+	case "config.schema":
+		return KeyStrings{"schema", "Print the JSON Schema for fly.toml",
+			`Prints the JSON Schema for app configuration, for editors to validate and
+complete fly.toml, fly.yaml and fly.json files. It's the schema config validate
+checks against locally. Save it alongside the config to point an editor at it:
+
+    flyctl config schema > fly.schema.json
+
+With the Even Better TOML extension for VS Code, add this line to the top of
+fly.toml:
+
+    #:schema ./fly.schema.json`,
+		}
+	case "config.show":
+		return KeyStrings{"show", "Show an app's local config file",
+			`Show the app's local config file as it's written.
+
+A config file can include other config files, so several apps or environments
+share services and checks and only set what differs:
+
+    include = ["../shared/base.toml"]
+
+Included files are relative to the file including them and may include files
+of their own. Later files override earlier ones and the including file overrides
+them all. Sections are merged setting by setting, lists like [[services]] and
+other values replace what's included.
+
+With --resolved, the config is shown with its includes and the --environment
+overlay merged in, as it's deployed.`,
 		}
 	case "config.validate":
 		return KeyStrings{"validate", "Validate an App's config file",
 			`Validates an application's config file against the Fly platform to 
-ensure it is correct and meaningful to the platform.`,
+ensure it is correct and meaningful to the platform. 
+
+The file is first checked locally against the platform's schema: unknown
+settings, values of the wrong type, invalid port handlers, external ports used
+by more than one service, check timeouts longer than their intervals, and the
+[deploy], [restart] and [regions] sections. Each problem is reported with the
+line it's on and the path of the setting, like services[0].ports[1].handlers.
+With --json the result is printed as JSON for editors and CI, and --local skips
+the check against the Fly service.`,
 		}
 	case "curl":
 		return KeyStrings{"curl <url>", "Run a performance test against a url",
@@ -251,15 +495,290 @@ Use the --config/-c flag to select a specific toml configuration file.
 
 Use the --image/-i flag to specify a local or remote image to deploy.
 
+Use --no-build to release an image that already exists without building one,
+for promoting the same image between apps or environments. The image is taken
+from --image, image in the [build] section of fly.toml or FLY_IMAGE_REF, and is
+looked up in the local docker daemon and then the registry. Pin it with a digest,
+like registry.fly.io/app@sha256:..., to fail the deploy unless the image found
+has exactly that digest.
+
+Use --git to deploy a branch, tag or commit of a git repository without a local
+checkout, like --git https://github.com/org/repo#v1.2.3. Only that commit is
+fetched, into a temporary directory, and its Dockerfile or buildpack settings are
+used. A local fly.toml is used when there is one, otherwise the repository's.
+The image is built on a remote builder unless --local-only is set.
+
 Use the --detach flag to return immediately from starting the deployment rather
-than monitoring the deployment progress.
+than monitoring the deployment progress. The deployment's ID is printed, so a
+later step can attach to it with flyctl releases watch <id>.
+
+Use flyctl monitor to restart monitoring deployment progress
+
+Use --dry-run to see the deploy plan without building or deploying anything:
+whether the image comes from --image, buildpacks, a Dockerfile or a builtin,
+whether it would be built on the local docker daemon or a remote builder, the
+deployment tag it would be pushed to, and the settings that would change from
+the current release's config.
+
+Use --config-only to deploy changes to fly.toml, like services, concurrency or
+env, as a new release without building or pushing an image. The new release
+runs the current release's image, pinned by its digest.
+
+Use the --require-approvals flag to hold the release until another member of
+the organization approves it with flyctl deploys approve.
+
+The canary strategy starts one instance of the new release and replaces the rest
+once its health checks pass. Use --canary-verify, or canary_verify in the
+[deploy] section of fly.toml, to also run a command against it first, like a
+smoke test. FLY_CANARY_PRIVATE_IP, FLY_CANARY_ID, FLY_CANARY_REGION,
+FLY_RELEASE_VERSION and FLY_APP are set for it. The deployment waits while it
+runs, for up to canary_verify_timeout (5m by default), and is rolled back if the
+command fails or times out.
+
+Use --manual-promote, or manual_promote = true in the [deploy] section of
+fly.toml, with the bluegreen strategy to hold a release once its full set of
+new instances is healthy. Traffic stays on the old instances until the release
+is promoted with flyctl releases promote, or cancelled with flyctl releases
+cancel. Deploy keeps monitoring until then, or returns with --detach.
+
+Set release_command in the [deploy] section of fly.toml, like
+release_command = "bin/rails db:migrate", to run a command in a one-off VM with
+the new image before any instance is replaced. Its output is shown as it runs,
+and the deploy fails without replacing anything if it exits with an error.
+
+Use --smoke-test with a command, or --smoke-test-http with a path like
+/healthz, to check the first new instance once its health checks pass. The
+command runs locally with FLY_INSTANCE_ADDR set to the instance's private
+address and port, alongside FLY_INSTANCE_IP, FLY_INSTANCE_ID,
+FLY_INSTANCE_REGION, FLY_RELEASE_VERSION and FLY_APP. The path is requested over
+WireGuard and must respond with a 2xx status. The rest of the instances are
+replaced when the smoke test passes, and the deployment is rolled back when it
+fails. Set them in fly.toml as command and http_path in a [deploy.smoke_test]
+section, with port (the service's internal port by default) and timeout (2m by
+default).
+
+Use --rollout-order iad,lhr,syd, or rollout_order = ["iad", "lhr", "syd"] in the
+[deploy] section of fly.toml, to deploy one region at a time in that order.
+After each region, the rollout pauses for --bake-time (bake_time, 5m by
+default) while the new instances rolled out so far are watched. It halts and
+rolls back if one of them fails or keeps reporting critical checks, and moves
+on to the next region otherwise. Regions that aren't listed are deployed to
+together after the last one.
+
+While it monitors a deployment, flyctl rolls it back to the last stable release
+as soon as one of its new instances fails, or reports critical checks after
+passing them. Set auto_rollback_threshold in the [deploy] section of fly.toml to
+wait for more failing instances first. Use --no-auto-rollback, or
+auto_rollback = false, to keep monitoring and leave failing deployments to the
+orchestrator.
+
+Deploys take the app's deploy lock before building, and hold it until they
+finish, so two deploys of the same app can't interleave. A deploy fails when
+another one holds the lock, or waits for it with --wait-for-lock. The lock
+expires a couple of minutes after a deploy is killed, or release it right away
+with --force-unlock. A deploy that loses its lock, because it was forced or
+couldn't be refreshed in time, stops rather than run alongside the next one.
+
+Use --only-regions iad,ord to place the new release in those regions only, to try
+a region-specific change. Instances in the app's other regions keep running the
+release they're on until the next deploy without --only-regions.
+
+To deploy only images signed with cosign, set signature_key in the [deploy]
+section of fly.toml to the path of the public key, like "cosign.pub", or use
+--signature-key. The image given with --image must then have a signature made
+with that key, and must include its registry, like docker.io/me/app:v1.
+Deploys that would build an image fail, as do images whose digest differs from
+the signed one once they're found.
+
+To manage several deployments of an app, like staging and production, from one
+repository, use --environment staging (or FLY_ENVIRONMENT). It loads
+fly.staging.toml next to fly.toml when there is one. Otherwise the settings in
+the [environments.staging] section of fly.toml, such as a different app name,
+[env] variables or [regions], are merged over the rest of the file. The flag
+works the same way with every command that reads fly.toml.
+
+Once a deploy finishes, flyctl runs the success or failure command from the
+[deploy.hooks] section of fly.toml, like success = "./notify.sh", with
+FLY_APP, FLY_RELEASE_VERSION, FLY_IMAGE, FLY_DEPLOY_AUTHOR and
+FLY_DEPLOY_STATUS set. To announce deploys without a script, set webhook to a
+URL in a [deploy.notify] section to have the outcome posted to it as JSON, or
+slack to a Slack incoming webhook URL. A failing hook or notification is only a
+warning. With --detach or the immediate strategy flyctl doesn't see the deploy
+finish, so the hooks don't run and the webhooks are sent a deploy_started event.
+
+With --json, deploy writes one JSON object per line to stdout as it goes, each
+with ts, event and data fields, and prints everything else to stderr. The events
+are build_started, build, image_built, image_pushed, release_created,
+deployment_started (with --detach), allocation_healthy, allocation_failed,
+deploy_aborted, deploy_failed, deploy_succeeded and error. Each build event
+carries one step of the build progress, in the form --build-output json prints
+it.
+
+With --build-output json and without --json, stdout only carries the build
+progress, one JSON object per line, and everything else deploy prints goes to
+stderr.
+
+Use the --scan flag, or set scan = true in the [build] section of fly.toml, to
+scan the built image for vulnerabilities with Trivy before it's pushed. The
+deploy fails when any are found at --scan-severity (HIGH by default) or worse.
+Scans run a pinned Trivy release. To use another, set scan_image in the [build]
+section, --scan-image or FLY_SCAN_IMAGE, preferably pinned by digest like
+aquasec/trivy:0.55.2@sha256:...
+
+To mirror each deployed image to other registries, list them in the [build]
+section of fly.toml as push_to = ["ghcr.io/me/app:latest"]. Credentials are read
+from the Docker config.
+
+A push that fails with a network error is retried up to five times, waiting
+longer between each attempt. Layers the registry already has aren't sent again,
+so a retry picks up where the failed push left off instead of starting over.
+
+Images built with the local Docker daemon are pushed by flyctl, uploading
+eight layers at once where Docker uploads five. Use --push-concurrency to
+change how many. Remote builders push their images themselves, since they run
+next to the registry.
+
+Use --compression-level, or set compression_level in the [build] section of
+fly.toml, to trade size for speed when compressing the build context sent to a
+remote builder and the layers flyctl pushes. It's a gzip level from 1, the
+fastest, to 9, the smallest. Level 1 usually pushes multi-GB images faster on
+quick connections.
+
+The Dockerfile is found in the working directory, preferring Dockerfile.fly over
+Dockerfile so an app can keep a separate one for Fly. Set target = "runtime" in
+the [build] section to build one stage of a multi-stage Dockerfile. A process
+group can use its own Dockerfile.<group>, like Dockerfile.worker, and its own
+stage from the [build.targets] table, like worker = "worker-runtime".
+Set dockerfile = "docker/Dockerfile.prod" in the [build] section to use a
+Dockerfile elsewhere, relative to the directory being deployed.
+
+Use --template-values values.yaml to render fly.toml, and the files it
+includes, as a Go template before it's read, so one config can drive many
+similar apps. The values come from a YAML or JSON file:
+
+  app = "{{ .name }}-{{ .region }}"
+  primary_region = {{ quote .region }}
+  [env]
+    LOG_LEVEL = "{{ index . "log_level" | default "info" }}"
 
-Use flyctl monitor to restart monitoring deployment progress`,
+A value that isn't set is an error, unless it's looked up with index and given
+a fallback with default as above.
+
+Each entry of the [processes] section runs as its own process group, all of
+them created and updated by the same release. An entry is a command, like
+web = "bin/server", or a section with its own settings:
+
+  [processes.worker]
+    command = "bin/worker"
+    vm_size = "dedicated-cpu-1x"
+    count = 2
+    build_target = "worker"
+    secrets = ["AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"]
+    [processes.worker.env]
+      QUEUE = "default"
+
+A group with a build_target, or a [build.targets] entry, that differs from the
+app's target gets its own image, tagged after the app's. Groups without a count
+keep their current number of instances, and groups no longer listed are removed.
+A group's env is merged over [env] for its instances only, and the secrets it
+lists are only given to its instances, not to the other groups.
+
+When the app has a package-lock.json, yarn.lock, go.sum, Gemfile.lock or
+requirements.txt, the Dockerfile is checked for steps that stop the layer cache
+from being reused, like COPY . . before the dependencies are installed, and
+hints are shown before the build. After the build, a summary shows how many
+steps came from the layer cache and the first one that had to be rebuilt.
+
+Commands in the [build.hooks] section run around the build, in the directory
+being deployed: pre = "make assets" runs before the build starts and post =
+"./scripts/notify.sh $IMAGE" after the image is built. Both get the image
+reference in $IMAGE, and the post hook also gets $IMAGE_ID and $IMAGE_DIGEST.
+A failing hook fails the deploy.
+
+Private base images are pulled with the credentials saved by docker login,
+including ones kept by credential helpers such as osxkeychain or ecr-login.
+
+Use the --reproducible flag, or set reproducible = true in the [build] section of
+fly.toml, so builds of the same commit produce the same image digest. File times
+in the build context are clamped to SOURCE_DATE_EPOCH, or the time of the last git
+commit, and SOURCE_DATE_EPOCH is passed to the Dockerfile as a build argument.
+Steps that write their own timestamps need to honor SOURCE_DATE_EPOCH as well.
+
+New instances can be warmed up before they receive traffic. Each
+[[services.warmup]] entry in fly.toml is a request (path, method, headers, count,
+timeout and expected_status) sent to the service after its health checks pass.
+Deploy progress shows how many instances are still warming up.
+
+Use --ssh default to forward your SSH agent into the build, for Dockerfile steps
+like RUN --mount=type=ssh git clone git@github.com:me/private.git. This works
+with local and remote builders and needs BuildKit. Keys are never stored in the
+image or its build arguments.
+
+Use --build-memory and --build-cpus to cap local builds, like --build-memory 4gb
+--build-cpus 2, so a build doesn't take over a laptop or CI runner. Remote
+builders are sized to fit the limits. A build step killed for running out of
+memory fails with a hint to raise --build-memory.
+
+Apps with long-lived connections, like websockets, can deploy with the
+websocket strategy. A full set of new instances starts alongside the old ones
+and takes every new connection, while old instances keep serving the
+connections they already have. Each old instance is stopped once it has
+connection_threshold or fewer connections open, or when max_connection_wait
+passes. Set them in the [deploy] section of fly.toml:
+
+    [deploy]
+      strategy = "websocket"
+      connection_threshold = 10
+      max_connection_wait = "1h"
+
+While a deploy replaces instances, the monitor shows each instance of the
+previous release that is draining: how many connections it still has open and
+how much of its kill_timeout is left before it's killed. Use --drain-timeout,
+like --drain-timeout 60s, to give instances longer to finish open requests for
+this deploy without changing kill_timeout in fly.toml.
+
+To run local commands when a deploy finishes, like smoke tests, add exit hooks
+to ~/.fly/config.yml:
+
+    exit_hooks:
+      hooks:
+        - after: deploy
+          when: success
+          run: ./scripts/smoke-test.sh
+
+Hooks can run after any command, on success, failure or always. Hooks get
+FLY_COMMAND, FLY_EXIT_STATUS, FLY_APP and FLY_ERROR in their environment, and
+after a deploy FLY_RELEASE_VERSION and FLY_IMAGE. Use allow and deny lists of
+command names under exit_hooks to pick which commands run hooks. Set
+FLY_NO_EXIT_HOOKS=1 to skip all hooks.`,
+		}
+	case "deploys":
+		return KeyStrings{"deploys", "Approve or reject releases waiting for approval",
+			`Releases created with deploy --require-approvals, or for apps that
+always require approval, aren't deployed until another member of the
+organization approves them. Set --approval-webhook or FLY_APPROVAL_WEBHOOK to
+have each step posted as JSON to a URL, such as a Slack incoming webhook.`,
+		}
+	case "deploys.approve":
+		return KeyStrings{"approve <release-id | version>", "Approve a release and deploy it",
+			`Approves a release waiting for approval and starts deploying it. The
+release can be given by ID or by version, like v42. Releases can't be
+approved by the user who created them.`,
+		}
+	case "deploys.reject":
+		return KeyStrings{"reject <release-id | version>", "Reject a release so it's never deployed",
+			`Rejects a release waiting for approval. The release is never deployed
+and the app keeps running its current release.`,
 		}
 	case "destroy":
 		return KeyStrings{"destroy [APPNAME]", "Permanently destroys an App",
 			`The DESTROY command will remove an application 
-from the Fly platform.`,
+from the Fly platform.
+
+Before destroying, the volumes, certificates, IP addresses and attached
+postgres databases that go with the app are listed, and the app's name has to
+be typed to confirm. Use --dry-run to only list them, or --yes to skip the
+confirmation.`,
 		}
 	case "dns-records":
 		return KeyStrings{"dns-records", "Manage DNS records",
@@ -317,6 +836,10 @@ Deploy an App with the deploy command
 View a Deployed web application with the open command
 Check the status of an application with the status command
 
+Use --read-only, or set FLY_READ_ONLY=1, when screensharing or recording a
+session. Commands that make changes, like deploy, scale, secrets set and
+destroy, are refused so production can't be changed by accident.
+
 To read more, use the docs command to view Fly's help on the web.`,
 		}
 	case "history":
@@ -324,6 +847,90 @@ To read more, use the docs command to view Fly's help on the web.`,
 			`List the history of changes in the application. Includes autoscaling 
 events and their results.`,
 		}
+	case "hostnames":
+		return KeyStrings{"hostnames", "Manage the hostnames an app answers to",
+			`Manages hostnames for an app beyond its default .fly.dev hostname.
+Public hostnames are served by the app's services and need a certificate, see
+flyctl certs. Internal hostnames end with .internal and only resolve on the
+organization's private network.`,
+		}
+	case "hostnames.add":
+		return KeyStrings{"add <hostname>", "Add a hostname",
+			`Adds a hostname to the app. Use --internal to add an alias like
+api.internal that only resolves on the organization's private network.`,
+		}
+	case "hostnames.list":
+		return KeyStrings{"list", "List hostnames and what serves them",
+			`Lists every hostname of the app, including hostnames with
+certificates, along with the certificate covering each one and the service
+ports it reaches.`,
+		}
+	case "hostnames.remove":
+		return KeyStrings{"remove <hostname>", "Remove a hostname",
+			`Removes a hostname from the app. Certificates for the hostname
+are left in place, remove them with flyctl certs remove.`,
+		}
+	case "image":
+		return KeyStrings{"image", "Inspect app images",
+			`The IMAGE commands inspect the images deployed with an application's
+releases directly from the Fly registry, without pulling them locally.`,
+		}
+	case "image.diff":
+		return KeyStrings{"diff <from-version> <to-version>", "Compare the images of two releases",
+			`Compares the images deployed by two releases layer by layer. Shows
+which layers were added or removed, the files that were added, removed or changed
+size, and OS packages (dpkg and apk) that were installed, removed or upgraded.
+Releases are given by version number, e.g. "flyctl image diff v41 v42".`,
+		}
+	case "image.digest":
+		return KeyStrings{"digest [version]", "Print the digest of a release's image",
+			`Prints the content digest of the image of a release, the latest by
+default. Unlike tags, a digest always refers to the same image, so it can be
+used to deploy or pull exactly what a release ran.`,
+		}
+	case "image.history":
+		return KeyStrings{"history [version]", "Show the build history of a release's image",
+			`Lists the build steps recorded in the image of a release, the latest
+by default, newest first with the size of the layer each step added.`,
+		}
+	case "image.pin":
+		return KeyStrings{"pin <version|tag|digest>", "Deploy an exact image by digest",
+			`Creates a release running an exact image, referenced by digest so
+it can't change if a tag is pushed again. The image is given as a release
+version like v42, a deployment tag listed by "flyctl image tags", a digest like
+sha256:... from the app's repository, or a full image reference. The app's
+current configuration is kept, only the image changes. Like a deploy, it takes
+the app's deploy lock, see --wait-for-lock and --force-unlock.`,
+		}
+	case "image.push":
+		return KeyStrings{"push [version]", "Push a release's image to another registry",
+			`Copies the image of a release, the latest by default, from the Fly
+registry to other registries given with --registry, e.g.
+"flyctl image push --registry ghcr.io/me/app:v42 v42". Only layers the
+destination doesn't have yet are uploaded, in chunks, and a chunk that fails
+with a network error resumes from the last byte the destination received.
+Credentials for the destination are read from the Docker config, so log in with
+docker login first. Use --push-concurrency to change how many layers are
+copied at once, eight by default.`,
+		}
+	case "image.show":
+		return KeyStrings{"show [version]", "Show the image of a release",
+			`Shows the image of a release, the latest by default: its digest,
+platform, size, entrypoint and command, labels, and layers with the build step
+that created each one. Releases are given by version number, e.g. "flyctl image
+show v42".
+
+Images built by deploy also show their provenance: the builder that ran the
+build, the git commit it was built from and the names of its build args. It's
+stored as a SLSA provenance attestation under the image's sha256-<digest>.att
+tag, where tools like cosign can read it.`,
+		}
+	case "image.tags":
+		return KeyStrings{"tags", "List the deployment images pushed to the Fly registry",
+			`Lists the deployment-<timestamp> tags pushed to the app's repository
+in the Fly registry by previous deploys, newest first. Any of them can be
+deployed again with "flyctl image pin".`,
+		}
 	case "info":
 		return KeyStrings{"info", "Show detailed App information",
 			`Shows information about the application on the Fly platform
@@ -371,7 +978,16 @@ Fly network`,
 		}
 	case "launch":
 		return KeyStrings{"launch", "Launch a new app",
-			`Create and configure a new app from source code or an image reference.`,
+			`Create and configure a new app from source code or an image reference.
+
+Use --from-compose docker-compose.yml to launch each service of a compose file
+as its own app, named <name>-<service>. Each app gets a fly.toml next to the
+service's build context: build sections become Dockerfile builds, published
+ports become services, and the first named volume becomes a Fly volume mounted
+at the same path. Environment variables that look like credentials, such as
+*_PASSWORD, *_TOKEN or URLs with a password, are set as app secrets instead of
+being written to [env]. Bind mounts, env_file and anything else that can't run
+on Fly are reported as warnings. Services reach each other at <app>.internal.`,
 		}
 	case "list":
 		return KeyStrings{"list", "Lists your Fly resources",
@@ -406,7 +1022,10 @@ short name of the organization and the long name.`,
 the Fly platform.
 
 Logs can be filtered to a specific instance using the --instance/-i flag or 
-to all instances running in a specific region using the --region/-r flag.`,
+to all instances running in a specific region using the --region/-r flag.
+
+Use --selector/-l to follow the logs of every app with matching labels, like
+--selector env=staging. Each line is prefixed with its app.`,
 		}
 	case "monitor":
 		return KeyStrings{"monitor", "Monitor Deployments",
@@ -416,7 +1035,20 @@ to get details of every instance . Control-C to stop output.`,
 	case "move":
 		return KeyStrings{"move [APPNAME]", "Move an App to another organization",
 			`The MOVE command will move an application to another 
-organization the current user belongs to.`,
+organization the current user belongs to.
+
+Before moving, flyctl lists the IP addresses, certificates, volumes and secrets
+the app has. Afterwards it checks them again and reports anything that didn't
+follow the app to the new organization, with the command to replace it.`,
+		}
+	case "new":
+		return KeyStrings{"new [template] [directory]", "Create a new project from a template",
+			`Create a minimal project that's ready to deploy, with a Dockerfile, a fly.toml
+whose health check hits /healthz, and a GitHub Actions workflow that deploys on
+every push to main. Templates are go-api, node-express and rails.
+
+The project is created in a directory named after the template unless one is
+given. Run without arguments to list the templates.`,
 		}
 	case "open":
 		return KeyStrings{"open [PATH]", "Open browser to current deployed application",
@@ -486,7 +1118,46 @@ about the Fly platform.`,
 		}
 	case "postgres.attach":
 		return KeyStrings{"attach", "Attach a postgres cluster to an app",
-			`Attach a postgres cluster to an app`,
+			`Attaches a postgres cluster to an app. A database user is created
+for the app, and its connection string is added to the app as a secret named by
+--variable-name, DATABASE_URL by default. With --replica the connection string
+points at the cluster's read-only replicas, and the secret defaults to
+READONLY_DATABASE_URL, so an app can be attached both ways.`,
+		}
+	case "postgres.attachments":
+		return KeyStrings{"attachments", "List postgres attachments",
+			`Lists the attachments of an app to postgres clusters, or of apps
+to a cluster, with the database, user and secret of each.`,
+		}
+	case "postgres.backup":
+		return KeyStrings{"backup", "Back up and restore a postgres cluster",
+			`Commands for a postgres cluster's backups. Clusters are backed up
+daily, and on demand with CREATE.`,
+		}
+	case "postgres.backup.create":
+		return KeyStrings{"create <postgres-cluster-name>", "Back up a postgres cluster",
+			`Takes a backup of a postgres cluster's data while it keeps
+running, and waits for it to complete unless --detach is given.`,
+		}
+	case "postgres.backup.list":
+		return KeyStrings{"list <postgres-cluster-name>", "List a postgres cluster's backups",
+			`Lists a postgres cluster's backups, newest first, both
+scheduled and taken with CREATE.`,
+		}
+	case "postgres.backup.restore":
+		return KeyStrings{"restore <postgres-cluster-name> <backup-id>", "Restore a postgres cluster's backup",
+			`Restores a backup. With --new-cluster it's restored into a new
+cluster, set up with the same flags as CREATE, leaving the original untouched.
+Otherwise the cluster's data is replaced with the backup's after a confirmation,
+losing changes made since the backup was taken.`,
+		}
+	case "postgres.connect":
+		return KeyStrings{"connect <postgres-cluster-name>", "Connect to a postgres cluster from this machine",
+			`Opens a WireGuard tunnel to the cluster's organization and runs
+psql against the cluster's leader through it, without setting up a WireGuard
+peer. With --proxy, it listens on localhost:5432, or the --port given, and
+forwards connections to the leader until it's stopped, so GUI tools, migrations
+and other clients can connect to postgres://<user>@localhost:5432/<database>.`,
 		}
 	case "postgres.create":
 		return KeyStrings{"create", "Create a postgres cluster",
@@ -498,7 +1169,12 @@ about the Fly platform.`,
 		}
 	case "postgres.db.create":
 		return KeyStrings{"create <postgres-cluster-name>", "create a database in a cluster",
-			`create a database in a cluster`,
+			`create the database named by --name in a cluster`,
+		}
+	case "postgres.db.drop":
+		return KeyStrings{"drop <postgres-cluster-name>", "drop a database from a cluster",
+			`drop the database named by --name from a cluster, after a
+confirmation. databases still attached to an app can't be dropped.`,
 		}
 	case "postgres.db.list":
 		return KeyStrings{"list <postgres-cluster-name>", "list databases in a cluster",
@@ -506,19 +1182,60 @@ about the Fly platform.`,
 		}
 	case "postgres.detach":
 		return KeyStrings{"detach", "Detach a postgres cluster from an app",
-			`Detach a postgres cluster from an app`,
+			`Detaches a postgres cluster from an app, removing its secret and
+revoking the database user created for it, unless --keep-user is given. When
+the app is attached to the cluster more than once, pick the attachment with
+--variable-name.`,
+		}
+	case "postgres.import":
+		return KeyStrings{"import <postgres-cluster-name>", "Import an external database into a cluster",
+			`Copies the database at --source, like a Heroku or RDS database,
+into the cluster. A temporary VM on the cluster's private network runs pg_dump
+against the source and pg_restore into the cluster, so the data doesn't pass
+through this machine, and the progress is shown until it's done. The database
+is named after the source's unless --database is given, and --clean drops what's
+already in it first. The source can also be set with FLY_PG_IMPORT_SOURCE, to
+keep its password out of the shell history.`,
 		}
 	case "postgres.list":
 		return KeyStrings{"list", "list postgres clusters",
 			`list postgres clusters`,
 		}
+	case "postgres.metrics":
+		return KeyStrings{"metrics <postgres-cluster-name>", "Show connections, replication lag, disk usage and slow queries",
+			`Shows the health of each of a cluster's instances, with its open
+connections out of max_connections, how far a replica is behind the leader,
+how full its volume is, and how many queries took over a second in the last
+hour. Instances that are unhealthy, or close to running out of connections or
+disk, are called out below the table.`,
+		}
+	case "postgres.upgrade":
+		return KeyStrings{"upgrade <postgres-cluster-name>", "Upgrade a postgres cluster to a new major version",
+			`Upgrades a cluster to the major version given with --to. A new
+cluster running that version is created next to it, named <cluster>-pg<version>
+unless --name is given, and each database is copied to it with pg_dump and
+pg_restore. Then every app attached to the cluster is attached to the new one
+with the same database and secret name, which restarts it.
+
+The original cluster keeps running unchanged. Switch the apps back to it with
+--rollback <upgraded-cluster>, and destroy it once the upgrade is settled.
+Writes made while the databases are copied are lost, so stop the apps from
+writing first.`,
+		}
 	case "postgres.users":
 		return KeyStrings{"users", "manage users in a cluster",
 			`manage users in a cluster`,
 		}
 	case "postgres.users.create":
 		return KeyStrings{"create <postgres-cluster-name>", "create a user in a cluster",
-			`create a user in a cluster`,
+			`create the user named by --username in a cluster, with a
+generated password unless --password is given. the user can't use any database
+until it's granted access, unless it's created with --superuser.`,
+		}
+	case "postgres.users.grant":
+		return KeyStrings{"grant <postgres-cluster-name>", "give a user access to a database",
+			`give the user named by --username access to the database
+named by --database, to read and write or, with --readonly-access, only to read.`,
 		}
 	case "postgres.users.list":
 		return KeyStrings{"list <postgres-cluster-name>", "list users in a cluster",
@@ -526,7 +1243,20 @@ about the Fly platform.`,
 		}
 	case "regions":
 		return KeyStrings{"regions", "Manage regions",
-			`Configure the region placement rules for an application.`,
+			`Configure the region placement rules for an application.
+
+The [regions] section of fly.toml can also tune the app in particular regions.
+Each [regions.<code>] section sets environment variables merged over [env], a
+VM size, and concurrency limits for every service, applied by deploy when the
+release is created:
+
+  [regions.fra]
+    vm_size = "shared-cpu-2x"
+    [regions.fra.env]
+      DATABASE_URL = "postgres://fra.db.internal/app"
+    [regions.fra.concurrency]
+      soft_limit = 40
+      hard_limit = 50`,
 		}
 	case "regions.add":
 		return KeyStrings{"add REGION ...", "Allow the app to run in the provided regions",
@@ -538,7 +1268,23 @@ about the Fly platform.`,
 		}
 	case "regions.list":
 		return KeyStrings{"list", "Shows the list of regions the app is allowed to run in",
-			`Shows the list of regions the app is allowed to run in.`,
+			`Shows the list of regions the app is allowed to run in, and the regions
+process groups are pinned to.`,
+		}
+	case "regions.pin":
+		return KeyStrings{"pin [<group> REGION ...]", "Pin a process group to a set of regions",
+			`Pins a process group to a set of regions, like "flyctl regions pin
+worker iad" to keep workers near a database while the rest of the app runs in
+the whole region pool. Without arguments, pins every process group listed in
+the [regions] section of fly.toml:
+
+  [regions]
+    app = ["iad", "lhr"]
+    worker = ["iad"]
+
+Pins are checked before they're applied. When the app mounts a volume, every
+pinned region needs one and each instance needs its own. A group with fewer
+instances than regions is warned about, since some regions won't run it.`,
 		}
 	case "regions.remove":
 		return KeyStrings{"remove REGION ...", "Prevent the app from running in the provided regions",
@@ -548,14 +1294,105 @@ about the Fly platform.`,
 		return KeyStrings{"set REGION ...", "Sets the region pool with provided regions",
 			`Sets the region pool with provided regions`,
 		}
+	case "registry":
+		return KeyStrings{"registry", "Manage images in the Fly registry",
+			`Commands for managing the images deploys push to the Fly registry.`,
+		}
+	case "registry.credential-helper":
+		return KeyStrings{"credential-helper <get|list|store|erase>", "Docker credential helper for the Fly registry",
+			`Implements the docker credential helper protocol for the Fly registry,
+run by docker through the script written by registry login.`,
+		}
+	case "registry.login":
+		return KeyStrings{"login", "Let docker use your Fly credentials for the registry",
+			`Install flyctl as a docker credential helper for the Fly registry, so
+docker commands like docker pull registry.fly.io/<app>:<tag> work without
+docker login. A docker-credential-flyctl script is written next to flyctl, or
+to ~/.fly/bin when that isn't writable, and registered in the docker config.
+
+Docker asks the helper for credentials each time it needs them, so they always
+use the current auth token and follow flyctl auth login and logout.`,
+		}
+	case "registry.prune":
+		return KeyStrings{"prune", "Remove old deployment images of the app",
+			`Every deploy pushes a new deployment-<timestamp> image that's kept until
+it's removed. Prune removes all but the newest --keep of them, 10 by default.
+The image of the current release is always kept, as is any image that's also
+tagged by a kept one. Tags other than deployment-<timestamp> are left alone.
+
+Use --dry-run to list the images that would be removed.`,
+		}
+	case "registry.retention":
+		return KeyStrings{"retention", "Manage the organization's registry retention policy",
+			`A retention policy has old deployment images of every app in the
+organization removed automatically, the way registry prune does, so they don't
+build up.`,
+		}
+	case "registry.retention.set":
+		return KeyStrings{"set <keep>", "Set how many deployment images each app keeps",
+			`Keep the newest <keep> deployment images of each app in the organization
+and have older ones removed automatically. The image of each app's current
+release is always kept. Use 0 to remove the policy and keep every image.`,
+		}
+	case "registry.retention.show":
+		return KeyStrings{"show", "Show the organization's retention policy",
+			`Show how many deployment images are kept for each app in the organization.`,
+		}
 	case "releases":
 		return KeyStrings{"releases", "List App releases",
 			`List all the releases of the application onto the Fly platform, 
 including type, when, success/fail and which user triggered the release.`,
 		}
+	case "releases.cancel":
+		return KeyStrings{"cancel [version]", "Remove the instances of a release waiting to be promoted",
+			`Cancel a blue-green deployment that's waiting to be promoted. Its new
+instances are removed and the previous release keeps serving traffic, without
+ever having been interrupted.`,
+		}
+	case "releases.diff":
+		return KeyStrings{"diff [from-version] <to-version>", "Show what changed between two releases",
+			`Show what changed between two releases: the image, by digest when
+it's still in the registry, environment variables, the other fly.toml settings,
+and which secrets were set, unset or changed. Secret values are never shown.
+With one version, like v42, it's compared to the release before it.`,
+		}
+	case "releases.promote":
+		return KeyStrings{"promote [version]", "Switch traffic to a release waiting to be promoted",
+			`Switch traffic to the new instances of a blue-green deployment started
+with --manual-promote, or manual_promote in the [deploy] section of fly.toml.
+The old instances are stopped once traffic has moved. The version, like v42, is
+optional and guards against promoting a different release than expected.`,
+		}
+	case "releases.rollback":
+		return KeyStrings{"rollback [version]", "Roll back to a previous release",
+			`Create a release with the image and configuration of a previous release,
+by default the newest stable release before the current one. Give a version
+like v42 to roll back further. The image is deployed by digest, so it's exactly
+the image that release ran even if its tag has been pushed again since.
+It fails while a deploy of the app holds the deploy lock, unless --wait-for-lock
+or --force-unlock is given.`,
+		}
+	case "releases.watch":
+		return KeyStrings{"watch [deployment-id|version]", "Show the progress of a running deployment",
+			`Attach to a deployment, like one started with deploy --detach, and show
+its progress until it finishes. The deployment is given by the ID printed by
+deploy --detach, or by its release version, like v42. Without either, the app's
+latest deployment is watched. Exits with an error when the deployment fails,
+and prints newline-delimited JSON events with --json, like deploy does.`,
+		}
 	case "restart":
 		return KeyStrings{"restart [APPNAME]", "Restart an application",
-			`The RESTART command will restart all running vms.`,
+			`The RESTART command will restart all running vms. 
+
+To restart on a schedule instead, add a [restart] section to fly.toml. Instances
+are restarted one at a time when the cron schedule fires, in each region's local
+time unless a timezone is set. Scheduled restarts are skipped while any instance
+is failing health checks, unless skip_if_unhealthy is false.
+
+  [restart]
+    schedule = "0 4 * * *"
+    timezone = "region"
+    skip_if_unhealthy = true`,
 		}
 	case "resume":
 		return KeyStrings{"resume [APPNAME]", "Resume an application",
@@ -606,12 +1443,46 @@ For pricing, see https://fly.io/docs/about/pricing/`,
 
 Secrets are provided to applications at runtime as ENV variables. Names are
 case sensitive and stored as-is, so ensure names are appropriate for
-the application and vm environment.`,
+the application and vm environment.
+
+List the secrets an app can't run without in the [secrets] section of
+fly.toml, and deploy fails before building anything when one isn't set:
+
+  [secrets]
+    required = ["DATABASE_URL", "SECRET_KEY_BASE"]`,
+		}
+	case "secrets.deploy":
+		return KeyStrings{"deploy", "Release staged secrets",
+			`Creates a release with the secrets staged by SET, IMPORT and
+UNSET with --stage, restarting the app once for all of them. A regular deploy
+releases staged secrets too. SECRETS LIST marks the staged secrets. The
+release waits for the app's deploy lock with --wait-for-lock, as deploys do.`,
+		}
+	case "secrets.diff":
+		return KeyStrings{"diff <file>", "Compare a .env file with the app's secrets",
+			`Compares the secrets in a .env file with the app's, listing the
+names only in the file, only on the app, and set on both with different
+values. Values are compared by digest, so they're never sent or shown. Use
+--exit-code to fail with status 1 when there are differences, like before a
+deploy in CI.`,
+		}
+	case "secrets.generate":
+		return KeyStrings{"generate [flags] NAME NAME ...", "Set secrets to random values",
+			`Sets each named secret to a cryptographically random value generated
+locally, like a framework's SECRET_KEY_BASE after a launch. Values are never
+printed, so they only exist on the app. --length sets the number of characters
+and --format the encoding: hex, base64 (URL safe, without padding) or
+alphanumeric. Secrets that are already set are left alone unless --force is
+given, since replacing a signing key invalidates what it signed.`,
 		}
 	case "secrets.import":
-		return KeyStrings{"import [flags]", "Read secrets in name=value from stdin",
-			`Set one or more encrypted secrets for an application. Values
-are read from stdin as name=value`,
+		return KeyStrings{"import [flags]", "Read secrets from a .env file",
+			`Set encrypted secrets for an application from a .env file,
+read from stdin or from the file given with --from-dotenv. Lines are
+name=value, optionally starting with export, and lines starting with # are
+comments. Values can be single quoted to keep them as written, or double
+quoted to use escapes like \n, and both can span lines for values like
+certificates. All the secrets are set together in a single release.`,
 		}
 	case "secrets.list":
 		return KeyStrings{"list", "Lists the secrets available to the App",
@@ -627,7 +1498,52 @@ Secrets are provided to application at runtime as ENV variables. Names are
 case sensitive and stored as-is, so ensure names are appropriate for
 the application and vm environment.
 
-Any value that equals "-" will be assigned from STDIN instead of args.`,
+Any value that equals "-" will be assigned from STDIN instead of args.
+Values starting with @ are read from the file at the path that follows, like
+TLS_KEY=@server.key, without its trailing newline, so multi-line secrets like
+PEM keys and service account JSON need no shell quoting. Write @@ for a value
+that really starts with @. --stdin sets the single NAME given from STDIN.
+Values from STDIN and files can be up to 64KB.
+
+Each change creates a release that restarts the app, holding the app's deploy
+lock like deploys do, so it fails while a deploy is running unless
+--wait-for-lock is set. Use --stage to record secrets without restarting, then
+release them all at once with the next deploy or SECRETS DEPLOY.
+
+--process scopes the secrets to one or more process groups, like
+--process worker for credentials only the worker needs. Other groups' instances
+don't get them. SECRETS LIST shows the groups of scoped secrets.
+
+With --encrypt-with, values are encrypted locally for one or more age
+recipients (age1... public keys from age-keygen) and only the ciphertext is
+sent to Fly. The application receives the ASCII armored ciphertext in the
+ENV variable and decrypts it at startup with the matching identity, which
+has to be provided some other way, for example baked into a private image
+or fetched from your own KMS. For example, in an entrypoint script:
+
+    export DATABASE_URL="$(printenv DATABASE_URL | age -d -i /keys/identity.txt)"
+
+The FLY_SECRETS_ENCRYPT_WITH environment variable sets default recipients.`,
+		}
+	case "secrets.sync":
+		return KeyStrings{"sync", "Set secrets from an external secret manager",
+			`Reads secrets from AWS Secrets Manager, GCP Secret Manager or
+Vault and sets them on the app in a single release, for teams that keep their
+secrets there. Secrets are read with the aws, gcloud and vault command line
+tools, using their current login, profile and project.
+
+For aws and gcp, --source is a name prefix. Each secret whose name starts with
+it is set under the rest of its name, upper cased with other characters
+replaced by underscores, so myapp/db-url becomes DB_URL. Secrets holding a JSON
+object of strings set each of its keys instead, named the same way. For vault,
+--source is the path of a KV secret whose keys are set, or a folder ending with
+/ to set the keys of every secret in it.
+
+Nothing is set when a name comes out empty, two secrets give the same name, or
+a value is a nested object or null.
+
+Use --dry-run to list the secrets without setting them, and --stage to release
+them later.`,
 		}
 	case "secrets.unset":
 		return KeyStrings{"unset [flags] NAME NAME ...", "Remove encrypted secrets from an App",
@@ -667,7 +1583,10 @@ WireGuard region to use for the connection.`,
 		return KeyStrings{"status", "Show App status",
 			`Show the application's current status including application 
 details, tasks, most recent deployment details and in which regions it is 
-currently allocated.`,
+currently allocated.
+
+Use --selector/-l to show the status of every app with matching labels, like
+--selector team=payments.`,
 		}
 	case "status.instance":
 		return KeyStrings{"instance [instance-id]", "Show instance status",
@@ -711,6 +1630,14 @@ command to update the application.`,
 		return KeyStrings{"volumes <command>", "Volume management commands",
 			`Commands for managing Fly Volumes associated with an application.`,
 		}
+	case "volumes.clone":
+		return KeyStrings{"clone <id>", "Copy a volume to another region",
+			`Snapshots a volume and restores the snapshot to a new volume in the
+region given with --region, the same size as the original. The copy keeps the
+volume's name unless --name is given, so instances the app starts in the new
+region mount it, which is how a stateful app moves regions. Writes made after
+the snapshot aren't copied.`,
+		}
 	case "volumes.create":
 		return KeyStrings{"create <volumename>", "Create new volume for app",
 			`Create new volume for app. --region flag must be included to specify
@@ -722,14 +1649,82 @@ sets the size as the number of gigabytes the volume will consume.`,
 			`Delete a volume from the application. Requires the volume's ID
 number to operate. This can be found through the volumes list command`,
 		}
+	case "volumes.export":
+		return KeyStrings{"export <id> --to s3://<bucket>/<path>", "Archive a volume to object storage",
+			`Streams a tar.gz archive of a volume's contents to S3, or an S3
+compatible store given with --endpoint, for backups that live off Fly. The
+archive is made from a snapshot taken when the export starts, by a temporary VM,
+so it's consistent while the app keeps running. The archive is sent as a
+multipart upload, so its size isn't limited to the 5GB of a single upload. The
+credentials in AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN,
+or the aws CLI's login, and AWS_REGION or the CLI's region, start the upload
+and sign the URLs the VM uploads the parts with; the credentials stay on this
+machine. The URLs work for 12 hours, or until temporary credentials expire when
+that's sooner (from AWS_CREDENTIAL_EXPIRATION or the CLI's login), so the
+export has to finish by then. A --to URL ending with / gets a name made from
+the volume ID and the time.`,
+		}
+	case "volumes.extend":
+		return KeyStrings{"extend <id>", "Grow a volume",
+			`Grows a volume to the size given with --size, in gigabytes,
+keeping its data. The filesystem of the instance the volume is attached to is
+resized while it runs. When that isn't possible the new space shows up after the
+instance restarts. Volumes can't be shrunk.`,
+		}
+	case "volumes.import":
+		return KeyStrings{"import <volumename> --from s3://<bucket>/<path>", "Restore a volume archive from object storage",
+			`Creates a new volume for the app from an archive made by EXPORT,
+streamed in by a temporary VM through a URL signed with the same AWS
+credentials, with the same time limit. --region is required. Existing volumes are never overwritten.`,
+		}
 	case "volumes.list":
 		return KeyStrings{"list", "List the volumes for app",
-			`List all the volumes associated with this application.`,
+			`List all the volumes associated with this application, with
+the instance each is attached to and how much of it is used. --json lists them
+as JSON, including used, free and total bytes.`,
 		}
 	case "volumes.show":
 		return KeyStrings{"show <id>", "Show details of an app's volume",
-			`Show details of an app's volume. Requires the volume's ID
-number to operate. This can be found through the volumes list command`,
+			`Show details of an app's volume, including the instance it's
+attached to and its used and free space, as last reported by that instance.
+Requires the volume's ID number to operate. This can be found through the
+volumes list command`,
+		}
+	case "volumes.snapshots":
+		return KeyStrings{"snapshots", "Manage volume snapshots",
+			`Commands for working with snapshots of an app's volumes.`,
+		}
+	case "volumes.snapshots.create":
+		return KeyStrings{"create <volume-id>", "Snapshot a volume",
+			`Takes a point-in-time snapshot of a volume's contents. The volume
+stays attached and in use while it's taken.`,
+		}
+	case "volumes.snapshots.download":
+		return KeyStrings{"download <snapshot-id>", "Download a volume snapshot as a disk image",
+			`Downloads a snapshot as a compressed disk image, for inspecting it
+locally or moving the data off Fly. The download is fetched in chunks and
+checked against the snapshot's checksum. An interrupted download resumes
+where it stopped when the same command is run again.`,
+		}
+	case "volumes.snapshots.list":
+		return KeyStrings{"list <volume-id>", "List a volume's snapshots",
+			`Lists the snapshots of a volume, newest first, with their sizes
+and digests.`,
+		}
+	case "volumes.snapshots.restore":
+		return KeyStrings{"restore <snapshot-id> <volumename>", "Create a volume from a snapshot",
+			`Creates a new volume for the app holding a snapshot's contents.
+--region is required and can be any region, not just the snapshotted volume's,
+so snapshots also move data between regions. --size defaults to the size of
+the snapshot's volume and can only make the new volume larger.`,
+		}
+	case "volumes.snapshots.schedule":
+		return KeyStrings{"schedule <volume-id>", "Show or set a volume's automatic snapshots",
+			`Shows how often a volume is snapshotted automatically and how
+many of those snapshots are kept, and whether that's the platform's default.
+Set the volume's own schedule with --hourly, --daily or --weekly and --retain,
+change only the retention with --retain, or stop automatic snapshots with
+--off.`,
 		}
 	case "wireguard":
 		return KeyStrings{"wireguard <command>", "Commands that manage WireGuard peer connections",
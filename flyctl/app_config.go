@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
 	"path"
 	"path/filepath"
@@ -19,6 +20,8 @@ type ConfigFormat string
 
 const (
 	TOMLFormat        ConfigFormat = ".toml"
+	YAMLFormat        ConfigFormat = ".yaml"
+	JSONFormat        ConfigFormat = ".json"
 	UnsupportedFormat              = ""
 )
 
@@ -26,6 +29,18 @@ type AppConfig struct {
 	AppName    string
 	Build      *Build
 	Definition map[string]interface{}
+	// Environment is the environment, like staging, the config was loaded for. Empty by default.
+	Environment string
+	// Environments holds the [environments] section, settings merged over the rest of the config
+	// for each named environment
+	Environments map[string]interface{}
+	// Includes are the files the config's include setting merged it over, resolved to absolute
+	// paths in the order they were merged
+	Includes []string
+
+	// templateValues render the config files as Go templates when they're loaded, nil when they're
+	// loaded as they are
+	templateValues map[string]interface{}
 }
 
 type Build struct {
@@ -37,6 +52,36 @@ type Build struct {
 	Settings map[string]interface{}
 	// Or...
 	Image string
+	// Timeout limits the total build time, no limit when zero
+	Timeout time.Duration
+	// BuilderTimeout limits how long to wait for a remote builder to become ready
+	BuilderTimeout time.Duration
+	// Scan enables scanning built images for vulnerabilities at ScanSeverity or worse
+	Scan         bool
+	ScanSeverity string
+	// ScanImage overrides the trivy image used to scan, best pinned by digest
+	ScanImage string
+	// Reproducible normalizes builds so the same commit produces the same image digest
+	Reproducible bool
+	// PushTo lists other registries the built image is pushed to, like ghcr.io/me/app:latest
+	PushTo []string
+	// Target is the multi-stage Dockerfile stage to build, overridden per process group by Targets
+	Target  string
+	Targets map[string]string
+	// Dockerfile is the path to the Dockerfile, relative to the directory being deployed
+	Dockerfile string
+	// Hooks are shell commands run before and after the image is built
+	Hooks BuildHooks
+	// CompressionLevel is the gzip level, 1 to 9, for build contexts and the layers flyctl pushes.
+	// Zero uses gzip's default.
+	CompressionLevel int
+}
+
+// BuildHooks is the [build.hooks] section. Commands run in the directory being deployed with
+// the image reference in $IMAGE.
+type BuildHooks struct {
+	Pre  string
+	Post string
 }
 
 func NewAppConfig() *AppConfig {
@@ -46,29 +91,65 @@ func NewAppConfig() *AppConfig {
 }
 
 func LoadAppConfig(configFile string) (*AppConfig, error) {
+	return loadAppConfig(configFile, "", nil)
+}
+
+func loadAppConfig(configFile, environment string, templateValues map[string]interface{}) (*AppConfig, error) {
 	fullConfigFilePath, err := filepath.Abs(configFile)
 	if err != nil {
 		return nil, err
 	}
 
 	appConfig := AppConfig{
-		Definition: map[string]interface{}{},
+		Definition:     map[string]interface{}{},
+		Environment:    environment,
+		templateValues: templateValues,
 	}
 
-	file, err := os.Open(fullConfigFilePath)
+	data, err := appConfig.decodeConfigFile(fullConfigFilePath)
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
 
-	switch ConfigFormatFromPath(fullConfigFilePath) {
+	if data, err = appConfig.applyIncludes(fullConfigFilePath, data, nil); err != nil {
+		return nil, err
+	}
+
+	err = appConfig.unmarshalNativeMap(data)
+
+	return &appConfig, err
+}
+
+// decodeConfigFile reads the raw settings of a config file in any of the supported formats,
+// rendering it as a template first when the config is loaded with template values
+func (ac *AppConfig) decodeConfigFile(path string) (map[string]interface{}, error) {
+	source, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if ac.templateValues != nil {
+		if source, err = RenderConfigTemplate(path, source, ac.templateValues); err != nil {
+			return nil, err
+		}
+	}
+
+	var data map[string]interface{}
+	switch ConfigFormatFromPath(path) {
 	case TOMLFormat:
-		err = appConfig.unmarshalTOML(file)
+		data, err = decodeTOML(bytes.NewReader(source))
+	case YAMLFormat:
+		data, err = decodeYAML(bytes.NewReader(source))
+	case JSONFormat:
+		data, err = decodeJSON(bytes.NewReader(source))
 	default:
 		return nil, errors.New("Unsupported config file format")
 	}
 
-	return &appConfig, err
+	if err != nil && ac.templateValues == nil && bytes.Contains(source, []byte("{{")) {
+		return nil, fmt.Errorf("%w, the file looks like a template that needs rendering with --template-values", err)
+	}
+	return data, err
 }
 
 func (ac *AppConfig) HasDefinition() bool {
@@ -83,26 +164,55 @@ func (ac *AppConfig) HasBuiltin() bool {
 	return ac.Build != nil && ac.Build.Builtin != ""
 }
 
+// BuildTarget returns the Dockerfile stage to build for a process group, falling back to
+// [build] target. An empty processGroup is the app's default image.
+func (ac *AppConfig) BuildTarget(processGroup string) string {
+	if ac.Build == nil {
+		return ""
+	}
+	if target, ok := ac.Build.Targets[processGroup]; ok && processGroup != "" {
+		return target
+	}
+	return ac.Build.Target
+}
+
 func (ac *AppConfig) WriteTo(w io.Writer, format ConfigFormat) error {
 	switch format {
 	case TOMLFormat:
 		return ac.marshalTOML(w)
+	case YAMLFormat:
+		return ac.marshalYAML(w)
+	case JSONFormat:
+		return ac.marshalJSON(w)
 	}
 
 	return fmt.Errorf("Unsupported format: %s", format)
 }
 
 func (ac *AppConfig) unmarshalTOML(r io.Reader) error {
+	data, err := decodeTOML(r)
+	if err != nil {
+		return err
+	}
+
+	return ac.unmarshalNativeMap(data)
+}
+
+func decodeTOML(r io.Reader) (map[string]interface{}, error) {
 	var data map[string]interface{}
 
 	if _, err := toml.DecodeReader(r, &data); err != nil {
-		return err
+		return nil, err
 	}
 
-	return ac.unmarshalNativeMap(data)
+	return data, nil
 }
 
 func (ac *AppConfig) unmarshalNativeMap(data map[string]interface{}) error {
+	if err := ac.applyEnvironment(data); err != nil {
+		return err
+	}
+
 	if appName, ok := (data["app"]).(string); ok {
 		ac.AppName = appName
 	}
@@ -146,13 +256,92 @@ func (ac *AppConfig) unmarshalNativeMap(data map[string]interface{}) error {
 			case "image":
 				b.Image = fmt.Sprint(v)
 				insection = true
+			case "timeout", "builder_timeout":
+				d, err := time.ParseDuration(fmt.Sprint(v))
+				if err != nil || d <= 0 {
+					return fmt.Errorf("build.%s must be a positive duration like \"30m\", got %v", k, v)
+				}
+				if k == "timeout" {
+					b.Timeout = d
+				} else {
+					b.BuilderTimeout = d
+				}
+				insection = true
+			case "scan":
+				scan, ok := v.(bool)
+				if !ok {
+					return fmt.Errorf("build.scan must be true or false, got %v", v)
+				}
+				b.Scan = scan
+				insection = true
+			case "scan_severity":
+				b.ScanSeverity = fmt.Sprint(v)
+				insection = true
+			case "scan_image":
+				b.ScanImage = fmt.Sprint(v)
+				insection = true
+			case "reproducible":
+				reproducible, ok := v.(bool)
+				if !ok {
+					return fmt.Errorf("build.reproducible must be true or false, got %v", v)
+				}
+				b.Reproducible = reproducible
+				insection = true
+			case "push_to":
+				refs, ok := v.([]interface{})
+				if !ok {
+					return fmt.Errorf("build.push_to must be a list of image references, got %v", v)
+				}
+				for _, ref := range refs {
+					b.PushTo = append(b.PushTo, fmt.Sprint(ref))
+				}
+				insection = true
+			case "target":
+				b.Target = fmt.Sprint(v)
+				insection = true
+			case "dockerfile":
+				b.Dockerfile = fmt.Sprint(v)
+				insection = true
+			case "targets":
+				targetMap, ok := v.(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("build.targets must be a table of process group names to targets, got %v", v)
+				}
+				b.Targets = map[string]string{}
+				for group, target := range targetMap {
+					b.Targets[group] = fmt.Sprint(target)
+				}
+				insection = true
+			case "compression_level":
+				level, ok := toInt(v)
+				if !ok || level < 1 || level > 9 {
+					return fmt.Errorf("build.compression_level must be a number from 1 (fastest) to 9 (smallest), got %v", v)
+				}
+				b.CompressionLevel = level
+				insection = true
+			case "hooks":
+				hookMap, ok := v.(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("build.hooks must be a table with pre and post commands, got %v", v)
+				}
+				for stage, command := range hookMap {
+					switch stage {
+					case "pre":
+						b.Hooks.Pre = fmt.Sprint(command)
+					case "post":
+						b.Hooks.Post = fmt.Sprint(command)
+					default:
+						return fmt.Errorf("build.hooks.%s is not a hook, use pre or post", stage)
+					}
+				}
+				insection = true
 			default:
 				if !insection {
 					b.Args[k] = fmt.Sprint(v)
 				}
 			}
 		}
-		if b.Builder != "" || b.Builtin != "" || b.Image != "" || len(b.Args) > 0 {
+		if b.Builder != "" || b.Builtin != "" || b.Image != "" || len(b.Args) > 0 || b.Timeout > 0 || b.BuilderTimeout > 0 || b.Scan || b.Reproducible || len(b.PushTo) > 0 || b.Target != "" || len(b.Targets) > 0 || b.Dockerfile != "" || b.Hooks != (BuildHooks{}) || b.CompressionLevel > 0 {
 			ac.Build = &b
 		}
 	}
@@ -177,42 +366,19 @@ func (ac AppConfig) marshalTOML(w io.Writer) error {
 		return err
 	}
 
-	rawData = ac.Definition
+	data := ac.marshalMap()
 
-	if ac.Build != nil {
-		buildData := map[string]interface{}{}
-		if ac.Build.Builder != "" {
-			buildData["builder"] = ac.Build.Builder
-		}
-		if len(ac.Build.Buildpacks) > 0 {
-			buildData["buildpacks"] = ac.Build.Buildpacks
-		}
-		if len(ac.Build.Args) > 0 {
-			buildData["args"] = ac.Build.Args
-		}
-		if ac.Build.Builtin != "" {
-			buildData["builtin"] = ac.Build.Builtin
-			if len(ac.Build.Settings) > 0 {
-				buildData["settings"] = ac.Build.Settings
-			}
-		}
-		if ac.Build.Image != "" {
-			buildData["image"] = ac.Build.Image
-		}
-		rawData["build"] = buildData
-	}
-
-	if len(ac.Definition) > 0 {
+	if len(data) > 0 {
 		// roundtrip through json encoder to convert float64 numbers to json.Number, otherwise numbers are floats in toml
 		var buf bytes.Buffer
-		json.NewEncoder(&buf).Encode(ac.Definition)
+		json.NewEncoder(&buf).Encode(data)
 		d := json.NewDecoder(&buf)
 		d.UseNumber()
-		if err := d.Decode(&ac.Definition); err != nil {
+		if err := d.Decode(&data); err != nil {
 			return err
 		}
 
-		if err := encoder.Encode(ac.Definition); err != nil {
+		if err := encoder.Encode(data); err != nil {
 			return err
 		}
 	}
@@ -220,6 +386,88 @@ func (ac AppConfig) marshalTOML(w io.Writer) error {
 	return nil
 }
 
+// marshalMap returns the config's settings as they're written to a config file, without the app name
+func (ac AppConfig) marshalMap() map[string]interface{} {
+	data := make(map[string]interface{}, len(ac.Definition)+2)
+	for k, v := range ac.Definition {
+		data[k] = v
+	}
+	if ac.Build != nil {
+		data["build"] = ac.Build.marshalMap()
+	}
+	if len(ac.Environments) > 0 && ac.Environment == "" {
+		data["environments"] = ac.Environments
+	}
+	return data
+}
+
+// marshalMap returns the [build] section's settings as they're written to a config file
+func (b *Build) marshalMap() map[string]interface{} {
+	buildData := map[string]interface{}{}
+	if b.Builder != "" {
+		buildData["builder"] = b.Builder
+	}
+	if len(b.Buildpacks) > 0 {
+		buildData["buildpacks"] = b.Buildpacks
+	}
+	if len(b.Args) > 0 {
+		buildData["args"] = b.Args
+	}
+	if b.Builtin != "" {
+		buildData["builtin"] = b.Builtin
+		if len(b.Settings) > 0 {
+			buildData["settings"] = b.Settings
+		}
+	}
+	if b.Image != "" {
+		buildData["image"] = b.Image
+	}
+	if b.Timeout > 0 {
+		buildData["timeout"] = b.Timeout.String()
+	}
+	if b.BuilderTimeout > 0 {
+		buildData["builder_timeout"] = b.BuilderTimeout.String()
+	}
+	if b.Scan {
+		buildData["scan"] = true
+	}
+	if b.ScanSeverity != "" {
+		buildData["scan_severity"] = b.ScanSeverity
+	}
+	if b.ScanImage != "" {
+		buildData["scan_image"] = b.ScanImage
+	}
+	if b.Reproducible {
+		buildData["reproducible"] = true
+	}
+	if len(b.PushTo) > 0 {
+		buildData["push_to"] = b.PushTo
+	}
+	if b.Target != "" {
+		buildData["target"] = b.Target
+	}
+	if len(b.Targets) > 0 {
+		buildData["targets"] = b.Targets
+	}
+	if b.Dockerfile != "" {
+		buildData["dockerfile"] = b.Dockerfile
+	}
+	if b.CompressionLevel > 0 {
+		buildData["compression_level"] = b.CompressionLevel
+	}
+	if b.Hooks != (BuildHooks{}) {
+		hooks := map[string]string{}
+		if b.Hooks.Pre != "" {
+			hooks["pre"] = b.Hooks.Pre
+		}
+		if b.Hooks.Post != "" {
+			hooks["post"] = b.Hooks.Post
+		}
+		buildData["hooks"] = hooks
+	}
+	return buildData
+}
+
 func (ac *AppConfig) WriteToFile(filename string) error {
 	if err := helpers.MkdirAll(filename); err != nil {
 		return err
@@ -316,6 +564,19 @@ func (ac *AppConfig) SetEnvVariable(name, value string) {
 
 const defaultConfigFileName = "fly.toml"
 
+// configFileNames are the config files looked for in a directory, in order
+var configFileNames = []string{defaultConfigFileName, "fly.yaml", "fly.yml", "fly.json"}
+
+// findConfigFile returns the first config file in dir, or fly.toml when there's none
+func findConfigFile(dir string) string {
+	for _, name := range configFileNames {
+		if p := path.Join(dir, name); helpers.FileExists(p) {
+			return p
+		}
+	}
+	return path.Join(dir, defaultConfigFileName)
+}
+
 func ResolveConfigFileFromPath(p string) (string, error) {
 	p, err := filepath.Abs(p)
 	if err != nil {
@@ -327,6 +588,10 @@ func ResolveConfigFileFromPath(p string) (string, error) {
 
 	if err != nil {
 		if os.IsNotExist(err) {
+			// the default fly.toml falls back to the other formats
+			if path.Base(p) == defaultConfigFileName {
+				return findConfigFile(path.Dir(p)), nil
+			}
 			return p, nil
 		}
 		return "", err
@@ -334,7 +599,7 @@ func ResolveConfigFileFromPath(p string) (string, error) {
 
 	// Ok, something exists. Is it a file - yes? return the path
 	if pd.IsDir() {
-		return path.Join(p, defaultConfigFileName), nil
+		return findConfigFile(p), nil
 	}
 
 	return p, nil
@@ -344,6 +609,10 @@ func ConfigFormatFromPath(p string) ConfigFormat {
 	switch path.Ext(p) {
 	case ".toml":
 		return TOMLFormat
+	case ".yaml", ".yml":
+		return YAMLFormat
+	case ".json":
+		return JSONFormat
 	}
 	return UnsupportedFormat
 }
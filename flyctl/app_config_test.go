@@ -1,10 +1,15 @@
 package flyctl
 
 import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/BurntSushi/toml"
 	"github.com/stretchr/testify/assert"
+	"github.com/superfly/flyctl/api"
 )
 
 func TestLoadTOMLAppConfigWithAppName(t *testing.T) {
@@ -40,3 +45,418 @@ func TestLoadTOMLAppConfigWithServices(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, p.Definition, rawData)
 }
+
+func TestLoadTOMLAppConfigWithRoutes(t *testing.T) {
+	path := "./testdata/routes.toml"
+	p, err := LoadAppConfig(path)
+	assert.NoError(t, err)
+
+	routes, errs := p.Routes()
+	assert.Equal(t, []Route{
+		{InternalPort: 8080, Path: "/webhooks", QueueTimeout: 60 * time.Second},
+		{InternalPort: 8080, Path: "/health", ExcludeFromConcurrency: true},
+	}, routes)
+	assert.Equal(t, []string{
+		"services[0].routes[2]: path must start with /",
+		"services[0].routes[2]: soft_limit can't be greater than hard_limit",
+	}, errorStrings(errs))
+}
+
+func TestLoadTOMLAppConfigWithBuildTimeouts(t *testing.T) {
+	path := "./testdata/build-timeouts.toml"
+	p, err := LoadAppConfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, 45*time.Minute, p.Build.Timeout)
+	assert.Equal(t, time.Minute, p.Build.BuilderTimeout)
+	assert.Equal(t, []string{"ghcr.io/me/app:latest", "docker.io/me/app:latest"}, p.Build.PushTo)
+	assert.Equal(t, 1, p.Build.CompressionLevel)
+}
+
+func TestLoadTOMLAppConfigWithBuildTargets(t *testing.T) {
+	path := "./testdata/build-targets.toml"
+	p, err := LoadAppConfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "runtime", p.BuildTarget(""))
+	assert.Equal(t, "worker-runtime", p.BuildTarget("worker"))
+	assert.Equal(t, "runtime", p.BuildTarget("web"))
+	assert.Equal(t, "docker/Dockerfile.prod", p.Build.Dockerfile)
+}
+
+func TestLoadTOMLAppConfigWithBuildHooks(t *testing.T) {
+	path := "./testdata/build-hooks.toml"
+	p, err := LoadAppConfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "make assets", p.Build.Hooks.Pre)
+	assert.Equal(t, "./scripts/notify.sh $IMAGE", p.Build.Hooks.Post)
+
+	var buf bytes.Buffer
+	assert.NoError(t, p.WriteTo(&buf, TOMLFormat))
+	written := NewAppConfig()
+	assert.NoError(t, written.unmarshalTOML(&buf))
+	assert.Equal(t, p.Build.Hooks, written.Build.Hooks)
+}
+
+func TestLoadTOMLAppConfigWithRegions(t *testing.T) {
+	path := "./testdata/regions.toml"
+	p, err := LoadAppConfig(path)
+	assert.NoError(t, err)
+
+	pins, errs := p.ProcessRegions()
+	assert.Equal(t, []string{"regions.broken: must be a list of region codes like [\"iad\", \"lhr\"]"}, errorStrings(errs))
+	assert.Equal(t, map[string][]string{"app": {"iad", "lhr"}, "worker": {"iad"}}, pins)
+	assert.Equal(t, "data", p.MountSource())
+}
+
+func TestLoadTOMLAppConfigWithRegionOverrides(t *testing.T) {
+	path := "./testdata/regions.toml"
+	p, err := LoadAppConfig(path)
+	assert.NoError(t, err)
+
+	overrides, errs := p.RegionOverrides()
+	assert.Equal(t, []string{
+		"regions.syd.concurrency.soft_limit: 60 is over the hard_limit of 50",
+		"regions.syd: unknown setting memory, a region can set env, vm_size and concurrency",
+	}, errorStrings(errs))
+	assert.Equal(t, []RegionOverride{
+		{
+			Region:      "fra",
+			Env:         map[string]string{"DATABASE_URL": "postgres://fra.db.internal/app", "WORKERS": "4"},
+			VMSize:      "shared-cpu-2x",
+			Concurrency: &RegionConcurrency{SoftLimit: 40, HardLimit: 50},
+		},
+		{Region: "syd"},
+	}, overrides)
+}
+
+func TestLoadTOMLAppConfigWithProcessGroups(t *testing.T) {
+	path := "./testdata/processes.toml"
+	p, err := LoadAppConfig(path)
+	assert.NoError(t, err)
+
+	groups, errs := p.ProcessGroups()
+	assert.Equal(t, []string{
+		"processes.cron: unknown setting memory, a process can set command, vm_size, count, build_target, env and secrets",
+		"processes.empty: command is required",
+		"processes.worker.secrets: not-valid isn't a valid secret name",
+	}, errorStrings(errs))
+	assert.Equal(t, []ProcessGroup{
+		{Name: "cron", Command: "supercronic /app/crontab"},
+		{Name: "web", Command: "bin/server"},
+		{
+			Name:        "worker",
+			Command:     "bin/worker",
+			VMSize:      "dedicated-cpu-1x",
+			Count:       2,
+			BuildTarget: "worker",
+			Env:         map[string]string{"QUEUE": "default", "THREADS": "4"},
+			Secrets:     []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"},
+		},
+	}, groups)
+	assert.Equal(t, map[string]interface{}{
+		"cron":   "supercronic /app/crontab",
+		"web":    "bin/server",
+		"worker": "bin/worker",
+	}, ProcessCommands(groups))
+}
+
+func TestLoadTOMLAppConfigWithRequiredSecrets(t *testing.T) {
+	path := "./testdata/secrets.toml"
+	p, err := LoadAppConfig(path)
+	assert.NoError(t, err)
+
+	required, errs := p.RequiredSecrets()
+	assert.Equal(t, []string{
+		"secrets: unknown setting optional",
+		"secrets.required: not-valid isn't a valid secret name",
+	}, errorStrings(errs))
+	assert.Equal(t, []string{"DATABASE_URL", "SECRET_KEY_BASE"}, required)
+
+	set := []api.Secret{{Name: "SECRET_KEY_BASE"}, {Name: "OTHER"}}
+	assert.Equal(t, []string{"DATABASE_URL"}, MissingSecrets(required, set))
+}
+
+func TestLoadAppConfigForEnvironment(t *testing.T) {
+	path := "./testdata/environments.toml"
+	p, err := LoadAppConfig(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "test-app", p.AppName)
+	assert.NotContains(t, p.Definition, "environments")
+	assert.Contains(t, p.Environments, "staging")
+
+	p, loaded, err := LoadAppConfigForEnvironment(path, "staging")
+	assert.NoError(t, err)
+	assert.Equal(t, path, loaded)
+	assert.Equal(t, "test-app-staging", p.AppName)
+	assert.Equal(t, map[string]interface{}{"LOG_LEVEL": "debug", "PORT": "8080"}, p.Definition["env"])
+	pins, _ := p.ProcessRegions()
+	assert.Equal(t, map[string][]string{"app": {"iad"}}, pins)
+
+	p, loaded, err = LoadAppConfigForEnvironment(path, "production")
+	assert.NoError(t, err)
+	assert.Equal(t, "testdata/environments.production.toml", filepath.ToSlash(filepath.Clean(loaded)))
+	assert.Equal(t, "test-app-production", p.AppName)
+	assert.Equal(t, "production", p.Environment)
+
+	_, _, err = LoadAppConfigForEnvironment(path, "dev")
+	assert.EqualError(t, err, "no [environments.dev] section for the dev environment")
+}
+
+func TestLoadYAMLAndJSONAppConfig(t *testing.T) {
+	yamlConfig, err := LoadAppConfig("./testdata/services.yaml")
+	assert.NoError(t, err)
+	assert.Equal(t, "services", yamlConfig.AppName)
+	assert.Equal(t, "builder/name", yamlConfig.Build.Builder)
+	assert.Equal(t, map[string]string{"A": "B"}, yamlConfig.Build.Args)
+
+	jsonConfig, err := LoadAppConfig("./testdata/services.json")
+	assert.NoError(t, err)
+	assert.Equal(t, yamlConfig.Definition, jsonConfig.Definition)
+	assert.Empty(t, jsonConfig.Validate())
+
+	ports := toMapSlice(yamlConfig.services()[0]["ports"])
+	assert.Equal(t, int64(443), ports[1]["port"])
+
+	for _, format := range []ConfigFormat{YAMLFormat, JSONFormat} {
+		path := filepath.Join(t.TempDir(), "fly"+string(format))
+		assert.NoError(t, jsonConfig.WriteToFile(path))
+
+		written, err := LoadAppConfig(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "services", written.AppName)
+		assert.Equal(t, jsonConfig.Definition, written.Definition)
+	}
+}
+
+func TestResolveConfigFileFormats(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "fly.yml"), []byte("app: test-app\n"), 0644))
+
+	p, err := ResolveConfigFileFromPath(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "fly.yml"), p)
+
+	p, err = ResolveConfigFileFromPath(filepath.Join(dir, "fly.toml"))
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, "fly.yml"), p)
+}
+
+func TestLoadAppConfigWithIncludes(t *testing.T) {
+	p, err := LoadAppConfig("./testdata/includes/app.toml")
+	assert.NoError(t, err)
+	assert.Equal(t, "test-app", p.AppName)
+	assert.NotContains(t, p.Definition, "include")
+	assert.Equal(t, "SIGTERM", p.Definition["kill_signal"])
+	assert.Equal(t, int64(10), p.Definition["kill_timeout"])
+	assert.Equal(t, map[string]interface{}{"LOG_LEVEL": "debug", "PORT": "8080"}, p.Definition["env"])
+	assert.Len(t, p.services(), 1)
+	assert.Empty(t, p.Validate())
+
+	var includes []string
+	for _, include := range p.Includes {
+		includes = append(includes, filepath.Base(include))
+	}
+	assert.Equal(t, []string{"base.toml", "checks.yaml"}, includes)
+
+	_, err = LoadAppConfig("./testdata/includes/cycle.toml")
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "include cycle")
+}
+
+func TestLoadAppConfigTemplate(t *testing.T) {
+	path := "./testdata/template/fly.toml"
+	values, err := LoadTemplateValues("./testdata/template/values.yaml")
+	assert.NoError(t, err)
+
+	p, loaded, err := LoadAppConfigTemplate(path, "", values)
+	assert.NoError(t, err)
+	assert.Equal(t, path, loaded)
+	assert.Equal(t, "api-fra", p.AppName)
+	assert.Equal(t, "fra", p.Definition["primary_region"])
+	assert.Equal(t, map[string]interface{}{"LOG_LEVEL": "info"}, p.Definition["env"])
+	ports := toMapSlice(p.services()[0]["ports"])
+	assert.Len(t, ports, 2)
+	assert.Equal(t, int64(443), ports[1]["port"])
+
+	delete(values, "port")
+	_, _, err = LoadAppConfigTemplate(path, "", values)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "port")
+
+	_, err = LoadAppConfig(path)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "--template-values")
+}
+
+func TestValidateRegionPins(t *testing.T) {
+	pins := map[string][]string{"app": {"iad", "lhr"}, "worker": {"iad"}}
+
+	errs, warnings := ValidateRegionPins(pins, RegionPlacement{Counts: map[string]int{"app": 1, "worker": 1}})
+	assert.Empty(t, errs)
+	assert.Equal(t, []string{"process group app has 1 instances for 2 regions, some regions won't run it"}, warnings)
+
+	errs, warnings = ValidateRegionPins(pins, RegionPlacement{
+		Counts:        map[string]int{"app": 3},
+		Volume:        "data",
+		VolumeRegions: map[string]int{"iad": 2},
+	})
+	assert.Equal(t, []string{
+		"process group app can't run in lhr, it mounts volume data and there is none in lhr",
+		"process group app has 3 instances but only 2 data volumes in its regions, each instance needs its own",
+	}, errs)
+	assert.Equal(t, []string{"process group worker has no instances yet, the pin applies once it's scaled up"}, warnings)
+}
+
+func TestLoadTOMLAppConfigWithDeployStrategy(t *testing.T) {
+	path := "./testdata/deploy-websocket.toml"
+	p, err := LoadAppConfig(path)
+	assert.NoError(t, err)
+
+	dc, errs := p.DeployConfig()
+	assert.Empty(t, errs)
+	assert.Equal(t, &DeployConfig{Strategy: StrategyWebsocket, ConnectionThreshold: 5, MaxConnectionWait: 2 * time.Hour, CanaryVerifyTimeout: 5 * time.Minute, BakeTime: 5 * time.Minute, AutoRollback: true, AutoRollbackThreshold: 1}, dc)
+
+	dc, errs = NewAppConfig().DeployConfig()
+	assert.Empty(t, errs)
+	assert.Equal(t, time.Hour, dc.MaxConnectionWait)
+
+	p.Definition["deploy"] = map[string]interface{}{"strategy": "sideways", "connection_threshold": int64(-1)}
+	_, errs = p.DeployConfig()
+	assert.ElementsMatch(t, []string{
+		"deploy: unknown deploy strategy sideways, use one of canary, rolling, bluegreen, immediate, websocket",
+		"deploy: connection_threshold must be a number of connections, 0 or more",
+	}, errorStrings(errs))
+
+	p.Definition["deploy"] = map[string]interface{}{"strategy": "canary", "canary_verify": "./smoke-test.sh", "canary_verify_timeout": "2m"}
+	dc, errs = p.DeployConfig()
+	assert.Empty(t, errs)
+	assert.Equal(t, "./smoke-test.sh", dc.CanaryVerify)
+	assert.Equal(t, 2*time.Minute, dc.CanaryVerifyTimeout)
+
+	p.Definition["deploy"] = map[string]interface{}{"strategy": "rolling", "canary_verify": "./smoke-test.sh", "canary_verify_timeout": "soon"}
+	_, errs = p.DeployConfig()
+	assert.ElementsMatch(t, []string{
+		"deploy: canary_verify needs the canary strategy, not rolling",
+		"deploy: canary_verify_timeout must be a positive duration like \"5m\", got soon",
+	}, errorStrings(errs))
+
+	p.Definition["deploy"] = map[string]interface{}{"strategy": "bluegreen", "manual_promote": true}
+	dc, errs = p.DeployConfig()
+	assert.Empty(t, errs)
+	assert.True(t, dc.ManualPromote)
+
+	p.Definition["deploy"] = map[string]interface{}{"release_command": "bin/rails db:migrate"}
+	dc, errs = p.DeployConfig()
+	assert.Empty(t, errs)
+	assert.Equal(t, "bin/rails db:migrate", dc.ReleaseCommand)
+
+	p.Definition["deploy"] = map[string]interface{}{"release_command": []interface{}{"bin/rails", "db:migrate"}}
+	_, errs = p.DeployConfig()
+	assert.Equal(t, []string{"deploy: release_command must be a command like \"bin/rails db:migrate\""}, errorStrings(errs))
+
+	p.Definition["deploy"] = map[string]interface{}{"strategy": "canary", "manual_promote": "yes"}
+	_, errs = p.DeployConfig()
+	assert.Equal(t, []string{"deploy: manual_promote must be true or false"}, errorStrings(errs))
+
+	p.Definition["deploy"] = map[string]interface{}{"smoke_test": map[string]interface{}{"http_path": "/healthz", "command": "./smoke.sh"}}
+	dc, errs = p.DeployConfig()
+	assert.Empty(t, errs)
+	assert.Equal(t, &SmokeTestConfig{Command: "./smoke.sh", HTTPPath: "/healthz", Timeout: 2 * time.Minute}, dc.SmokeTest)
+
+	p.Definition["deploy"] = map[string]interface{}{"smoke_test": map[string]interface{}{"http_path": "healthz", "port": int64(70000), "timeout": "30s"}}
+	_, errs = p.DeployConfig()
+	assert.ElementsMatch(t, []string{
+		"deploy.smoke_test: http_path must be a path like \"/healthz\", got healthz",
+		"deploy.smoke_test: port must be a port number, got 70000",
+	}, errorStrings(errs))
+
+	p.Definition["deploy"] = map[string]interface{}{"smoke_test": map[string]interface{}{"timeout": "30s"}}
+	_, errs = p.DeployConfig()
+	assert.Equal(t, []string{"deploy.smoke_test: needs a command, an http_path or both"}, errorStrings(errs))
+
+	p.Definition["deploy"] = map[string]interface{}{"rollout_order": []interface{}{"iad", "lhr"}, "bake_time": "10m"}
+	dc, errs = p.DeployConfig()
+	assert.Empty(t, errs)
+	assert.Equal(t, []string{"iad", "lhr"}, dc.RolloutOrder)
+	assert.Equal(t, 10*time.Minute, dc.BakeTime)
+
+	p.Definition["deploy"] = map[string]interface{}{"rollout_order": []interface{}{"iad", "iad"}, "bake_time": "a while"}
+	_, errs = p.DeployConfig()
+	assert.ElementsMatch(t, []string{
+		"deploy: bake_time must be a duration like \"5m\", got a while",
+		"deploy: rollout_order lists iad more than once",
+	}, errorStrings(errs))
+
+	p.Definition["deploy"] = map[string]interface{}{"auto_rollback": false, "auto_rollback_threshold": int64(3)}
+	dc, errs = p.DeployConfig()
+	assert.Empty(t, errs)
+	assert.False(t, dc.AutoRollback)
+	assert.Equal(t, 3, dc.AutoRollbackThreshold)
+
+	p.Definition["deploy"] = map[string]interface{}{"auto_rollback_threshold": int64(0)}
+	_, errs = p.DeployConfig()
+	assert.Equal(t, []string{"deploy: auto_rollback_threshold must be a number of instances, 1 or more"}, errorStrings(errs))
+
+	p.Definition["deploy"] = map[string]interface{}{
+		"hooks":  map[string]interface{}{"success": "./notify.sh"},
+		"notify": map[string]interface{}{"slack": "https://hooks.slack.com/services/T0/B0/x"},
+	}
+	dc, errs = p.DeployConfig()
+	assert.Empty(t, errs)
+	assert.Equal(t, DeployHooks{Success: "./notify.sh"}, dc.Hooks)
+	assert.Equal(t, DeployNotify{Slack: "https://hooks.slack.com/services/T0/B0/x"}, dc.Notify)
+
+	p.Definition["deploy"] = map[string]interface{}{"signature_key": "cosign.pub"}
+	dc, errs = p.DeployConfig()
+	assert.Empty(t, errs)
+	assert.Equal(t, "cosign.pub", dc.SignatureKey)
+
+	p.Definition["deploy"] = map[string]interface{}{
+		"hooks":  map[string]interface{}{"failure": "", "started": "./start.sh"},
+		"notify": map[string]interface{}{"webhook": "hooks.example.com"},
+	}
+	_, errs = p.DeployConfig()
+	assert.ElementsMatch(t, []string{
+		"deploy.hooks: failure must be a command like \"./notify.sh\"",
+		"deploy.hooks: unknown hook started, use success or failure",
+		"deploy.notify: webhook must be a URL like \"https://hooks.example.com/deploys\", got hooks.example.com",
+	}, errorStrings(errs))
+}
+
+func TestLoadTOMLAppConfigWithRestartSchedule(t *testing.T) {
+	path := "./testdata/restart-schedule.toml"
+	p, err := LoadAppConfig(path)
+	assert.NoError(t, err)
+
+	rs, errs := p.RestartSchedule()
+	assert.Empty(t, errs)
+	assert.Equal(t, "America/Chicago", rs.Timezone)
+	assert.True(t, rs.SkipIfUnhealthy)
+
+	next, ok := rs.Next(time.Date(2021, time.March, 10, 12, 0, 0, 0, time.UTC))
+	assert.True(t, ok)
+	assert.Equal(t, "2021-03-11T04:00:00-06:00", next.Format(time.RFC3339))
+}
+
+func TestLoadTOMLAppConfigWithWarmup(t *testing.T) {
+	path := "./testdata/warmup.toml"
+	p, err := LoadAppConfig(path)
+	assert.NoError(t, err)
+
+	requests, errs := p.WarmupRequests()
+	assert.Equal(t, []WarmupRequest{
+		{InternalPort: 8080, Method: "GET", Path: "/", Count: 20, Timeout: 10 * time.Second},
+		{InternalPort: 8080, Method: "POST", Path: "/api/cache/fill", Count: 1, Timeout: 30 * time.Second, ExpectedStatus: 204, Headers: map[string]string{"Authorization": "Bearer warmup"}},
+	}, requests)
+	assert.ElementsMatch(t, []string{
+		"services[0].warmup[2]: count must be between 1 and 1000",
+		"services[0].warmup[2]: path must start with /",
+	}, errorStrings(errs))
+}
+
+func errorStrings(errs []ConfigError) []string {
+	var out []string
+	for _, e := range errs {
+		out = append(out, e.Error())
+	}
+	return out
+}
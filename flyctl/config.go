@@ -13,6 +13,7 @@ const (
 	ConfigBuiltinsfile    = "builtins_file"
 	ConfigGQLErrorLogging = "gqlerrorlogging"
 	ConfigInstaller       = "installer"
+	ConfigReadOnly        = "read_only"
 	BuildKitNodeID        = "buildkit_node_id"
 
 	ConfigWireGuardState = "wire_guard_state"
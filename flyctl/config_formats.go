@@ -0,0 +1,121 @@
+package flyctl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+
+	"gopkg.in/yaml.v2"
+)
+
+func decodeYAML(r io.Reader) (map[string]interface{}, error) {
+	var data map[interface{}]interface{}
+	if err := yaml.NewDecoder(r).Decode(&data); err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	native, err := normalizeConfigValue("", data)
+	if err != nil {
+		return nil, err
+	}
+	m, _ := native.(map[string]interface{})
+	if m == nil {
+		m = map[string]interface{}{}
+	}
+	return m, nil
+}
+
+func decodeJSON(r io.Reader) (map[string]interface{}, error) {
+	var data map[string]interface{}
+	if err := json.NewDecoder(r).Decode(&data); err != nil {
+		return nil, err
+	}
+
+	native, err := normalizeConfigValue("", data)
+	if err != nil {
+		return nil, err
+	}
+	return native.(map[string]interface{}), nil
+}
+
+// normalizeConfigValue converts values decoded from YAML or JSON to the types TOML decodes to:
+// sections are map[string]interface{} and whole numbers are int64
+func normalizeConfigValue(path string, v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: setting names must be strings, got %v", path, k)
+			}
+			normalized, err := normalizeConfigValue(joinConfigPath(path, key), item)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = normalized
+		}
+		return m, nil
+	case map[string]interface{}:
+		for key, item := range val {
+			normalized, err := normalizeConfigValue(joinConfigPath(path, key), item)
+			if err != nil {
+				return nil, err
+			}
+			val[key] = normalized
+		}
+		return val, nil
+	case []interface{}:
+		for i, item := range val {
+			normalized, err := normalizeConfigValue(fmt.Sprintf("%s[%d]", path, i), item)
+			if err != nil {
+				return nil, err
+			}
+			val[i] = normalized
+		}
+		return val, nil
+	case int:
+		return int64(val), nil
+	case float64:
+		if val == math.Trunc(val) && math.Abs(val) < math.MaxInt64 {
+			return int64(val), nil
+		}
+		return val, nil
+	}
+	return v, nil
+}
+
+func joinConfigPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func (ac AppConfig) marshalYAML(w io.Writer) error {
+	data := ac.marshalMap()
+
+	// the app name comes first, the rest are sorted
+	doc := yaml.MapSlice{yaml.MapItem{Key: "app", Value: ac.AppName}}
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		doc = append(doc, yaml.MapItem{Key: k, Value: data[k]})
+	}
+
+	return yaml.NewEncoder(w).Encode(doc)
+}
+
+func (ac AppConfig) marshalJSON(w io.Writer) error {
+	data := ac.marshalMap()
+	data["app"] = ac.AppName
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(data)
+}
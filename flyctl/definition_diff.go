@@ -0,0 +1,173 @@
+package flyctl
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/superfly/flyctl/api"
+)
+
+// Kinds of DefinitionChange
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeUpdated = "updated"
+)
+
+// DefinitionChange is a setting that differs between two config definitions
+type DefinitionChange struct {
+	// Path is the setting's dotted path, like services.0.internal_port
+	Path string
+	Kind string
+	From interface{}
+	To   interface{}
+}
+
+func (c DefinitionChange) String() string {
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("+ %s = %s", c.Path, formatDefinitionValue(c.To))
+	case ChangeRemoved:
+		return fmt.Sprintf("- %s = %s", c.Path, formatDefinitionValue(c.From))
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Path, formatDefinitionValue(c.From), formatDefinitionValue(c.To))
+	}
+}
+
+func formatDefinitionValue(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// DiffDefinitions returns the settings changed from one definition to another, sorted by path.
+// Both are compared as JSON so a value decoded from fly.toml equals the same value from the API.
+func DiffDefinitions(from, to map[string]interface{}) ([]DefinitionChange, error) {
+	a, err := normalizeDefinition(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := normalizeDefinition(to)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []DefinitionChange
+	diffDefinitionValues("", a, b, &changes)
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Path < changes[j].Path })
+	return changes, nil
+}
+
+func normalizeDefinition(def map[string]interface{}) (interface{}, error) {
+	if def == nil {
+		return map[string]interface{}{}, nil
+	}
+	data, err := json.Marshal(def)
+	if err != nil {
+		return nil, err
+	}
+	var v interface{}
+	err = json.Unmarshal(data, &v)
+	return v, err
+}
+
+func diffDefinitionValues(path string, from, to interface{}, changes *[]DefinitionChange) {
+	join := func(key string) string {
+		if path == "" {
+			return key
+		}
+		return path + "." + key
+	}
+
+	switch a := from.(type) {
+	case map[string]interface{}:
+		b, ok := to.(map[string]interface{})
+		if !ok {
+			break
+		}
+		for k, v := range a {
+			if w, ok := b[k]; ok {
+				diffDefinitionValues(join(k), v, w, changes)
+			} else {
+				*changes = append(*changes, DefinitionChange{Path: join(k), Kind: ChangeRemoved, From: v})
+			}
+		}
+		for k, w := range b {
+			if _, ok := a[k]; !ok {
+				*changes = append(*changes, DefinitionChange{Path: join(k), Kind: ChangeAdded, To: w})
+			}
+		}
+		return
+	case []interface{}:
+		b, ok := to.([]interface{})
+		if !ok || len(a) != len(b) {
+			break
+		}
+		for i := range a {
+			diffDefinitionValues(join(fmt.Sprint(i)), a[i], b[i], changes)
+		}
+		return
+	}
+
+	if !reflect.DeepEqual(from, to) {
+		*changes = append(*changes, DefinitionChange{Path: path, Kind: ChangeUpdated, From: from, To: to})
+	}
+}
+
+// SecretChange is a secret set, unset or given a new value between two sets of secrets. Only the
+// name is kept, never the value.
+type SecretChange struct {
+	Name string
+	Kind string
+}
+
+func (c SecretChange) String() string {
+	switch c.Kind {
+	case ChangeAdded:
+		return "+ " + c.Name
+	case ChangeRemoved:
+		return "- " + c.Name
+	default:
+		return "~ " + c.Name
+	}
+}
+
+// DiffSecrets compares two sets of secrets by the digests of their values, sorted by name
+func DiffSecrets(from, to []api.Secret) []SecretChange {
+	digests := map[string]string{}
+	for _, s := range from {
+		digests[s.Name] = s.Digest
+	}
+
+	var changes []SecretChange
+	for _, s := range to {
+		digest, ok := digests[s.Name]
+		switch {
+		case !ok:
+			changes = append(changes, SecretChange{Name: s.Name, Kind: ChangeAdded})
+		case digest != s.Digest:
+			changes = append(changes, SecretChange{Name: s.Name, Kind: ChangeUpdated})
+		}
+		delete(digests, s.Name)
+	}
+	for name := range digests {
+		changes = append(changes, SecretChange{Name: name, Kind: ChangeRemoved})
+	}
+
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Name < changes[j].Name })
+	return changes
+}
+
+// SecretDigest is the digest the platform reports for a secret's value, the first 16 hex characters
+// of its SHA-256, so local values can be compared with an app's without sending them
+func SecretDigest(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])[:16]
+}
@@ -0,0 +1,58 @@
+package flyctl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/superfly/flyctl/api"
+)
+
+func TestDiffDefinitions(t *testing.T) {
+	from := map[string]interface{}{
+		"kill_timeout": 5,
+		"env":          map[string]interface{}{"LOG_LEVEL": "info", "OLD": "1"},
+		"services": []map[string]interface{}{
+			{"internal_port": 8080, "protocol": "tcp"},
+		},
+	}
+	to := map[string]interface{}{
+		"kill_timeout": int64(5),
+		"env":          map[string]interface{}{"LOG_LEVEL": "debug", "NEW": "1"},
+		"services": []interface{}{
+			map[string]interface{}{"internal_port": 3000, "protocol": "tcp"},
+		},
+	}
+
+	changes, err := DiffDefinitions(from, to)
+	assert.NoError(t, err)
+	assert.Equal(t, []DefinitionChange{
+		{Path: "env.LOG_LEVEL", Kind: ChangeUpdated, From: "info", To: "debug"},
+		{Path: "env.NEW", Kind: ChangeAdded, To: "1"},
+		{Path: "env.OLD", Kind: ChangeRemoved, From: "1"},
+		{Path: "services.0.internal_port", Kind: ChangeUpdated, From: float64(8080), To: float64(3000)},
+	}, changes)
+
+	assert.Equal(t, `~ env.LOG_LEVEL: "info" -> "debug"`, changes[0].String())
+	assert.Equal(t, `+ env.NEW = "1"`, changes[1].String())
+
+	changes, err = DiffDefinitions(nil, map[string]interface{}{"app": "test"})
+	assert.NoError(t, err)
+	assert.Equal(t, []DefinitionChange{{Path: "app", Kind: ChangeAdded, To: "test"}}, changes)
+}
+
+func TestDiffSecrets(t *testing.T) {
+	from := []api.Secret{{Name: "DATABASE_URL", Digest: "a"}, {Name: "OLD_KEY", Digest: "b"}, {Name: "SAME", Digest: "c"}}
+	to := []api.Secret{{Name: "DATABASE_URL", Digest: "d"}, {Name: "NEW_KEY", Digest: "e"}, {Name: "SAME", Digest: "c"}}
+
+	changes := DiffSecrets(from, to)
+	assert.Equal(t, []SecretChange{
+		{Name: "DATABASE_URL", Kind: ChangeUpdated},
+		{Name: "NEW_KEY", Kind: ChangeAdded},
+		{Name: "OLD_KEY", Kind: ChangeRemoved},
+	}, changes)
+	assert.Equal(t, "~ DATABASE_URL", changes[0].String())
+}
+
+func TestSecretDigest(t *testing.T) {
+	assert.Equal(t, "2cf24dba5fb0a30e", SecretDigest("hello"))
+}
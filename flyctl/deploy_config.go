@@ -0,0 +1,398 @@
+package flyctl
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Deploy strategies, sent to the API upper cased
+const (
+	StrategyCanary    = "canary"
+	StrategyRolling   = "rolling"
+	StrategyBlueGreen = "bluegreen"
+	StrategyImmediate = "immediate"
+	// StrategyWebsocket is for apps with long-lived connections. A full set of new instances starts
+	// alongside the old ones and takes all new connections, while old instances keep serving the
+	// connections they have until few enough remain or the maximum wait passes.
+	StrategyWebsocket = "websocket"
+)
+
+// Strategies lists the deploy strategies in the order they're documented
+var Strategies = []string{StrategyCanary, StrategyRolling, StrategyBlueGreen, StrategyImmediate, StrategyWebsocket}
+
+const (
+	defaultMaxConnectionWait   = time.Hour
+	defaultCanaryVerifyTimeout = 5 * time.Minute
+	defaultSmokeTestTimeout    = 2 * time.Minute
+	defaultBakeTime            = 5 * time.Minute
+)
+
+// DeployConfig holds the [deploy] section of fly.toml
+type DeployConfig struct {
+	// Strategy is empty to use the server's default
+	Strategy string
+	// ConnectionThreshold is the number of open connections at or below which the websocket strategy
+	// stops an old instance
+	ConnectionThreshold int
+	// MaxConnectionWait stops old instances that still have connections once it passes
+	MaxConnectionWait time.Duration
+	// CanaryVerify is a command run against the canary instance once it's healthy. The rest of the
+	// instances are replaced when it succeeds, and the deployment is rolled back when it fails.
+	CanaryVerify string
+	// CanaryVerifyTimeout limits how long CanaryVerify may run
+	CanaryVerifyTimeout time.Duration
+	// ManualPromote keeps a blue-green deployment's new instances out of load balancing once they're
+	// healthy, until the release is promoted or cancelled
+	ManualPromote bool
+	// ReleaseCommand runs in a one-off VM with the new image before any instance is replaced, like
+	// database migrations. The deploy fails when it fails.
+	ReleaseCommand string
+	// SmokeTest checks the first new instance once it's healthy, nil when there's no
+	// [deploy.smoke_test] section
+	SmokeTest *SmokeTestConfig
+	// RolloutOrder deploys to one region at a time in this order, pausing for BakeTime after each.
+	// Regions that aren't listed are deployed to together after the last one.
+	RolloutOrder []string
+	// BakeTime is how long a region's new instances must stay healthy before the next region
+	BakeTime time.Duration
+	// AutoRollback has flyctl roll back a deployment it's monitoring once AutoRollbackThreshold of
+	// its new instances are failing their health checks
+	AutoRollback          bool
+	AutoRollbackThreshold int
+	// Hooks run locally once a deploy finishes
+	Hooks DeployHooks
+	// Notify posts to webhooks once a deploy finishes
+	Notify DeployNotify
+	// SignatureKey is the path to a cosign public key. Only images with a signature made with it
+	// are deployed.
+	SignatureKey string
+}
+
+// DeployHooks holds the [deploy.hooks] section: commands run with the release's details exported as
+// FLY_APP, FLY_RELEASE_VERSION, FLY_IMAGE, FLY_DEPLOY_AUTHOR and FLY_DEPLOY_STATUS
+type DeployHooks struct {
+	Success string
+	Failure string
+}
+
+// DeployNotify holds the [deploy.notify] section
+type DeployNotify struct {
+	// Webhook is sent the deploy's outcome as JSON
+	Webhook string
+	// Slack is a Slack incoming webhook sent a message describing the deploy
+	Slack string
+}
+
+// SmokeTestConfig holds the [deploy.smoke_test] section. The rest of the instances are replaced when
+// the first new instance passes it, and the deployment is rolled back when it fails.
+type SmokeTestConfig struct {
+	// Command runs locally with the instance's private address exported as FLY_INSTANCE_ADDR
+	Command string
+	// HTTPPath is requested from the instance on Port, passing on a 2xx response
+	HTTPPath string
+	// Port is the instance's port for HTTPPath and FLY_INSTANCE_ADDR, zero for the app's internal port
+	Port int
+	// Timeout limits how long the whole smoke test may run
+	Timeout time.Duration
+}
+
+// ValidateStrategy checks a strategy given on the command line or in fly.toml
+func ValidateStrategy(strategy string) error {
+	for _, s := range Strategies {
+		if strings.EqualFold(strategy, s) {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown deploy strategy %s, use one of %s", strategy, strings.Join(Strategies, ", "))
+}
+
+// DeployConfig parses the [deploy] section, returning the defaults when there isn't one
+func (ac *AppConfig) DeployConfig() (*DeployConfig, []ConfigError) {
+	dc := &DeployConfig{MaxConnectionWait: defaultMaxConnectionWait, CanaryVerifyTimeout: defaultCanaryVerifyTimeout, BakeTime: defaultBakeTime, AutoRollback: true, AutoRollbackThreshold: 1}
+
+	raw, ok := ac.Definition["deploy"]
+	if !ok {
+		return dc, nil
+	}
+
+	section, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, []ConfigError{configErrorf("deploy", "must be a section like [deploy]")}
+	}
+
+	var errs []ConfigError
+
+	for k, v := range section {
+		switch k {
+		case "strategy":
+			dc.Strategy = strings.ToLower(fmt.Sprint(v))
+			if err := ValidateStrategy(dc.Strategy); err != nil {
+				errs = append(errs, configErrorf("deploy", "%s", err))
+			}
+		case "connection_threshold":
+			n, ok := toInt(v)
+			if !ok || n < 0 {
+				errs = append(errs, configErrorf("deploy", "connection_threshold must be a number of connections, 0 or more"))
+			}
+			dc.ConnectionThreshold = n
+		case "max_connection_wait":
+			d, err := time.ParseDuration(fmt.Sprint(v))
+			if err != nil || d <= 0 {
+				errs = append(errs, configErrorf("deploy", "max_connection_wait must be a positive duration like \"2h\", got %v", v))
+			}
+			dc.MaxConnectionWait = d
+		case "canary_verify":
+			cmd, ok := v.(string)
+			if !ok || strings.TrimSpace(cmd) == "" {
+				errs = append(errs, configErrorf("deploy", "canary_verify must be a command like \"./scripts/smoke-test.sh\""))
+			}
+			dc.CanaryVerify = cmd
+		case "canary_verify_timeout":
+			d, err := time.ParseDuration(fmt.Sprint(v))
+			if err != nil || d <= 0 {
+				errs = append(errs, configErrorf("deploy", "canary_verify_timeout must be a positive duration like \"5m\", got %v", v))
+			}
+			dc.CanaryVerifyTimeout = d
+		case "release_command":
+			cmd, ok := v.(string)
+			if !ok || strings.TrimSpace(cmd) == "" {
+				errs = append(errs, configErrorf("deploy", "release_command must be a command like \"bin/rails db:migrate\""))
+			}
+			dc.ReleaseCommand = cmd
+		case "manual_promote":
+			b, ok := v.(bool)
+			if !ok {
+				errs = append(errs, configErrorf("deploy", "manual_promote must be true or false"))
+			}
+			dc.ManualPromote = b
+		case "rollout_order":
+			list, ok := v.([]interface{})
+			if !ok || len(list) == 0 {
+				errs = append(errs, configErrorf("deploy", "rollout_order must be a list of region codes like [\"iad\", \"lhr\"]"))
+			}
+			for _, region := range list {
+				dc.RolloutOrder = append(dc.RolloutOrder, fmt.Sprint(region))
+			}
+		case "bake_time":
+			d, err := time.ParseDuration(fmt.Sprint(v))
+			if err != nil || d < 0 {
+				errs = append(errs, configErrorf("deploy", "bake_time must be a duration like \"5m\", got %v", v))
+			}
+			dc.BakeTime = d
+		case "auto_rollback":
+			b, ok := v.(bool)
+			if !ok {
+				errs = append(errs, configErrorf("deploy", "auto_rollback must be true or false"))
+			}
+			dc.AutoRollback = b
+		case "auto_rollback_threshold":
+			n, ok := toInt(v)
+			if !ok || n < 1 {
+				errs = append(errs, configErrorf("deploy", "auto_rollback_threshold must be a number of instances, 1 or more"))
+			}
+			dc.AutoRollbackThreshold = n
+		case "smoke_test":
+			st, stErrs := parseSmokeTest(v)
+			errs = append(errs, stErrs...)
+			dc.SmokeTest = st
+		case "signature_key":
+			path, ok := v.(string)
+			if !ok || strings.TrimSpace(path) == "" {
+				errs = append(errs, configErrorf("deploy", "signature_key must be the path to a cosign public key like \"cosign.pub\""))
+			}
+			dc.SignatureKey = path
+		case "hooks":
+			hooks, hookErrs := parseDeployHooks(v)
+			errs = append(errs, hookErrs...)
+			dc.Hooks = hooks
+		case "notify":
+			notify, notifyErrs := parseDeployNotify(v)
+			errs = append(errs, notifyErrs...)
+			dc.Notify = notify
+		default:
+			errs = append(errs, configErrorf("deploy", "unknown setting %s", k))
+		}
+	}
+
+	if dc.CanaryVerify != "" && dc.Strategy != "" && dc.Strategy != StrategyCanary {
+		errs = append(errs, configErrorf("deploy", "canary_verify needs the canary strategy, not %s", dc.Strategy))
+	}
+
+	if dc.ManualPromote && dc.Strategy != "" && dc.Strategy != StrategyBlueGreen {
+		errs = append(errs, configErrorf("deploy", "manual_promote needs the bluegreen strategy, not %s", dc.Strategy))
+	}
+
+	if err := ValidateRolloutOrder(dc.RolloutOrder); err != nil {
+		errs = append(errs, configErrorf("deploy", "%s", err))
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return dc, nil
+}
+
+// ValidateRolloutOrder checks a rollout order given on the command line or in fly.toml lists each
+// region once
+func ValidateRolloutOrder(regions []string) error {
+	seen := map[string]bool{}
+	for _, region := range regions {
+		if strings.TrimSpace(region) == "" {
+			return errors.New("rollout_order can't have an empty region")
+		}
+		if seen[region] {
+			return fmt.Errorf("rollout_order lists %s more than once", region)
+		}
+		seen[region] = true
+	}
+	return nil
+}
+
+// NewSmokeTestConfig returns a smoke test with the default timeout, for one given on the command line
+func NewSmokeTestConfig() *SmokeTestConfig {
+	return &SmokeTestConfig{Timeout: defaultSmokeTestTimeout}
+}
+
+func parseSmokeTest(raw interface{}) (*SmokeTestConfig, []ConfigError) {
+	section, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, []ConfigError{configErrorf("deploy", "smoke_test must be a section like [deploy.smoke_test]")}
+	}
+
+	st := NewSmokeTestConfig()
+	var errs []ConfigError
+
+	for k, v := range section {
+		switch k {
+		case "command":
+			cmd, ok := v.(string)
+			if !ok || strings.TrimSpace(cmd) == "" {
+				errs = append(errs, configErrorf("deploy.smoke_test", "command must be a command like \"./scripts/smoke-test.sh\""))
+			}
+			st.Command = cmd
+		case "http_path":
+			path, ok := v.(string)
+			if !ok || !strings.HasPrefix(path, "/") {
+				errs = append(errs, configErrorf("deploy.smoke_test", "http_path must be a path like \"/healthz\", got %v", v))
+			}
+			st.HTTPPath = path
+		case "port":
+			n, ok := toInt(v)
+			if !ok || n < 1 || n > 65535 {
+				errs = append(errs, configErrorf("deploy.smoke_test", "port must be a port number, got %v", v))
+			}
+			st.Port = n
+		case "timeout":
+			d, err := time.ParseDuration(fmt.Sprint(v))
+			if err != nil || d <= 0 {
+				errs = append(errs, configErrorf("deploy.smoke_test", "timeout must be a positive duration like \"2m\", got %v", v))
+			}
+			st.Timeout = d
+		default:
+			errs = append(errs, configErrorf("deploy.smoke_test", "unknown setting %s", k))
+		}
+	}
+
+	if st.Command == "" && st.HTTPPath == "" && len(errs) == 0 {
+		errs = append(errs, configErrorf("deploy.smoke_test", "needs a command, an http_path or both"))
+	}
+
+	return st, errs
+}
+
+func parseDeployHooks(raw interface{}) (DeployHooks, []ConfigError) {
+	var hooks DeployHooks
+	section, ok := raw.(map[string]interface{})
+	if !ok {
+		return hooks, []ConfigError{configErrorf("deploy", "hooks must be a section like [deploy.hooks]")}
+	}
+
+	var errs []ConfigError
+	for k, v := range section {
+		cmd, ok := v.(string)
+		if !ok || strings.TrimSpace(cmd) == "" {
+			cmd = ""
+		}
+		switch k {
+		case "success":
+			hooks.Success = cmd
+		case "failure":
+			hooks.Failure = cmd
+		default:
+			errs = append(errs, configErrorf("deploy.hooks", "unknown hook %s, use success or failure", k))
+			continue
+		}
+		if cmd == "" {
+			errs = append(errs, configErrorf("deploy.hooks", "%s must be a command like \"./notify.sh\"", k))
+		}
+	}
+	return hooks, errs
+}
+
+func parseDeployNotify(raw interface{}) (DeployNotify, []ConfigError) {
+	var notify DeployNotify
+	section, ok := raw.(map[string]interface{})
+	if !ok {
+		return notify, []ConfigError{configErrorf("deploy", "notify must be a section like [deploy.notify]")}
+	}
+
+	var errs []ConfigError
+	for k, v := range section {
+		url, _ := v.(string)
+		switch k {
+		case "webhook":
+			notify.Webhook = url
+		case "slack":
+			notify.Slack = url
+		default:
+			errs = append(errs, configErrorf("deploy.notify", "unknown setting %s, use webhook or slack", k))
+			continue
+		}
+		if !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+			errs = append(errs, configErrorf("deploy.notify", "%s must be a URL like \"https://hooks.example.com/deploys\", got %v", k, v))
+		}
+	}
+	return notify, errs
+}
+
+// RolloutSummary describes how a staged rollout will move through regions
+func (dc *DeployConfig) RolloutSummary() string {
+	return fmt.Sprintf("Regions are deployed to one at a time in the order %s, then any others. Each region's new instances must stay healthy for %s before the next region starts, and the deployment is rolled back if their checks fail.", strings.Join(dc.RolloutOrder, ", "), dc.BakeTime)
+}
+
+// SmokeTestSummary describes how the first new instance will be checked before the rest are replaced
+func (dc *DeployConfig) SmokeTestSummary() string {
+	st := dc.SmokeTest
+	var checks []string
+	if st.HTTPPath != "" {
+		checks = append(checks, fmt.Sprintf("GET %s must respond with a 2xx status", st.HTTPPath))
+	}
+	if st.Command != "" {
+		checks = append(checks, fmt.Sprintf("\"%s\" must succeed", st.Command))
+	}
+	return fmt.Sprintf("Once the first new instance passes its health checks, %s within %s before the rest are replaced. Otherwise the deployment is rolled back.", strings.Join(checks, " and "), st.Timeout)
+}
+
+// WebsocketSummary describes how the websocket strategy will replace instances
+func (dc *DeployConfig) WebsocketSummary() string {
+	return fmt.Sprintf("New instances start alongside the old ones and take all new connections. Old instances stop once they have %d or fewer connections open, or after %s.", dc.ConnectionThreshold, dc.MaxConnectionWait)
+}
+
+// CanarySummary describes how the canary strategy will verify and replace instances
+func (dc *DeployConfig) CanarySummary() string {
+	if dc.CanaryVerify == "" {
+		return "One instance of the new release starts first, and the rest are replaced once its health checks pass."
+	}
+	return fmt.Sprintf("One instance of the new release starts first. Once its health checks pass, \"%s\" runs against it, and the rest are replaced if it succeeds within %s. Otherwise the deployment is rolled back.", dc.CanaryVerify, dc.CanaryVerifyTimeout)
+}
+
+// BlueGreenSummary describes how the bluegreen strategy will switch traffic to the new instances
+func (dc *DeployConfig) BlueGreenSummary() string {
+	if !dc.ManualPromote {
+		return "A full set of new instances starts alongside the old ones, and traffic switches to them once they're all healthy."
+	}
+	return "A full set of new instances starts alongside the old ones. Once they're all healthy, traffic stays on the old instances until the release is promoted with `releases promote`, or the new instances are removed with `releases cancel`."
+}
@@ -0,0 +1,93 @@
+package flyctl
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/superfly/flyctl/helpers"
+)
+
+// EnvironmentConfigFile returns the config file for an environment alongside configFile, like
+// fly.staging.toml for fly.toml
+func EnvironmentConfigFile(configFile, environment string) string {
+	ext := filepath.Ext(configFile)
+	return strings.TrimSuffix(configFile, ext) + "." + environment + ext
+}
+
+// LoadAppConfigForEnvironment loads the config for a named environment, like staging. A file for
+// the environment alongside configFile is loaded on its own when there is one. Otherwise the
+// [environments.<name>] section of configFile is merged over the rest of it. The path of the file
+// that was loaded is returned with the config.
+func LoadAppConfigForEnvironment(configFile, environment string) (*AppConfig, string, error) {
+	return LoadAppConfigTemplate(configFile, environment, nil)
+}
+
+// LoadAppConfigTemplate loads configFile, or the config for environment when it's set, rendering
+// the files as Go templates with values first. With nil values they're loaded as they are, like
+// LoadAppConfigForEnvironment. The path of the file that was loaded is returned with the config.
+func LoadAppConfigTemplate(configFile, environment string, values map[string]interface{}) (*AppConfig, string, error) {
+	if environment == "" {
+		cfg, err := loadAppConfig(configFile, "", values)
+		return cfg, configFile, err
+	}
+
+	if envFile := EnvironmentConfigFile(configFile, environment); helpers.FileExists(envFile) {
+		cfg, err := loadAppConfig(envFile, "", values)
+		if cfg != nil {
+			cfg.Environment = environment
+		}
+		return cfg, envFile, err
+	}
+
+	if !helpers.FileExists(configFile) {
+		return nil, "", fmt.Errorf("no config for the %s environment, expected %s", environment, EnvironmentConfigFile(configFile, environment))
+	}
+
+	cfg, err := loadAppConfig(configFile, environment, values)
+	return cfg, configFile, err
+}
+
+// applyEnvironment moves the [environments] section out of a config's raw data, then merges the
+// named environment's settings over the rest
+func (ac *AppConfig) applyEnvironment(data map[string]interface{}) error {
+	raw, ok := data["environments"]
+	delete(data, "environments")
+	if ok {
+		envs, ok := raw.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("environments must be a section of environment names, like [environments.staging]")
+		}
+		for name, env := range envs {
+			if _, ok := env.(map[string]interface{}); !ok {
+				return fmt.Errorf("environments.%s must be a section like [environments.%s]", name, name)
+			}
+		}
+		ac.Environments = envs
+	}
+
+	if ac.Environment == "" {
+		return nil
+	}
+
+	overlay, ok := ac.Environments[ac.Environment].(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("no [environments.%s] section for the %s environment", ac.Environment, ac.Environment)
+	}
+	mergeDefinition(data, overlay)
+	return nil
+}
+
+// mergeDefinition merges overlay into data. Sections are merged setting by setting, while lists,
+// like [[services]], and values replace what's in data.
+func mergeDefinition(data, overlay map[string]interface{}) {
+	for k, v := range overlay {
+		section, isSection := v.(map[string]interface{})
+		existing, hasSection := data[k].(map[string]interface{})
+		if isSection && hasSection {
+			mergeDefinition(existing, section)
+			continue
+		}
+		data[k] = v
+	}
+}
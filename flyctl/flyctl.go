@@ -64,6 +64,7 @@ func initViper() {
 
 	viper.BindEnv(ConfigVerboseOutput, "VERBOSE")
 	viper.BindEnv(ConfigGQLErrorLogging, "GQLErrorLogging")
+	viper.BindEnv(ConfigReadOnly, "FLY_READ_ONLY")
 
 	viper.SetEnvPrefix("FLY")
 	viper.AutomaticEnv()
@@ -0,0 +1,63 @@
+package flyctl
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// applyIncludes merges the config at path over the files listed in its include setting, like
+// include = ["../base.toml"]. Included files are relative to the file including them, may be in
+// any supported format and may include files of their own. Later files override earlier ones and
+// the including file overrides them all, merged the same way as [environments].
+func (ac *AppConfig) applyIncludes(path string, data map[string]interface{}, including []string) (map[string]interface{}, error) {
+	raw, ok := data["include"]
+	delete(data, "include")
+	if !ok {
+		return data, nil
+	}
+
+	var files []string
+	switch v := raw.(type) {
+	case string:
+		files = []string{v}
+	case []interface{}:
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("%s: include must be a list of file paths, got %v", path, item)
+			}
+			files = append(files, s)
+		}
+	default:
+		return nil, fmt.Errorf("%s: include must be a list of file paths, got %v", path, raw)
+	}
+
+	including = append(including, path)
+	merged := map[string]interface{}{}
+
+	for _, file := range files {
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(path), file)
+		}
+		for _, p := range including {
+			if p == file {
+				return nil, fmt.Errorf("%s: include cycle %s -> %s", path, strings.Join(including, " -> "), file)
+			}
+		}
+
+		included, err := ac.decodeConfigFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("%s: can't include %s: %w", path, file, err)
+		}
+		if included, err = ac.applyIncludes(file, included, including); err != nil {
+			return nil, err
+		}
+
+		mergeDefinition(merged, included)
+		ac.Includes = append(ac.Includes, file)
+	}
+
+	mergeDefinition(merged, data)
+	return merged, nil
+}
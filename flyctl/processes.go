@@ -0,0 +1,132 @@
+package flyctl
+
+import (
+	"fmt"
+)
+
+// ProcessGroup is an entry of the [processes] section, a command run as its own group of instances
+type ProcessGroup struct {
+	Name    string
+	Command string
+	// VMSize is the group's VM size, like shared-cpu-2x, the app's size when empty
+	VMSize string
+	// Count is how many instances the group runs, its current count when zero
+	Count int
+	// BuildTarget is the Dockerfile stage built for the group, overriding [build.targets]
+	BuildTarget string
+	// Env is merged over [env] for the group's instances only
+	Env map[string]string
+	// Secrets names the app's secrets scoped to the group, other groups don't get them
+	Secrets []string
+}
+
+// ProcessGroups returns the [processes] section, sorted by name. Each process is either a command
+// or a section with its own VM size, count, build target, environment and secrets:
+//
+//	[processes]
+//	  web = "bin/server"
+//	  [processes.worker]
+//	    command = "bin/worker"
+//	    vm_size = "dedicated-cpu-1x"
+//	    count = 2
+//	    build_target = "worker"
+//	    secrets = ["AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY"]
+//	    [processes.worker.env]
+//	      QUEUE = "default"
+func (ac *AppConfig) ProcessGroups() ([]ProcessGroup, []ConfigError) {
+	raw, ok := ac.Definition["processes"]
+	if !ok {
+		return nil, nil
+	}
+
+	processes, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, []ConfigError{configErrorf("processes", "must be a table of process names to commands")}
+	}
+
+	var groups []ProcessGroup
+	var errs []ConfigError
+
+	for _, name := range sortedKeys(processes) {
+		path := "processes." + name
+		g := ProcessGroup{Name: name}
+
+		switch v := processes[name].(type) {
+		case string:
+			g.Command = v
+		case map[string]interface{}:
+			for _, k := range sortedKeys(v) {
+				switch k {
+				case "command", "vm_size", "build_target":
+					s, ok := v[k].(string)
+					if !ok {
+						errs = append(errs, configErrorf(path+"."+k, "must be a string"))
+						continue
+					}
+					switch k {
+					case "command":
+						g.Command = s
+					case "vm_size":
+						g.VMSize = s
+					case "build_target":
+						g.BuildTarget = s
+					}
+				case "count":
+					n, ok := toInt(v[k])
+					if !ok || n < 0 {
+						errs = append(errs, configErrorf(path+".count", "must be a whole number of instances"))
+						continue
+					}
+					g.Count = n
+				case "env":
+					env, ok := v[k].(map[string]interface{})
+					if !ok {
+						errs = append(errs, configErrorf(path+".env", "must be a section of environment variables"))
+						continue
+					}
+					g.Env = make(map[string]string, len(env))
+					for name, value := range env {
+						g.Env[name] = fmt.Sprint(value)
+					}
+				case "secrets":
+					list, ok := v[k].([]interface{})
+					if !ok {
+						errs = append(errs, configErrorf(path+".secrets", "must be a list of secret names like [\"AWS_ACCESS_KEY_ID\"]"))
+						continue
+					}
+					for _, item := range list {
+						name, ok := item.(string)
+						if !ok || !secretNamePattern.MatchString(name) {
+							errs = append(errs, configErrorf(path+".secrets", "%v isn't a valid secret name", item))
+							continue
+						}
+						g.Secrets = append(g.Secrets, name)
+					}
+				default:
+					errs = append(errs, configErrorf(path, "unknown setting %s, a process can set command, vm_size, count, build_target, env and secrets", k))
+				}
+			}
+		default:
+			errs = append(errs, configErrorf(path, "must be a command or a section like [%s]", path))
+			continue
+		}
+
+		if g.Command == "" {
+			errs = append(errs, configErrorf(path, "command is required"))
+			continue
+		}
+		groups = append(groups, g)
+	}
+
+	return groups, errs
+}
+
+// ProcessCommands returns the command of each process group, the form of [processes] the platform
+// reads
+func ProcessCommands(groups []ProcessGroup) map[string]interface{} {
+	commands := make(map[string]interface{}, len(groups))
+	for _, g := range groups {
+		commands[g.Name] = g.Command
+	}
+	return commands
+}
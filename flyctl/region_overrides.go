@@ -0,0 +1,119 @@
+package flyctl
+
+import (
+	"fmt"
+)
+
+// RegionOverride is a [regions.<code>] section, tuning the app's instances in one region
+type RegionOverride struct {
+	Region string
+	// Env is merged over [env] for instances in the region
+	Env map[string]string
+	// VMSize replaces the app's VM size in the region, like shared-cpu-2x
+	VMSize string
+	// Concurrency replaces the limits of every service in the region, nil to keep them
+	Concurrency *RegionConcurrency
+}
+
+// RegionConcurrency is a region's [regions.<code>.concurrency] section
+type RegionConcurrency struct {
+	SoftLimit int
+	HardLimit int
+}
+
+// RegionOverrides returns the [regions.<code>] sections, which share [regions] with process group
+// pins and are told apart by being sections rather than lists:
+//
+//	[regions.fra]
+//	  vm_size = "shared-cpu-2x"
+//	  [regions.fra.env]
+//	    DATABASE_URL = "postgres://fra.db.internal/app"
+//	  [regions.fra.concurrency]
+//	    soft_limit = 40
+//	    hard_limit = 50
+func (ac *AppConfig) RegionOverrides() ([]RegionOverride, []ConfigError) {
+	groups, ok := ac.Definition["regions"].(map[string]interface{})
+	if !ok {
+		return nil, nil
+	}
+
+	var overrides []RegionOverride
+	var errs []ConfigError
+
+	for _, region := range sortedKeys(groups) {
+		section, ok := groups[region].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		path := "regions." + region
+		o := RegionOverride{Region: region}
+
+		for _, k := range sortedKeys(section) {
+			v := section[k]
+			switch k {
+			case "env":
+				env, ok := v.(map[string]interface{})
+				if !ok {
+					errs = append(errs, configErrorf(path+".env", "must be a section of environment variables"))
+					continue
+				}
+				o.Env = make(map[string]string, len(env))
+				for name, value := range env {
+					o.Env[name] = fmt.Sprint(value)
+				}
+			case "vm_size":
+				s, ok := v.(string)
+				if !ok || s == "" {
+					errs = append(errs, configErrorf(path+".vm_size", "must be a VM size like \"shared-cpu-2x\""))
+					continue
+				}
+				o.VMSize = s
+			case "concurrency":
+				c, cerrs := parseRegionConcurrency(path+".concurrency", v)
+				errs = append(errs, cerrs...)
+				o.Concurrency = c
+			default:
+				errs = append(errs, configErrorf(path, "unknown setting %s, a region can set env, vm_size and concurrency", k))
+			}
+		}
+
+		overrides = append(overrides, o)
+	}
+
+	return overrides, errs
+}
+
+func parseRegionConcurrency(path string, v interface{}) (*RegionConcurrency, []ConfigError) {
+	section, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, []ConfigError{configErrorf(path, "must be a section with soft_limit and hard_limit")}
+	}
+
+	var c RegionConcurrency
+	var errs []ConfigError
+	for _, k := range sortedKeys(section) {
+		var limit *int
+		switch k {
+		case "soft_limit":
+			limit = &c.SoftLimit
+		case "hard_limit":
+			limit = &c.HardLimit
+		default:
+			errs = append(errs, configErrorf(path, "unknown setting %s", k))
+			continue
+		}
+		n, ok := toInt(section[k])
+		if !ok || n < 1 {
+			errs = append(errs, configErrorf(path+"."+k, "must be a positive whole number"))
+			continue
+		}
+		*limit = n
+	}
+	if c.SoftLimit > 0 && c.HardLimit > 0 && c.SoftLimit > c.HardLimit {
+		errs = append(errs, configErrorf(path+".soft_limit", "%d is over the hard_limit of %d", c.SoftLimit, c.HardLimit))
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return &c, nil
+}
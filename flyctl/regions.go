@@ -0,0 +1,119 @@
+package flyctl
+
+import (
+	"fmt"
+	"sort"
+)
+
+// ProcessRegions returns the regions each process group is pinned to by the [regions] section,
+// keeping workers near a database while web instances run closer to users:
+//
+//	[regions]
+//	  app = ["iad", "lhr"]
+//	  worker = ["iad"]
+//
+// Sections in [regions], like [regions.fra], are region overrides and are skipped here.
+func (ac *AppConfig) ProcessRegions() (map[string][]string, []ConfigError) {
+	raw, ok := ac.Definition["regions"]
+	if !ok {
+		return nil, nil
+	}
+
+	groups, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, []ConfigError{configErrorf("regions", "must be a table of process group names to lists of regions, or region overrides like [regions.fra]")}
+	}
+
+	pins := map[string][]string{}
+	var errs []ConfigError
+
+	for _, group := range sortedKeys(groups) {
+		if _, ok := groups[group].(map[string]interface{}); ok {
+			continue
+		}
+		list, ok := groups[group].([]interface{})
+		if !ok || len(list) == 0 {
+			errs = append(errs, configErrorf("regions."+group, "must be a list of region codes like [\"iad\", \"lhr\"]"))
+			continue
+		}
+		for _, region := range list {
+			pins[group] = append(pins[group], fmt.Sprint(region))
+		}
+	}
+
+	return pins, errs
+}
+
+// MountSource returns the name of the volume the app mounts, or an empty string
+func (ac *AppConfig) MountSource() string {
+	switch mounts := ac.Definition["mounts"].(type) {
+	case map[string]interface{}:
+		if source, ok := mounts["source"]; ok {
+			return fmt.Sprint(source)
+		}
+	case []map[string]interface{}:
+		if len(mounts) > 0 {
+			if source, ok := mounts[0]["source"]; ok {
+				return fmt.Sprint(source)
+			}
+		}
+	}
+	return ""
+}
+
+// RegionPlacement is what pinned process groups need to be placed
+type RegionPlacement struct {
+	// Counts is the number of instances of each process group
+	Counts map[string]int
+	// Volume is the name of the volume the app mounts, empty when it doesn't mount one
+	Volume string
+	// VolumeRegions counts the volumes named Volume in each region
+	VolumeRegions map[string]int
+}
+
+// ValidateRegionPins checks that each pinned process group can be placed in its regions. Instances
+// of an app mounting a volume need one in their region, so a region without one is an error. A
+// group with fewer instances than regions leaves some of them empty, which is only a warning.
+func ValidateRegionPins(pins map[string][]string, placement RegionPlacement) (errs []string, warnings []string) {
+	groups := make([]string, 0, len(pins))
+	for group := range pins {
+		groups = append(groups, group)
+	}
+	sort.Strings(groups)
+
+	for _, group := range groups {
+		regions := pins[group]
+		count, known := placement.Counts[group]
+
+		if !known {
+			warnings = append(warnings, fmt.Sprintf("process group %s has no instances yet, the pin applies once it's scaled up", group))
+		} else if count > 0 && count < len(regions) {
+			warnings = append(warnings, fmt.Sprintf("process group %s has %d instances for %d regions, some regions won't run it", group, count, len(regions)))
+		}
+
+		if placement.Volume == "" {
+			continue
+		}
+		volumes := 0
+		for _, region := range regions {
+			if placement.VolumeRegions[region] == 0 {
+				errs = append(errs, fmt.Sprintf("process group %s can't run in %s, it mounts volume %s and there is none in %s", group, region, placement.Volume, region))
+			}
+			volumes += placement.VolumeRegions[region]
+		}
+		if count > volumes && volumes > 0 {
+			errs = append(errs, fmt.Sprintf("process group %s has %d instances but only %d %s volumes in its regions, each instance needs its own", group, count, volumes, placement.Volume))
+		}
+	}
+
+	return errs, warnings
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,67 @@
+package flyctl
+
+import (
+	"regexp"
+
+	"github.com/superfly/flyctl/api"
+)
+
+var secretNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// RequiredSecrets returns the secrets the [secrets] section says the app needs, so a deploy can
+// fail before it releases an app that's missing one:
+//
+//	[secrets]
+//	  required = ["DATABASE_URL", "SECRET_KEY_BASE"]
+func (ac *AppConfig) RequiredSecrets() ([]string, []ConfigError) {
+	raw, ok := ac.Definition["secrets"]
+	if !ok {
+		return nil, nil
+	}
+
+	section, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, []ConfigError{configErrorf("secrets", "must be a section like [secrets]")}
+	}
+
+	var required []string
+	var errs []ConfigError
+
+	for _, k := range sortedKeys(section) {
+		if k != "required" {
+			errs = append(errs, configErrorf("secrets", "unknown setting %s", k))
+			continue
+		}
+		list, ok := section[k].([]interface{})
+		if !ok {
+			errs = append(errs, configErrorf("secrets.required", "must be a list of secret names like [\"DATABASE_URL\"]"))
+			continue
+		}
+		for _, item := range list {
+			name, ok := item.(string)
+			if !ok || !secretNamePattern.MatchString(name) {
+				errs = append(errs, configErrorf("secrets.required", "%v isn't a valid secret name", item))
+				continue
+			}
+			required = append(required, name)
+		}
+	}
+
+	return required, errs
+}
+
+// MissingSecrets returns the required secrets that aren't set, in the order they're required
+func MissingSecrets(required []string, set []api.Secret) []string {
+	names := make(map[string]bool, len(set))
+	for _, s := range set {
+		names[s.Name] = true
+	}
+
+	var missing []string
+	for _, name := range required {
+		if !names[name] {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
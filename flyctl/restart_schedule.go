@@ -0,0 +1,106 @@
+package flyctl
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/superfly/flyctl/internal/cron"
+)
+
+// RegionLocalTime is the restart timezone that runs the schedule in each region's own local time
+const RegionLocalTime = "region"
+
+// RestartSchedule holds the periodic restarts declared in fly.toml as [restart]. Instances are
+// restarted one at a time when the schedule fires, for apps that need regular recycling.
+type RestartSchedule struct {
+	Schedule *cron.Schedule
+	// Timezone is an IANA zone name, or RegionLocalTime
+	Timezone string
+	// SkipIfUnhealthy skips a scheduled restart while any instance is failing health checks,
+	// so a restart never takes down the last healthy instances
+	SkipIfUnhealthy bool
+}
+
+// RestartSchedule parses the [restart] section, returning nil when there isn't one
+func (ac *AppConfig) RestartSchedule() (*RestartSchedule, []ConfigError) {
+	raw, ok := ac.Definition["restart"]
+	if !ok {
+		return nil, nil
+	}
+
+	section, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, []ConfigError{configErrorf("restart", "must be a section like [restart]")}
+	}
+
+	rs := &RestartSchedule{Timezone: RegionLocalTime, SkipIfUnhealthy: true}
+	var errs []ConfigError
+
+	for k, v := range section {
+		switch k {
+		case "schedule":
+			s, err := cron.Parse(fmt.Sprint(v))
+			if err != nil {
+				errs = append(errs, configErrorf("restart", "%s", err))
+			}
+			rs.Schedule = s
+		case "timezone":
+			rs.Timezone = fmt.Sprint(v)
+			if rs.Timezone == RegionLocalTime {
+				continue
+			}
+			if _, err := time.LoadLocation(rs.Timezone); err != nil {
+				errs = append(errs, configErrorf("restart", "unknown timezone %s, use an IANA name like \"America/Chicago\" or \"%s\" for each region's local time", rs.Timezone, RegionLocalTime))
+			}
+		case "skip_if_unhealthy":
+			b, ok := v.(bool)
+			if !ok {
+				errs = append(errs, configErrorf("restart", "skip_if_unhealthy must be true or false"))
+			}
+			rs.SkipIfUnhealthy = b
+		default:
+			errs = append(errs, configErrorf("restart", "unknown setting %s", k))
+		}
+	}
+
+	if _, ok := section["schedule"]; !ok {
+		errs = append(errs, configErrorf("restart", "schedule is required, like \"0 4 * * *\" for 04:00 every day"))
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return rs, nil
+}
+
+// Next returns when the schedule next fires after now. It's false for region local schedules,
+// which fire at a different time in each region.
+func (rs *RestartSchedule) Next(now time.Time) (time.Time, bool) {
+	if rs.Timezone == RegionLocalTime {
+		return time.Time{}, false
+	}
+
+	loc, err := time.LoadLocation(rs.Timezone)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	next := rs.Schedule.Next(now.In(loc))
+	return next, !next.IsZero()
+}
+
+func (rs *RestartSchedule) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "\"%s\"", rs.Schedule)
+	if rs.Timezone == RegionLocalTime {
+		b.WriteString(" in each region's local time")
+	} else {
+		fmt.Fprintf(&b, " %s", rs.Timezone)
+	}
+	if rs.SkipIfUnhealthy {
+		b.WriteString(", skipped while instances are unhealthy")
+	}
+	return b.String()
+}
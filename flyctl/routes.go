@@ -0,0 +1,134 @@
+package flyctl
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Route holds per-path overrides for a service, declared in fly.toml as [[services.routes]]
+// and applied by the edge proxy when the config is deployed
+type Route struct {
+	InternalPort           int
+	Path                   string
+	QueueTimeout           time.Duration
+	ExcludeFromConcurrency bool
+	HardLimit              int
+	SoftLimit              int
+}
+
+// Routes parses the routes declared in each service
+func (ac *AppConfig) Routes() ([]Route, []ConfigError) {
+	var routes []Route
+	var errs []ConfigError
+
+	for i, service := range ac.services() {
+		port, _ := toInt(service["internal_port"])
+
+		rawRoutes, ok := service["routes"]
+		if !ok {
+			continue
+		}
+
+		seen := map[string]bool{}
+		for j, raw := range toMapSlice(rawRoutes) {
+			prefix := fmt.Sprintf("services[%d].routes[%d]", i, j)
+			route, routeErrs := parseRoute(raw, prefix)
+			errs = append(errs, routeErrs...)
+			if len(routeErrs) > 0 {
+				continue
+			}
+			if seen[route.Path] {
+				errs = append(errs, configErrorf(prefix, "path %s is declared more than once", route.Path))
+				continue
+			}
+			seen[route.Path] = true
+			route.InternalPort = port
+			routes = append(routes, route)
+		}
+	}
+
+	return routes, errs
+}
+
+func parseRoute(raw map[string]interface{}, prefix string) (Route, []ConfigError) {
+	var r Route
+	var errs []ConfigError
+
+	for k, v := range raw {
+		switch k {
+		case "path":
+			r.Path = fmt.Sprint(v)
+		case "queue_timeout":
+			d, err := time.ParseDuration(fmt.Sprint(v))
+			if err != nil || d <= 0 {
+				errs = append(errs, configErrorf(prefix, "queue_timeout must be a positive duration like \"30s\", got %v", v))
+			}
+			r.QueueTimeout = d
+		case "exclude_from_concurrency":
+			b, ok := v.(bool)
+			if !ok {
+				errs = append(errs, configErrorf(prefix, "exclude_from_concurrency must be true or false"))
+			}
+			r.ExcludeFromConcurrency = b
+		case "hard_limit", "soft_limit":
+			n, ok := toInt(v)
+			if !ok || n <= 0 {
+				errs = append(errs, configErrorf(prefix, "%s must be a positive number", k))
+			}
+			if k == "hard_limit" {
+				r.HardLimit = n
+			} else {
+				r.SoftLimit = n
+			}
+		default:
+			errs = append(errs, configErrorf(prefix, "unknown setting %s", k))
+		}
+	}
+
+	if !strings.HasPrefix(r.Path, "/") {
+		errs = append(errs, configErrorf(prefix, "path must start with /"))
+	}
+	if r.ExcludeFromConcurrency && (r.HardLimit > 0 || r.SoftLimit > 0) {
+		errs = append(errs, configErrorf(prefix, "concurrency limits can't be set on a route excluded from concurrency"))
+	}
+	if r.HardLimit > 0 && r.SoftLimit > r.HardLimit {
+		errs = append(errs, configErrorf(prefix, "soft_limit can't be greater than hard_limit"))
+	}
+
+	return r, errs
+}
+
+// services returns the services section, which is a slice of maps when decoded from toml
+// and a slice of interfaces when decoded from json
+func (ac *AppConfig) services() []map[string]interface{} {
+	return toMapSlice(ac.Definition["services"])
+}
+
+func toMapSlice(v interface{}) []map[string]interface{} {
+	switch s := v.(type) {
+	case []map[string]interface{}:
+		return s
+	case []interface{}:
+		out := make([]map[string]interface{}, 0, len(s))
+		for _, item := range s {
+			if m, ok := item.(map[string]interface{}); ok {
+				out = append(out, m)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func toInt(v interface{}) (int, bool) {
+	switch n := v.(type) {
+	case int:
+		return n, true
+	case int64:
+		return int(n), true
+	case float64:
+		return int(n), true
+	}
+	return 0, false
+}
@@ -0,0 +1,400 @@
+package flyctl
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type schemaKind int
+
+const (
+	// kindAny accepts any value, for sections checked elsewhere or free-form like [build.args]
+	kindAny schemaKind = iota
+	kindString
+	kindInt
+	kindBool
+	// kindDuration is a duration string like "10s", or a whole number of milliseconds
+	kindDuration
+	// kindPort is a port number, which fly.toml also accepts as a string like "443"
+	kindPort
+	kindStringList
+	// kindStringMap is a section of string values, like [env]
+	kindStringMap
+	kindSection
+	// kindSectionList is a list of sections, like [[services]]
+	kindSectionList
+)
+
+// schemaNode describes a setting of fly.toml
+type schemaNode struct {
+	kind schemaKind
+	// fields are the settings of a section, or of each section in a list
+	fields map[string]*schemaNode
+	// oneOf lists the allowed values of a string or string list setting
+	oneOf []string
+}
+
+func schemaSection(fields map[string]*schemaNode) *schemaNode {
+	return &schemaNode{kind: kindSection, fields: fields}
+}
+
+func schemaSectionList(fields map[string]*schemaNode) *schemaNode {
+	return &schemaNode{kind: kindSectionList, fields: fields}
+}
+
+func schemaOneOf(kind schemaKind, values ...string) *schemaNode {
+	return &schemaNode{kind: kind, oneOf: values}
+}
+
+var (
+	schemaAny        = &schemaNode{kind: kindAny}
+	schemaString     = &schemaNode{kind: kindString}
+	schemaInt        = &schemaNode{kind: kindInt}
+	schemaBool       = &schemaNode{kind: kindBool}
+	schemaDuration   = &schemaNode{kind: kindDuration}
+	schemaPort       = &schemaNode{kind: kindPort}
+	schemaStringList = &schemaNode{kind: kindStringList}
+	schemaStringMap  = &schemaNode{kind: kindStringMap}
+)
+
+// ServiceHandlers are the handlers a service port can list
+var ServiceHandlers = []string{"http", "tls", "proxy_proto", "pg_tls", "edge_http"}
+
+var checkFields = map[string]*schemaNode{
+	"interval":      schemaDuration,
+	"timeout":       schemaDuration,
+	"grace_period":  schemaDuration,
+	"restart_limit": schemaInt,
+}
+
+var httpCheckFields = map[string]*schemaNode{
+	"method":          schemaOneOf(kindString, "get", "head", "post", "GET", "HEAD", "POST"),
+	"path":            schemaString,
+	"protocol":        schemaOneOf(kindString, "http", "https"),
+	"tls_skip_verify": schemaBool,
+	"headers":         schemaStringMap,
+}
+
+func init() {
+	for k, v := range checkFields {
+		httpCheckFields[k] = v
+	}
+}
+
+// appConfigSchema is every setting fly.toml can have. Sections with their own parsers, like
+// [deploy] and [restart], accept anything here and are checked by those parsers.
+var appConfigSchema = schemaSection(map[string]*schemaNode{
+	"app":            schemaString,
+	"primary_region": schemaString,
+	"kill_signal":    schemaOneOf(kindString, "SIGINT", "SIGTERM", "SIGQUIT", "SIGUSR1", "SIGUSR2", "SIGKILL", "SIGSTOP"),
+	"kill_timeout":   schemaInt,
+	"build": schemaSection(map[string]*schemaNode{
+		"builder":           schemaString,
+		"buildpacks":        schemaStringList,
+		"args":              schemaStringMap,
+		"builtin":           schemaString,
+		"settings":          schemaAny,
+		"image":             schemaString,
+		"timeout":           schemaDuration,
+		"builder_timeout":   schemaDuration,
+		"scan":              schemaBool,
+		"scan_severity":     schemaOneOf(kindString, "UNKNOWN", "LOW", "MEDIUM", "HIGH", "CRITICAL"),
+		"scan_image":        schemaString,
+		"reproducible":      schemaBool,
+		"push_to":           schemaStringList,
+		"target":            schemaString,
+		"targets":           schemaStringMap,
+		"dockerfile":        schemaString,
+		"compression_level": schemaInt,
+		"hooks": schemaSection(map[string]*schemaNode{
+			"pre":  schemaString,
+			"post": schemaString,
+		}),
+	}),
+	"env":          schemaStringMap,
+	"secrets":      schemaAny,
+	"experimental": schemaAny,
+	"deploy":       schemaAny,
+	"restart":      schemaAny,
+	"regions":      schemaAny,
+	"processes":    schemaAny,
+	"mounts": schemaSectionList(map[string]*schemaNode{
+		"source":      schemaString,
+		"destination": schemaString,
+	}),
+	"statics": schemaSectionList(map[string]*schemaNode{
+		"guest_path": schemaString,
+		"url_prefix": schemaString,
+	}),
+	"metrics": schemaSection(map[string]*schemaNode{
+		"port": schemaPort,
+		"path": schemaString,
+	}),
+	"services": schemaSectionList(map[string]*schemaNode{
+		"internal_port": schemaPort,
+		"protocol":      schemaOneOf(kindString, "tcp", "udp"),
+		"processes":     schemaStringList,
+		"concurrency": schemaSection(map[string]*schemaNode{
+			"type":       schemaOneOf(kindString, "connections", "requests"),
+			"hard_limit": schemaInt,
+			"soft_limit": schemaInt,
+		}),
+		"ports": schemaSectionList(map[string]*schemaNode{
+			"port":        schemaPort,
+			"handlers":    schemaOneOf(kindStringList, ServiceHandlers...),
+			"force_https": schemaBool,
+			"tls_options": schemaAny,
+		}),
+		"http_checks":   schemaSectionList(httpCheckFields),
+		"tcp_checks":    schemaSectionList(checkFields),
+		"script_checks": schemaAny,
+		"routes":        schemaAny,
+		"warmup":        schemaAny,
+	}),
+})
+
+// check appends the problems with v, found at path, to errs
+func (n *schemaNode) check(path string, v interface{}, errs []ConfigError) []ConfigError {
+	fail := func(format string, args ...interface{}) []ConfigError {
+		return append(errs, ConfigError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	switch n.kind {
+	case kindAny:
+		return errs
+	case kindString:
+		s, ok := v.(string)
+		if !ok {
+			return fail("must be a string, got %v", v)
+		}
+		if len(n.oneOf) > 0 && !containsString(n.oneOf, s) {
+			return fail("must be one of %s, got %s", strings.Join(n.oneOf, ", "), s)
+		}
+	case kindInt:
+		if _, ok := toInt(v); !ok {
+			return fail("must be a whole number, got %v", v)
+		}
+	case kindBool:
+		if _, ok := v.(bool); !ok {
+			return fail("must be true or false, got %v", v)
+		}
+	case kindDuration:
+		if d, ok := toDuration(v); !ok || d <= 0 {
+			return fail("must be a positive duration like \"10s\", got %v", v)
+		}
+	case kindPort:
+		if p, ok := toPort(v); !ok || p < 1 || p > 65535 {
+			return fail("must be a port number from 1 to 65535, got %v", v)
+		}
+	case kindStringList:
+		list, ok := v.([]interface{})
+		if !ok {
+			return fail("must be a list of strings, got %v", v)
+		}
+		for i, item := range list {
+			s, ok := item.(string)
+			switch {
+			case !ok:
+				errs = append(errs, ConfigError{Path: fmt.Sprintf("%s[%d]", path, i), Message: fmt.Sprintf("must be a string, got %v", item)})
+			case len(n.oneOf) > 0 && !containsString(n.oneOf, s):
+				errs = append(errs, ConfigError{Path: fmt.Sprintf("%s[%d]", path, i), Message: fmt.Sprintf("must be one of %s, got %s", strings.Join(n.oneOf, ", "), s)})
+			}
+		}
+	case kindStringMap:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return fail("must be a section of string values")
+		}
+		for _, k := range sortedKeys(m) {
+			switch m[k].(type) {
+			case string, int64, float64, bool:
+			default:
+				errs = append(errs, ConfigError{Path: path + "." + k, Message: fmt.Sprintf("must be a string, got %v", m[k])})
+			}
+		}
+	case kindSection:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return fail("must be a section like [%s]", path)
+		}
+		errs = n.checkFields(path, m, errs)
+	case kindSectionList:
+		if m, ok := v.(map[string]interface{}); ok {
+			// a single [section] is a list of one
+			return n.checkFields(path, m, errs)
+		}
+		list := toMapSlice(v)
+		if list == nil {
+			return fail("must be a list of sections like [[%s]]", path)
+		}
+		for i, m := range list {
+			errs = n.checkFields(fmt.Sprintf("%s[%d]", path, i), m, errs)
+		}
+	}
+
+	return errs
+}
+
+func (n *schemaNode) checkFields(path string, m map[string]interface{}, errs []ConfigError) []ConfigError {
+	for _, k := range sortedKeys(m) {
+		fieldPath := k
+		if path != "" {
+			fieldPath = path + "." + k
+		}
+		field, ok := n.fields[k]
+		if !ok {
+			errs = append(errs, ConfigError{Path: fieldPath, Message: "unknown setting"})
+			continue
+		}
+		errs = field.check(fieldPath, m[k], errs)
+	}
+	return errs
+}
+
+// toDuration reads a duration string, or a whole number of milliseconds
+func toDuration(v interface{}) (time.Duration, bool) {
+	if n, ok := toInt(v); ok {
+		return time.Duration(n) * time.Millisecond, true
+	}
+	s, ok := v.(string)
+	if !ok {
+		return 0, false
+	}
+	d, err := time.ParseDuration(s)
+	return d, err == nil
+}
+
+// toPort reads a port number, given as a number or a string
+func toPort(v interface{}) (int, bool) {
+	if n, ok := toInt(v); ok {
+		return n, true
+	}
+	s, ok := v.(string)
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(s)
+	return n, err == nil
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// durationPattern matches the duration strings time.ParseDuration accepts
+const durationPattern = `^([0-9]+(\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$`
+
+// AppConfigJSONSchema returns a JSON Schema (draft 7) for fly.toml, for editors to validate and
+// complete config files with. It's generated from the same schema as AppConfig.Validate, plus
+// the settings flyctl handles before validation like include and [environments].
+func AppConfigJSONSchema() map[string]interface{} {
+	schema := appConfigSchema.jsonSchema()
+	schema["$schema"] = "http://json-schema.org/draft-07/schema#"
+	schema["title"] = "fly.toml"
+	schema["description"] = "Fly application configuration"
+
+	properties := schema["properties"].(map[string]interface{})
+	properties["include"] = map[string]interface{}{
+		"description": "Config files this one is merged over, relative to it",
+		"oneOf": []interface{}{
+			map[string]interface{}{"type": "string"},
+			map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+		},
+	}
+	properties["processes"] = map[string]interface{}{
+		"type": "object",
+		"additionalProperties": map[string]interface{}{
+			"oneOf": []interface{}{
+				map[string]interface{}{"type": "string"},
+				schemaSection(map[string]*schemaNode{
+					"command":      schemaString,
+					"vm_size":      schemaString,
+					"count":        schemaInt,
+					"build_target": schemaString,
+				}).jsonSchema(),
+			},
+		},
+	}
+	properties["secrets"] = schemaSection(map[string]*schemaNode{
+		"required": schemaStringList,
+	}).jsonSchema()
+	// each environment overlays any of the settings, so its sections aren't checked
+	properties["environments"] = map[string]interface{}{
+		"description":          "Settings merged over the rest of the config for each named environment",
+		"type":                 "object",
+		"additionalProperties": map[string]interface{}{"type": "object"},
+	}
+
+	return schema
+}
+
+func (n *schemaNode) jsonSchema() map[string]interface{} {
+	enum := func(kind string) map[string]interface{} {
+		s := map[string]interface{}{"type": kind}
+		if len(n.oneOf) > 0 {
+			s["enum"] = n.oneOf
+		}
+		return s
+	}
+
+	switch n.kind {
+	case kindString:
+		return enum("string")
+	case kindInt:
+		return map[string]interface{}{"type": "integer"}
+	case kindBool:
+		return map[string]interface{}{"type": "boolean"}
+	case kindDuration:
+		return map[string]interface{}{
+			"oneOf": []interface{}{
+				map[string]interface{}{"type": "string", "pattern": durationPattern},
+				map[string]interface{}{"type": "integer", "minimum": 1, "description": "milliseconds"},
+			},
+		}
+	case kindPort:
+		return map[string]interface{}{
+			"oneOf": []interface{}{
+				map[string]interface{}{"type": "integer", "minimum": 1, "maximum": 65535},
+				map[string]interface{}{"type": "string", "pattern": "^[0-9]+$"},
+			},
+		}
+	case kindStringList:
+		return map[string]interface{}{"type": "array", "items": enum("string")}
+	case kindStringMap:
+		return map[string]interface{}{
+			"type":                 "object",
+			"additionalProperties": map[string]interface{}{"type": []string{"string", "number", "boolean"}},
+		}
+	case kindSection:
+		return n.jsonObjectSchema()
+	case kindSectionList:
+		// a single [section] is a list of one
+		return map[string]interface{}{
+			"oneOf": []interface{}{
+				map[string]interface{}{"type": "array", "items": n.jsonObjectSchema()},
+				n.jsonObjectSchema(),
+			},
+		}
+	}
+
+	return map[string]interface{}{}
+}
+
+func (n *schemaNode) jsonObjectSchema() map[string]interface{} {
+	properties := make(map[string]interface{}, len(n.fields))
+	for k, field := range n.fields {
+		properties[k] = field.jsonSchema()
+	}
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
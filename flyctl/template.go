@@ -0,0 +1,57 @@
+package flyctl
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"text/template"
+)
+
+// RenderConfigTemplate renders a config file as a Go template with values, like
+// app = "{{ .name }}". Referencing a value that isn't set is an error, unless it's looked up with
+// index and given a fallback, like {{ index . "count" | default 1 }}. quote quotes a string.
+func RenderConfigTemplate(name string, source []byte, values map[string]interface{}) ([]byte, error) {
+	tmpl, err := template.New(filepath.Base(name)).
+		Option("missingkey=error").
+		Funcs(configTemplateFuncs).
+		Parse(string(source))
+	if err != nil {
+		return nil, fmt.Errorf("error parsing %s as a template: %w", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, values); err != nil {
+		return nil, fmt.Errorf("error rendering %s: %w", name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+var configTemplateFuncs = template.FuncMap{
+	// default returns fallback when v is missing or empty
+	"default": func(fallback, v interface{}) interface{} {
+		if v == nil || v == "" {
+			return fallback
+		}
+		return v
+	},
+	"quote": func(v interface{}) string {
+		return strconv.Quote(fmt.Sprint(v))
+	},
+}
+
+// LoadTemplateValues reads the values a config template is rendered with from a YAML or JSON file
+func LoadTemplateValues(path string) (map[string]interface{}, error) {
+	source, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// YAML is a superset of JSON, so one decoder reads both
+	values, err := decodeYAML(bytes.NewReader(source))
+	if err != nil {
+		return nil, fmt.Errorf("error reading template values from %s: %w", path, err)
+	}
+	return values, nil
+}
@@ -0,0 +1,237 @@
+package flyctl
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// ConfigError is a problem with a setting in fly.toml, located so editors can point at it. The
+// parsers of sections flyctl reads itself, like [deploy], return them too, so their problems are
+// reported in the same form as the schema's and the server's.
+type ConfigError struct {
+	// Path is the setting with the problem, like services[0].ports[1].handlers
+	Path string `json:"path,omitempty"`
+	// Line is the line of the config file the setting is on, zero when it isn't known
+	Line    int    `json:"line,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e ConfigError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+func configErrorf(path string, format string, args ...interface{}) ConfigError {
+	return ConfigError{Path: path, Message: fmt.Sprintf(format, args...)}
+}
+
+// minCheckInterval catches check intervals given as a number of seconds, they're milliseconds
+const minCheckInterval = time.Second
+
+// Validate checks the config against the platform's schema without contacting it: unknown
+// settings, values of the wrong type, invalid handlers, port conflicts and check timings, along
+// with the sections flyctl parses itself like [deploy] and [[services.routes]].
+func (ac *AppConfig) Validate() []ConfigError {
+	data := make(map[string]interface{}, len(ac.Definition)+1)
+	for k, v := range ac.Definition {
+		data[k] = v
+	}
+	if ac.AppName != "" {
+		data["app"] = ac.AppName
+	}
+
+	errs := appConfigSchema.checkFields("", data, nil)
+	errs = append(errs, ac.validateServices()...)
+
+	_, routeErrs := ac.Routes()
+	errs = append(errs, routeErrs...)
+	_, warmupErrs := ac.WarmupRequests()
+	errs = append(errs, warmupErrs...)
+	_, restartErrs := ac.RestartSchedule()
+	errs = append(errs, restartErrs...)
+	_, regionErrs := ac.ProcessRegions()
+	errs = append(errs, regionErrs...)
+	_, overrideErrs := ac.RegionOverrides()
+	errs = append(errs, overrideErrs...)
+	_, processErrs := ac.ProcessGroups()
+	errs = append(errs, processErrs...)
+	_, secretErrs := ac.RequiredSecrets()
+	errs = append(errs, secretErrs...)
+	_, deployErrs := ac.DeployConfig()
+	errs = append(errs, deployErrs...)
+
+	return errs
+}
+
+// validateServices checks what the schema can't: ports used by more than one service, handlers
+// that don't suit the service's protocol, and check timeouts that outlast their intervals
+func (ac *AppConfig) validateServices() []ConfigError {
+	var errs []ConfigError
+	// the first port declared for each external port and protocol
+	ports := map[string]string{}
+
+	for i, service := range ac.services() {
+		protocol, _ := service["protocol"].(string)
+		if protocol == "" {
+			protocol = "tcp"
+		}
+
+		for j, p := range toMapSlice(service["ports"]) {
+			path := fmt.Sprintf("services[%d].ports[%d]", i, j)
+
+			if n, ok := toPort(p["port"]); ok {
+				key := fmt.Sprintf("%d/%s", n, protocol)
+				if first, ok := ports[key]; ok {
+					errs = append(errs, ConfigError{Path: path + ".port", Message: fmt.Sprintf("%s port %d is already used by %s", protocol, n, first)})
+				} else {
+					ports[key] = path
+				}
+			}
+
+			handlers, _ := p["handlers"].([]interface{})
+			if protocol == "udp" && len(handlers) > 0 {
+				errs = append(errs, ConfigError{Path: path + ".handlers", Message: "udp services can't have handlers"})
+			}
+			seen := map[string]bool{}
+			for _, h := range handlers {
+				name := fmt.Sprint(h)
+				if seen[name] {
+					errs = append(errs, ConfigError{Path: path + ".handlers", Message: fmt.Sprintf("handler %s is listed more than once", name)})
+				}
+				seen[name] = true
+			}
+			if seen["http"] && seen["edge_http"] {
+				errs = append(errs, ConfigError{Path: path + ".handlers", Message: "http and edge_http can't be used together"})
+			}
+		}
+
+		for _, kind := range []string{"http_checks", "tcp_checks"} {
+			for j, check := range toMapSlice(service[kind]) {
+				errs = append(errs, validateCheckTimings(fmt.Sprintf("services[%d].%s[%d]", i, kind, j), check)...)
+			}
+		}
+	}
+
+	return errs
+}
+
+func validateCheckTimings(path string, check map[string]interface{}) []ConfigError {
+	var errs []ConfigError
+
+	interval, hasInterval := toDuration(check["interval"])
+	timeout, hasTimeout := toDuration(check["timeout"])
+
+	if hasInterval && interval > 0 && interval < minCheckInterval {
+		errs = append(errs, ConfigError{Path: path + ".interval", Message: fmt.Sprintf("interval of %s is under %s, numbers are milliseconds so use a duration like \"10s\"", interval, minCheckInterval)})
+	}
+	if hasInterval && hasTimeout && interval > 0 && timeout >= interval {
+		errs = append(errs, ConfigError{Path: path + ".timeout", Message: fmt.Sprintf("timeout of %s must be shorter than the interval of %s", timeout, interval)})
+	}
+
+	return errs
+}
+
+// MessageConfigErrors converts messages in the "path: message" form used by the API into ConfigErrors
+func MessageConfigErrors(msgs []string) []ConfigError {
+	errs := make([]ConfigError, 0, len(msgs))
+	for _, msg := range msgs {
+		e := ConfigError{Message: msg}
+		if i := strings.Index(msg, ": "); i > 0 && !strings.Contains(msg[:i], " ") {
+			e.Path, e.Message = msg[:i], msg[i+2:]
+		}
+		errs = append(errs, e)
+	}
+	return errs
+}
+
+// LocateConfigErrors sets the line of each error from the config file's source. Errors in
+// settings without a line of their own, like a list item, get the line of the setting holding them.
+func LocateConfigErrors(source []byte, errs []ConfigError) {
+	lines := configLines(string(source))
+	for i := range errs {
+		for path := errs[i].Path; path != ""; path = parentPath(path) {
+			if line, ok := lines[path]; ok {
+				errs[i].Line = line
+				break
+			}
+		}
+	}
+}
+
+var lastPathElement = regexp.MustCompile(`(\.?[^.\[\]]+|\[\d+\])$`)
+
+// parentPath strips the last key or index from a path, services[0].ports returns services[0]
+func parentPath(path string) string {
+	return lastPathElement.ReplaceAllString(path, "")
+}
+
+// configLines maps the settings and sections of a TOML config to the lines they're declared on
+func configLines(source string) map[string]int {
+	lines := map[string]int{}
+	// how many times each [[array]] has been declared
+	counts := map[string]int{}
+	table := ""
+
+	// resolve adds the index of the latest [[array]] to each part of a dotted table name
+	resolve := func(parts []string) string {
+		path := ""
+		for _, part := range parts {
+			if path != "" {
+				path += "."
+			}
+			path += strings.Trim(strings.TrimSpace(part), `"`)
+			if n := counts[path]; n > 0 {
+				path = fmt.Sprintf("%s[%d]", path, n-1)
+			}
+		}
+		return path
+	}
+
+	for i, line := range strings.Split(source, "\n") {
+		line = strings.TrimSpace(line)
+
+		switch {
+		case line == "" || strings.HasPrefix(line, "#"):
+			continue
+		case strings.HasPrefix(line, "[["):
+			end := strings.Index(line, "]]")
+			if end < 0 {
+				continue
+			}
+			parts := strings.Split(line[2:end], ".")
+			array := strings.Trim(strings.TrimSpace(parts[len(parts)-1]), `"`)
+			if parent := resolve(parts[:len(parts)-1]); parent != "" {
+				array = parent + "." + array
+			}
+			if counts[array] == 0 {
+				lines[array] = i + 1
+			}
+			table = fmt.Sprintf("%s[%d]", array, counts[array])
+			counts[array]++
+			lines[table] = i + 1
+		case strings.HasPrefix(line, "["):
+			end := strings.Index(line, "]")
+			if end < 0 {
+				continue
+			}
+			table = resolve(strings.Split(line[1:end], "."))
+			lines[table] = i + 1
+		default:
+			eq := strings.Index(line, "=")
+			if eq <= 0 {
+				continue
+			}
+			key := strings.Trim(strings.TrimSpace(line[:eq]), `"`)
+			if table != "" {
+				key = table + "." + key
+			}
+			lines[key] = i + 1
+		}
+	}
+
+	return lines
+}
@@ -0,0 +1,62 @@
+package flyctl
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	path := "./testdata/invalid.toml"
+	p, err := LoadAppConfig(path)
+	require.NoError(t, err)
+
+	errs := p.Validate()
+	source, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	LocateConfigErrors(source, errs)
+
+	assert.ElementsMatch(t, []ConfigError{
+		{Path: "kill_timeout", Line: 2, Message: "must be a whole number, got 5"},
+		{Path: "services[0].max_connections", Line: 10, Message: "unknown setting"},
+		{Path: "services[0].ports[1].handlers[1]", Line: 17, Message: "must be one of http, tls, proxy_proto, pg_tls, edge_http, got htp"},
+		{Path: "services[0].tcp_checks[0].interval", Line: 21, Message: "interval of 10ms is under 1s, numbers are milliseconds so use a duration like \"10s\""},
+		{Path: "services[0].tcp_checks[0].timeout", Line: 22, Message: "timeout of 2s must be shorter than the interval of 10ms"},
+		{Path: "services[1].ports[0].port", Line: 30, Message: "tcp port 80 is already used by services[0].ports[0]"},
+		{Path: "services[1].http_checks[0].timeout", Line: 34, Message: "timeout of 15s must be shorter than the interval of 10s"},
+		{Path: "deploy", Line: 37, Message: "unknown deploy strategy sideways, use one of canary, rolling, bluegreen, immediate, websocket"},
+	}, errs)
+
+	p, err = LoadAppConfig("./testdata/services.toml")
+	require.NoError(t, err)
+	assert.Equal(t, []ConfigError{{Path: "service", Message: "unknown setting"}}, p.Validate())
+}
+
+func TestParentPath(t *testing.T) {
+	assert.Equal(t, "services[0].ports[1]", parentPath("services[0].ports[1].handlers"))
+	assert.Equal(t, "services[0].ports", parentPath("services[0].ports[1]"))
+	assert.Equal(t, "", parentPath("deploy"))
+}
+
+func TestAppConfigJSONSchema(t *testing.T) {
+	schema := AppConfigJSONSchema()
+	_, err := json.Marshal(schema)
+	require.NoError(t, err)
+
+	properties := schema["properties"].(map[string]interface{})
+	assert.Equal(t, map[string]interface{}{"type": "string"}, properties["app"])
+	assert.Contains(t, properties, "include")
+
+	services := properties["services"].(map[string]interface{})["oneOf"].([]interface{})
+	service := services[0].(map[string]interface{})["items"].(map[string]interface{})
+	assert.Equal(t, false, service["additionalProperties"])
+	ports := service["properties"].(map[string]interface{})["ports"].(map[string]interface{})["oneOf"].([]interface{})
+	port := ports[0].(map[string]interface{})["items"].(map[string]interface{})["properties"].(map[string]interface{})
+	assert.Equal(t, ServiceHandlers, port["handlers"].(map[string]interface{})["items"].(map[string]interface{})["enum"])
+
+	assert.Regexp(t, durationPattern, "1m30s")
+	assert.NotRegexp(t, durationPattern, "10")
+}
@@ -0,0 +1,106 @@
+package flyctl
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WarmupRequest is a priming request sent to new instances of a service, declared in fly.toml as
+// [[services.warmup]]. Instances receive them after passing health checks and before they're
+// added to load balancing, to warm JIT compilers and fill caches.
+type WarmupRequest struct {
+	InternalPort int
+	Method       string
+	Path         string
+	Headers      map[string]string
+	// Count is how many times the request is sent
+	Count   int
+	Timeout time.Duration
+	// ExpectedStatus fails the warm-up when a response has a different status, any status is accepted when zero
+	ExpectedStatus int
+}
+
+const (
+	defaultWarmupTimeout = 10 * time.Second
+	maxWarmupCount       = 1000
+)
+
+// WarmupRequests parses the warm-up requests declared in each service
+func (ac *AppConfig) WarmupRequests() ([]WarmupRequest, []ConfigError) {
+	var requests []WarmupRequest
+	var errs []ConfigError
+
+	for i, service := range ac.services() {
+		port, _ := toInt(service["internal_port"])
+
+		for j, raw := range toMapSlice(service["warmup"]) {
+			prefix := fmt.Sprintf("services[%d].warmup[%d]", i, j)
+			req, reqErrs := parseWarmupRequest(raw, prefix)
+			if len(reqErrs) > 0 {
+				errs = append(errs, reqErrs...)
+				continue
+			}
+			req.InternalPort = port
+			requests = append(requests, req)
+		}
+	}
+
+	return requests, errs
+}
+
+func parseWarmupRequest(raw map[string]interface{}, prefix string) (WarmupRequest, []ConfigError) {
+	r := WarmupRequest{Method: http.MethodGet, Count: 1, Timeout: defaultWarmupTimeout}
+	var errs []ConfigError
+
+	for k, v := range raw {
+		switch k {
+		case "path":
+			r.Path = fmt.Sprint(v)
+		case "method":
+			r.Method = strings.ToUpper(fmt.Sprint(v))
+		case "headers":
+			headers, ok := v.(map[string]interface{})
+			if !ok {
+				errs = append(errs, configErrorf(prefix, "headers must be a table of header names and values"))
+				continue
+			}
+			r.Headers = map[string]string{}
+			for name, value := range headers {
+				r.Headers[name] = fmt.Sprint(value)
+			}
+		case "count":
+			n, ok := toInt(v)
+			if !ok || n <= 0 || n > maxWarmupCount {
+				errs = append(errs, configErrorf(prefix, "count must be between 1 and %d", maxWarmupCount))
+			}
+			r.Count = n
+		case "timeout":
+			d, err := time.ParseDuration(fmt.Sprint(v))
+			if err != nil || d <= 0 {
+				errs = append(errs, configErrorf(prefix, "timeout must be a positive duration like \"5s\", got %v", v))
+			}
+			r.Timeout = d
+		case "expected_status":
+			n, ok := toInt(v)
+			if !ok || n < 100 || n > 599 {
+				errs = append(errs, configErrorf(prefix, "expected_status must be an HTTP status code"))
+			}
+			r.ExpectedStatus = n
+		default:
+			errs = append(errs, configErrorf(prefix, "unknown setting %s", k))
+		}
+	}
+
+	if !strings.HasPrefix(r.Path, "/") {
+		errs = append(errs, configErrorf(prefix, "path must start with /"))
+	}
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions:
+	default:
+		errs = append(errs, configErrorf(prefix, "unsupported method %s", r.Method))
+	}
+
+	return r, errs
+}
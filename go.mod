@@ -3,6 +3,7 @@ module github.com/superfly/flyctl
 go 1.16
 
 require (
+	filippo.io/age v1.0.0
 	github.com/AlecAivazis/survey/v2 v2.2.7
 	github.com/BurntSushi/toml v0.3.1
 	github.com/PuerkitoBio/rehttp v1.0.0
@@ -13,7 +14,7 @@ require (
 	github.com/buildpacks/pack v0.17.0
 	github.com/cli/safeexec v1.0.0
 	github.com/containerd/console v1.0.1
-	github.com/docker/cli v20.10.4+incompatible // indirect
+	github.com/docker/cli v20.10.4+incompatible
 	github.com/docker/docker v20.10.0-beta1.0.20201110211921-af34b94a78a1+incompatible
 	github.com/docker/go-connections v0.4.0
 	github.com/dustin/go-humanize v1.0.0
@@ -35,6 +36,7 @@ require (
 	github.com/muesli/termenv v0.7.4
 	github.com/novln/docker-parser v1.0.0
 	github.com/olekukonko/tablewriter v0.0.5
+	github.com/opencontainers/go-digest v1.0.0
 	github.com/pelletier/go-toml v1.8.1
 	github.com/pkg/errors v0.9.1
 	github.com/segmentio/textio v1.2.0
@@ -42,13 +44,15 @@ require (
 	github.com/spf13/cobra v1.1.3
 	github.com/spf13/viper v1.7.1
 	github.com/stretchr/testify v1.7.0
-	golang.org/x/crypto v0.0.0-20210322153248-0c34fe9e7dc2
+	github.com/tonistiigi/fsutil v0.0.0-20201103201449-0834f99b7b85
+	golang.org/x/crypto v0.0.0-20210817164053-32db794688a5
 	golang.org/x/net v0.0.0-20210331212208-0fccb6fa2b5c
 	golang.org/x/sync v0.0.0-20210220032951-036812b2e83c
-	golang.org/x/sys v0.0.0-20210402192133-700132347e07 // indirect
-	golang.org/x/term v0.0.0-20210220032956-6a3ed077a48d
+	golang.org/x/sys v0.0.0-20210903071746-97244b99971b // indirect
+	golang.org/x/term v0.0.0-20210615171337-6886f2dfbf5b
 	golang.zx2c4.com/wireguard v0.0.20201118
 	golang.zx2c4.com/wireguard/tun/netstack v0.0.0-20210402170708-10533c3e73cd
+	google.golang.org/grpc v1.36.0-dev.0.20210208035533-9280052d3665
 	gopkg.in/yaml.v2 v2.4.0
 )
 
@@ -0,0 +1,99 @@
+package awsauth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// configuredRegion runs the aws CLI's configure get region, replaced in tests
+var configuredRegion = func(ctx context.Context) string {
+	out, err := exec.CommandContext(ctx, "aws", "configure", "get", "region").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// exportCredentials runs the aws CLI's export-credentials, replaced in tests
+var exportCredentials = func(ctx context.Context) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "aws", "configure", "export-credentials", "--format", "process")
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if _, ok := err.(*exec.Error); ok {
+			return nil, errors.New("set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY, or install the aws CLI and log in")
+		}
+		return nil, fmt.Errorf("aws configure export-credentials: %s", strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+// LoadCredentials takes the credentials from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY,
+// AWS_SESSION_TOKEN and AWS_CREDENTIAL_EXPIRATION, or from the aws CLI's profiles, SSO logins and
+// roles when those aren't set
+func LoadCredentials(ctx context.Context) (Credentials, error) {
+	creds := Credentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.AccessKeyID != "" && creds.SecretAccessKey != "" {
+		if expiration := os.Getenv("AWS_CREDENTIAL_EXPIRATION"); expiration != "" {
+			expires, err := time.Parse(time.RFC3339, expiration)
+			if err != nil {
+				return Credentials{}, fmt.Errorf("AWS_CREDENTIAL_EXPIRATION must be an RFC 3339 time: %s", err)
+			}
+			creds.Expires = expires.UTC()
+		}
+		return creds, nil
+	}
+
+	out, err := exportCredentials(ctx)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	// the credential_process format
+	var exported struct {
+		AccessKeyID     string `json:"AccessKeyId"`
+		SecretAccessKey string
+		SessionToken    string
+		// Expiration is only set for temporary credentials
+		Expiration time.Time
+	}
+	if err := json.Unmarshal(out, &exported); err != nil {
+		return Credentials{}, fmt.Errorf("unexpected output from aws configure export-credentials: %s", err)
+	}
+	if exported.AccessKeyID == "" || exported.SecretAccessKey == "" {
+		return Credentials{}, errors.New("aws configure export-credentials returned no credentials")
+	}
+
+	return Credentials{
+		AccessKeyID:     exported.AccessKeyID,
+		SecretAccessKey: exported.SecretAccessKey,
+		SessionToken:    exported.SessionToken,
+		Expires:         exported.Expiration.UTC(),
+	}, nil
+}
+
+// LoadRegion takes the region from AWS_REGION or AWS_DEFAULT_REGION, or from the aws CLI's profile
+// when those aren't set. It's empty when none is configured.
+func LoadRegion(ctx context.Context) string {
+	if region := os.Getenv("AWS_REGION"); region != "" {
+		return region
+	}
+	if region := os.Getenv("AWS_DEFAULT_REGION"); region != "" {
+		return region
+	}
+	return configuredRegion(ctx)
+}
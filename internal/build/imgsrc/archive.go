@@ -2,10 +2,12 @@ package imgsrc
 
 import (
 	"archive/tar"
+	"compress/gzip"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/docker/docker/builder/dockerignore"
 	"github.com/docker/docker/pkg/archive"
@@ -17,13 +19,18 @@ type archiveOptions struct {
 	exclusions []string
 	compressed bool
 	additions  map[string][]byte
+	// modTime enables reproducible archives, with file times clamped to it
+	modTime *time.Time
+	// compressionLevel is the gzip level of compressed archives, gzip's default when zero
+	compressionLevel int
 }
 
 func archiveDirectory(options archiveOptions) (io.ReadCloser, error) {
 	opts := &archive.TarOptions{
 		ExcludePatterns: options.exclusions,
 	}
-	if options.compressed && len(options.additions) == 0 {
+	compress := options.compressed && len(options.additions) == 0
+	if compress && options.modTime == nil && options.compressionLevel == 0 {
 		opts.Compression = archive.Gzip
 	}
 
@@ -48,9 +55,43 @@ func archiveDirectory(options archiveOptions) (io.ReadCloser, error) {
 		r = archive.ReplaceFileTarWrapper(r, mods)
 	}
 
+	if options.modTime != nil {
+		r = normalizeTar(r, *options.modTime, compress, options.compressionLevel)
+	} else if compress && options.compressionLevel != 0 {
+		r = gzipStream(r, options.compressionLevel)
+	}
+
 	return r, nil
 }
 
+// gzipStream compresses r as it's read
+func gzipStream(r io.ReadCloser, level int) io.ReadCloser {
+	pr, pw := io.Pipe()
+
+	go func() {
+		defer r.Close()
+
+		gz, err := gzip.NewWriterLevel(pw, gzipLevel(level))
+		if err == nil {
+			_, err = io.Copy(gz, r)
+			if closeErr := gz.Close(); err == nil {
+				err = closeErr
+			}
+		}
+		pw.CloseWithError(err)
+	}()
+
+	return pr
+}
+
+// gzipLevel returns the gzip level for a configured compression level, where zero is the default
+func gzipLevel(level int) int {
+	if level == 0 {
+		return gzip.DefaultCompression
+	}
+	return level
+}
+
 func readDockerignore(workingDir string) ([]string, error) {
 	file, err := os.Open(filepath.Join(workingDir, ".dockerignore"))
 	if os.IsNotExist(err) {
@@ -2,11 +2,14 @@ package imgsrc
 
 import (
 	"archive/tar"
+	"bytes"
+	"compress/gzip"
 	"io"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/docker/docker/pkg/archive"
 	"github.com/stretchr/testify/assert"
@@ -144,6 +147,23 @@ func TestArchiverCompression(t *testing.T) {
 	assert.Equal(t, archive.Uncompressed, archive.DetectCompression(data))
 }
 
+func TestArchiverCompressionLevel(t *testing.T) {
+	testDir, err := newTestDir("a.jpg", "content/foo.md", "images/a.jpg", "images/b.jpg")
+	assert.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	r, err := archiveDirectory(archiveOptions{sourcePath: testDir, compressed: true, compressionLevel: 1})
+	assert.NoError(t, err)
+	data, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, archive.Gzip, archive.DetectCompression(data))
+
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	assert.NoError(t, err)
+	_, err = io.ReadAll(gz)
+	assert.NoError(t, err)
+}
+
 func TestArchiverNoCompressionWithAdditions(t *testing.T) {
 	testDir, err := newTestDir("a.jpg", "content/foo.md", "images/a.jpg", "images/b.jpg")
 	assert.NoError(t, err)
@@ -158,6 +178,26 @@ func TestArchiverNoCompressionWithAdditions(t *testing.T) {
 	assert.Equal(t, archive.Uncompressed, archive.DetectCompression(data))
 }
 
+func TestArchiverReproducible(t *testing.T) {
+	testDir, err := newTestDir("a.jpg", "content/foo.md", "images/a.jpg")
+	assert.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	epoch := time.Unix(1600000000, 0)
+	archiveBytes := func() []byte {
+		r, err := archiveDirectory(archiveOptions{sourcePath: testDir, compressed: true, modTime: &epoch})
+		assert.NoError(t, err)
+		data, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		return data
+	}
+
+	first := archiveBytes()
+	assert.NoError(t, os.Chtimes(filepath.Join(testDir, "content/foo.md"), time.Now(), time.Now()))
+	assert.Equal(t, first, archiveBytes())
+	assert.Equal(t, archive.Gzip, archive.DetectCompression(first))
+}
+
 func TestParseDockerignore(t *testing.T) {
 	cases := map[string][]string{
 		"node_modules\n*.jpg":                {"node_modules", "*.jpg", "fly.toml"},
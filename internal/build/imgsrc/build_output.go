@@ -0,0 +1,340 @@
+package imgsrc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/docker/docker/pkg/stringid"
+	"github.com/dustin/go-humanize"
+	buildkitClient "github.com/moby/buildkit/client"
+	"github.com/superfly/flyctl/internal/cmdfmt"
+	"github.com/superfly/flyctl/pkg/iostreams"
+)
+
+const (
+	BuildOutputText = "text"
+	BuildOutputJSON = "json"
+)
+
+// ValidateBuildOutput returns an error if format isn't a supported build output format
+func ValidateBuildOutput(format string) error {
+	switch format {
+	case "", BuildOutputText, BuildOutputJSON:
+		return nil
+	}
+	return fmt.Errorf("invalid build output format \"%s\", must be one of %s or %s", format, BuildOutputText, BuildOutputJSON)
+}
+
+const (
+	BuildEventStepStart    = "step_start"
+	BuildEventStepFinish   = "step_finish"
+	BuildEventLog          = "log"
+	BuildEventPushProgress = "push_progress"
+	BuildEventImage        = "image"
+	BuildEventError        = "error"
+)
+
+// BuildEvent is a single machine readable build progress record, written as one JSON object per line
+type BuildEvent struct {
+	Time       time.Time `json:"time"`
+	Type       string    `json:"type"`
+	Step       string    `json:"step,omitempty"`
+	Message    string    `json:"message,omitempty"`
+	Cached     bool      `json:"cached,omitempty"`
+	DurationMS int64     `json:"duration_ms,omitempty"`
+	Layer      string    `json:"layer,omitempty"`
+	Status     string    `json:"status,omitempty"`
+	Current    int64     `json:"current,omitempty"`
+	Total      int64     `json:"total,omitempty"`
+	Tag        string    `json:"tag,omitempty"`
+	Digest     string    `json:"digest,omitempty"`
+	Size       int64     `json:"size,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// EventFunc receives events for a command's --json output, like CmdContext.Emit
+type EventFunc func(event string, data interface{})
+
+// JSONLines returns an EventFunc that writes each build event to w as a line of JSON, the way
+// --build-output json prints them
+func JSONLines(w io.Writer) EventFunc {
+	return func(event string, data interface{}) {
+		line, err := json.Marshal(data)
+		if err != nil {
+			return
+		}
+		fmt.Fprintln(w, string(line))
+	}
+}
+
+// buildReporter renders build progress either as human readable text or as a stream of BuildEvents
+// handed to events, which prints them to stdout as lines of JSON unless the caller says otherwise
+type buildReporter struct {
+	streams *iostreams.IOStreams
+	json    bool
+	events  EventFunc
+
+	mu      sync.Mutex
+	started map[string]time.Time
+
+	// cache counts the build steps served from the layer cache
+	cache cacheStats
+}
+
+func newBuildReporter(streams *iostreams.IOStreams, format string, events EventFunc) *buildReporter {
+	if format == BuildOutputJSON && events == nil {
+		events = JSONLines(streams.Out)
+	}
+	return &buildReporter{
+		streams: streams,
+		json:    events != nil,
+		events:  events,
+		started: map[string]time.Time{},
+	}
+}
+
+func (r *buildReporter) emit(e BuildEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+	r.events("build", e)
+}
+
+func (r *buildReporter) stepStarted(step string) time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	r.started[step] = now
+	return now
+}
+
+func (r *buildReporter) stepDuration(step string) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	start, ok := r.started[step]
+	if !ok {
+		return 0
+	}
+	delete(r.started, step)
+	return time.Since(start).Milliseconds()
+}
+
+// Begin marks the start of a named build step
+func (r *buildReporter) Begin(step string, message string) {
+	if !r.json {
+		cmdfmt.PrintBegin(r.streams.ErrOut, message)
+		return
+	}
+	r.emit(BuildEvent{Time: r.stepStarted(step), Type: BuildEventStepStart, Step: step, Message: message})
+}
+
+// Done marks the successful completion of a named build step
+func (r *buildReporter) Done(step string, message string) {
+	if !r.json {
+		cmdfmt.PrintDone(r.streams.ErrOut, message)
+		return
+	}
+	r.emit(BuildEvent{Type: BuildEventStepFinish, Step: step, Message: message, DurationMS: r.stepDuration(step)})
+}
+
+// Fail reports a build step that ended with an error
+func (r *buildReporter) Fail(step string, err error) {
+	if !r.json || err == nil {
+		return
+	}
+	r.emit(BuildEvent{Type: BuildEventError, Step: step, DurationMS: r.stepDuration(step), Error: err.Error()})
+}
+
+// Image reports the final image produced by the build
+func (r *buildReporter) Image(tag string, digest string, size int64) {
+	if !r.json {
+		return
+	}
+	r.emit(BuildEvent{Type: BuildEventImage, Tag: tag, Digest: digest, Size: size})
+}
+
+// Log reports a message about a build step, such as a retry
+func (r *buildReporter) Log(step string, message string) {
+	if !r.json {
+		fmt.Fprintln(r.streams.ErrOut, message)
+		return
+	}
+	r.emit(BuildEvent{Type: BuildEventLog, Step: step, Message: message})
+}
+
+// Layer reports a layer that finished pushing
+func (r *buildReporter) Layer(step string, digest string, status string, size int64) {
+	if !r.json {
+		fmt.Fprintf(r.streams.ErrOut, "%s: %s %s\n", stringid.TruncateID(digest), status, humanize.Bytes(uint64(size)))
+		return
+	}
+	r.emit(BuildEvent{Type: BuildEventPushProgress, Step: step, Layer: digest, Status: status, Current: size, Total: size})
+}
+
+// Writer returns a writer for free form build logs. In JSON mode each line becomes a log event.
+// Close it when the step ends, which emits a last line that has no newline.
+func (r *buildReporter) Writer(step string) io.WriteCloser {
+	if !r.json {
+		return nopWriteCloser{r.streams.ErrOut}
+	}
+	return &logWriter{reporter: r, step: step}
+}
+
+// logWriter emits a log event for each line written to it
+type logWriter struct {
+	reporter *buildReporter
+	step     string
+
+	mu  sync.Mutex
+	buf []byte
+}
+
+func (w *logWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			break
+		}
+		w.emit(w.buf[:i])
+		w.buf = w.buf[i+1:]
+	}
+	return len(p), nil
+}
+
+func (w *logWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if len(w.buf) > 0 {
+		w.emit(w.buf)
+		w.buf = nil
+	}
+	return nil
+}
+
+func (w *logWriter) emit(line []byte) {
+	w.reporter.emit(BuildEvent{Type: BuildEventLog, Step: w.step, Message: strings.TrimSuffix(string(line), "\r")})
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// DisplayJSONMessages renders a docker daemon message stream, as returned by image builds and pushes
+func (r *buildReporter) DisplayJSONMessages(step string, in io.Reader, auxCallback func(jsonmessage.JSONMessage)) error {
+	if !r.json {
+		return jsonmessage.DisplayJSONMessagesStream(in, r.streams.ErrOut, r.streams.StderrFd(), r.streams.IsStderrTTY(), auxCallback)
+	}
+
+	dec := json.NewDecoder(in)
+	for {
+		var m jsonmessage.JSONMessage
+		if err := dec.Decode(&m); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		if m.Aux != nil {
+			if auxCallback != nil {
+				auxCallback(m)
+			}
+			continue
+		}
+
+		if m.Error != nil {
+			return m.Error
+		}
+
+		switch {
+		case m.Progress != nil || (m.ID != "" && m.Status != ""):
+			e := BuildEvent{Type: BuildEventPushProgress, Step: step, Layer: m.ID, Status: m.Status}
+			if m.Progress != nil {
+				e.Current = m.Progress.Current
+				e.Total = m.Progress.Total
+			}
+			r.emit(e)
+		case m.Stream != "":
+			if line := strings.TrimRight(m.Stream, "\n"); line != "" {
+				r.emit(BuildEvent{Type: BuildEventLog, Step: step, Message: line})
+			}
+		case m.Status != "":
+			r.emit(BuildEvent{Type: BuildEventLog, Step: step, Message: m.Status})
+		}
+	}
+}
+
+// DisplaySolveStatus converts buildkit solve status updates to step events
+func (r *buildReporter) DisplaySolveStatus(ctx context.Context, ch chan *buildkitClient.SolveStatus) error {
+	vertexes := map[string]*buildkitClient.Vertex{}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case s, ok := <-ch:
+			if !ok {
+				return nil
+			}
+			for _, v := range s.Vertexes {
+				prev, seen := vertexes[v.Digest.String()]
+				vertexes[v.Digest.String()] = v
+
+				if v.Started != nil && (!seen || prev.Started == nil) {
+					r.emit(BuildEvent{Time: *v.Started, Type: BuildEventStepStart, Step: v.Name})
+				}
+				if v.Completed != nil && (!seen || prev.Completed == nil) {
+					e := BuildEvent{Time: *v.Completed, Type: BuildEventStepFinish, Step: v.Name, Cached: v.Cached}
+					if v.Started != nil {
+						e.DurationMS = v.Completed.Sub(*v.Started).Milliseconds()
+					}
+					if v.Error != "" {
+						e.Type = BuildEventError
+						e.Error = v.Error
+					}
+					r.emit(e)
+				}
+			}
+			for _, l := range s.Logs {
+				step := ""
+				if v, ok := vertexes[l.Vertex.String()]; ok {
+					step = v.Name
+				}
+				for _, line := range strings.Split(strings.TrimRight(string(l.Data), "\n"), "\n") {
+					r.emit(BuildEvent{Time: l.Timestamp, Type: BuildEventLog, Step: step, Message: line})
+				}
+			}
+		}
+	}
+}
+
+// pushResultDigest extracts the pushed image digest from a push stream aux message
+func pushResultDigest(m jsonmessage.JSONMessage) string {
+	var result types.PushResult
+	if err := json.Unmarshal(*m.Aux, &result); err != nil {
+		return ""
+	}
+	return result.Digest
+}
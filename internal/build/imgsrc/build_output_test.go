@@ -0,0 +1,176 @@
+package imgsrc
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/pkg/jsonmessage"
+	buildkitClient "github.com/moby/buildkit/client"
+	digest "github.com/opencontainers/go-digest"
+	"github.com/stretchr/testify/assert"
+	"github.com/superfly/flyctl/pkg/iostreams"
+)
+
+func readBuildEvents(t *testing.T, out *bytes.Buffer) []BuildEvent {
+	var events []BuildEvent
+	scanner := bufio.NewScanner(out)
+	for scanner.Scan() {
+		var e BuildEvent
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("invalid event %q: %s", scanner.Text(), err)
+		}
+		events = append(events, e)
+	}
+	return events
+}
+
+func TestBuildReporterWriter(t *testing.T) {
+	streams, _, out, _ := iostreams.Test()
+	reporter := newBuildReporter(streams, BuildOutputJSON, nil)
+
+	w := reporter.Writer("scan")
+	fmt.Fprint(w, "first line\r\nsecond ")
+	fmt.Fprint(w, "line\nno newline")
+
+	var messages []string
+	for _, e := range readBuildEvents(t, out) {
+		messages = append(messages, e.Message)
+	}
+	assert.Equal(t, []string{"first line", "second line"}, messages)
+
+	assert.NoError(t, w.Close())
+	events := readBuildEvents(t, out)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, BuildEvent{Time: events[0].Time, Type: BuildEventLog, Step: "scan", Message: "no newline"}, events[0])
+	}
+}
+
+func TestBuildReporterTextWriter(t *testing.T) {
+	streams, _, out, errOut := iostreams.Test()
+	reporter := newBuildReporter(streams, BuildOutputText, nil)
+
+	w := reporter.Writer("scan")
+	fmt.Fprint(w, "no newline")
+	assert.NoError(t, w.Close())
+
+	assert.Equal(t, "no newline", errOut.String())
+	assert.Empty(t, out.String())
+}
+
+func TestBuildReporterEvents(t *testing.T) {
+	streams, _, out, errOut := iostreams.Test()
+	var names []string
+	var events []BuildEvent
+	reporter := newBuildReporter(streams, BuildOutputText, func(event string, data interface{}) {
+		names = append(names, event)
+		events = append(events, data.(BuildEvent))
+	})
+
+	reporter.Begin("push", "Pushing image to fly")
+	reporter.Done("push", "Pushing image done")
+
+	assert.Equal(t, []string{"build", "build"}, names)
+	assert.Equal(t, BuildEventStepStart, events[0].Type)
+	assert.Equal(t, BuildEventStepFinish, events[1].Type)
+	assert.Empty(t, out.String())
+	assert.Empty(t, errOut.String())
+}
+
+func TestBuildReporterJSONLines(t *testing.T) {
+	streams, _, out, errOut := iostreams.Test()
+	var events bytes.Buffer
+	reporter := newBuildReporter(streams, BuildOutputJSON, JSONLines(&events))
+
+	reporter.Begin("push", "Pushing image to fly")
+	reporter.Done("push", "Pushing image done")
+
+	lines := readBuildEvents(t, &events)
+	if assert.Len(t, lines, 2) {
+		assert.Equal(t, BuildEventStepStart, lines[0].Type)
+		assert.Equal(t, BuildEventStepFinish, lines[1].Type)
+	}
+	assert.Empty(t, out.String())
+	assert.Empty(t, errOut.String())
+}
+
+func TestBuildReporterJSONMessages(t *testing.T) {
+	streams, _, out, _ := iostreams.Test()
+	reporter := newBuildReporter(streams, BuildOutputJSON, nil)
+
+	aux := json.RawMessage(`{"Digest": "sha256:abc"}`)
+	var stream bytes.Buffer
+	enc := json.NewEncoder(&stream)
+	for _, m := range []jsonmessage.JSONMessage{
+		{Stream: "Step 1/2 : FROM alpine\n"},
+		{ID: "3c9", Status: "Pushing", Progress: &jsonmessage.JSONProgress{Current: 512, Total: 1024}},
+		{ID: "3c9", Status: "Pushed"},
+		{Status: "latest: digest: sha256:abc size: 528"},
+		{Aux: &aux},
+	} {
+		enc.Encode(m)
+	}
+
+	var digests []string
+	err := reporter.DisplayJSONMessages("push", &stream, func(m jsonmessage.JSONMessage) {
+		digests = append(digests, pushResultDigest(m))
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"sha256:abc"}, digests)
+
+	events := readBuildEvents(t, out)
+	if assert.Len(t, events, 4) {
+		assert.Equal(t, BuildEvent{Time: events[0].Time, Type: BuildEventLog, Step: "push", Message: "Step 1/2 : FROM alpine"}, events[0])
+		assert.Equal(t, BuildEvent{Time: events[1].Time, Type: BuildEventPushProgress, Step: "push", Layer: "3c9", Status: "Pushing", Current: 512, Total: 1024}, events[1])
+		assert.Equal(t, BuildEventPushProgress, events[2].Type)
+		assert.Equal(t, "Pushed", events[2].Status)
+		assert.Equal(t, BuildEvent{Time: events[3].Time, Type: BuildEventLog, Step: "push", Message: "latest: digest: sha256:abc size: 528"}, events[3])
+	}
+
+	err = reporter.DisplayJSONMessages("push", strings.NewReader(`{"errorDetail": {"message": "denied"}, "error": "denied"}`), nil)
+	assert.EqualError(t, err, "denied")
+}
+
+func TestBuildReporterSolveStatus(t *testing.T) {
+	streams, _, out, _ := iostreams.Test()
+	reporter := newBuildReporter(streams, BuildOutputJSON, nil)
+
+	started := time.Date(2021, 4, 1, 12, 0, 0, 0, time.UTC)
+	completed := started.Add(1500 * time.Millisecond)
+	from := digest.FromString("from")
+	run := digest.FromString("run")
+
+	ch := make(chan *buildkitClient.SolveStatus, 3)
+	ch <- &buildkitClient.SolveStatus{Vertexes: []*buildkitClient.Vertex{
+		{Digest: from, Name: "[1/2] FROM alpine", Started: &started, Completed: &started, Cached: true},
+		{Digest: run, Name: "[2/2] RUN make", Started: &started},
+	}}
+	ch <- &buildkitClient.SolveStatus{Logs: []*buildkitClient.VertexLog{
+		{Vertex: run, Data: []byte("compiling\nlinking\n"), Timestamp: started},
+	}}
+	ch <- &buildkitClient.SolveStatus{Vertexes: []*buildkitClient.Vertex{
+		{Digest: run, Name: "[2/2] RUN make", Started: &started, Completed: &completed, Error: "exit code 2"},
+	}}
+	close(ch)
+
+	assert.NoError(t, reporter.DisplaySolveStatus(context.Background(), ch))
+
+	events := readBuildEvents(t, out)
+	var types, steps, messages []string
+	for _, e := range events {
+		types = append(types, e.Type)
+		steps = append(steps, e.Step)
+		messages = append(messages, e.Message)
+	}
+	assert.Equal(t, []string{BuildEventStepStart, BuildEventStepFinish, BuildEventStepStart, BuildEventLog, BuildEventLog, BuildEventError}, types)
+	assert.Equal(t, []string{"[1/2] FROM alpine", "[1/2] FROM alpine", "[2/2] RUN make", "[2/2] RUN make", "[2/2] RUN make", "[2/2] RUN make"}, steps)
+	assert.Equal(t, []string{"", "", "", "compiling", "linking", ""}, messages)
+	assert.True(t, events[1].Cached)
+	assert.Equal(t, int64(1500), events[5].DurationMS)
+	assert.Equal(t, "exit code 2", events[5].Error)
+}
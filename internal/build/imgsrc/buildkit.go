@@ -73,11 +73,15 @@ func getBuildNodeID() string {
 
 type tracer struct {
 	displayCh chan *buildkitClient.SolveStatus
+	cache     *cacheStats
+	completed map[string]bool
 }
 
-func newTracer() *tracer {
+func newTracer(cache *cacheStats) *tracer {
 	return &tracer{
 		displayCh: make(chan *buildkitClient.SolveStatus),
+		cache:     cache,
+		completed: map[string]bool{},
 	}
 }
 
@@ -108,6 +112,10 @@ func (t *tracer) write(msg jsonmessage.JSONMessage) {
 			Error:     v.Error,
 			Cached:    v.Cached,
 		})
+		if v.Completed != nil && !t.completed[v.Digest.String()] {
+			t.completed[v.Digest.String()] = true
+			t.cache.buildkitVertex(v.Name, v.Cached)
+		}
 	}
 	for _, v := range resp.Statuses {
 		s.Statuses = append(s.Statuses, &buildkitClient.VertexStatus{
@@ -40,12 +40,19 @@ func (s *buildpacksBuilder) Run(ctx context.Context, dockerFactory *dockerClient
 
 	defer clearDeploymentTags(ctx, docker, opts.Tag)
 
-	packClient, err := pack.NewClient(pack.WithDockerClient(docker), pack.WithLogger(newPackLogger(streams.Out)))
+	reporter := newBuildReporter(streams, opts.BuildOutput, opts.Events)
+
+	var packOut io.WriteCloser = nopWriteCloser{streams.Out}
+	if reporter.json {
+		packOut = reporter.Writer("build")
+	}
+
+	packClient, err := pack.NewClient(pack.WithDockerClient(docker), pack.WithLogger(newPackLogger(packOut)))
 	if err != nil {
 		return nil, err
 	}
 
-	cmdfmt.PrintBegin(streams.ErrOut, "Building image with Buildpacks")
+	reporter.Begin("build", "Building image with Buildpacks")
 
 	err = packClient.Build(ctx, pack.BuildOptions{
 		AppPath:      opts.WorkingDir,
@@ -55,21 +62,32 @@ func (s *buildpacksBuilder) Run(ctx context.Context, dockerFactory *dockerClient
 		Env:          normalizeBuildArgs(opts.AppConfig, opts.ExtraBuildArgs),
 		TrustBuilder: true,
 	})
+	packOut.Close()
 
 	if err != nil {
+		reporter.Fail("build", err)
 		return nil, err
 	}
 
-	cmdfmt.PrintDone(streams.ErrOut, "Building image done")
+	reporter.Done("build", "Building image done")
+
+	if opts.ScanSeverity != "" {
+		if err := scanImage(ctx, docker, reporter, opts.Tag, opts.ScanSeverity, opts.ScanImage); err != nil {
+			return nil, err
+		}
+	}
 
+	var digest string
 	if opts.Publish {
-		cmdfmt.PrintBegin(streams.ErrOut, "Pushing image to fly")
+		reporter.Begin("push", "Pushing image to fly")
 
-		if err := pushToFly(ctx, docker, streams, opts.Tag); err != nil {
+		digest, err = pushToFly(ctx, docker, reporter, opts.Tag, pushOptions(dockerFactory, opts.PushConcurrency, opts.CompressionLevel))
+		if err != nil {
+			reporter.Fail("push", err)
 			return nil, err
 		}
 
-		cmdfmt.PrintDone(streams.ErrOut, "Pushing image done")
+		reporter.Done("push", "Pushing image done")
 	}
 
 	img, err := findImageWithDocker(docker, ctx, opts.Tag)
@@ -77,10 +95,13 @@ func (s *buildpacksBuilder) Run(ctx context.Context, dockerFactory *dockerClient
 		return nil, err
 	}
 
+	reporter.Image(opts.Tag, digest, img.Size)
+
 	return &DeploymentImage{
-		ID:   img.ID,
-		Tag:  opts.Tag,
-		Size: img.Size,
+		ID:     img.ID,
+		Tag:    opts.Tag,
+		Digest: digest,
+		Size:   img.Size,
 	}, nil
 }
 
@@ -1,11 +1,8 @@
 package imgsrc
 
 import (
-	"fmt"
-
 	"github.com/pkg/errors"
 	"github.com/superfly/flyctl/internal/build/imgsrc/builtins"
-	"github.com/superfly/flyctl/internal/cmdfmt"
 	"github.com/superfly/flyctl/pkg/iostreams"
 	"github.com/superfly/flyctl/terminal"
 	"golang.org/x/net/context"
@@ -45,10 +42,15 @@ func (ds *builtinBuilder) Run(ctx context.Context, dockerFactory *dockerClientFa
 
 	defer clearDeploymentTags(ctx, docker, opts.Tag)
 
-	cmdfmt.PrintBegin(streams.ErrOut, "Creating build context")
+	reporter := newBuildReporter(streams, opts.BuildOutput, opts.Events)
+
+	reporter.Begin("context", "Creating build context")
+	epoch := reproducibleEpoch(opts)
 	archiveOpts := archiveOptions{
-		sourcePath: opts.WorkingDir,
-		compressed: dockerFactory.mode.IsRemote(),
+		sourcePath:       opts.WorkingDir,
+		compressed:       dockerFactory.mode.IsRemote(),
+		modTime:          epoch,
+		compressionLevel: opts.CompressionLevel,
 	}
 
 	excludes, err := readDockerignore(opts.WorkingDir)
@@ -64,42 +66,57 @@ func (ds *builtinBuilder) Run(ctx context.Context, dockerFactory *dockerClientFa
 
 	r, err := archiveDirectory(archiveOpts)
 	if err != nil {
+		reporter.Fail("context", err)
 		return nil, errors.Wrap(err, "error archiving build context")
 	}
-	cmdfmt.PrintDone(streams.ErrOut, "Creating build context done")
+	reporter.Done("context", "Creating build context done")
 
 	var imageID string
 
-	cmdfmt.PrintBegin(streams.ErrOut, "Building image with Docker")
+	reporter.Begin("build", "Building image with Docker")
 
 	buildArgs := normalizeBuildArgsForDocker(opts.AppConfig, opts.ExtraBuildArgs)
-	imageID, err = runClassicBuild(ctx, streams, docker, r, opts, "", buildArgs)
+	addSourceDateEpochArg(buildArgs, epoch)
+	imageID, err = runClassicBuild(ctx, reporter, docker, r, opts, "", buildArgs)
 	if err != nil {
+		reporter.Fail("build", err)
 		return nil, errors.Wrap(err, "error building")
 	}
 
-	cmdfmt.PrintDone(streams.ErrOut, "Building image done")
+	reporter.Done("build", "Building image done")
 
+	if opts.ScanSeverity != "" {
+		if err := scanImage(ctx, docker, reporter, opts.Tag, opts.ScanSeverity, opts.ScanImage); err != nil {
+			return nil, err
+		}
+	}
+
+	var digest string
 	if opts.Publish {
-		cmdfmt.PrintBegin(streams.ErrOut, "Pushing image to fly")
+		reporter.Begin("push", "Pushing image to fly")
 
-		if err := pushToFly(ctx, docker, streams, opts.Tag); err != nil {
+		digest, err = pushToFly(ctx, docker, reporter, opts.Tag, pushOptions(dockerFactory, opts.PushConcurrency, opts.CompressionLevel))
+		if err != nil {
+			reporter.Fail("push", err)
 			return nil, err
 		}
 
-		cmdfmt.PrintDone(streams.ErrOut, "Pushing image done")
+		reporter.Done("push", "Pushing image done")
 	}
 
 	img, _, err := docker.ImageInspectWithRaw(ctx, imageID)
 	if err != nil {
 		return nil, errors.Wrap(err, "count not find built image")
 	}
-	fmt.Println(img)
+	terminal.Debug(img)
+
+	reporter.Image(opts.Tag, digest, img.Size)
 
 	return &DeploymentImage{
-		ID:   img.ID,
-		Tag:  opts.Tag,
-		Size: img.Size,
+		ID:     img.ID,
+		Tag:    opts.Tag,
+		Digest: digest,
+		Size:   img.Size,
 	}, nil
 
 }
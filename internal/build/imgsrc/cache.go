@@ -0,0 +1,221 @@
+package imgsrc
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/pkg/jsonmessage"
+	"github.com/superfly/flyctl/helpers"
+)
+
+// dependencyManifest is a lockfile whose dependencies are installed by a build step. Installing
+// them before copying the rest of the source lets the step stay cached until the lockfile changes.
+type dependencyManifest struct {
+	file    string
+	install *regexp.Regexp
+	// cacheDir is where the package manager keeps downloads, worth a RUN --mount=type=cache
+	cacheDir string
+}
+
+var dependencyManifests = []dependencyManifest{
+	{file: "package-lock.json", install: regexp.MustCompile(`\bnpm\s+(ci|install|i)\b`), cacheDir: "/root/.npm"},
+	{file: "yarn.lock", install: regexp.MustCompile(`\byarn(\s+install\b|\s*$|\s*&&)`), cacheDir: "/usr/local/share/.cache/yarn"},
+	{file: "go.sum", install: regexp.MustCompile(`\bgo\s+mod\s+download\b`), cacheDir: "/root/go/pkg/mod"},
+	{file: "Gemfile.lock", install: regexp.MustCompile(`\bbundle(\s+install\b|\s*$|\s*&&)`), cacheDir: "/usr/local/bundle/cache"},
+	{file: "requirements.txt", install: regexp.MustCompile(`\bpip3?\s+install\b`), cacheDir: "/root/.cache/pip"},
+}
+
+// dockerfileInstruction is one instruction with its continuation lines joined
+type dockerfileInstruction struct {
+	line int
+	cmd  string
+	args string
+}
+
+func parseDockerfileInstructions(data []byte) []dockerfileInstruction {
+	var instructions []dockerfileInstruction
+	var current strings.Builder
+	start := 0
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if current.Len() == 0 && (line == "" || strings.HasPrefix(line, "#")) {
+			continue
+		}
+		if current.Len() == 0 {
+			start = n
+		}
+		if strings.HasSuffix(line, "\\") {
+			current.WriteString(strings.TrimSuffix(line, "\\") + " ")
+			continue
+		}
+		current.WriteString(line)
+
+		fields := strings.SplitN(current.String(), " ", 2)
+		inst := dockerfileInstruction{line: start, cmd: strings.ToUpper(fields[0])}
+		if len(fields) > 1 {
+			inst.args = strings.TrimSpace(fields[1])
+		}
+		instructions = append(instructions, inst)
+		current.Reset()
+	}
+
+	return instructions
+}
+
+// copiesWholeContext reports whether a COPY or ADD copies the whole build context, like COPY . .
+func copiesWholeContext(inst dockerfileInstruction) bool {
+	if inst.cmd != "COPY" && inst.cmd != "ADD" {
+		return false
+	}
+	var sources []string
+	for _, f := range strings.Fields(inst.args) {
+		if !strings.HasPrefix(f, "--") {
+			sources = append(sources, f)
+		}
+	}
+	if len(sources) < 2 {
+		return false
+	}
+	for _, src := range sources[:len(sources)-1] {
+		if src == "." || src == "./" {
+			return true
+		}
+	}
+	return false
+}
+
+// dockerfileCacheHints suggests changes to a Dockerfile that let more of its steps be cached:
+// installing dependencies before copying the whole source, and keeping package manager downloads
+// in a cache mount when building with BuildKit.
+func dockerfileCacheHints(workingDir string, dockerfile string, buildkit bool) []string {
+	data, err := os.ReadFile(dockerfile)
+	if err != nil {
+		return nil
+	}
+	instructions := parseDockerfileInstructions(data)
+
+	var hints []string
+	for _, dep := range dependencyManifests {
+		if !helpers.FileExists(filepath.Join(workingDir, dep.file)) {
+			continue
+		}
+
+		var copied *dockerfileInstruction
+		for i, inst := range instructions {
+			switch {
+			case inst.cmd == "FROM":
+				copied = nil
+			case copiesWholeContext(inst) && copied == nil:
+				copied = &instructions[i]
+			case inst.cmd == "RUN" && dep.install.MatchString(inst.args):
+				if copied != nil {
+					hints = append(hints, fmt.Sprintf("line %d copies the whole context before line %d installs dependencies, so any source change reinstalls them. Copy %s first and install before copying the rest.", copied.line, inst.line, dep.file))
+				}
+				if buildkit && !strings.Contains(inst.args, "--mount=type=cache") {
+					hints = append(hints, fmt.Sprintf("line %d can keep downloads between builds with RUN --mount=type=cache,target=%s", inst.line, dep.cacheDir))
+				}
+			}
+		}
+	}
+
+	return hints
+}
+
+var (
+	// buildkit names Dockerfile steps like "[stage-1 3/6] RUN npm ci"
+	buildkitStepPattern = regexp.MustCompile(`^\[[^\]]*\d+/\d+\] (RUN|COPY|ADD) `)
+	// the classic builder streams "Step 3/6 : RUN npm ci" before each step
+	classicStepPattern = regexp.MustCompile(`^Step \d+/\d+ : ((RUN|COPY|ADD) .*)`)
+)
+
+// cacheStats counts how many of a build's RUN, COPY and ADD steps were served from the layer cache
+type cacheStats struct {
+	mu        sync.Mutex
+	total     int
+	cached    int
+	firstMiss string
+
+	// classic builds report a cache hit on the line after the step
+	pending string
+	buf     []byte
+}
+
+func (s *cacheStats) step(name string, cached bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.total++
+	if cached {
+		s.cached++
+	} else if s.firstMiss == "" {
+		s.firstMiss = name
+	}
+}
+
+// buildkitVertex records a completed buildkit vertex
+func (s *cacheStats) buildkitVertex(name string, cached bool) {
+	if buildkitStepPattern.MatchString(name) {
+		s.step(name, cached)
+	}
+}
+
+// Write scans a classic build's JSON message stream for steps and cache hits
+func (s *cacheStats) Write(p []byte) (int, error) {
+	s.buf = append(s.buf, p...)
+	for {
+		i := bytes.IndexByte(s.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		var m jsonmessage.JSONMessage
+		if err := json.Unmarshal(s.buf[:i], &m); err == nil {
+			s.classicLine(strings.TrimSpace(m.Stream))
+		}
+		s.buf = s.buf[i+1:]
+	}
+}
+
+func (s *cacheStats) classicLine(line string) {
+	switch {
+	case classicStepPattern.MatchString(line):
+		s.finishPending(false)
+		s.pending = classicStepPattern.FindStringSubmatch(line)[1]
+	case strings.HasPrefix(line, "Step "):
+		s.finishPending(false)
+	case line == "---> Using cache":
+		s.finishPending(true)
+	}
+}
+
+func (s *cacheStats) finishPending(cached bool) {
+	if s.pending != "" {
+		s.step(s.pending, cached)
+		s.pending = ""
+	}
+}
+
+// Summary describes how much of the build was cached, empty when it had no steps
+func (s *cacheStats) Summary() string {
+	s.finishPending(false)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.total == 0 {
+		return ""
+	}
+	summary := fmt.Sprintf("Layer cache reused %d of %d steps (%d%%)", s.cached, s.total, s.cached*100/s.total)
+	if s.firstMiss != "" {
+		summary += fmt.Sprintf(", rebuilt from %s", s.firstMiss)
+	}
+	return summary
+}
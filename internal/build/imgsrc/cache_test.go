@@ -0,0 +1,78 @@
+package imgsrc
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDockerfileCacheHints(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "package-lock.json"), []byte("{}"), 0644))
+
+	write := func(dockerfile string) string {
+		path := filepath.Join(dir, "Dockerfile")
+		assert.NoError(t, os.WriteFile(path, []byte(dockerfile), 0644))
+		return path
+	}
+
+	hints := dockerfileCacheHints(dir, write(`FROM node:16
+WORKDIR /app
+COPY . .
+RUN npm ci \
+    --production
+CMD ["node", "index.js"]
+`), false)
+	assert.Equal(t, []string{"line 3 copies the whole context before line 4 installs dependencies, so any source change reinstalls them. Copy package-lock.json first and install before copying the rest."}, hints)
+
+	hints = dockerfileCacheHints(dir, write(`FROM node:16
+COPY package.json package-lock.json ./
+RUN npm ci
+COPY . .
+RUN npm run build
+`), true)
+	assert.Equal(t, []string{"line 3 can keep downloads between builds with RUN --mount=type=cache,target=/root/.npm"}, hints)
+
+	// a later stage starts with an empty filesystem, the copy in the first one doesn't count
+	hints = dockerfileCacheHints(dir, write(`FROM node:16 AS assets
+COPY . .
+RUN npm run build
+
+FROM node:16
+COPY package-lock.json ./
+RUN --mount=type=cache,target=/root/.npm npm ci
+`), true)
+	assert.Empty(t, hints)
+}
+
+func TestCacheStatsClassic(t *testing.T) {
+	var stats cacheStats
+	stream := []string{
+		`{"stream":"Step 1/4 : FROM node:16"}`,
+		`{"stream":"\n"}`,
+		`{"stream":" ---> 1b2c3d4e5f6a\n"}`,
+		`{"stream":"Step 2/4 : COPY package-lock.json ./"}`,
+		`{"stream":" ---> Using cache\n"}`,
+		`{"stream":"Step 3/4 : RUN npm ci"}`,
+		`{"stream":" ---> Using cache\n"}`,
+		`{"stream":"Step 4/4 : COPY . ."}`,
+		`{"stream":" ---> 9f8e7d6c5b4a\n"}`,
+	}
+	_, err := stats.Write([]byte(strings.Join(stream, "\r\n") + "\r\n"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, "Layer cache reused 2 of 3 steps (66%), rebuilt from COPY . .", stats.Summary())
+}
+
+func TestCacheStatsBuildKit(t *testing.T) {
+	var stats cacheStats
+	stats.buildkitVertex("[internal] load build definition from Dockerfile", false)
+	stats.buildkitVertex("[1/3] FROM docker.io/library/node:16", true)
+	stats.buildkitVertex("[2/3] RUN npm ci", true)
+	stats.buildkitVertex("[3/3] COPY . .", false)
+
+	assert.Equal(t, "Layer cache reused 1 of 2 steps (50%), rebuilt from [3/3] COPY . .", stats.Summary())
+}
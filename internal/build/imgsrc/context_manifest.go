@@ -0,0 +1,211 @@
+package imgsrc
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	dockerclient "github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/fileutils"
+	"github.com/dustin/go-humanize"
+	"github.com/moby/buildkit/session/filesync"
+	"github.com/superfly/flyctl/flyctl"
+	fstypes "github.com/tonistiigi/fsutil/types"
+)
+
+// contextManifest records the content hash of every file in a build context, so the
+// next build can tell which files changed since the last upload
+type contextManifest struct {
+	Builder string                 `json:"builder"`
+	Files   map[string]contextFile `json:"files"`
+}
+
+type contextFile struct {
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"mtime"`
+	Hash    string `json:"hash"`
+}
+
+type contextChanges struct {
+	Changed    []string
+	Removed    []string
+	Unchanged  int
+	UploadSize int64
+}
+
+func (c contextChanges) String() string {
+	return fmt.Sprintf("%d changed files to upload (%s), %d unchanged, %d removed", len(c.Changed), humanize.Bytes(uint64(c.UploadSize)), c.Unchanged, len(c.Removed))
+}
+
+// diffBuildContext hashes the build context and compares it with the last one uploaded for the
+// app. The previous manifest only counts when it was uploaded to the same builder.
+func diffBuildContext(docker *dockerclient.Client, opts ImageOptions, excludes []string) (*contextManifest, contextChanges, error) {
+	prev := loadContextManifest(opts.AppName)
+	if prev != nil && prev.Builder != docker.DaemonHost() {
+		prev = nil
+	}
+
+	manifest, err := buildContextManifest(opts.WorkingDir, excludes, prev)
+	if err != nil {
+		return nil, contextChanges{}, err
+	}
+	manifest.Builder = docker.DaemonHost()
+
+	return manifest, manifest.Diff(prev), nil
+}
+
+// contextSyncedDirs are the directories buildkit pulls the context and Dockerfile from over the build session
+func contextSyncedDirs(contextDir, dockerfile string, excludes []string, modTime *time.Time) []filesync.SyncedDir {
+	mapFn := resetUIDAndGID
+	if modTime != nil {
+		mapFn = clampModTimes(*modTime)
+	}
+
+	return []filesync.SyncedDir{
+		{Name: "context", Dir: contextDir, Map: mapFn, Excludes: excludes},
+		{Name: "dockerfile", Dir: filepath.Dir(dockerfile)},
+	}
+}
+
+// resetUIDAndGID matches the ownership of files sent in a tar context, which docker resets to root
+func resetUIDAndGID(_ string, s *fstypes.Stat) bool {
+	s.Uid = 0
+	s.Gid = 0
+	return true
+}
+
+// buildContextManifest hashes the files in dir that aren't excluded. Files whose size and
+// modification time match prev reuse its hash instead of being read again.
+func buildContextManifest(dir string, excludes []string, prev *contextManifest) (*contextManifest, error) {
+	pm, err := fileutils.NewPatternMatcher(excludes)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &contextManifest{Files: map[string]contextFile{}}
+
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		skip, err := pm.Matches(rel)
+		if err != nil {
+			return err
+		}
+		if skip {
+			// keep walking excluded dirs when an exclusion has exceptions, they may include files below it
+			if info.IsDir() && !pm.Exclusions() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+
+		file := contextFile{Size: info.Size(), ModTime: info.ModTime().UnixNano()}
+		if old, ok := prev.file(rel); ok && old.Size == file.Size && old.ModTime == file.ModTime {
+			file.Hash = old.Hash
+		} else if file.Hash, err = hashFile(path); err != nil {
+			return err
+		}
+
+		m.Files[rel] = file
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func (m *contextManifest) file(path string) (contextFile, bool) {
+	if m == nil {
+		return contextFile{}, false
+	}
+	f, ok := m.Files[path]
+	return f, ok
+}
+
+// Diff reports the files that need to be uploaded for m when the builder already has prev
+func (m *contextManifest) Diff(prev *contextManifest) contextChanges {
+	var changes contextChanges
+
+	for path, f := range m.Files {
+		if old, ok := prev.file(path); ok && old.Hash == f.Hash {
+			changes.Unchanged++
+			continue
+		}
+		changes.Changed = append(changes.Changed, path)
+		changes.UploadSize += f.Size
+	}
+
+	if prev != nil {
+		for path := range prev.Files {
+			if _, ok := m.Files[path]; !ok {
+				changes.Removed = append(changes.Removed, path)
+			}
+		}
+	}
+
+	return changes
+}
+
+func contextManifestPath(appName string) string {
+	return filepath.Join(flyctl.ConfigDir(), "build-context", appName+".json")
+}
+
+// loadContextManifest returns the manifest of the last context uploaded for an app, or nil if there isn't one
+func loadContextManifest(appName string) *contextManifest {
+	data, err := os.ReadFile(contextManifestPath(appName))
+	if err != nil {
+		return nil
+	}
+
+	var m contextManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil
+	}
+	return &m
+}
+
+func (m *contextManifest) save(appName string) error {
+	path := contextManifestPath(appName)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
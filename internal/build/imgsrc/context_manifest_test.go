@@ -0,0 +1,35 @@
+package imgsrc
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContextManifestDiff(t *testing.T) {
+	testDir, err := newTestDir("a.jpg", "content/foo.md", "images/a.jpg", "images/b.jpg")
+	assert.NoError(t, err)
+	defer os.RemoveAll(testDir)
+
+	prev, err := buildContextManifest(testDir, []string{"images/b.jpg"}, nil)
+	assert.NoError(t, err)
+	assert.Len(t, prev.Files, 3)
+
+	changes := prev.Diff(nil)
+	assert.ElementsMatch(t, changes.Changed, []string{"a.jpg", "content/foo.md", "images/a.jpg"})
+	assert.Equal(t, int64(len("a.jpg")+len("content/foo.md")+len("images/a.jpg")), changes.UploadSize)
+
+	assert.NoError(t, os.WriteFile(filepath.Join(testDir, "content/foo.md"), []byte("updated"), 0777))
+	assert.NoError(t, os.Remove(filepath.Join(testDir, "a.jpg")))
+
+	next, err := buildContextManifest(testDir, []string{"images/b.jpg"}, prev)
+	assert.NoError(t, err)
+
+	changes = next.Diff(prev)
+	assert.Equal(t, []string{"content/foo.md"}, changes.Changed)
+	assert.Equal(t, []string{"a.jpg"}, changes.Removed)
+	assert.Equal(t, 1, changes.Unchanged)
+	assert.Equal(t, int64(len("updated")), changes.UploadSize)
+}
@@ -7,7 +7,6 @@ import (
 	"fmt"
 	"net"
 	"net/http"
-	"net/url"
 	"os"
 	"path/filepath"
 	"time"
@@ -22,17 +21,27 @@ import (
 	"github.com/superfly/flyctl/api"
 	"github.com/superfly/flyctl/flyctl"
 	"github.com/superfly/flyctl/helpers"
+	"github.com/superfly/flyctl/internal/dockerconfig"
 	"github.com/superfly/flyctl/internal/monitor"
 	"github.com/superfly/flyctl/pkg/iostreams"
 	"github.com/superfly/flyctl/terminal"
 )
 
+// DefaultBuilderTimeout is how long to wait for a remote builder to become ready when no timeout is configured
+const DefaultBuilderTimeout = 5 * time.Minute
+
 type dockerClientFactory struct {
 	mode    DockerDaemonType
 	buildFn func(ctx context.Context) (*dockerclient.Client, error)
 }
 
-func newDockerClientFactory(daemonType DockerDaemonType, apiClient *api.Client, appName string, streams *iostreams.IOStreams) *dockerClientFactory {
+// newDockerClientFactory picks the docker daemon to build with. builder names one of the
+// organization's remote builders, or is empty for its default one.
+func newDockerClientFactory(daemonType DockerDaemonType, apiClient *api.Client, appName string, streams *iostreams.IOStreams, builderTimeout time.Duration, resources BuildResources, network PrivateNetwork, builder string) *dockerClientFactory {
+	if builderTimeout <= 0 {
+		builderTimeout = DefaultBuilderTimeout
+	}
+
 	if daemonType.AllowLocal() {
 		terminal.Debug("trying local docker daemon")
 		c, err := newLocalDockerClient()
@@ -60,7 +69,7 @@ func newDockerClientFactory(daemonType DockerDaemonType, apiClient *api.Client,
 				if cachedDocker != nil {
 					return cachedDocker, nil
 				}
-				c, err := newRemoteDockerClient(ctx, apiClient, appName, streams)
+				c, err := newRemoteDockerClient(ctx, apiClient, appName, streams, builderTimeout, resources, network, builder)
 				if err != nil {
 					return nil, err
 				}
@@ -152,39 +161,26 @@ func newLocalDockerClient() (*dockerclient.Client, error) {
 	return c, nil
 }
 
-func newRemoteDockerClient(ctx context.Context, apiClient *api.Client, appName string, streams *iostreams.IOStreams) (*dockerclient.Client, error) {
-	host, remoteBuilderAppName, err := remoteBuilderURL(apiClient, appName)
-	if err != nil {
-		return nil, err
-	}
-
-	terminal.Debugf("Remote Docker builder host: %s\n", host)
-
-	transport := &http.Transport{
-		TLSHandshakeTimeout:   10 * time.Second,
-		ResponseHeaderTimeout: 60 * time.Second,
-		// don't reuse connections to remote daemon to prevent deadlock in buildpack layer fetching.
-		// remove this once an http proxy is working with pack again
-		DisableKeepAlives: true,
-	}
-	if os.Getenv("FLY_REMOTE_BUILDER_NO_TLS") != "1" {
-		transport.TLSClientConfig = tlsconfig.ClientDefault()
-	}
-
-	httpc := &http.Client{
-		Transport: transport,
+func newRemoteDockerClient(ctx context.Context, apiClient *api.Client, appName string, streams *iostreams.IOStreams, timeout time.Duration, resources BuildResources, network PrivateNetwork, builder string) (*dockerclient.Client, error) {
+	var (
+		client               *dockerclient.Client
+		remoteBuilderAppName string
+		err                  error
+	)
+
+	if host := os.Getenv("FLY_REMOTE_BUILDER_HOST"); host != "" {
+		terminal.Debugf("Remote Docker builder host: %s\n", host)
+		client, err = newPublicRemoteDockerClient(host, appName)
+	} else {
+		var app *api.App
+		if app, err = ensureRemoteBuilder(apiClient, appName, resources, builder); err != nil {
+			return nil, err
+		}
+		remoteBuilderAppName = app.Name
+		client, err = newPrivateRemoteDockerClient(ctx, network, app)
 	}
-
-	client, err := dockerclient.NewClientWithOpts(
-		dockerclient.WithAPIVersionNegotiation(),
-		dockerclient.WithHTTPClient(httpc),
-		dockerclient.WithHost(host),
-		dockerclient.WithHTTPHeaders(map[string]string{
-			"Authorization": basicAuth(appName, flyctl.GetAPIToken()),
-		}))
-
 	if err != nil {
-		return nil, errors.Wrap(err, "Error creating docker client")
+		return nil, err
 	}
 
 	err = func() error {
@@ -195,7 +191,7 @@ func newRemoteDockerClient(ctx context.Context, apiClient *api.Client, appName s
 			} else {
 				fmt.Fprintf(streams.ErrOut, "Waiting for remote builder %s...\n", remoteBuilderAppName)
 			}
-			remoteBuilderLaunched, err := monitor.WaitForRunningVM(ctx, remoteBuilderAppName, apiClient, 5*time.Minute, func(status string) {
+			remoteBuilderLaunched, err := monitor.WaitForRunningVM(ctx, remoteBuilderAppName, apiClient, timeout, func(status string) {
 				streams.ChangeProgressIndicatorMsg(fmt.Sprintf("Waiting for remote builder %s... %s", remoteBuilderAppName, status))
 			})
 			if err != nil {
@@ -207,7 +203,7 @@ func newRemoteDockerClient(ctx context.Context, apiClient *api.Client, appName s
 			}
 		}
 
-		return waitForDaemon(ctx, client)
+		return waitForDaemon(ctx, client, timeout)
 	}()
 
 	if err != nil {
@@ -217,29 +213,82 @@ func newRemoteDockerClient(ctx context.Context, apiClient *api.Client, appName s
 	return client, nil
 }
 
-func remoteBuilderURL(apiClient *api.Client, appName string) (string, string, error) {
-	if v := os.Getenv("FLY_REMOTE_BUILDER_HOST"); v != "" {
-		return v, "", nil
+// newPublicRemoteDockerClient connects to a builder at a public address given with FLY_REMOTE_BUILDER_HOST,
+// authenticating with the API token over TLS
+func newPublicRemoteDockerClient(host, appName string) (*dockerclient.Client, error) {
+	transport := &http.Transport{
+		TLSHandshakeTimeout:   10 * time.Second,
+		ResponseHeaderTimeout: 60 * time.Second,
+		// don't reuse connections to remote daemon to prevent deadlock in buildpack layer fetching.
+		// remove this once an http proxy is working with pack again
+		DisableKeepAlives: true,
+	}
+	if os.Getenv("FLY_REMOTE_BUILDER_NO_TLS") != "1" {
+		transport.TLSClientConfig = tlsconfig.ClientDefault()
 	}
 
-	rawURL, app, err := apiClient.EnsureRemoteBuilder(appName)
+	client, err := dockerclient.NewClientWithOpts(
+		dockerclient.WithAPIVersionNegotiation(),
+		dockerclient.WithHTTPClient(&http.Client{Transport: transport}),
+		dockerclient.WithHost(host),
+		dockerclient.WithHTTPHeaders(map[string]string{
+			"Authorization": basicAuth(appName, flyctl.GetAPIToken()),
+		}))
 	if err != nil {
-		return "", "", errors.Errorf("could not create remote builder: %v", err)
+		return nil, errors.Wrap(err, "Error creating docker client")
+	}
+
+	return client, nil
+}
+
+// newPrivateRemoteDockerClient connects to the builder's 6PN address through the organization's
+// WireGuard network, so the builder doesn't need a public Docker API port
+func newPrivateRemoteDockerClient(ctx context.Context, network PrivateNetwork, builder *api.App) (*dockerclient.Client, error) {
+	if network == nil {
+		return nil, errors.New("remote builds need a connection to the organization's private network")
 	}
 
-	parsedURL, err := url.Parse(rawURL)
+	tunnel, err := network(ctx, &builder.Organization)
 	if err != nil {
-		return "", "", errors.Wrap(err, "error parsing remote builder url")
+		return nil, errors.Wrap(err, "error connecting to the remote builder over WireGuard")
 	}
 
-	host := parsedURL.Hostname()
-	port := parsedURL.Port()
+	host := "tcp://" + net.JoinHostPort(builder.Name+".internal", remoteBuilderPort)
+	terminal.Debugf("Remote Docker builder host: %s\n", host)
 
-	if port == "" {
-		port = "10000"
+	transport := &http.Transport{
+		DialContext:           tunnelDialer(tunnel),
+		ResponseHeaderTimeout: 60 * time.Second,
+		// don't reuse connections to remote daemon to prevent deadlock in buildpack layer fetching.
+		// remove this once an http proxy is working with pack again
+		DisableKeepAlives: true,
 	}
 
-	return "tcp://" + net.JoinHostPort(host, port), app.Name, nil
+	client, err := dockerclient.NewClientWithOpts(
+		dockerclient.WithAPIVersionNegotiation(),
+		dockerclient.WithHTTPClient(&http.Client{Transport: transport}),
+		dockerclient.WithHost(host),
+	)
+	if err != nil {
+		return nil, errors.Wrap(err, "Error creating docker client")
+	}
+
+	return client, nil
+}
+
+// ensureRemoteBuilder returns the organization's builder app, provisioning it if needed
+func ensureRemoteBuilder(apiClient *api.Client, appName string, resources BuildResources, builder string) (*api.App, error) {
+	input := resources.remoteBuilderInput(appName)
+	input.Name = builder
+
+	_, app, err := apiClient.EnsureRemoteBuilder(input)
+	if err != nil {
+		return nil, errors.Errorf("could not create remote builder: %v", err)
+	}
+	if app == nil {
+		return nil, errors.New("remote builder app unavailable")
+	}
+	return app, nil
 }
 
 func basicAuth(appName, authToken string) string {
@@ -247,8 +296,8 @@ func basicAuth(appName, authToken string) string {
 	return "Basic " + base64.StdEncoding.EncodeToString([]byte(auth))
 }
 
-func waitForDaemon(ctx context.Context, client *dockerclient.Client) error {
-	deadline := time.After(5 * time.Minute)
+func waitForDaemon(ctx context.Context, client *dockerclient.Client, timeout time.Duration) error {
+	deadline := time.After(timeout)
 
 	b := &backoff.Backoff{
 		//These are the defaults
@@ -298,7 +347,7 @@ OUTER:
 				time.Sleep(dur)
 			}
 		case <-deadline:
-			return fmt.Errorf("Could not ping remote builder within 5 minutes, aborting.")
+			return fmt.Errorf("Could not ping remote builder within %s, aborting.", timeout)
 		case <-ctx.Done():
 			terminal.Warn("Canceled")
 			break OUTER
@@ -336,9 +385,24 @@ func registryAuth(token string) types.AuthConfig {
 	}
 }
 
+// authConfigs returns credentials for pulling private base images, from the docker config
+// and its credential helpers, or for docker hub from DOCKER_HUB_USERNAME and DOCKER_HUB_PASSWORD
 func authConfigs() map[string]types.AuthConfig {
 	authConfigs := map[string]types.AuthConfig{}
 
+	if dockerCfg, err := dockerconfig.Load(); err != nil {
+		terminal.Warnf("Could not read docker credentials: %v\n", err)
+	} else {
+		for server, cred := range dockerCfg.All() {
+			authConfigs[server] = types.AuthConfig{
+				Username:      cred.Username,
+				Password:      cred.Password,
+				IdentityToken: cred.IdentityToken,
+				ServerAddress: server,
+			}
+		}
+	}
+
 	dockerhubUsername := os.Getenv("DOCKER_HUB_USERNAME")
 	dockerhubPassword := os.Getenv("DOCKER_HUB_PASSWORD")
 
@@ -379,15 +443,20 @@ func newDeploymentTag(appName string, label string) string {
 	return fmt.Sprintf("%s/%s:%s", registry, appName, label)
 }
 
-// resolveDockerfile - Resolve the location of the dockerfile, allowing for upper and lowercase naming
-func resolveDockerfile(cwd string) string {
-	dockerfilePath := filepath.Join(cwd, "Dockerfile")
-	if helpers.FileExists(dockerfilePath) {
-		return dockerfilePath
+// resolveDockerfile finds the Dockerfile to build, allowing for upper and lowercase naming. A process
+// group's own Dockerfile.<group> wins, then a fly specific Dockerfile.fly, then the plain Dockerfile.
+func resolveDockerfile(cwd string, processGroup string) string {
+	var names []string
+	if processGroup != "" {
+		names = append(names, "Dockerfile."+processGroup, "dockerfile."+processGroup)
 	}
-	dockerfilePath = filepath.Join(cwd, "dockerfile")
-	if helpers.FileExists(dockerfilePath) {
-		return dockerfilePath
+	names = append(names, "Dockerfile.fly", "dockerfile.fly", "Dockerfile", "dockerfile")
+
+	for _, name := range names {
+		dockerfilePath := filepath.Join(cwd, name)
+		if helpers.FileExists(dockerfilePath) {
+			return dockerfilePath
+		}
 	}
 	return ""
 }
@@ -9,18 +9,21 @@ import (
 	"net"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/containerd/console"
 	"github.com/docker/docker/api/types"
 	dockerclient "github.com/docker/docker/client"
 	"github.com/docker/docker/pkg/jsonmessage"
 	"github.com/docker/docker/pkg/stringid"
+	"github.com/jpillora/backoff"
+	"github.com/moby/buildkit/session/filesync"
 	"github.com/moby/buildkit/util/progress/progressui"
 	"github.com/moby/term"
 	"github.com/pkg/errors"
 	"github.com/superfly/flyctl/flyctl"
 	"github.com/superfly/flyctl/helpers"
-	"github.com/superfly/flyctl/internal/cmdfmt"
+	"github.com/superfly/flyctl/internal/registry"
 	"github.com/superfly/flyctl/pkg/iostreams"
 	"github.com/superfly/flyctl/terminal"
 	"golang.org/x/sync/errgroup"
@@ -46,7 +49,7 @@ func (ds *dockerfileBuilder) Run(ctx context.Context, dockerFactory *dockerClien
 		}
 		dockerfile = opts.DockerfilePath
 	} else {
-		dockerfile = resolveDockerfile(opts.WorkingDir)
+		dockerfile = resolveDockerfile(opts.WorkingDir, opts.ProcessGroup)
 	}
 
 	if dockerfile == "" {
@@ -61,77 +64,134 @@ func (ds *dockerfileBuilder) Run(ctx context.Context, dockerFactory *dockerClien
 
 	defer clearDeploymentTags(ctx, docker, opts.Tag)
 
-	cmdfmt.PrintBegin(streams.ErrOut, "Creating build context")
-	archiveOpts := archiveOptions{
-		sourcePath: opts.WorkingDir,
-		compressed: dockerFactory.mode.IsRemote(),
+	reporter := newBuildReporter(streams, opts.BuildOutput, opts.Events)
+
+	buildkitEnabled, err := buildkitEnabled(docker)
+	terminal.Debugf("buildkitEnabled", buildkitEnabled)
+	if err != nil {
+		return nil, errors.Wrap(err, "error checking for buildkit support")
+	}
+	if len(opts.SSH) > 0 && !buildkitEnabled {
+		return nil, errors.New("ssh forwarding requires BuildKit, set DOCKER_BUILDKIT=1 or enable it in the docker daemon")
+	}
+	if !opts.Resources.IsZero() && buildkitEnabled && dockerFactory.mode == DockerDaemonTypeLocal {
+		// the daemon's buildkit runs steps with its own limits rather than per build ones
+		terminal.Warnf("BuildKit doesn't apply --build-memory or --build-cpus, limit the docker daemon's resources instead or set DOCKER_BUILDKIT=0\n")
+	}
+
+	for _, hint := range dockerfileCacheHints(opts.WorkingDir, dockerfile, buildkitEnabled) {
+		reporter.Log("build", "Cache hint: Dockerfile "+hint)
 	}
 
 	excludes, err := readDockerignore(opts.WorkingDir)
 	if err != nil {
 		return nil, errors.Wrap(err, "error reading .dockerignore")
 	}
-	archiveOpts.exclusions = excludes
 
+	epoch := reproducibleEpoch(opts)
+
+	var r io.ReadCloser
+	var syncedDirs []filesync.SyncedDir
+	var manifest *contextManifest
 	var relativedockerfilePath string
 
-	// copy dockerfile into the archive if it's outside the context dir
-	if !isPathInRoot(dockerfile, opts.WorkingDir) {
-		dockerfileData, err := os.ReadFile(dockerfile)
+	if buildkitEnabled && dockerFactory.mode.IsRemote() {
+		// the builder keeps the context it last received from this machine, so only changed files are sent
+		reporter.Begin("context", "Checking build context for changes")
+		var changes contextChanges
+		manifest, changes, err = diffBuildContext(docker, opts, excludes)
 		if err != nil {
-			return nil, errors.Wrap(err, "error reading Dockerfile")
+			reporter.Fail("context", err)
+			return nil, errors.Wrap(err, "error checking build context")
 		}
-		archiveOpts.additions = map[string][]byte{
-			"Dockerfile": dockerfileData,
+		syncedDirs = contextSyncedDirs(opts.WorkingDir, dockerfile, excludes, epoch)
+		relativedockerfilePath = filepath.Base(dockerfile)
+		reporter.Done("context", changes.String())
+	} else {
+		reporter.Begin("context", "Creating build context")
+		archiveOpts := archiveOptions{
+			sourcePath:       opts.WorkingDir,
+			compressed:       dockerFactory.mode.IsRemote(),
+			exclusions:       excludes,
+			modTime:          epoch,
+			compressionLevel: opts.CompressionLevel,
 		}
-	} else if filepath.Base(dockerfile) != "Dockerfile" {
-		// pass the relative path to Dockerfile through if it isn't the default
-		p, err := filepath.Rel(opts.WorkingDir, dockerfile)
-		if err != nil {
-			return nil, err
+
+		// copy dockerfile into the archive if it's outside the context dir
+		if !isPathInRoot(dockerfile, opts.WorkingDir) {
+			dockerfileData, err := os.ReadFile(dockerfile)
+			if err != nil {
+				return nil, errors.Wrap(err, "error reading Dockerfile")
+			}
+			archiveOpts.additions = map[string][]byte{
+				"Dockerfile": dockerfileData,
+			}
+		} else if filepath.Base(dockerfile) != "Dockerfile" {
+			// pass the relative path to Dockerfile through if it isn't the default
+			p, err := filepath.Rel(opts.WorkingDir, dockerfile)
+			if err != nil {
+				return nil, err
+			}
+			relativedockerfilePath = p
 		}
-		relativedockerfilePath = p
-	}
 
-	r, err := archiveDirectory(archiveOpts)
-	if err != nil {
-		return nil, errors.Wrap(err, "error archiving build context")
+		r, err = archiveDirectory(archiveOpts)
+		if err != nil {
+			reporter.Fail("context", err)
+			return nil, errors.Wrap(err, "error archiving build context")
+		}
+		reporter.Done("context", "Creating build context done")
 	}
-	cmdfmt.PrintDone(streams.ErrOut, "Creating build context done")
 
 	var imageID string
 
-	cmdfmt.PrintBegin(streams.ErrOut, "Building image with Docker")
+	reporter.Begin("build", "Building image with Docker")
 
 	buildArgs := normalizeBuildArgsForDocker(opts.AppConfig, opts.ExtraBuildArgs)
+	addSourceDateEpochArg(buildArgs, epoch)
 
-	buildkitEnabled, err := buildkitEnabled(docker)
-	terminal.Debugf("buildkitEnabled", buildkitEnabled)
-	if err != nil {
-		return nil, errors.Wrap(err, "error checking for buildkit support")
-	}
 	if buildkitEnabled {
-		imageID, err = runBuildKitBuild(ctx, streams, docker, r, opts, relativedockerfilePath, buildArgs)
-		if err != nil {
-			return nil, errors.Wrap(err, "error building")
-		}
+		imageID, err = runBuildKitBuild(ctx, reporter, docker, r, syncedDirs, opts, relativedockerfilePath, buildArgs)
 	} else {
-		imageID, err = runClassicBuild(ctx, streams, docker, r, opts, relativedockerfilePath, buildArgs)
-		if err != nil {
-			return nil, errors.Wrap(err, "error building")
+		imageID, err = runClassicBuild(ctx, reporter, docker, r, opts, relativedockerfilePath, buildArgs)
+	}
+	if isOOMKill(err) {
+		err = &BuildOOMError{MemoryBytes: opts.Resources.MemoryBytes, Err: err}
+	}
+	if err != nil {
+		reporter.Fail("build", err)
+		return nil, errors.Wrap(err, "error building")
+	}
+
+	reporter.Done("build", "Building image done")
+
+	if summary := reporter.cache.Summary(); summary != "" {
+		reporter.Log("build", summary)
+	}
+
+	if manifest != nil {
+		if err := manifest.save(opts.AppName); err != nil {
+			terminal.Debug("error saving build context manifest:", err)
 		}
 	}
 
-	cmdfmt.PrintDone(streams.ErrOut, "Building image done")
+	if opts.ScanSeverity != "" {
+		if err := scanImage(ctx, docker, reporter, opts.Tag, opts.ScanSeverity, opts.ScanImage); err != nil {
+			return nil, err
+		}
+	}
 
+	var digest string
 	if opts.Publish {
-		cmdfmt.PrintBegin(streams.ErrOut, "Pushing image to fly")
+		reporter.Begin("push", "Pushing image to fly")
 
-		if err := pushToFly(ctx, docker, streams, opts.Tag); err != nil {
+		digest, err = pushToFly(ctx, docker, reporter, opts.Tag, pushOptions(dockerFactory, opts.PushConcurrency, opts.CompressionLevel))
+		if err != nil {
+			reporter.Fail("push", err)
 			return nil, err
 		}
 
-		cmdfmt.PrintDone(streams.ErrOut, "Pushing image done")
+		reporter.Done("push", "Pushing image done")
 	}
 
 	img, _, err := docker.ImageInspectWithRaw(ctx, imageID)
@@ -139,10 +199,13 @@ func (ds *dockerfileBuilder) Run(ctx context.Context, dockerFactory *dockerClien
 		return nil, errors.Wrap(err, "count not find built image")
 	}
 
+	reporter.Image(opts.Tag, digest, img.Size)
+
 	return &DeploymentImage{
-		ID:   img.ID,
-		Tag:  opts.Tag,
-		Size: img.Size,
+		ID:     img.ID,
+		Tag:    opts.Tag,
+		Digest: digest,
+		Size:   img.Size,
 	}, nil
 }
 
@@ -166,7 +229,15 @@ func normalizeBuildArgsForDocker(appConfig *flyctl.AppConfig, extra map[string]s
 	return out
 }
 
-func runClassicBuild(ctx context.Context, streams *iostreams.IOStreams, docker *dockerclient.Client, r io.ReadCloser, opts ImageOptions, dockerfilePath string, buildArgs map[string]*string) (imageID string, err error) {
+// buildTarget is the multi-stage target from fly.toml, empty to build the last stage
+func buildTarget(opts ImageOptions) string {
+	if opts.AppConfig == nil {
+		return ""
+	}
+	return opts.AppConfig.BuildTarget(opts.ProcessGroup)
+}
+
+func runClassicBuild(ctx context.Context, reporter *buildReporter, docker *dockerclient.Client, r io.ReadCloser, opts ImageOptions, dockerfilePath string, buildArgs map[string]*string) (imageID string, err error) {
 	options := types.ImageBuildOptions{
 		Tags:      []string{opts.Tag},
 		BuildArgs: buildArgs,
@@ -174,7 +245,9 @@ func runClassicBuild(ctx context.Context, streams *iostreams.IOStreams, docker *
 		AuthConfigs: authConfigs(),
 		Platform:    "linux/amd64",
 		Dockerfile:  dockerfilePath,
+		Target:      buildTarget(opts),
 	}
+	opts.Resources.apply(&options)
 
 	resp, err := docker.ImageBuild(ctx, r, options)
 	if err != nil {
@@ -183,24 +256,30 @@ func runClassicBuild(ctx context.Context, streams *iostreams.IOStreams, docker *
 	defer resp.Body.Close()
 
 	idCallback := func(m jsonmessage.JSONMessage) {
-		fmt.Println("got a message", m.ID, m)
+		terminal.Debug("got a message", m.ID, m)
 		var aux types.BuildResult
 		if err := json.Unmarshal(*m.Aux, &aux); err != nil {
-			fmt.Fprintf(streams.Out, "failed to parse aux message: %v", err)
+			fmt.Fprintf(reporter.streams.ErrOut, "failed to parse aux message: %v", err)
 		}
 		imageID = aux.ID
 	}
 
-	if err := jsonmessage.DisplayJSONMessagesStream(resp.Body, streams.ErrOut, streams.StderrFd(), streams.IsStderrTTY(), idCallback); err != nil {
+	body := io.TeeReader(resp.Body, &reporter.cache)
+	if err := reporter.DisplayJSONMessages("build", body, idCallback); err != nil {
 		return "", errors.Wrap(err, "error rendering build status stream")
 	}
 
 	return imageID, nil
 }
 
-const uploadRequestRemote = "upload-request"
+const (
+	uploadRequestRemote = "upload-request"
+	clientSessionRemote = "client-session"
+)
 
-func runBuildKitBuild(ctx context.Context, streams *iostreams.IOStreams, docker *dockerclient.Client, r io.ReadCloser, opts ImageOptions, dockerfilePath string, buildArgs map[string]*string) (imageID string, err error) {
+// runBuildKitBuild builds with buildkit. The context is either uploaded in full from r, or
+// synced from syncedDirs over the build session when r is nil.
+func runBuildKitBuild(ctx context.Context, reporter *buildReporter, docker *dockerclient.Client, r io.ReadCloser, syncedDirs []filesync.SyncedDir, opts ImageOptions, dockerfilePath string, buildArgs map[string]*string) (imageID string, err error) {
 	s, err := createBuildSession(opts.WorkingDir)
 	if err != nil {
 		panic(err)
@@ -210,6 +289,23 @@ func runBuildKitBuild(ctx context.Context, streams *iostreams.IOStreams, docker
 		panic("buildkit not supported")
 	}
 
+	// buildkit asks the session for registry credentials when pulling base images
+	s.Allow(newRegistryAuthProvider(reporter.streams.ErrOut))
+
+	sshProvider, err := sshAgentProvider(opts.SSH)
+	if err != nil {
+		return "", err
+	}
+	if sshProvider != nil {
+		s.Allow(sshProvider)
+	}
+
+	remoteContext := uploadRequestRemote
+	if r == nil {
+		remoteContext = clientSessionRemote
+		s.Allow(filesync.NewFSSyncProvider(syncedDirs))
+	}
+
 	eg, errCtx := errgroup.WithContext(ctx)
 
 	dialSession := func(ctx context.Context, proto string, meta map[string][]string) (net.Conn, error) {
@@ -220,19 +316,21 @@ func runBuildKitBuild(ctx context.Context, streams *iostreams.IOStreams, docker
 	})
 
 	buildID := stringid.GenerateRandomID()
-	eg.Go(func() error {
-		buildOptions := types.ImageBuildOptions{
-			Version: types.BuilderBuildKit,
-			BuildID: uploadRequestRemote + ":" + buildID,
-		}
+	if r != nil {
+		eg.Go(func() error {
+			buildOptions := types.ImageBuildOptions{
+				Version: types.BuilderBuildKit,
+				BuildID: uploadRequestRemote + ":" + buildID,
+			}
 
-		response, err := docker.ImageBuild(context.Background(), r, buildOptions)
-		if err != nil {
-			return err
-		}
-		defer response.Body.Close()
-		return nil
-	})
+			response, err := docker.ImageBuild(context.Background(), r, buildOptions)
+			if err != nil {
+				return err
+			}
+			defer response.Body.Close()
+			return nil
+		})
+	}
 
 	eg.Go(func() error {
 		defer s.Close()
@@ -243,10 +341,11 @@ func runBuildKitBuild(ctx context.Context, streams *iostreams.IOStreams, docker
 			Version:       types.BuilderBuildKit,
 			AuthConfigs:   authConfigs(),
 			SessionID:     s.ID(),
-			RemoteContext: uploadRequestRemote,
+			RemoteContext: remoteContext,
 			BuildID:       buildID,
 			Platform:      "linux/amd64",
 			Dockerfile:    dockerfilePath,
+			Target:        buildTarget(opts),
 		}
 
 		return func() error {
@@ -270,7 +369,7 @@ func runBuildKitBuild(ctx context.Context, streams *iostreams.IOStreams, docker
 
 			// TODO: replace with iostreams
 			termFd, isTerm := term.GetFdInfo(os.Stderr)
-			tracer := newTracer()
+			tracer := newTracer(&reporter.cache)
 			var c2 console.Console
 			if isTerm {
 				if cons, err := console.ConsoleFromFile(os.Stderr); err == nil {
@@ -279,6 +378,9 @@ func runBuildKitBuild(ctx context.Context, streams *iostreams.IOStreams, docker
 			}
 
 			eg.Go(func() error {
+				if reporter.json {
+					return reporter.DisplaySolveStatus(context.TODO(), tracer.displayCh)
+				}
 				return progressui.DisplaySolveStatus(context.TODO(), "", c2, os.Stderr, tracer.displayCh)
 			})
 
@@ -286,7 +388,7 @@ func runBuildKitBuild(ctx context.Context, streams *iostreams.IOStreams, docker
 				if m.ID == "moby.image.id" {
 					var result types.BuildResult
 					if err := json.Unmarshal(*m.Aux, &result); err != nil {
-						fmt.Fprintf(streams.Out, "failed to parse aux message: %v", err)
+						fmt.Fprintf(reporter.streams.ErrOut, "failed to parse aux message: %v", err)
 					}
 					imageID = result.ID
 					return
@@ -313,26 +415,120 @@ func runBuildKitBuild(ctx context.Context, streams *iostreams.IOStreams, docker
 	return imageID, nil
 }
 
-func pushToFly(ctx context.Context, docker *dockerclient.Client, streams *iostreams.IOStreams, tag string) error {
+var (
+	// pushAttempts is how many times a push is tried before the deploy fails. The daemon skips layers
+	// the registry already has, so each retry only sends the layers that didn't make it.
+	pushAttempts     = 5
+	pushRetryBackoff = backoff.Backoff{Min: 2 * time.Second, Max: 30 * time.Second, Factor: 2, Jitter: true}
+)
+
+// pushOptions returns how flyctl pushes layers, with a zero concurrency to let the daemon push the
+// image. A remote builder runs next to the registry, so exporting its image would only slow it down.
+func pushOptions(dockerFactory *dockerClientFactory, concurrency int, compressionLevel int) registry.PushOptions {
+	if !dockerFactory.mode.IsLocal() {
+		return registry.PushOptions{}
+	}
+	if concurrency <= 0 {
+		concurrency = registry.DefaultPushConcurrency
+	}
+	return registry.PushOptions{Concurrency: concurrency, CompressionLevel: compressionLevel}
+}
+
+// pushToFly pushes an image to the fly registry. With a concurrency, the image is exported from the
+// daemon and its layers are compressed and uploaded by flyctl, otherwise the daemon pushes it.
+func pushToFly(ctx context.Context, docker *dockerclient.Client, reporter *buildReporter, tag string, push registry.PushOptions) (digest string, err error) {
+	if push.Concurrency > 0 {
+		return pushArchive(ctx, docker, reporter, tag, push)
+	}
+
+	b := pushRetryBackoff
+
+	for attempt := 1; ; attempt++ {
+		digest, err = pushImage(ctx, docker, reporter, tag)
+		if err == nil || attempt >= pushAttempts || !retryablePushError(ctx, err) {
+			return digest, err
+		}
+
+		delay := b.Duration()
+		reporter.Log("push", fmt.Sprintf("Push failed: %s, retrying in %s (attempt %d of %d)", err, delay.Round(time.Second), attempt+1, pushAttempts))
+
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(delay):
+		}
+	}
+}
+
+// pushArchive exports an image from the daemon and pushes its layers with the registry client.
+// Failed uploads are retried and resumed by the client.
+func pushArchive(ctx context.Context, docker *dockerclient.Client, reporter *buildReporter, tag string, push registry.PushOptions) (string, error) {
+	ref, err := registry.ParseReference(tag)
+	if err != nil {
+		return "", err
+	}
+
+	archive, err := docker.ImageSave(ctx, []string{tag})
+	if err != nil {
+		return "", errors.Wrap(err, "error exporting image")
+	}
+	defer archive.Close()
+
+	client := registry.NewClient(ref.Host, "x", flyctl.GetAPIToken())
+	push.Progress = func(desc registry.Descriptor, uploaded bool) {
+		status := "Layer already exists"
+		if uploaded {
+			status = "Pushed"
+		}
+		reporter.Layer("push", desc.Digest, status, desc.Size)
+	}
+	digest, err := registry.PushArchive(ctx, client, ref, archive, push)
+	if err != nil {
+		var unauthorized *registry.UnauthorizedError
+		if errors.As(err, &unauthorized) {
+			return "", &RegistryUnauthorizedError{Tag: tag}
+		}
+		return "", errors.Wrap(err, "error pushing image to registry")
+	}
+
+	return digest, nil
+}
+
+// retryablePushError reports whether a failed push may succeed if tried again
+func retryablePushError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var unauthorized *RegistryUnauthorizedError
+	return !errors.As(err, &unauthorized)
+}
+
+func pushImage(ctx context.Context, docker *dockerclient.Client, reporter *buildReporter, tag string) (digest string, err error) {
 	pushResp, err := docker.ImagePush(ctx, tag, types.ImagePushOptions{
 		RegistryAuth: flyRegistryAuth(),
 	})
 	if err != nil {
-		return errors.Wrap(err, "error pushing image to registry")
+		return "", errors.Wrap(err, "error pushing image to registry")
 	}
 	defer pushResp.Close()
 
-	err = jsonmessage.DisplayJSONMessagesStream(pushResp, streams.ErrOut, streams.StderrFd(), streams.IsStderrTTY(), nil)
+	digestCallback := func(m jsonmessage.JSONMessage) {
+		if d := pushResultDigest(m); d != "" {
+			digest = d
+		}
+	}
+
+	err = reporter.DisplayJSONMessages("push", pushResp, digestCallback)
 	if err != nil {
 		var msgerr *jsonmessage.JSONError
 
 		if errors.As(err, &msgerr) {
 			if msgerr.Message == "denied: requested access to the resource is denied" {
-				return &RegistryUnauthorizedError{Tag: tag}
+				return "", &RegistryUnauthorizedError{Tag: tag}
 			}
 		}
-		return errors.Wrap(err, "error rendering push status stream")
+		return "", errors.Wrap(err, "error rendering push status stream")
 	}
 
-	return nil
+	return digest, nil
 }
@@ -14,7 +14,7 @@ import (
 
 func TestBuildDockerfileApp(t *testing.T) {
 	t.Skip()
-	df := newDockerClientFactory(DockerDaemonTypeLocal, nil, "test-app", nil)
+	df := newDockerClientFactory(DockerDaemonTypeLocal, nil, "test-app", nil, 0, BuildResources{}, nil, "")
 
 	dfStrategy := dockerfileBuilder{}
 	testStreams, _, _, _ := iostreams.Test()
@@ -42,3 +42,13 @@ func TestBuildDockerfileApp(t *testing.T) {
 
 	assert.Equal(t, "test-dockerfile-app", img.Tag)
 }
+
+func TestRetryablePushError(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	assert.True(t, retryablePushError(ctx, fmt.Errorf("connection reset by peer")))
+	assert.False(t, retryablePushError(ctx, fmt.Errorf("error pushing: %w", &RegistryUnauthorizedError{Tag: "registry.fly.io/app"})))
+
+	cancel()
+	assert.False(t, retryablePushError(ctx, fmt.Errorf("connection reset by peer")))
+}
@@ -1,6 +1,10 @@
 package imgsrc
 
-import "fmt"
+import (
+	"fmt"
+
+	"github.com/dustin/go-humanize"
+)
 
 type RegistryUnauthorizedError struct {
 	Tag string
@@ -9,3 +13,30 @@ type RegistryUnauthorizedError struct {
 func (err *RegistryUnauthorizedError) Error() string {
 	return fmt.Sprintf("you are not authorized to push \"%s\"", err.Tag)
 }
+
+type VulnerabilitiesFoundError struct {
+	Count    int
+	Severity string
+	Summary  string
+}
+
+func (err *VulnerabilitiesFoundError) Error() string {
+	return fmt.Sprintf("found %d vulnerabilities of severity %s or worse (%s)", err.Count, err.Severity, err.Summary)
+}
+
+type BuildOOMError struct {
+	// MemoryBytes is the --build-memory limit, zero when none was set
+	MemoryBytes int64
+	Err         error
+}
+
+func (err *BuildOOMError) Error() string {
+	if err.MemoryBytes > 0 {
+		return fmt.Sprintf("the build ran out of memory with a %s limit, raise it with --build-memory: %v", humanize.IBytes(uint64(err.MemoryBytes)), err.Err)
+	}
+	return fmt.Sprintf("the build ran out of memory, give the builder more with --build-memory: %v", err.Err)
+}
+
+func (err *BuildOOMError) Unwrap() error {
+	return err.Err
+}
@@ -0,0 +1,96 @@
+package imgsrc
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// GitSource is a repository and ref to build from, given as URL#REF
+type GitSource struct {
+	URL string
+	// Ref is a branch, tag or commit, empty for the repository's default branch
+	Ref string
+}
+
+// ParseGitSource parses a source like https://github.com/org/repo#v1.2.3
+func ParseGitSource(s string) (GitSource, error) {
+	src := GitSource{URL: s}
+	if i := strings.LastIndex(s, "#"); i >= 0 {
+		src.URL, src.Ref = s[:i], s[i+1:]
+	}
+
+	if src.URL == "" {
+		return GitSource{}, fmt.Errorf("invalid git source \"%s\", expected a repository URL like https://github.com/org/repo#v1.2.3", s)
+	}
+	// both are passed to git as arguments, which mustn't be mistaken for options
+	if strings.HasPrefix(src.URL, "-") || strings.HasPrefix(src.Ref, "-") {
+		return GitSource{}, fmt.Errorf("invalid git source \"%s\"", s)
+	}
+
+	return src, nil
+}
+
+func (s GitSource) String() string {
+	if s.Ref == "" {
+		return s.URL
+	}
+	return s.URL + "#" + s.Ref
+}
+
+// Clone fetches only the ref's commit into a new temporary directory, returning the directory and
+// the commit's hash. Fetching by ref rather than cloning a branch lets the ref be a tag or a commit.
+// The caller removes the directory.
+func (s GitSource) Clone(ctx context.Context, progress io.Writer) (dir string, commit string, err error) {
+	if _, err := exec.LookPath("git"); err != nil {
+		return "", "", errors.New("building from a git repository needs git installed")
+	}
+
+	dir, err = ioutil.TempDir("", "flyctl-git")
+	if err != nil {
+		return "", "", err
+	}
+	defer func() {
+		if err != nil {
+			os.RemoveAll(dir)
+		}
+	}()
+
+	ref := s.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	git := func(args ...string) error {
+		cmd := exec.CommandContext(ctx, "git", append([]string{"-C", dir}, args...)...)
+		cmd.Stdout = progress
+		cmd.Stderr = progress
+		return cmd.Run()
+	}
+
+	if err := git("init", "-q"); err != nil {
+		return "", "", errors.Wrap(err, "error creating git repository")
+	}
+	if err := git("remote", "add", "origin", s.URL); err != nil {
+		return "", "", errors.Wrap(err, "error adding git remote")
+	}
+	if err := git("fetch", "--depth", "1", "origin", ref); err != nil {
+		return "", "", errors.Wrapf(err, "error fetching %s", s)
+	}
+	if err := git("checkout", "-q", "FETCH_HEAD"); err != nil {
+		return "", "", errors.Wrapf(err, "error checking out %s", s)
+	}
+
+	out, err := exec.CommandContext(ctx, "git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", "", errors.Wrap(err, "error reading the checked out commit")
+	}
+
+	return dir, strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,74 @@
+package imgsrc
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseGitSource(t *testing.T) {
+	src, err := ParseGitSource("https://github.com/org/repo#v1.2.3")
+	assert.NoError(t, err)
+	assert.Equal(t, GitSource{URL: "https://github.com/org/repo", Ref: "v1.2.3"}, src)
+	assert.Equal(t, "https://github.com/org/repo#v1.2.3", src.String())
+
+	src, err = ParseGitSource("git@github.com:org/repo.git")
+	assert.NoError(t, err)
+	assert.Equal(t, GitSource{URL: "git@github.com:org/repo.git"}, src)
+
+	for _, s := range []string{"", "#main", "--upload-pack=touch /tmp/x", "https://github.com/org/repo#--help"} {
+		_, err := ParseGitSource(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestGitSourceClone(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git isn't installed")
+	}
+
+	repo, err := ioutil.TempDir("", "flyctl-git-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(repo)
+
+	git := func(args ...string) {
+		cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %s", args, out)
+		}
+	}
+	write := func(content string) {
+		if err := ioutil.WriteFile(filepath.Join(repo, "Dockerfile"), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	git("init", "-q")
+	write("FROM alpine:3.13\n")
+	git("add", ".")
+	git("commit", "-q", "-m", "first")
+	git("tag", "v1")
+	write("FROM alpine:3.14\n")
+	git("commit", "-q", "-am", "second")
+
+	dir, commit, err := GitSource{URL: repo, Ref: "v1"}.Clone(context.Background(), ioutil.Discard)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "Dockerfile"))
+	assert.NoError(t, err)
+	assert.Equal(t, "FROM alpine:3.13\n", string(data))
+	assert.Len(t, commit, 40)
+
+	_, _, err = GitSource{URL: repo, Ref: "missing"}.Clone(context.Background(), ioutil.Discard)
+	assert.Error(t, err)
+}
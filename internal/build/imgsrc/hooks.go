@@ -0,0 +1,50 @@
+package imgsrc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/pkg/errors"
+	"github.com/superfly/flyctl/internal/shellcmd"
+	"github.com/superfly/flyctl/pkg/iostreams"
+)
+
+const (
+	preBuildHook  = "pre"
+	postBuildHook = "post"
+)
+
+// runBuildHook runs a [build.hooks] command in the working directory. The image reference is
+// exported as IMAGE, along with IMAGE_ID and IMAGE_DIGEST once the image is built.
+func runBuildHook(ctx context.Context, streams *iostreams.IOStreams, stage, command string, opts ImageOptions, img *DeploymentImage) error {
+	if command == "" {
+		return nil
+	}
+
+	fmt.Fprintf(streams.ErrOut, "Running %s-build hook: %s\n", stage, command)
+
+	err := shellcmd.Run(ctx, command, shellcmd.Options{
+		Dir:    opts.WorkingDir,
+		Env:    buildHookEnv(opts, img),
+		Stdout: streams.Out,
+		Stderr: streams.ErrOut,
+	})
+	if err != nil {
+		return errors.Wrapf(err, "%s-build hook \"%s\" failed", stage, command)
+	}
+	return nil
+}
+
+func buildHookEnv(opts ImageOptions, img *DeploymentImage) []string {
+	if img == nil {
+		return []string{"FLY_APP=" + opts.AppName, "IMAGE=" + opts.Tag}
+	}
+	return []string{"FLY_APP=" + opts.AppName, "IMAGE=" + img.Tag, "IMAGE_ID=" + img.ID, "IMAGE_DIGEST=" + img.Digest}
+}
+
+func buildHooks(opts ImageOptions) (pre, post string) {
+	if opts.AppConfig == nil || opts.AppConfig.Build == nil {
+		return "", ""
+	}
+	return opts.AppConfig.Build.Hooks.Pre, opts.AppConfig.Build.Hooks.Post
+}
@@ -0,0 +1,45 @@
+package imgsrc
+
+import (
+	"context"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/superfly/flyctl/flyctl"
+	"github.com/superfly/flyctl/pkg/iostreams"
+)
+
+func TestRunBuildHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("hook commands use sh")
+	}
+
+	streams, _, out, _ := iostreams.Test()
+	opts := ImageOptions{AppName: "test-app", Tag: "registry.fly.io/test-app:deployment-1", WorkingDir: t.TempDir()}
+
+	err := runBuildHook(context.Background(), streams, preBuildHook, "echo pre $IMAGE", opts, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "pre registry.fly.io/test-app:deployment-1\n", out.String())
+
+	out.Reset()
+	img := &DeploymentImage{Tag: opts.Tag, Digest: "sha256:abc"}
+	err = runBuildHook(context.Background(), streams, postBuildHook, "echo $FLY_APP $IMAGE_DIGEST", opts, img)
+	assert.NoError(t, err)
+	assert.Equal(t, "test-app sha256:abc\n", out.String())
+
+	err = runBuildHook(context.Background(), streams, postBuildHook, "exit 3", opts, img)
+	assert.EqualError(t, err, "post-build hook \"exit 3\" failed: exit status 3")
+}
+
+func TestBuildHooks(t *testing.T) {
+	pre, post := buildHooks(ImageOptions{})
+	assert.Empty(t, pre)
+	assert.Empty(t, post)
+
+	cfg := flyctl.NewAppConfig()
+	cfg.Build = &flyctl.Build{Hooks: flyctl.BuildHooks{Pre: "make assets"}}
+	pre, post = buildHooks(ImageOptions{AppConfig: cfg})
+	assert.Equal(t, "make assets", pre)
+	assert.Empty(t, post)
+}
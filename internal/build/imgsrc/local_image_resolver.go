@@ -10,7 +10,6 @@ import (
 	dockerclient "github.com/docker/docker/client"
 	dockerparser "github.com/novln/docker-parser"
 	"github.com/pkg/errors"
-	"github.com/superfly/flyctl/internal/cmdfmt"
 	"github.com/superfly/flyctl/pkg/iostreams"
 	"github.com/superfly/flyctl/terminal"
 )
@@ -67,6 +66,12 @@ func (s *localImageResolver) Run(ctx context.Context, dockerFactory *dockerClien
 
 	fmt.Fprintf(streams.ErrOut, "image found: %s\n", img.ID)
 
+	want := refDigest(ref)
+	if want != "" && !hasDigest(img, want) {
+		return nil, fmt.Errorf("local image %s doesn't have the digest %s it's pinned to", img.ID, want)
+	}
+
+	var digest string
 	if opts.Publish {
 		err = docker.ImageTag(ctx, img.ID, opts.Tag)
 		if err != nil {
@@ -75,24 +80,52 @@ func (s *localImageResolver) Run(ctx context.Context, dockerFactory *dockerClien
 
 		defer clearDeploymentTags(ctx, docker, opts.Tag)
 
-		cmdfmt.PrintBegin(streams.ErrOut, "Pushing image to fly")
+		reporter := newBuildReporter(streams, opts.BuildOutput, opts.Events)
+		reporter.Begin("push", "Pushing image to fly")
 
-		if err := pushToFly(ctx, docker, streams, opts.Tag); err != nil {
+		digest, err = pushToFly(ctx, docker, reporter, opts.Tag, pushOptions(dockerFactory, opts.PushConcurrency, opts.CompressionLevel))
+		if err != nil {
+			reporter.Fail("push", err)
 			return nil, err
 		}
 
-		cmdfmt.PrintDone(streams.ErrOut, "Pushing image done")
+		reporter.Done("push", "Pushing image done")
+		reporter.Image(opts.Tag, digest, img.Size)
 	}
 
 	di := &DeploymentImage{
-		ID:   img.ID,
-		Tag:  opts.Tag,
-		Size: img.Size,
+		ID:           img.ID,
+		Tag:          opts.Tag,
+		Digest:       digest,
+		SourceDigest: want,
+		Size:         img.Size,
 	}
 
 	return di, nil
 }
 
+// refDigest returns the digest an image reference is pinned to, like the sha256:... of
+// registry.fly.io/app@sha256:..., or an empty string
+func refDigest(ref string) string {
+	if i := strings.LastIndex(ref, "@"); i >= 0 {
+		return ref[i+1:]
+	}
+	return ""
+}
+
+// hasDigest reports whether a local image was pulled or pushed with digest, or has it as its ID
+func hasDigest(img *types.ImageSummary, digest string) bool {
+	if img.ID == digest {
+		return true
+	}
+	for _, d := range img.RepoDigests {
+		if strings.HasSuffix(d, "@"+digest) {
+			return true
+		}
+	}
+	return false
+}
+
 var imageIDPattern = regexp.MustCompile("[a-f0-9]")
 
 func findImageWithDocker(d *dockerclient.Client, ctx context.Context, imageName string) (*types.ImageSummary, error) {
@@ -108,6 +141,17 @@ func findImageWithDocker(d *dockerclient.Client, ctx context.Context, imageName
 		return nil, err
 	}
 
+	// a pinned image is found by the digest it was pulled with, since its tags can point
+	// anywhere
+	if digest := refDigest(imageName); digest != "" {
+		for _, img := range images {
+			if hasDigest(&img, digest) {
+				return &img, nil
+			}
+		}
+		return nil, nil
+	}
+
 	if isID {
 		for _, img := range images {
 			if len(img.ID) < len(imageName)+7 {
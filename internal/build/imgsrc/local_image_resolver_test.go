@@ -0,0 +1,24 @@
+package imgsrc
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefDigest(t *testing.T) {
+	assert.Equal(t, "sha256:abc", refDigest("registry.fly.io/test-app@sha256:abc"))
+	assert.Equal(t, "", refDigest("registry.fly.io/test-app:deployment-1"))
+}
+
+func TestHasDigest(t *testing.T) {
+	img := &types.ImageSummary{
+		ID:          "sha256:config",
+		RepoDigests: []string{"registry.fly.io/test-app@sha256:manifest"},
+	}
+
+	assert.True(t, hasDigest(img, "sha256:manifest"))
+	assert.True(t, hasDigest(img, "sha256:config"))
+	assert.False(t, hasDigest(img, "sha256:other"))
+}
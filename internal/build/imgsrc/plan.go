@@ -0,0 +1,103 @@
+package imgsrc
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Image sources a BuildPlan can use
+const (
+	PlanSourceImage      = "image"
+	PlanSourceBuildpacks = "buildpacks"
+	PlanSourceDockerfile = "dockerfile"
+	PlanSourceBuiltin    = "builtin"
+	// PlanSourceRelease reuses the image of the app's current release
+	PlanSourceRelease = "release"
+)
+
+// BuildPlan describes how a deploy would get its image, worked out without building anything
+type BuildPlan struct {
+	// Source is one of the PlanSource constants
+	Source string
+	// Daemon is where the image is built or looked up: local, remote or none
+	Daemon string
+	// RemoteBuilder is the named remote builder, empty for the organization's default one
+	RemoteBuilder string `json:",omitempty"`
+	Dockerfile    string `json:",omitempty"`
+	Target        string `json:",omitempty"`
+	// Builder is the buildpacks builder image
+	Builder    string   `json:",omitempty"`
+	Buildpacks []string `json:",omitempty"`
+	Builtin    string   `json:",omitempty"`
+	// Image is the reference deployed as it is, for the image source
+	Image string `json:",omitempty"`
+	// Tag is the reference the built image would be pushed to
+	Tag string
+}
+
+func daemonName(mode DockerDaemonType) string {
+	switch {
+	case mode.IsLocal():
+		return "local"
+	case mode.IsRemote():
+		return "remote"
+	default:
+		return "none"
+	}
+}
+
+// PlanBuild works out which of BuildImage's strategies would build the image, in the same order,
+// and which daemon it would use. A remote builder isn't started for this.
+func (r *Resolver) PlanBuild(opts ImageOptions) (*BuildPlan, error) {
+	plan := &BuildPlan{
+		Daemon: daemonName(r.dockerFactory.mode),
+		Tag:    opts.Tag,
+	}
+	if plan.Tag == "" {
+		plan.Tag = newDeploymentTag(opts.AppName, opts.ImageLabel)
+	}
+	if r.dockerFactory.mode.IsRemote() {
+		plan.RemoteBuilder = r.builder
+	}
+
+	switch {
+	case opts.AppConfig.HasBuilder():
+		plan.Source = PlanSourceBuildpacks
+		plan.Builder = opts.AppConfig.Build.Builder
+		plan.Buildpacks = opts.AppConfig.Build.Buildpacks
+	case opts.DockerfilePath != "":
+		if _, err := os.Stat(opts.DockerfilePath); err != nil {
+			return nil, fmt.Errorf("Dockerfile '%s' not found", opts.DockerfilePath)
+		}
+		plan.Source = PlanSourceDockerfile
+		plan.Dockerfile = opts.DockerfilePath
+		plan.Target = buildTarget(opts)
+	case resolveDockerfile(opts.WorkingDir, opts.ProcessGroup) != "":
+		plan.Source = PlanSourceDockerfile
+		plan.Dockerfile = resolveDockerfile(opts.WorkingDir, opts.ProcessGroup)
+		plan.Target = buildTarget(opts)
+	case opts.AppConfig.HasBuiltin():
+		plan.Source = PlanSourceBuiltin
+		plan.Builtin = opts.AppConfig.Build.Builtin
+	default:
+		return nil, errors.New("app does not have a Dockerfile or buildpacks configured. See https://fly.io/docs/reference/configuration/#the-build-section")
+	}
+
+	return plan, nil
+}
+
+// PlanReference describes deploying an existing image. Whether it's found in the local daemon or
+// a registry is only known once it's looked up.
+func (r *Resolver) PlanReference(opts RefOptions) *BuildPlan {
+	plan := &BuildPlan{
+		Source: PlanSourceImage,
+		Daemon: daemonName(r.dockerFactory.mode),
+		Image:  opts.ImageRef,
+		Tag:    opts.Tag,
+	}
+	if plan.Tag == "" {
+		plan.Tag = newDeploymentTag(opts.AppName, opts.ImageLabel)
+	}
+	return plan
+}
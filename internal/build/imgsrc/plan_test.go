@@ -0,0 +1,51 @@
+package imgsrc
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/superfly/flyctl/flyctl"
+)
+
+func TestPlanBuild(t *testing.T) {
+	dir, err := ioutil.TempDir("", "flyctl-plan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	r := &Resolver{dockerFactory: &dockerClientFactory{mode: DockerDaemonTypeRemote}, builder: "eu"}
+	opts := ImageOptions{AppName: "test-app", WorkingDir: dir, AppConfig: flyctl.NewAppConfig(), Tag: "registry.fly.io/test-app:deployment-1"}
+
+	_, err = r.PlanBuild(opts)
+	assert.Error(t, err, "nothing to build")
+
+	dockerfile := filepath.Join(dir, "Dockerfile")
+	if err := ioutil.WriteFile(dockerfile, []byte("FROM alpine\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	plan, err := r.PlanBuild(opts)
+	assert.NoError(t, err)
+	assert.Equal(t, &BuildPlan{
+		Source:        PlanSourceDockerfile,
+		Daemon:        "remote",
+		RemoteBuilder: "eu",
+		Dockerfile:    dockerfile,
+		Tag:           "registry.fly.io/test-app:deployment-1",
+	}, plan)
+
+	opts.AppConfig.Build = &flyctl.Build{Builder: "paketobuildpacks/builder:base", Buildpacks: []string{"gcr.io/paketo-buildpacks/go"}}
+	plan, err = r.PlanBuild(opts)
+	assert.NoError(t, err)
+	assert.Equal(t, PlanSourceBuildpacks, plan.Source)
+	assert.Equal(t, "paketobuildpacks/builder:base", plan.Builder)
+
+	opts.DockerfilePath = filepath.Join(dir, "Dockerfile.missing")
+	opts.AppConfig.Build = nil
+	_, err = r.PlanBuild(opts)
+	assert.Error(t, err)
+}
@@ -0,0 +1,55 @@
+package imgsrc
+
+import (
+	"context"
+	"io"
+	"sync"
+
+	"github.com/docker/cli/cli/config"
+	"github.com/docker/cli/cli/config/configfile"
+	"github.com/moby/buildkit/session"
+	"github.com/moby/buildkit/session/auth"
+	"google.golang.org/grpc"
+)
+
+// dockerHubServer is the name docker login and credential helpers save docker hub credentials under
+const dockerHubServer = "https://index.docker.io/v1/"
+
+// registryAuthProvider answers buildkit's requests for registry credentials over the build
+// session from the docker config and its credential helpers. Only Credentials is implemented,
+// so the builder fetches registry tokens itself.
+type registryAuthProvider struct {
+	auth.UnimplementedAuthServer
+
+	config *configfile.ConfigFile
+
+	// credential helpers like osxkeychain misbehave when called concurrently
+	mu sync.Mutex
+}
+
+func newRegistryAuthProvider(stderr io.Writer) session.Attachable {
+	return &registryAuthProvider{config: config.LoadDefaultConfigFile(stderr)}
+}
+
+func (p *registryAuthProvider) Register(server *grpc.Server) {
+	auth.RegisterAuthServer(server, p)
+}
+
+func (p *registryAuthProvider) Credentials(ctx context.Context, req *auth.CredentialsRequest) (*auth.CredentialsResponse, error) {
+	host := req.Host
+	if host == "registry-1.docker.io" {
+		host = dockerHubServer
+	}
+
+	p.mu.Lock()
+	ac, err := p.config.GetAuthConfig(host)
+	p.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	if ac.IdentityToken != "" {
+		return &auth.CredentialsResponse{Secret: ac.IdentityToken}, nil
+	}
+	return &auth.CredentialsResponse{Username: ac.Username, Secret: ac.Password}, nil
+}
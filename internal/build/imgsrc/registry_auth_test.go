@@ -0,0 +1,33 @@
+package imgsrc
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/cli/cli/config/configfile"
+	"github.com/docker/cli/cli/config/types"
+	"github.com/moby/buildkit/session/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryAuthProviderCredentials(t *testing.T) {
+	cfg := configfile.New(filepath.Join(t.TempDir(), "config.json"))
+	cfg.AuthConfigs = map[string]types.AuthConfig{
+		dockerHubServer: {Username: "hubuser", Password: "hubpass"},
+		"ghcr.io":       {Username: "ignored", IdentityToken: "refresh-token"},
+	}
+	p := &registryAuthProvider{config: cfg}
+
+	res, err := p.Credentials(context.Background(), &auth.CredentialsRequest{Host: "registry-1.docker.io"})
+	assert.NoError(t, err)
+	assert.Equal(t, &auth.CredentialsResponse{Username: "hubuser", Secret: "hubpass"}, res)
+
+	res, err = p.Credentials(context.Background(), &auth.CredentialsRequest{Host: "ghcr.io"})
+	assert.NoError(t, err)
+	assert.Equal(t, &auth.CredentialsResponse{Secret: "refresh-token"}, res)
+
+	res, err = p.Credentials(context.Background(), &auth.CredentialsRequest{Host: "quay.io"})
+	assert.NoError(t, err)
+	assert.Equal(t, &auth.CredentialsResponse{}, res)
+}
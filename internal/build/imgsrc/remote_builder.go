@@ -0,0 +1,133 @@
+package imgsrc
+
+import (
+	"context"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	dockerclient "github.com/docker/docker/client"
+	"github.com/pkg/errors"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/pkg/iostreams"
+)
+
+// RemoteBuilder gives direct access to the remote builder used to build images for an app's organization
+type RemoteBuilder struct {
+	apiClient *api.Client
+	appName   string
+	// name is the builder's name in the organization, empty for its default builder
+	name    string
+	streams *iostreams.IOStreams
+
+	factory *dockerClientFactory
+}
+
+// NewRemoteBuilder returns the builder called name, or the organization's default builder when name is empty
+func NewRemoteBuilder(apiClient *api.Client, appName string, name string, streams *iostreams.IOStreams, network PrivateNetwork) *RemoteBuilder {
+	return &RemoteBuilder{
+		apiClient: apiClient,
+		appName:   appName,
+		name:      name,
+		streams:   streams,
+		factory:   newDockerClientFactory(DockerDaemonTypeRemote, apiClient, appName, streams, DefaultBuilderTimeout, BuildResources{}, network, name),
+	}
+}
+
+// AppName returns the name of the builder app, provisioning a builder if the organization doesn't have one yet
+func (b *RemoteBuilder) AppName() (string, error) {
+	_, app, err := b.apiClient.EnsureRemoteBuilder(api.EnsureRemoteBuilderInput{AppName: b.appName, Name: b.name})
+	if err != nil {
+		return "", errors.Wrap(err, "could not find remote builder")
+	}
+	if app == nil {
+		return "", errors.New("remote builder app unavailable")
+	}
+	return app.Name, nil
+}
+
+// ErrNoBuilder is returned by ExistingAppName when the organization doesn't have the builder
+var ErrNoBuilder = errors.New("no remote builder")
+
+// ExistingAppName returns the name of the builder app without provisioning a builder, or
+// ErrNoBuilder when the organization doesn't have one yet
+func (b *RemoteBuilder) ExistingAppName() (string, error) {
+	app, err := b.apiClient.GetApp(b.appName)
+	if err != nil {
+		return "", err
+	}
+
+	builders, err := b.apiClient.ListRemoteBuilders(app.Organization.Slug)
+	if err != nil {
+		return "", errors.Wrap(err, "could not list remote builders")
+	}
+
+	for _, builder := range builders {
+		if builder.App == nil {
+			continue
+		}
+		if (b.name == "" && builder.Default) || (b.name != "" && builder.Name == b.name) {
+			return builder.App.Name, nil
+		}
+	}
+	return "", ErrNoBuilder
+}
+
+// Docker connects to the builder's docker daemon, starting the builder and waiting for it to become ready
+func (b *RemoteBuilder) Docker(ctx context.Context) (*dockerclient.Client, error) {
+	return b.factory.buildFn(ctx)
+}
+
+// DiskUsage reports the space used by images, containers, volumes and build cache on the builder
+func (b *RemoteBuilder) DiskUsage(ctx context.Context) (types.DiskUsage, error) {
+	docker, err := b.Docker(ctx)
+	if err != nil {
+		return types.DiskUsage{}, err
+	}
+	return docker.DiskUsage(ctx)
+}
+
+// BuildCache lists the dangling images and build cache records taking up space on the builder
+func (b *RemoteBuilder) BuildCache(ctx context.Context) ([]types.ImageSummary, []*types.BuildCache, error) {
+	docker, err := b.Docker(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	images, err := docker.ImageList(ctx, types.ImageListOptions{Filters: filters.NewArgs(filters.Arg("dangling", "true"))})
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error listing images")
+	}
+
+	usage, err := docker.DiskUsage(ctx)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "error reading build cache")
+	}
+
+	return images, usage.BuildCache, nil
+}
+
+// PruneBuildCache removes dangling images and unused build cache from the builder. When all is set,
+// every image and cache record not used by a running build is removed, not just dangling ones.
+func (b *RemoteBuilder) PruneBuildCache(ctx context.Context, all bool) (uint64, error) {
+	docker, err := b.Docker(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	imageFilters := filters.NewArgs(filters.Arg("dangling", "true"))
+	if all {
+		imageFilters = filters.NewArgs(filters.Arg("dangling", "false"))
+	}
+
+	imagesReport, err := docker.ImagesPrune(ctx, imageFilters)
+	if err != nil {
+		return 0, errors.Wrap(err, "error pruning images")
+	}
+
+	cacheReport, err := docker.BuildCachePrune(ctx, types.BuildCachePruneOptions{All: all})
+	if err != nil {
+		return imagesReport.SpaceReclaimed, errors.Wrap(err, "error pruning build cache")
+	}
+
+	return imagesReport.SpaceReclaimed + cacheReport.SpaceReclaimed, nil
+}
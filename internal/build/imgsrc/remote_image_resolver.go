@@ -36,10 +36,16 @@ func (s *remoteImageResolver) Run(ctx context.Context, dockerFactory *dockerClie
 
 	fmt.Fprintf(streams.ErrOut, "image found: %s\n", img.ID)
 
+	if want := refDigest(ref); want != "" && img.Digest != want {
+		return nil, fmt.Errorf("image %s has digest %s, not the %s it's pinned to", ref, img.Digest, want)
+	}
+
 	di := &DeploymentImage{
-		ID:   img.ID,
-		Tag:  img.Ref,
-		Size: int64(img.CompressedSize),
+		ID:           img.ID,
+		Tag:          img.Ref,
+		Digest:       img.Digest,
+		SourceDigest: img.Digest,
+		Size:         int64(img.CompressedSize),
 	}
 
 	return di, nil
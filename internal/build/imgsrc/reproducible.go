@@ -0,0 +1,145 @@
+package imgsrc
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/superfly/flyctl/terminal"
+	fstypes "github.com/tonistiigi/fsutil/types"
+)
+
+const sourceDateEpochArg = "SOURCE_DATE_EPOCH"
+
+// sourceDateEpoch is the timestamp reproducible builds clamp file times to. It's read from
+// SOURCE_DATE_EPOCH when set, otherwise it's the time of the last git commit in dir, so two
+// builds of the same commit use the same time.
+func sourceDateEpoch(dir string) time.Time {
+	if v := os.Getenv(sourceDateEpochArg); v != "" {
+		if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return time.Unix(secs, 0).UTC()
+		}
+		terminal.Warnf("Ignoring invalid %s \"%s\", expected seconds since the unix epoch\n", sourceDateEpochArg, v)
+	}
+
+	out, err := exec.Command("git", "-C", dir, "log", "-1", "--format=%ct").Output()
+	if err == nil {
+		if secs, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64); err == nil {
+			return time.Unix(secs, 0).UTC()
+		}
+	}
+
+	terminal.Debug("no git commit found for reproducible build, using the unix epoch")
+	return time.Unix(0, 0).UTC()
+}
+
+// reproducibleEpoch returns the time to clamp the build context to, or nil when the build isn't reproducible
+func reproducibleEpoch(opts ImageOptions) *time.Time {
+	if !opts.Reproducible {
+		return nil
+	}
+	epoch := sourceDateEpoch(opts.WorkingDir)
+	return &epoch
+}
+
+// addSourceDateEpochArg passes the epoch to the Dockerfile, where builders and tools that
+// support SOURCE_DATE_EPOCH use it for their own timestamps
+func addSourceDateEpochArg(buildArgs map[string]*string, epoch *time.Time) {
+	if epoch == nil {
+		return
+	}
+	if _, ok := buildArgs[sourceDateEpochArg]; ok {
+		return
+	}
+	v := strconv.FormatInt(epoch.Unix(), 10)
+	buildArgs[sourceDateEpochArg] = &v
+}
+
+// normalizeTar rewrites a tar stream so it only depends on file names, modes and contents.
+// Modification times after epoch are clamped to it, and access and change times and ownership
+// are dropped. Entries are already in a fixed order since the context is walked in lexical order.
+func normalizeTar(r io.ReadCloser, epoch time.Time, compressed bool, compressionLevel int) io.ReadCloser {
+	pr, pw := io.Pipe()
+
+	go func() {
+		defer r.Close()
+
+		var out io.Writer = pw
+		var gz *gzip.Writer
+		if compressed {
+			// the gzip header has no timestamp unless one is set
+			var err error
+			if gz, err = gzip.NewWriterLevel(pw, gzipLevel(compressionLevel)); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			out = gz
+		}
+
+		tr := tar.NewReader(r)
+		tw := tar.NewWriter(out)
+
+		err := func() error {
+			for {
+				hdr, err := tr.Next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					return err
+				}
+
+				normalizeHeader(hdr, epoch)
+
+				if err := tw.WriteHeader(hdr); err != nil {
+					return err
+				}
+				if _, err := io.Copy(tw, tr); err != nil {
+					return err
+				}
+			}
+
+			if err := tw.Close(); err != nil {
+				return err
+			}
+			if gz != nil {
+				return gz.Close()
+			}
+			return nil
+		}()
+
+		pw.CloseWithError(err)
+	}()
+
+	return pr
+}
+
+func normalizeHeader(hdr *tar.Header, epoch time.Time) {
+	if hdr.ModTime.After(epoch) {
+		hdr.ModTime = epoch
+	}
+	hdr.ModTime = hdr.ModTime.Truncate(time.Second)
+	hdr.AccessTime = time.Time{}
+	hdr.ChangeTime = time.Time{}
+	hdr.Uid, hdr.Gid = 0, 0
+	hdr.Uname, hdr.Gname = "", ""
+
+	for _, k := range []string{"mtime", "atime", "ctime"} {
+		delete(hdr.PAXRecords, k)
+	}
+}
+
+// clampModTimes is the session sync equivalent of normalizeTar
+func clampModTimes(epoch time.Time) func(string, *fstypes.Stat) bool {
+	return func(path string, s *fstypes.Stat) bool {
+		if s.ModTime > epoch.UnixNano() {
+			s.ModTime = epoch.UnixNano()
+		}
+		return resetUIDAndGID(path, s)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/superfly/flyctl/api"
 	"github.com/superfly/flyctl/flyctl"
@@ -21,27 +22,67 @@ type ImageOptions struct {
 	ImageLabel     string
 	Publish        bool
 	Tag            string
+	BuildOutput    string
+	// Events receives the build progress as "build" events with a BuildEvent each, instead of
+	// it being printed, when set
+	Events EventFunc
+	// Timeout limits the total build time, no limit when zero
+	Timeout time.Duration
+	// ScanSeverity enables scanning the built image for vulnerabilities, failing the build when any
+	// at this severity or worse are found
+	ScanSeverity string
+	// ScanImage is the trivy image to scan with, like aquasec/trivy:0.55.2@sha256:..., the
+	// default when empty
+	ScanImage string
+	// Reproducible normalizes the build context and sets SOURCE_DATE_EPOCH so builds of the
+	// same commit produce the same image
+	Reproducible bool
+	// SSH forwards ssh agents or keys to RUN --mount=type=ssh steps, given like docker build --ssh
+	SSH []string
+	// Resources limits the memory and cpu a local build may use
+	Resources BuildResources
+	// ProcessGroup selects the group's Dockerfile.<group> and [build.targets] entry, empty for
+	// the image shared by all groups
+	ProcessGroup string
+	// PushConcurrency is how many layers are pushed at once from a local daemon, the default when zero
+	PushConcurrency int
+	// CompressionLevel is the gzip level, 1 to 9, for the build context and the layers flyctl pushes.
+	// Zero uses gzip's default.
+	CompressionLevel int
 }
 
 type RefOptions struct {
-	AppName    string
-	WorkingDir string
-	ImageRef   string
-	AppConfig  *flyctl.AppConfig
-	ImageLabel string
-	Publish    bool
-	Tag        string
+	AppName     string
+	WorkingDir  string
+	ImageRef    string
+	AppConfig   *flyctl.AppConfig
+	ImageLabel  string
+	Publish     bool
+	Tag         string
+	BuildOutput string
+	// Events receives the push progress as "build" events, like ImageOptions.Events
+	Events EventFunc
+	// PushConcurrency is how many layers are pushed at once from a local daemon, the default when zero
+	PushConcurrency int
+	// CompressionLevel is the gzip level, 1 to 9, for the layers flyctl pushes, gzip's default when zero
+	CompressionLevel int
 }
 
 type DeploymentImage struct {
-	ID   string
-	Tag  string
-	Size int64
+	ID     string
+	Tag    string
+	Digest string
+	// SourceDigest is the digest of the image the reference resolved to. It differs from Digest
+	// when a local image is pushed again, and is empty for a local image that isn't pinned.
+	SourceDigest string
+	Size         int64
 }
 
 type Resolver struct {
 	dockerFactory *dockerClientFactory
 	apiClient     *api.Client
+	// builder is the name of the remote builder, empty for the organization's default one
+	builder string
 }
 
 // ResolveReference returns an Image give an reference using either the local docker daemon or remote registry
@@ -76,6 +117,23 @@ func (r *Resolver) BuildImage(ctx context.Context, streams *iostreams.IOStreams,
 		opts.Tag = newDeploymentTag(opts.AppName, opts.ImageLabel)
 	}
 
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+
+		defer func() {
+			if err != nil && ctx.Err() == context.DeadlineExceeded {
+				img, err = nil, fmt.Errorf("build did not finish within %s, set a longer timeout with --build-timeout or timeout in the [build] section of fly.toml", opts.Timeout)
+			}
+		}()
+	}
+
+	preHook, postHook := buildHooks(opts)
+	if err := runBuildHook(ctx, streams, preBuildHook, preHook, opts, nil); err != nil {
+		return nil, err
+	}
+
 	strategies := []imageBuilder{
 		&buildpacksBuilder{},
 		&dockerfileBuilder{},
@@ -90,6 +148,9 @@ func (r *Resolver) BuildImage(ctx context.Context, streams *iostreams.IOStreams,
 			return nil, err
 		}
 		if img != nil {
+			if err := runBuildHook(ctx, streams, postBuildHook, postHook, opts, img); err != nil {
+				return nil, err
+			}
 			return img, nil
 		}
 	}
@@ -97,10 +158,14 @@ func (r *Resolver) BuildImage(ctx context.Context, streams *iostreams.IOStreams,
 	return nil, errors.New("app does not have a Dockerfile or buildpacks configured. See https://fly.io/docs/reference/configuration/#the-build-section")
 }
 
-func NewResolver(daemonType DockerDaemonType, apiClient *api.Client, appName string, iostreams *iostreams.IOStreams) *Resolver {
+// NewResolver creates a resolver. builderTimeout limits how long to wait for a remote builder, or DefaultBuilderTimeout when zero.
+// builder names the organization's remote builder to use, empty for its default one.
+// Remote builders are reached through network.
+func NewResolver(daemonType DockerDaemonType, apiClient *api.Client, appName string, iostreams *iostreams.IOStreams, builderTimeout time.Duration, resources BuildResources, network PrivateNetwork, builder string) *Resolver {
 	return &Resolver{
-		dockerFactory: newDockerClientFactory(daemonType, apiClient, appName, iostreams),
+		dockerFactory: newDockerClientFactory(daemonType, apiClient, appName, iostreams, builderTimeout, resources, network, builder),
 		apiClient:     apiClient,
+		builder:       builder,
 	}
 }
 
@@ -0,0 +1,96 @@
+package imgsrc
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+
+	"github.com/docker/docker/api/types"
+	"github.com/dustin/go-humanize"
+	"github.com/superfly/flyctl/api"
+)
+
+// cpuPeriod is the scheduler period cpu quotas are expressed in, the docker default of 100ms
+const cpuPeriod = 100000
+
+// BuildResources caps the memory and cpu a build may use. Zero values are unlimited.
+type BuildResources struct {
+	MemoryBytes int64
+	CPUs        float64
+}
+
+// ParseBuildResources parses --build-memory values like 4gb or 512MB and --build-cpus values like 2 or 1.5
+func ParseBuildResources(memory, cpus string) (BuildResources, error) {
+	var r BuildResources
+
+	if memory != "" {
+		n, err := humanize.ParseBytes(memory)
+		if err != nil || n == 0 {
+			return r, fmt.Errorf("invalid build memory \"%s\", expected a size like 4gb", memory)
+		}
+		r.MemoryBytes = int64(n)
+	}
+
+	if cpus != "" {
+		n, err := strconv.ParseFloat(cpus, 64)
+		if err != nil || n <= 0 {
+			return r, fmt.Errorf("invalid build cpus \"%s\", expected a number like 2 or 1.5", cpus)
+		}
+		r.CPUs = n
+	}
+
+	return r, nil
+}
+
+// IsZero reports whether no limits are set
+func (r BuildResources) IsZero() bool {
+	return r.MemoryBytes == 0 && r.CPUs == 0
+}
+
+func (r BuildResources) String() string {
+	s := ""
+	if r.MemoryBytes > 0 {
+		s = humanize.IBytes(uint64(r.MemoryBytes)) + " memory"
+	}
+	if r.CPUs > 0 {
+		if s != "" {
+			s += ", "
+		}
+		s += strconv.FormatFloat(r.CPUs, 'f', -1, 64) + " cpus"
+	}
+	return s
+}
+
+// apply sets the limits on a classic build. Swap is capped at the memory limit so an
+// oversized build fails instead of grinding the machine to a halt.
+func (r BuildResources) apply(options *types.ImageBuildOptions) {
+	if r.MemoryBytes > 0 {
+		options.Memory = r.MemoryBytes
+		options.MemorySwap = r.MemoryBytes
+	}
+	if r.CPUs > 0 {
+		options.CPUPeriod = cpuPeriod
+		options.CPUQuota = int64(r.CPUs * cpuPeriod)
+	}
+}
+
+// remoteBuilderInput asks for a remote builder large enough for the limits
+func (r BuildResources) remoteBuilderInput(appName string) api.EnsureRemoteBuilderInput {
+	input := api.EnsureRemoteBuilderInput{AppName: appName}
+	if r.MemoryBytes > 0 {
+		input.MemoryMb = int(math.Ceil(float64(r.MemoryBytes) / (1024 * 1024)))
+	}
+	if r.CPUs > 0 {
+		input.CpuCount = int(math.Ceil(r.CPUs))
+	}
+	return input
+}
+
+// a step killed by the kernel oom killer exits with 128 + SIGKILL. The classic builder reports
+// "returned a non-zero code: 137" and buildkit "exit code: 137".
+var oomExitCode = regexp.MustCompile(`code: 137\b`)
+
+func isOOMKill(err error) bool {
+	return err != nil && oomExitCode.MatchString(err.Error())
+}
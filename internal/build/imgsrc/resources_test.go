@@ -0,0 +1,37 @@
+package imgsrc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseBuildResources(t *testing.T) {
+	r, err := ParseBuildResources("4GiB", "1.5")
+	assert.NoError(t, err)
+	assert.Equal(t, BuildResources{MemoryBytes: 4 << 30, CPUs: 1.5}, r)
+	assert.Equal(t, "4.0 GiB memory, 1.5 cpus", r.String())
+
+	input := r.remoteBuilderInput("test-app")
+	assert.Equal(t, 4096, input.MemoryMb)
+	assert.Equal(t, 2, input.CpuCount)
+
+	r, err = ParseBuildResources("", "")
+	assert.NoError(t, err)
+	assert.True(t, r.IsZero())
+
+	_, err = ParseBuildResources("lots", "")
+	assert.Error(t, err)
+
+	_, err = ParseBuildResources("", "-1")
+	assert.Error(t, err)
+}
+
+func TestIsOOMKill(t *testing.T) {
+	assert.True(t, isOOMKill(errors.New("The command '/bin/sh -c npm run build' returned a non-zero code: 137")))
+	assert.True(t, isOOMKill(errors.New("executor failed running [/bin/sh -c npm run build]: exit code: 137")))
+	assert.False(t, isOOMKill(errors.New("exit code: 1")))
+	assert.False(t, isOOMKill(errors.New("exit code: 1370")))
+	assert.False(t, isOOMKill(nil))
+}
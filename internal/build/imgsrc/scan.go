@@ -0,0 +1,245 @@
+package imgsrc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/mount"
+	dockerclient "github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/pkg/errors"
+	"github.com/superfly/flyctl/helpers"
+	"github.com/superfly/flyctl/terminal"
+)
+
+const (
+	// DefaultScanImage is the trivy image scans run with unless another is configured. It's pinned
+	// so the scanner, and the report format it prints, only change when flyctl is updated.
+	DefaultScanImage    = "aquasec/trivy:0.55.2"
+	trivyCacheVol       = "flyctl-trivy-cache"
+	maxListedVulns      = 20
+	DefaultScanSeverity = "HIGH"
+)
+
+// Severities are the vulnerability severities reported by scans, from least to most severe
+var Severities = []string{"UNKNOWN", "LOW", "MEDIUM", "HIGH", "CRITICAL"}
+
+func severityRank(severity string) int {
+	for i, s := range Severities {
+		if strings.EqualFold(s, severity) {
+			return i
+		}
+	}
+	return 0
+}
+
+// ValidateScanSeverity returns an error if severity can't be used as a scan threshold
+func ValidateScanSeverity(severity string) error {
+	for _, s := range Severities {
+		if strings.EqualFold(s, severity) {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid severity \"%s\", must be one of %s", severity, strings.Join(Severities, ", "))
+}
+
+type Vulnerability struct {
+	ID               string `json:"VulnerabilityID"`
+	Package          string `json:"PkgName"`
+	InstalledVersion string `json:"InstalledVersion"`
+	FixedVersion     string `json:"FixedVersion"`
+	Severity         string `json:"Severity"`
+	Title            string `json:"Title"`
+}
+
+type scanTarget struct {
+	Target          string          `json:"Target"`
+	Vulnerabilities []Vulnerability `json:"Vulnerabilities"`
+}
+
+// ScanResult holds the vulnerabilities found in an image
+type ScanResult struct {
+	Vulnerabilities []Vulnerability
+}
+
+// parseScanReport reads a trivy JSON report. Newer versions wrap the results in an object,
+// older ones print the list of results directly.
+func parseScanReport(data []byte) (*ScanResult, error) {
+	data = bytes.TrimSpace(data)
+
+	var targets []scanTarget
+	if bytes.HasPrefix(data, []byte("[")) {
+		if err := json.Unmarshal(data, &targets); err != nil {
+			return nil, err
+		}
+	} else {
+		var report struct {
+			Results []scanTarget `json:"Results"`
+		}
+		if err := json.Unmarshal(data, &report); err != nil {
+			return nil, err
+		}
+		targets = report.Results
+	}
+
+	result := &ScanResult{}
+	for _, t := range targets {
+		result.Vulnerabilities = append(result.Vulnerabilities, t.Vulnerabilities...)
+	}
+
+	// most severe first
+	sort.SliceStable(result.Vulnerabilities, func(i, j int) bool {
+		return severityRank(result.Vulnerabilities[i].Severity) > severityRank(result.Vulnerabilities[j].Severity)
+	})
+
+	return result, nil
+}
+
+// AtOrAbove returns the vulnerabilities with the given severity or worse
+func (r *ScanResult) AtOrAbove(severity string) []Vulnerability {
+	threshold := severityRank(severity)
+
+	var out []Vulnerability
+	for _, v := range r.Vulnerabilities {
+		if severityRank(v.Severity) >= threshold {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Summary counts vulnerabilities by severity, like "CRITICAL: 1, HIGH: 3"
+func (r *ScanResult) Summary() string {
+	counts := map[string]int{}
+	for _, v := range r.Vulnerabilities {
+		counts[strings.ToUpper(v.Severity)]++
+	}
+
+	var parts []string
+	for i := len(Severities) - 1; i >= 0; i-- {
+		if n := counts[Severities[i]]; n > 0 {
+			parts = append(parts, fmt.Sprintf("%s: %d", Severities[i], n))
+		}
+	}
+	if len(parts) == 0 {
+		return "no vulnerabilities found"
+	}
+	return strings.Join(parts, ", ")
+}
+
+// scanImage runs trivy in a container on the same docker daemon that built the image, and fails
+// when vulnerabilities at or above the threshold severity are found. scanner is the trivy image
+// to run, DefaultScanImage when empty.
+func scanImage(ctx context.Context, docker *dockerclient.Client, reporter *buildReporter, tag string, threshold string, scanner string) error {
+	reporter.Begin("scan", fmt.Sprintf("Scanning image for %s or worse vulnerabilities", strings.ToUpper(threshold)))
+
+	if scanner == "" {
+		scanner = DefaultScanImage
+	}
+	report, err := runTrivy(ctx, docker, scanner, tag)
+	if err != nil {
+		reporter.Fail("scan", err)
+		return errors.Wrap(err, "error scanning image")
+	}
+
+	result, err := parseScanReport(report)
+	if err != nil {
+		reporter.Fail("scan", err)
+		return errors.Wrap(err, "error reading scan report")
+	}
+
+	found := result.AtOrAbove(threshold)
+	if len(found) == 0 {
+		reporter.Done("scan", "Scanning image done, "+result.Summary())
+		return nil
+	}
+
+	out := reporter.Writer("scan")
+	table := helpers.MakeSimpleTable(out, []string{"Severity", "ID", "Package", "Installed", "Fixed In"})
+	for i, v := range found {
+		if i >= maxListedVulns {
+			break
+		}
+		table.Append([]string{v.Severity, v.ID, v.Package, v.InstalledVersion, v.FixedVersion})
+	}
+	table.Render()
+	if len(found) > maxListedVulns {
+		fmt.Fprintf(out, "... and %d more\n", len(found)-maxListedVulns)
+	}
+	out.Close()
+
+	err = &VulnerabilitiesFoundError{Count: len(found), Severity: strings.ToUpper(threshold), Summary: result.Summary()}
+	reporter.Fail("scan", err)
+	return err
+}
+
+func runTrivy(ctx context.Context, docker *dockerclient.Client, scanner string, tag string) ([]byte, error) {
+	pull, err := docker.ImagePull(ctx, scanner, types.ImagePullOptions{})
+	if err != nil {
+		return nil, errors.Wrap(err, "error pulling scanner image")
+	}
+	_, err = io.Copy(io.Discard, pull)
+	pull.Close()
+	if err != nil {
+		return nil, errors.Wrap(err, "error pulling scanner image")
+	}
+
+	created, err := docker.ContainerCreate(ctx,
+		&container.Config{
+			Image: scanner,
+			Cmd:   []string{"image", "--format", "json", "--quiet", "--no-progress", tag},
+		},
+		&container.HostConfig{
+			// scan the image from the daemon's image store instead of pulling it from a registry
+			Binds: []string{"/var/run/docker.sock:/var/run/docker.sock"},
+			// keep the vulnerability database between scans
+			Mounts: []mount.Mount{{Type: mount.TypeVolume, Source: trivyCacheVol, Target: "/root/.cache"}},
+		},
+		nil, nil, "")
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating scanner container")
+	}
+	defer func() {
+		if err := docker.ContainerRemove(context.Background(), created.ID, types.ContainerRemoveOptions{Force: true}); err != nil {
+			terminal.Debug("error removing scanner container:", err)
+		}
+	}()
+
+	waitCh, errCh := docker.ContainerWait(ctx, created.ID, container.WaitConditionNextExit)
+
+	if err := docker.ContainerStart(ctx, created.ID, types.ContainerStartOptions{}); err != nil {
+		return nil, errors.Wrap(err, "error starting scanner container")
+	}
+
+	var exitCode int64
+	select {
+	case status := <-waitCh:
+		exitCode = status.StatusCode
+	case err := <-errCh:
+		return nil, err
+	}
+
+	logs, err := docker.ContainerLogs(ctx, created.ID, types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return nil, errors.Wrap(err, "error reading scanner output")
+	}
+	defer logs.Close()
+
+	var stdout, stderr bytes.Buffer
+	if _, err := stdcopy.StdCopy(&stdout, &stderr, logs); err != nil {
+		return nil, errors.Wrap(err, "error reading scanner output")
+	}
+
+	if exitCode != 0 {
+		return nil, fmt.Errorf("scanner exited with code %d: %s", exitCode, strings.TrimSpace(stderr.String()))
+	}
+
+	return stdout.Bytes(), nil
+}
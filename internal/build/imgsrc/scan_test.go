@@ -0,0 +1,35 @@
+package imgsrc
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseScanReport(t *testing.T) {
+	data, err := os.ReadFile("testdata/trivy-report.json")
+	assert.NoError(t, err)
+
+	result, err := parseScanReport(data)
+	assert.NoError(t, err)
+	assert.Len(t, result.Vulnerabilities, 3)
+	assert.Equal(t, "CVE-2021-33574", result.Vulnerabilities[0].ID)
+	assert.Equal(t, "CRITICAL: 1, HIGH: 1, LOW: 1", result.Summary())
+
+	assert.Len(t, result.AtOrAbove("CRITICAL"), 1)
+	assert.Len(t, result.AtOrAbove("high"), 2)
+	assert.Len(t, result.AtOrAbove("LOW"), 3)
+}
+
+func TestParseLegacyScanReport(t *testing.T) {
+	result, err := parseScanReport([]byte(`[{"Target": "alpine:3.13", "Vulnerabilities": null}]`))
+	assert.NoError(t, err)
+	assert.Empty(t, result.Vulnerabilities)
+	assert.Equal(t, "no vulnerabilities found", result.Summary())
+}
+
+func TestValidateScanSeverity(t *testing.T) {
+	assert.NoError(t, ValidateScanSeverity("medium"))
+	assert.Error(t, ValidateScanSeverity("severe"))
+}
@@ -0,0 +1,59 @@
+package imgsrc
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/moby/buildkit/session"
+	"github.com/moby/buildkit/session/sshforward/sshprovider"
+	"github.com/pkg/errors"
+)
+
+// parseSSHSpecs parses --ssh values in the same form as docker build: "default" forwards the
+// agent at $SSH_AUTH_SOCK, "id=socket-or-key[,key...]" forwards a specific agent or key files
+// under an id that RUN --mount=type=ssh,id=... refers to.
+func parseSSHSpecs(specs []string) ([]sshprovider.AgentConfig, error) {
+	var configs []sshprovider.AgentConfig
+	seen := map[string]bool{}
+
+	for _, spec := range specs {
+		id, paths := spec, ""
+		if i := strings.Index(spec, "="); i >= 0 {
+			id, paths = spec[:i], spec[i+1:]
+		}
+		if id == "" {
+			return nil, fmt.Errorf("invalid ssh spec \"%s\", expected default or id=path", spec)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("ssh id %s is given more than once", id)
+		}
+		seen[id] = true
+
+		cfg := sshprovider.AgentConfig{ID: id}
+		if paths != "" {
+			cfg.Paths = strings.Split(paths, ",")
+		}
+		configs = append(configs, cfg)
+	}
+
+	return configs, nil
+}
+
+// sshAgentProvider forwards ssh agents over the build session, nil when none are requested
+func sshAgentProvider(specs []string) (session.Attachable, error) {
+	if len(specs) == 0 {
+		return nil, nil
+	}
+
+	configs, err := parseSSHSpecs(specs)
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := sshprovider.NewSSHAgentProvider(configs)
+	if err != nil {
+		return nil, errors.Wrap(err, "error setting up ssh forwarding")
+	}
+
+	return provider, nil
+}
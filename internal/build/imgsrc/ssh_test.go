@@ -0,0 +1,23 @@
+package imgsrc
+
+import (
+	"testing"
+
+	"github.com/moby/buildkit/session/sshforward/sshprovider"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSSHSpecs(t *testing.T) {
+	configs, err := parseSSHSpecs([]string{"default", "github=/home/me/.ssh/id_ed25519,/home/me/.ssh/id_rsa"})
+	assert.NoError(t, err)
+	assert.Equal(t, []sshprovider.AgentConfig{
+		{ID: "default"},
+		{ID: "github", Paths: []string{"/home/me/.ssh/id_ed25519", "/home/me/.ssh/id_rsa"}},
+	}, configs)
+
+	_, err = parseSSHSpecs([]string{"default", "default=/tmp/agent.sock"})
+	assert.Error(t, err)
+
+	_, err = parseSSHSpecs([]string{"=/tmp/agent.sock"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,40 @@
+package imgsrc
+
+import (
+	"context"
+	"net"
+
+	"github.com/pkg/errors"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/pkg/wg"
+)
+
+// remoteBuilderPort is where builders serve the Docker API on their private network address
+const remoteBuilderPort = "2375"
+
+// PrivateNetwork connects to an organization's private WireGuard network, the only place remote
+// builders accept Docker API connections
+type PrivateNetwork func(ctx context.Context, org *api.Organization) (*wg.Tunnel, error)
+
+// tunnelDialer dials addresses through the tunnel, resolving .internal names with the private network's DNS
+func tunnelDialer(tunnel *wg.Tunnel) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			return nil, err
+		}
+
+		if net.ParseIP(host) == nil {
+			addrs, err := tunnel.Resolver().LookupHost(ctx, host)
+			if err != nil {
+				return nil, errors.Wrapf(err, "could not resolve %s on the private network", host)
+			}
+			if len(addrs) == 0 {
+				return nil, errors.Errorf("%s has no private network address", host)
+			}
+			host = addrs[0]
+		}
+
+		return tunnel.DialContext(ctx, "tcp", net.JoinHostPort(host, port))
+	}
+}
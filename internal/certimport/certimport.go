@@ -0,0 +1,129 @@
+// Package certimport reads the hostname lists of certs import and keeps its progress, so an
+// interrupted import resumes where it stopped
+package certimport
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+var hostnamePattern = regexp.MustCompile(`^(\*\.)?([a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?\.)+[a-z][a-z0-9-]{0,62}$`)
+
+// ParseHostnames reads one hostname per line, skipping blank lines, # comments and duplicates.
+// Hostnames are lowercased, and an invalid one fails with its line number.
+func ParseHostnames(r io.Reader) ([]string, error) {
+	var hostnames []string
+	seen := map[string]bool{}
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		if i := strings.Index(text, "#"); i >= 0 {
+			text = text[:i]
+		}
+		hostname := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(text)), ".")
+		if hostname == "" || seen[hostname] {
+			continue
+		}
+		if len(hostname) > 253 || !hostnamePattern.MatchString(hostname) {
+			return nil, fmt.Errorf("line %d: %s is not a valid hostname", line, hostname)
+		}
+		seen[hostname] = true
+		hostnames = append(hostnames, hostname)
+	}
+
+	return hostnames, scanner.Err()
+}
+
+// State is the progress of an import, saved after each hostname
+type State struct {
+	// Added are the hostnames with certificates, added by the import or before it
+	Added []string `json:"added"`
+	// Failed maps the hostnames that couldn't be added to the last error
+	Failed map[string]string `json:"failed"`
+
+	added map[string]bool
+}
+
+// LoadState reads the state saved at path, or returns an empty one when there's none
+func LoadState(path string) (*State, error) {
+	state := &State{}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, state); err != nil {
+			return nil, fmt.Errorf("%s is not an import state file: %w", path, err)
+		}
+	}
+
+	if state.Failed == nil {
+		state.Failed = map[string]string{}
+	}
+	state.added = map[string]bool{}
+	for _, hostname := range state.Added {
+		state.added[hostname] = true
+	}
+	return state, nil
+}
+
+// IsAdded reports whether hostname has a certificate
+func (s *State) IsAdded(hostname string) bool {
+	return s.added[hostname]
+}
+
+func (s *State) MarkAdded(hostname string) {
+	delete(s.Failed, hostname)
+	if !s.added[hostname] {
+		s.added[hostname] = true
+		s.Added = append(s.Added, hostname)
+	}
+}
+
+func (s *State) MarkFailed(hostname string, err error) {
+	s.Failed[hostname] = err.Error()
+}
+
+// FailedHostnames returns the hostnames that couldn't be added, sorted
+func (s *State) FailedHostnames() []string {
+	var hostnames []string
+	for hostname := range s.Failed {
+		hostnames = append(hostnames, hostname)
+	}
+	sort.Strings(hostnames)
+	return hostnames
+}
+
+// Save writes the state to path through a temporary file, so an interrupted save keeps the
+// previous state
+func (s *State) Save(path string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,54 @@
+package certimport
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseHostnames(t *testing.T) {
+	input := `# customer domains
+shop.example.com
+WWW.Example.com.
+
+shop.example.com   # duplicate
+*.tenant.example.org
+`
+	hostnames, err := ParseHostnames(strings.NewReader(input))
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"shop.example.com", "www.example.com", "*.tenant.example.org"}, hostnames)
+}
+
+func TestParseHostnamesInvalid(t *testing.T) {
+	_, err := ParseHostnames(strings.NewReader("shop.example.com\nhttps://example.com/\n"))
+	assert.EqualError(t, err, "line 2: https://example.com/ is not a valid hostname")
+
+	_, err = ParseHostnames(strings.NewReader("localhost\n"))
+	assert.EqualError(t, err, "line 1: localhost is not a valid hostname")
+}
+
+func TestState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "domains.txt.progress.json")
+
+	state, err := LoadState(path)
+	assert.NoError(t, err)
+	assert.False(t, state.IsAdded("a.example.com"))
+
+	state.MarkFailed("a.example.com", errors.New("rate limited"))
+	state.MarkFailed("b.example.com", errors.New("invalid"))
+	state.MarkAdded("a.example.com")
+	assert.NoError(t, state.Save(path))
+
+	state, err = LoadState(path)
+	assert.NoError(t, err)
+	assert.True(t, state.IsAdded("a.example.com"))
+	assert.Equal(t, []string{"b.example.com"}, state.FailedHostnames())
+	assert.Equal(t, "invalid", state.Failed["b.example.com"])
+
+	state.MarkAdded("a.example.com")
+	assert.Equal(t, []string{"a.example.com"}, state.Added)
+}
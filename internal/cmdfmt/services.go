@@ -2,9 +2,12 @@ package cmdfmt
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/logrusorgru/aurora"
 	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/flyctl"
 	"github.com/superfly/flyctl/pkg/iostreams"
 )
 
@@ -14,3 +17,54 @@ func PrintServicesList(s *iostreams.IOStreams, services []api.Service) {
 		fmt.Fprintln(s.Out, svc.Description)
 	}
 }
+
+func PrintRoutesList(s *iostreams.IOStreams, routes []flyctl.Route) {
+	fmt.Fprintln(s.Out, aurora.Bold("Routes"))
+	for _, r := range routes {
+		var settings []string
+		if r.QueueTimeout > 0 {
+			settings = append(settings, fmt.Sprintf("queue timeout %s", r.QueueTimeout))
+		}
+		if r.ExcludeFromConcurrency {
+			settings = append(settings, "excluded from concurrency limits")
+		}
+		if r.SoftLimit > 0 || r.HardLimit > 0 {
+			settings = append(settings, fmt.Sprintf("concurrency soft=%d hard=%d", r.SoftLimit, r.HardLimit))
+		}
+		fmt.Fprintf(s.Out, "%d %s: %s\n", r.InternalPort, r.Path, strings.Join(settings, ", "))
+	}
+}
+
+func PrintWarmupList(s *iostreams.IOStreams, requests []flyctl.WarmupRequest) {
+	fmt.Fprintln(s.Out, aurora.Bold("Warm-up Requests"))
+	for _, r := range requests {
+		fmt.Fprintf(s.Out, "%d %s %s x%d (timeout %s)\n", r.InternalPort, r.Method, r.Path, r.Count, r.Timeout)
+	}
+}
+
+func PrintRestartSchedule(s *iostreams.IOStreams, rs *flyctl.RestartSchedule) {
+	fmt.Fprintln(s.Out, aurora.Bold("Scheduled Restarts"))
+	fmt.Fprintln(s.Out, rs)
+	if next, ok := rs.Next(time.Now()); ok {
+		fmt.Fprintf(s.Out, "Next restart at %s\n", next.Format(time.RFC1123))
+	}
+}
+
+func PrintDeployStrategy(s *iostreams.IOStreams, strategy string, dc *flyctl.DeployConfig) {
+	fmt.Fprintln(s.Out, aurora.Bold("Deploy Strategy"))
+	fmt.Fprintln(s.Out, strategy)
+	switch strategy {
+	case flyctl.StrategyWebsocket:
+		fmt.Fprintln(s.Out, dc.WebsocketSummary())
+	case flyctl.StrategyCanary:
+		fmt.Fprintln(s.Out, dc.CanarySummary())
+	case flyctl.StrategyBlueGreen:
+		fmt.Fprintln(s.Out, dc.BlueGreenSummary())
+	}
+	if dc.SmokeTest != nil {
+		fmt.Fprintln(s.Out, dc.SmokeTestSummary())
+	}
+	if len(dc.RolloutOrder) > 0 {
+		fmt.Fprintln(s.Out, dc.RolloutSummary())
+	}
+}
@@ -0,0 +1,364 @@
+// Package compose reads docker-compose files and converts their services to Fly app configs
+package compose
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Project is a parsed compose file
+type Project struct {
+	// Dir is the directory of the compose file, which relative paths are resolved against
+	Dir      string
+	Services []Service
+	// Warnings describe parts of the file that can't be carried over to Fly
+	Warnings []string
+}
+
+// Service is one compose service, which becomes one Fly app
+type Service struct {
+	Name        string
+	Image       string
+	Build       *Build
+	Ports       []Port
+	Environment map[string]string
+	Volumes     []Volume
+	Command     []string
+	DependsOn   []string
+	// Secrets are the environment variables that look like credentials, which are set as app
+	// secrets instead of being written to fly.toml
+	Secrets map[string]string
+}
+
+// Build is a service's build section
+type Build struct {
+	// Context is an absolute path
+	Context    string
+	Dockerfile string
+	Target     string
+	Args       map[string]string
+}
+
+// Port maps a published port to the port the service listens on. Published is zero for
+// ports that are only reachable by other services.
+type Port struct {
+	Published int
+	Target    int
+	Protocol  string
+}
+
+// Volume is a named volume or a bind mount
+type Volume struct {
+	Source   string
+	Target   string
+	Named    bool
+	ReadOnly bool
+}
+
+type rawFile struct {
+	Services map[string]rawService `yaml:"services"`
+}
+
+type rawService struct {
+	Image       string        `yaml:"image"`
+	Build       interface{}   `yaml:"build"`
+	Ports       []interface{} `yaml:"ports"`
+	Environment interface{}   `yaml:"environment"`
+	EnvFile     interface{}   `yaml:"env_file"`
+	Volumes     []interface{} `yaml:"volumes"`
+	Command     interface{}   `yaml:"command"`
+	DependsOn   interface{}   `yaml:"depends_on"`
+}
+
+// Load reads a compose file
+func Load(path string) (*Project, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(absPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Parse(f, filepath.Dir(absPath))
+}
+
+// Parse reads a compose file whose relative paths are relative to dir
+func Parse(r io.Reader, dir string) (*Project, error) {
+	var raw rawFile
+	if err := yaml.NewDecoder(r).Decode(&raw); err != nil {
+		return nil, errors.Wrap(err, "error parsing compose file")
+	}
+	if len(raw.Services) == 0 {
+		return nil, errors.New("compose file has no services")
+	}
+
+	p := &Project{Dir: dir}
+
+	names := make([]string, 0, len(raw.Services))
+	for name := range raw.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		svc, err := p.parseService(name, raw.Services[name])
+		if err != nil {
+			return nil, errors.Wrapf(err, "services.%s", name)
+		}
+		if svc.Image == "" && svc.Build == nil {
+			p.warnf("services.%s has no image or build and was skipped", name)
+			continue
+		}
+		p.Services = append(p.Services, *svc)
+	}
+
+	return p, nil
+}
+
+func (p *Project) warnf(format string, args ...interface{}) {
+	p.Warnings = append(p.Warnings, fmt.Sprintf(format, args...))
+}
+
+func (p *Project) parseService(name string, raw rawService) (*Service, error) {
+	svc := &Service{Name: name, Image: raw.Image, Environment: map[string]string{}, Secrets: map[string]string{}}
+
+	switch b := raw.Build.(type) {
+	case nil:
+	case string:
+		svc.Build = &Build{Context: p.path(b)}
+	case map[interface{}]interface{}:
+		svc.Build = &Build{Context: p.path(str(b["context"]))}
+		svc.Build.Dockerfile = str(b["dockerfile"])
+		svc.Build.Target = str(b["target"])
+		args, err := stringMap(b["args"])
+		if err != nil {
+			return nil, errors.Wrap(err, "build.args")
+		}
+		svc.Build.Args = args
+	default:
+		return nil, errors.New("build must be a path or a section")
+	}
+
+	for _, raw := range raw.Ports {
+		port, err := parsePort(raw)
+		if err != nil {
+			return nil, err
+		}
+		if port.Published == 0 {
+			p.warnf("services.%s port %d isn't published, other apps can still reach it over the private network", name, port.Target)
+		}
+		svc.Ports = append(svc.Ports, *port)
+	}
+
+	env, err := stringMap(raw.Environment)
+	if err != nil {
+		return nil, errors.Wrap(err, "environment")
+	}
+	for k, v := range env {
+		if v == "" {
+			p.warnf("services.%s environment %s has no value, set it with flyctl secrets set", name, k)
+			continue
+		}
+		if isSecret(k, v) {
+			svc.Secrets[k] = v
+		} else {
+			svc.Environment[k] = v
+		}
+	}
+	if raw.EnvFile != nil {
+		p.warnf("services.%s env_file isn't read, set its variables with flyctl secrets import", name)
+	}
+
+	for _, raw := range raw.Volumes {
+		vol, err := parseVolume(raw)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case vol.Named:
+			svc.Volumes = append(svc.Volumes, *vol)
+		case vol.Source == "":
+			p.warnf("services.%s anonymous volume %s isn't persisted, give it a name to keep its data", name, vol.Target)
+		default:
+			p.warnf("services.%s bind mount %s can't be used on Fly, copy its files into the image instead", name, vol.Source)
+		}
+	}
+
+	switch c := raw.Command.(type) {
+	case nil:
+	case string:
+		svc.Command = strings.Fields(c)
+	case []interface{}:
+		for _, arg := range c {
+			svc.Command = append(svc.Command, fmt.Sprint(arg))
+		}
+	default:
+		return nil, errors.New("command must be a string or a list")
+	}
+
+	switch d := raw.DependsOn.(type) {
+	case nil:
+	case []interface{}:
+		for _, dep := range d {
+			svc.DependsOn = append(svc.DependsOn, fmt.Sprint(dep))
+		}
+	case map[interface{}]interface{}:
+		for dep := range d {
+			svc.DependsOn = append(svc.DependsOn, fmt.Sprint(dep))
+		}
+		sort.Strings(svc.DependsOn)
+	}
+
+	return svc, nil
+}
+
+var secretNamePattern = regexp.MustCompile(`(?i)(PASSWORD|PASSWD|SECRET|TOKEN|PRIVATE|CREDENTIAL|(^|_)(API_)?KEY$|(^|_)PASS$)`)
+
+// isSecret reports whether an environment variable looks like a credential, by its name or
+// because its value is a URL with a password like postgres://app:pw@db/app
+func isSecret(name string, value string) bool {
+	if secretNamePattern.MatchString(name) {
+		return true
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.User == nil {
+		return false
+	}
+	_, hasPassword := u.User.Password()
+	return hasPassword
+}
+
+func (p *Project) path(rel string) string {
+	if rel == "" {
+		return p.Dir
+	}
+	if filepath.IsAbs(rel) {
+		return filepath.Clean(rel)
+	}
+	return filepath.Join(p.Dir, rel)
+}
+
+// parsePort reads the short "[host:]published:target[/protocol]" form or the long form section
+func parsePort(raw interface{}) (*Port, error) {
+	port := &Port{Protocol: "tcp"}
+
+	if m, ok := raw.(map[interface{}]interface{}); ok {
+		var err error
+		if port.Target, err = portNumber(m["target"]); err != nil {
+			return nil, err
+		}
+		if m["published"] != nil {
+			if port.Published, err = portNumber(m["published"]); err != nil {
+				return nil, err
+			}
+		}
+		if proto := str(m["protocol"]); proto != "" {
+			port.Protocol = proto
+		}
+		return port, nil
+	}
+
+	spec := fmt.Sprint(raw)
+	if i := strings.Index(spec, "/"); i >= 0 {
+		spec, port.Protocol = spec[:i], spec[i+1:]
+	}
+	if strings.Contains(spec, "-") {
+		return nil, fmt.Errorf("port range %s isn't supported, list each port", spec)
+	}
+
+	parts := strings.Split(spec, ":")
+	var err error
+	if port.Target, err = portNumber(parts[len(parts)-1]); err != nil {
+		return nil, err
+	}
+	if len(parts) > 1 {
+		if port.Published, err = portNumber(parts[len(parts)-2]); err != nil {
+			return nil, err
+		}
+	}
+	return port, nil
+}
+
+func portNumber(v interface{}) (int, error) {
+	n, err := strconv.Atoi(strings.TrimSpace(fmt.Sprint(v)))
+	if err != nil || n <= 0 || n > 65535 {
+		return 0, fmt.Errorf("invalid port %v", v)
+	}
+	return n, nil
+}
+
+// parseVolume reads the short "[source:]target[:mode]" form or the long form section
+func parseVolume(raw interface{}) (*Volume, error) {
+	vol := &Volume{}
+
+	if m, ok := raw.(map[interface{}]interface{}); ok {
+		vol.Source = str(m["source"])
+		vol.Target = str(m["target"])
+		vol.ReadOnly = m["read_only"] == true
+		vol.Named = str(m["type"]) == "volume" && vol.Source != ""
+	} else {
+		parts := strings.Split(fmt.Sprint(raw), ":")
+		switch len(parts) {
+		case 1:
+			vol.Target = parts[0]
+		case 2, 3:
+			vol.Source, vol.Target = parts[0], parts[1]
+			vol.ReadOnly = len(parts) == 3 && parts[2] == "ro"
+		default:
+			return nil, fmt.Errorf("invalid volume %v", raw)
+		}
+		vol.Named = vol.Source != "" && !strings.ContainsAny(vol.Source[:1], "./~")
+	}
+
+	if vol.Target == "" {
+		return nil, fmt.Errorf("volume %v has no target path", raw)
+	}
+	return vol, nil
+}
+
+// stringMap reads environment and args, which are either a map or a list of KEY=VALUE
+func stringMap(raw interface{}) (map[string]string, error) {
+	out := map[string]string{}
+
+	switch v := raw.(type) {
+	case nil:
+	case map[interface{}]interface{}:
+		for k, val := range v {
+			out[fmt.Sprint(k)] = str(val)
+		}
+	case []interface{}:
+		for _, item := range v {
+			kv := strings.SplitN(fmt.Sprint(item), "=", 2)
+			if len(kv) == 1 {
+				out[kv[0]] = ""
+			} else {
+				out[kv[0]] = kv[1]
+			}
+		}
+	default:
+		return nil, errors.New("must be a map or a list of KEY=VALUE")
+	}
+
+	return out, nil
+}
+
+func str(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(v)
+}
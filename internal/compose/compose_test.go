@@ -0,0 +1,99 @@
+package compose
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func loadTestProject(t *testing.T) *Project {
+	f, err := os.Open("testdata/docker-compose.yml")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	p, err := Parse(f, "/src/shop")
+	assert.NoError(t, err)
+	return p
+}
+
+func TestParse(t *testing.T) {
+	p := loadTestProject(t)
+
+	assert.Len(t, p.Services, 3)
+	api, db, web := p.Services[0], p.Services[1], p.Services[2]
+
+	assert.Equal(t, &Build{
+		Context:    "/src/shop/web",
+		Dockerfile: "Dockerfile.prod",
+		Target:     "runtime",
+		Args:       map[string]string{"NODE_ENV": "production"},
+	}, web.Build)
+	assert.Equal(t, []Port{{Published: 80, Target: 3000, Protocol: "tcp"}}, web.Ports)
+	assert.Equal(t, map[string]string{"API_URL": "http://api:8080"}, web.Environment)
+	assert.Empty(t, web.Secrets)
+	assert.Equal(t, []string{"api"}, web.DependsOn)
+
+	assert.Equal(t, "/src/shop/api", api.Build.Context)
+	assert.Equal(t, map[string]string{"LOG_LEVEL": "info"}, api.Environment)
+	assert.Equal(t, map[string]string{"DATABASE_URL": "postgres://app:hunter2@db:5432/app"}, api.Secrets)
+	assert.Equal(t, []string{"./api", "--listen", ":8080"}, api.Command)
+	assert.Equal(t, []Port{{Published: 8080, Target: 8080, Protocol: "tcp"}, {Target: 9090, Protocol: "tcp"}}, api.Ports)
+	assert.Equal(t, []Volume{{Source: "uploads", Target: "/data/uploads", Named: true}}, api.Volumes)
+
+	assert.Equal(t, "postgres:13", db.Image)
+	assert.Empty(t, db.Environment)
+	assert.Equal(t, map[string]string{"POSTGRES_PASSWORD": "example"}, db.Secrets)
+	assert.Equal(t, []string{"web"}, p.Dependents(api))
+
+	assert.Equal(t, []string{
+		"services.api port 9090 isn't published, other apps can still reach it over the private network",
+		"services.api bind mount ./config can't be used on Fly, copy its files into the image instead",
+		"services.db env_file isn't read, set its variables with flyctl secrets import",
+		"services.db anonymous volume /tmp/scratch isn't persisted, give it a name to keep its data",
+		"services.web environment SESSION_SECRET has no value, set it with flyctl secrets set",
+		"services.worker has no image or build and was skipped",
+	}, p.Warnings)
+}
+
+func TestIsSecret(t *testing.T) {
+	assert.True(t, isSecret("STRIPE_API_KEY", "sk_live"))
+	assert.True(t, isSecret("jwt_secret", "x"))
+	assert.True(t, isSecret("REDIS_URL", "redis://:pw@redis:6379"))
+	assert.False(t, isSecret("API_URL", "http://api:8080"))
+	assert.False(t, isSecret("KEYBOARD_LAYOUT", "us"))
+}
+
+func TestAppConfig(t *testing.T) {
+	p := loadTestProject(t)
+	api, db, web := p.Services[0], p.Services[1], p.Services[2]
+
+	cfg, warnings := web.AppConfig("shop-web")
+	assert.Empty(t, warnings)
+	assert.Equal(t, "shop-web", cfg.AppName)
+	assert.Equal(t, "Dockerfile.prod", cfg.Build.Dockerfile)
+	assert.Equal(t, "runtime", cfg.Build.Target)
+	assert.Equal(t, []map[string]interface{}{{
+		"internal_port": 3000,
+		"protocol":      "tcp",
+		"ports": []map[string]interface{}{
+			{"port": 80, "handlers": []string{"http"}},
+			{"port": 443, "handlers": []string{"tls", "http"}},
+		},
+	}}, cfg.Definition["services"])
+
+	cfg, _ = api.AppConfig("shop-api")
+	assert.Equal(t, map[string]interface{}{"source": "uploads", "destination": "/data/uploads"}, cfg.Definition["mounts"])
+	assert.Equal(t, map[string]interface{}{"cmd": []string{"./api", "--listen", ":8080"}}, cfg.Definition["experimental"])
+	assert.Equal(t, map[string]interface{}{"LOG_LEVEL": "info"}, cfg.Definition["env"])
+
+	cfg, _ = db.AppConfig("shop-db")
+	assert.Nil(t, cfg.Definition["env"])
+
+	assert.Equal(t, "postgres:13", cfg.Build.Image)
+	assert.Equal(t, map[string]interface{}{"source": "db_data", "destination": "/var/lib/postgresql/data"}, cfg.Definition["mounts"])
+	assert.Nil(t, cfg.Definition["services"])
+
+	assert.Equal(t, "/src/shop/web/fly.toml", p.ConfigPath(web))
+	assert.Equal(t, "/src/shop/fly.toml", p.ConfigPath(db))
+}
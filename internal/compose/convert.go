@@ -0,0 +1,152 @@
+package compose
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/superfly/flyctl/flyctl"
+)
+
+// AppConfig converts the service to a fly.toml for appName. Published ports become services, with
+// port 80 also served over TLS on 443, and the first named volume becomes the app's mount.
+func (s *Service) AppConfig(appName string) (*flyctl.AppConfig, []string) {
+	cfg := flyctl.NewAppConfig()
+	cfg.AppName = appName
+	var warnings []string
+
+	if s.Build != nil {
+		cfg.Build = &flyctl.Build{
+			Args:       s.Build.Args,
+			Dockerfile: s.Build.Dockerfile,
+			Target:     s.Build.Target,
+		}
+	} else {
+		cfg.Build = &flyctl.Build{Image: s.Image}
+	}
+
+	if len(s.Environment) > 0 {
+		env := map[string]interface{}{}
+		for k, v := range s.Environment {
+			env[k] = v
+		}
+		cfg.Definition["env"] = env
+	}
+
+	if services := s.services(); len(services) > 0 {
+		cfg.Definition["services"] = services
+	}
+
+	for i, vol := range s.Volumes {
+		if i > 0 {
+			warnings = append(warnings, fmt.Sprintf("services.%s volume %s wasn't mounted, apps can mount one volume", s.Name, vol.Source))
+			continue
+		}
+		cfg.Definition["mounts"] = map[string]interface{}{
+			"source":      VolumeName(vol.Source),
+			"destination": vol.Target,
+		}
+	}
+
+	if len(s.Command) > 0 {
+		cfg.Definition["experimental"] = map[string]interface{}{"cmd": s.Command}
+	}
+
+	return cfg, warnings
+}
+
+// services groups published ports by the port the service listens on
+func (s *Service) services() []map[string]interface{} {
+	type key struct {
+		port     int
+		protocol string
+	}
+	published := map[key][]int{}
+	var keys []key
+
+	for _, p := range s.Ports {
+		if p.Published == 0 {
+			continue
+		}
+		k := key{p.Target, p.Protocol}
+		if _, ok := published[k]; !ok {
+			keys = append(keys, k)
+		}
+		published[k] = append(published[k], p.Published)
+	}
+
+	var services []map[string]interface{}
+	for _, k := range keys {
+		var ports []map[string]interface{}
+		for _, port := range published[k] {
+			switch {
+			case port == 80 && k.protocol == "tcp":
+				ports = append(ports,
+					map[string]interface{}{"port": 80, "handlers": []string{"http"}},
+					map[string]interface{}{"port": 443, "handlers": []string{"tls", "http"}},
+				)
+			default:
+				ports = append(ports, map[string]interface{}{"port": port})
+			}
+		}
+		services = append(services, map[string]interface{}{
+			"internal_port": k.port,
+			"protocol":      k.protocol,
+			"ports":         ports,
+		})
+	}
+	return services
+}
+
+var invalidVolumeNameChars = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// VolumeName converts a compose volume name to a Fly volume name, which only allows
+// lowercase letters, numbers and underscores
+func VolumeName(source string) string {
+	name := invalidVolumeNameChars.ReplaceAllString(strings.ToLower(source), "_")
+	if len(name) > 30 {
+		name = name[:30]
+	}
+	return name
+}
+
+// ConfigPath is where a service's fly.toml is written, in its build context so deploys of that
+// directory build the right source. Services sharing a directory get fly.<service>.toml each.
+func (p *Project) ConfigPath(s Service) string {
+	dir := p.serviceDir(s)
+
+	shared := 0
+	for _, other := range p.Services {
+		if p.serviceDir(other) == dir {
+			shared++
+		}
+	}
+
+	if shared > 1 {
+		return filepath.Join(dir, fmt.Sprintf("fly.%s.toml", s.Name))
+	}
+	return filepath.Join(dir, "fly.toml")
+}
+
+func (p *Project) serviceDir(s Service) string {
+	if s.Build != nil {
+		return s.Build.Context
+	}
+	return p.Dir
+}
+
+// Dependents returns the services that depend on s, which need its new .internal hostname
+func (p *Project) Dependents(s Service) []string {
+	var out []string
+	for _, other := range p.Services {
+		for _, dep := range other.DependsOn {
+			if dep == s.Name {
+				out = append(out, other.Name)
+			}
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,44 @@
+package cron
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNext(t *testing.T) {
+	from := time.Date(2021, time.March, 10, 4, 30, 15, 0, time.UTC) // a wednesday
+
+	cases := map[string]time.Time{
+		"0 4 * * *":      time.Date(2021, time.March, 11, 4, 0, 0, 0, time.UTC),
+		"*/15 * * * *":   time.Date(2021, time.March, 10, 4, 45, 0, 0, time.UTC),
+		"0 0 1 * *":      time.Date(2021, time.April, 1, 0, 0, 0, 0, time.UTC),
+		"30 2 * * 1-5":   time.Date(2021, time.March, 11, 2, 30, 0, 0, time.UTC),
+		"0 3 * * 7":      time.Date(2021, time.March, 14, 3, 0, 0, 0, time.UTC),
+		"0 12 15 * 5":    time.Date(2021, time.March, 12, 12, 0, 0, 0, time.UTC),
+		"@hourly":        time.Date(2021, time.March, 10, 5, 0, 0, 0, time.UTC),
+		"0 0 29 2 *":     time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC),
+		"5,35 4-6 * * *": time.Date(2021, time.March, 10, 4, 35, 0, 0, time.UTC),
+		"0 0 */2 * 1":    time.Date(2021, time.March, 15, 0, 0, 0, 0, time.UTC),
+	}
+
+	for spec, want := range cases {
+		s, err := Parse(spec)
+		assert.NoError(t, err, spec)
+		assert.Equal(t, want, s.Next(from), spec)
+	}
+}
+
+func TestNextNever(t *testing.T) {
+	s, err := Parse("0 0 30 2 *")
+	assert.NoError(t, err)
+	assert.True(t, s.Next(time.Now()).IsZero())
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, spec := range []string{"", "0 4 * *", "60 * * * *", "0 24 * * *", "0 0 0 * *", "*/0 * * * *", "5-1 * * * *", "a * * * *"} {
+		_, err := Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
@@ -0,0 +1,58 @@
+package deployment
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/superfly/flyctl/api"
+)
+
+// HealthyCanary returns the canary instance of a deployment once it's healthy with every check
+// passing, nil until then
+func HealthyCanary(d *api.DeploymentStatus) *api.AllocationStatus {
+	for _, alloc := range d.Allocations {
+		if alloc.Canary && alloc.Version == d.Version && healthy(alloc) {
+			return alloc
+		}
+	}
+	return nil
+}
+
+// CanaryVerifier runs a command against the canary instance of a deployment before the rest of
+// its instances are replaced
+type CanaryVerifier struct {
+	AppName string
+	Command string
+	// Timeout limits how long the command may run, no limit when zero
+	Timeout time.Duration
+	Out     io.Writer
+	ErrOut  io.Writer
+}
+
+func (v *CanaryVerifier) Name() string {
+	return "canary verification"
+}
+
+func (v *CanaryVerifier) Ready(d *api.DeploymentStatus) *api.AllocationStatus {
+	return HealthyCanary(d)
+}
+
+func (v *CanaryVerifier) Check(ctx context.Context, d *api.DeploymentStatus, canary *api.AllocationStatus) error {
+	return v.Verify(ctx, d, canary)
+}
+
+// Verify runs the command with the canary's details exported as FLY_APP, FLY_RELEASE_VERSION,
+// FLY_CANARY_ID, FLY_CANARY_REGION and FLY_CANARY_PRIVATE_IP. It fails when the command exits
+// with an error or runs past the timeout.
+func (v *CanaryVerifier) Verify(ctx context.Context, d *api.DeploymentStatus, canary *api.AllocationStatus) error {
+	env := []string{
+		"FLY_APP=" + v.AppName,
+		"FLY_RELEASE_VERSION=" + strconv.Itoa(d.Version),
+		"FLY_CANARY_ID=" + canary.ID,
+		"FLY_CANARY_REGION=" + canary.Region,
+		"FLY_CANARY_PRIVATE_IP=" + canary.PrivateIP,
+	}
+	return runGateCommand(ctx, "canary verification", v.Command, v.Timeout, env, v.Out, v.ErrOut)
+}
@@ -0,0 +1,44 @@
+package deployment
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/superfly/flyctl/api"
+)
+
+func TestHealthyCanary(t *testing.T) {
+	d := &api.DeploymentStatus{Version: 3, Allocations: []*api.AllocationStatus{
+		{ID: "old", Version: 2, Healthy: true},
+		{ID: "canary", Version: 3, Canary: true, Healthy: true, WarningCheckCount: 1},
+	}}
+	assert.Nil(t, HealthyCanary(d))
+
+	d.Allocations[1].WarningCheckCount = 0
+	assert.Equal(t, "canary", HealthyCanary(d).ID)
+}
+
+func TestCanaryVerifier(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+
+	d := &api.DeploymentStatus{Version: 3}
+	canary := &api.AllocationStatus{ID: "abc123", Region: "ord", PrivateIP: "fdaa::3"}
+
+	var out bytes.Buffer
+	v := &CanaryVerifier{AppName: "test-app", Command: "echo $FLY_APP v$FLY_RELEASE_VERSION $FLY_CANARY_ID $FLY_CANARY_REGION $FLY_CANARY_PRIVATE_IP", Out: &out}
+	assert.NoError(t, v.Verify(context.Background(), d, canary))
+	assert.Equal(t, "test-app v3 abc123 ord fdaa::3\n", out.String())
+
+	v.Command = "exit 1"
+	assert.Error(t, v.Verify(context.Background(), d, canary))
+
+	v.Command = "exec sleep 5"
+	v.Timeout = 50 * time.Millisecond
+	assert.EqualError(t, v.Verify(context.Background(), d, canary), "canary verification \"exec sleep 5\" did not finish within 50ms")
+}
@@ -0,0 +1,78 @@
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/superfly/flyctl/api"
+	"github.com/superfly/flyctl/internal/shellcmd"
+)
+
+// Gate checks an instance of a deployment that's held until it's promoted or aborted, before the
+// rest of its instances are replaced
+type Gate interface {
+	// Name describes the check in progress messages
+	Name() string
+	// Ready returns the instance to check once it's healthy, nil until then
+	Ready(d *api.DeploymentStatus) *api.AllocationStatus
+	Check(ctx context.Context, d *api.DeploymentStatus, instance *api.AllocationStatus) error
+}
+
+// Gates checks the same instance with several gates, in order, once the first gate finds it ready
+type Gates []Gate
+
+func (g Gates) Name() string {
+	names := make([]string, len(g))
+	for i, gate := range g {
+		names[i] = gate.Name()
+	}
+	return strings.Join(names, " and ")
+}
+
+func (g Gates) Ready(d *api.DeploymentStatus) *api.AllocationStatus {
+	if len(g) == 0 {
+		return nil
+	}
+	return g[0].Ready(d)
+}
+
+func (g Gates) Check(ctx context.Context, d *api.DeploymentStatus, instance *api.AllocationStatus) error {
+	for _, gate := range g {
+		if err := gate.Check(ctx, d, instance); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FirstHealthyInstance returns an instance of the deployment's release that's healthy with every
+// check passing, nil until there is one
+func FirstHealthyInstance(d *api.DeploymentStatus) *api.AllocationStatus {
+	for _, alloc := range d.Allocations {
+		if alloc.Version == d.Version && healthy(alloc) {
+			return alloc
+		}
+	}
+	return nil
+}
+
+func healthy(alloc *api.AllocationStatus) bool {
+	return alloc.Healthy && alloc.CriticalCheckCount == 0 && alloc.WarningCheckCount == 0
+}
+
+// runGateCommand runs a user's command through the shell with env added to flyctl's environment,
+// failing when it exits with an error or runs past timeout
+func runGateCommand(ctx context.Context, what, command string, timeout time.Duration, env []string, out, errOut io.Writer) error {
+	err := shellcmd.Run(ctx, command, shellcmd.Options{Env: env, Timeout: timeout, Stdout: out, Stderr: errOut})
+	if err == shellcmd.ErrTimeout {
+		return fmt.Errorf("%s \"%s\" did not finish within %s", what, command, timeout)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "%s \"%s\" failed", what, command)
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package deployment
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/superfly/flyctl/api"
+)
+
+var (
+	// lockTTL is how long a deploy lock lasts without a refresh, so the lock of a deploy that's
+	// killed expires soon after
+	lockTTL             = 2 * time.Minute
+	lockRefreshInterval = 30 * time.Second
+	lockRetryInterval   = 5 * time.Second
+)
+
+// LockClient is the part of the API client managing deploy locks
+type LockClient interface {
+	AcquireDeployLock(appName string, holder string, ttl int) (*api.DeployLock, *api.DeployLock, error)
+	RefreshDeployLock(lockID string, ttl int) (*api.DeployLock, error)
+	ReleaseDeployLock(lockID string) error
+}
+
+// LockedError is returned by AcquireLock when another deploy holds the lock
+type LockedError struct {
+	Lock *api.DeployLock
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("another deploy holds the deploy lock, %s since %s", e.Lock.Holder, e.Lock.CreatedAt.Format(time.RFC3339))
+}
+
+// Lock is an app's deploy lock held by this deploy, refreshed until it's released
+type Lock struct {
+	client LockClient
+	lock   *api.DeployLock
+
+	stop    chan struct{}
+	stopped sync.WaitGroup
+
+	lost    chan struct{}
+	lostErr error
+}
+
+// AcquireLock takes an app's deploy lock for holder. While another deploy holds it, it returns a
+// *LockedError, or with wait set it calls waiting and tries again until the lock is free or ctx
+// is done.
+func AcquireLock(ctx context.Context, c LockClient, appName string, holder string, wait bool, waiting func(held *api.DeployLock)) (*Lock, error) {
+	for {
+		lock, held, err := c.AcquireDeployLock(appName, holder, int(lockTTL/time.Second))
+		if err != nil {
+			return nil, err
+		}
+		if lock != nil {
+			return newLock(c, lock), nil
+		}
+		if held == nil {
+			return nil, fmt.Errorf("the deploy lock of %s could not be acquired", appName)
+		}
+		if !wait {
+			return nil, &LockedError{Lock: held}
+		}
+
+		if waiting != nil {
+			waiting(held)
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(lockRetryInterval):
+		}
+	}
+}
+
+func newLock(c LockClient, lock *api.DeployLock) *Lock {
+	l := &Lock{client: c, lock: lock, stop: make(chan struct{}), lost: make(chan struct{})}
+
+	l.stopped.Add(1)
+	go func() {
+		defer l.stopped.Done()
+
+		refreshed := time.Now()
+		for {
+			select {
+			case <-l.stop:
+				return
+			case <-time.After(lockRefreshInterval):
+			}
+
+			// a failed refresh is tried again next time, until the lock has expired
+			held, err := l.client.RefreshDeployLock(l.lock.ID, int(lockTTL/time.Second))
+			switch {
+			case err == nil && held != nil:
+				refreshed = time.Now()
+				continue
+			case err == nil:
+				l.lostErr = errors.New("the lock was released, another deploy may have forced it with --force-unlock")
+			case time.Since(refreshed) >= lockTTL:
+				l.lostErr = fmt.Errorf("the lock couldn't be refreshed for %s: %w", lockTTL, err)
+			default:
+				continue
+			}
+			close(l.lost)
+			return
+		}
+	}()
+
+	return l
+}
+
+// Lost is closed once the lock is lost, having been released by another deploy or having expired
+// because it couldn't be refreshed. Another deploy can start from then on.
+func (l *Lock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+// Err says why the lock was lost, once Lost is closed
+func (l *Lock) Err() error {
+	select {
+	case <-l.lost:
+		return l.lostErr
+	default:
+		return nil
+	}
+}
+
+// Release stops refreshing the lock and gives it up, letting the next deploy start
+func (l *Lock) Release() error {
+	close(l.stop)
+	l.stopped.Wait()
+	return l.client.ReleaseDeployLock(l.lock.ID)
+}
+
+// LockHolder describes this deploy for other deploys waiting on its lock: the host and process
+// running it, and the CI job when it's running in GitHub Actions or GitLab CI
+func LockHolder() string {
+	host, _ := os.Hostname()
+	holder := fmt.Sprintf("flyctl on %s (pid %d)", host, os.Getpid())
+
+	switch {
+	case os.Getenv("GITHUB_RUN_ID") != "":
+		holder += fmt.Sprintf(", %s/%s/actions/runs/%s", os.Getenv("GITHUB_SERVER_URL"), os.Getenv("GITHUB_REPOSITORY"), os.Getenv("GITHUB_RUN_ID"))
+	case os.Getenv("CI_JOB_URL") != "":
+		holder += ", " + os.Getenv("CI_JOB_URL")
+	}
+
+	return holder
+}
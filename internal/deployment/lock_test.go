@@ -0,0 +1,134 @@
+package deployment
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/superfly/flyctl/api"
+)
+
+type fakeLockClient struct {
+	mu        sync.Mutex
+	held      *api.DeployLock
+	refreshed int
+	released  []string
+	// refreshErr fails every refresh
+	refreshErr error
+}
+
+func (c *fakeLockClient) AcquireDeployLock(appName string, holder string, ttl int) (*api.DeployLock, *api.DeployLock, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.held != nil {
+		return nil, c.held, nil
+	}
+	c.held = &api.DeployLock{ID: "lock-" + holder, Holder: holder}
+	return c.held, nil, nil
+}
+
+func (c *fakeLockClient) RefreshDeployLock(lockID string, ttl int) (*api.DeployLock, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refreshed++
+	if c.refreshErr != nil {
+		return nil, c.refreshErr
+	}
+	return c.held, nil
+}
+
+func (c *fakeLockClient) ReleaseDeployLock(lockID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.released = append(c.released, lockID)
+	c.held = nil
+	return nil
+}
+
+func TestAcquireLock(t *testing.T) {
+	defer func(refresh, retry time.Duration) {
+		lockRefreshInterval, lockRetryInterval = refresh, retry
+	}(lockRefreshInterval, lockRetryInterval)
+	lockRefreshInterval = time.Millisecond
+	lockRetryInterval = time.Millisecond
+
+	c := &fakeLockClient{}
+	first, err := AcquireLock(context.Background(), c, "test-app", "first", false, nil)
+	assert.NoError(t, err)
+
+	_, err = AcquireLock(context.Background(), c, "test-app", "second", false, nil)
+	var locked *LockedError
+	assert.True(t, errors.As(err, &locked))
+	assert.Equal(t, "first", locked.Lock.Holder)
+
+	// the second deploy queues until the first releases the lock
+	waits := 0
+	acquired := make(chan *Lock)
+	go func() {
+		second, err := AcquireLock(context.Background(), c, "test-app", "second", true, func(held *api.DeployLock) {
+			c.mu.Lock()
+			waits++
+			c.mu.Unlock()
+		})
+		assert.NoError(t, err)
+		acquired <- second
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+	assert.NoError(t, first.Release())
+	second := <-acquired
+	assert.NoError(t, second.Release())
+
+	assert.Equal(t, []string{"lock-first", "lock-second"}, c.released)
+	assert.Greater(t, waits, 0)
+	assert.Greater(t, c.refreshed, 0)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.held = &api.DeployLock{Holder: "someone"}
+	cancel()
+	_, err = AcquireLock(ctx, c, "test-app", "third", true, nil)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestLockLost(t *testing.T) {
+	defer func(ttl, refresh time.Duration) {
+		lockTTL, lockRefreshInterval = ttl, refresh
+	}(lockTTL, lockRefreshInterval)
+	lockTTL = 20 * time.Millisecond
+	lockRefreshInterval = time.Millisecond
+
+	// released by another deploy
+	c := &fakeLockClient{}
+	lock, err := AcquireLock(context.Background(), c, "test-app", "first", false, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, lock.Err())
+	c.mu.Lock()
+	c.held = nil
+	c.mu.Unlock()
+	select {
+	case <-lock.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("the lock wasn't lost")
+	}
+	assert.Contains(t, lock.Err().Error(), "released")
+
+	// refreshes failing until it expires
+	c = &fakeLockClient{}
+	lock, err = AcquireLock(context.Background(), c, "test-app", "first", false, nil)
+	assert.NoError(t, err)
+	c.mu.Lock()
+	c.refreshErr = errors.New("connection refused")
+	c.mu.Unlock()
+	started := time.Now()
+	select {
+	case <-lock.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("the lock wasn't lost")
+	}
+	assert.GreaterOrEqual(t, time.Since(started), 15*time.Millisecond)
+	assert.True(t, errors.Is(lock.Err(), c.refreshErr))
+	assert.NoError(t, lock.Release())
+}
@@ -23,6 +23,9 @@ func NewDeploymentMonitor(client *api.Client, appID string) *DeploymentMonitor {
 
 type DeploymentMonitor struct {
 	AppID string
+	// DeploymentID is the deployment to start monitoring, even if it has already finished. Empty
+	// waits for the app's next deployment.
+	DeploymentID string
 
 	client       *api.Client
 	err          error
@@ -51,7 +54,7 @@ func (dm *DeploymentMonitor) start(ctx context.Context) <-chan *deploymentStatus
 			close(statusCh)
 		}()
 
-		currentID := ""
+		currentID := dm.DeploymentID
 		prevID := ""
 		num := 0
 		startTime := time.Now()
@@ -79,7 +82,10 @@ func (dm *DeploymentMonitor) start(ctx context.Context) <-chan *deploymentStatus
 				return errDeploymentComplete
 			}
 
-			if currentDeployment == nil && !deployment.InProgress {
+			// a deployment being attached to is reported however far along it is
+			attaching := num == 0 && dm.DeploymentID != "" && deployment.ID == dm.DeploymentID
+
+			if currentDeployment == nil && !deployment.InProgress && !attaching {
 				// wait for deployment (new deployment not yet created)
 				return errDeploymentNotReady
 			}
@@ -0,0 +1,134 @@
+package deployment
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Outcome is how a deploy finished, passed to [deploy.hooks] commands and [deploy.notify] webhooks
+type Outcome struct {
+	App     string
+	Version int
+	Image   string
+	// Author is the email of the user who deployed the release
+	Author string
+	Err    error
+	// Detached is set when flyctl stopped following the deployment after creating its release,
+	// with --detach or the immediate strategy, so it's not known yet how it finishes
+	Detached bool
+}
+
+// hookTimeout bounds how long a [deploy.hooks] command can run
+const hookTimeout = 5 * time.Minute
+
+// Succeeded returns true when the deploy finished without an error
+func (o Outcome) Succeeded() bool {
+	return o.Err == nil
+}
+
+// Env returns the outcome as environment variables for hook commands
+func (o Outcome) Env() []string {
+	status := "success"
+	if !o.Succeeded() {
+		status = "failure"
+	} else if o.Detached {
+		status = "started"
+	}
+
+	env := []string{
+		"FLY_APP=" + o.App,
+		"FLY_RELEASE_VERSION=" + strconv.Itoa(o.Version),
+		"FLY_IMAGE=" + o.Image,
+		"FLY_DEPLOY_AUTHOR=" + o.Author,
+		"FLY_DEPLOY_STATUS=" + status,
+	}
+	if o.Err != nil {
+		env = append(env, "FLY_ERROR="+o.Err.Error())
+	}
+	return env
+}
+
+// Text describes the outcome in a sentence for chat notifications
+func (o Outcome) Text() string {
+	by := ""
+	if o.Author != "" {
+		by = " by " + o.Author
+	}
+	if o.Succeeded() && o.Detached {
+		return fmt.Sprintf("Started deploying %s v%d%s (%s)", o.App, o.Version, by, o.Image)
+	}
+	if o.Succeeded() {
+		return fmt.Sprintf("Deployed %s v%d%s (%s)", o.App, o.Version, by, o.Image)
+	}
+	return fmt.Sprintf("Deploy of %s v%d%s failed: %s", o.App, o.Version, by, o.Err)
+}
+
+// RunHook runs a [deploy.hooks] command with the outcome in its environment
+func RunHook(ctx context.Context, command string, o Outcome, out, errOut io.Writer) error {
+	return runGateCommand(ctx, "deploy hook", command, hookTimeout, o.Env(), out, errOut)
+}
+
+// notification is the body posted to [deploy.notify] webhooks. Text is set so it can also be sent
+// to Slack compatible incoming webhooks.
+type notification struct {
+	Event   string `json:"event"`
+	App     string `json:"app"`
+	Version int    `json:"version"`
+	Image   string `json:"image"`
+	Author  string `json:"author,omitempty"`
+	Error   string `json:"error,omitempty"`
+	Text    string `json:"text"`
+}
+
+// Notify posts the outcome to a webhook as JSON. Slack incoming webhooks are only sent the text.
+func Notify(ctx context.Context, url string, slack bool, o Outcome) error {
+	var body interface{}
+	if slack {
+		body = map[string]string{"text": o.Text()}
+	} else {
+		n := notification{Event: "deploy_succeeded", App: o.App, Version: o.Version, Image: o.Image, Author: o.Author, Text: o.Text()}
+		if !o.Succeeded() {
+			n.Event = "deploy_failed"
+			n.Error = o.Err.Error()
+		} else if o.Detached {
+			n.Event = "deploy_started"
+		}
+		body = n
+	}
+	return PostWebhook(ctx, url, body)
+}
+
+// PostWebhook posts body as JSON to a webhook, failing when it doesn't respond with a 2xx status
+// within 10 seconds
+func PostWebhook(ctx context.Context, url string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
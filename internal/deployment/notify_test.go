@@ -0,0 +1,61 @@
+package deployment
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutcomeText(t *testing.T) {
+	o := Outcome{App: "test-app", Version: 7, Image: "registry.fly.io/test-app:deployment-1", Author: "jo@example.com"}
+	assert.Equal(t, "Deployed test-app v7 by jo@example.com (registry.fly.io/test-app:deployment-1)", o.Text())
+
+	o.Err = errors.New("instances failed their health checks")
+	assert.Equal(t, "Deploy of test-app v7 by jo@example.com failed: instances failed their health checks", o.Text())
+	assert.Contains(t, o.Env(), "FLY_DEPLOY_STATUS=failure")
+
+	o.Err = nil
+	o.Detached = true
+	assert.Equal(t, "Started deploying test-app v7 by jo@example.com (registry.fly.io/test-app:deployment-1)", o.Text())
+	assert.Contains(t, o.Env(), "FLY_DEPLOY_STATUS=started")
+}
+
+func TestRunHook(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+
+	var out bytes.Buffer
+	o := Outcome{App: "test-app", Version: 7, Image: "img", Author: "jo@example.com"}
+	assert.NoError(t, RunHook(context.Background(), "echo $FLY_APP v$FLY_RELEASE_VERSION $FLY_DEPLOY_STATUS $FLY_DEPLOY_AUTHOR", o, &out, &out))
+	assert.Equal(t, "test-app v7 success jo@example.com\n", out.String())
+}
+
+func TestNotify(t *testing.T) {
+	var bodies []map[string]interface{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]interface{}
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies = append(bodies, body)
+	}))
+	defer srv.Close()
+
+	o := Outcome{App: "test-app", Version: 7, Image: "img"}
+	assert.NoError(t, Notify(context.Background(), srv.URL, false, o))
+	assert.NoError(t, Notify(context.Background(), srv.URL, true, o))
+
+	assert.Equal(t, "deploy_succeeded", bodies[0]["event"])
+	assert.Equal(t, float64(7), bodies[0]["version"])
+	assert.Equal(t, map[string]interface{}{"text": "Deployed test-app v7 (img)"}, bodies[1])
+
+	o.Detached = true
+	assert.NoError(t, Notify(context.Background(), srv.URL, false, o))
+	assert.Equal(t, "deploy_started", bodies[2]["event"])
+}
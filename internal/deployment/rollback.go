@@ -0,0 +1,55 @@
+package deployment
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/superfly/flyctl/api"
+)
+
+// AutoRollback watches a deployment's new instances, tripping once Threshold of them are failing
+// their health checks
+type AutoRollback struct {
+	Threshold int
+
+	// passed holds the new instances seen passing their checks, since a starting instance reports
+	// critical checks until it's up
+	passed map[string]bool
+}
+
+func NewAutoRollback(threshold int) *AutoRollback {
+	return &AutoRollback{Threshold: threshold, passed: map[string]bool{}}
+}
+
+// Failing returns the deployment's new instances that have failed, or that are reporting critical
+// checks after passing them
+func (a *AutoRollback) Failing(d *api.DeploymentStatus) []*api.AllocationStatus {
+	var failing []*api.AllocationStatus
+	for _, alloc := range d.Allocations {
+		if alloc.Version != d.Version {
+			continue
+		}
+		if healthy(alloc) {
+			a.passed[alloc.ID] = true
+			continue
+		}
+		if alloc.Failed || (a.passed[alloc.ID] && alloc.CriticalCheckCount > 0) {
+			failing = append(failing, alloc)
+		}
+	}
+	return failing
+}
+
+// Check returns an error describing the failing instances once there are Threshold or more
+func (a *AutoRollback) Check(d *api.DeploymentStatus) error {
+	failing := a.Failing(d)
+	if len(failing) == 0 || len(failing) < a.Threshold {
+		return nil
+	}
+
+	ids := make([]string, len(failing))
+	for i, alloc := range failing {
+		ids[i] = alloc.IDShort
+	}
+	return fmt.Errorf("%d of v%d's new instances are failing their health checks (%s)", len(failing), d.Version, strings.Join(ids, ", "))
+}
@@ -0,0 +1,28 @@
+package deployment
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/superfly/flyctl/api"
+)
+
+func TestAutoRollback(t *testing.T) {
+	a := NewAutoRollback(2)
+	d := &api.DeploymentStatus{Version: 4, Allocations: []*api.AllocationStatus{
+		{ID: "old", IDShort: "old", Version: 3, Failed: true},
+		{ID: "a", IDShort: "a", Version: 4, CriticalCheckCount: 1},
+		{ID: "b", IDShort: "b", Version: 4, Healthy: true},
+	}}
+
+	// a is still starting, and the previous release's instance doesn't count
+	assert.NoError(t, a.Check(d))
+
+	d.Allocations[2].Healthy = false
+	d.Allocations[2].CriticalCheckCount = 1
+	assert.Len(t, a.Failing(d), 1)
+	assert.NoError(t, a.Check(d))
+
+	d.Allocations[1].Failed = true
+	assert.EqualError(t, a.Check(d), "2 of v4's new instances are failing their health checks (a, b)")
+}
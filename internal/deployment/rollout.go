@@ -0,0 +1,102 @@
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/superfly/flyctl/api"
+)
+
+// bakeCriticalPolls is how many polls in a row an instance must report critical checks before the
+// rollout halts, so a single flapping check doesn't roll back a healthy release
+const bakeCriticalPolls = 2
+
+var bakePollInterval = 10 * time.Second
+
+// Rollout bakes each region of a staged rollout, watching the new instances rolled out so far for
+// BakeTime before the deployment is promoted to the next region
+type Rollout struct {
+	Regions  []string
+	BakeTime time.Duration
+	// Status fetches the deployment while a region bakes
+	Status func(id string) (*api.DeploymentStatus, error)
+}
+
+func (r *Rollout) Name() string {
+	return "rollout bake"
+}
+
+// Ready returns a new instance in the region the deployment is held at, nil while it isn't held
+func (r *Rollout) Ready(d *api.DeploymentStatus) *api.AllocationStatus {
+	if !d.AwaitingPromotion() || d.RolloutRegion == "" {
+		return nil
+	}
+	for _, alloc := range d.Allocations {
+		if alloc.Version == d.Version && alloc.Region == d.RolloutRegion {
+			return alloc
+		}
+	}
+	return nil
+}
+
+// Check watches the new instances in every region rolled out to so far until BakeTime passes. It
+// fails when one of them fails, or reports critical checks on consecutive polls.
+func (r *Rollout) Check(ctx context.Context, d *api.DeploymentStatus, instance *api.AllocationStatus) error {
+	regions := r.rolledOut(d.RolloutRegion)
+	critical := map[string]int{}
+	deadline := time.After(r.BakeTime)
+
+	for {
+		if err := rolloutFailure(d, regions, critical); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline:
+			return nil
+		case <-time.After(bakePollInterval):
+		}
+
+		// keep baking on the last status when a poll fails, a lost request isn't a failed release
+		if next, err := r.Status(d.ID); err == nil && next != nil {
+			d = next
+		}
+	}
+}
+
+// rolledOut returns the regions up to and including region in the rollout order
+func (r *Rollout) rolledOut(region string) map[string]bool {
+	regions := map[string]bool{region: true}
+	for _, rr := range r.Regions {
+		regions[rr] = true
+		if rr == region {
+			break
+		}
+	}
+	return regions
+}
+
+// rolloutFailure checks the new instances in regions, counting the consecutive polls each has
+// reported critical checks on in critical
+func rolloutFailure(d *api.DeploymentStatus, regions map[string]bool, critical map[string]int) error {
+	for _, alloc := range d.Allocations {
+		if alloc.Version != d.Version || !regions[alloc.Region] {
+			continue
+		}
+		if alloc.Failed {
+			return fmt.Errorf("instance %s in %s failed during the rollout", alloc.IDShort, alloc.Region)
+		}
+		if alloc.CriticalCheckCount == 0 {
+			delete(critical, alloc.ID)
+			continue
+		}
+		critical[alloc.ID]++
+		if critical[alloc.ID] >= bakeCriticalPolls {
+			return fmt.Errorf("instance %s in %s has %d critical checks during the rollout", alloc.IDShort, alloc.Region, alloc.CriticalCheckCount)
+		}
+	}
+	return nil
+}
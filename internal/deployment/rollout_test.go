@@ -0,0 +1,61 @@
+package deployment
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/superfly/flyctl/api"
+)
+
+func TestRolloutReady(t *testing.T) {
+	r := &Rollout{Regions: []string{"iad", "lhr"}}
+	d := &api.DeploymentStatus{Version: 3, InProgress: true, Allocations: []*api.AllocationStatus{
+		{ID: "old", Version: 2, Region: "iad"},
+		{ID: "new", Version: 3, Region: "iad"},
+	}}
+	assert.Nil(t, r.Ready(d))
+
+	d.Status = api.DeploymentAwaitingPromotion
+	d.RolloutRegion = "iad"
+	assert.Equal(t, "new", r.Ready(d).ID)
+}
+
+func TestRolloutCheck(t *testing.T) {
+	defer func(interval time.Duration) { bakePollInterval = interval }(bakePollInterval)
+	bakePollInterval = time.Millisecond
+
+	statuses := map[int]*api.DeploymentStatus{}
+	polls := 0
+	r := &Rollout{
+		Regions:  []string{"iad", "lhr", "syd"},
+		BakeTime: time.Second,
+		Status: func(id string) (*api.DeploymentStatus, error) {
+			polls++
+			if s, ok := statuses[polls]; ok {
+				return s, nil
+			}
+			return statuses[0], nil
+		},
+	}
+
+	alloc := func(id, region string, critical int) *api.AllocationStatus {
+		return &api.AllocationStatus{ID: id, IDShort: id, Version: 3, Region: region, CriticalCheckCount: critical}
+	}
+	status := func(allocs ...*api.AllocationStatus) *api.DeploymentStatus {
+		return &api.DeploymentStatus{ID: "d1", Version: 3, RolloutRegion: "lhr", Allocations: allocs}
+	}
+
+	// a check that's critical on a single poll doesn't halt the rollout, and syd isn't rolled out yet
+	statuses[0] = status(alloc("a", "iad", 0), alloc("b", "lhr", 0), alloc("c", "syd", 4))
+	statuses[2] = status(alloc("a", "iad", 1), alloc("b", "lhr", 0))
+	r.BakeTime = 50 * time.Millisecond
+	assert.NoError(t, r.Check(context.Background(), statuses[0], nil))
+
+	// an earlier region's instance failing its checks halts it
+	polls = 0
+	statuses = map[int]*api.DeploymentStatus{0: status(alloc("a", "iad", 2), alloc("b", "lhr", 0))}
+	r.BakeTime = time.Minute
+	assert.EqualError(t, r.Check(context.Background(), status(alloc("a", "iad", 0)), nil), "instance a in iad has 2 critical checks during the rollout")
+}
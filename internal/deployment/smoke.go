@@ -0,0 +1,127 @@
+package deployment
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/superfly/flyctl/api"
+)
+
+// smokeTestAttempts is how many times the HTTP probe is tried before it fails, since a fresh
+// instance can pass its checks a moment before its routes are warm
+const smokeTestAttempts = 3
+
+var smokeTestRetryInterval = 2 * time.Second
+
+// SmokeTest checks the first healthy instance of a deployment with a command, an HTTP probe or
+// both, before the rest of its instances are replaced
+type SmokeTest struct {
+	AppName string
+	// Command runs through the shell, with the instance's address exported as FLY_INSTANCE_ADDR
+	Command string
+	// HTTPPath is requested from the instance on Port, passing on a 2xx response
+	HTTPPath string
+	Port     int
+	// Timeout limits how long the whole smoke test may run, no limit when zero
+	Timeout time.Duration
+	// Dial connects to the instance's private address for the HTTP probe, through a WireGuard
+	// tunnel into the app's network
+	Dial   func(ctx context.Context, network, addr string) (net.Conn, error)
+	Out    io.Writer
+	ErrOut io.Writer
+}
+
+func (s *SmokeTest) Name() string {
+	return "smoke test"
+}
+
+func (s *SmokeTest) Ready(d *api.DeploymentStatus) *api.AllocationStatus {
+	return FirstHealthyInstance(d)
+}
+
+// Check probes the instance over HTTP when HTTPPath is set, then runs the command with the
+// instance's details exported as FLY_APP, FLY_RELEASE_VERSION, FLY_INSTANCE_ID,
+// FLY_INSTANCE_REGION, FLY_INSTANCE_IP and FLY_INSTANCE_ADDR
+func (s *SmokeTest) Check(ctx context.Context, d *api.DeploymentStatus, instance *api.AllocationStatus) error {
+	if s.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.Timeout)
+		defer cancel()
+	}
+
+	if s.HTTPPath != "" {
+		if err := s.probe(ctx, instance); err != nil {
+			return err
+		}
+	}
+
+	if s.Command == "" {
+		return nil
+	}
+	env := []string{
+		"FLY_APP=" + s.AppName,
+		"FLY_RELEASE_VERSION=" + strconv.Itoa(d.Version),
+		"FLY_INSTANCE_ID=" + instance.ID,
+		"FLY_INSTANCE_REGION=" + instance.Region,
+		"FLY_INSTANCE_IP=" + instance.PrivateIP,
+		"FLY_INSTANCE_ADDR=" + s.addr(instance),
+	}
+	return runGateCommand(ctx, "smoke test", s.Command, s.Timeout, env, s.Out, s.ErrOut)
+}
+
+func (s *SmokeTest) addr(instance *api.AllocationStatus) string {
+	return net.JoinHostPort(instance.PrivateIP, strconv.Itoa(s.Port))
+}
+
+func (s *SmokeTest) probe(ctx context.Context, instance *api.AllocationStatus) error {
+	path := s.HTTPPath
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	url := "http://" + s.addr(instance) + path
+
+	client := &http.Client{
+		Transport: &http.Transport{DialContext: s.Dial},
+		Timeout:   10 * time.Second,
+	}
+
+	var err error
+	for attempt := 1; attempt <= smokeTestAttempts; attempt++ {
+		if err = probeOnce(ctx, client, url); err == nil {
+			return nil
+		}
+		if attempt == smokeTestAttempts {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("smoke test GET %s failed: %s", path, ctx.Err())
+		case <-time.After(smokeTestRetryInterval):
+		}
+	}
+	return fmt.Errorf("smoke test GET %s failed: %s", path, err)
+}
+
+func probeOnce(ctx context.Context, client *http.Client, url string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("got status %s", resp.Status)
+	}
+	return nil
+}
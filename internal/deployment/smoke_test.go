@@ -0,0 +1,78 @@
+package deployment
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/superfly/flyctl/api"
+)
+
+func TestFirstHealthyInstance(t *testing.T) {
+	d := &api.DeploymentStatus{Version: 3, Allocations: []*api.AllocationStatus{
+		{ID: "old", Version: 2, Healthy: true},
+		{ID: "new", Version: 3, Healthy: true, CriticalCheckCount: 1},
+	}}
+	assert.Nil(t, FirstHealthyInstance(d))
+
+	d.Allocations[1].CriticalCheckCount = 0
+	assert.Equal(t, "new", FirstHealthyInstance(d).ID)
+}
+
+func TestSmokeTestCommand(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+
+	d := &api.DeploymentStatus{Version: 3}
+	instance := &api.AllocationStatus{ID: "abc123", Region: "ord", PrivateIP: "fdaa::3"}
+
+	var out bytes.Buffer
+	s := &SmokeTest{AppName: "test-app", Port: 8080, Command: "echo $FLY_APP v$FLY_RELEASE_VERSION $FLY_INSTANCE_ID $FLY_INSTANCE_REGION $FLY_INSTANCE_ADDR", Out: &out}
+	assert.NoError(t, s.Check(context.Background(), d, instance))
+	assert.Equal(t, "test-app v3 abc123 ord [fdaa::3]:8080\n", out.String())
+
+	s.Command = "exit 1"
+	assert.Error(t, s.Check(context.Background(), d, instance))
+}
+
+func TestSmokeTestHTTP(t *testing.T) {
+	defer func(interval time.Duration) { smokeTestRetryInterval = interval }(smokeTestRetryInterval)
+	smokeTestRetryInterval = time.Millisecond
+
+	var paths []string
+	status := http.StatusServiceUnavailable
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+
+	// the instance's private address isn't reachable here, so every dial goes to the test server
+	var dialed []string
+	dial := func(ctx context.Context, network, addr string) (net.Conn, error) {
+		dialed = append(dialed, addr)
+		return (&net.Dialer{}).DialContext(ctx, network, srv.Listener.Addr().String())
+	}
+
+	d := &api.DeploymentStatus{Version: 3}
+	instance := &api.AllocationStatus{ID: "abc123", PrivateIP: "fdaa::3"}
+	s := &SmokeTest{HTTPPath: "healthz", Port: p, Dial: dial}
+
+	assert.EqualError(t, s.Check(context.Background(), d, instance), "smoke test GET /healthz failed: got status 503 Service Unavailable")
+	assert.Equal(t, []string{"/healthz", "/healthz", "/healthz"}, paths)
+	assert.Equal(t, "[fdaa::3]:"+port, dialed[0])
+
+	status = http.StatusOK
+	assert.NoError(t, s.Check(context.Background(), d, instance))
+}
@@ -0,0 +1,120 @@
+package dnsprovider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+const cloudflareAPI = "https://api.cloudflare.com/client/v4"
+
+type cloudflare struct {
+	token   string
+	baseURL string
+	client  *http.Client
+}
+
+func newCloudflare() (*cloudflare, error) {
+	token := os.Getenv("CLOUDFLARE_API_TOKEN")
+	if token == "" {
+		return nil, errors.New("set CLOUDFLARE_API_TOKEN to an API token with the Zone DNS edit permission")
+	}
+	return &cloudflare{token: token, baseURL: cloudflareAPI, client: defaultClient()}, nil
+}
+
+func (c *cloudflare) UpsertRecord(ctx context.Context, r Record) error {
+	zone, err := c.zoneID(ctx, r.Name)
+	if err != nil {
+		return err
+	}
+
+	var existing []struct {
+		ID string `json:"id"`
+	}
+	path := fmt.Sprintf("/zones/%s/dns_records?type=%s&name=%s", zone, r.Type, url.QueryEscape(r.Name))
+	if err := c.do(ctx, http.MethodGet, path, nil, &existing); err != nil {
+		return err
+	}
+
+	// proxied records answer with cloudflare's addresses, which fail validation
+	body := map[string]interface{}{
+		"type":    r.Type,
+		"name":    r.Name,
+		"content": r.Value,
+		"ttl":     ttl,
+		"proxied": false,
+	}
+	if len(existing) > 0 {
+		return c.do(ctx, http.MethodPut, fmt.Sprintf("/zones/%s/dns_records/%s", zone, existing[0].ID), body, nil)
+	}
+	return c.do(ctx, http.MethodPost, fmt.Sprintf("/zones/%s/dns_records", zone), body, nil)
+}
+
+func (c *cloudflare) zoneID(ctx context.Context, name string) (string, error) {
+	for _, candidate := range zoneCandidates(name) {
+		var zones []struct {
+			ID string `json:"id"`
+		}
+		if err := c.do(ctx, http.MethodGet, "/zones?name="+url.QueryEscape(candidate), nil, &zones); err != nil {
+			return "", err
+		}
+		if len(zones) > 0 {
+			return zones[0].ID, nil
+		}
+	}
+	return "", fmt.Errorf("no cloudflare zone holding %s is visible to the API token", name)
+}
+
+func (c *cloudflare) do(ctx context.Context, method string, path string, body interface{}, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var data struct {
+		Success bool
+		Errors  []struct {
+			Code    int
+			Message string
+		}
+		Result json.RawMessage
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return errors.Wrapf(err, "cloudflare %s %s returned %s", method, path, resp.Status)
+	}
+	if !data.Success {
+		if len(data.Errors) > 0 {
+			return fmt.Errorf("cloudflare: %s (%d)", data.Errors[0].Message, data.Errors[0].Code)
+		}
+		return fmt.Errorf("cloudflare %s %s returned %s", method, path, resp.Status)
+	}
+
+	if result != nil {
+		return json.Unmarshal(data.Result, result)
+	}
+	return nil
+}
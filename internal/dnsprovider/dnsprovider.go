@@ -0,0 +1,74 @@
+// Package dnsprovider creates DNS records through the APIs of DNS hosts, so a certificate's
+// records don't have to be copied into the host's dashboard by hand
+package dnsprovider
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/net/publicsuffix"
+)
+
+// Names lists the supported providers
+var Names = []string{"cloudflare", "route53", "gcloud"}
+
+// ttl is short so mistakes in the records are corrected quickly
+const ttl = 300
+
+type Record struct {
+	// Type is A, AAAA or CNAME
+	Type string
+	// Name is the fully qualified name, without a trailing dot
+	Name  string
+	Value string
+}
+
+func (r Record) String() string {
+	return fmt.Sprintf("%s %s %s", r.Type, r.Name, r.Value)
+}
+
+type Provider interface {
+	// UpsertRecord creates the record in the zone holding its name, or replaces the value of a
+	// record with the same name and type
+	UpsertRecord(ctx context.Context, r Record) error
+}
+
+// New returns the named provider, with credentials from the environment
+func New(name string) (Provider, error) {
+	switch name {
+	case "cloudflare":
+		return newCloudflare()
+	case "route53":
+		return newRoute53()
+	case "gcloud":
+		return newGcloud()
+	}
+	return nil, fmt.Errorf("unknown DNS provider %s, use one of %s", name, strings.Join(Names, ", "))
+}
+
+func defaultClient() *http.Client {
+	return &http.Client{Timeout: 30 * time.Second}
+}
+
+// zoneCandidates returns name and its parents up to its registered domain, the names of the zones
+// that could hold it, most specific first
+func zoneCandidates(name string) []string {
+	name = strings.TrimSuffix(name, ".")
+	root, err := publicsuffix.EffectiveTLDPlusOne(name)
+	if err != nil {
+		return []string{name}
+	}
+
+	var candidates []string
+	for {
+		candidates = append(candidates, name)
+		i := strings.Index(name, ".")
+		if name == root || i < 0 {
+			return candidates
+		}
+		name = name[i+1:]
+	}
+}
@@ -0,0 +1,102 @@
+package dnsprovider
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/superfly/flyctl/internal/awsauth"
+)
+
+func TestZoneCandidates(t *testing.T) {
+	assert.Equal(t, []string{"_acme-challenge.www.example.com", "www.example.com", "example.com"}, zoneCandidates("_acme-challenge.www.example.com"))
+	assert.Equal(t, []string{"example.co.uk"}, zoneCandidates("example.co.uk."))
+}
+
+func TestCloudflareUpsertRecord(t *testing.T) {
+	var created map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/zones":
+			// only the registered domain is a zone
+			if r.URL.Query().Get("name") == "example.com" {
+				w.Write([]byte(`{"success": true, "result": [{"id": "zone1"}]}`))
+				return
+			}
+			w.Write([]byte(`{"success": true, "result": []}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/zones/zone1/dns_records":
+			assert.Equal(t, "_acme-challenge.www.example.com", r.URL.Query().Get("name"))
+			w.Write([]byte(`{"success": true, "result": []}`))
+		case r.Method == http.MethodPost && r.URL.Path == "/zones/zone1/dns_records":
+			json.NewDecoder(r.Body).Decode(&created)
+			w.Write([]byte(`{"success": true, "result": {}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"success": false, "errors": [{"code": 7003, "message": "no route"}]}`))
+		}
+	}))
+	defer server.Close()
+
+	c := &cloudflare{token: "token", baseURL: server.URL, client: server.Client()}
+	err := c.UpsertRecord(context.Background(), Record{Type: "CNAME", Name: "_acme-challenge.www.example.com", Value: "www.example.com.abc.flydns.net"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "CNAME", created["type"])
+	assert.Equal(t, "www.example.com.abc.flydns.net", created["content"])
+	assert.Equal(t, false, created["proxied"])
+}
+
+func TestCloudflareError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"success": false, "errors": [{"code": 9109, "message": "Invalid access token"}]}`))
+	}))
+	defer server.Close()
+
+	c := &cloudflare{token: "token", baseURL: server.URL, client: server.Client()}
+	err := c.UpsertRecord(context.Background(), Record{Type: "CNAME", Name: "www.example.com", Value: "app.fly.dev"})
+
+	assert.EqualError(t, err, "cloudflare: Invalid access token (9109)")
+}
+
+func TestRoute53UpsertRecord(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/"))
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/hostedzonesbyname":
+			// a private zone of the same name is skipped
+			w.Write([]byte(`<ListHostedZonesByNameResponse><HostedZones>
+				<HostedZone><Id>/hostedzone/PRIVATE</Id><Name>example.com.</Name><Config><PrivateZone>true</PrivateZone></Config></HostedZone>
+				<HostedZone><Id>/hostedzone/Z1</Id><Name>example.com.</Name><Config><PrivateZone>false</PrivateZone></Config></HostedZone>
+			</HostedZones></ListHostedZonesByNameResponse>`))
+		case r.Method == http.MethodPost && r.URL.Path == "/hostedzone/Z1/rrset":
+			data, _ := io.ReadAll(r.Body)
+			body = string(data)
+			w.Write([]byte(`<ChangeResourceRecordSetsResponse/>`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`<ErrorResponse><Error><Code>NoSuchHostedZone</Code><Message>no zone</Message></Error></ErrorResponse>`))
+		}
+	}))
+	defer server.Close()
+
+	r := &route53{
+		creds:   awsauth.Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"},
+		baseURL: server.URL,
+		client:  server.Client(),
+		now:     time.Now,
+	}
+	err := r.UpsertRecord(context.Background(), Record{Type: "CNAME", Name: "example.com", Value: "app.fly.dev"})
+
+	assert.NoError(t, err)
+	assert.Contains(t, body, `<ChangeResourceRecordSetsRequest xmlns="https://route53.amazonaws.com/doc/2013-04-01/">`)
+	assert.Contains(t, body, `<Change><Action>UPSERT</Action><ResourceRecordSet><Name>example.com.</Name><Type>CNAME</Type><TTL>300</TTL><ResourceRecords><ResourceRecord><Value>app.fly.dev</Value></ResourceRecord></ResourceRecords></ResourceRecordSet></Change>`)
+}
@@ -0,0 +1,161 @@
+package dnsprovider
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const gcloudAPI = "https://dns.googleapis.com/dns/v1"
+
+type gcloud struct {
+	project string
+	token   string
+	baseURL string
+	client  *http.Client
+}
+
+type gcloudRecordSet struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	TTL     int      `json:"ttl"`
+	RRDatas []string `json:"rrdatas"`
+}
+
+// newGcloud takes the access token and project from the environment, or from the gcloud CLI's
+// logged in account and configuration
+func newGcloud() (*gcloud, error) {
+	token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN")
+	if token == "" {
+		var err error
+		if token, err = gcloudOutput("auth", "print-access-token"); err != nil {
+			return nil, err
+		}
+	}
+
+	project := os.Getenv("GOOGLE_CLOUD_PROJECT")
+	if project == "" {
+		project = os.Getenv("CLOUDSDK_CORE_PROJECT")
+	}
+	if project == "" {
+		var err error
+		if project, err = gcloudOutput("config", "get-value", "project"); err != nil {
+			return nil, err
+		}
+	}
+	if project == "" {
+		return nil, errors.New("set GOOGLE_CLOUD_PROJECT to the project holding the Cloud DNS zone")
+	}
+
+	return &gcloud{project: project, token: token, baseURL: gcloudAPI, client: defaultClient()}, nil
+}
+
+func gcloudOutput(args ...string) (string, error) {
+	out, err := exec.Command("gcloud", args...).Output()
+	if err != nil {
+		return "", errors.Wrapf(err, "gcloud %s failed, log in with gcloud auth login or set GOOGLE_OAUTH_ACCESS_TOKEN", strings.Join(args, " "))
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+func (g *gcloud) UpsertRecord(ctx context.Context, r Record) error {
+	zone, err := g.zoneName(ctx, r.Name)
+	if err != nil {
+		return err
+	}
+
+	// cloud DNS wants fully qualified names, with the trailing dot, in CNAME values too
+	value := r.Value
+	if r.Type == "CNAME" && !strings.HasSuffix(value, ".") {
+		value += "."
+	}
+	name := r.Name + "."
+
+	var existing struct {
+		RRSets []gcloudRecordSet `json:"rrsets"`
+	}
+	path := fmt.Sprintf("/projects/%s/managedZones/%s/rrsets?name=%s&type=%s", g.project, zone, url.QueryEscape(name), r.Type)
+	if err := g.do(ctx, http.MethodGet, path, nil, &existing); err != nil {
+		return err
+	}
+
+	change := map[string]interface{}{
+		"additions": []gcloudRecordSet{{Name: name, Type: r.Type, TTL: ttl, RRDatas: []string{value}}},
+	}
+	if len(existing.RRSets) > 0 {
+		change["deletions"] = existing.RRSets
+	}
+	return g.do(ctx, http.MethodPost, fmt.Sprintf("/projects/%s/managedZones/%s/changes", g.project, zone), change, nil)
+}
+
+func (g *gcloud) zoneName(ctx context.Context, name string) (string, error) {
+	for _, candidate := range zoneCandidates(name) {
+		var result struct {
+			ManagedZones []struct {
+				Name       string `json:"name"`
+				Visibility string `json:"visibility"`
+			} `json:"managedZones"`
+		}
+		path := fmt.Sprintf("/projects/%s/managedZones?dnsName=%s", g.project, url.QueryEscape(candidate+"."))
+		if err := g.do(ctx, http.MethodGet, path, nil, &result); err != nil {
+			return "", err
+		}
+		for _, zone := range result.ManagedZones {
+			if zone.Visibility != "private" {
+				return zone.Name, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no cloud DNS zone holding %s in project %s", name, g.project)
+}
+
+func (g *gcloud) do(ctx context.Context, method string, path string, body interface{}, result interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, g.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+g.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		var failure struct {
+			Error struct {
+				Code    int
+				Message string
+			}
+		}
+		if json.NewDecoder(resp.Body).Decode(&failure) == nil && failure.Error.Message != "" {
+			return fmt.Errorf("cloud DNS: %s (%d)", failure.Error.Message, failure.Error.Code)
+		}
+		return fmt.Errorf("cloud DNS %s %s returned %s", method, path, resp.Status)
+	}
+
+	if result != nil {
+		return json.NewDecoder(resp.Body).Decode(result)
+	}
+	return nil
+}
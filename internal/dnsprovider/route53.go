@@ -0,0 +1,132 @@
+package dnsprovider
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/superfly/flyctl/internal/awsauth"
+)
+
+const route53API = "https://route53.amazonaws.com/2013-04-01"
+
+type route53 struct {
+	creds   awsauth.Credentials
+	baseURL string
+	client  *http.Client
+	now     func() time.Time
+}
+
+// newRoute53 takes credentials allowed to change the hosted zone's records from the environment,
+// or from the aws CLI
+func newRoute53() (*route53, error) {
+	creds, err := awsauth.LoadCredentials(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	return &route53{creds: creds, baseURL: route53API, client: defaultClient(), now: time.Now}, nil
+}
+
+type route53ChangeRequest struct {
+	XMLName xml.Name        `xml:"https://route53.amazonaws.com/doc/2013-04-01/ ChangeResourceRecordSetsRequest"`
+	Changes []route53Change `xml:"ChangeBatch>Changes>Change"`
+}
+
+type route53Change struct {
+	Action string
+	Name   string `xml:"ResourceRecordSet>Name"`
+	Type   string `xml:"ResourceRecordSet>Type"`
+	TTL    int    `xml:"ResourceRecordSet>TTL"`
+	Value  string `xml:"ResourceRecordSet>ResourceRecords>ResourceRecord>Value"`
+}
+
+func (r *route53) UpsertRecord(ctx context.Context, record Record) error {
+	zone, err := r.zoneID(ctx, record.Name)
+	if err != nil {
+		return err
+	}
+
+	body, err := xml.Marshal(route53ChangeRequest{
+		Changes: []route53Change{{
+			Action: "UPSERT",
+			Name:   record.Name + ".",
+			Type:   record.Type,
+			TTL:    ttl,
+			Value:  record.Value,
+		}},
+	})
+	if err != nil {
+		return err
+	}
+
+	return r.do(ctx, http.MethodPost, zone+"/rrset", append([]byte(xml.Header), body...), nil)
+}
+
+// zoneID returns the /hostedzone/<id> path of the public zone holding name
+func (r *route53) zoneID(ctx context.Context, name string) (string, error) {
+	for _, candidate := range zoneCandidates(name) {
+		var result struct {
+			HostedZones []struct {
+				ID          string `xml:"Id"`
+				Name        string
+				PrivateZone bool `xml:"Config>PrivateZone"`
+			} `xml:"HostedZones>HostedZone"`
+		}
+		path := fmt.Sprintf("/hostedzonesbyname?dnsname=%s&maxitems=10", url.QueryEscape(candidate))
+		if err := r.do(ctx, http.MethodGet, path, nil, &result); err != nil {
+			return "", err
+		}
+		for _, zone := range result.HostedZones {
+			if strings.TrimSuffix(zone.Name, ".") == candidate && !zone.PrivateZone {
+				return zone.ID, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("no route53 hosted zone holding %s is visible to the AWS credentials", name)
+}
+
+func (r *route53) do(ctx context.Context, method string, path string, body []byte, result interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, method, r.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/xml")
+	}
+	awsauth.Sign(req, body, r.creds, "us-east-1", "route53", r.now())
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode >= 300 {
+		var failure struct {
+			Error struct {
+				Code    string
+				Message string
+			}
+		}
+		if xml.Unmarshal(data, &failure) == nil && failure.Error.Message != "" {
+			return fmt.Errorf("route53: %s (%s)", failure.Error.Message, failure.Error.Code)
+		}
+		return fmt.Errorf("route53 %s %s returned %s", method, path, resp.Status)
+	}
+
+	if result != nil {
+		return xml.Unmarshal(data, result)
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+// Package dockerconfig reads registry credentials saved by `docker login`, including ones kept
+// by credential helpers like osxkeychain or ecr-login
+package dockerconfig
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// dockerHubServer is the name docker login and credential helpers use for docker hub
+const dockerHubServer = "https://index.docker.io/v1/"
+
+type authEntry struct {
+	Auth          string `json:"auth"`
+	Username      string `json:"username"`
+	Password      string `json:"password"`
+	IdentityToken string `json:"identitytoken"`
+}
+
+// Config is the subset of ~/.docker/config.json holding credentials
+type Config struct {
+	Auths map[string]authEntry `json:"auths"`
+	// CredsStore is the helper storing credentials for every registry, and CredHelpers
+	// overrides it for specific registries
+	CredsStore  string            `json:"credsStore"`
+	CredHelpers map[string]string `json:"credHelpers"`
+}
+
+// Credential is what's needed to log in to a registry. IdentityToken is set instead of a
+// password for registries that use OAuth refresh tokens.
+type Credential struct {
+	ServerAddress string
+	Username      string
+	Password      string
+	IdentityToken string
+}
+
+// Path returns the location of the docker config file, honoring DOCKER_CONFIG like the docker CLI does
+func Path() string {
+	dir := os.Getenv("DOCKER_CONFIG")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".docker")
+	}
+	return filepath.Join(dir, "config.json")
+}
+
+// Load reads the docker config file. A missing file is treated as having no credentials.
+func Load() (*Config, error) {
+	cfg := &Config{Auths: map[string]authEntry{}}
+
+	path := Path()
+	if path == "" {
+		return cfg, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	} else if err != nil {
+		return nil, errors.Wrap(err, "error reading docker config")
+	}
+
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, errors.Wrapf(err, "error parsing %s", path)
+	}
+
+	return cfg, nil
+}
+
+// Credentials returns the username and password saved for a registry host
+func (c *Config) Credentials(host string) (username, password string, ok bool) {
+	cred, ok := c.Credential(host)
+	if !ok {
+		return "", "", false
+	}
+	if cred.IdentityToken != "" {
+		return cred.Username, cred.IdentityToken, true
+	}
+	return cred.Username, cred.Password, true
+}
+
+// Credential looks up a registry host, asking the credential helper first like the docker CLI does
+func (c *Config) Credential(host string) (Credential, bool) {
+	host = normalizeHost(host)
+
+	if helper := c.helperFor(host); helper != "" {
+		if cred, err := helperGet(helper, serverAddress(host)); err == nil {
+			return cred, true
+		}
+	}
+
+	for key, entry := range c.Auths {
+		if normalizeHost(key) != host {
+			continue
+		}
+		if cred, ok := entry.credential(key); ok {
+			return cred, true
+		}
+	}
+
+	return Credential{}, false
+}
+
+// All returns credentials for every registry the docker config knows about, keyed by server address.
+// Registries whose helper fails are skipped, so one locked keychain doesn't break every build.
+func (c *Config) All() map[string]Credential {
+	all := map[string]Credential{}
+
+	for key, entry := range c.Auths {
+		if cred, ok := entry.credential(key); ok {
+			all[key] = cred
+		}
+	}
+
+	if c.CredsStore != "" {
+		servers, err := helperList(c.CredsStore)
+		if err == nil {
+			for server := range servers {
+				if cred, err := helperGet(c.CredsStore, server); err == nil {
+					all[server] = cred
+				}
+			}
+		}
+	}
+
+	for host, helper := range c.CredHelpers {
+		if cred, err := helperGet(helper, host); err == nil {
+			all[host] = cred
+		}
+	}
+
+	return all
+}
+
+func (e authEntry) credential(server string) (Credential, bool) {
+	cred := Credential{ServerAddress: server, Username: e.Username, Password: e.Password, IdentityToken: e.IdentityToken}
+
+	if e.Auth != "" {
+		decoded, err := base64.StdEncoding.DecodeString(e.Auth)
+		if err != nil {
+			return Credential{}, false
+		}
+		parts := strings.SplitN(string(decoded), ":", 2)
+		if len(parts) != 2 {
+			return Credential{}, false
+		}
+		cred.Username, cred.Password = parts[0], parts[1]
+	}
+
+	return cred, cred.Username != "" || cred.IdentityToken != ""
+}
+
+func (c *Config) helperFor(host string) string {
+	for key, helper := range c.CredHelpers {
+		if normalizeHost(key) == host {
+			return helper
+		}
+	}
+	return c.CredsStore
+}
+
+// execHelper runs a docker-credential-<helper> program, replaced in tests
+var execHelper = func(helper, action, input string) ([]byte, error) {
+	cmd := exec.Command("docker-credential-"+helper, action)
+	cmd.Stdin = strings.NewReader(input)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		// helpers print errors like "credentials not found in native keychain" to stdout
+		msg := strings.TrimSpace(string(out) + stderr.String())
+		return nil, fmt.Errorf("docker-credential-%s %s: %s", helper, action, msg)
+	}
+	return out, nil
+}
+
+func helperGet(helper, server string) (Credential, error) {
+	out, err := execHelper(helper, "get", server)
+	if err != nil {
+		return Credential{}, err
+	}
+
+	var resp struct {
+		ServerURL string
+		Username  string
+		Secret    string
+	}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return Credential{}, errors.Wrapf(err, "error parsing docker-credential-%s output", helper)
+	}
+
+	cred := Credential{ServerAddress: server, Username: resp.Username, Password: resp.Secret}
+	// helpers store identity tokens with this username
+	if resp.Username == "<token>" {
+		cred.Username, cred.Password, cred.IdentityToken = "", "", resp.Secret
+	}
+
+	return cred, nil
+}
+
+func helperList(helper string) (map[string]string, error) {
+	out, err := execHelper(helper, "list", "")
+	if err != nil {
+		return nil, err
+	}
+
+	servers := map[string]string{}
+	if err := json.Unmarshal(out, &servers); err != nil {
+		return nil, errors.Wrapf(err, "error parsing docker-credential-%s output", helper)
+	}
+	return servers, nil
+}
+
+// serverAddress is the name a host is saved under by docker login
+func serverAddress(host string) string {
+	if host == "index.docker.io" {
+		return dockerHubServer
+	}
+	return host
+}
+
+// normalizeHost reduces config keys like https://ghcr.io/v1/ and image hosts like ghcr.io to the same form
+func normalizeHost(host string) string {
+	host = strings.TrimPrefix(host, "https://")
+	host = strings.TrimPrefix(host, "http://")
+	if i := strings.Index(host, "/"); i >= 0 {
+		host = host[:i]
+	}
+
+	switch host {
+	case "docker.io", "index.docker.io", "registry-1.docker.io":
+		return "index.docker.io"
+	}
+	return host
+}
@@ -0,0 +1,69 @@
+package dockerconfig
+
+import (
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCredentials(t *testing.T) {
+	cfg := &Config{Auths: map[string]authEntry{
+		"https://index.docker.io/v1/": {Auth: base64.StdEncoding.EncodeToString([]byte("hubuser:hubpass"))},
+		"ghcr.io":                     {Username: "me", Password: "token"},
+	}}
+
+	user, pass, ok := cfg.Credentials("docker.io")
+	assert.True(t, ok)
+	assert.Equal(t, "hubuser", user)
+	assert.Equal(t, "hubpass", pass)
+
+	user, _, ok = cfg.Credentials("https://ghcr.io")
+	assert.True(t, ok)
+	assert.Equal(t, "me", user)
+
+	_, _, ok = cfg.Credentials("quay.io")
+	assert.False(t, ok)
+}
+
+func TestCredentialHelpers(t *testing.T) {
+	defer func(orig func(string, string, string) ([]byte, error)) { execHelper = orig }(execHelper)
+
+	execHelper = func(helper, action, input string) ([]byte, error) {
+		switch helper + " " + action + " " + input {
+		case "osxkeychain list ":
+			return []byte(`{"https://index.docker.io/v1/":"hubuser"}`), nil
+		case "osxkeychain get https://index.docker.io/v1/":
+			return []byte(`{"ServerURL":"https://index.docker.io/v1/","Username":"hubuser","Secret":"hubpass"}`), nil
+		case "ecr-login get 123.dkr.ecr.us-east-1.amazonaws.com":
+			return []byte(`{"Username":"AWS","Secret":"ecrtoken"}`), nil
+		}
+		return nil, errors.New("credentials not found in native keychain")
+	}
+
+	cfg := &Config{
+		Auths:       map[string]authEntry{"ghcr.io": {Username: "me", Password: "token"}},
+		CredsStore:  "osxkeychain",
+		CredHelpers: map[string]string{"123.dkr.ecr.us-east-1.amazonaws.com": "ecr-login"},
+	}
+
+	user, pass, ok := cfg.Credentials("docker.io")
+	assert.True(t, ok)
+	assert.Equal(t, "hubuser", user)
+	assert.Equal(t, "hubpass", pass)
+
+	user, pass, ok = cfg.Credentials("123.dkr.ecr.us-east-1.amazonaws.com")
+	assert.True(t, ok)
+	assert.Equal(t, "AWS", user)
+	assert.Equal(t, "ecrtoken", pass)
+
+	// not in the keychain, falls back to the config file
+	user, _, ok = cfg.Credentials("ghcr.io")
+	assert.True(t, ok)
+	assert.Equal(t, "me", user)
+
+	all := cfg.All()
+	assert.Len(t, all, 3)
+	assert.Equal(t, "hubpass", all["https://index.docker.io/v1/"].Password)
+}
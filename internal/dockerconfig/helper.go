@@ -0,0 +1,134 @@
+package dockerconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// errCredentialsNotFound is the message docker expects from a helper without credentials for a server
+const errCredentialsNotFound = "credentials not found in native keychain"
+
+// SetCredHelper makes docker ask docker-credential-<helper> for host's credentials, dropping any
+// credentials saved for host by docker login. Settings flyctl doesn't know about are kept.
+func SetCredHelper(host, helper string) error {
+	path := Path()
+	if path == "" {
+		return errors.New("can't find the docker config directory, set DOCKER_CONFIG")
+	}
+
+	raw := map[string]json.RawMessage{}
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return errors.Wrap(err, "error reading docker config")
+	default:
+		if err := json.Unmarshal(data, &raw); err != nil {
+			return errors.Wrapf(err, "error parsing %s", path)
+		}
+	}
+
+	helpers := map[string]string{}
+	if v, ok := raw["credHelpers"]; ok {
+		if err := json.Unmarshal(v, &helpers); err != nil {
+			return errors.Wrapf(err, "error parsing credHelpers in %s", path)
+		}
+	}
+	helpers[host] = helper
+	if raw["credHelpers"], err = json.Marshal(helpers); err != nil {
+		return err
+	}
+
+	if v, ok := raw["auths"]; ok {
+		auths := map[string]json.RawMessage{}
+		if err := json.Unmarshal(v, &auths); err != nil {
+			return errors.Wrapf(err, "error parsing auths in %s", path)
+		}
+		for key := range auths {
+			if normalizeHost(key) == normalizeHost(host) {
+				delete(auths, key)
+			}
+		}
+		if raw["auths"], err = json.Marshal(auths); err != nil {
+			return err
+		}
+	}
+
+	out, err := json.MarshalIndent(raw, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	// written beside the config and renamed over it so docker never reads half a file
+	tmp, err := ioutil.TempFile(filepath.Dir(path), "config.json.")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(out, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// ServeHelper answers a docker credential helper request, the way a docker-credential-<name>
+// program does: action is get, list, store or erase, with the request read from in and the
+// response written to out. lookup returns the credential for a server. Credentials can't be
+// stored or erased, they follow the credential lookup returns.
+func ServeHelper(action string, in io.Reader, out io.Writer, servers []string, lookup func(server string) (Credential, bool)) error {
+	switch action {
+	case "get":
+		data, err := io.ReadAll(in)
+		if err != nil {
+			return err
+		}
+		server := strings.TrimSpace(string(data))
+
+		cred, ok := lookup(normalizeHost(server))
+		if !ok {
+			return errors.New(errCredentialsNotFound)
+		}
+
+		username, secret := cred.Username, cred.Password
+		if cred.IdentityToken != "" {
+			username, secret = "<token>", cred.IdentityToken
+		}
+		return json.NewEncoder(out).Encode(map[string]string{
+			"ServerURL": server,
+			"Username":  username,
+			"Secret":    secret,
+		})
+	case "list":
+		list := map[string]string{}
+		for _, server := range servers {
+			if cred, ok := lookup(server); ok {
+				list[server] = cred.Username
+			}
+		}
+		return json.NewEncoder(out).Encode(list)
+	case "store", "erase":
+		// docker stores credentials after docker login, which isn't needed since they come from lookup
+		_, err := io.Copy(ioutil.Discard, in)
+		return err
+	default:
+		return fmt.Errorf("unknown credential helper action %s, expected get, list, store or erase", action)
+	}
+}
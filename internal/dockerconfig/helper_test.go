@@ -0,0 +1,84 @@
+package dockerconfig
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetCredHelper(t *testing.T) {
+	dir := t.TempDir()
+	os.Setenv("DOCKER_CONFIG", dir)
+	defer os.Unsetenv("DOCKER_CONFIG")
+
+	path := filepath.Join(dir, "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"auths": {"https://registry.fly.io": {"auth": "eDp0b2tlbg=="}, "ghcr.io": {"auth": "bWU6dG9rZW4="}},
+		"credHelpers": {"gcr.io": "gcloud"},
+		"psFormat": "table {{.ID}}"
+	}`), 0644))
+
+	require.NoError(t, SetCredHelper("registry.fly.io", "flyctl"))
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"gcr.io": "gcloud", "registry.fly.io": "flyctl"}, cfg.CredHelpers)
+	assert.Contains(t, cfg.Auths, "ghcr.io")
+	assert.NotContains(t, cfg.Auths, "https://registry.fly.io")
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var raw map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &raw))
+	assert.Equal(t, "table {{.ID}}", raw["psFormat"])
+
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+}
+
+func TestSetCredHelperNewConfig(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), ".docker")
+	os.Setenv("DOCKER_CONFIG", dir)
+	defer os.Unsetenv("DOCKER_CONFIG")
+
+	require.NoError(t, SetCredHelper("registry.fly.io", "flyctl"))
+
+	cfg, err := Load()
+	require.NoError(t, err)
+	assert.Equal(t, "flyctl", cfg.CredHelpers["registry.fly.io"])
+}
+
+func TestServeHelper(t *testing.T) {
+	lookup := func(server string) (Credential, bool) {
+		if server != "registry.fly.io" {
+			return Credential{}, false
+		}
+		return Credential{Username: "x", Password: "token"}, true
+	}
+	servers := []string{"registry.fly.io"}
+
+	var out bytes.Buffer
+	require.NoError(t, ServeHelper("get", strings.NewReader("https://registry.fly.io\n"), &out, servers, lookup))
+
+	var resp map[string]string
+	require.NoError(t, json.Unmarshal(out.Bytes(), &resp))
+	assert.Equal(t, map[string]string{"ServerURL": "https://registry.fly.io", "Username": "x", "Secret": "token"}, resp)
+
+	out.Reset()
+	err := ServeHelper("get", strings.NewReader("ghcr.io"), &out, servers, lookup)
+	assert.EqualError(t, err, errCredentialsNotFound)
+
+	out.Reset()
+	require.NoError(t, ServeHelper("list", strings.NewReader(""), &out, servers, lookup))
+	assert.JSONEq(t, `{"registry.fly.io": "x"}`, out.String())
+
+	assert.NoError(t, ServeHelper("erase", strings.NewReader("registry.fly.io"), &out, servers, lookup))
+	assert.Error(t, ServeHelper("delete", strings.NewReader(""), &out, servers, lookup))
+}
@@ -0,0 +1,110 @@
+// Package dotenv parses .env files: KEY=VALUE lines with optional export prefixes, comments, and
+// single, double or triple quoted values that can span lines.
+package dotenv
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var validKey = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.]*$`)
+
+// Parse returns the variables in data and the order their keys first appear in. Later
+// assignments to a key replace earlier ones.
+func Parse(data string) (map[string]string, []string, error) {
+	vars := map[string]string{}
+	var keys []string
+
+	lines := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		lineNo := i + 1
+		line := strings.TrimSpace(lines[i])
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		eq := strings.Index(line, "=")
+		if eq < 0 {
+			return nil, nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNo)
+		}
+		key := strings.TrimSpace(line[:eq])
+		if !validKey.MatchString(key) {
+			return nil, nil, fmt.Errorf("line %d: invalid key \"%s\"", lineNo, key)
+		}
+		rest := strings.TrimLeft(line[eq+1:], " \t")
+
+		var value string
+		switch {
+		case strings.HasPrefix(rest, `"""`):
+			v, end, err := readQuoted(lines, i, rest[3:], `"""`, false)
+			if err != nil {
+				return nil, nil, fmt.Errorf("line %d: %s %s", lineNo, key, err)
+			}
+			value, i = v, end
+		case strings.HasPrefix(rest, `"`):
+			v, end, err := readQuoted(lines, i, rest[1:], `"`, true)
+			if err != nil {
+				return nil, nil, fmt.Errorf("line %d: %s %s", lineNo, key, err)
+			}
+			value, i = v, end
+		case strings.HasPrefix(rest, `'`):
+			v, end, err := readQuoted(lines, i, rest[1:], `'`, false)
+			if err != nil {
+				return nil, nil, fmt.Errorf("line %d: %s %s", lineNo, key, err)
+			}
+			value, i = v, end
+		default:
+			// unquoted values end at an inline comment
+			if c := strings.Index(rest, " #"); c >= 0 {
+				rest = rest[:c]
+			}
+			value = strings.TrimSpace(rest)
+		}
+
+		if _, ok := vars[key]; !ok {
+			keys = append(keys, key)
+		}
+		vars[key] = value
+	}
+
+	return vars, keys, nil
+}
+
+// readQuoted reads a value from after its opening quote on lines[start] up to the closing quote,
+// which may be on a later line. It returns the value and the line the quote closed on.
+func readQuoted(lines []string, start int, first string, quote string, escapes bool) (string, int, error) {
+	var b strings.Builder
+	text := first
+
+	for i := start; i < len(lines); i++ {
+		if i > start {
+			b.WriteString("\n")
+			text = lines[i]
+		}
+
+		for j := 0; j < len(text); j++ {
+			if escapes && text[j] == '\\' && j+1 < len(text) {
+				j++
+				switch text[j] {
+				case 'n':
+					b.WriteByte('\n')
+				case 'r':
+					b.WriteByte('\r')
+				case 't':
+					b.WriteByte('\t')
+				default:
+					b.WriteByte(text[j])
+				}
+				continue
+			}
+			if strings.HasPrefix(text[j:], quote) {
+				return b.String(), i, nil
+			}
+			b.WriteByte(text[j])
+		}
+	}
+
+	return "", 0, fmt.Errorf("has no closing %s", quote)
+}
@@ -0,0 +1,48 @@
+package dotenv
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	data := `# database
+export DATABASE_URL=postgres://user@host/db
+LOG_LEVEL = info # set to debug when needed
+EMPTY=
+SINGLE='literal $value \n'
+DOUBLE="tab\tand \"quotes\""
+PEM="-----BEGIN KEY-----
+abc
+-----END KEY-----"
+TRIPLE="""first
+second
+"""
+LOG_LEVEL=debug
+`
+	vars, keys, err := Parse(data)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"DATABASE_URL", "LOG_LEVEL", "EMPTY", "SINGLE", "DOUBLE", "PEM", "TRIPLE"}, keys)
+	assert.Equal(t, map[string]string{
+		"DATABASE_URL": "postgres://user@host/db",
+		"LOG_LEVEL":    "debug",
+		"EMPTY":        "",
+		"SINGLE":       `literal $value \n`,
+		"DOUBLE":       "tab\tand \"quotes\"",
+		"PEM":          "-----BEGIN KEY-----\nabc\n-----END KEY-----",
+		"TRIPLE":       "first\nsecond\n",
+	}, vars)
+}
+
+func TestParseErrors(t *testing.T) {
+	cases := map[string]string{
+		"NO_EQUALS":            "line 1: expected KEY=VALUE",
+		"1BAD=x":               "line 1: invalid key \"1BAD\"",
+		"A=1\nOPEN=\"unclosed": "line 2: OPEN has no closing \"",
+	}
+	for data, want := range cases {
+		_, _, err := Parse(data)
+		assert.EqualError(t, err, want, data)
+	}
+}
@@ -0,0 +1,229 @@
+// Package download fetches large files over HTTP in chunks, resuming partial downloads and
+// verifying the result against a checksum
+package download
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	DefaultChunkSize = 64 * 1024 * 1024
+	maxAttempts      = 5
+)
+
+// ErrURLExpired is returned by a chunk request when the server rejects the URL, so a fresh one can be requested
+var ErrURLExpired = errors.New("download url expired")
+
+type Options struct {
+	// URL is the location to download from. It's requested again through RefreshURL if it expires.
+	URL        string
+	RefreshURL func(ctx context.Context) (string, error)
+
+	// Path is where the file is written. Data is written to Path.partial until the download completes
+	// and the checksum matches, and an existing Path.partial is resumed from where it stopped.
+	Path   string
+	Size   int64
+	SHA256 string
+
+	ChunkSize int64
+	Client    *http.Client
+
+	// Progress is called after each write with the number of bytes downloaded so far
+	Progress func(done, total int64)
+}
+
+// ChecksumError is returned when the downloaded file doesn't match the expected checksum
+type ChecksumError struct {
+	Expected string
+	Actual   string
+}
+
+func (err *ChecksumError) Error() string {
+	return fmt.Sprintf("checksum mismatch, expected sha256 %s but got %s", err.Expected, err.Actual)
+}
+
+// PartialPath is where an incomplete download for path is kept
+func PartialPath(path string) string {
+	return path + ".partial"
+}
+
+// File downloads opts.URL to opts.Path. It returns the number of bytes that were already
+// present from an earlier attempt.
+func File(ctx context.Context, opts Options) (resumed int64, err error) {
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = DefaultChunkSize
+	}
+	if opts.Client == nil {
+		opts.Client = &http.Client{}
+	}
+
+	partial := PartialPath(opts.Path)
+
+	f, err := os.OpenFile(partial, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	// rehash what's already been downloaded so the checksum covers the whole file
+	h := sha256.New()
+	offset, err := io.Copy(h, f)
+	if err != nil {
+		return 0, errors.Wrap(err, "error reading partial download")
+	}
+	if opts.Size > 0 && offset > opts.Size {
+		// not a partial copy of this file, start over
+		if err := f.Truncate(0); err != nil {
+			return 0, err
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return 0, err
+		}
+		h.Reset()
+		offset = 0
+	}
+	resumed = offset
+
+	progress := func(n int64) {
+		if opts.Progress != nil {
+			opts.Progress(n, opts.Size)
+		}
+	}
+	progress(offset)
+
+	w := io.MultiWriter(f, h)
+	url := opts.URL
+
+	for opts.Size <= 0 || offset < opts.Size {
+		end := offset + opts.ChunkSize - 1
+		if opts.Size > 0 && end >= opts.Size {
+			end = opts.Size - 1
+		}
+
+		n, err := fetchChunkWithRetries(ctx, &opts, &url, w, offset, end, progress)
+		if err != nil {
+			return resumed, err
+		}
+		offset += n
+
+		// the server ran out of data before the size it was expected to have
+		if opts.Size > 0 && n == 0 {
+			return resumed, fmt.Errorf("file is shorter than reported, got %d of %d bytes", offset, opts.Size)
+		}
+
+		// without a known size, a short chunk is the end of the file
+		if opts.Size <= 0 && offset < end+1 {
+			break
+		}
+	}
+
+	if opts.SHA256 != "" {
+		if actual := hex.EncodeToString(h.Sum(nil)); actual != opts.SHA256 {
+			// the data is bad, don't resume from it next time
+			f.Close()
+			os.Remove(partial)
+			return resumed, &ChecksumError{Expected: opts.SHA256, Actual: actual}
+		}
+	}
+
+	if err := f.Close(); err != nil {
+		return resumed, err
+	}
+
+	return resumed, os.Rename(partial, opts.Path)
+}
+
+func fetchChunkWithRetries(ctx context.Context, opts *Options, url *string, w io.Writer, start, end int64, progress func(int64)) (int64, error) {
+	var written int64
+	var lastErr error
+
+	for attempt := 0; attempt < maxAttempts; attempt++ {
+		// an expired url is retried right away with the fresh one
+		if attempt > 0 && lastErr != ErrURLExpired {
+			select {
+			case <-ctx.Done():
+				return written, ctx.Err()
+			case <-time.After(time.Duration(attempt) * time.Second):
+			}
+		}
+
+		n, err := fetchChunk(ctx, opts.Client, *url, w, start+written, end, func(n int64) {
+			progress(start + written + n)
+		})
+		written += n
+		if err == nil {
+			return written, nil
+		}
+		if ctx.Err() != nil {
+			return written, ctx.Err()
+		}
+		lastErr = err
+
+		if err == ErrURLExpired {
+			if opts.RefreshURL == nil {
+				return written, err
+			}
+			fresh, err := opts.RefreshURL(ctx)
+			if err != nil {
+				return written, errors.Wrap(err, "error refreshing download url")
+			}
+			*url = fresh
+		}
+	}
+
+	return written, errors.Wrapf(lastErr, "giving up after %d attempts", maxAttempts)
+}
+
+// fetchChunk writes bytes start through end (inclusive) to w, returning how many were written
+func fetchChunk(ctx context.Context, client *http.Client, url string, w io.Writer, start, end int64, progress func(int64)) (int64, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+	case http.StatusRequestedRangeNotSatisfiable:
+		// asked for bytes past the end, nothing left to download
+		return 0, nil
+	case http.StatusOK:
+		if start > 0 {
+			return 0, errors.New("server does not support resuming downloads")
+		}
+	case http.StatusForbidden, http.StatusUnauthorized:
+		return 0, ErrURLExpired
+	default:
+		return 0, fmt.Errorf("unexpected response %s", resp.Status)
+	}
+
+	return io.Copy(w, &progressReader{r: resp.Body, fn: progress})
+}
+
+type progressReader struct {
+	r  io.Reader
+	n  int64
+	fn func(int64)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.n += int64(n)
+	p.fn(p.n)
+	return n, err
+}
@@ -0,0 +1,139 @@
+package download
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testServer(content []byte) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/expired" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		http.ServeContent(w, r, "snapshot", time.Time{}, bytes.NewReader(content))
+	}))
+}
+
+func testContent(size int) ([]byte, string) {
+	content := make([]byte, size)
+	rand.New(rand.NewSource(1)).Read(content)
+	sum := sha256.Sum256(content)
+	return content, hex.EncodeToString(sum[:])
+}
+
+func TestFileResumes(t *testing.T) {
+	content, sum := testContent(10000)
+	srv := testServer(content)
+	defer srv.Close()
+
+	dir, err := os.MkdirTemp("", "")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "snapshot.img.gz")
+	assert.NoError(t, os.WriteFile(PartialPath(path), content[:1000], 0644))
+
+	var lastProgress int64
+	resumed, err := File(context.Background(), Options{
+		URL:       srv.URL + "/snapshot",
+		Path:      path,
+		Size:      int64(len(content)),
+		SHA256:    sum,
+		ChunkSize: 4096,
+		Progress:  func(done, total int64) { lastProgress = done },
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1000), resumed)
+	assert.Equal(t, int64(len(content)), lastProgress)
+
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, content, data)
+
+	_, err = os.Stat(PartialPath(path))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFileChecksumMismatch(t *testing.T) {
+	content, _ := testContent(5000)
+	srv := testServer(content)
+	defer srv.Close()
+
+	dir, err := os.MkdirTemp("", "")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "snapshot.img.gz")
+	_, err = File(context.Background(), Options{
+		URL:    srv.URL + "/snapshot",
+		Path:   path,
+		Size:   int64(len(content)),
+		SHA256: "0000",
+	})
+	assert.IsType(t, &ChecksumError{}, err)
+
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+	_, err = os.Stat(PartialPath(path))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestFileRefreshesExpiredURL(t *testing.T) {
+	content, sum := testContent(3000)
+	srv := testServer(content)
+	defer srv.Close()
+
+	dir, err := os.MkdirTemp("", "")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	refreshed := 0
+	_, err = File(context.Background(), Options{
+		URL: srv.URL + "/expired",
+		RefreshURL: func(ctx context.Context) (string, error) {
+			refreshed++
+			return srv.URL + "/snapshot", nil
+		},
+		Path:      filepath.Join(dir, "snapshot"),
+		Size:      int64(len(content)),
+		SHA256:    sum,
+		ChunkSize: 1024,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, refreshed)
+}
+
+func TestFileShorterThanSize(t *testing.T) {
+	content, _ := testContent(1000)
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.ServeContent(w, r, "snapshot", time.Time{}, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+
+	dir, err := os.MkdirTemp("", "")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	_, err = File(context.Background(), Options{
+		URL:       srv.URL + "/snapshot",
+		Path:      filepath.Join(dir, "snapshot.img.gz"),
+		Size:      5000,
+		ChunkSize: 512,
+	})
+	assert.EqualError(t, err, "file is shorter than reported, got 1000 of 5000 bytes")
+	assert.Equal(t, 3, requests)
+}
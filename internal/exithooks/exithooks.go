@@ -0,0 +1,178 @@
+// Package exithooks runs local commands configured in the flyctl config file after flyctl commands
+// finish, for chaining tools like smoke tests after a deploy.
+package exithooks
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"github.com/superfly/flyctl/internal/shellcmd"
+)
+
+// ConfigKey is where hooks are configured in the flyctl config file
+const ConfigKey = "exit_hooks"
+
+// DisableEnv turns off all hooks when set to 1, like for a CI run
+const DisableEnv = "FLY_NO_EXIT_HOOKS"
+
+const (
+	WhenSuccess = "success"
+	WhenFailure = "failure"
+	WhenAlways  = "always"
+)
+
+// Config is the exit_hooks section of the flyctl config file:
+//
+//	exit_hooks:
+//	  deny: ["ssh console"]
+//	  hooks:
+//	    - after: deploy
+//	      run: ./scripts/smoke-test.sh
+type Config struct {
+	// Allow lists the commands that may run hooks, all of them when empty
+	Allow []string `mapstructure:"allow"`
+	// Deny lists commands that never run hooks, even when allowed
+	Deny  []string `mapstructure:"deny"`
+	Hooks []Hook   `mapstructure:"hooks"`
+}
+
+// Hook is a shell command run after a flyctl command
+type Hook struct {
+	// After is the flyctl command, like deploy or "ssh console"
+	After string `mapstructure:"after"`
+	// When is success, failure or always. Defaults to success.
+	When string `mapstructure:"when"`
+	Run  string `mapstructure:"run"`
+	// Timeout kills the hook after a duration like 5m, no limit when empty
+	Timeout string `mapstructure:"timeout"`
+}
+
+// Result is how a flyctl command finished, passed to hooks as FLY_ environment variables
+type Result struct {
+	Command string
+	AppName string
+	Err     error
+	// Values are command specific results like the release version, passed as FLY_<NAME>
+	Values map[string]string
+}
+
+// Load reads the hooks from the flyctl config file
+func Load() (*Config, error) {
+	var cfg Config
+	if err := viper.UnmarshalKey(ConfigKey, &cfg); err != nil {
+		return nil, errors.Wrapf(err, "invalid %s in config file", ConfigKey)
+	}
+
+	for i, h := range cfg.Hooks {
+		if h.After == "" || h.Run == "" {
+			return nil, fmt.Errorf("%s.hooks[%d]: after and run are required", ConfigKey, i)
+		}
+		switch h.When {
+		case "", WhenSuccess, WhenFailure, WhenAlways:
+		default:
+			return nil, fmt.Errorf("%s.hooks[%d]: when must be %s, %s or %s", ConfigKey, i, WhenSuccess, WhenFailure, WhenAlways)
+		}
+		if h.Timeout != "" {
+			if d, err := time.ParseDuration(h.Timeout); err != nil || d <= 0 {
+				return nil, fmt.Errorf("%s.hooks[%d]: timeout must be a duration like 5m", ConfigKey, i)
+			}
+		}
+	}
+
+	return &cfg, nil
+}
+
+// Allowed reports whether a command may run hooks
+func (cfg *Config) Allowed(command string) bool {
+	if os.Getenv(DisableEnv) == "1" {
+		return false
+	}
+	if contains(cfg.Deny, command) {
+		return false
+	}
+	return len(cfg.Allow) == 0 || contains(cfg.Allow, command)
+}
+
+// Matching returns the hooks to run for how a command finished, in the order they're configured
+func (cfg *Config) Matching(result Result) []Hook {
+	if !cfg.Allowed(result.Command) {
+		return nil
+	}
+
+	var hooks []Hook
+	for _, h := range cfg.Hooks {
+		if h.After != result.Command {
+			continue
+		}
+		switch h.When {
+		case WhenAlways:
+		case WhenFailure:
+			if result.Err == nil {
+				continue
+			}
+		default:
+			if result.Err != nil {
+				continue
+			}
+		}
+		hooks = append(hooks, h)
+	}
+	return hooks
+}
+
+// Env returns the variables describing the result, sorted by name
+func (result Result) Env() []string {
+	status, code := WhenSuccess, "0"
+	if result.Err != nil {
+		status, code = WhenFailure, "1"
+	}
+
+	env := []string{
+		"FLY_COMMAND=" + result.Command,
+		"FLY_EXIT_STATUS=" + status,
+		"FLY_EXIT_CODE=" + code,
+	}
+	if result.AppName != "" {
+		env = append(env, "FLY_APP="+result.AppName)
+	}
+	if result.Err != nil {
+		env = append(env, "FLY_ERROR="+result.Err.Error())
+	}
+	for k, v := range result.Values {
+		env = append(env, "FLY_"+strings.ToUpper(k)+"="+v)
+	}
+
+	sort.Strings(env)
+	return env
+}
+
+// Run runs a hook in dir with the result in its environment
+func Run(ctx context.Context, hook Hook, result Result, dir string, out, errOut io.Writer) error {
+	// the timeout was checked when the hooks were loaded
+	timeout, _ := time.ParseDuration(hook.Timeout)
+
+	err := shellcmd.Run(ctx, hook.Run, shellcmd.Options{Dir: dir, Env: result.Env(), Timeout: timeout, Stdout: out, Stderr: errOut})
+	if err == shellcmd.ErrTimeout {
+		return fmt.Errorf("hook \"%s\" timed out after %s", hook.Run, hook.Timeout)
+	}
+	if err != nil {
+		return errors.Wrapf(err, "hook \"%s\" failed", hook.Run)
+	}
+	return nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
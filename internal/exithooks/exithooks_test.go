@@ -0,0 +1,112 @@
+package exithooks
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/assert"
+)
+
+const testConfig = `
+exit_hooks:
+  deny: ["ssh console"]
+  hooks:
+    - after: deploy
+      run: ./smoke-test.sh
+    - after: deploy
+      when: failure
+      run: ./page-me.sh
+    - after: logs
+      when: always
+      run: gzip capture.log
+      timeout: 1m
+    - after: ssh console
+      run: echo bye
+`
+
+func loadTestConfig(t *testing.T) *Config {
+	viper.Reset()
+	viper.SetConfigType("yaml")
+	assert.NoError(t, viper.ReadConfig(strings.NewReader(testConfig)))
+	t.Cleanup(viper.Reset)
+
+	cfg, err := Load()
+	assert.NoError(t, err)
+	return cfg
+}
+
+func runs(hooks []Hook) []string {
+	var out []string
+	for _, h := range hooks {
+		out = append(out, h.Run)
+	}
+	return out
+}
+
+func TestMatching(t *testing.T) {
+	cfg := loadTestConfig(t)
+
+	assert.Equal(t, []string{"./smoke-test.sh"}, runs(cfg.Matching(Result{Command: "deploy"})))
+	assert.Equal(t, []string{"./page-me.sh"}, runs(cfg.Matching(Result{Command: "deploy", Err: errors.New("boom")})))
+	assert.Equal(t, []string{"gzip capture.log"}, runs(cfg.Matching(Result{Command: "logs", Err: errors.New("boom")})))
+	assert.Empty(t, cfg.Matching(Result{Command: "ssh console"}))
+	assert.Empty(t, cfg.Matching(Result{Command: "status"}))
+
+	cfg.Allow = []string{"logs"}
+	assert.Empty(t, cfg.Matching(Result{Command: "deploy"}))
+
+	os.Setenv(DisableEnv, "1")
+	defer os.Unsetenv(DisableEnv)
+	assert.Empty(t, cfg.Matching(Result{Command: "logs"}))
+}
+
+func TestLoadInvalid(t *testing.T) {
+	viper.Reset()
+	t.Cleanup(viper.Reset)
+	viper.Set(ConfigKey, map[string]interface{}{
+		"hooks": []interface{}{map[string]interface{}{"after": "deploy", "run": "true", "when": "sometimes"}},
+	})
+
+	_, err := Load()
+	assert.EqualError(t, err, "exit_hooks.hooks[0]: when must be success, failure or always")
+}
+
+func TestResultEnv(t *testing.T) {
+	result := Result{
+		Command: "deploy",
+		AppName: "test-app",
+		Err:     errors.New("boom"),
+		Values:  map[string]string{"release_version": "3"},
+	}
+
+	assert.Equal(t, []string{
+		"FLY_APP=test-app",
+		"FLY_COMMAND=deploy",
+		"FLY_ERROR=boom",
+		"FLY_EXIT_CODE=1",
+		"FLY_EXIT_STATUS=failure",
+		"FLY_RELEASE_VERSION=3",
+	}, result.Env())
+}
+
+func TestRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+
+	var out bytes.Buffer
+	hook := Hook{After: "deploy", Run: "echo $FLY_COMMAND v$FLY_RELEASE_VERSION"}
+	result := Result{Command: "deploy", Values: map[string]string{"release_version": "3"}}
+
+	assert.NoError(t, Run(context.Background(), hook, result, t.TempDir(), &out, &out))
+	assert.Equal(t, "deploy v3\n", out.String())
+
+	hook = Hook{After: "deploy", Run: "exec sleep 5", Timeout: "10ms"}
+	assert.EqualError(t, Run(context.Background(), hook, result, t.TempDir(), &out, &out), `hook "exec sleep 5" timed out after 10ms`)
+}
@@ -0,0 +1,149 @@
+// Package labels parses the labels apps are tagged with, like env=staging, and the selectors that
+// pick apps by them.
+package labels
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const maxLength = 63
+
+var validKey = regexp.MustCompile(`^[a-z0-9]([a-z0-9._/-]*[a-z0-9])?$`)
+
+// ValidateKey checks a label key is lowercase letters, digits, dots, dashes, underscores and
+// slashes, starting and ending with a letter or digit
+func ValidateKey(key string) error {
+	if len(key) > maxLength || !validKey.MatchString(key) {
+		return fmt.Errorf("invalid label key \"%s\", keys are up to %d lowercase letters, digits and . _ - / characters", key, maxLength)
+	}
+	return nil
+}
+
+// ValidateValue checks a label value fits and can be used in a selector
+func ValidateValue(value string) error {
+	if len(value) > maxLength || strings.ContainsAny(value, ",=! ") {
+		return fmt.Errorf("invalid label value \"%s\", values are up to %d characters without spaces, commas, = or !", value, maxLength)
+	}
+	return nil
+}
+
+// Parse parses KEY=VALUE arguments into labels
+func Parse(args []string) (map[string]string, error) {
+	labels := make(map[string]string, len(args))
+	for _, arg := range args {
+		eq := strings.Index(arg, "=")
+		if eq < 0 {
+			return nil, fmt.Errorf("label \"%s\" must be in the form KEY=VALUE", arg)
+		}
+		key, value := arg[:eq], arg[eq+1:]
+		if err := ValidateKey(key); err != nil {
+			return nil, err
+		}
+		if err := ValidateValue(value); err != nil {
+			return nil, err
+		}
+		labels[key] = value
+	}
+	return labels, nil
+}
+
+// Format writes labels as key=value pairs sorted by key
+func Format(labels map[string]string) string {
+	pairs := make([]string, 0, len(labels))
+	for k, v := range labels {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// Requirement is one comma separated part of a selector
+type Requirement struct {
+	Key   string
+	Value string
+	// Op is =, != or, for requirements without a value, exists and !exists
+	Op string
+}
+
+func (r Requirement) matches(labels map[string]string) bool {
+	value, ok := labels[r.Key]
+	switch r.Op {
+	case "=":
+		return ok && value == r.Value
+	case "!=":
+		return !ok || value != r.Value
+	case "exists":
+		return ok
+	default:
+		return !ok
+	}
+}
+
+func (r Requirement) String() string {
+	switch r.Op {
+	case "exists":
+		return r.Key
+	case "!exists":
+		return "!" + r.Key
+	default:
+		return r.Key + r.Op + r.Value
+	}
+}
+
+// Selector picks apps whose labels meet all of its requirements. An empty selector matches every app.
+type Selector []Requirement
+
+// ParseSelector parses selectors like "env=staging,team!=payments,canary,!legacy"
+func ParseSelector(s string) (Selector, error) {
+	var sel Selector
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		var r Requirement
+		switch {
+		case strings.Contains(part, "!="):
+			i := strings.Index(part, "!=")
+			r = Requirement{Key: part[:i], Value: part[i+2:], Op: "!="}
+		case strings.Contains(part, "="):
+			i := strings.Index(part, "=")
+			r = Requirement{Key: part[:i], Value: part[i+1:], Op: "="}
+		case strings.HasPrefix(part, "!"):
+			r = Requirement{Key: part[1:], Op: "!exists"}
+		default:
+			r = Requirement{Key: part, Op: "exists"}
+		}
+
+		if err := ValidateKey(r.Key); err != nil {
+			return nil, fmt.Errorf("selector %s: %s", part, err)
+		}
+		if err := ValidateValue(r.Value); err != nil {
+			return nil, fmt.Errorf("selector %s: %s", part, err)
+		}
+		sel = append(sel, r)
+	}
+	return sel, nil
+}
+
+// Matches reports whether labels meet every requirement
+func (s Selector) Matches(labels map[string]string) bool {
+	for _, r := range s {
+		if !r.matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s Selector) String() string {
+	parts := make([]string, len(s))
+	for i, r := range s {
+		parts[i] = r.String()
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,48 @@
+package labels
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	labels, err := Parse([]string{"team=payments", "env=prod", "tier="})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"team": "payments", "env": "prod", "tier": ""}, labels)
+	assert.Equal(t, "env=prod,team=payments,tier=", Format(labels))
+
+	for _, arg := range []string{"team", "Team=payments", "=prod", "env=a,b", "-env=prod"} {
+		_, err := Parse([]string{arg})
+		assert.Error(t, err, arg)
+	}
+}
+
+func TestSelector(t *testing.T) {
+	sel, err := ParseSelector("env=staging, team!=payments,canary,!legacy")
+	assert.NoError(t, err)
+	assert.Equal(t, "env=staging,team!=payments,canary,!legacy", sel.String())
+
+	cases := []struct {
+		labels map[string]string
+		want   bool
+	}{
+		{map[string]string{"env": "staging", "canary": ""}, true},
+		{map[string]string{"env": "staging", "canary": "", "team": "search"}, true},
+		{map[string]string{"env": "staging", "canary": "", "team": "payments"}, false},
+		{map[string]string{"env": "prod", "canary": ""}, false},
+		{map[string]string{"env": "staging"}, false},
+		{map[string]string{"env": "staging", "canary": "", "legacy": "true"}, false},
+		{nil, false},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.want, sel.Matches(c.labels), Format(c.labels))
+	}
+
+	empty, err := ParseSelector("")
+	assert.NoError(t, err)
+	assert.True(t, empty.Matches(nil))
+
+	_, err = ParseSelector("Env=prod")
+	assert.Error(t, err)
+}
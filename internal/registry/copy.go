@@ -0,0 +1,121 @@
+package registry
+
+import (
+	"context"
+	"sync"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sync/errgroup"
+)
+
+// DefaultPushConcurrency is how many blobs are uploaded at once. The docker daemon pushes five
+// layers at a time, which leaves bandwidth unused when an image has many layers.
+const DefaultPushConcurrency = 8
+
+// CopyImage copies an image between repositories, which may be in different registries. Blobs the
+// destination already has aren't uploaded again, and up to concurrency blobs are copied at once.
+// progress is called after each blob with the number of bytes copied or skipped so far. It returns
+// the digest of the copied manifest.
+func CopyImage(ctx context.Context, src *Client, from Reference, dst *Client, to Reference, concurrency int, progress func(done, total int64)) (string, error) {
+	raw, mediaType, _, err := src.getRawManifest(ctx, from.Repository, from.Identifier())
+	if err != nil {
+		return "", errors.Wrapf(err, "error fetching manifest for %s", from)
+	}
+	m, err := decodeManifest(raw)
+	if err != nil {
+		return "", err
+	}
+	if mediaType == "" {
+		mediaType = m.MediaType
+	}
+
+	blobs := uniqueBlobs(append([]Descriptor{m.Config}, m.Layers...))
+
+	var total, done int64
+	for _, b := range blobs {
+		total += b.Size
+	}
+
+	var mu sync.Mutex
+	err = parallel(ctx, len(blobs), concurrency, func(ctx context.Context, i int) error {
+		b := blobs[i]
+		if err := copyBlob(ctx, src, from.Repository, dst, to.Repository, b); err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		done += b.Size
+		if progress != nil {
+			progress(done, total)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return dst.PutManifest(ctx, to.Repository, to.Identifier(), mediaType, raw)
+}
+
+func copyBlob(ctx context.Context, src *Client, srcRepo string, dst *Client, dstRepo string, desc Descriptor) error {
+	exists, err := dst.BlobExists(ctx, dstRepo, desc.Digest)
+	if err != nil {
+		return errors.Wrapf(err, "error checking for blob %s", desc.Digest)
+	}
+	if exists {
+		return nil
+	}
+
+	blob, err := src.GetBlob(ctx, srcRepo, desc.Digest)
+	if err != nil {
+		return errors.Wrapf(err, "error fetching blob %s", desc.Digest)
+	}
+	defer blob.Close()
+
+	return dst.UploadBlob(ctx, dstRepo, desc, blob)
+}
+
+// parallel calls fn with each index up to n, running up to concurrency calls at once, or
+// DefaultPushConcurrency when it's zero. The first error cancels the calls still running.
+func parallel(ctx context.Context, n, concurrency int, fn func(ctx context.Context, i int) error) error {
+	if concurrency <= 0 {
+		concurrency = DefaultPushConcurrency
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	sem := make(chan struct{}, concurrency)
+
+loop:
+	for i := 0; i < n; i++ {
+		select {
+		case sem <- struct{}{}:
+		case <-gctx.Done():
+			break loop
+		}
+
+		i := i
+		g.Go(func() error {
+			defer func() { <-sem }()
+			return fn(gctx, i)
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return err
+	}
+	return ctx.Err()
+}
+
+// uniqueBlobs drops repeated blobs, an image can have the same layer twice
+func uniqueBlobs(blobs []Descriptor) []Descriptor {
+	seen := map[string]bool{}
+	unique := blobs[:0:0]
+	for _, b := range blobs {
+		if !seen[b.Digest] {
+			seen[b.Digest] = true
+			unique = append(unique, b)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,177 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type fakeRegistry struct {
+	mu        sync.Mutex
+	blobs     map[string][]byte
+	manifests map[string][]byte
+	tags      []string
+	uploads   int
+	started   int
+	// partial holds the data received by each unfinished upload
+	partial map[string][]byte
+	// failPatches makes that many chunk uploads store half their data and fail
+	failPatches int
+	// deleted lists the digests of deleted manifests
+	deleted []string
+	// digestHeader is sent as the Docker-Content-Digest of every fetched manifest when set
+	digestHeader string
+}
+
+func (f *fakeRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := r.URL.Path
+	switch {
+	case strings.HasSuffix(path, "/tags/list"):
+		// serve two tags per page, like registries paging with ?n=2
+		start := 0
+		if last := r.URL.Query().Get("last"); last != "" {
+			for i, t := range f.tags {
+				if t == last {
+					start = i + 1
+				}
+			}
+		}
+		end := start + 2
+		if end < len(f.tags) {
+			w.Header().Set("Link", fmt.Sprintf(`<%s?last=%s&n=2>; rel="next"`, path, f.tags[end-1]))
+		} else {
+			end = len(f.tags)
+		}
+		json.NewEncoder(w).Encode(map[string][]string{"tags": f.tags[start:end]})
+	case strings.HasPrefix(path, "/upload/"):
+		switch r.Method {
+		case http.MethodPatch:
+			data, _ := io.ReadAll(r.Body)
+			if f.failPatches > 0 {
+				f.failPatches--
+				f.partial[path] = append(f.partial[path], data[:len(data)/2]...)
+				w.WriteHeader(http.StatusBadGateway)
+				return
+			}
+			f.partial[path] = append(f.partial[path], data...)
+			w.Header().Set("Location", path)
+			w.WriteHeader(http.StatusAccepted)
+		case http.MethodGet:
+			if n := len(f.partial[path]); n > 0 {
+				w.Header().Set("Range", fmt.Sprintf("0-%d", n-1))
+			}
+			w.Header().Set("Location", path)
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodPut:
+			f.blobs[r.URL.Query().Get("digest")] = f.partial[path]
+			delete(f.partial, path)
+			f.uploads++
+			w.WriteHeader(http.StatusCreated)
+		}
+	case strings.HasSuffix(path, "/blobs/uploads/"):
+		f.started++
+		w.Header().Set("Location", fmt.Sprintf("/upload/%d", f.started))
+		w.WriteHeader(http.StatusAccepted)
+	case strings.Contains(path, "/blobs/"):
+		data, ok := f.blobs[path[strings.LastIndex(path, "/")+1:]]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write(data)
+	case strings.Contains(path, "/manifests/"):
+		if r.Method == http.MethodDelete {
+			digest := path[strings.LastIndex(path, "/")+1:]
+			for p, data := range f.manifests {
+				if digestOf(data) == digest {
+					delete(f.manifests, p)
+				}
+			}
+			f.deleted = append(f.deleted, digest)
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		if r.Method == http.MethodPut {
+			data, _ := io.ReadAll(r.Body)
+			f.manifests[path] = data
+			w.Header().Set("Docker-Content-Digest", digestOf(data))
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		data, ok := f.manifests[path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", mediaTypeDockerManifest)
+		if f.digestHeader != "" {
+			w.Header().Set("Docker-Content-Digest", f.digestHeader)
+		}
+		w.Write(data)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func digestOf(data []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+}
+
+func newFakeRegistry(t *testing.T) (*fakeRegistry, *Client, func()) {
+	f := &fakeRegistry{blobs: map[string][]byte{}, manifests: map[string][]byte{}, partial: map[string][]byte{}}
+	srv := httptest.NewTLSServer(f)
+
+	c := NewClient(strings.TrimPrefix(srv.URL, "https://"), "", "")
+	c.http = srv.Client()
+
+	return f, c, srv.Close
+}
+
+func TestCopyImage(t *testing.T) {
+	src, srcClient, closeSrc := newFakeRegistry(t)
+	defer closeSrc()
+	dst, dstClient, closeDst := newFakeRegistry(t)
+	defer closeDst()
+
+	config, layer, shared := []byte(`{"os":"linux"}`), []byte("layer"), []byte("shared layer")
+	m := Manifest{
+		SchemaVersion: 2,
+		MediaType:     mediaTypeDockerManifest,
+		Config:        Descriptor{Digest: digestOf(config), Size: int64(len(config))},
+		Layers: []Descriptor{
+			{Digest: digestOf(layer), Size: int64(len(layer))},
+			{Digest: digestOf(shared), Size: int64(len(shared))},
+		},
+	}
+	raw, _ := json.Marshal(m)
+
+	for _, b := range [][]byte{config, layer, shared} {
+		src.blobs[digestOf(b)] = b
+	}
+	src.manifests["/v2/myapp/manifests/deployment-1"] = raw
+	dst.blobs[digestOf(shared)] = shared
+
+	from := Reference{Host: srcClient.host, Repository: "myapp", Tag: "deployment-1"}
+	to := Reference{Host: dstClient.host, Repository: "me/myapp", Tag: "v1"}
+
+	var done, total int64
+	digest, err := CopyImage(context.Background(), srcClient, from, dstClient, to, 2, func(d, t int64) { done, total = d, t })
+	assert.NoError(t, err)
+	assert.Equal(t, digestOf(raw), digest)
+	assert.Equal(t, raw, dst.manifests["/v2/me/myapp/manifests/v1"])
+	assert.Equal(t, layer, dst.blobs[digestOf(layer)])
+	assert.Equal(t, 2, dst.uploads)
+	assert.Equal(t, total, done)
+}
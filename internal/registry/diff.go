@@ -0,0 +1,412 @@
+package registry
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io"
+	"io/ioutil"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	whiteoutPrefix = ".wh."
+	whiteoutOpaque = ".wh..wh..opq"
+)
+
+// package databases captured while reading layers so installed packages can be compared
+var packageDatabases = map[string]func([]byte) map[string]string{
+	"var/lib/dpkg/status":  parseDpkgStatus,
+	"lib/apk/db/installed": parseApkInstalled,
+}
+
+const (
+	ChangeAdded   = "added"
+	ChangeRemoved = "removed"
+	ChangeChanged = "changed"
+	ChangeShared  = "shared"
+)
+
+// LayerChange describes a layer that is shared by, or unique to, one of the compared images
+type LayerChange struct {
+	Digest    string
+	Size      int64
+	CreatedBy string
+	Change    string
+}
+
+// FileChange describes a file added, removed or resized between two images
+type FileChange struct {
+	Path    string
+	Change  string
+	OldSize int64
+	NewSize int64
+}
+
+// SizeDelta is the change in size of the file in bytes
+func (f FileChange) SizeDelta() int64 {
+	return f.NewSize - f.OldSize
+}
+
+// PackageChange describes an OS package installed, removed or upgraded between two images
+type PackageChange struct {
+	Name       string
+	Change     string
+	OldVersion string
+	NewVersion string
+}
+
+// ImageDiff is the result of comparing two images layer by layer
+type ImageDiff struct {
+	From     string
+	To       string
+	FromSize int64
+	ToSize   int64
+	Layers   []LayerChange
+	Files    []FileChange
+	Packages []PackageChange
+}
+
+type layerIndex struct {
+	files      map[string]int64
+	whiteouts  []string
+	opaqueDirs []string
+	captured   map[string][]byte
+}
+
+type image struct {
+	ref      Reference
+	manifest *Manifest
+	config   *ImageConfig
+}
+
+func (img *image) size() (total int64) {
+	for _, l := range img.manifest.Layers {
+		total += l.Size
+	}
+	return total
+}
+
+// layerCommands pairs each layer with the history entry that created it
+func (img *image) layerCommands() []string {
+	var out []string
+	for _, h := range img.config.History {
+		if !h.EmptyLayer {
+			out = append(out, h.CreatedBy)
+		}
+	}
+	return out
+}
+
+// DiffImages compares two images stored in the registry. Layers shared by both images are only downloaded once.
+func DiffImages(ctx context.Context, c *Client, from, to Reference) (*ImageDiff, error) {
+	a, err := fetchImage(ctx, c, from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := fetchImage(ctx, c, to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &ImageDiff{
+		From:     from.String(),
+		To:       to.String(),
+		FromSize: a.size(),
+		ToSize:   b.size(),
+		Layers:   diffLayers(a, b),
+	}
+
+	indexes := map[string]*layerIndex{}
+	fsA, dbA, err := buildFilesystem(ctx, c, a, indexes)
+	if err != nil {
+		return nil, err
+	}
+	fsB, dbB, err := buildFilesystem(ctx, c, b, indexes)
+	if err != nil {
+		return nil, err
+	}
+
+	diff.Files = diffFiles(fsA, fsB)
+	diff.Packages = diffPackages(dbA, dbB)
+
+	return diff, nil
+}
+
+func fetchImage(ctx context.Context, c *Client, ref Reference) (*image, error) {
+	m, err := c.GetManifest(ctx, ref.Repository, ref.Identifier())
+	if err != nil {
+		return nil, errors.Wrapf(err, "error fetching manifest for %s", ref)
+	}
+	cfg, err := c.GetConfig(ctx, ref.Repository, m)
+	if err != nil {
+		return nil, errors.Wrapf(err, "error fetching config for %s", ref)
+	}
+	return &image{ref: ref, manifest: m, config: cfg}, nil
+}
+
+func diffLayers(a, b *image) []LayerChange {
+	inA := map[string]bool{}
+	for _, l := range a.manifest.Layers {
+		inA[l.Digest] = true
+	}
+	inB := map[string]bool{}
+	for _, l := range b.manifest.Layers {
+		inB[l.Digest] = true
+	}
+
+	var out []LayerChange
+
+	cmdsA := a.layerCommands()
+	for i, l := range a.manifest.Layers {
+		if inB[l.Digest] {
+			continue
+		}
+		out = append(out, LayerChange{Digest: l.Digest, Size: l.Size, CreatedBy: commandAt(cmdsA, i), Change: ChangeRemoved})
+	}
+
+	cmdsB := b.layerCommands()
+	for i, l := range b.manifest.Layers {
+		change := ChangeAdded
+		if inA[l.Digest] {
+			change = ChangeShared
+		}
+		out = append(out, LayerChange{Digest: l.Digest, Size: l.Size, CreatedBy: commandAt(cmdsB, i), Change: change})
+	}
+
+	return out
+}
+
+func commandAt(cmds []string, i int) string {
+	if i < len(cmds) {
+		return cmds[i]
+	}
+	return ""
+}
+
+func buildFilesystem(ctx context.Context, c *Client, img *image, indexes map[string]*layerIndex) (map[string]int64, map[string]string, error) {
+	fs := map[string]int64{}
+	dbs := map[string][]byte{}
+
+	for _, l := range img.manifest.Layers {
+		idx, ok := indexes[l.Digest]
+		if !ok {
+			blob, err := c.GetBlob(ctx, img.ref.Repository, l.Digest)
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "error fetching layer %s", l.Digest)
+			}
+			idx, err = readLayer(blob)
+			blob.Close()
+			if err != nil {
+				return nil, nil, errors.Wrapf(err, "error reading layer %s", l.Digest)
+			}
+			indexes[l.Digest] = idx
+		}
+		applyLayer(fs, idx)
+		for name, data := range idx.captured {
+			dbs[name] = data
+		}
+	}
+
+	packages := map[string]string{}
+	for name, data := range dbs {
+		if _, ok := fs[name]; !ok {
+			continue
+		}
+		for pkg, version := range packageDatabases[name](data) {
+			packages[pkg] = version
+		}
+	}
+
+	return fs, packages, nil
+}
+
+func readLayer(r io.Reader) (*layerIndex, error) {
+	br := bufio.NewReader(r)
+
+	// layers are usually gzipped but uncompressed tars are valid too
+	var tr *tar.Reader
+	if magic, err := br.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		tr = tar.NewReader(gz)
+	} else {
+		tr = tar.NewReader(br)
+	}
+
+	idx := &layerIndex{
+		files:    map[string]int64{},
+		captured: map[string][]byte{},
+	}
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		name := cleanPath(hdr.Name)
+		dir, base := path.Split(name)
+
+		switch {
+		case base == whiteoutOpaque:
+			idx.opaqueDirs = append(idx.opaqueDirs, strings.TrimSuffix(dir, "/"))
+			continue
+		case strings.HasPrefix(base, whiteoutPrefix):
+			idx.whiteouts = append(idx.whiteouts, dir+strings.TrimPrefix(base, whiteoutPrefix))
+			continue
+		case hdr.Typeflag == tar.TypeDir:
+			continue
+		}
+
+		idx.files[name] = hdr.Size
+
+		if _, ok := packageDatabases[name]; ok {
+			data, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return nil, err
+			}
+			idx.captured[name] = data
+		}
+	}
+
+	return idx, nil
+}
+
+func applyLayer(fs map[string]int64, idx *layerIndex) {
+	for _, dir := range idx.opaqueDirs {
+		removeTree(fs, dir)
+	}
+	for _, p := range idx.whiteouts {
+		delete(fs, p)
+		removeTree(fs, p)
+	}
+	for p, size := range idx.files {
+		fs[p] = size
+	}
+}
+
+func removeTree(fs map[string]int64, dir string) {
+	prefix := dir + "/"
+	for p := range fs {
+		if strings.HasPrefix(p, prefix) {
+			delete(fs, p)
+		}
+	}
+}
+
+func cleanPath(name string) string {
+	return strings.TrimPrefix(path.Clean("/"+name), "/")
+}
+
+func diffFiles(a, b map[string]int64) []FileChange {
+	var out []FileChange
+
+	for p, size := range a {
+		newSize, ok := b[p]
+		switch {
+		case !ok:
+			out = append(out, FileChange{Path: "/" + p, Change: ChangeRemoved, OldSize: size})
+		case newSize != size:
+			out = append(out, FileChange{Path: "/" + p, Change: ChangeChanged, OldSize: size, NewSize: newSize})
+		}
+	}
+	for p, size := range b {
+		if _, ok := a[p]; !ok {
+			out = append(out, FileChange{Path: "/" + p, Change: ChangeAdded, NewSize: size})
+		}
+	}
+
+	// biggest growth first, that's usually what people are looking for
+	sort.Slice(out, func(i, j int) bool {
+		di, dj := abs(out[i].SizeDelta()), abs(out[j].SizeDelta())
+		if di != dj {
+			return di > dj
+		}
+		return out[i].Path < out[j].Path
+	})
+
+	return out
+}
+
+func diffPackages(a, b map[string]string) []PackageChange {
+	var out []PackageChange
+
+	for name, version := range a {
+		newVersion, ok := b[name]
+		switch {
+		case !ok:
+			out = append(out, PackageChange{Name: name, Change: ChangeRemoved, OldVersion: version})
+		case newVersion != version:
+			out = append(out, PackageChange{Name: name, Change: ChangeChanged, OldVersion: version, NewVersion: newVersion})
+		}
+	}
+	for name, version := range b {
+		if _, ok := a[name]; !ok {
+			out = append(out, PackageChange{Name: name, Change: ChangeAdded, NewVersion: version})
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+
+	return out
+}
+
+func parseDpkgStatus(data []byte) map[string]string {
+	return parsePackageStanzas(data, "Package: ", "Version: ")
+}
+
+func parseApkInstalled(data []byte) map[string]string {
+	return parsePackageStanzas(data, "P:", "V:")
+}
+
+// parsePackageStanzas reads blank line separated package records with name and version fields
+func parsePackageStanzas(data []byte, nameField, versionField string) map[string]string {
+	out := map[string]string{}
+
+	var name, version string
+	flush := func() {
+		if name != "" {
+			out[name] = version
+		}
+		name, version = "", ""
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			flush()
+		case strings.HasPrefix(line, nameField):
+			name = strings.TrimPrefix(line, nameField)
+		case strings.HasPrefix(line, versionField):
+			version = strings.TrimPrefix(line, versionField)
+		}
+	}
+	flush()
+
+	return out
+}
+
+func abs(n int64) int64 {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
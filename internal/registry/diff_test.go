@@ -0,0 +1,79 @@
+package registry
+
+import (
+	"archive/tar"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReference(t *testing.T) {
+	ref, err := ParseReference("registry.fly.io/my-app:deployment-1234")
+	assert.NoError(t, err)
+	assert.Equal(t, "registry.fly.io", ref.Host)
+	assert.Equal(t, "my-app", ref.Repository)
+	assert.Equal(t, "deployment-1234", ref.Identifier())
+
+	ref, err = ParseReference("registry.fly.io/my-app@sha256:abc")
+	assert.NoError(t, err)
+	assert.Equal(t, "sha256:abc", ref.Identifier())
+
+	_, err = ParseReference("my-app")
+	assert.Error(t, err)
+}
+
+func tarball(t *testing.T, files map[string]string) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	tw := tar.NewWriter(buf)
+	for name, content := range files {
+		err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		assert.NoError(t, err)
+		_, err = tw.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, tw.Close())
+	return buf
+}
+
+func TestApplyLayersWithWhiteouts(t *testing.T) {
+	status := "Package: curl\nVersion: 7.64\n\nPackage: git\nVersion: 2.20\n"
+
+	base, err := readLayer(tarball(t, map[string]string{
+		"app/main.js":         "console.log(1)",
+		"app/node_modules/a":  "aaaa",
+		"var/lib/dpkg/status": status,
+	}))
+	assert.NoError(t, err)
+
+	top, err := readLayer(tarball(t, map[string]string{
+		"app/.wh.main.js":               "",
+		"app/node_modules/.wh..wh..opq": "",
+		"app/node_modules/b":            "bb",
+	}))
+	assert.NoError(t, err)
+
+	fs := map[string]int64{}
+	applyLayer(fs, base)
+	applyLayer(fs, top)
+
+	assert.Equal(t, map[string]int64{
+		"app/node_modules/b":  2,
+		"var/lib/dpkg/status": int64(len(status)),
+	}, fs)
+
+	assert.Equal(t, map[string]string{"curl": "7.64", "git": "2.20"}, parseDpkgStatus(base.captured["var/lib/dpkg/status"]))
+}
+
+func TestDiffFiles(t *testing.T) {
+	changes := diffFiles(
+		map[string]int64{"a": 10, "b": 20, "c": 5},
+		map[string]int64{"a": 10, "b": 500, "d": 1},
+	)
+
+	assert.Equal(t, []FileChange{
+		{Path: "/b", Change: ChangeChanged, OldSize: 20, NewSize: 500},
+		{Path: "/c", Change: ChangeRemoved, OldSize: 5},
+		{Path: "/d", Change: ChangeAdded, NewSize: 1},
+	}, changes)
+}
@@ -0,0 +1,29 @@
+package registry
+
+import "fmt"
+
+type UnauthorizedError struct {
+	Path string
+}
+
+func (err *UnauthorizedError) Error() string {
+	return fmt.Sprintf("you are not authorized to access \"%s\"", err.Path)
+}
+
+type NotFoundError struct {
+	Path string
+}
+
+func (err *NotFoundError) Error() string {
+	return fmt.Sprintf("\"%s\" not found in registry", err.Path)
+}
+
+// ServerError is a 5xx response from the registry, usually worth retrying
+type ServerError struct {
+	Path   string
+	Status string
+}
+
+func (err *ServerError) Error() string {
+	return fmt.Sprintf("registry returned %s for %s", err.Status, err.Path)
+}
@@ -0,0 +1,90 @@
+package registry
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DeploymentTagPrefix starts the tags flyctl deploy pushes to the fly registry
+const DeploymentTagPrefix = "deployment-"
+
+// ImageInfo describes an image stored in the registry
+type ImageInfo struct {
+	Reference    string            `json:"reference"`
+	Digest       string            `json:"digest"`
+	Created      string            `json:"created"`
+	OS           string            `json:"os"`
+	Architecture string            `json:"architecture"`
+	Size         int64             `json:"size"`
+	Entrypoint   []string          `json:"entrypoint"`
+	Cmd          []string          `json:"cmd"`
+	WorkingDir   string            `json:"working_dir"`
+	Labels       map[string]string `json:"labels"`
+	Layers       []LayerInfo       `json:"layers"`
+	History      []HistoryEntry    `json:"history"`
+	// Provenance is how the image was built, when it's been looked up with GetProvenance
+	Provenance *Provenance `json:"provenance,omitempty"`
+}
+
+// LayerInfo is one layer of an image, with the history step that created it
+type LayerInfo struct {
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+	CreatedBy string `json:"created_by"`
+}
+
+// InspectImage fetches the manifest and config of an image without downloading its layers
+func InspectImage(ctx context.Context, c *Client, ref Reference) (*ImageInfo, error) {
+	img, err := fetchImage(ctx, c, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &ImageInfo{
+		Reference:    ref.String(),
+		Digest:       img.manifest.Digest,
+		Created:      img.config.Created,
+		OS:           img.config.OS,
+		Architecture: img.config.Architecture,
+		Size:         img.size(),
+		Entrypoint:   img.config.Config.Entrypoint,
+		Cmd:          img.config.Config.Cmd,
+		WorkingDir:   img.config.Config.WorkingDir,
+		Labels:       img.config.Config.Labels,
+		History:      img.config.History,
+	}
+
+	cmds := img.layerCommands()
+	for i, l := range img.manifest.Layers {
+		info.Layers = append(info.Layers, LayerInfo{Digest: l.Digest, Size: l.Size, CreatedBy: commandAt(cmds, i)})
+	}
+
+	return info, nil
+}
+
+// SortDeploymentTags returns the deployment-<unix time> tags pushed by deploys, newest first.
+// Tags pushed with a custom --image-label are left out.
+func SortDeploymentTags(tags []string) []string {
+	var out []string
+	for _, t := range tags {
+		if strings.HasPrefix(t, DeploymentTagPrefix) {
+			out = append(out, t)
+		}
+	}
+
+	timestamp := func(tag string) int64 {
+		n, _ := strconv.ParseInt(strings.TrimPrefix(tag, DeploymentTagPrefix), 10, 64)
+		return n
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		a, b := timestamp(out[i]), timestamp(out[j])
+		if a != b {
+			return a > b
+		}
+		return out[i] > out[j]
+	})
+
+	return out
+}
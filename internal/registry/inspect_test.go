@@ -0,0 +1,78 @@
+package registry
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListTags(t *testing.T) {
+	f, c, closeFn := newFakeRegistry(t)
+	defer closeFn()
+
+	f.tags = []string{"deployment-1", "deployment-2", "deployment-3", "v1", "deployment-4"}
+
+	tags, err := c.ListTags(context.Background(), "myapp")
+	assert.NoError(t, err)
+	assert.Equal(t, f.tags, tags)
+}
+
+func TestSortDeploymentTags(t *testing.T) {
+	tags := []string{"deployment-1600000000", "latest", "deployment-1700000000", "v2", "deployment-1650000000"}
+
+	assert.Equal(t, []string{"deployment-1700000000", "deployment-1650000000", "deployment-1600000000"}, SortDeploymentTags(tags))
+}
+
+func TestInspectImage(t *testing.T) {
+	f, c, closeFn := newFakeRegistry(t)
+	defer closeFn()
+
+	config := []byte(`{
+		"os": "linux",
+		"architecture": "amd64",
+		"config": {"Cmd": ["./server"], "Labels": {"org.opencontainers.image.revision": "abc123"}},
+		"history": [
+			{"created_by": "ADD rootfs.tar /"},
+			{"created_by": "ENV PORT=8080", "empty_layer": true},
+			{"created_by": "COPY server ."}
+		]
+	}`)
+	base, app := []byte("base"), []byte("app layer")
+	m := Manifest{
+		SchemaVersion: 2,
+		MediaType:     mediaTypeDockerManifest,
+		Config:        Descriptor{Digest: digestOf(config), Size: int64(len(config))},
+		Layers: []Descriptor{
+			{Digest: digestOf(base), Size: int64(len(base))},
+			{Digest: digestOf(app), Size: int64(len(app))},
+		},
+	}
+	raw, _ := json.Marshal(m)
+	f.blobs[digestOf(config)] = config
+	f.manifests["/v2/myapp/manifests/deployment-1"] = raw
+
+	info, err := InspectImage(context.Background(), c, Reference{Host: c.host, Repository: "myapp", Tag: "deployment-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, "linux", info.OS)
+	assert.Equal(t, []string{"./server"}, info.Cmd)
+	assert.Equal(t, "abc123", info.Labels["org.opencontainers.image.revision"])
+	assert.Equal(t, int64(len(base)+len(app)), info.Size)
+	assert.Len(t, info.Layers, 2)
+	assert.Equal(t, "COPY server .", info.Layers[1].CreatedBy)
+	assert.Len(t, info.History, 3)
+}
+
+func TestGetManifestDigestIgnoresHeader(t *testing.T) {
+	f, c, closeFn := newFakeRegistry(t)
+	defer closeFn()
+
+	raw, _ := json.Marshal(Manifest{SchemaVersion: 2, MediaType: mediaTypeDockerManifest})
+	f.manifests["/v2/myapp/manifests/v1"] = raw
+	f.digestHeader = "sha256:0000000000000000000000000000000000000000000000000000000000000000"
+
+	m, err := c.GetManifest(context.Background(), "myapp", "v1")
+	assert.NoError(t, err)
+	assert.Equal(t, digestOf(raw), m.Digest)
+}
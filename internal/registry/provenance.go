@@ -0,0 +1,189 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// ProvenancePredicateType identifies SLSA provenance in an in-toto statement
+	ProvenancePredicateType = "https://slsa.dev/provenance/v0.2"
+
+	inTotoStatementType = "https://in-toto.io/Statement/v0.1"
+	mediaTypeInToto     = "application/vnd.in-toto+json"
+	mediaTypeOCIConfig  = "application/vnd.oci.image.config.v1+json"
+)
+
+// Provenance is a SLSA provenance predicate recording what built an image and from which source
+type Provenance struct {
+	Builder    ProvenanceBuilder    `json:"builder"`
+	BuildType  string               `json:"buildType"`
+	Invocation ProvenanceInvocation `json:"invocation"`
+	Metadata   ProvenanceMetadata   `json:"metadata"`
+}
+
+// ProvenanceBuilder identifies what ran the build, like a remote builder
+type ProvenanceBuilder struct {
+	ID string `json:"id"`
+}
+
+// ProvenanceInvocation is what the build was started with
+type ProvenanceInvocation struct {
+	ConfigSource ProvenanceSource `json:"configSource"`
+	// Parameters are the build's settings, like the Dockerfile and a hash of the build args
+	Parameters map[string]string `json:"parameters,omitempty"`
+}
+
+// ProvenanceSource is the source the image was built from. Digest holds the git commit as sha1
+// when the source is a git checkout.
+type ProvenanceSource struct {
+	URI        string            `json:"uri,omitempty"`
+	Digest     map[string]string `json:"digest,omitempty"`
+	EntryPoint string            `json:"entryPoint,omitempty"`
+}
+
+// ProvenanceMetadata holds when the build ran and how complete the record is
+type ProvenanceMetadata struct {
+	BuildStartedOn  *time.Time `json:"buildStartedOn,omitempty"`
+	BuildFinishedOn *time.Time `json:"buildFinishedOn,omitempty"`
+	Reproducible    bool       `json:"reproducible"`
+}
+
+type inTotoStatement struct {
+	Type          string          `json:"_type"`
+	Subject       []inTotoSubject `json:"subject"`
+	PredicateType string          `json:"predicateType"`
+	Predicate     json.RawMessage `json:"predicate"`
+}
+
+type inTotoSubject struct {
+	Name   string            `json:"name"`
+	Digest map[string]string `json:"digest"`
+}
+
+// AttestationTag is the tag an image's attestations are stored under, following cosign's
+// sha256-<hex>.att convention so other tools find them
+func AttestationTag(digest string) string {
+	return strings.Replace(digest, ":", "-", 1) + ".att"
+}
+
+// AttachProvenance stores provenance for the image with digest as an in-toto attestation in
+// the image's repository, replacing any attestation already stored for it. It returns the
+// digest of the attestation manifest.
+func AttachProvenance(ctx context.Context, c *Client, ref Reference, digest string, p *Provenance) (string, error) {
+	predicate, err := json.Marshal(p)
+	if err != nil {
+		return "", err
+	}
+	statement, err := json.Marshal(inTotoStatement{
+		Type: inTotoStatementType,
+		Subject: []inTotoSubject{{
+			Name:   ref.Host + "/" + ref.Repository,
+			Digest: map[string]string{"sha256": strings.TrimPrefix(digest, "sha256:")},
+		}},
+		PredicateType: ProvenancePredicateType,
+		Predicate:     predicate,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	config, err := putBlob(ctx, c, ref.Repository, mediaTypeOCIConfig, []byte("{}"))
+	if err != nil {
+		return "", err
+	}
+	layer, err := putBlob(ctx, c, ref.Repository, mediaTypeInToto, statement)
+	if err != nil {
+		return "", err
+	}
+
+	manifest, err := json.Marshal(Manifest{
+		SchemaVersion: 2,
+		MediaType:     mediaTypeOCIManifest,
+		Config:        config,
+		Layers:        []Descriptor{layer},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return c.PutManifest(ctx, ref.Repository, AttestationTag(digest), mediaTypeOCIManifest, manifest)
+}
+
+// GetProvenance returns the provenance attested for the image with digest, or nil when the
+// image has none
+func GetProvenance(ctx context.Context, c *Client, repo, digest string) (*Provenance, error) {
+	m, err := c.GetManifest(ctx, repo, AttestationTag(digest))
+	if err != nil {
+		var notFound *NotFoundError
+		if errors.As(err, &notFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	for _, layer := range m.Layers {
+		if layer.MediaType != mediaTypeInToto {
+			continue
+		}
+
+		blob, err := c.GetBlob(ctx, repo, layer.Digest)
+		if err != nil {
+			return nil, err
+		}
+		var statement inTotoStatement
+		err = json.NewDecoder(blob).Decode(&statement)
+		blob.Close()
+		if err != nil {
+			return nil, errors.Wrap(err, "error decoding attestation")
+		}
+
+		if statement.PredicateType != ProvenancePredicateType || !attestsDigest(statement, digest) {
+			continue
+		}
+
+		var p Provenance
+		if err := json.Unmarshal(statement.Predicate, &p); err != nil {
+			return nil, errors.Wrap(err, "error decoding provenance")
+		}
+		return &p, nil
+	}
+
+	return nil, nil
+}
+
+func attestsDigest(statement inTotoStatement, digest string) bool {
+	for _, s := range statement.Subject {
+		if "sha256:"+s.Digest["sha256"] == digest {
+			return true
+		}
+	}
+	return false
+}
+
+// putBlob uploads a small blob unless the registry already has it
+func putBlob(ctx context.Context, c *Client, repo, mediaType string, data []byte) (Descriptor, error) {
+	desc := Descriptor{
+		MediaType: mediaType,
+		Size:      int64(len(data)),
+		Digest:    fmt.Sprintf("sha256:%x", sha256.Sum256(data)),
+	}
+
+	exists, err := c.BlobExists(ctx, repo, desc.Digest)
+	if err != nil {
+		return desc, err
+	}
+	if !exists {
+		if err := c.UploadBlob(ctx, repo, desc, bytes.NewReader(data)); err != nil {
+			return desc, err
+		}
+	}
+	return desc, nil
+}
@@ -0,0 +1,55 @@
+package registry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvenance(t *testing.T) {
+	f, c, closeRegistry := newFakeRegistry(t)
+	defer closeRegistry()
+
+	ctx := context.Background()
+	ref := Reference{Host: "registry.fly.io", Repository: "test-app", Tag: "deployment-1"}
+	digest := digestOf([]byte("image"))
+	finished := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+
+	p := &Provenance{
+		Builder:   ProvenanceBuilder{ID: "https://fly.io/remote-builder/default"},
+		BuildType: "https://fly.io/flyctl/dockerfile@v1",
+		Invocation: ProvenanceInvocation{
+			ConfigSource: ProvenanceSource{
+				URI:    "git+https://github.com/me/app",
+				Digest: map[string]string{"sha1": "0123abcd"},
+			},
+			Parameters: map[string]string{"buildArgs": "NODE_ENV"},
+		},
+		Metadata: ProvenanceMetadata{BuildFinishedOn: &finished},
+	}
+
+	_, err := AttachProvenance(ctx, c, ref, digest, p)
+	require.NoError(t, err)
+	assert.Contains(t, f.manifests, "/v2/test-app/manifests/"+AttestationTag(digest))
+
+	got, err := GetProvenance(ctx, c, "test-app", digest)
+	require.NoError(t, err)
+	assert.Equal(t, p, got)
+
+	// attaching again reuses the stored blobs
+	uploads := f.uploads
+	_, err = AttachProvenance(ctx, c, ref, digest, p)
+	require.NoError(t, err)
+	assert.Equal(t, uploads, f.uploads)
+
+	got, err = GetProvenance(ctx, c, "test-app", digestOf([]byte("other image")))
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+func TestAttestationTag(t *testing.T) {
+	assert.Equal(t, "sha256-0123abcd.att", AttestationTag("sha256:0123abcd"))
+}
@@ -0,0 +1,121 @@
+package registry
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+
+	"github.com/pkg/errors"
+)
+
+// PrunedTag is a deployment tag removed, or that would be removed, by PruneDeploymentTags
+type PrunedTag struct {
+	Tag    string `json:"tag"`
+	Digest string `json:"digest"`
+}
+
+// PruneOptions controls which deployment tags PruneDeploymentTags removes
+type PruneOptions struct {
+	// Keep is how many of the newest deployment tags are kept
+	Keep int
+	// Protect lists tags or digests that are never removed, like the image of the current release
+	Protect []string
+	// DryRun finds the tags to remove without removing them
+	DryRun bool
+}
+
+// PruneDeploymentTags removes all but the newest deployment-<timestamp> tags of repo. Other tags are
+// left alone. Removing a tag deletes its manifest, which removes every tag of that manifest, so a
+// tag is kept when its manifest is also tagged by a kept tag or is protected.
+func PruneDeploymentTags(ctx context.Context, c *Client, repo string, opts PruneOptions) ([]PrunedTag, error) {
+	if opts.Keep < 0 {
+		return nil, fmt.Errorf("can't keep %d tags", opts.Keep)
+	}
+
+	tags, err := c.ListTags(ctx, repo)
+	if err != nil {
+		return nil, err
+	}
+	tags = SortDeploymentTags(tags)
+	if len(tags) <= opts.Keep {
+		return nil, nil
+	}
+
+	keep := map[string]bool{}
+	for _, ref := range append(append([]string{}, tags[:opts.Keep]...), opts.Protect...) {
+		digest, err := c.manifestDigest(ctx, repo, ref)
+		if err != nil {
+			var notFound *NotFoundError
+			if errors.As(err, &notFound) {
+				continue
+			}
+			return nil, err
+		}
+		keep[digest] = true
+	}
+
+	var pruned []PrunedTag
+	deleted := map[string]bool{}
+
+	for _, tag := range tags[opts.Keep:] {
+		digest, err := c.manifestDigest(ctx, repo, tag)
+		if err != nil {
+			return pruned, err
+		}
+		if keep[digest] {
+			continue
+		}
+
+		if !opts.DryRun && !deleted[digest] {
+			if err := c.DeleteManifest(ctx, repo, digest); err != nil {
+				return pruned, errors.Wrapf(err, "error deleting %s", tag)
+			}
+			if err := c.deleteAttestation(ctx, repo, digest); err != nil {
+				return pruned, errors.Wrapf(err, "error deleting the attestation of %s", tag)
+			}
+		}
+		deleted[digest] = true
+		pruned = append(pruned, PrunedTag{Tag: tag, Digest: digest})
+	}
+
+	return pruned, nil
+}
+
+// manifestDigest returns the digest of the manifest a tag or digest refers to
+func (c *Client) manifestDigest(ctx context.Context, repo, ref string) (string, error) {
+	raw, _, digest, err := c.getRawManifest(ctx, repo, ref)
+	if err != nil {
+		return "", err
+	}
+	if digest == "" {
+		digest = fmt.Sprintf("sha256:%x", sha256.Sum256(raw))
+	}
+	return digest, nil
+}
+
+// DeleteManifest deletes a manifest by digest, along with every tag of it
+func (c *Client) DeleteManifest(ctx context.Context, repo, digest string) error {
+	resp, err := c.do(ctx, repo, func() (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodDelete, c.url(fmt.Sprintf("/v2/%s/manifests/%s", repo, digest)), nil)
+	})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	return nil
+}
+
+// deleteAttestation deletes the attestation stored for an image, if it has one
+func (c *Client) deleteAttestation(ctx context.Context, repo, digest string) error {
+	attDigest, err := c.manifestDigest(ctx, repo, AttestationTag(digest))
+	if err != nil {
+		var notFound *NotFoundError
+		if errors.As(err, &notFound) {
+			return nil
+		}
+		return err
+	}
+	return c.DeleteManifest(ctx, repo, attDigest)
+}
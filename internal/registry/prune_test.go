@@ -0,0 +1,47 @@
+package registry
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPruneDeploymentTags(t *testing.T) {
+	f, c, closeRegistry := newFakeRegistry(t)
+	defer closeRegistry()
+
+	manifest := func(n int) []byte {
+		return []byte(fmt.Sprintf(`{"schemaVersion":2,"config":{"digest":"sha256:%d"}}`, n))
+	}
+
+	// deployment-1 and deployment-4 are the same image, and deployment-2 is the current release
+	f.tags = []string{"latest", "deployment-1", "deployment-2", "deployment-3", "deployment-4", "deployment-5"}
+	for i, n := range []int{1, 2, 3, 1, 5} {
+		f.manifests[fmt.Sprintf("/v2/test-app/manifests/deployment-%d", i+1)] = manifest(n)
+	}
+	f.manifests["/v2/test-app/manifests/latest"] = manifest(5)
+	// deployment-3 has an attestation, removed along with it
+	f.manifests["/v2/test-app/manifests/"+AttestationTag(digestOf(manifest(3)))] = manifest(30)
+
+	opts := PruneOptions{Keep: 2, Protect: []string{"deployment-2"}, DryRun: true}
+	pruned, err := PruneDeploymentTags(context.Background(), c, "test-app", opts)
+	assert.NoError(t, err)
+	assert.Equal(t, []PrunedTag{{Tag: "deployment-3", Digest: digestOf(manifest(3))}}, pruned)
+	assert.Empty(t, f.deleted)
+
+	opts.DryRun = false
+	opts.Protect = nil
+	pruned, err = PruneDeploymentTags(context.Background(), c, "test-app", opts)
+	assert.NoError(t, err)
+	assert.Equal(t, []PrunedTag{
+		{Tag: "deployment-3", Digest: digestOf(manifest(3))},
+		{Tag: "deployment-2", Digest: digestOf(manifest(2))},
+	}, pruned)
+	assert.Equal(t, []string{digestOf(manifest(3)), digestOf(manifest(30)), digestOf(manifest(2))}, f.deleted)
+
+	pruned, err = PruneDeploymentTags(context.Background(), c, "test-app", PruneOptions{Keep: 10})
+	assert.NoError(t, err)
+	assert.Empty(t, pruned)
+}
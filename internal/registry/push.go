@@ -0,0 +1,240 @@
+package registry
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	mediaTypeDockerConfig = "application/vnd.docker.container.image.v1+json"
+	mediaTypeDockerLayer  = "application/vnd.docker.image.rootfs.diff.tar.gzip"
+)
+
+// archiveManifest is an entry of the manifest.json written by docker save
+type archiveManifest struct {
+	Config string
+	Layers []string
+}
+
+// PushOptions controls how PushArchive compresses and uploads layers
+type PushOptions struct {
+	// Concurrency is how many layers are compressed and uploaded at once, DefaultPushConcurrency when zero
+	Concurrency int
+	// CompressionLevel is the gzip level for layers, gzip's default when zero
+	CompressionLevel int
+	// Progress is called as each blob finishes, with whether it had to be uploaded
+	Progress func(desc Descriptor, uploaded bool)
+}
+
+// PushArchive pushes an image exported by docker save. Layers the registry already has are
+// skipped. It returns the digest of the pushed manifest.
+func PushArchive(ctx context.Context, c *Client, ref Reference, archive io.Reader, opts PushOptions) (string, error) {
+	dir, err := ioutil.TempDir("", "flyctl-push")
+	if err != nil {
+		return "", err
+	}
+	defer os.RemoveAll(dir)
+
+	files, err := extractArchive(archive, dir)
+	if err != nil {
+		return "", errors.Wrap(err, "error reading image archive")
+	}
+
+	manifestFile, ok := files["manifest.json"]
+	if !ok {
+		return "", errors.New("image archive doesn't have a manifest.json")
+	}
+	data, err := ioutil.ReadFile(manifestFile)
+	if err != nil {
+		return "", err
+	}
+	var entries []archiveManifest
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return "", errors.Wrap(err, "error decoding image archive manifest")
+	}
+	if len(entries) != 1 {
+		return "", fmt.Errorf("image archive has %d images, expected one", len(entries))
+	}
+	entry := entries[0]
+
+	// the config is last so its blob is uploaded after the layers it refers to
+	names := append(append([]string{}, entry.Layers...), entry.Config)
+	blobs := make([]Descriptor, len(names))
+
+	var mu sync.Mutex
+	err = parallel(ctx, len(names), opts.Concurrency, func(ctx context.Context, i int) (err error) {
+		path, ok := files[names[i]]
+		if !ok {
+			return fmt.Errorf("image archive is missing %s", names[i])
+		}
+
+		blob, desc := path, Descriptor{}
+		if i == len(names)-1 {
+			desc, err = fileDescriptor(path, mediaTypeDockerConfig)
+		} else {
+			blob, desc, err = compressLayer(path, opts.CompressionLevel)
+		}
+		if err != nil {
+			return errors.Wrapf(err, "error reading %s from the image archive", names[i])
+		}
+		blobs[i] = desc
+
+		uploaded, err := pushBlob(ctx, c, ref.Repository, desc, blob)
+		if err != nil {
+			return err
+		}
+
+		if opts.Progress != nil {
+			mu.Lock()
+			defer mu.Unlock()
+			opts.Progress(desc, uploaded)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	m := Manifest{
+		SchemaVersion: 2,
+		MediaType:     mediaTypeDockerManifest,
+		Config:        blobs[len(blobs)-1],
+		Layers:        blobs[:len(blobs)-1],
+	}
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return "", err
+	}
+
+	return c.PutManifest(ctx, ref.Repository, ref.Identifier(), mediaTypeDockerManifest, raw)
+}
+
+// extractArchive writes the files of a tar archive to dir, returning where each was written
+func extractArchive(archive io.Reader, dir string) (map[string]string, error) {
+	files := map[string]string{}
+	tr := tar.NewReader(archive)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// files are numbered rather than named after the archive so no name can escape dir
+		path := filepath.Join(dir, fmt.Sprintf("%d", len(files)))
+		f, err := os.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		_, err = io.Copy(f, tr)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+		files[hdr.Name] = path
+	}
+}
+
+// compressLayer gzips a layer tar next to it at level, returning the compressed file and its
+// descriptor. Layers that are already compressed are used as they are.
+func compressLayer(path string, level int) (string, Descriptor, error) {
+	in, err := os.Open(path)
+	if err != nil {
+		return "", Descriptor{}, err
+	}
+	defer in.Close()
+
+	r := bufio.NewReader(in)
+	if magic, err := r.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		desc, err := fileDescriptor(path, mediaTypeDockerLayer)
+		return path, desc, err
+	}
+
+	compressed := path + ".gz"
+	out, err := os.Create(compressed)
+	if err != nil {
+		return "", Descriptor{}, err
+	}
+	defer out.Close()
+
+	if level == 0 {
+		level = gzip.DefaultCompression
+	}
+
+	h := sha256.New()
+	counter := &countingWriter{}
+	gz, err := gzip.NewWriterLevel(io.MultiWriter(out, h, counter), level)
+	if err != nil {
+		return "", Descriptor{}, err
+	}
+	if _, err := io.Copy(gz, r); err != nil {
+		return "", Descriptor{}, err
+	}
+	if err := gz.Close(); err != nil {
+		return "", Descriptor{}, err
+	}
+
+	return compressed, Descriptor{MediaType: mediaTypeDockerLayer, Size: counter.n, Digest: fmt.Sprintf("sha256:%x", h.Sum(nil))}, nil
+}
+
+// fileDescriptor describes a file as a blob of the given media type
+func fileDescriptor(path, mediaType string) (Descriptor, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Descriptor{}, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	n, err := io.Copy(h, f)
+	if err != nil {
+		return Descriptor{}, err
+	}
+	return Descriptor{MediaType: mediaType, Size: n, Digest: fmt.Sprintf("sha256:%x", h.Sum(nil))}, nil
+}
+
+// pushBlob uploads a file unless the registry already has it, reporting whether it was uploaded
+func pushBlob(ctx context.Context, c *Client, repo string, desc Descriptor, path string) (bool, error) {
+	exists, err := c.BlobExists(ctx, repo, desc.Digest)
+	if err != nil {
+		return false, errors.Wrapf(err, "error checking for blob %s", desc.Digest)
+	}
+	if exists {
+		return false, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	return true, c.UploadBlob(ctx, repo, desc, f)
+}
+
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,108 @@
+package registry
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func imageArchive(t *testing.T, files map[string][]byte) io.Reader {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	for name, data := range files {
+		assert.NoError(t, tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data)), Typeflag: tar.TypeReg}))
+		_, err := tw.Write(data)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, tw.Close())
+	return &buf
+}
+
+func TestPushArchive(t *testing.T) {
+	f, c, closeSrv := newFakeRegistry(t)
+	defer closeSrv()
+
+	config := []byte(`{"os":"linux"}`)
+	layers := [][]byte{[]byte("base layer"), []byte("app layer"), []byte("config layer")}
+	manifest, _ := json.Marshal([]archiveManifest{{Config: "abc.json", Layers: []string{"1/layer.tar", "2/layer.tar", "3/layer.tar"}}})
+
+	archive := func() io.Reader {
+		return imageArchive(t, map[string][]byte{
+			"manifest.json": manifest,
+			"abc.json":      config,
+			"1/layer.tar":   layers[0],
+			"2/layer.tar":   layers[1],
+			"3/layer.tar":   layers[2],
+		})
+	}
+
+	ref, _ := ParseReference("registry.fly.io/app:deployment-1")
+	uploaded := 0
+	digest, err := PushArchive(context.Background(), c, ref, archive(), PushOptions{Concurrency: 2, Progress: func(desc Descriptor, up bool) {
+		if up {
+			uploaded++
+		}
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, uploaded)
+
+	raw := f.manifests["/v2/app/manifests/deployment-1"]
+	assert.Equal(t, digestOf(raw), digest)
+
+	m, err := decodeManifest(raw)
+	assert.NoError(t, err)
+	assert.Equal(t, digestOf(config), m.Config.Digest)
+	assert.Len(t, m.Layers, 3)
+	for i, l := range m.Layers {
+		gz, err := gzip.NewReader(bytes.NewReader(f.blobs[l.Digest]))
+		assert.NoError(t, err)
+		data, _ := io.ReadAll(gz)
+		assert.Equal(t, layers[i], data)
+	}
+
+	// pushing again finds every blob in the registry
+	uploaded = 0
+	_, err = PushArchive(context.Background(), c, ref, archive(), PushOptions{Concurrency: 2, Progress: func(desc Descriptor, up bool) {
+		if up {
+			uploaded++
+		}
+	}})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, uploaded)
+	assert.Equal(t, 4, f.uploads)
+}
+
+func TestPushArchiveCompressionLevel(t *testing.T) {
+	f, c, closeSrv := newFakeRegistry(t)
+	defer closeSrv()
+
+	words := []string{"layer", "image", "registry", "push", "fly", "deploy", "app", "build"}
+	rnd := rand.New(rand.NewSource(1))
+	var layer []byte
+	for i := 0; i < 20000; i++ {
+		layer = append(layer, words[rnd.Intn(len(words))]...)
+		layer = append(layer, ' ')
+	}
+	manifest, _ := json.Marshal([]archiveManifest{{Config: "abc.json", Layers: []string{"1/layer.tar"}}})
+	ref, _ := ParseReference("registry.fly.io/app:deployment-1")
+
+	sizes := map[int]int64{}
+	for _, level := range []int{1, 9} {
+		archive := imageArchive(t, map[string][]byte{"manifest.json": manifest, "abc.json": []byte(`{}`), "1/layer.tar": layer})
+		_, err := PushArchive(context.Background(), c, ref, archive, PushOptions{CompressionLevel: level})
+		assert.NoError(t, err)
+
+		m, err := decodeManifest(f.manifests["/v2/app/manifests/deployment-1"])
+		assert.NoError(t, err)
+		sizes[level] = m.Layers[0].Size
+	}
+
+	assert.Less(t, sizes[9], sizes[1])
+}
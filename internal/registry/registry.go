@@ -0,0 +1,445 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	mediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+	mediaTypeOCIManifest    = "application/vnd.oci.image.manifest.v1+json"
+)
+
+// Reference is a parsed image reference such as registry.fly.io/myapp:deployment-123
+type Reference struct {
+	Host       string
+	Repository string
+	Tag        string
+	Digest     string
+}
+
+// ParseReference splits an image reference into its registry host, repository and tag or digest
+func ParseReference(ref string) (Reference, error) {
+	var r Reference
+
+	slash := strings.Index(ref, "/")
+	if slash < 0 {
+		return r, fmt.Errorf("image reference \"%s\" does not include a registry host", ref)
+	}
+	r.Host = ref[:slash]
+	rest := ref[slash+1:]
+
+	if at := strings.Index(rest, "@"); at >= 0 {
+		r.Digest = rest[at+1:]
+		rest = rest[:at]
+	}
+	if colon := strings.LastIndex(rest, ":"); colon >= 0 {
+		r.Tag = rest[colon+1:]
+		rest = rest[:colon]
+	}
+	r.Repository = rest
+
+	if r.Repository == "" {
+		return r, fmt.Errorf("image reference \"%s\" does not include a repository", ref)
+	}
+	if r.Tag == "" && r.Digest == "" {
+		r.Tag = "latest"
+	}
+
+	return r, nil
+}
+
+// Identifier returns the digest if present, otherwise the tag
+func (r Reference) Identifier() string {
+	if r.Digest != "" {
+		return r.Digest
+	}
+	return r.Tag
+}
+
+func (r Reference) String() string {
+	s := r.Host + "/" + r.Repository
+	if r.Tag != "" {
+		s += ":" + r.Tag
+	}
+	if r.Digest != "" {
+		s += "@" + r.Digest
+	}
+	return s
+}
+
+// Descriptor points at a blob stored in the registry
+type Descriptor struct {
+	MediaType string `json:"mediaType"`
+	Size      int64  `json:"size"`
+	Digest    string `json:"digest"`
+	// Annotations hold metadata about the blob, like a cosign signature
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// Manifest is a schema 2 image manifest
+type Manifest struct {
+	SchemaVersion int          `json:"schemaVersion"`
+	MediaType     string       `json:"mediaType"`
+	Config        Descriptor   `json:"config"`
+	Layers        []Descriptor `json:"layers"`
+
+	// Digest is the content digest returned by the registry
+	Digest string `json:"-"`
+}
+
+// HistoryEntry is a single step from the image config history
+type HistoryEntry struct {
+	Created    string `json:"created"`
+	CreatedBy  string `json:"created_by"`
+	Comment    string `json:"comment,omitempty"`
+	EmptyLayer bool   `json:"empty_layer,omitempty"`
+}
+
+// ImageConfig is the subset of the image config blob flyctl cares about
+type ImageConfig struct {
+	Architecture string `json:"architecture"`
+	OS           string `json:"os"`
+	Created      string `json:"created"`
+	Config       struct {
+		Env        []string          `json:"Env"`
+		Cmd        []string          `json:"Cmd"`
+		Entrypoint []string          `json:"Entrypoint"`
+		WorkingDir string            `json:"WorkingDir"`
+		Labels     map[string]string `json:"Labels"`
+	} `json:"config"`
+	RootFS struct {
+		Type    string   `json:"type"`
+		DiffIDs []string `json:"diff_ids"`
+	} `json:"rootfs"`
+	History []HistoryEntry `json:"history"`
+}
+
+// Client talks to a docker v2 registry over HTTP
+type Client struct {
+	host     string
+	username string
+	password string
+	http     *http.Client
+
+	// mu guards tokens, the client is used by concurrent blob uploads
+	mu     sync.Mutex
+	tokens map[string]string
+}
+
+// NewClient returns a registry client authenticating with the given credentials
+func NewClient(host, username, password string) *Client {
+	return &Client{
+		host:     apiHost(host),
+		username: username,
+		password: password,
+		http:     http.DefaultClient,
+		tokens:   map[string]string{},
+	}
+}
+
+// WithHTTPClient makes the client send its requests with hc, like one trusting a test registry's certificate
+func (c *Client) WithHTTPClient(hc *http.Client) *Client {
+	c.http = hc
+	return c
+}
+
+// docker hub images are named docker.io/... but its registry API is served from another host
+func apiHost(host string) string {
+	if host == "docker.io" || host == "index.docker.io" {
+		return "registry-1.docker.io"
+	}
+	return host
+}
+
+// GetManifest fetches the manifest for a tag or digest
+func (c *Client) GetManifest(ctx context.Context, repo, ref string) (*Manifest, error) {
+	raw, _, digest, err := c.getRawManifest(ctx, repo, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	m, err := decodeManifest(raw)
+	if err != nil {
+		return nil, err
+	}
+	m.Digest = digest
+
+	return m, nil
+}
+
+// getRawManifest returns the manifest exactly as stored, since re-encoding it would change its digest
+func (c *Client) getRawManifest(ctx context.Context, repo, ref string) (raw []byte, mediaType string, digest string, err error) {
+	resp, err := c.get(ctx, repo, fmt.Sprintf("/v2/%s/manifests/%s", repo, ref), mediaTypeDockerManifest+", "+mediaTypeOCIManifest)
+	if err != nil {
+		return nil, "", "", err
+	}
+	defer resp.Body.Close()
+
+	if raw, err = io.ReadAll(resp.Body); err != nil {
+		return nil, "", "", errors.Wrap(err, "error reading image manifest")
+	}
+
+	return raw, resp.Header.Get("Content-Type"), manifestDigest(raw), nil
+}
+
+// manifestDigest computes a manifest's digest from its bytes rather than trusting the registry's
+// Docker-Content-Digest header, since signatures and pins are checked against it
+func manifestDigest(raw []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(raw))
+}
+
+func decodeManifest(raw []byte) (*Manifest, error) {
+	var m Manifest
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return nil, errors.Wrap(err, "error decoding image manifest")
+	}
+	if m.SchemaVersion != 2 {
+		return nil, fmt.Errorf("unsupported manifest schema version %d", m.SchemaVersion)
+	}
+	return &m, nil
+}
+
+// GetConfig fetches and decodes the image config blob referenced by a manifest
+func (c *Client) GetConfig(ctx context.Context, repo string, m *Manifest) (*ImageConfig, error) {
+	blob, err := c.GetBlob(ctx, repo, m.Config.Digest)
+	if err != nil {
+		return nil, err
+	}
+	defer blob.Close()
+
+	var cfg ImageConfig
+	if err := json.NewDecoder(blob).Decode(&cfg); err != nil {
+		return nil, errors.Wrap(err, "error decoding image config")
+	}
+	return &cfg, nil
+}
+
+// GetBlob streams a blob (layer or config) from the registry. Callers must close the reader.
+func (c *Client) GetBlob(ctx context.Context, repo, digest string) (io.ReadCloser, error) {
+	resp, err := c.get(ctx, repo, fmt.Sprintf("/v2/%s/blobs/%s", repo, digest), "")
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// BlobExists reports whether the repository already has a blob
+func (c *Client) BlobExists(ctx context.Context, repo, digest string) (bool, error) {
+	resp, err := c.do(ctx, repo, func() (*http.Request, error) {
+		return http.NewRequestWithContext(ctx, http.MethodHead, c.url(fmt.Sprintf("/v2/%s/blobs/%s", repo, digest)), nil)
+	})
+	if err != nil {
+		var notFound *NotFoundError
+		if errors.As(err, &notFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	resp.Body.Close()
+
+	return true, nil
+}
+
+// PutManifest stores a manifest under a tag or digest, returning its digest
+func (c *Client) PutManifest(ctx context.Context, repo, ref, mediaType string, raw []byte) (string, error) {
+	resp, err := c.do(ctx, repo, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, c.url(fmt.Sprintf("/v2/%s/manifests/%s", repo, ref)), bytes.NewReader(raw))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", mediaType)
+		return req, nil
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "error pushing image manifest")
+	}
+	resp.Body.Close()
+
+	return manifestDigest(raw), nil
+}
+
+// ListTags returns every tag in a repository, following the registry's pagination links
+func (c *Client) ListTags(ctx context.Context, repo string) ([]string, error) {
+	var tags []string
+
+	next := fmt.Sprintf("/v2/%s/tags/list", repo)
+	for next != "" {
+		resp, err := c.get(ctx, repo, next, "")
+		if err != nil {
+			return nil, err
+		}
+
+		var page struct {
+			Tags []string `json:"tags"`
+		}
+		err = json.NewDecoder(resp.Body).Decode(&page)
+		resp.Body.Close()
+		if err != nil {
+			return nil, errors.Wrap(err, "error decoding tag list")
+		}
+
+		tags = append(tags, page.Tags...)
+		next = nextLink(resp.Header.Get("Link"))
+	}
+
+	return tags, nil
+}
+
+var nextLinkPattern = regexp.MustCompile(`<([^>]+)>;\s*rel="next"`)
+
+// nextLink reads the next page from a Link header like </v2/app/tags/list?last=x&n=100>; rel="next"
+func nextLink(header string) string {
+	if m := nextLinkPattern.FindStringSubmatch(header); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+func (c *Client) url(path string) string {
+	if strings.HasPrefix(path, "https://") || strings.HasPrefix(path, "http://") {
+		return path
+	}
+	return "https://" + c.host + path
+}
+
+func (c *Client) get(ctx context.Context, repo, path, accept string) (*http.Response, error) {
+	return c.do(ctx, repo, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.url(path), nil)
+		if err != nil {
+			return nil, err
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		return req, nil
+	})
+}
+
+// do sends the request built by newRequest, exchanging the credentials for a bearer token and
+// sending a fresh request when the registry asks for one
+func (c *Client) do(ctx context.Context, repo string, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	send := func() (*http.Response, error) {
+		req, err := newRequest()
+		if err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		token, ok := c.tokens[repo]
+		c.mu.Unlock()
+
+		if ok {
+			req.Header.Set("Authorization", "Bearer "+token)
+		} else if c.username != "" || c.password != "" {
+			req.SetBasicAuth(c.username, c.password)
+		}
+		return c.http.Do(req)
+	}
+
+	resp, err := send()
+	if err != nil {
+		return nil, err
+	}
+
+	path := resp.Request.URL.Path
+
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+
+		if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+			return nil, &UnauthorizedError{Path: path}
+		}
+		token, err := c.fetchToken(ctx, challenge)
+		if err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		c.tokens[repo] = token
+		c.mu.Unlock()
+
+		if resp, err = send(); err != nil {
+			return nil, err
+		}
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized, resp.StatusCode == http.StatusForbidden:
+		resp.Body.Close()
+		return nil, &UnauthorizedError{Path: path}
+	case resp.StatusCode == http.StatusNotFound:
+		resp.Body.Close()
+		return nil, &NotFoundError{Path: path}
+	case resp.StatusCode >= 500:
+		resp.Body.Close()
+		return nil, &ServerError{Path: path, Status: resp.Status}
+	case resp.StatusCode >= 300:
+		resp.Body.Close()
+		return nil, fmt.Errorf("registry returned %s for %s", resp.Status, path)
+	}
+
+	return resp, nil
+}
+
+var challengeParamPattern = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+func (c *Client) fetchToken(ctx context.Context, challenge string) (string, error) {
+	params := map[string]string{}
+	for _, m := range challengeParamPattern.FindAllStringSubmatch(challenge, -1) {
+		params[m[1]] = m[2]
+	}
+
+	realm, ok := params["realm"]
+	if !ok {
+		return "", fmt.Errorf("registry auth challenge is missing a realm")
+	}
+
+	q := url.Values{}
+	if v := params["service"]; v != "" {
+		q.Set("service", v)
+	}
+	if v := params["scope"]; v != "" {
+		q.Set("scope", v)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, realm+"?"+q.Encode(), nil)
+	if err != nil {
+		return "", err
+	}
+	if c.username != "" || c.password != "" {
+		req.SetBasicAuth(c.username, c.password)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return "", errors.Wrap(err, "error requesting registry token")
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", &UnauthorizedError{Path: realm}
+	}
+
+	var body struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", errors.Wrap(err, "error decoding registry token")
+	}
+	if body.Token != "" {
+		return body.Token, nil
+	}
+	return body.AccessToken, nil
+}
@@ -0,0 +1,113 @@
+package registry
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	mediaTypeCosignPayload = "application/vnd.dev.cosign.simplesigning.v1+json"
+	cosignSignatureKey     = "dev.cosignproject.cosign/signature"
+)
+
+// ErrUnsigned is returned by VerifySignature when an image has no signature made with the key
+var ErrUnsigned = errors.New("image has no valid signature")
+
+// SignatureTag is the tag cosign stores an image's signatures under
+func SignatureTag(digest string) string {
+	return strings.Replace(digest, ":", "-", 1) + ".sig"
+}
+
+// ParsePublicKey parses a PEM encoded ECDSA public key, like the cosign.pub written by
+// cosign generate-key-pair
+func ParsePublicKey(data []byte) (*ecdsa.PublicKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("public key is not PEM encoded")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, errors.Wrap(err, "error parsing public key")
+	}
+	ecKey, ok := key.(*ecdsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("unsupported %T public key, cosign keys are ECDSA", key)
+	}
+	return ecKey, nil
+}
+
+// cosignPayload is the simple signing payload a cosign signature is made over
+type cosignPayload struct {
+	Critical struct {
+		Image struct {
+			DockerManifestDigest string `json:"docker-manifest-digest"`
+		} `json:"image"`
+		Type string `json:"type"`
+	} `json:"critical"`
+}
+
+// VerifySignature checks that the image in repo with digest has a cosign signature made with key,
+// returning ErrUnsigned when it has none
+func VerifySignature(ctx context.Context, c *Client, repo, digest string, key *ecdsa.PublicKey) error {
+	m, err := c.GetManifest(ctx, repo, SignatureTag(digest))
+	if err != nil {
+		var notFound *NotFoundError
+		if errors.As(err, &notFound) {
+			return ErrUnsigned
+		}
+		return err
+	}
+
+	for _, layer := range m.Layers {
+		sig, ok := layer.Annotations[cosignSignatureKey]
+		if layer.MediaType != mediaTypeCosignPayload || !ok {
+			continue
+		}
+
+		blob, err := c.GetBlob(ctx, repo, layer.Digest)
+		if err != nil {
+			return err
+		}
+		payload, err := ioutil.ReadAll(blob)
+		blob.Close()
+		if err != nil {
+			return err
+		}
+
+		if verifyCosignPayload(payload, layer.Digest, sig, digest, key) {
+			return nil
+		}
+	}
+
+	return ErrUnsigned
+}
+
+// verifyCosignPayload checks payload is what was stored, is signed with key and is about the
+// image with digest
+func verifyCosignPayload(payload []byte, payloadDigest, sig, digest string, key *ecdsa.PublicKey) bool {
+	hash := sha256.Sum256(payload)
+	if fmt.Sprintf("sha256:%x", hash) != payloadDigest {
+		return false
+	}
+
+	rawSig, err := base64.StdEncoding.DecodeString(sig)
+	if err != nil || !ecdsa.VerifyASN1(key, hash[:], rawSig) {
+		return false
+	}
+
+	var p cosignPayload
+	if err := json.Unmarshal(payload, &p); err != nil {
+		return false
+	}
+	return p.Critical.Image.DockerManifestDigest == digest
+}
@@ -0,0 +1,68 @@
+package registry
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func signImage(t *testing.T, f *fakeRegistry, repo, digest string, key *ecdsa.PrivateKey) {
+	payload := []byte(fmt.Sprintf(`{"critical":{"identity":{"docker-reference":"registry.fly.io/%s"},"image":{"docker-manifest-digest":"%s"},"type":"cosign container image signature"},"optional":null}`, repo, digest))
+	hash := sha256.Sum256(payload)
+	sig, err := ecdsa.SignASN1(rand.Reader, key, hash[:])
+	require.NoError(t, err)
+
+	f.blobs[digestOf(payload)] = payload
+	raw, err := json.Marshal(Manifest{
+		SchemaVersion: 2,
+		MediaType:     mediaTypeOCIManifest,
+		Layers: []Descriptor{{
+			MediaType:   mediaTypeCosignPayload,
+			Size:        int64(len(payload)),
+			Digest:      digestOf(payload),
+			Annotations: map[string]string{cosignSignatureKey: base64.StdEncoding.EncodeToString(sig)},
+		}},
+	})
+	require.NoError(t, err)
+	f.manifests["/v2/"+repo+"/manifests/"+SignatureTag(digest)] = raw
+}
+
+func TestVerifySignature(t *testing.T) {
+	f, c, closeRegistry := newFakeRegistry(t)
+	defer closeRegistry()
+
+	ctx := context.Background()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	otherKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	pub, err := ParsePublicKey(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	require.NoError(t, err)
+
+	signed := digestOf([]byte("signed image"))
+	signImage(t, f, "test-app", signed, key)
+	assert.NoError(t, VerifySignature(ctx, c, "test-app", signed, pub))
+
+	signedByOther := digestOf([]byte("image signed with another key"))
+	signImage(t, f, "test-app", signedByOther, otherKey)
+	assert.Equal(t, ErrUnsigned, VerifySignature(ctx, c, "test-app", signedByOther, pub))
+
+	assert.Equal(t, ErrUnsigned, VerifySignature(ctx, c, "test-app", digestOf([]byte("unsigned image")), pub))
+
+	_, err = ParsePublicKey([]byte("not a key"))
+	assert.EqualError(t, err, "public key is not PEM encoded")
+}
@@ -0,0 +1,206 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+var (
+	// uploadChunkSize is how much of a blob is sent per request, and the most that's resent after a failure
+	uploadChunkSize = 16 << 20
+	// uploadAttempts is how many times each request of an upload is tried
+	uploadAttempts = 5
+	// uploadRetryDelay doubles after each failed attempt, up to maxUploadRetryDelay
+	uploadRetryDelay    = time.Second
+	maxUploadRetryDelay = 30 * time.Second
+)
+
+// UploadBlob uploads a blob in chunks. A chunk that fails with a network error or a server error is
+// retried with exponential backoff, resuming from the last byte the registry received, so a flaky
+// connection doesn't restart the upload of a large layer.
+func (c *Client) UploadBlob(ctx context.Context, repo string, desc Descriptor, r io.Reader) error {
+	var location string
+	err := retry(ctx, func() error {
+		resp, err := c.do(ctx, repo, func() (*http.Request, error) {
+			return http.NewRequestWithContext(ctx, http.MethodPost, c.url(fmt.Sprintf("/v2/%s/blobs/uploads/", repo)), nil)
+		})
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		if location = resp.Header.Get("Location"); location == "" {
+			return errors.New("registry did not return an upload location")
+		}
+		return nil
+	})
+	if err != nil {
+		return errors.Wrap(err, "error starting blob upload")
+	}
+
+	chunk := make([]byte, uploadChunkSize)
+	var offset int64
+
+	for {
+		n, readErr := io.ReadFull(r, chunk)
+		if readErr != nil && readErr != io.ErrUnexpectedEOF && readErr != io.EOF {
+			return errors.Wrapf(readErr, "error reading blob %s", desc.Digest)
+		}
+		if n > 0 {
+			if location, err = c.uploadChunk(ctx, repo, location, offset, chunk[:n]); err != nil {
+				return errors.Wrapf(err, "error uploading blob %s", desc.Digest)
+			}
+			offset += int64(n)
+		}
+		if readErr != nil {
+			break
+		}
+	}
+
+	u, err := url.Parse(c.url(location))
+	if err != nil {
+		return errors.Wrap(err, "invalid upload location")
+	}
+	q := u.Query()
+	q.Set("digest", desc.Digest)
+	u.RawQuery = q.Encode()
+
+	err = retry(ctx, func() error {
+		resp, err := c.do(ctx, repo, func() (*http.Request, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), nil)
+			if err != nil {
+				return nil, err
+			}
+			req.ContentLength = 0
+			return req, nil
+		})
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		return nil
+	})
+	if err != nil {
+		return errors.Wrapf(err, "error completing upload of blob %s", desc.Digest)
+	}
+
+	return nil
+}
+
+// uploadChunk sends data, which starts at offset in the blob, returning the location for the next request.
+// After a failed attempt it asks the registry how much it received and only resends the rest.
+func (c *Client) uploadChunk(ctx context.Context, repo, location string, offset int64, data []byte) (string, error) {
+	sent := int64(0)
+
+	err := retry(ctx, func() error {
+		if sent == int64(len(data)) {
+			// the last attempt arrived but its response didn't
+			return nil
+		}
+
+		resp, err := c.do(ctx, repo, func() (*http.Request, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodPatch, c.url(location), bytes.NewReader(data[sent:]))
+			if err != nil {
+				return nil, err
+			}
+			req.ContentLength = int64(len(data)) - sent
+			req.Header.Set("Content-Type", "application/octet-stream")
+			req.Header.Set("Content-Range", fmt.Sprintf("%d-%d", offset+sent, offset+int64(len(data))-1))
+			return req, nil
+		})
+		if err == nil {
+			resp.Body.Close()
+			if next := resp.Header.Get("Location"); next != "" {
+				location = next
+			}
+			return nil
+		}
+		if !retryable(err) {
+			return err
+		}
+
+		// find out how much of the chunk arrived before the failure so the next attempt resumes from there
+		received, next, statusErr := c.uploadStatus(ctx, repo, location)
+		if statusErr != nil {
+			return err
+		}
+		if received < offset || received > offset+int64(len(data)) {
+			return errors.Errorf("registry has %d bytes of the upload, can't resume a chunk starting at %d", received, offset)
+		}
+		sent = received - offset
+		if next != "" {
+			location = next
+		}
+		return err
+	})
+
+	return location, err
+}
+
+// uploadStatus asks the registry how many bytes of an upload it has, and where to continue it
+func (c *Client) uploadStatus(ctx context.Context, repo, location string) (int64, string, error) {
+	resp, err := c.get(ctx, repo, location, "")
+	if err != nil {
+		return 0, "", err
+	}
+	resp.Body.Close()
+	next := resp.Header.Get("Location")
+
+	// Range is "0-<last byte received>", missing before any data arrives
+	rng := resp.Header.Get("Range")
+	if rng == "" {
+		return 0, next, nil
+	}
+	dash := strings.Index(rng, "-")
+	if dash < 0 {
+		return 0, "", fmt.Errorf("invalid upload range %s", rng)
+	}
+	last, err := strconv.ParseInt(rng[dash+1:], 10, 64)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid upload range %s", rng)
+	}
+	return last + 1, next, nil
+}
+
+// retry calls fn until it succeeds, fails with an error that isn't worth retrying, or runs out of attempts
+func retry(ctx context.Context, fn func() error) error {
+	delay := uploadRetryDelay
+
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= uploadAttempts || !retryable(err) {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(delay):
+		}
+
+		if delay *= 2; delay > maxUploadRetryDelay {
+			delay = maxUploadRetryDelay
+		}
+	}
+}
+
+// retryable reports whether err is a network failure or server error that may not happen again
+func retryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var serverErr *ServerError
+	var netErr net.Error
+	return errors.As(err, &serverErr) || errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
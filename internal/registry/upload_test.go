@@ -0,0 +1,41 @@
+package registry
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploadBlobResumesAfterFailure(t *testing.T) {
+	defer func(size int, delay time.Duration) { uploadChunkSize, uploadRetryDelay = size, delay }(uploadChunkSize, uploadRetryDelay)
+	uploadChunkSize, uploadRetryDelay = 8, time.Millisecond
+
+	f, c, closeSrv := newFakeRegistry(t)
+	defer closeSrv()
+	f.failPatches = 2
+
+	blob := []byte("a layer that takes a few chunks to upload")
+	desc := Descriptor{Digest: digestOf(blob), Size: int64(len(blob))}
+
+	err := c.UploadBlob(context.Background(), "app", desc, bytes.NewReader(blob))
+	assert.NoError(t, err)
+	assert.Equal(t, blob, f.blobs[desc.Digest])
+	assert.Equal(t, 1, f.uploads)
+}
+
+func TestUploadBlobGivesUp(t *testing.T) {
+	defer func(delay time.Duration) { uploadRetryDelay = delay }(uploadRetryDelay)
+	uploadRetryDelay = time.Millisecond
+
+	f, c, closeSrv := newFakeRegistry(t)
+	defer closeSrv()
+	f.failPatches = uploadAttempts
+
+	blob := []byte("layer")
+	err := c.UploadBlob(context.Background(), "app", Descriptor{Digest: digestOf(blob), Size: int64(len(blob))}, bytes.NewReader(blob))
+	assert.Error(t, err)
+	assert.Equal(t, 0, f.uploads)
+}
@@ -0,0 +1,119 @@
+// Package scaffold generates starter projects that are ready to deploy to Fly
+package scaffold
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultPort is the port every template's server listens on
+const DefaultPort = 8080
+
+// Template is a starter project
+type Template struct {
+	Name        string
+	Description string
+	Files       []File
+}
+
+// File is one file of a template. Templated files are rendered with text/template
+// and Vars, others are copied as is.
+type File struct {
+	Path     string
+	Content  string
+	Template bool
+}
+
+// Vars are the values templated files are rendered with
+type Vars struct {
+	AppName string
+	Port    int
+}
+
+// Templates returns all templates sorted by name
+func Templates() []Template {
+	out := make([]Template, len(templates))
+	copy(out, templates)
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Get returns the template with a name, or nil if there isn't one
+func Get(name string) *Template {
+	for i := range templates {
+		if templates[i].Name == name {
+			return &templates[i]
+		}
+	}
+	return nil
+}
+
+// Names lists the template names
+func Names() []string {
+	var names []string
+	for _, t := range Templates() {
+		names = append(names, t.Name)
+	}
+	return names
+}
+
+var invalidAppNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// AppName derives an app name from a directory, like "my-api" from "/src/My API"
+func AppName(dir string) string {
+	name := strings.ToLower(filepath.Base(dir))
+	name = invalidAppNameChars.ReplaceAllString(name, "-")
+	return strings.Trim(name, "-")
+}
+
+// Render writes the template's files into dir, returning the paths written relative to dir.
+// Nothing is written if any of the files already exist.
+func (t *Template) Render(dir string, vars Vars) ([]string, error) {
+	if vars.Port == 0 {
+		vars.Port = DefaultPort
+	}
+
+	contents := make([][]byte, len(t.Files))
+	for i, f := range t.Files {
+		if _, err := os.Stat(filepath.Join(dir, f.Path)); err == nil {
+			return nil, fmt.Errorf("%s already exists, use an empty directory", f.Path)
+		}
+
+		if !f.Template {
+			contents[i] = []byte(f.Content)
+			continue
+		}
+
+		tmpl, err := template.New(f.Path).Parse(f.Content)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error parsing template %s", f.Path)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, vars); err != nil {
+			return nil, errors.Wrapf(err, "error rendering %s", f.Path)
+		}
+		contents[i] = buf.Bytes()
+	}
+
+	var written []string
+	for i, f := range t.Files {
+		path := filepath.Join(dir, f.Path)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return written, err
+		}
+		if err := os.WriteFile(path, contents[i], 0644); err != nil {
+			return written, err
+		}
+		written = append(written, f.Path)
+	}
+
+	return written, nil
+}
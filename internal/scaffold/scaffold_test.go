@@ -0,0 +1,45 @@
+package scaffold
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRender(t *testing.T) {
+	for _, tmpl := range Templates() {
+		t.Run(tmpl.Name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			written, err := tmpl.Render(dir, Vars{AppName: "test-app"})
+			assert.NoError(t, err)
+			assert.Contains(t, written, "Dockerfile")
+			assert.Contains(t, written, ".github/workflows/fly.yml")
+
+			flyToml, err := os.ReadFile(filepath.Join(dir, "fly.toml"))
+			assert.NoError(t, err)
+			assert.Contains(t, string(flyToml), `app = "test-app"`)
+			assert.Contains(t, string(flyToml), "internal_port = 8080")
+
+			workflow, err := os.ReadFile(filepath.Join(dir, ".github/workflows/fly.yml"))
+			assert.NoError(t, err)
+			assert.Contains(t, string(workflow), "${{ secrets.FLY_API_TOKEN }}")
+
+			_, err = tmpl.Render(dir, Vars{AppName: "test-app"})
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestAppName(t *testing.T) {
+	assert.Equal(t, "my-api", AppName("/src/My API"))
+	assert.Equal(t, "api", AppName("/src/_api_"))
+}
+
+func TestGet(t *testing.T) {
+	assert.NotNil(t, Get("go-api"))
+	assert.Nil(t, Get("cobol"))
+	assert.Equal(t, []string{"go-api", "node-express", "rails"}, Names())
+}
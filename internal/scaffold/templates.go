@@ -0,0 +1,197 @@
+package scaffold
+
+// flyToml serves the app on 80 and 443 and gates deploys on the /healthz check
+const flyToml = `app = "{{.AppName}}"
+
+kill_signal = "SIGINT"
+kill_timeout = 5
+
+[env]
+  PORT = "{{.Port}}"
+
+[[services]]
+  internal_port = {{.Port}}
+  protocol = "tcp"
+
+  [[services.ports]]
+    handlers = ["http"]
+    port = 80
+
+  [[services.ports]]
+    handlers = ["tls", "http"]
+    port = 443
+
+  [[services.http_checks]]
+    interval = "10s"
+    grace_period = "5s"
+    method = "get"
+    path = "/healthz"
+    protocol = "http"
+    timeout = "2s"
+`
+
+// deployWorkflow deploys on every push to main, authenticating with a FLY_API_TOKEN repository secret
+const deployWorkflow = `name: Fly Deploy
+
+on:
+  push:
+    branches:
+      - main
+
+env:
+  FLY_API_TOKEN: ${{ secrets.FLY_API_TOKEN }}
+
+jobs:
+  deploy:
+    name: Deploy app
+    runs-on: ubuntu-latest
+    steps:
+      - uses: actions/checkout@v2
+      - uses: superfly/flyctl-actions@1.1
+        with:
+          args: "deploy --remote-only"
+`
+
+func commonFiles() []File {
+	return []File{
+		{Path: "fly.toml", Content: flyToml, Template: true},
+		{Path: ".github/workflows/fly.yml", Content: deployWorkflow},
+	}
+}
+
+var templates = []Template{
+	{
+		Name:        "go-api",
+		Description: "Go HTTP API using only the standard library",
+		Files: append([]File{
+			{Path: "go.mod", Template: true, Content: `module {{.AppName}}
+
+go 1.16
+`},
+			{Path: "main.go", Content: `package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+)
+
+func main() {
+	port := os.Getenv("PORT")
+	if port == "" {
+		port = "8080"
+	}
+
+	http.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]string{"message": "Hello from Fly"})
+	})
+
+	log.Printf("listening on :%s", port)
+	log.Fatal(http.ListenAndServe(":"+port, nil))
+}
+`},
+			{Path: "Dockerfile", Content: `FROM golang:1.16 as builder
+WORKDIR /src
+COPY go.mod ./
+RUN go mod download
+COPY . .
+RUN CGO_ENABLED=0 go build -o /app .
+
+FROM gcr.io/distroless/static
+COPY --from=builder /app /app
+ENV PORT=8080
+EXPOSE 8080
+CMD ["/app"]
+`},
+			{Path: ".dockerignore", Content: ".git\n"},
+		}, commonFiles()...),
+	},
+	{
+		Name:        "node-express",
+		Description: "Node.js app with Express",
+		Files: append([]File{
+			{Path: "package.json", Template: true, Content: `{
+  "name": "{{.AppName}}",
+  "version": "1.0.0",
+  "private": true,
+  "main": "server.js",
+  "scripts": {
+    "start": "node server.js"
+  },
+  "dependencies": {
+    "express": "^4.17.1"
+  }
+}
+`},
+			{Path: "server.js", Content: `const express = require("express");
+
+const app = express();
+const port = process.env.PORT || 8080;
+
+app.get("/healthz", (req, res) => res.send("ok"));
+
+app.get("/", (req, res) => res.json({ message: "Hello from Fly" }));
+
+app.listen(port, () => console.log(` + "`listening on :${port}`" + `));
+`},
+			{Path: "Dockerfile", Content: `FROM node:16-slim
+WORKDIR /app
+COPY package*.json ./
+RUN npm install --production
+COPY . .
+ENV PORT=8080
+EXPOSE 8080
+CMD ["npm", "start"]
+`},
+			{Path: ".dockerignore", Content: ".git\nnode_modules\n"},
+		}, commonFiles()...),
+	},
+	{
+		Name:        "rails",
+		Description: "Single file Ruby on Rails app served by Puma",
+		Files: append([]File{
+			{Path: "Gemfile", Content: `source "https://rubygems.org"
+
+gem "rails", "~> 6.1"
+gem "puma", "~> 5.0"
+`},
+			{Path: "config.ru", Content: `require "rails"
+require "action_controller/railtie"
+
+class App < Rails::Application
+  config.root = __dir__
+  config.eager_load = true
+  config.logger = Logger.new($stdout)
+  config.secret_key_base = ENV.fetch("SECRET_KEY_BASE") { SecureRandom.hex(64) }
+  config.hosts.clear
+
+  routes.append do
+    get "/healthz", to: proc { [200, {"Content-Type" => "text/plain"}, ["ok"]] }
+    root to: proc { [200, {"Content-Type" => "application/json"}, [{message: "Hello from Fly"}.to_json]] }
+  end
+end
+
+App.initialize!
+
+run App
+`},
+			{Path: "Dockerfile", Content: `FROM ruby:3.0-slim
+RUN apt-get update && apt-get install -y --no-install-recommends build-essential && rm -rf /var/lib/apt/lists/*
+WORKDIR /app
+COPY Gemfile* ./
+RUN bundle config set --local without "development test" && bundle install
+COPY . .
+ENV PORT=8080 RAILS_ENV=production RAILS_LOG_TO_STDOUT=1
+EXPOSE 8080
+CMD ["bundle", "exec", "puma", "-b", "tcp://0.0.0.0:8080", "config.ru"]
+`},
+			{Path: ".dockerignore", Content: ".git\nlog\ntmp\n"},
+		}, commonFiles()...),
+	},
+}
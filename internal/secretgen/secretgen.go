@@ -0,0 +1,60 @@
+// Package secretgen generates random secret values, like framework signing keys, from crypto/rand.
+package secretgen
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// Formats are the encodings Generate supports
+var Formats = []string{"hex", "base64", "alphanumeric"}
+
+const alphanumeric = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789"
+
+// Generate returns a random value of length characters. base64 is URL safe without padding, so
+// values can go in URLs and connection strings unescaped.
+func Generate(length int, format string) (string, error) {
+	if length < 1 {
+		return "", fmt.Errorf("length must be at least 1")
+	}
+
+	switch format {
+	case "hex":
+		b, err := randomBytes((length + 1) / 2)
+		if err != nil {
+			return "", err
+		}
+		return hex.EncodeToString(b)[:length], nil
+	case "base64":
+		b, err := randomBytes((length*3 + 3) / 4)
+		if err != nil {
+			return "", err
+		}
+		return base64.RawURLEncoding.EncodeToString(b)[:length], nil
+	case "alphanumeric":
+		var sb strings.Builder
+		max := big.NewInt(int64(len(alphanumeric)))
+		for i := 0; i < length; i++ {
+			n, err := rand.Int(rand.Reader, max)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteByte(alphanumeric[n.Int64()])
+		}
+		return sb.String(), nil
+	default:
+		return "", fmt.Errorf("unknown format %s, use one of %s", format, strings.Join(Formats, ", "))
+	}
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
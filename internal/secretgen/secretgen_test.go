@@ -0,0 +1,37 @@
+package secretgen
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerate(t *testing.T) {
+	patterns := map[string]*regexp.Regexp{
+		"hex":          regexp.MustCompile(`^[0-9a-f]+$`),
+		"base64":       regexp.MustCompile(`^[A-Za-z0-9_-]+$`),
+		"alphanumeric": regexp.MustCompile(`^[A-Za-z0-9]+$`),
+	}
+
+	for _, format := range Formats {
+		for _, length := range []int{1, 7, 32, 64} {
+			value, err := Generate(length, format)
+			assert.NoError(t, err)
+			assert.Len(t, value, length, format)
+			assert.Regexp(t, patterns[format], value, format)
+		}
+
+		a, _ := Generate(32, format)
+		b, _ := Generate(32, format)
+		assert.NotEqual(t, a, b, format)
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	_, err := Generate(0, "hex")
+	assert.EqualError(t, err, "length must be at least 1")
+
+	_, err = Generate(32, "base32")
+	assert.EqualError(t, err, "unknown format base32, use one of hex, base64, alphanumeric")
+}
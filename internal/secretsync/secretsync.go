@@ -0,0 +1,250 @@
+// Package secretsync reads secrets from external secret managers, through their command line
+// tools so the user's existing logins and profiles are used.
+package secretsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Provider fetches the secrets under source, a name prefix or path, keyed by app secret name
+type Provider interface {
+	Fetch(ctx context.Context, source string) (map[string]string, error)
+}
+
+// Providers are the secret managers secrets can be synced from
+var Providers = []string{"aws", "gcp", "vault"}
+
+// NewProvider returns the provider called name, one of Providers
+func NewProvider(name string) (Provider, error) {
+	switch name {
+	case "aws":
+		return awsProvider{}, nil
+	case "gcp":
+		return gcpProvider{}, nil
+	case "vault":
+		return vaultProvider{}, nil
+	default:
+		return nil, fmt.Errorf("unknown secrets provider %s, use one of %s", name, strings.Join(Providers, ", "))
+	}
+}
+
+// runCLI runs a provider's command line tool and returns its output, replaced in tests
+var runCLI = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, name, args...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if _, ok := err.(*exec.Error); ok {
+			return nil, fmt.Errorf("%s not found, install it and log in to sync secrets from it", name)
+		}
+		return nil, fmt.Errorf("%s %s: %s", name, args[0], strings.TrimSpace(stderr.String()))
+	}
+	return out, nil
+}
+
+func runJSON(ctx context.Context, v interface{}, name string, args ...string) error {
+	out, err := runCLI(ctx, name, args...)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(out, v); err != nil {
+		return fmt.Errorf("unexpected output from %s %s: %s", name, args[0], err)
+	}
+	return nil
+}
+
+var invalidNameChars = regexp.MustCompile(`[^A-Z0-9_]+`)
+
+// SecretName turns the part of a secret manager's name after the synced prefix into an
+// environment variable name, db/password becomes DB_PASSWORD
+func SecretName(name string) string {
+	name = strings.Trim(strings.ToUpper(name), "/")
+	return strings.Trim(invalidNameChars.ReplaceAllString(name, "_"), "_")
+}
+
+// addSecret adds a secret under the SecretName of key, read from the secret manager's secret from.
+// Keys that don't give a name, or give the name of a secret already added, are errors rather than
+// being dropped or overwriting each other.
+func addSecret(secrets map[string]string, key, value, from string) error {
+	name := SecretName(key)
+	if name == "" {
+		if key == "" {
+			return fmt.Errorf("%s has no name after the synced prefix, sync from a prefix that's shorter than its name", from)
+		}
+		return fmt.Errorf("%s of %s isn't a valid secret name", key, from)
+	}
+	if _, ok := secrets[name]; ok {
+		return fmt.Errorf("%s of %s would set %s, which another synced secret already sets", key, from, name)
+	}
+	secrets[name] = value
+	return nil
+}
+
+// addValue adds a secret under name, or each of its keys when the value is a JSON object of
+// strings, the way key/value secrets are stored in AWS and GCP
+func addValue(secrets map[string]string, name, value, from string) error {
+	var fields map[string]interface{}
+	if !strings.HasPrefix(strings.TrimSpace(value), "{") || json.Unmarshal([]byte(value), &fields) != nil {
+		return addSecret(secrets, name, value, from)
+	}
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		s, ok := fields[k].(string)
+		if !ok {
+			return fmt.Errorf("%s of %s isn't a string, only string values can be synced", k, from)
+		}
+		if err := addSecret(secrets, k, s, from); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type awsProvider struct{}
+
+// Fetch reads every secret whose name starts with source
+func (awsProvider) Fetch(ctx context.Context, source string) (map[string]string, error) {
+	var list struct {
+		SecretList []struct {
+			Name string
+		}
+	}
+	if err := runJSON(ctx, &list, "aws", "secretsmanager", "list-secrets", "--filters", "Key=name,Values="+source, "--output", "json"); err != nil {
+		return nil, err
+	}
+
+	secrets := map[string]string{}
+	for _, s := range list.SecretList {
+		if !strings.HasPrefix(s.Name, source) {
+			continue
+		}
+		var value struct {
+			SecretString string
+		}
+		if err := runJSON(ctx, &value, "aws", "secretsmanager", "get-secret-value", "--secret-id", s.Name, "--output", "json"); err != nil {
+			return nil, err
+		}
+		if err := addValue(secrets, strings.TrimPrefix(s.Name, source), value.SecretString, s.Name); err != nil {
+			return nil, err
+		}
+	}
+
+	return secrets, nil
+}
+
+type gcpProvider struct{}
+
+// Fetch reads the latest version of every secret whose name starts with source, in gcloud's
+// current project
+func (gcpProvider) Fetch(ctx context.Context, source string) (map[string]string, error) {
+	var list []struct {
+		Name string
+	}
+	if err := runJSON(ctx, &list, "gcloud", "secrets", "list", "--filter=name:"+source, "--format=json"); err != nil {
+		return nil, err
+	}
+
+	secrets := map[string]string{}
+	for _, s := range list {
+		// names are projects/<project>/secrets/<name>
+		name := s.Name[strings.LastIndex(s.Name, "/")+1:]
+		if !strings.HasPrefix(name, source) {
+			continue
+		}
+		out, err := runCLI(ctx, "gcloud", "secrets", "versions", "access", "latest", "--secret="+name)
+		if err != nil {
+			return nil, err
+		}
+		if err := addValue(secrets, strings.TrimPrefix(name, source), string(out), name); err != nil {
+			return nil, err
+		}
+	}
+
+	return secrets, nil
+}
+
+type vaultProvider struct{}
+
+// Fetch reads the keys of the KV secret at source, or of every secret in it when source ends
+// with a slash
+func (vaultProvider) Fetch(ctx context.Context, source string) (map[string]string, error) {
+	paths := []string{source}
+	if strings.HasSuffix(source, "/") {
+		var list struct {
+			Data struct {
+				Keys []string
+			}
+		}
+		if err := runJSON(ctx, &list, "vault", "kv", "list", "-format=json", source); err != nil {
+			return nil, err
+		}
+		paths = nil
+		for _, key := range list.Data.Keys {
+			// nested folders aren't synced
+			if !strings.HasSuffix(key, "/") {
+				paths = append(paths, source+key)
+			}
+		}
+		sort.Strings(paths)
+	}
+
+	secrets := map[string]string{}
+	for _, path := range paths {
+		var secret struct {
+			Data map[string]json.RawMessage
+		}
+		if err := runJSON(ctx, &secret, "vault", "kv", "get", "-format=json", path); err != nil {
+			return nil, err
+		}
+
+		// KV version 2 nests the values under data.data, next to data.metadata
+		data := secret.Data
+		if raw, ok := data["data"]; ok {
+			if _, hasMeta := data["metadata"]; hasMeta {
+				data = nil
+				if err := json.Unmarshal(raw, &data); err != nil {
+					return nil, fmt.Errorf("unexpected output from vault kv get %s: %s", path, err)
+				}
+			}
+		}
+
+		keys := make([]string, 0, len(data))
+		for k := range data {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+
+		for _, k := range keys {
+			raw := bytes.TrimSpace(data[k])
+			var s string
+			if json.Unmarshal(raw, &s) != nil {
+				// numbers and booleans are kept as written, there's no string to make of the rest
+				if len(raw) == 0 || raw[0] == '{' || raw[0] == '[' || string(raw) == "null" {
+					return nil, fmt.Errorf("%s of %s isn't a string, only string, number and boolean values can be synced", k, path)
+				}
+				s = string(raw)
+			}
+			if err := addSecret(secrets, k, s, path); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return secrets, nil
+}
@@ -0,0 +1,127 @@
+package secretsync
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeCLI replaces runCLI with canned output keyed by command line
+func fakeCLI(t *testing.T, outputs map[string]string) {
+	orig := runCLI
+	t.Cleanup(func() { runCLI = orig })
+
+	runCLI = func(ctx context.Context, name string, args ...string) ([]byte, error) {
+		line := name + " " + strings.Join(args, " ")
+		out, ok := outputs[line]
+		if !ok {
+			return nil, fmt.Errorf("unexpected command %s", line)
+		}
+		return []byte(out), nil
+	}
+}
+
+func TestSecretName(t *testing.T) {
+	assert.Equal(t, "DB_PASSWORD", SecretName("/db/password"))
+	assert.Equal(t, "API_KEY", SecretName("api-key"))
+}
+
+func TestAWS(t *testing.T) {
+	fakeCLI(t, map[string]string{
+		"aws secretsmanager list-secrets --filters Key=name,Values=myapp/ --output json": `{"SecretList": [{"Name": "myapp/db-url"}, {"Name": "myapp/stripe"}, {"Name": "other/myapp/x"}]}`,
+		"aws secretsmanager get-secret-value --secret-id myapp/db-url --output json":     `{"SecretString": "postgres://db"}`,
+		"aws secretsmanager get-secret-value --secret-id myapp/stripe --output json":     `{"SecretString": "{\"STRIPE_KEY\": \"sk_1\", \"STRIPE_WEBHOOK\": \"wh_1\"}"}`,
+	})
+
+	p, err := NewProvider("aws")
+	assert.NoError(t, err)
+	secrets, err := p.Fetch(context.Background(), "myapp/")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"DB_URL": "postgres://db", "STRIPE_KEY": "sk_1", "STRIPE_WEBHOOK": "wh_1"}, secrets)
+}
+
+func TestAWSInvalidSecrets(t *testing.T) {
+	tests := []struct {
+		name  string
+		value string
+		err   string
+	}{
+		{"myapp/", `"x"`, "myapp/ has no name after the synced prefix, sync from a prefix that's shorter than its name"},
+		{"myapp/stripe", `"{\"STRIPE_KEY\": {\"live\": \"sk_1\"}}"`, "STRIPE_KEY of myapp/stripe isn't a string, only string values can be synced"},
+		{"myapp/stripe", `"{\"STRIPE_KEY\": null}"`, "STRIPE_KEY of myapp/stripe isn't a string, only string values can be synced"},
+		{"myapp/stripe", `"{\"stripe-key\": \"sk_1\", \"STRIPE_KEY\": \"sk_2\"}"`, "stripe-key of myapp/stripe would set STRIPE_KEY, which another synced secret already sets"},
+	}
+
+	for _, tt := range tests {
+		fakeCLI(t, map[string]string{
+			"aws secretsmanager list-secrets --filters Key=name,Values=myapp/ --output json": `{"SecretList": [{"Name": "` + tt.name + `"}]}`,
+			"aws secretsmanager get-secret-value --secret-id " + tt.name + " --output json":  `{"SecretString": ` + tt.value + `}`,
+		})
+
+		p, _ := NewProvider("aws")
+		_, err := p.Fetch(context.Background(), "myapp/")
+		assert.EqualError(t, err, tt.err)
+	}
+}
+
+func TestAWSNormalizesKeys(t *testing.T) {
+	fakeCLI(t, map[string]string{
+		"aws secretsmanager list-secrets --filters Key=name,Values=myapp/ --output json": `{"SecretList": [{"Name": "myapp/db"}]}`,
+		"aws secretsmanager get-secret-value --secret-id myapp/db --output json":         `{"SecretString": "{\"db-password\": \"pw\", \"user\": \"app\"}"}`,
+	})
+
+	p, _ := NewProvider("aws")
+	secrets, err := p.Fetch(context.Background(), "myapp/")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"DB_PASSWORD": "pw", "USER": "app"}, secrets)
+}
+
+func TestGCP(t *testing.T) {
+	fakeCLI(t, map[string]string{
+		"gcloud secrets list --filter=name:myapp_ --format=json":     `[{"name": "projects/1/secrets/myapp_token"}, {"name": "projects/1/secrets/old_myapp_x"}]`,
+		"gcloud secrets versions access latest --secret=myapp_token": "abc",
+	})
+
+	p, err := NewProvider("gcp")
+	assert.NoError(t, err)
+	secrets, err := p.Fetch(context.Background(), "myapp_")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"TOKEN": "abc"}, secrets)
+}
+
+func TestVault(t *testing.T) {
+	fakeCLI(t, map[string]string{
+		"vault kv list -format=json secret/myapp/":  `{"data": {"keys": ["db", "nested/"]}}`,
+		"vault kv get -format=json secret/myapp/db": `{"data": {"data": {"DATABASE_URL": "postgres://db", "POOL": 5}, "metadata": {"version": 3}}}`,
+		"vault kv get -format=json kv1/myapp":       `{"data": {"API_KEY": "k"}}`,
+	})
+
+	p, err := NewProvider("vault")
+	assert.NoError(t, err)
+
+	secrets, err := p.Fetch(context.Background(), "secret/myapp/")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"DATABASE_URL": "postgres://db", "POOL": "5"}, secrets)
+
+	secrets, err = p.Fetch(context.Background(), "kv1/myapp")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"API_KEY": "k"}, secrets)
+}
+
+func TestVaultNestedValue(t *testing.T) {
+	fakeCLI(t, map[string]string{
+		"vault kv get -format=json kv1/myapp": `{"data": {"API_KEY": "k", "OPTIONS": {"a": 1}}}`,
+	})
+
+	p, _ := NewProvider("vault")
+	_, err := p.Fetch(context.Background(), "kv1/myapp")
+	assert.EqualError(t, err, "OPTIONS of kv1/myapp isn't a string, only string, number and boolean values can be synced")
+}
+
+func TestUnknownProvider(t *testing.T) {
+	_, err := NewProvider("azure")
+	assert.EqualError(t, err, "unknown secrets provider azure, use one of aws, gcp, vault")
+}
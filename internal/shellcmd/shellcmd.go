@@ -0,0 +1,54 @@
+// Package shellcmd runs commands users give flyctl, in fly.toml, flags or the flyctl config file,
+// through the platform's shell so they can use pipes, variables and quoting.
+package shellcmd
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"time"
+)
+
+// ErrTimeout is returned when a command runs past its timeout
+var ErrTimeout = errors.New("command timed out")
+
+// Options are how a command is run
+type Options struct {
+	// Dir is the directory the command runs in, flyctl's working directory when empty
+	Dir string
+	// Env is added to flyctl's environment
+	Env []string
+	// Timeout stops the command once it passes, no limit when zero
+	Timeout time.Duration
+	Stdout  io.Writer
+	Stderr  io.Writer
+}
+
+// Run runs command with sh -c, or cmd /C on Windows, returning an error when it exits with one
+func Run(ctx context.Context, command string, opts Options) error {
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.CommandContext(ctx, "cmd", "/C", command)
+	} else {
+		cmd = exec.CommandContext(ctx, "sh", "-c", command)
+	}
+	cmd.Dir = opts.Dir
+	cmd.Env = append(os.Environ(), opts.Env...)
+	cmd.Stdout = opts.Stdout
+	cmd.Stderr = opts.Stderr
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return ErrTimeout
+	}
+	return err
+}
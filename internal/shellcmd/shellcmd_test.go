@@ -0,0 +1,26 @@
+package shellcmd
+
+import (
+	"bytes"
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRun(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses sh")
+	}
+
+	dir := t.TempDir()
+	var out bytes.Buffer
+	err := Run(context.Background(), `echo "$GREETING from $(pwd)"`, Options{Dir: dir, Env: []string{"GREETING=hello"}, Stdout: &out})
+	assert.NoError(t, err)
+	assert.Equal(t, "hello from "+dir+"\n", out.String())
+
+	assert.Error(t, Run(context.Background(), "exit 3", Options{}))
+	assert.Equal(t, ErrTimeout, Run(context.Background(), "sleep 5", Options{Timeout: 50 * time.Millisecond}))
+}
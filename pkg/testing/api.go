@@ -0,0 +1,112 @@
+package testing
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"sync"
+)
+
+// HandlerFunc answers a GraphQL operation given its variables. The returned value is encoded as
+// the operation's data, and an error is returned to flyctl as a GraphQL error.
+type HandlerFunc func(vars map[string]interface{}) (interface{}, error)
+
+// Request is a GraphQL operation the API received
+type Request struct {
+	Query     string
+	Variables map[string]interface{}
+}
+
+// API is a fake Fly GraphQL API. Operations are matched by the name of their first field, or its
+// alias when it has one, like ensureRemoteBuilder or appstatus. Operations without a handler fail
+// with a GraphQL error naming the field, so tests see which calls they still need to answer.
+type API struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	handlers map[string]HandlerFunc
+	requests map[string][]Request
+}
+
+// NewAPI starts a fake API. Point flyctl at it with api.SetBaseURL(a.URL), or FLY_API_BASE_URL
+// for a flyctl binary.
+func NewAPI() *API {
+	a := &API{
+		handlers: map[string]HandlerFunc{},
+		requests: map[string][]Request{},
+	}
+	a.Server = httptest.NewServer(http.HandlerFunc(a.serveHTTP))
+	return a
+}
+
+// Handle answers operations on field with fn, replacing any earlier handler
+func (a *API) Handle(field string, fn HandlerFunc) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.handlers[field] = fn
+}
+
+// Respond answers every operation on field with data
+func (a *API) Respond(field string, data interface{}) {
+	a.Handle(field, func(map[string]interface{}) (interface{}, error) {
+		return data, nil
+	})
+}
+
+// Requests returns the operations received on field, oldest first
+func (a *API) Requests(field string) []Request {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return append([]Request(nil), a.requests[field]...)
+}
+
+// rootFieldPattern finds the first field of an operation, and its alias as in appstatus:app(...)
+var rootFieldPattern = regexp.MustCompile(`^[^{]*\{\s*(\w+)\s*(?::\s*\w+)?`)
+
+type graphQLError struct {
+	Message string `json:"message"`
+}
+
+func (a *API) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	var req Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	match := rootFieldPattern.FindStringSubmatch(req.Query)
+	if match == nil {
+		http.Error(w, "can't find the operation's field", http.StatusBadRequest)
+		return
+	}
+	field := match[1]
+
+	a.mu.Lock()
+	a.requests[field] = append(a.requests[field], req)
+	handler, ok := a.handlers[field]
+	a.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+
+	if !ok {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"errors": []graphQLError{{Message: "no fake response for " + field}},
+		})
+		return
+	}
+
+	data, err := handler(req.Variables)
+	if err != nil {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"errors": []graphQLError{{Message: err.Error()}},
+		})
+		return
+	}
+
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"data": map[string]interface{}{field: data},
+	})
+}
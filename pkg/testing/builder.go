@@ -0,0 +1,66 @@
+package testing
+
+import (
+	"errors"
+	"sync"
+)
+
+// RemoteBuilder answers ensureRemoteBuilder with a builder app called name, which reports its
+// instance as starting for the first startingPolls status checks and as running after that, like a
+// builder booting from a stopped state. Use 0 for a builder that's already running.
+func (a *API) RemoteBuilder(name string, startingPolls int) {
+	a.Respond("ensureRemoteBuilder", map[string]interface{}{
+		"url": "",
+		"app": map[string]interface{}{
+			"name": name,
+			"organization": map[string]interface{}{
+				"id":   "test-org-id",
+				"slug": "test-org",
+			},
+		},
+	})
+
+	var mu sync.Mutex
+	polls := 0
+
+	a.Handle("appstatus", func(vars map[string]interface{}) (interface{}, error) {
+		if vars["appName"] != name {
+			return nil, errors.New("Could not resolve App")
+		}
+
+		mu.Lock()
+		polls++
+		transitioning := polls <= startingPolls
+		mu.Unlock()
+
+		status := "running"
+		if transitioning {
+			status = "pending"
+		}
+
+		return map[string]interface{}{
+			"id":       name,
+			"name":     name,
+			"deployed": true,
+			"status":   "running",
+			"allocations": []map[string]interface{}{
+				{
+					"id":            "builder-alloc",
+					"idShort":       "builder",
+					"region":        "iad",
+					"status":        status,
+					"desiredStatus": "run",
+					"transitioning": transitioning,
+				},
+			},
+		}, nil
+	})
+}
+
+// RemoteBuilderUnavailable makes ensureRemoteBuilder fail with message, like an organization
+// that can't provision a builder
+func (a *API) RemoteBuilderUnavailable(message string) {
+	a.Handle("ensureRemoteBuilder", func(map[string]interface{}) (interface{}, error) {
+		return nil, errors.New(message)
+	})
+}
@@ -0,0 +1,257 @@
+package testing
+
+import (
+	"archive/tar"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// dockerAPIVersion is the Engine API version the fake daemon reports
+const dockerAPIVersion = "1.41"
+
+// Docker is a fake Docker Engine API for the calls flyctl makes to build and push images with the
+// classic builder. A build's image has a single layer, the build context it was sent.
+type Docker struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	images map[string]*fakeImage
+	builds int
+	pushes []string
+	// pingFailures is how many more pings fail, like a daemon that's still starting
+	pingFailures int
+	// failPushes is how many more pushes fail with pushError
+	failPushes int
+	pushError  string
+}
+
+type fakeImage struct {
+	id     string
+	config []byte
+	layer  []byte
+}
+
+// NewDocker starts a fake docker daemon. Point flyctl at it with DOCKER_HOST set to Host().
+func NewDocker() *Docker {
+	d := &Docker{images: map[string]*fakeImage{}}
+	d.Server = httptest.NewServer(http.HandlerFunc(d.serveHTTP))
+	return d
+}
+
+// Host is the daemon's address in the form DOCKER_HOST expects
+func (d *Docker) Host() string {
+	return "tcp://" + strings.TrimPrefix(d.URL, "http://")
+}
+
+// PingFailures makes the next n pings fail, like a remote builder whose daemon hasn't started yet
+func (d *Docker) PingFailures(n int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.pingFailures = n
+}
+
+// FailPushes makes the next n image pushes fail with message, the way the daemon reports a push
+// error partway through its output
+func (d *Docker) FailPushes(n int, message string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	d.failPushes = n
+	d.pushError = message
+}
+
+// Builds returns how many images were built
+func (d *Docker) Builds() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.builds
+}
+
+// Pushes returns the references of the images pushed through the daemon, oldest first
+func (d *Docker) Pushes() []string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return append([]string(nil), d.pushes...)
+}
+
+var versionPrefixPattern = regexp.MustCompile(`^/v[0-9.]+`)
+
+func (d *Docker) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	w.Header().Set("Api-Version", dockerAPIVersion)
+	path := versionPrefixPattern.ReplaceAllString(r.URL.Path, "")
+
+	switch {
+	case path == "/_ping":
+		if d.pingFailures > 0 {
+			d.pingFailures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, "OK")
+	case path == "/version":
+		writeJSON(w, map[string]string{"Version": "20.10.0", "ApiVersion": dockerAPIVersion, "Os": "linux", "Arch": "amd64"})
+	case path == "/info":
+		writeJSON(w, map[string]interface{}{"OSType": "linux", "Architecture": "x86_64", "NCPU": 1})
+	case path == "/build":
+		d.build(w, r)
+	case path == "/images/json":
+		d.list(w)
+	case path == "/images/get":
+		d.save(w, r)
+	case strings.HasPrefix(path, "/images/"):
+		d.image(w, r, strings.TrimPrefix(path, "/images/"))
+	default:
+		http.Error(w, fmt.Sprintf(`{"message":"page not found: %s"}`, path), http.StatusNotFound)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// build stores the build context as the image's layer and tags it with each t parameter
+func (d *Docker) build(w http.ResponseWriter, r *http.Request) {
+	layer, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	config, _ := json.Marshal(map[string]interface{}{
+		"architecture": "amd64",
+		"os":           "linux",
+		"rootfs":       map[string]interface{}{"type": "layers", "diff_ids": []string{digestOf(layer)}},
+	})
+	img := &fakeImage{id: digestOf(config), config: config, layer: layer}
+
+	d.images[img.id] = img
+	for _, tag := range r.URL.Query()["t"] {
+		d.images[tag] = img
+	}
+	d.builds++
+
+	enc := json.NewEncoder(w)
+	enc.Encode(map[string]string{"stream": "Step 1/1 : COPY . .\n"})
+	enc.Encode(map[string]string{"stream": fmt.Sprintf(" ---> %s\n", img.id[7:19])})
+	enc.Encode(map[string]interface{}{"aux": map[string]string{"ID": img.id}})
+	enc.Encode(map[string]string{"stream": fmt.Sprintf("Successfully built %s\n", img.id[7:19])})
+}
+
+func (d *Docker) list(w http.ResponseWriter) {
+	out := []map[string]interface{}{}
+	for name, img := range d.images {
+		if name == img.id {
+			continue
+		}
+		out = append(out, map[string]interface{}{"Id": img.id, "RepoTags": []string{name}, "Size": len(img.layer)})
+	}
+	writeJSON(w, out)
+}
+
+// image serves the calls on /images/<name>/..., where name may contain slashes
+func (d *Docker) image(w http.ResponseWriter, r *http.Request, rest string) {
+	name, action := rest, ""
+	if i := strings.LastIndex(rest, "/"); i >= 0 {
+		switch rest[i+1:] {
+		case "json", "tag", "push":
+			name, action = rest[:i], rest[i+1:]
+		}
+	}
+
+	img, ok := d.images[name]
+	if !ok {
+		// pushes name the repository and pass the tag separately
+		img, ok = d.images[name+":"+r.URL.Query().Get("tag")]
+	}
+	if !ok {
+		http.Error(w, fmt.Sprintf(`{"message":"No such image: %s"}`, name), http.StatusNotFound)
+		return
+	}
+
+	switch {
+	case action == "json":
+		writeJSON(w, map[string]interface{}{"Id": img.id, "Size": len(img.layer), "Os": "linux", "Architecture": "amd64"})
+	case action == "tag":
+		q := r.URL.Query()
+		ref := q.Get("repo")
+		if tag := q.Get("tag"); tag != "" {
+			ref += ":" + tag
+		}
+		d.images[ref] = img
+		w.WriteHeader(http.StatusCreated)
+	case action == "push":
+		d.push(w, name+":"+r.URL.Query().Get("tag"), img)
+	case r.Method == http.MethodDelete:
+		delete(d.images, name)
+		writeJSON(w, []map[string]string{{"Untagged": name}})
+	default:
+		http.Error(w, `{"message":"not supported by the fake daemon"}`, http.StatusNotImplemented)
+	}
+}
+
+func (d *Docker) push(w http.ResponseWriter, ref string, img *fakeImage) {
+	enc := json.NewEncoder(w)
+	enc.Encode(map[string]string{"status": "Preparing", "id": img.id[7:19]})
+
+	if d.failPushes > 0 {
+		d.failPushes--
+		enc.Encode(map[string]interface{}{
+			"errorDetail": map[string]string{"message": d.pushError},
+			"error":       d.pushError,
+		})
+		return
+	}
+
+	d.pushes = append(d.pushes, ref)
+	enc.Encode(map[string]string{"status": "Pushed", "id": img.id[7:19]})
+	enc.Encode(map[string]interface{}{"aux": map[string]interface{}{"Tag": ref[strings.LastIndex(ref, ":")+1:], "Digest": img.id, "Size": len(img.layer)}})
+}
+
+// save writes images in the docker save format, a tar of their configs, layers and a manifest.json
+func (d *Docker) save(w http.ResponseWriter, r *http.Request) {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	var manifest []map[string]interface{}
+
+	add := func(name string, data []byte) {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(data))})
+		tw.Write(data)
+	}
+
+	for _, name := range r.URL.Query()["names"] {
+		img, ok := d.images[name]
+		if !ok {
+			http.Error(w, fmt.Sprintf(`{"message":"No such image: %s"}`, name), http.StatusNotFound)
+			return
+		}
+		id := img.id[7:]
+		add(id+".json", img.config)
+		add(id+"/layer.tar", img.layer)
+		manifest = append(manifest, map[string]interface{}{
+			"Config":   id + ".json",
+			"RepoTags": []string{name},
+			"Layers":   []string{id + "/layer.tar"},
+		})
+	}
+
+	data, _ := json.Marshal(manifest)
+	add("manifest.json", data)
+	tw.Close()
+
+	w.Header().Set("Content-Type", "application/x-tar")
+	w.Write(buf.Bytes())
+}
@@ -0,0 +1,82 @@
+// Package testing provides fakes of the services flyctl talks to, so tools wrapping flyctl and
+// flyctl's own build code can be tested without a Fly account or a docker daemon:
+//
+//	h, err := testing.New()
+//	if err != nil {
+//		t.Fatal(err)
+//	}
+//	defer h.Close()
+//
+//	h.API.RemoteBuilder("fly-builder-test", 2)
+//	h.Registry.FailUploads(1)
+//
+//	cmd := exec.Command("flyctl", "deploy", "--local-only")
+//	cmd.Env = append(os.Environ(), h.Env()...)
+//
+// In process, point the api package at the fake with api.SetBaseURL(h.API.URL).
+package testing
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Harness runs a fake API, registry and docker daemon together
+type Harness struct {
+	API      *API
+	Registry *Registry
+	Docker   *Docker
+
+	dir string
+}
+
+// New starts the fakes. Close stops them.
+func New() (*Harness, error) {
+	dir, err := ioutil.TempDir("", "flyctl-testing")
+	if err != nil {
+		return nil, err
+	}
+
+	h := &Harness{
+		API:      NewAPI(),
+		Registry: NewRegistry(),
+		Docker:   NewDocker(),
+		dir:      dir,
+	}
+
+	// flyctl only pushes over https, so the registry's certificate is written where a flyctl
+	// binary can be told to trust it
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: h.Registry.Certificate().Raw})
+	if err := ioutil.WriteFile(h.certFile(), cert, 0600); err != nil {
+		h.Close()
+		return nil, err
+	}
+
+	return h, nil
+}
+
+func (h *Harness) certFile() string {
+	return filepath.Join(h.dir, "registry.pem")
+}
+
+// Env returns the environment that points a flyctl binary at the fakes. It replaces the trusted
+// certificates with the fake registry's, so the binary can't reach real services by accident.
+func (h *Harness) Env() []string {
+	return []string{
+		"FLY_API_BASE_URL=" + h.API.URL,
+		"FLY_ACCESS_TOKEN=test-token",
+		"FLY_REGISTRY_HOST=" + h.Registry.Host(),
+		"DOCKER_HOST=" + h.Docker.Host(),
+		"SSL_CERT_FILE=" + h.certFile(),
+	}
+}
+
+// Close stops the fakes
+func (h *Harness) Close() {
+	h.API.Close()
+	h.Registry.Close()
+	h.Docker.Close()
+	os.RemoveAll(h.dir)
+}
@@ -0,0 +1,155 @@
+package testing_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/superfly/flyctl/internal/registry"
+	flytesting "github.com/superfly/flyctl/pkg/testing"
+)
+
+func newHarness(t *testing.T) *flytesting.Harness {
+	h, err := flytesting.New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(h.Close)
+	return h
+}
+
+func buildContext(t *testing.T) io.Reader {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	data := []byte("FROM scratch\nCOPY . .\n")
+	if err := tw.WriteHeader(&tar.Header{Name: "Dockerfile", Mode: 0644, Size: int64(len(data))}); err != nil {
+		t.Fatal(err)
+	}
+	tw.Write(data)
+	tw.Close()
+	return &buf
+}
+
+func TestBuildAndPushWithFailedUpload(t *testing.T) {
+	h := newHarness(t)
+	ref := h.Registry.Host() + "/test-app:v1"
+
+	resp, err := http.Post(h.Docker.URL+"/v1.41/build?t="+ref, "application/x-tar", buildContext(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	assert.Contains(t, string(out), `"aux"`)
+	assert.Equal(t, 1, h.Docker.Builds())
+
+	resp, err = http.Get(h.Docker.URL + "/v1.41/images/get?names=" + ref)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	// the first chunk fails and is retried
+	h.Registry.FailUploads(1)
+
+	c := h.Registry.NewClient()
+	digest, err := registry.PushArchive(context.Background(), c, registry.Reference{Host: h.Registry.Host(), Repository: "test-app", Tag: "v1"}, resp.Body, registry.PushOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, ok := h.Registry.Manifest("test-app", "v1")
+	assert.True(t, ok)
+	_, ok = h.Registry.Manifest("test-app", digest)
+	assert.True(t, ok)
+}
+
+func TestDockerFailPushes(t *testing.T) {
+	h := newHarness(t)
+
+	resp, err := http.Post(h.Docker.URL+"/build?t=test-app:v1", "application/x-tar", buildContext(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	h.Docker.FailPushes(1, "blob upload unknown")
+
+	push := func() string {
+		resp, err := http.Post(h.Docker.URL+"/images/test-app/push?tag=v1", "", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		out, _ := io.ReadAll(resp.Body)
+		return string(out)
+	}
+
+	assert.Contains(t, push(), "blob upload unknown")
+	assert.Empty(t, h.Docker.Pushes())
+
+	assert.Contains(t, push(), "Pushed")
+	assert.Equal(t, []string{"test-app:v1"}, h.Docker.Pushes())
+}
+
+func TestDockerPingFailures(t *testing.T) {
+	h := newHarness(t)
+	h.Docker.PingFailures(1)
+
+	for _, want := range []int{http.StatusServiceUnavailable, http.StatusOK} {
+		resp, err := http.Get(h.Docker.URL + "/_ping")
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		assert.Equal(t, want, resp.StatusCode)
+	}
+}
+
+func graphQL(t *testing.T, url, query string, vars map[string]interface{}) map[string]interface{} {
+	body, _ := json.Marshal(map[string]interface{}{"query": query, "variables": vars})
+	resp, err := http.Post(url+"/graphql", "application/json", bytes.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var out map[string]interface{}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestRemoteBuilderWaitStates(t *testing.T) {
+	h := newHarness(t)
+	h.API.RemoteBuilder("fly-builder-test", 1)
+
+	out := graphQL(t, h.API.URL, `mutation($input: EnsureRemoteBuilderInput!) { ensureRemoteBuilder(input: $input) { app { name } } }`, map[string]interface{}{"input": map[string]interface{}{"appName": "test-app"}})
+	app := out["data"].(map[string]interface{})["ensureRemoteBuilder"].(map[string]interface{})["app"].(map[string]interface{})
+	assert.Equal(t, "fly-builder-test", app["name"])
+
+	status := func() bool {
+		out := graphQL(t, h.API.URL, `query($appName: String!) { appstatus:app(name: $appName) { allocations { transitioning } } }`, map[string]interface{}{"appName": "fly-builder-test"})
+		allocs := out["data"].(map[string]interface{})["appstatus"].(map[string]interface{})["allocations"].([]interface{})
+		return allocs[0].(map[string]interface{})["transitioning"].(bool)
+	}
+
+	assert.True(t, status(), "first poll should still be starting")
+	assert.False(t, status(), "second poll should be running")
+	assert.Len(t, h.API.Requests("appstatus"), 2)
+}
+
+func TestAPIUnhandledField(t *testing.T) {
+	h := newHarness(t)
+
+	out := graphQL(t, h.API.URL, `query { viewer { email } }`, nil)
+	assert.Nil(t, out["data"])
+	errs, _ := json.Marshal(out["errors"])
+	assert.Contains(t, string(errs), "no fake response for viewer")
+}
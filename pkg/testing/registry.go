@@ -0,0 +1,210 @@
+package testing
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"github.com/superfly/flyctl/internal/registry"
+)
+
+const mediaTypeDockerManifest = "application/vnd.docker.distribution.manifest.v2+json"
+
+// Registry is a fake docker v2 registry served over TLS, accepting pushes without credentials.
+// Blobs are shared by every repository.
+type Registry struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	blobs     map[string][]byte
+	manifests map[string][]byte
+	uploads   map[string][]byte
+	started   int
+	// failUploads is how many more chunk uploads fail with a server error
+	failUploads int
+}
+
+// NewRegistry starts a fake registry. Its certificate is only trusted by HTTPClient, or by a
+// flyctl binary run with the environment from Harness.Env.
+func NewRegistry() *Registry {
+	r := &Registry{
+		blobs:     map[string][]byte{},
+		manifests: map[string][]byte{},
+		uploads:   map[string][]byte{},
+	}
+	r.Server = httptest.NewTLSServer(http.HandlerFunc(r.serveHTTP))
+	return r
+}
+
+// Host is the registry's address, for image references like Host()/app:tag
+func (r *Registry) Host() string {
+	return strings.TrimPrefix(r.URL, "https://")
+}
+
+// HTTPClient returns an http client trusting the registry's certificate
+func (r *Registry) HTTPClient() *http.Client {
+	return r.Client()
+}
+
+// NewClient returns a registry client for the fake registry
+func (r *Registry) NewClient() *registry.Client {
+	return registry.NewClient(r.Host(), "x", "test-token").WithHTTPClient(r.HTTPClient())
+}
+
+// FailUploads makes the next n blob chunk uploads fail with a 502, after which pushes work again.
+// Failed chunks store nothing, so a resumed upload starts the chunk over.
+func (r *Registry) FailUploads(n int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.failUploads = n
+}
+
+// Blob returns the blob with digest, and whether the registry has it
+func (r *Registry) Blob(digest string) ([]byte, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, ok := r.blobs[digest]
+	return data, ok
+}
+
+// Manifest returns the manifest pushed to repo under a tag or digest, and whether there is one
+func (r *Registry) Manifest(repo, ref string) ([]byte, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	data, ok := r.manifests[repo+":"+ref]
+	return data, ok
+}
+
+func digestOf(data []byte) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(data))
+}
+
+// splitRepo splits a /v2/<repo>/<kind>/<rest> path, where repo may itself contain slashes
+func splitRepo(path, kind string) (repo string, rest string, ok bool) {
+	path = strings.TrimPrefix(path, "/v2/")
+	i := strings.LastIndex(path, "/"+kind+"/")
+	if i < 0 {
+		return "", "", false
+	}
+	return path[:i], path[i+len(kind)+2:], true
+}
+
+func (r *Registry) serveHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	path := req.URL.Path
+
+	if path == "/v2/" {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if strings.HasPrefix(path, "/upload/") {
+		r.serveUpload(w, req)
+		return
+	}
+
+	if _, rest, ok := splitRepo(path, "blobs"); ok {
+		if rest == "uploads/" && req.Method == http.MethodPost {
+			r.started++
+			w.Header().Set("Location", fmt.Sprintf("/upload/%d", r.started))
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		data, ok := r.blobs[rest]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+		w.Header().Set("Docker-Content-Digest", rest)
+		if req.Method == http.MethodGet {
+			w.Write(data)
+		}
+		return
+	}
+
+	if repo, ref, ok := splitRepo(path, "manifests"); ok {
+		if req.Method == http.MethodPut {
+			data, _ := io.ReadAll(req.Body)
+			digest := digestOf(data)
+			r.manifests[repo+":"+ref] = data
+			r.manifests[repo+":"+digest] = data
+			w.Header().Set("Docker-Content-Digest", digest)
+			w.WriteHeader(http.StatusCreated)
+			return
+		}
+		data, ok := r.manifests[repo+":"+ref]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", mediaTypeDockerManifest)
+		w.Header().Set("Docker-Content-Digest", digestOf(data))
+		if req.Method == http.MethodGet {
+			w.Write(data)
+		}
+		return
+	}
+
+	if strings.HasSuffix(path, "/tags/list") {
+		repo := strings.TrimSuffix(strings.TrimPrefix(path, "/v2/"), "/tags/list")
+		tags := []string{}
+		for key := range r.manifests {
+			if tag := strings.TrimPrefix(key, repo+":"); tag != key && !strings.HasPrefix(tag, "sha256:") {
+				tags = append(tags, tag)
+			}
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"name": repo, "tags": tags})
+		return
+	}
+
+	w.WriteHeader(http.StatusNotFound)
+}
+
+func (r *Registry) serveUpload(w http.ResponseWriter, req *http.Request) {
+	path := req.URL.Path
+
+	switch req.Method {
+	case http.MethodPatch:
+		data, _ := io.ReadAll(req.Body)
+		if r.failUploads > 0 {
+			r.failUploads--
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		r.uploads[path] = append(r.uploads[path], data...)
+		w.Header().Set("Location", path)
+		w.WriteHeader(http.StatusAccepted)
+	case http.MethodGet:
+		if n := len(r.uploads[path]); n > 0 {
+			w.Header().Set("Range", fmt.Sprintf("0-%d", n-1))
+		}
+		w.Header().Set("Location", path)
+		w.WriteHeader(http.StatusNoContent)
+	case http.MethodPut:
+		data, _ := io.ReadAll(req.Body)
+		data = append(r.uploads[path], data...)
+		delete(r.uploads, path)
+
+		digest := req.URL.Query().Get("digest")
+		if digest != digestOf(data) {
+			http.Error(w, "digest mismatch", http.StatusBadRequest)
+			return
+		}
+		r.blobs[digest] = data
+		w.Header().Set("Docker-Content-Digest", digest)
+		w.WriteHeader(http.StatusCreated)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}